@@ -56,6 +56,12 @@ echo "running govluncheck on everything"
         govulncheck -test ./ingesters/AzureEventHubs
         govulncheck -test ./ingesters/utils
         govulncheck -test ./ingesters/IPMIIngester
+        govulncheck -test ./ingesters/journald
+        govulncheck -test ./ingesters/mqtt
+        govulncheck -test ./ingesters/nats_consumer
+        govulncheck -test ./ingesters/amqp_consumer
+        govulncheck -test ./ingesters/redis_consumer
+        govulncheck -test ./ingesters/rest_poller
         govulncheck -test ./ingesters/regexFile
         govulncheck -test ./ingesters/PacketFleet
         govulncheck -test ./ingesters/canbus
@@ -80,9 +86,21 @@ echo "Running go test -v"
         go test -v ./filewatch
         go test -v ./ingesters/utils
         go test -v ./ingesters/kafka_consumer
+        go test -v ./ingesters/HttpIngester
         go test -v ./ingesters/SimpleRelay
         go test -v ./ipexist
         go test -v ./netflow
+        go test -v ./ingesters/netflow
+        go test -v ./journal
+        go test -v ./ingesters/journald
+        go test -v ./internal/mqtt
+        go test -v ./ingesters/mqtt
+        go test -v ./ingesters/nats_consumer
+        go test -v ./ingesters/amqp_consumer
+        go test -v ./ingesters/redis_consumer
+        go test -v ./ingesters/rest_poller
+        go test -v ./ingesters/KinesisIngester
+        go test -v ./ingesters/s3Ingester
         go test -v ./client/...
 
 
@@ -0,0 +1,469 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// stateCompleted is stored in the state file for compressed files that have been entirely consumed
+	// compressed files are not appendable, so once we have read the whole stream there is never anything left to do
+	stateCompleted int64 = -1
+
+	compressionNone  compressionType = 0
+	compressionGzip  compressionType = 1
+	compressionZstd  compressionType = 2
+	compressionBzip2 compressionType = 3
+
+	magicSize = 10 // enough to identify all of the compression formats we support
+
+	maxRotatedFiles = 1024
+	rotatedFileTTL  = 24 * time.Hour
+)
+
+var (
+	magicGzip       = []byte{0x1f, 0x8b, 0x08}
+	magicZstd       = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicBzip2      = []byte{'B', 'Z', 'h'}
+	magicBzip2Block = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+	magicBzip2Empty = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90}
+
+	compressedExtensions = map[string]compressionType{
+		`.gz`:   compressionGzip,
+		`.tgz`:  compressionGzip,
+		`.zst`:  compressionZstd,
+		`.zstd`: compressionZstd,
+		`.bz2`:  compressionBzip2,
+	}
+
+	ErrUnknownCompression = errors.New("Unknown compression format")
+)
+
+type compressionType int
+
+// streamReader is implemented by readers whose index is not an offset into the underlying file.
+// Finished returns true once the stream has been entirely consumed and can never grow.
+type streamReader interface {
+	Finished() bool
+}
+
+// detectCompression checks the magic bytes of a file for a supported compression format
+func detectCompression(hdr []byte) compressionType {
+	if bytes.HasPrefix(hdr, magicGzip) {
+		return compressionGzip
+	} else if bytes.HasPrefix(hdr, magicZstd) {
+		return compressionZstd
+	} else if len(hdr) >= magicSize && bytes.HasPrefix(hdr, magicBzip2) && hdr[3] >= '1' && hdr[3] <= '9' {
+		if bytes.Equal(hdr[4:10], magicBzip2Block) || bytes.Equal(hdr[4:10], magicBzip2Empty) {
+			return compressionBzip2
+		}
+	}
+	return compressionNone
+}
+
+func readMagic(f *os.File) (hdr []byte, err error) {
+	hdr = make([]byte, magicSize)
+	var n int
+	if n, err = f.ReadAt(hdr, 0); err == io.EOF {
+		err = nil
+	}
+	hdr = hdr[:n]
+	return
+}
+
+// isCompressedFile returns true if the file is compressed or looks like it will be.
+// A file that is too short to contain the magic bytes is treated as compressed if it has a
+// compressed file extension, this handles compression utilities that are still writing the file.
+func isCompressedFile(f *os.File) bool {
+	hdr, err := readMagic(f)
+	if err != nil {
+		return false
+	} else if detectCompression(hdr) != compressionNone {
+		return true
+	} else if len(hdr) < magicSize {
+		_, ok := compressedExtensions[strings.ToLower(filepath.Ext(f.Name()))]
+		return ok
+	}
+	return false
+}
+
+func isCompressedPath(pth string) (r bool) {
+	if f, err := os.Open(pth); err == nil {
+		r = isCompressedFile(f)
+		f.Close()
+	}
+	return
+}
+
+// CompressedReader decompresses an entire gzip, zstd, or bzip2 file and hands the decompressed stream
+// to the configured engine. The index is an offset into the decompressed stream, resuming requires
+// decompressing and discarding everything up to the index.
+// If the compressed stream is truncated, typically because it is still being written, the reader waits
+// until the file has settled and then reopens the stream and skips to the last index.
+type CompressedReader struct {
+	baseReader // idx is the decompressed offset to resume at when (re)opening the stream
+	cfg        ReaderConfig
+	dec        io.Closer
+	inner      Reader
+	finished   bool
+	resolved   bool
+	broken     bool
+	brokenSize int64
+}
+
+func NewCompressedReader(cfg ReaderConfig) (*CompressedReader, error) {
+	if cfg.Fin == nil {
+		return nil, errors.New("Reader is nil")
+	} else if cfg.MaxLineLen < 0 {
+		return nil, errors.New("maxline is invalid")
+	}
+	cr := &CompressedReader{
+		baseReader: baseReader{
+			f:       cfg.Fin,
+			maxLine: cfg.MaxLineLen,
+		},
+		cfg: cfg,
+	}
+	if cfg.StartIndex == stateCompleted {
+		cr.finished = true
+	} else if cfg.StartIndex < 0 {
+		return nil, errors.New("Invalid start index")
+	} else {
+		cr.idx = cfg.StartIndex
+	}
+	// only files we have never read from are candidates for matching an already followed file
+	cr.resolved = cr.idx != 0 || cfg.resolve == nil
+	//make sure the engine is valid before we start reading
+	if _, err := newStreamEngine(cfg, baseReader{}, bytes.NewReader(nil)); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// newStreamEngine creates the configured reader engine on top of an arbitrary stream
+func newStreamEngine(cfg ReaderConfig, br baseReader, rdr io.Reader) (Reader, error) {
+	switch cfg.Engine {
+	case RegexEngine:
+		rx, err := regexp.Compile(cfg.EngineArgs)
+		if err != nil {
+			return nil, err
+		}
+		return newRegexReader(br, rx, rdr), nil
+	case LineEngine:
+		return newLineReader(br, rdr), nil
+	case JSONEngine:
+		return newJSONReader(br, rdr), nil
+	case CSVEngine:
+		//the CSV reader needs the header, so it always starts at the beginning of the stream
+		comma, err := csvDelimiter(cfg.EngineArgs)
+		if err != nil {
+			return nil, err
+		}
+		return newCSVReader(baseReader{maxLine: br.maxLine}, rdr, comma, br.idx), nil
+	}
+	return nil, errors.New("Unsupported engine for compressed files")
+}
+
+// open (re)opens the decompression stream and skips to the current index
+func (cr *CompressedReader) open() (err error) {
+	cr.closeStream()
+	var hdr []byte
+	if hdr, err = readMagic(cr.f); err != nil {
+		return
+	} else if len(hdr) < magicSize {
+		//not enough data yet, treat it like a truncated stream and wait for more
+		return io.ErrUnexpectedEOF
+	}
+	if _, err = cr.f.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	var rdr io.Reader
+	switch detectCompression(hdr) {
+	case compressionGzip:
+		var gz *gzip.Reader
+		if gz, err = gzip.NewReader(cr.f); err != nil {
+			return
+		}
+		rdr, cr.dec = gz, gz
+	case compressionZstd:
+		var zr *zstd.Decoder
+		if zr, err = zstd.NewReader(cr.f, zstd.WithDecoderConcurrency(1)); err != nil {
+			return
+		}
+		rc := zr.IOReadCloser()
+		rdr, cr.dec = rc, rc
+	case compressionBzip2:
+		rdr = bzip2.NewReader(cr.f)
+	default:
+		return ErrUnknownCompression
+	}
+	brdr := bufio.NewReaderSize(rdr, int(DefaultFingerprintSize))
+
+	if !cr.resolved {
+		var prefix []byte
+		if prefix, err = brdr.Peek(int(DefaultFingerprintSize)); err != nil && err != io.EOF {
+			return
+		}
+		err = nil
+		cr.idx = cr.cfg.resolve(prefix)
+		cr.resolved = true
+	}
+	if cr.idx > 0 && cr.cfg.Engine != CSVEngine {
+		if _, err = io.CopyN(io.Discard, brdr, cr.idx); err != nil {
+			if err == io.EOF {
+				//the stream is shorter than our index, there is nothing left to read
+				err = nil
+				cr.finished = true
+			}
+			return
+		}
+	}
+	cr.inner, err = newStreamEngine(cr.cfg, baseReader{idx: cr.idx, maxLine: cr.maxLine}, brdr)
+	return
+}
+
+func (cr *CompressedReader) closeStream() {
+	if cr.dec != nil {
+		cr.dec.Close()
+		cr.dec = nil
+	}
+	cr.inner = nil
+}
+
+// settled returns true if a truncated stream should be retried, the file must have changed
+// and then sat idle long enough that the writer is probably done with it
+func (cr *CompressedReader) settled() bool {
+	fi, err := cr.f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Size() != cr.brokenSize && time.Since(fi.ModTime()) > maxIdleCloseTime
+}
+
+func (cr *CompressedReader) markBroken() {
+	if cr.inner != nil {
+		cr.idx = cr.inner.Index()
+	}
+	cr.closeStream()
+	cr.broken = true
+	if sz, err := cr.baseReader.FileSize(); err == nil {
+		cr.brokenSize = sz
+	}
+}
+
+func isTruncated(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) || err == io.EOF
+}
+
+func (cr *CompressedReader) ReadEntry() (ln []byte, ok bool, wasEOF bool, err error) {
+	if cr.finished && cr.inner == nil {
+		wasEOF = true
+		return
+	}
+	if cr.inner == nil {
+		if cr.broken && !cr.settled() {
+			wasEOF = true
+			return
+		}
+		if err = cr.open(); err != nil {
+			if isTruncated(err) {
+				cr.markBroken()
+				wasEOF = true
+				err = nil
+			}
+			return
+		} else if cr.inner == nil {
+			wasEOF = true
+			return
+		}
+		cr.broken = false
+	}
+	if ln, ok, wasEOF, err = cr.inner.ReadEntry(); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			cr.markBroken()
+			ln, ok, wasEOF, err = nil, false, true, nil
+		}
+		return
+	}
+	if wasEOF && !ok {
+		//the decompressor only hands back a clean EOF once the entire stream is validated
+		cr.finished = true
+	}
+	return
+}
+
+func (cr *CompressedReader) ReadRemaining() (ln []byte, err error) {
+	if cr.inner == nil {
+		return
+	}
+	return cr.inner.ReadRemaining()
+}
+
+// Finished returns true once the entire compressed stream has been decompressed
+func (cr *CompressedReader) Finished() bool {
+	return cr.finished
+}
+
+func (cr *CompressedReader) Index() int64 {
+	if cr.finished && cr.inner == nil {
+		return stateCompleted
+	} else if cr.inner != nil {
+		return cr.inner.Index()
+	}
+	return cr.idx
+}
+
+// SeekFile moves the reader to an offset in the decompressed stream, the stream is lazily reopened
+func (cr *CompressedReader) SeekFile(offset int64) error {
+	if offset < 0 {
+		return errors.New("Invalid offset")
+	}
+	cr.closeStream()
+	cr.idx = offset
+	cr.finished = false
+	cr.broken = false
+	return nil
+}
+
+func (cr *CompressedReader) Close() error {
+	cr.closeStream()
+	return cr.baseReader.Close()
+}
+
+// rotationTracker remembers the leading bytes of uncompressed files that we stopped following so that
+// when a compressed copy of the same file shows up we can skip the data we already ingested.
+type rotationTracker struct {
+	sync.Mutex
+	files []rotatedFile
+}
+
+type rotatedFile struct {
+	fp     Fingerprint
+	offset int64
+	ts     time.Time
+}
+
+// rotationState is the persisted form of a rotatedFile, it is stored in the state file after the file states
+// so that a restart between a rotation and the compression of the rotated file does not lose the fingerprint
+type rotationState struct {
+	Fingerprint Fingerprint
+	Offset      int64
+	TS          time.Time
+}
+
+func newRotationTracker() *rotationTracker {
+	return &rotationTracker{}
+}
+
+// export returns the persistable set of fingerprints
+func (rt *rotationTracker) export() (rs []rotationState) {
+	if rt == nil {
+		return
+	}
+	rt.Lock()
+	defer rt.Unlock()
+	rt.expire(time.Now())
+	rs = make([]rotationState, 0, len(rt.files))
+	for _, rf := range rt.files {
+		rs = append(rs, rotationState{Fingerprint: rf.fp, Offset: rf.offset, TS: rf.ts})
+	}
+	return
+}
+
+// restore loads previously exported fingerprints, anything invalid or expired is dropped
+func (rt *rotationTracker) restore(rs []rotationState) {
+	if rt == nil {
+		return
+	}
+	rt.Lock()
+	defer rt.Unlock()
+	for _, r := range rs {
+		if !r.Fingerprint.Usable() || r.Fingerprint.Size > DefaultFingerprintSize || r.Offset < r.Fingerprint.Size {
+			continue
+		}
+		rt.files = append(rt.files, rotatedFile{fp: r.Fingerprint, offset: r.Offset, ts: r.TS})
+	}
+	//records are appended in time order, expire relies on that
+	sort.SliceStable(rt.files, func(i, j int) bool { return rt.files[i].ts.Before(rt.files[j].ts) })
+	if len(rt.files) > maxRotatedFiles {
+		rt.files = rt.files[len(rt.files)-maxRotatedFiles:]
+	}
+	rt.expire(time.Now())
+}
+
+// record fingerprints an uncompressed file that has been consumed up to offset
+func (rt *rotationTracker) record(f *os.File, offset int64) {
+	if rt == nil || f == nil || offset < MinFingerprintSize {
+		return
+	}
+	//only fingerprint what we actually ingested
+	fp, err := FingerprintFile(f, min(offset, DefaultFingerprintSize))
+	if err != nil || !fp.Usable() {
+		return
+	}
+	rf := rotatedFile{
+		fp:     fp,
+		offset: offset,
+		ts:     time.Now(),
+	}
+	rt.Lock()
+	rt.expire(rf.ts)
+	rt.files = append(rt.files, rf)
+	if len(rt.files) > maxRotatedFiles {
+		rt.files = rt.files[len(rt.files)-maxRotatedFiles:]
+	}
+	rt.Unlock()
+}
+
+// resolve matches the decompressed prefix of a compressed file against the files we have
+// followed and returns the offset we already consumed, zero means start from the beginning.
+func (rt *rotationTracker) resolve(prefix []byte) (offset int64) {
+	if rt == nil {
+		return
+	}
+	rt.Lock()
+	defer rt.Unlock()
+	rt.expire(time.Now())
+	//newest first, the most recent rotation is the most likely match
+	for i := len(rt.files) - 1; i >= 0; i-- {
+		rf := rt.files[i]
+		if !rf.fp.matches(prefix) {
+			continue
+		}
+		rt.files = append(rt.files[:i], rt.files[i+1:]...)
+		return rf.offset
+	}
+	return
+}
+
+// expire drops fingerprints that are too old to be of any use, caller must hold the lock
+func (rt *rotationTracker) expire(now time.Time) {
+	var i int
+	for i < len(rt.files) && now.Sub(rt.files[i].ts) > rotatedFileTTL {
+		i++
+	}
+	if i > 0 {
+		rt.files = rt.files[i:]
+	}
+}
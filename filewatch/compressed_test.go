@@ -0,0 +1,435 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	compressedLineCount int = 512
+)
+
+type compressor func(io.Writer) (io.WriteCloser, error)
+
+func gzipCompressor(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriter(w), nil
+}
+
+func zstdCompressor(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func compressedLines(cnt int) (lines []string, raw []byte) {
+	bb := bytes.NewBuffer(nil)
+	for i := 0; i < cnt; i++ {
+		ln := fmt.Sprintf("%d %s", i, randomString(48))
+		lines = append(lines, ln)
+		fmt.Fprintf(bb, "%s\n", ln)
+	}
+	raw = bb.Bytes()
+	return
+}
+
+func compress(cf compressor, raw []byte) ([]byte, error) {
+	bb := bytes.NewBuffer(nil)
+	wtr, err := cf(bb)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = wtr.Write(raw); err != nil {
+		return nil, err
+	} else if err = wtr.Close(); err != nil {
+		return nil, err
+	}
+	return bb.Bytes(), nil
+}
+
+func writeCompressed(dir, name string, b []byte) (pth string, err error) {
+	pth = filepath.Join(dir, name)
+	err = os.WriteFile(pth, b, 0640)
+	return
+}
+
+func openCompressed(pth string, idx int64, resolve func([]byte) int64) (Reader, error) {
+	fin, err := os.Open(pth)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := NewReader(ReaderConfig{
+		Fin:        fin,
+		MaxLineLen: defaultMaxLine,
+		StartIndex: idx,
+		Engine:     LineEngine,
+		resolve:    resolve,
+	})
+	if err != nil {
+		fin.Close()
+		return nil, err
+	}
+	return rdr, nil
+}
+
+// readAll pulls every entry out of a reader until it hits an EOF
+func readAll(rdr Reader) (lines []string, err error) {
+	for {
+		ln, ok, eof, lerr := rdr.ReadEntry()
+		if lerr != nil {
+			return nil, lerr
+		} else if !ok {
+			if eof {
+				return
+			}
+			continue
+		}
+		lines = append(lines, string(ln))
+	}
+}
+
+func checkLines(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, expected %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("line %d mismatch: %q != %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDetectCompression(t *testing.T) {
+	_, raw := compressedLines(4)
+	gz, err := compress(gzipCompressor, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zs, err := compress(zstdCompressor, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		hdr []byte
+		ct  compressionType
+	}{
+		{hdr: gz, ct: compressionGzip},
+		{hdr: zs, ct: compressionZstd},
+		{hdr: []byte("BZh91AY&SY\x00\x00"), ct: compressionBzip2},
+		{hdr: []byte("BZh9\x17rE8P\x90"), ct: compressionBzip2},
+		{hdr: []byte("BZh hello world"), ct: compressionNone},
+		{hdr: raw, ct: compressionNone},
+		{hdr: nil, ct: compressionNone},
+	}
+	for i, tt := range tests {
+		if ct := detectCompression(tt.hdr); ct != tt.ct {
+			t.Fatalf("%d: bad compression type %v != %v", i, ct, tt.ct)
+		}
+	}
+}
+
+func TestCompressedReader(t *testing.T) {
+	dir := t.TempDir()
+	lines, raw := compressedLines(compressedLineCount)
+	for name, cf := range map[string]compressor{`test.log.gz`: gzipCompressor, `test.log.zst`: zstdCompressor} {
+		b, err := compress(cf, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pth, err := writeCompressed(dir, name, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rdr, err := openCompressed(pth, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := rdr.(*CompressedReader); !ok {
+			t.Fatalf("%s: got a %T instead of a compressed reader", name, rdr)
+		}
+		got, err := readAll(rdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkLines(t, got, lines)
+		if !rdr.(streamReader).Finished() {
+			t.Fatalf("%s: reader is not finished", name)
+		}
+		if err := rdr.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		//resume halfway through the decompressed stream
+		half := compressedLineCount / 2
+		var idx int64
+		for _, ln := range lines[:half] {
+			idx += int64(len(ln) + 1)
+		}
+		if rdr, err = openCompressed(pth, idx, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got, err = readAll(rdr); err != nil {
+			t.Fatal(err)
+		}
+		checkLines(t, got, lines[half:])
+		rdr.Close()
+
+		//completed files never hand back anything
+		if rdr, err = openCompressed(pth, stateCompleted, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got, err = readAll(rdr); err != nil {
+			t.Fatal(err)
+		} else if len(got) != 0 {
+			t.Fatalf("%s: got %d lines out of a completed file", name, len(got))
+		} else if idx := rdr.Index(); idx != stateCompleted {
+			t.Fatalf("%s: bad completed index %d", name, idx)
+		}
+		rdr.Close()
+	}
+}
+
+func TestCompressedReaderTruncated(t *testing.T) {
+	dir := t.TempDir()
+	lines, raw := compressedLines(compressedLineCount)
+	for name, cf := range map[string]compressor{`test.log.gz`: gzipCompressor, `test.log.zst`: zstdCompressor} {
+		b, err := compress(cf, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//write out a partial file, as if the compression utility was still working
+		pth, err := writeCompressed(dir, name, b[:len(b)/2])
+		if err != nil {
+			t.Fatal(err)
+		}
+		rdr, err := openCompressed(pth, 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, err := readAll(rdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rdr.(streamReader).Finished() {
+			t.Fatalf("%s: truncated stream reported as finished", name)
+		}
+		if len(first) >= len(lines) {
+			t.Fatalf("%s: got too many lines out of a truncated stream: %d", name, len(first))
+		}
+
+		//finish the file and backdate it so that it looks settled
+		if err = os.WriteFile(pth, b, 0640); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-time.Minute)
+		if err = os.Chtimes(pth, old, old); err != nil {
+			t.Fatal(err)
+		}
+		rest, err := readAll(rdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkLines(t, append(first, rest...), lines)
+		if !rdr.(streamReader).Finished() {
+			t.Fatalf("%s: reader is not finished", name)
+		}
+		rdr.Close()
+	}
+}
+
+func TestCompressedFollower(t *testing.T) {
+	var tlh trackingLH
+	var state int64
+	lines, raw := compressedLines(compressedLineCount)
+	b, err := compress(gzipCompressor, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pth, err := writeCompressed(t.TempDir(), `test.log.gz`, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fl, err := NewFollower(FollowerConfig{
+		BaseName: baseName,
+		FilePath: pth,
+		State:    &state,
+		Handler:  &tlh,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fl.Sync(make(chan os.Signal)); err != nil {
+		t.Fatal(err)
+	}
+	if err = fl.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(tlh.mp) != len(lines) {
+		t.Fatalf("got %d lines, expected %d", len(tlh.mp), len(lines))
+	}
+	if state != stateCompleted {
+		t.Fatalf("compressed file not marked completed: %d", state)
+	}
+}
+
+func TestRotationResolve(t *testing.T) {
+	dir := t.TempDir()
+	lines, raw := compressedLines(compressedLineCount)
+	rt := newRotationTracker()
+
+	//pretend we followed the uncompressed file up to some point and then it was rotated
+	consumed := len(lines) * 3 / 4
+	var offset int64
+	for _, ln := range lines[:consumed] {
+		offset += int64(len(ln) + 1)
+	}
+	pth := filepath.Join(dir, `test.log`)
+	if err := os.WriteFile(pth, raw, 0640); err != nil {
+		t.Fatal(err)
+	}
+	fin, err := os.Open(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.record(fin, offset)
+	fin.Close()
+
+	b, err := compress(gzipCompressor, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pth, err = writeCompressed(dir, `test.log.1.gz`, b); err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := openCompressed(pth, 0, rt.resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readAll(rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr.Close()
+	checkLines(t, got, lines[consumed:])
+
+	//a match is only ever used once
+	if rdr, err = openCompressed(pth, 0, rt.resolve); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = readAll(rdr); err != nil {
+		t.Fatal(err)
+	}
+	rdr.Close()
+	checkLines(t, got, lines)
+
+	//nil files are ignored
+	if rt.record(nil, offset); len(rt.files) != 0 {
+		t.Fatal("recorded a nil file")
+	}
+}
+
+func TestRotationRestart(t *testing.T) {
+	for _, ic := range []IdentityConfig{{}, {Fingerprint: true}} {
+		dir := t.TempDir()
+		stateFile := filepath.Join(dir, `state`)
+		lines, raw := compressedLines(compressedLineCount)
+		consumed := len(lines) / 2
+		var offset int64
+		for _, ln := range lines[:consumed] {
+			offset += int64(len(ln) + 1)
+		}
+		pth := filepath.Join(dir, `test.log`)
+		if err := os.WriteFile(pth, raw, 0640); err != nil {
+			t.Fatal(err)
+		}
+
+		//the uncompressed file is rotated away and then we are stopped before it is compressed
+		fm, err := NewFilterManagerWithIdentity(stateFile, ic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fin, err := os.Open(pth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fm.rotations.record(fin, offset)
+		fin.Close()
+		if err = fm.Close(); err != nil {
+			t.Fatal(err)
+		} else if err = os.Remove(pth); err != nil {
+			t.Fatal(err)
+		}
+
+		//older readers only look at the states and must not trip over the rotations
+		if _, err = ReadStateFile(stateFile); err != nil {
+			t.Fatal(err)
+		}
+
+		//the compressed copy shows up after the restart
+		if fm, err = NewFilterManagerWithIdentity(stateFile, ic); err != nil {
+			t.Fatal(err)
+		}
+		b, err := compress(gzipCompressor, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pth, err = writeCompressed(dir, `test.log.1.gz`, b); err != nil {
+			t.Fatal(err)
+		}
+		rdr, err := openCompressed(pth, 0, fm.rotations.resolve)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := readAll(rdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rdr.Close()
+		checkLines(t, got, lines[consumed:])
+
+		//the consumed fingerprint is not persisted again
+		if err = fm.Close(); err != nil {
+			t.Fatal(err)
+		} else if fm, err = NewFilterManagerWithIdentity(stateFile, ic); err != nil {
+			t.Fatal(err)
+		} else if len(fm.rotations.files) != 0 {
+			t.Fatalf("consumed rotation was restored: %d", len(fm.rotations.files))
+		}
+		fm.Close()
+	}
+}
+
+func TestRotationRestore(t *testing.T) {
+	now := time.Now()
+	rt := newRotationTracker()
+	rt.restore([]rotationState{
+		{Fingerprint: Fingerprint{Size: DefaultFingerprintSize}, Offset: 8192, TS: now.Add(-time.Minute)},
+		{Fingerprint: Fingerprint{Size: MinFingerprintSize}, Offset: 1024, TS: now.Add(-2 * time.Minute)},
+		{Fingerprint: Fingerprint{Size: DefaultFingerprintSize}, Offset: 8192, TS: now.Add(-2 * rotatedFileTTL)}, //expired
+		{Fingerprint: Fingerprint{Size: 1}, Offset: 8192, TS: now},                                               //too small
+		{Fingerprint: Fingerprint{Size: DefaultFingerprintSize + 1}, Offset: 8192, TS: now},                      //too large
+		{Fingerprint: Fingerprint{Size: DefaultFingerprintSize}, Offset: 10, TS: now},                            //offset before the end of the fingerprint
+	})
+	if len(rt.files) != 2 {
+		t.Fatalf("bad restored count %d", len(rt.files))
+	} else if rt.files[0].offset != 1024 || rt.files[1].offset != 8192 {
+		t.Fatalf("restored fingerprints are out of order: %+v", rt.files)
+	}
+	if rs := rt.export(); len(rs) != 2 || rs[0].Offset != 1024 || !rs[1].TS.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("bad export %+v", rs)
+	}
+}
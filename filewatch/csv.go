@@ -0,0 +1,257 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidCSVDelimiter = errors.New("CSV delimiter must be a single character that is not a quote or line break")
+	ErrMissingCSVHeader    = errors.New("Failed to read CSV header")
+)
+
+// CSVReader reads CSV files with a header row and emits each row as a JSON object keyed by the header.
+// The engine arguments optionally specify the delimiter, the default is a comma.  Quoted fields may span lines.
+// The index is the offset of the end of the last row emitted, when resuming the header is re-read from the
+// start of the file.  Columns without a header name, or beyond the end of the header, are named by their
+// position, e.g. field3.
+type CSVReader struct {
+	baseReader
+	brdr    *bufio.Reader
+	comma   rune
+	header  [][]byte // JSON encoded column names
+	buff    []byte   // unconsumed data, buff[0] is at idx
+	pos     int      // scan position in buff
+	inQuote bool
+	skip    int64 // rows ending at or before this offset are not emitted, used when the stream can't be seeked
+}
+
+// csvDelimiter returns the delimiter specified in the engine arguments
+func csvDelimiter(args string) (r rune, err error) {
+	if args == `` {
+		r = ','
+		return
+	} else if args == `\t` {
+		r = '\t'
+		return
+	}
+	var sz int
+	if r, sz = utf8.DecodeRuneInString(args); sz != len(args) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		err = ErrInvalidCSVDelimiter
+	}
+	return
+}
+
+// ValidateCSVDelimiter checks the engine arguments for the CSV engine
+func ValidateCSVDelimiter(args string) (err error) {
+	_, err = csvDelimiter(args)
+	return
+}
+
+func NewCSVReader(cfg ReaderConfig) (*CSVReader, error) {
+	comma, err := csvDelimiter(cfg.EngineArgs)
+	if err != nil {
+		return nil, err
+	}
+	var hdr [][]byte
+	if cfg.StartIndex > 0 && cfg.Fin != nil {
+		//we are picking up part way through the file, go get the header
+		tmp := newCSVReader(baseReader{maxLine: cfg.MaxLineLen}, io.NewSectionReader(cfg.Fin, 0, cfg.StartIndex), comma, 0)
+		if hdr, err = tmp.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+	br, err := newBaseReader(cfg.Fin, cfg.MaxLineLen, cfg.StartIndex)
+	if err != nil {
+		return nil, err
+	}
+	cr := newCSVReader(br, cfg.Fin, comma, 0)
+	cr.header = hdr
+	return cr, nil
+}
+
+// newCSVReader builds a CSV reader that pulls from an arbitrary stream rather than the base file.
+// The stream must start at the beginning of the file so that the header can be read, rows up to skip are discarded.
+func newCSVReader(br baseReader, rdr io.Reader, comma rune, skip int64) *CSVReader {
+	return &CSVReader{
+		baseReader: br,
+		brdr:       bufio.NewReader(rdr),
+		comma:      comma,
+		skip:       skip,
+	}
+}
+
+func (cr *CSVReader) SeekFile(offset int64) error {
+	if err := cr.baseReader.SeekFile(offset); err != nil {
+		return err
+	}
+	cr.brdr.Reset(cr.f)
+	cr.buff, cr.pos, cr.inQuote = nil, 0, false
+	if offset == 0 {
+		cr.header = nil
+	}
+	return nil
+}
+
+// readHeader reads rows until the header is populated
+func (cr *CSVReader) readHeader() ([][]byte, error) {
+	for cr.header == nil {
+		_, _, wasEOF, err := cr.ReadEntry()
+		if err != nil {
+			return nil, err
+		} else if wasEOF && cr.header == nil {
+			if _, err = cr.ReadRemaining(); err != nil {
+				return nil, err
+			} else if cr.header == nil {
+				return nil, ErrMissingCSVHeader
+			}
+		}
+	}
+	return cr.header, nil
+}
+
+func (cr *CSVReader) ReadEntry() (ln []byte, ok bool, wasEOF bool, err error) {
+	for {
+		var row []byte
+		for row == nil {
+			if row = cr.next(); row != nil {
+				break
+			}
+			b := make([]byte, 8*1024)
+			n, lerr := cr.brdr.Read(b)
+			if lerr != nil && lerr != io.EOF {
+				err = lerr
+				return
+			} else if lerr == io.EOF {
+				wasEOF = true
+			}
+			if n == 0 {
+				return
+			}
+			cr.buff = append(cr.buff, b[:n]...)
+		}
+		if ln, ok = cr.handleRow(row); ok {
+			return
+		}
+	}
+}
+
+func (cr *CSVReader) ReadRemaining() (ln []byte, err error) {
+	var ok bool
+	if ln, ok, _, err = cr.ReadEntry(); err != nil || ok {
+		return
+	} else if len(cr.buff) == 0 {
+		return
+	}
+	row := cr.buff
+	cr.idx += int64(len(row))
+	cr.buff, cr.pos, cr.inQuote = nil, 0, false
+	ln, _ = cr.handleRow(row)
+	return
+}
+
+// next pulls a complete row out of the buffer, line breaks inside quoted fields do not end a row
+func (cr *CSVReader) next() (row []byte) {
+	for ; cr.pos < len(cr.buff); cr.pos++ {
+		switch cr.buff[cr.pos] {
+		case '"':
+			//escaped quotes inside a quoted field toggle twice, so this works out
+			cr.inQuote = !cr.inQuote
+		case '\n':
+			if cr.inQuote {
+				continue
+			}
+			row = cr.buff[:cr.pos+1]
+			cr.idx += int64(len(row))
+			cr.buff = cr.buff[cr.pos+1:]
+			cr.pos = 0
+			return
+		}
+	}
+	if cr.maxLine > 0 && len(cr.buff) > cr.maxLine {
+		//something is wrong, probably an unbalanced quote, take what we have
+		row = cr.buff
+		cr.idx += int64(len(row))
+		cr.buff, cr.pos, cr.inQuote = nil, 0, false
+	}
+	return
+}
+
+// handleRow parses a row, ok is false if the row does not produce an entry
+func (cr *CSVReader) handleRow(row []byte) (ln []byte, ok bool) {
+	if cr.idx <= cr.skip && cr.header != nil {
+		return
+	}
+	if len(bytes.TrimSpace(row)) == 0 {
+		return
+	}
+	rdr := csv.NewReader(bytes.NewReader(row))
+	rdr.Comma = cr.comma
+	rdr.FieldsPerRecord = -1
+	rdr.LazyQuotes = true
+	flds, err := rdr.Read()
+	if err != nil {
+		//not parseable, hand the raw row back so that nothing is lost
+		if cr.header != nil {
+			ln, ok = bytes.TrimRight(row, "\r\n"), true
+		}
+		return
+	}
+	if cr.header == nil {
+		cr.setHeader(flds)
+		return
+	}
+	bb := bytes.NewBuffer(make([]byte, 0, len(row)*2))
+	bb.WriteByte('{')
+	for i, v := range flds {
+		if i > 0 {
+			bb.WriteByte(',')
+		}
+		if i < len(cr.header) {
+			bb.Write(cr.header[i])
+		} else {
+			bb.Write(csvColumnName(i))
+		}
+		bb.WriteByte(':')
+		val, _ := json.Marshal(v)
+		bb.Write(val)
+	}
+	bb.WriteByte('}')
+	ln, ok = bb.Bytes(), true
+	return
+}
+
+func (cr *CSVReader) setHeader(flds []string) {
+	if len(flds) > 0 {
+		flds[0] = strings.TrimPrefix(flds[0], "\ufeff") //drop the byte order mark
+	}
+	cr.header = make([][]byte, len(flds))
+	seen := make(map[string]bool, len(flds))
+	for i, v := range flds {
+		if v == `` || seen[v] {
+			cr.header[i] = csvColumnName(i)
+			continue
+		}
+		seen[v] = true
+		cr.header[i], _ = json.Marshal(v)
+	}
+}
+
+func csvColumnName(i int) []byte {
+	return []byte(fmt.Sprintf(`"field%d"`, i+1))
+}
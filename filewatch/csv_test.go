@@ -0,0 +1,112 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testCSV = "\ufeffname,count,,note\r\n" +
+	"alpha,1,x,plain\r\n" +
+	"\r\n" +
+	"beta,2,y,\"multi\nline, with \"\"quotes\"\"\"\r\n" +
+	"gamma,3,z,extra,columns\r\n" +
+	"delta,4\r\n"
+
+var testCSVRecords = []string{
+	`{"name":"alpha","count":"1","field3":"x","note":"plain"}`,
+	`{"name":"beta","count":"2","field3":"y","note":"multi\nline, with \"quotes\""}`,
+	`{"name":"gamma","count":"3","field3":"z","note":"extra","field5":"columns"}`,
+	`{"name":"delta","count":"4"}`,
+}
+
+func TestCSVReader(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `test.csv`)
+	if err := os.WriteFile(pth, []byte(testCSV), 0640); err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := openEngine(pth, CSVEngine, ``, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, ok, _, err := rdr.ReadEntry()
+	if err != nil || !ok {
+		t.Fatal("failed to read first row", ok, err)
+	} else if string(ln) != testCSVRecords[0] {
+		t.Fatalf("bad row %s", ln)
+	}
+	idx := rdr.Index()
+	lines, err := readAll(rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, lines, testCSVRecords[1:])
+	rdr.Close()
+
+	//resuming re-reads the header from the start of the file
+	if rdr, err = openEngine(pth, CSVEngine, ``, idx); err != nil {
+		t.Fatal(err)
+	}
+	if lines, err = readAll(rdr); err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, lines, testCSVRecords[1:])
+	rdr.Close()
+
+	//same thing out of a compressed file, where the stream has to be skipped rather than seeked
+	b, err := compress(gzipCompressor, []byte(testCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzpth, err := writeCompressed(t.TempDir(), `test.csv.gz`, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rdr, err = openEngine(gzpth, CSVEngine, ``, idx); err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+	if lines, err = readAll(rdr); err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, lines, testCSVRecords[1:])
+}
+
+func TestCSVReaderDelimiter(t *testing.T) {
+	for _, v := range []string{`"`, `ab`, "\n"} {
+		if _, err := csvDelimiter(v); err == nil {
+			t.Fatalf("failed to catch bad delimiter %q", v)
+		}
+	}
+	pth := filepath.Join(t.TempDir(), `test.tsv`)
+	if err := os.WriteFile(pth, []byte("a\tb\n1\t2\n3\t4"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := openEngine(pth, CSVEngine, `\t`, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+	lines, err := readAll(rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the last row has no line break, it is only handed back once the reader is told to take what is left
+	checkLines(t, lines, []string{`{"a":"1","b":"2"}`})
+	ln, err := rdr.ReadRemaining()
+	if err != nil {
+		t.Fatal(err)
+	} else if string(ln) != `{"a":"3","b":"4"}` {
+		t.Fatalf("bad remaining row %s", ln)
+	} else if rdr.Index() != int64(len("a\tb\n1\t2\n3\t4")) {
+		t.Fatalf("bad index %d", rdr.Index())
+	}
+}
@@ -0,0 +1,381 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	DispositionNone   int = 0 // leave files alone once they are ingested
+	DispositionDelete int = 1 // delete files once they are ingested
+	DispositionMove   int = 2 // move files into an archive directory once they are ingested
+	DispositionRename int = 3 // add a suffix to files once they are ingested
+
+	// DefaultDispositionIdle is how long a fully ingested file must sit idle before its disposition runs
+	DefaultDispositionIdle = time.Minute
+	// MinDispositionIdle is the shortest idle time we allow, a file must outlive the partial line timeout
+	MinDispositionIdle = 2 * maxIdleDataTime
+	// DefaultDispositionSuffix is appended to file names when renaming without a configured suffix
+	DefaultDispositionSuffix = `.ingested`
+
+	dispositionSyncTimeout = 10 * time.Second
+	dispositionSkipWarn    = 6 // warn every time this many disposition passes in a row are skipped
+)
+
+var (
+	ErrNoDispositionTarget     = errors.New("Disposition requires a target directory or suffix")
+	ErrDispositionStillMatches = errors.New("Disposition target still matches the follower filter")
+	errDispositionBusy         = errors.New("previous disposition pass is still running")
+
+	dispositionTickInterval = 10 * time.Second
+)
+
+// Syncer is used to ensure that everything handed to the ingest pipeline is durable before a file
+// disposition runs, the ingest muxer satisfies this interface.
+type Syncer interface {
+	Sync(time.Duration) error
+}
+
+// DispositionConfig describes what happens to a file once it has been completely ingested and has gone idle.
+// Target is the archive directory when moving and the suffix when renaming.
+type DispositionConfig struct {
+	Action int
+	Target string
+	Idle   time.Duration
+}
+
+func (dc DispositionConfig) Enabled() bool {
+	return dc.Action != DispositionNone
+}
+
+// Validate checks the disposition config and applies defaults
+func (dc *DispositionConfig) Validate() error {
+	switch dc.Action {
+	case DispositionNone:
+		return nil
+	case DispositionDelete:
+	case DispositionMove:
+		if dc.Target == `` {
+			return ErrNoDispositionTarget
+		}
+		dc.Target = filepath.Clean(dc.Target)
+	case DispositionRename:
+		if dc.Target == `` {
+			dc.Target = DefaultDispositionSuffix
+		}
+	default:
+		return fmt.Errorf("Unknown disposition action %d", dc.Action)
+	}
+	if dc.Idle == 0 {
+		dc.Idle = DefaultDispositionIdle
+	} else if dc.Idle < MinDispositionIdle {
+		return fmt.Errorf("Disposition idle time must be at least %v", MinDispositionIdle)
+	}
+	return nil
+}
+
+func (dc DispositionConfig) String() string {
+	switch dc.Action {
+	case DispositionNone:
+		return `none`
+	case DispositionDelete:
+		return `delete`
+	case DispositionMove:
+		return `move`
+	case DispositionRename:
+		return `rename`
+	}
+	return `unknown`
+}
+
+// dispositionCandidate is a completely ingested file that is waiting on an ingest sync before its
+// disposition runs.  We snapshot the file and the states so that we can make sure nothing moved while syncing.
+type dispositionCandidate struct {
+	pth     string
+	cfg     DispositionConfig
+	mtchs   [][]string
+	size    int64
+	modTime time.Time
+	states  map[FileName]int64
+}
+
+// dispositionCandidates collects files that have been completely consumed by every filter that follows them
+// and have been idle for at least the disposition idle time.
+func (fm *FilterManager) dispositionCandidates() (cands []dispositionCandidate) {
+	fm.mtx.Lock()
+	defer fm.mtx.Unlock()
+	if !fm.nolockHasDispositions() {
+		return
+	}
+	seen := map[string]bool{}
+	for k := range fm.states {
+		if seen[k.FilePath] {
+			continue
+		}
+		seen[k.FilePath] = true
+		if dc, ok := fm.nolockDispositionCandidate(k.FilePath); ok {
+			cands = append(cands, dc)
+		}
+	}
+	return
+}
+
+func (fm *FilterManager) hasDispositions() bool {
+	fm.mtx.Lock()
+	defer fm.mtx.Unlock()
+	return fm.nolockHasDispositions()
+}
+
+// caller MUST HOLD THE LOCK
+func (fm *FilterManager) nolockHasDispositions() bool {
+	for _, v := range fm.filters {
+		if v.Disposition.Enabled() {
+			return true
+		}
+	}
+	return false
+}
+
+// nolockDispositionCandidate checks if a file is ready for its disposition
+// caller MUST HOLD THE LOCK
+func (fm *FilterManager) nolockDispositionCandidate(pth string) (dc dispositionCandidate, ok bool) {
+	fi, err := os.Stat(pth)
+	if err != nil || !fi.Mode().IsRegular() {
+		return
+	}
+	dc = dispositionCandidate{
+		pth:     pth,
+		size:    fi.Size(),
+		modTime: fi.ModTime(),
+		states:  map[FileName]int64{},
+	}
+	fname := filepath.Base(pth)
+	fdir := filepath.Dir(pth)
+	for _, v := range fm.filters {
+		if v.loc != fdir || !fm.matchFile(v.mtchs, fname) {
+			continue
+		}
+		//every filter that follows this file must agree on what happens to it
+		if !v.Disposition.Enabled() {
+			return dc, false
+		} else if len(dc.states) == 0 {
+			dc.cfg = v.Disposition
+		} else if dc.cfg != v.Disposition {
+			return dc, false
+		}
+		stid := FileName{BaseName: v.bname, FilePath: pth}
+		si, ok := fm.states[stid]
+		if !ok || si == nil {
+			return dc, false
+		}
+		//compressed files are done when their stream is exhausted, everything else when we hit the end
+		if st := *si; st != stateCompleted && (st != dc.size || isCompressedPath(pth)) {
+			return dc, false
+		}
+		idle := time.Since(dc.modTime)
+		if flw, ok := fm.followers[stid]; ok && flw.IdleDuration() < idle {
+			idle = flw.IdleDuration()
+		}
+		if idle < v.Disposition.Idle {
+			return dc, false
+		}
+		dc.states[stid] = *si
+		dc.mtchs = append(dc.mtchs, v.mtchs)
+	}
+	ok = len(dc.states) > 0
+	return
+}
+
+// dispose runs the disposition for each candidate that has not changed since it was collected.
+// The caller must have synced the ingest pipeline after collecting the candidates.
+func (fm *FilterManager) dispose(cands []dispositionCandidate) {
+	fm.mtx.Lock()
+	defer fm.mtx.Unlock()
+	var hit bool
+	for _, dc := range cands {
+		if !fm.nolockUnchanged(dc) {
+			continue
+		}
+		//stop following without purging the states, if the disposition fails the states are still good
+		if _, err := fm.nolockRemoveFollower(dc.pth, false); err != nil {
+			fm.logger.Error("failed to stop following file for disposition", log.KV("path", dc.pth), log.KVErr(err))
+			continue
+		}
+		dst, err := dc.apply()
+		if err != nil {
+			fm.logger.Error("failed to apply file disposition",
+				log.KV("path", dc.pth), log.KV("disposition", dc.cfg), log.KVErr(err))
+			continue
+		}
+		for k := range dc.states {
+			delete(fm.states, k)
+		}
+		hit = true
+		fm.logger.Info("applied file disposition",
+			log.KV("path", dc.pth), log.KV("disposition", dc.cfg), log.KV("destination", dst))
+	}
+	if hit {
+		if err := fm.nolockDumpStates(); err != nil {
+			fm.logger.Error("failed to flush states", log.KVErr(err))
+		}
+	}
+}
+
+// nolockUnchanged makes sure a candidate file and its states did not change while we were syncing
+// caller MUST HOLD THE LOCK
+func (fm *FilterManager) nolockUnchanged(dc dispositionCandidate) bool {
+	fi, err := os.Stat(dc.pth)
+	if err != nil || fi.Size() != dc.size || !fi.ModTime().Equal(dc.modTime) {
+		return false
+	}
+	for k, v := range dc.states {
+		if si, ok := fm.states[k]; !ok || si == nil || *si != v {
+			return false
+		}
+	}
+	return true
+}
+
+// apply performs the file disposition, returning where the file went
+func (dc dispositionCandidate) apply() (dst string, err error) {
+	switch dc.cfg.Action {
+	case DispositionDelete:
+		err = os.Remove(dc.pth)
+	case DispositionMove:
+		if err = os.MkdirAll(dc.cfg.Target, 0750); err != nil {
+			return
+		} else if dst, err = freeName(filepath.Join(dc.cfg.Target, filepath.Base(dc.pth))); err != nil {
+			return
+		}
+		err = moveFile(dc.pth, dst)
+	case DispositionRename:
+		dst = dc.pth + dc.cfg.Target
+		//a renamed file that still matches the filter would just get ingested again
+		for _, mtchs := range dc.mtchs {
+			for _, m := range mtchs {
+				if ok, _ := filepath.Match(m, filepath.Base(dst)); ok {
+					return ``, ErrDispositionStillMatches
+				}
+			}
+		}
+		if dst, err = freeName(dst); err != nil {
+			return
+		}
+		err = os.Rename(dc.pth, dst)
+	default:
+		err = fmt.Errorf("Unknown disposition action %d", dc.cfg.Action)
+	}
+	return
+}
+
+// freeName finds a name that does not exist by appending a counter
+func freeName(pth string) (string, error) {
+	if _, err := os.Lstat(pth); os.IsNotExist(err) {
+		return pth, nil
+	}
+	for i := 1; i < RENAME_COUNT_MAX; i++ {
+		p := fmt.Sprintf("%s.%d", pth, i)
+		if _, err := os.Lstat(p); os.IsNotExist(err) {
+			return p, nil
+		}
+	}
+	return ``, fmt.Errorf("Failed to find a free name for %s", pth)
+}
+
+// moveFile renames a file, falling back to a copy when the destination is on another filesystem
+func moveFile(src, dst string) (err error) {
+	if err = os.Rename(src, dst); err == nil {
+		return
+	} else if _, ok := err.(*os.LinkError); !ok {
+		return
+	}
+	var fin, fout *os.File
+	if fin, err = os.Open(src); err != nil {
+		return
+	}
+	defer fin.Close()
+	if fout, err = os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640); err != nil {
+		return
+	}
+	if _, err = io.Copy(fout, fin); err == nil {
+		err = fout.Sync()
+	}
+	if lerr := fout.Close(); err == nil {
+		err = lerr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return
+	}
+	return os.Remove(src)
+}
+
+// SetSyncer sets the syncer used to make sure entries are durable before file dispositions run.
+// File dispositions never run without a syncer.
+func (wm *WatchManager) SetSyncer(s Syncer) {
+	wm.mtx.Lock()
+	defer wm.mtx.Unlock()
+	wm.syncer = s
+}
+
+// startDisposition runs a disposition pass in the background so that a slow pipeline sync does not
+// stall the watch routine, the pass is skipped if the previous one is still running.
+func (wm *WatchManager) startDisposition() {
+	if !atomic.CompareAndSwapInt32(&wm.disposing, 0, 1) {
+		wm.dispositionSkipped(errDispositionBusy)
+		return
+	}
+	wm.disposeWg.Add(1)
+	go func() {
+		defer wm.disposeWg.Done()
+		defer atomic.StoreInt32(&wm.disposing, 0)
+		wm.disposeFiles()
+	}()
+}
+
+// dispositionSkipped counts disposition passes that did not run, a pipeline that never manages
+// to sync would otherwise quietly leave every file in place.
+func (wm *WatchManager) dispositionSkipped(err error) {
+	if n := atomic.AddInt32(&wm.dispositionSkips, 1); n == 1 {
+		wm.logger.Info("delaying file dispositions", log.KVErr(err))
+	} else if n%dispositionSkipWarn == 0 {
+		wm.logger.Warn("file dispositions have been skipped repeatedly", log.KV("skipped", n), log.KVErr(err))
+	}
+}
+
+// disposeFiles syncs the ingest pipeline and then applies dispositions to completely ingested idle files.
+// Candidates are collected before the sync and checked again after so that we never act on a file
+// that has entries which were not part of the sync.
+func (wm *WatchManager) disposeFiles() {
+	wm.mtx.Lock()
+	fman, syncer := wm.fman, wm.syncer
+	wm.mtx.Unlock()
+	if fman == nil || syncer == nil {
+		return
+	}
+	cands := fman.dispositionCandidates()
+	if len(cands) == 0 {
+		return
+	}
+	if err := syncer.Sync(dispositionSyncTimeout); err != nil {
+		wm.dispositionSkipped(fmt.Errorf("failed to sync ingest pipeline with %d files pending: %w", len(cands), err))
+		return
+	}
+	atomic.StoreInt32(&wm.dispositionSkips, 0)
+	fman.dispose(cands)
+}
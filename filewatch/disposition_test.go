@@ -0,0 +1,206 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testDispositionIdle = 50 * time.Millisecond
+
+type testSyncer struct {
+	err   error
+	calls int
+}
+
+func (ts *testSyncer) Sync(time.Duration) error {
+	ts.calls++
+	return ts.err
+}
+
+// newDispositionManager fires up a filter manager with a single consumed file in it
+func newDispositionManager(t *testing.T, dc DispositionConfig, filter string) (fm *FilterManager, pth string) {
+	dir := t.TempDir()
+	pth = filepath.Join(dir, `test.log`)
+	_, raw := compressedLines(64)
+	if err := os.WriteFile(pth, raw, 0640); err != nil {
+		t.Fatal(err)
+	}
+	fm, err := NewFilterManager(filepath.Join(t.TempDir(), `state`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fm.Close() })
+	var tlh trackingLH
+	if err = fm.AddFilter(baseName, dir, []string{filter}, &tlh, FollowerEngineConfig{Disposition: dc}); err != nil {
+		t.Fatal(err)
+	} else if err = catchup(fm, pth); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestDispositionConfig(t *testing.T) {
+	dc := DispositionConfig{Action: DispositionRename}
+	if err := dc.Validate(); err != nil {
+		t.Fatal(err)
+	} else if dc.Target != DefaultDispositionSuffix || dc.Idle != DefaultDispositionIdle {
+		t.Fatalf("defaults not applied: %+v", dc)
+	}
+	dc = DispositionConfig{Action: DispositionMove}
+	if err := dc.Validate(); err != ErrNoDispositionTarget {
+		t.Fatalf("bad error on missing target: %v", err)
+	}
+	dc = DispositionConfig{Action: DispositionDelete, Idle: time.Second}
+	if err := dc.Validate(); err == nil {
+		t.Fatal("failed to catch short idle time")
+	}
+}
+
+func TestDispositionActions(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), `archive`)
+	tests := []struct {
+		name string
+		dc   DispositionConfig
+		dst  func(pth string) string
+	}{
+		{`delete`, DispositionConfig{Action: DispositionDelete}, nil},
+		{`move`, DispositionConfig{Action: DispositionMove, Target: archive}, func(pth string) string {
+			return filepath.Join(archive, filepath.Base(pth))
+		}},
+		{`rename`, DispositionConfig{Action: DispositionRename, Target: `.done`}, func(pth string) string {
+			return pth + `.done`
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.dc.Idle = testDispositionIdle
+			fm, pth := newDispositionManager(t, tt.dc, `*.log`)
+			if cands := fm.dispositionCandidates(); len(cands) != 0 {
+				t.Fatal("file is not idle yet")
+			}
+			time.Sleep(2 * testDispositionIdle)
+			cands := fm.dispositionCandidates()
+			if len(cands) != 1 || cands[0].pth != pth {
+				t.Fatalf("bad candidates: %+v", cands)
+			}
+			fm.dispose(cands)
+			if _, err := os.Stat(pth); !os.IsNotExist(err) {
+				t.Fatalf("file was not disposed of: %v", err)
+			} else if len(fm.states) != 0 {
+				t.Fatalf("state was not purged: %d", len(fm.states))
+			}
+			if tt.dst != nil {
+				if _, err := os.Stat(tt.dst(pth)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDispositionSafety(t *testing.T) {
+	dc := DispositionConfig{Action: DispositionDelete, Idle: testDispositionIdle}
+
+	//files with outstanding data are left alone
+	fm, pth := newDispositionManager(t, dc, `*.log`)
+	if err := appendFile(pth, []byte("more data\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * testDispositionIdle)
+	if cands := fm.dispositionCandidates(); len(cands) != 0 {
+		t.Fatalf("file with outstanding data was a candidate: %+v", cands)
+	}
+
+	//files that change after the candidates are collected are left alone
+	fm, pth = newDispositionManager(t, dc, `*.log`)
+	time.Sleep(2 * testDispositionIdle)
+	cands := fm.dispositionCandidates()
+	if len(cands) != 1 {
+		t.Fatalf("bad candidates: %+v", cands)
+	} else if err := appendFile(pth, []byte("more data\n")); err != nil {
+		t.Fatal(err)
+	}
+	fm.dispose(cands)
+	if _, err := os.Stat(pth); err != nil {
+		t.Fatal(err)
+	}
+
+	//renames that would be picked up again are refused and the state is kept
+	fm, pth = newDispositionManager(t, DispositionConfig{Action: DispositionRename, Target: `.done`, Idle: testDispositionIdle}, `test.log*`)
+	time.Sleep(2 * testDispositionIdle)
+	fm.dispose(fm.dispositionCandidates())
+	if _, err := os.Stat(pth); err != nil {
+		t.Fatal(err)
+	} else if len(fm.states) != 1 {
+		t.Fatal("state was purged on failed disposition")
+	}
+
+	//nothing happens if the ingest pipeline fails to sync
+	fm, pth = newDispositionManager(t, dc, `*.log`)
+	time.Sleep(2 * testDispositionIdle)
+	ts := &testSyncer{err: errors.New("all connections down")}
+	wm := &WatchManager{mtx: &sync.Mutex{}, fman: fm, syncer: ts, logger: fm.logger}
+	wm.disposeFiles()
+	if ts.calls != 1 {
+		t.Fatalf("bad sync calls %d", ts.calls)
+	} else if _, err := os.Stat(pth); err != nil {
+		t.Fatal(err)
+	}
+	ts.err = nil
+	wm.disposeFiles()
+	if _, err := os.Stat(pth); !os.IsNotExist(err) {
+		t.Fatalf("file was not disposed of after sync: %v", err)
+	}
+}
+
+type blockingSyncer struct {
+	release chan struct{}
+	calls   int32
+}
+
+func (bs *blockingSyncer) Sync(time.Duration) error {
+	atomic.AddInt32(&bs.calls, 1)
+	<-bs.release
+	return nil
+}
+
+func TestDispositionBackground(t *testing.T) {
+	fm, pth := newDispositionManager(t, DispositionConfig{Action: DispositionDelete, Idle: testDispositionIdle}, `*.log`)
+	time.Sleep(2 * testDispositionIdle)
+	bs := &blockingSyncer{release: make(chan struct{})}
+	wm := &WatchManager{mtx: &sync.Mutex{}, fman: fm, syncer: bs, logger: fm.logger}
+
+	//the pass blocks in the sync without holding up the caller, overlapping passes are skipped
+	wm.startDisposition()
+	for atomic.LoadInt32(&bs.calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	wm.startDisposition()
+	if n := atomic.LoadInt32(&wm.dispositionSkips); n != 1 {
+		t.Fatalf("bad skip count %d", n)
+	} else if _, err := os.Stat(pth); err != nil {
+		t.Fatal(err)
+	}
+	close(bs.release)
+	wm.disposeWg.Wait()
+	if n := atomic.LoadInt32(&bs.calls); n != 1 {
+		t.Fatalf("bad sync calls %d", n)
+	} else if n = atomic.LoadInt32(&wm.dispositionSkips); n != 0 {
+		t.Fatalf("skip count not reset %d", n)
+	} else if _, err := os.Stat(pth); !os.IsNotExist(err) {
+		t.Fatalf("file was not disposed of: %v", err)
+	}
+}
@@ -55,6 +55,14 @@ type WatchManager struct {
 	watched map[string][]WatchConfig
 	// This tracks directories which have been deleted and we hope will come back
 	removed map[string][]WatchConfig
+	// This tracks directories that are polled rather than watched with fsnotify
+	pollers map[string]*dirPoller
+	// syncer makes sure entries are durable before file dispositions run
+	syncer Syncer
+	// disposing is set while a disposition pass runs, dispositionSkips counts passes that did not run
+	disposing        int32
+	dispositionSkips int32
+	disposeWg        sync.WaitGroup
 
 	routineRet chan error
 	logger     ingest.IngestLogger
@@ -69,10 +77,17 @@ type WatchConfig struct {
 	FileFilter string
 	Hnd        handler
 	Recursive  bool
+	// PollScanLimit caps the number of directory entries examined per poll pass when polling
+	PollScanLimit int
 }
 
 func NewWatcher(stateFilePath string) (*WatchManager, error) {
-	fman, err := NewFilterManager(stateFilePath)
+	return NewWatcherWithIdentity(stateFilePath, IdentityConfig{})
+}
+
+// NewWatcherWithIdentity creates a watcher that identifies followed files using the given identity config
+func NewWatcherWithIdentity(stateFilePath string, ic IdentityConfig) (*WatchManager, error) {
+	fman, err := NewFilterManagerWithIdentity(stateFilePath, ic)
 	if err != nil {
 		return nil, err
 	}
@@ -88,6 +103,7 @@ func NewWatcher(stateFilePath string) (*WatchManager, error) {
 		watcher: w,
 		watched: map[string][]WatchConfig{},
 		removed: map[string][]WatchConfig{},
+		pollers: map[string]*dirPoller{},
 		logger:  ingest.NoLogger(),
 		ctx:     ctx,
 		cancel:  cancel,
@@ -151,6 +167,8 @@ func (wm *WatchManager) Close() error {
 		err = <-retCh
 		close(retCh)
 	}
+	//a disposition pass may still be waiting on the pipeline, it must finish before the filter manager goes away
+	wm.disposeWg.Wait()
 
 	//we can lock for the duration of this call
 	wm.mtx.Lock()
@@ -161,6 +179,9 @@ func (wm *WatchManager) Close() error {
 		}
 	}
 
+	for k := range wm.pollers {
+		wm.removePollerNoLock(k)
+	}
 	wm.watcher = nil
 	wm.fman = nil
 	return err
@@ -192,6 +213,10 @@ func (wm *WatchManager) addNoLock(c WatchConfig) error {
 		return ErrLocationNotDir
 	}
 
+	if err := c.Disposition.Validate(); err != nil {
+		return err
+	}
+
 	//extract all the filters from the match
 	fltrs, err := ExtractFilters(c.FileFilter)
 	if err != nil {
@@ -214,7 +239,9 @@ func (wm *WatchManager) addNoLock(c WatchConfig) error {
 	}
 
 	if doAdd {
-		if err := wm.watcher.Add(c.BaseDir); err != nil {
+		if c.PollInterval > 0 {
+			wm.addPollerNoLock(c, fltrs)
+		} else if err := wm.watcher.Add(c.BaseDir); err != nil {
 			return err
 		}
 		wm.watched[c.BaseDir] = append(wm.watched[c.BaseDir], c)
@@ -335,6 +362,7 @@ func (wm *WatchManager) removeNoLock(path string) error {
 
 	delete(wm.watched, path)
 	wm.fman.RemoveDirectory(path)
+	wm.removePollerNoLock(path)
 	wm.watcher.Remove(path)
 	return nil
 }
@@ -365,6 +393,10 @@ func (wm *WatchManager) Start() error {
 		return ErrAlreadyStarted
 	}
 
+	if wm.syncer == nil && wm.fman.hasDispositions() {
+		wm.logger.Warn("file dispositions are configured but there is no ingest syncer, files will not be touched")
+	}
+
 	//first scan all files, loading existing states as we go
 	if err := wm.initExisting(); err != nil {
 		return err
@@ -459,6 +491,10 @@ func (wm *WatchManager) routine(errch chan error) {
 	defer tckr.Stop()
 	mkdirTicker := time.NewTicker(newDirTickInterval)
 	defer mkdirTicker.Stop()
+	pollTicker := time.NewTicker(pollTickInterval)
+	defer pollTicker.Stop()
+	dispositionTicker := time.NewTicker(dispositionTickInterval)
+	defer dispositionTicker.Stop()
 
 watchRoutine:
 	for {
@@ -550,6 +586,12 @@ watchRoutine:
 			if err := wm.scanRemoved(); err != nil {
 				wm.logger.Error("failed to check removed directories", log.KVErr(err))
 			}
+		case _ = <-pollTicker.C:
+			// Scan any directories that are polled rather than watched
+			wm.poll()
+		case _ = <-dispositionTicker.C:
+			// Act on files that have been completely ingested
+			wm.startDisposition()
 		}
 	}
 	errch <- err
@@ -12,8 +12,10 @@ import (
 	"encoding/gob"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 
 	"github.com/gravwell/gravwell/v3/ingest"
@@ -69,17 +71,32 @@ type FilterManager struct {
 	stateFout       *os.File
 	maxFilesWatched int
 	logger          ingest.IngestLogger
+	rotations       *rotationTracker
+	identity        IdentityConfig
+	idents          map[*int64]*identity // fingerprints keyed by state, only used with fingerprint identity
+	orphans         map[FileName]*int64  // states whose file went missing or changed, candidates for renamed files
 }
 
 func NewFilterManager(stateFile string) (*FilterManager, error) {
-	fout, states, err := initStateFile(stateFile)
+	return NewFilterManagerWithIdentity(stateFile, IdentityConfig{})
+}
+
+// NewFilterManagerWithIdentity creates a filter manager that identifies files using the given identity config
+func NewFilterManagerWithIdentity(stateFile string, ic IdentityConfig) (*FilterManager, error) {
+	if err := ic.normalize(); err != nil {
+		return nil, err
+	}
+	fout, states, idents, err := initStateFile(stateFile, ic)
 	if err != nil {
 		return nil, err
 	}
-	if err := cleanStates(states); err != nil {
+	orphans, err := cleanStates(states, idents)
+	if err != nil {
 		fout.Close()
 		return nil, err
 	}
+	rotations := newRotationTracker()
+	rotations.restore(decodeRotations(fout))
 
 	return &FilterManager{
 		mtx:       &sync.Mutex{},
@@ -88,6 +105,10 @@ func NewFilterManager(stateFile string) (*FilterManager, error) {
 		states:    states,
 		followers: map[FileName]*follower{},
 		logger:    ingest.NoLogger(),
+		rotations: rotations,
+		identity:  ic,
+		idents:    idents,
+		orphans:   orphans,
 	}, nil
 }
 
@@ -223,10 +244,113 @@ func (fm *FilterManager) nolockDumpStates() error {
 	if err := fm.stateFout.Truncate(0); err != nil {
 		return err
 	}
-	if err := gob.NewEncoder(fm.stateFout).Encode(fm.states); err != nil {
+	enc := gob.NewEncoder(fm.stateFout)
+	if fm.identity.Fingerprint {
+		if err := enc.Encode(fm.nolockFingerprintStates()); err != nil {
+			return err
+		}
+	} else if err := enc.Encode(fm.states); err != nil {
 		return err
 	}
-	return nil
+	//rotation fingerprints trail the states, older state files simply don't have them
+	return enc.Encode(fm.rotations.export())
+}
+
+// nolockFingerprintStates builds the fingerprint state file and drops fingerprints for states that are gone
+// caller MUST HOLD THE LOCK
+func (fm *FilterManager) nolockFingerprintStates() (sf fingerprintStateFile) {
+	sf = fingerprintStateFile{
+		Version: stateFileVersion,
+		States:  make(map[FileName]fingerprintState, len(fm.states)),
+	}
+	live := make(map[*int64]bool, len(fm.states)+len(fm.orphans))
+	for k, v := range fm.states {
+		if v == nil {
+			continue
+		}
+		live[v] = true
+		sf.States[k] = fingerprintState{
+			Offset:      *v,
+			Fingerprint: fm.idents[v].get(),
+		}
+	}
+	for _, v := range fm.orphans {
+		live[v] = true
+	}
+	for k := range fm.idents {
+		if !live[k] {
+			delete(fm.idents, k)
+		}
+	}
+	return
+}
+
+// nolockIdentity returns the fingerprint tracker for a state, nil if fingerprinting is disabled
+// caller MUST HOLD THE LOCK
+func (fm *FilterManager) nolockIdentity(si *int64) *identity {
+	if !fm.identity.Fingerprint || si == nil {
+		return nil
+	}
+	id, ok := fm.idents[si]
+	if !ok {
+		id = newIdentity(Fingerprint{}, fm.identity.FingerprintSize)
+		fm.idents[si] = id
+	}
+	return id
+}
+
+// adoptState looks for the state of a file that was renamed or moved while we were not watching it by
+// matching the leading content of the file against states whose files went missing or were replaced.
+// caller MUST HOLD THE LOCK
+func (fm *FilterManager) adoptState(bname, fpath string) (si *int64) {
+	if len(fm.orphans) == 0 {
+		return
+	}
+	//orphans may have been fingerprinted with a different size, make sure we read enough to check all of them
+	sz := fm.identity.FingerprintSize
+	for _, v := range fm.orphans {
+		if fp := fm.idents[v].get(); fp.Size > sz {
+			sz = fp.Size
+		}
+	}
+	fin, err := os.Open(fpath)
+	if err != nil {
+		return
+	}
+	buff, err := readPrefix(fin, sz)
+	fin.Close()
+	if err != nil {
+		return
+	}
+	var match FileName
+	var best Fingerprint
+	var ambiguous bool
+	for k, v := range fm.orphans {
+		if k.BaseName != bname {
+			continue
+		}
+		fp := fm.idents[v].get()
+		if !fp.Usable() || !fp.matches(buff) {
+			continue
+		}
+		if fp.Size > best.Size {
+			match, best, ambiguous = k, fp, false
+		} else if fp.Size == best.Size {
+			ambiguous = true
+		}
+	}
+	if best.IsZero() || ambiguous {
+		return
+	}
+	si = fm.orphans[match]
+	delete(fm.orphans, match)
+	fm.states[FileName{BaseName: bname, FilePath: fpath}] = si
+	fm.logger.Info("file identified by content, resuming state",
+		log.KV("path", fpath),
+		log.KV("previous-path", match.FilePath),
+		log.KV("follower", bname),
+		log.KV("state", *si))
+	return
 }
 
 func (f *FilterManager) AddFilter(bname, loc string, mtchs []string, lh handler, ecfg FollowerEngineConfig) error {
@@ -463,6 +587,8 @@ func (f *FilterManager) addFollower(fcfg FollowerConfig) error {
 			return nil
 		}
 	}
+	fcfg.rotations = f.rotations
+	fcfg.ident = f.nolockIdentity(fcfg.State)
 	fl, err := NewFollower(fcfg)
 	if err != nil {
 		return err
@@ -553,7 +679,10 @@ FILTER_LOOP:
 			//see if we have state information for this file
 			si = f.seekInfo(v.bname, fpath)
 		}
-		//if not add it
+		//if not, check if it is a file that moved while we were down, then add it
+		if si == nil && !deleteState {
+			si = f.adoptState(v.bname, fpath)
+		}
 		if si == nil {
 			si = f.addSeekInfo(v.bname, fpath)
 		}
@@ -589,12 +718,15 @@ func (f *FilterManager) checkState(wf watchedFile) (si *int64, hasWork bool, err
 		}
 		//see if we have state information for this file
 		if si = f.seekInfo(v.bname, wf.pth); si == nil {
-			//no state information, if the size is > 0, go ahead and declare that there is work to do
-			if wf.size > 0 {
+			if si = f.adoptState(v.bname, wf.pth); si != nil {
+				//the file moved while we were down, pick up where we left off
+				hasWork = *si != stateCompleted && *si < wf.size
+			} else if wf.size > 0 {
+				//no state information, if the size is > 0, go ahead and declare that there is work to do
 				hasWork = true
 				si = f.addSeekInfo(v.bname, wf.pth)
 			}
-		} else if *si < wf.size {
+		} else if *si != stateCompleted && *si < wf.size {
 			//we have a state, check if there is new data
 			hasWork = true
 		}
@@ -614,7 +746,7 @@ func (f *FilterManager) checkRename(fpath string, id FileId) (isRename bool, err
 	var fdir string
 	for k, v := range f.followers {
 		var removeFollower bool
-		if v.FileId() == id {
+		if v.FileId() == id && f.sameContent(v, fpath) {
 			fname = filepath.Base(fpath)
 			fdir = filepath.Dir(fpath)
 			//check if the new name still matches the filter
@@ -653,6 +785,13 @@ func (f *FilterManager) checkRename(fpath string, id FileId) (isRename bool, err
 	return
 }
 
+// sameContent guards against inode reuse when using fingerprint identity, a follower only
+// matches a file with the same id if the leading content also matches.
+// Without fingerprint identity the follower has no fingerprint and the id alone decides.
+func (f *FilterManager) sameContent(flw *follower, fpath string) bool {
+	return contentMatches(fpath, flw.ident.get())
+}
+
 func (f *FilterManager) matchFile(mtchs []string, fname string) (matched bool) {
 	for _, m := range mtchs {
 		if ok, err := filepath.Match(m, fname); err == nil && ok {
@@ -731,6 +870,8 @@ func (f *FilterManager) CatchupFile(wf watchedFile, qc chan os.Signal) (bool, er
 // catchupFollower is a linear operation to get outstanding files up to date.
 func (f *FilterManager) catchupFollower(fcfg FollowerConfig, qc chan os.Signal) (bool, error) {
 	f.logger.Info("performing initial catch-up preprocessing for file", log.KV("file", fcfg.FilePath))
+	fcfg.rotations = f.rotations
+	fcfg.ident = f.nolockIdentity(fcfg.State)
 	if fl, err := NewFollower(fcfg); err != nil {
 		return false, err
 	} else if quit, err := fl.Sync(qc); err != nil || quit {
@@ -791,8 +932,8 @@ func ReadStateFile(p string) (states map[string]int64, err error) {
 		fin.Close()
 		return
 	} else if fi.Size() > 0 {
-		temp := map[FileName]*int64{}
-		if err = gob.NewDecoder(fin).Decode(&temp); err != nil {
+		var temp map[FileName]*int64
+		if temp, _, _, err = decodeStates(fin); err != nil {
 			err = fmt.Errorf("Failed to load existing states: %v", err)
 			fin.Close()
 			return
@@ -812,9 +953,10 @@ func ReadStateFile(p string) (states map[string]int64, err error) {
 	return
 }
 
-func initStateFile(p string) (fout *os.File, states map[FileName]*int64, err error) {
+func initStateFile(p string, ic IdentityConfig) (fout *os.File, states map[FileName]*int64, idents map[*int64]*identity, err error) {
 	var fi os.FileInfo
 	states = map[FileName]*int64{}
+	idents = map[*int64]*identity{}
 	//attempt to open state file
 	fi, err = os.Stat(p)
 	if err != nil {
@@ -848,7 +990,9 @@ func initStateFile(p string) (fout *os.File, states map[FileName]*int64, err err
 		return
 	}
 	if fi.Size() > 0 {
-		if err = gob.NewDecoder(fout).Decode(&states); err != nil {
+		var prints map[FileName]Fingerprint
+		var fingerprinted bool
+		if states, prints, fingerprinted, err = decodeStates(fout); err != nil {
 			// hold onto the decode error in case we can't get to a backup
 			serr := err
 			fout.Close()
@@ -868,7 +1012,7 @@ func initStateFile(p string) (fout *os.File, states map[FileName]*int64, err err
 
 				if count == RENAME_COUNT_MAX {
 					// if we got here then we ran out of attempts
-					return nil, nil, fmt.Errorf("Failed to rename old state file")
+					return nil, nil, nil, fmt.Errorf("Failed to rename old state file")
 				}
 			}
 
@@ -878,19 +1022,44 @@ func initStateFile(p string) (fout *os.File, states map[FileName]*int64, err err
 			}
 
 			// success!
-			return initStateFile(p)
+			return initStateFile(p, ic)
+		}
+		if len(states) > 0 && fingerprinted != ic.Fingerprint {
+			//switching identity modes requires an explicit migration
+			fout.Close()
+			if ic.Fingerprint {
+				err = ErrLegacyStateFile
+			} else {
+				err = ErrFingerprintStateFile
+			}
+			return nil, nil, nil, err
+		}
+		if states == nil {
+			states = map[FileName]*int64{}
+		}
+		if ic.Fingerprint {
+			for k, v := range states {
+				idents[v] = newIdentity(prints[k], ic.FingerprintSize)
+			}
 		}
 	}
 	return
 }
 
-func cleanStates(states map[FileName]*int64) error {
+// cleanStates removes states for files that are gone and resets states for files that were truncated.
+// When using fingerprint identity, states whose file is gone or whose content changed are handed back
+// as orphans so that they can be matched to files that were renamed or moved while we were down.
+func cleanStates(states map[FileName]*int64, idents map[*int64]*identity) (orphans map[FileName]*int64, err error) {
+	orphans = map[FileName]*int64{}
 	for k, v := range states {
 		fi, err := os.Stat(k.FilePath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				//file is gone, delete it
 				delete(states, k)
+				if v != nil && idents[v].get().Usable() {
+					orphans[k] = v
+				}
 			} else {
 				// TODO: decide if we need to specifically check for other errors here
 				//return err
@@ -899,12 +1068,75 @@ func cleanStates(states map[FileName]*int64) error {
 			if v == nil {
 				v = new(int64)
 			}
+			if id, ok := idents[v]; ok && !contentMatches(k.FilePath, id.get()) {
+				//the file at this path is not the file we were following, it was replaced or truncated
+				delete(states, k)
+				if id.get().Usable() {
+					orphans[k] = v
+				}
+				continue
+			}
 			//if file shrank, we have to assume this was a truncation, so remove the state
-			if fi.Size() < *v {
+			//compressed file states are offsets into the decompressed stream so they are always larger
+			if fi.Size() < *v && !isCompressedPath(k.FilePath) {
 				*v = 0 //reset the size
 			}
 		}
 		//all other cases are just fine, roll
 	}
-	return nil
+	return
+}
+
+// contentMatches checks if the file at pth starts with the fingerprinted content, errors are treated as a match
+// so that a transient failure does not cause re-ingestion
+func contentMatches(pth string, fp Fingerprint) bool {
+	fin, err := os.Open(pth)
+	if err != nil {
+		return true
+	}
+	defer fin.Close()
+	ok, err := fp.MatchesFile(fin)
+	return err != nil || ok
+}
+
+// decodeRotations reads the rotation fingerprints that trail the states in a state file.
+// Rotation fingerprints are best effort, a state file without them or a failure to read them yields nothing.
+func decodeRotations(rdr io.ReadSeeker) (rs []rotationState) {
+	if _, err := rdr.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	dec := gob.NewDecoder(rdr)
+	//skip over the states
+	if err := dec.DecodeValue(reflect.Value{}); err != nil {
+		return
+	} else if err = dec.Decode(&rs); err != nil {
+		rs = nil
+	}
+	return
+}
+
+// decodeStates reads a state file in either the path identity or fingerprint identity format
+func decodeStates(rdr io.ReadSeeker) (states map[FileName]*int64, prints map[FileName]Fingerprint, fingerprinted bool, err error) {
+	if err = gob.NewDecoder(rdr).Decode(&states); err == nil {
+		return
+	}
+	//not a path identity state file, see if it is a fingerprint state file
+	lerr := err
+	if _, err = rdr.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	var sf fingerprintStateFile
+	if err = gob.NewDecoder(rdr).Decode(&sf); err != nil || sf.Version != stateFileVersion {
+		err = lerr
+		return
+	}
+	fingerprinted = true
+	states = make(map[FileName]*int64, len(sf.States))
+	prints = make(map[FileName]Fingerprint, len(sf.States))
+	for k, v := range sf.States {
+		offset := v.Offset
+		states[k] = &offset
+		prints[k] = v.Fingerprint
+	}
+	return
 }
@@ -0,0 +1,191 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	// DefaultFingerprintSize is the number of leading bytes used to identify a file when fingerprinting,
+	// it is also used to match a compressed file to the uncompressed file it was rotated from
+	DefaultFingerprintSize int64 = 4096
+	// MinFingerprintSize is the smallest fingerprint that will be used to match a file that moved,
+	// anything shorter is likely to be a common header shared by many files
+	MinFingerprintSize int64 = 256
+
+	stateFileVersion = 2
+)
+
+var (
+	ErrLegacyStateFile      = errors.New("State file uses path and inode file identity and must be migrated to fingerprint identity with stateImportExport")
+	ErrFingerprintStateFile = errors.New("State file uses fingerprint file identity and must be reverted with stateImportExport")
+	ErrInvalidFingerprint   = errors.New("Invalid fingerprint size")
+)
+
+// IdentityConfig controls how followed files are identified in the state file.
+// By default files are identified by path plus inode and device, enabling fingerprinting
+// identifies files by the content of the first FingerprintSize bytes so that inode reuse,
+// copytruncate rotation, and moves between filesystems are detected.
+type IdentityConfig struct {
+	Fingerprint     bool
+	FingerprintSize int64
+}
+
+func (ic *IdentityConfig) normalize() error {
+	if !ic.Fingerprint {
+		return nil
+	}
+	if ic.FingerprintSize == 0 {
+		ic.FingerprintSize = DefaultFingerprintSize
+	} else if ic.FingerprintSize < MinFingerprintSize {
+		return ErrInvalidFingerprint
+	}
+	return nil
+}
+
+// Fingerprint is the hash of the first Size bytes of a file.
+// Files smaller than the configured fingerprint size have a partial fingerprint that grows with the file.
+type Fingerprint struct {
+	Size int64
+	Hash string
+}
+
+// FingerprintFile hashes up to max leading bytes of a file
+func FingerprintFile(f *os.File, max int64) (fp Fingerprint, err error) {
+	var buff []byte
+	if buff, err = readPrefix(f, max); err == nil {
+		fp = newFingerprint(buff)
+	}
+	return
+}
+
+// FingerprintPath hashes up to max leading bytes of the file at pth
+func FingerprintPath(pth string, max int64) (fp Fingerprint, err error) {
+	var f *os.File
+	if f, err = os.Open(pth); err != nil {
+		return
+	}
+	fp, err = FingerprintFile(f, max)
+	f.Close()
+	return
+}
+
+func newFingerprint(b []byte) Fingerprint {
+	sum := sha256.Sum256(b)
+	return Fingerprint{
+		Size: int64(len(b)),
+		Hash: hex.EncodeToString(sum[:]),
+	}
+}
+
+func readPrefix(f *os.File, max int64) (buff []byte, err error) {
+	buff = make([]byte, max)
+	var n int
+	if n, err = f.ReadAt(buff, 0); err == io.EOF {
+		err = nil
+	}
+	buff = buff[:n]
+	return
+}
+
+// IsZero returns true if the fingerprint has never been populated
+func (fp Fingerprint) IsZero() bool {
+	return fp.Size == 0
+}
+
+// Usable returns true if the fingerprint covers enough data to identify a file that moved
+func (fp Fingerprint) Usable() bool {
+	return fp.Size >= MinFingerprintSize
+}
+
+// matches checks if the leading bytes in buff are the same content the fingerprint was built from.
+// A zero fingerprint covers no content, either fingerprinting is disabled or the file was empty, so it matches anything.
+func (fp Fingerprint) matches(buff []byte) bool {
+	if fp.IsZero() {
+		return true
+	} else if int64(len(buff)) < fp.Size {
+		return false
+	}
+	return newFingerprint(buff[:fp.Size]).Hash == fp.Hash
+}
+
+// MatchesFile returns true if the file starts with the content the fingerprint was built from
+func (fp Fingerprint) MatchesFile(f *os.File) (bool, error) {
+	buff, err := readPrefix(f, fp.Size)
+	if err != nil {
+		return false, err
+	}
+	return fp.matches(buff), nil
+}
+
+// identity is the live fingerprint of a followed file, it is shared between the filter manager
+// which persists it and the follower which keeps it current as the file grows.
+type identity struct {
+	mtx sync.Mutex
+	fp  Fingerprint
+	max int64
+}
+
+func newIdentity(fp Fingerprint, max int64) *identity {
+	return &identity{fp: fp, max: max}
+}
+
+func (id *identity) get() (fp Fingerprint) {
+	if id != nil {
+		id.mtx.Lock()
+		fp = id.fp
+		id.mtx.Unlock()
+	}
+	return
+}
+
+// check verifies that the file still starts with the fingerprinted content, if the file grew the
+// fingerprint is extended.  If the content changed the fingerprint is replaced and false is returned,
+// the caller should treat the file as truncated or replaced and start over.
+func (id *identity) check(f *os.File) (ok bool, err error) {
+	if id == nil || f == nil {
+		return true, nil
+	}
+	id.mtx.Lock()
+	defer id.mtx.Unlock()
+	if id.fp.Size >= id.max {
+		//the fingerprint is complete, only read what we need
+		if ok, err = id.fp.MatchesFile(f); err != nil || ok {
+			return
+		}
+		//content changed, rebuild the fingerprint
+		id.fp, err = FingerprintFile(f, id.max)
+		return
+	}
+	var buff []byte
+	if buff, err = readPrefix(f, id.max); err != nil {
+		return
+	}
+	ok = id.fp.matches(buff)
+	id.fp = newFingerprint(buff)
+	return
+}
+
+// fingerprintState is the persisted state of a single followed file when using fingerprint identity
+type fingerprintState struct {
+	Offset      int64
+	Fingerprint Fingerprint
+}
+
+// fingerprintStateFile is the fingerprint identity state file format
+type fingerprintStateFile struct {
+	Version int
+	States  map[FileName]fingerprintState
+}
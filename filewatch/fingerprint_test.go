@@ -0,0 +1,291 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func appendFile(pth string, b []byte) error {
+	fout, err := os.OpenFile(pth, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err = fout.Write(b); err != nil {
+		fout.Close()
+		return err
+	}
+	return fout.Close()
+}
+
+func catchup(fm *FilterManager, pth string) error {
+	fi, err := os.Stat(pth)
+	if err != nil {
+		return err
+	}
+	_, err = fm.CatchupFile(watchedFile{pth: pth, size: fi.Size(), modTime: fi.ModTime()}, make(chan os.Signal))
+	return err
+}
+
+func TestIdentityCheck(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `test.log`)
+	_, raw := compressedLines(64)
+	if err := os.WriteFile(pth, raw[:100], 0640); err != nil {
+		t.Fatal(err)
+	}
+	fin, err := os.Open(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fin.Close()
+	id := newIdentity(Fingerprint{}, 512)
+	if ok, err := id.check(fin); err != nil || !ok {
+		t.Fatalf("bad initial check: %v %v", ok, err)
+	} else if fp := id.get(); fp.Size != 100 {
+		t.Fatalf("bad fingerprint size %d", fp.Size)
+	}
+
+	//growing the file extends the fingerprint up to the max
+	if err = appendFile(pth, raw[100:]); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := id.check(fin); err != nil || !ok {
+		t.Fatalf("bad check after growth: %v %v", ok, err)
+	} else if fp := id.get(); fp.Size != 512 || !fp.Usable() {
+		t.Fatalf("bad fingerprint size %d", fp.Size)
+	}
+
+	//truncate and rewrite past the original size, as if copytruncate rotated it
+	if err = os.Truncate(pth, 0); err != nil {
+		t.Fatal(err)
+	}
+	_, other := compressedLines(64)
+	if err = appendFile(pth, other); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := id.check(fin); err != nil {
+		t.Fatal(err)
+	} else if ok {
+		t.Fatal("failed to detect replaced content")
+	}
+	//the fingerprint is rebuilt for the new content
+	if ok, err := id.check(fin); err != nil || !ok {
+		t.Fatalf("bad check after rebuild: %v %v", ok, err)
+	}
+}
+
+func TestFingerprintStateFileMigration(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, `state`)
+	pth := filepath.Join(dir, `test.log`)
+	_, raw := compressedLines(64)
+	if err := os.WriteFile(pth, raw, 0640); err != nil {
+		t.Fatal(err)
+	}
+	legacy := []FileState{{BaseName: baseName, FilePath: pth, State: 1234}}
+	if err := EncodeStateFile(statePath, legacy); err != nil {
+		t.Fatal(err)
+	}
+	//fingerprint identity refuses to silently adopt a path identity state file
+	if _, err := NewFilterManagerWithIdentity(statePath, IdentityConfig{Fingerprint: true}); !errors.Is(err, ErrLegacyStateFile) {
+		t.Fatalf("bad error on legacy state file: %v", err)
+	}
+
+	//migrate the same way stateImportExport does
+	states, err := DecodeStateFile(statePath)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(states) != 1 || states[0].Fingerprint != nil {
+		t.Fatalf("bad legacy states: %+v", states)
+	}
+	fp, err := FingerprintPath(pth, DefaultFingerprintSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states[0].Fingerprint = &fp
+	if err = EncodeStateFile(statePath, states); err != nil {
+		t.Fatal(err)
+	}
+	if states, err = DecodeStateFile(statePath); err != nil {
+		t.Fatal(err)
+	} else if len(states) != 1 || states[0].Fingerprint == nil || *states[0].Fingerprint != fp || states[0].State != 1234 {
+		t.Fatalf("bad migrated states: %+v", states)
+	}
+	if _, err = NewFilterManager(statePath); !errors.Is(err, ErrFingerprintStateFile) {
+		t.Fatalf("bad error on fingerprint state file: %v", err)
+	}
+	fm, err := NewFilterManagerWithIdentity(statePath, IdentityConfig{Fingerprint: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if si := fm.seekInfo(baseName, pth); si == nil || *si != 1234 {
+		t.Fatal("migrated state was not loaded")
+	}
+	if err = fm.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewFilterManager(statePath); !errors.Is(err, ErrFingerprintStateFile) {
+		t.Fatalf("bad error on fingerprint state file: %v", err)
+	}
+}
+
+func TestFingerprintRename(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), `state`)
+	pth := filepath.Join(dir, `test.log`)
+	rotated := filepath.Join(dir, `test.log.1`)
+	ic := IdentityConfig{Fingerprint: true}
+	lines, raw := compressedLines(64)
+	if err := os.WriteFile(pth, raw, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	//consume the file, then shut down
+	var tlh trackingLH
+	fm, err := NewFilterManagerWithIdentity(statePath, ic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = fm.AddFilter(baseName, dir, []string{`test.log*`}, &tlh, FollowerEngineConfig{}); err != nil {
+		t.Fatal(err)
+	} else if err = catchup(fm, pth); err != nil {
+		t.Fatal(err)
+	} else if err = fm.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(tlh.mp) != len(lines) {
+		t.Fatalf("got %d lines, expected %d", len(tlh.mp), len(lines))
+	}
+
+	//while we are down the file is rotated, the old file picks up a few more lines and a new file shows up
+	if err = os.Rename(pth, rotated); err != nil {
+		t.Fatal(err)
+	}
+	extra, extraRaw := compressedLines(8)
+	if err = appendFile(rotated, extraRaw); err != nil {
+		t.Fatal(err)
+	}
+	newLines, newRaw := compressedLines(16)
+	if err = os.WriteFile(pth, newRaw, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	tlh = trackingLH{}
+	if fm, err = NewFilterManagerWithIdentity(statePath, ic); err != nil {
+		t.Fatal(err)
+	}
+	if len(fm.orphans) != 1 {
+		t.Fatalf("replaced file was not orphaned: %d", len(fm.orphans))
+	}
+	if err = fm.AddFilter(baseName, dir, []string{`test.log*`}, &tlh, FollowerEngineConfig{}); err != nil {
+		t.Fatal(err)
+	} else if err = catchup(fm, rotated); err != nil {
+		t.Fatal(err)
+	} else if err = catchup(fm, pth); err != nil {
+		t.Fatal(err)
+	} else if err = fm.Close(); err != nil {
+		t.Fatal(err)
+	}
+	//we should only see the lines appended to the rotated file and the new file
+	if len(tlh.mp) != len(extra)+len(newLines) {
+		t.Fatalf("got %d lines, expected %d", len(tlh.mp), len(extra)+len(newLines))
+	}
+	for _, ln := range append(extra, newLines...) {
+		if _, ok := tlh.mp[ln]; !ok {
+			t.Fatalf("missing line %q", ln)
+		}
+	}
+}
+
+func TestDefaultIdentityRename(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), `state`)
+	pth := filepath.Join(dir, `test.log`)
+	rotated := filepath.Join(dir, `test.log.1`)
+	lines, raw := compressedLines(64)
+	if err := os.WriteFile(pth, raw, 0640); err != nil {
+		t.Fatal(err)
+	}
+	lh := newSafeTrackingLH()
+	fm, err := NewFilterManager(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fm.Close()
+	if err = fm.AddFilter(baseName, dir, []string{`test.log*`}, lh, FollowerEngineConfig{}); err != nil {
+		t.Fatal(err)
+	} else if ok, err := fm.LoadFile(pth); err != nil || !ok {
+		t.Fatalf("failed to load file %v %v", ok, err)
+	}
+	waitLines(t, lh, len(lines))
+
+	//without fingerprints the file id alone identifies a renamed file
+	if err = os.Rename(pth, rotated); err != nil {
+		t.Fatal(err)
+	} else if ok, err := fm.LoadFile(rotated); err != nil || !ok {
+		t.Fatalf("failed to load renamed file %v %v", ok, err)
+	}
+	if fm.Followed() != 1 {
+		t.Fatalf("rename was not detected, %d followers", fm.Followed())
+	} else if fm.seekInfo(baseName, rotated) == nil || fm.seekInfo(baseName, pth) != nil {
+		t.Fatal("state was not moved to the renamed file")
+	}
+	extra, extraRaw := compressedLines(8)
+	if err = appendFile(rotated, extraRaw); err != nil {
+		t.Fatal(err)
+	}
+	waitLines(t, lh, len(lines)+len(extra))
+	time.Sleep(100 * time.Millisecond)
+	lh.Lock()
+	cnt := lh.cnt
+	lh.Unlock()
+	if cnt != len(lines)+len(extra) {
+		t.Fatalf("renamed file was re-ingested, got %d lines, expected %d", cnt, len(lines)+len(extra))
+	}
+}
+
+func TestFingerprintEmptyState(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, `state`)
+	pth := filepath.Join(dir, `test.log`)
+	if err := os.WriteFile(pth, nil, 0640); err != nil {
+		t.Fatal(err)
+	}
+	//a file that was empty when we last saw it has an empty fingerprint and must keep its state
+	if err := EncodeStateFile(statePath, []FileState{{BaseName: baseName, FilePath: pth, Fingerprint: &Fingerprint{}}}); err != nil {
+		t.Fatal(err)
+	}
+	_, raw := compressedLines(8)
+	if err := os.WriteFile(pth, raw, 0640); err != nil {
+		t.Fatal(err)
+	}
+	fm, err := NewFilterManagerWithIdentity(statePath, IdentityConfig{Fingerprint: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fm.Close()
+	if fm.seekInfo(baseName, pth) == nil {
+		t.Fatal("state with an empty fingerprint was dropped")
+	}
+}
+
+func waitLines(t *testing.T, lh *safeTrackingLH, cnt int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for lh.Len() < cnt {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for lines, got %d, expected %d", lh.Len(), cnt)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -51,6 +51,11 @@ type FileId struct {
 type FollowerEngineConfig struct {
 	Engine     int
 	EngineArgs string
+	// PollInterval enables polling, the follower checks the file on this interval rather than
+	// waiting for filesystem notifications which network filesystems do not deliver
+	PollInterval time.Duration
+	// Disposition controls what happens to files once they are completely ingested
+	Disposition DispositionConfig
 }
 
 type FollowerConfig struct {
@@ -60,12 +65,16 @@ type FollowerConfig struct {
 	State    *int64
 	FilterID int
 	Handler  handler
+
+	rotations *rotationTracker
+	ident     *identity
 }
 
 type follower struct {
 	FileName
 	filterId int
 	id       FileId
+	fin      *os.File
 	lnr      Reader
 	state    *int64
 	mtx      *sync.Mutex
@@ -76,6 +85,9 @@ type follower struct {
 	wg       *sync.WaitGroup
 	lh       handler
 	lastAct  time.Time
+	rots     *rotationTracker
+	ident    *identity
+	poll     time.Duration
 }
 
 func NewFollower(cfg FollowerConfig) (*follower, error) {
@@ -92,9 +104,13 @@ func NewFollower(cfg FollowerConfig) (*follower, error) {
 		return nil, err
 	}
 
-	if _, err := fin.Seek(*cfg.State, 0); err != nil {
-		fin.Close()
-		return nil, err
+	if *cfg.State < 0 && !isCompressedFile(fin) {
+		//only compressed files can be marked as completed, this file was replaced so start over
+		*cfg.State = 0
+	}
+	if ok, err := cfg.ident.check(fin); err == nil && !ok {
+		//the content changed since we last saw the file, it was replaced or truncated
+		*cfg.State = 0
 	}
 	rdrCfg := ReaderConfig{
 		Fin:        fin,
@@ -103,6 +119,9 @@ func NewFollower(cfg FollowerConfig) (*follower, error) {
 		Engine:     cfg.Engine,
 		EngineArgs: cfg.EngineArgs,
 	}
+	if cfg.rotations != nil {
+		rdrCfg.resolve = cfg.rotations.resolve
+	}
 	lnr, err := NewReader(rdrCfg)
 	if err != nil {
 		fin.Close()
@@ -119,6 +138,7 @@ func NewFollower(cfg FollowerConfig) (*follower, error) {
 	return &follower{
 		filterId: cfg.FilterID,
 		id:       id,
+		fin:      fin,
 		lnr:      lnr,
 		mtx:      &sync.Mutex{},
 		wg:       &sync.WaitGroup{},
@@ -130,6 +150,9 @@ func NewFollower(cfg FollowerConfig) (*follower, error) {
 			BaseName: cfg.BaseName,
 		},
 		lastAct: time.Now(),
+		rots:    cfg.rotations,
+		ident:   cfg.ident,
+		poll:    cfg.PollInterval,
 	}, nil
 }
 
@@ -168,6 +191,36 @@ func (f *follower) fileSize() (sz int64) {
 	return
 }
 
+// streaming returns true if the reader index is not an offset into the file, e.g. a compressed file
+func (f *follower) streaming() (ok bool) {
+	_, ok = f.lnr.(streamReader)
+	return
+}
+
+// finished returns true if the reader has consumed a stream that can never grow
+func (f *follower) finished() bool {
+	if sr, ok := f.lnr.(streamReader); ok {
+		return sr.Finished()
+	}
+	return false
+}
+
+// replaced returns true if the leading content of the file no longer matches its fingerprint,
+// this catches truncations where the file grew past our offset before we noticed
+func (f *follower) replaced() bool {
+	ok, err := f.ident.check(f.fin)
+	return err == nil && !ok
+}
+
+// updateState sets the state to the current reader index, or marks it completed if the stream is exhausted
+func (f *follower) updateState() {
+	if f.finished() {
+		*f.state = stateCompleted
+	} else {
+		*f.state = f.lnr.Index()
+	}
+}
+
 // Sync is a linear operation where we consume all the data out of a file
 // it is typically used during the initialization and Catchup phase of a restart.
 // When existing we will check if there is floating data, if so, then we check the
@@ -188,15 +241,18 @@ func (f *follower) Sync(qc chan os.Signal) (bool, error) {
 		if err != nil {
 			return false, err
 		} else if !ok {
-			if sawEOF && time.Since(f.lastFileModTime()) > maxIdleCloseTime {
+			if sawEOF && (f.finished() || time.Since(f.lastFileModTime()) > maxIdleCloseTime) {
 				//partial write and file hasn't been written to in a while
 				//go ahead and force a write update
 				if ln, err = f.lnr.ReadRemaining(); err != nil {
 					return false, err
 				} else if len(ln) > 0 {
 					if err = f.lh.HandleLog(ln, time.Now(), f.FilePath); err == nil {
-						*f.state = f.lnr.Index()
+						f.updateState()
 					}
+				} else {
+					//the reader may have consumed trailing data that does not produce an entry, e.g. a closing JSON array
+					f.updateState()
 				}
 			}
 			return false, err
@@ -210,7 +266,8 @@ func (f *follower) Sync(qc chan os.Signal) (bool, error) {
 		f.lastAct = now
 		// This makes sure we don't read forever, in case the writer is really fast
 		// and the connection to the indexer isn't.
-		if f.lnr.Index() >= size {
+		// Streaming readers are not indexed by file offset, compressed files don't grow so they can be read to the end
+		if !f.streaming() && f.lnr.Index() >= size {
 			break // this essentially returns false, nil
 		}
 		select {
@@ -232,8 +289,10 @@ func (f *follower) Start() error {
 	if f.abortCh != nil || f.running != 0 {
 		return ErrAlreadyStarted
 	}
-	if err := f.fsn.Add(f.FilePath); err != nil {
-		return err
+	if f.poll <= 0 {
+		if err := f.fsn.Add(f.FilePath); err != nil {
+			return err
+		}
 	}
 	f.abortCh = make(chan bool, 1)
 	f.running = 1
@@ -272,6 +331,10 @@ func (f *follower) Close() error {
 	if err := f.fsn.Close(); err != nil {
 		f.err = err
 	}
+	if !f.streaming() {
+		//remember what we consumed so that a compressed copy of this file doesn't get ingested again
+		f.rots.record(f.fin, *f.state)
+	}
 	if err := f.lnr.Close(); err != nil {
 		f.err = err
 	}
@@ -302,10 +365,10 @@ func (f *follower) processLines(writeEvent, removing, allowPartial bool) error {
 		if err != nil {
 			return err
 		}
-		if sawEOF && writeEvent {
+		if sawEOF && writeEvent && !f.streaming() {
 			// We got an EOF on the file after a write
 			sz, err := f.lnr.FileSize()
-			if sz < *f.state {
+			if sz < *f.state || f.replaced() {
 				// the file must have been truncated
 				*f.state = 0
 				if err = f.lnr.SeekFile(0); err != nil {
@@ -317,7 +380,7 @@ func (f *follower) processLines(writeEvent, removing, allowPartial bool) error {
 			// e.g. no trailing newline or delimiter, but what IS there has been sitting for XYZ seconds
 			// go ahead and consume it
 			var force bool
-			if idleTime := time.Since(f.lastAct); (idleTime > maxIdleDataTime && allowPartial) || removing {
+			if idleTime := time.Since(f.lastAct); (idleTime > maxIdleDataTime && allowPartial) || removing || f.finished() {
 				force = true
 			}
 			if force {
@@ -326,8 +389,14 @@ func (f *follower) processLines(writeEvent, removing, allowPartial bool) error {
 				} else if len(ln) > 0 {
 					if err = f.lh.HandleLog(ln, time.Now(), f.FilePath); err == nil {
 						hit = true
-						*f.state = f.lnr.Index()
+						f.updateState()
 					}
+				} else {
+					//the reader may have consumed trailing data that does not produce an entry, e.g. a closing JSON array
+					f.updateState()
+				}
+				if hit {
+					f.lastAct = time.Now()
 				}
 				return err
 			}
@@ -351,7 +420,11 @@ func (f *follower) routine() {
 	defer func(r *int32) {
 		atomic.CompareAndSwapInt32(r, 1, 0)
 	}(&f.running)
-	tckr := time.NewTicker(tickInterval)
+	interval := tickInterval
+	if f.poll > 0 {
+		interval = f.poll
+	}
+	tckr := time.NewTicker(interval)
 	defer tckr.Stop()
 
 	if err := f.processLines(false, false, false); err != nil {
@@ -392,14 +465,15 @@ routineLoop:
 					}
 					return
 				}
-				tckr.Reset(tickInterval)
+				tckr.Reset(interval)
 			}
 		case _ = <-tckr.C:
 			//just loop and attempt to get some lines
 			//this is purely to deal with race conditions where lines come in when we are starting up
 			//causing us to miss the event
 			//this whole process is kind of racy, so every iteration we attempt to process lines
-			if err := f.processLines(false, false, true); err != nil {
+			//when polling there are no write events, so every tick is treated as a potential write
+			if err := f.processLines(f.poll > 0, false, true); err != nil {
 				f.lnr.Close()
 				if !os.IsNotExist(err) {
 					f.err = err
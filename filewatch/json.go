@@ -0,0 +1,200 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// JSONReader emits each top-level JSON object in a file as an entry.  It handles newline delimited JSON,
+// concatenated and pretty printed documents, and arrays of objects; commas, whitespace, and array brackets
+// between objects are skipped.  Anything else outside of an object is discarded.
+// Objects are compacted onto a single line, objects that are not valid JSON are emitted as is.
+// The index always lands just after the last object emitted so that resuming in the middle of an array works.
+type JSONReader struct {
+	baseReader
+	brdr  *bufio.Reader
+	buff  []byte // unconsumed data, buff[0] is at idx
+	pos   int    // scan position in buff
+	begin int    // start of the current object in buff
+	depth int
+	inStr bool
+	esc   bool
+	big   []byte // head of an object that exceeded the maximum entry size
+	bigAt int64  // index of the start of the oversized object
+}
+
+func NewJSONReader(cfg ReaderConfig) (*JSONReader, error) {
+	br, err := newBaseReader(cfg.Fin, cfg.MaxLineLen, cfg.StartIndex)
+	if err != nil {
+		return nil, err
+	}
+	return newJSONReader(br, cfg.Fin), nil
+}
+
+// newJSONReader builds a JSON reader that pulls from an arbitrary stream rather than the base file
+func newJSONReader(br baseReader, rdr io.Reader) *JSONReader {
+	return &JSONReader{
+		baseReader: br,
+		brdr:       bufio.NewReader(rdr),
+	}
+}
+
+func (jr *JSONReader) SeekFile(offset int64) error {
+	if err := jr.baseReader.SeekFile(offset); err != nil {
+		return err
+	}
+	jr.brdr.Reset(jr.f)
+	jr.buff, jr.big = nil, nil
+	jr.pos, jr.begin, jr.depth = 0, 0, 0
+	jr.inStr, jr.esc = false, false
+	return nil
+}
+
+func (jr *JSONReader) ReadEntry() (ln []byte, ok bool, wasEOF bool, err error) {
+	if ln, ok = jr.scan(); ok {
+		return
+	}
+	b := make([]byte, 8*1024)
+	n, lerr := jr.brdr.Read(b)
+	if lerr != nil && lerr != io.EOF {
+		err = lerr
+		return
+	} else if lerr == io.EOF {
+		wasEOF = true
+	}
+	if n == 0 {
+		return
+	}
+	jr.buff = append(jr.buff, b[:n]...)
+	ln, ok = jr.scan()
+	return
+}
+
+// ReadRemaining hands back a trailing partial object, trailing separators are consumed without an entry
+func (jr *JSONReader) ReadRemaining() (ln []byte, err error) {
+	var ok bool
+	if ln, ok, _, err = jr.ReadEntry(); err != nil || ok {
+		return
+	}
+	if jr.depth > 0 {
+		if jr.big != nil {
+			ln = jr.big
+		} else {
+			ln = append([]byte(nil), jr.buff[jr.begin:]...)
+		}
+	}
+	jr.idx += int64(len(jr.buff))
+	jr.buff, jr.big = nil, nil
+	jr.pos, jr.begin, jr.depth = 0, 0, 0
+	jr.inStr, jr.esc = false, false
+	return
+}
+
+// scan walks the buffer looking for the end of the current object
+func (jr *JSONReader) scan() (ln []byte, ok bool) {
+	for ; jr.pos < len(jr.buff); jr.pos++ {
+		c := jr.buff[jr.pos]
+		if jr.inStr {
+			if jr.esc {
+				jr.esc = false
+			} else if c == '\\' {
+				jr.esc = true
+			} else if c == '"' {
+				jr.inStr = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			jr.inStr = true
+		case '{':
+			if jr.depth == 0 {
+				jr.begin = jr.pos
+			}
+			jr.depth++
+		case '[':
+			if jr.depth > 0 {
+				jr.depth++
+			}
+		case '}', ']':
+			if jr.depth == 0 {
+				continue //closing a top level array, or garbage
+			} else if jr.depth--; jr.depth > 0 {
+				continue
+			}
+			ln, ok = jr.emit(jr.buff[jr.begin:jr.pos+1]), true
+			jr.consume(jr.pos + 1)
+			return
+		}
+	}
+	if jr.depth == 0 {
+		//a string between objects is held so that the index stays ahead of it, unless it grows past
+		//the maximum entry size, then it is garbage (likely a stray quote) and is thrown away as we go
+		if !jr.inStr || len(jr.buff) > jr.strLimit() {
+			jr.consume(len(jr.buff))
+		}
+	} else if sz := jr.pos - jr.begin; jr.big == nil && jr.maxLine > 0 && sz > jr.maxLine {
+		//the object is too large, hang onto the head of it and throw the rest away as we go
+		jr.big = append([]byte(nil), jr.buff[jr.begin:jr.begin+jr.maxLine]...)
+		jr.bigAt = jr.idx + int64(jr.begin)
+		jr.consume(jr.pos)
+	} else if jr.big != nil {
+		jr.consume(jr.pos)
+	}
+	return
+}
+
+// strLimit is the longest string we will hold between objects, the maximum entry size if there is one
+func (jr *JSONReader) strLimit() int {
+	if jr.maxLine > 0 {
+		return jr.maxLine
+	}
+	return defaultMaxLine
+}
+
+// Index never points into the middle of an object, even while an oversized object is being thrown away
+func (jr *JSONReader) Index() int64 {
+	if jr.big != nil {
+		return jr.bigAt
+	}
+	return jr.idx
+}
+
+// consume drops n bytes off the front of the buffer
+func (jr *JSONReader) consume(n int) {
+	jr.idx += int64(n)
+	jr.buff = jr.buff[n:]
+	if jr.pos -= n; jr.pos < 0 {
+		jr.pos = 0
+	}
+	if jr.begin -= n; jr.begin < 0 {
+		jr.begin = 0
+	}
+	if len(jr.buff) == 0 {
+		jr.buff = nil
+	}
+}
+
+func (jr *JSONReader) emit(obj []byte) (ln []byte) {
+	if jr.big != nil {
+		ln, jr.big = jr.big, nil
+		return
+	} else if jr.maxLine > 0 && len(obj) > jr.maxLine {
+		return append([]byte(nil), obj[:jr.maxLine]...)
+	}
+	bb := bytes.NewBuffer(make([]byte, 0, len(obj)))
+	if err := json.Compact(bb, obj); err != nil {
+		return append([]byte(nil), obj...)
+	}
+	return bb.Bytes()
+}
@@ -0,0 +1,192 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testJSONArray = `[
+  {
+    "id": 1,
+    "msg": "braces } and ] in a string",
+    "nested": {"a": [1, 2, {"b": "c"}]}
+  },
+  {
+    "id": 2,
+    "msg": "escaped \" quote and \\"
+  },
+  {"id": 3, "msg": "last"}
+]
+`
+
+var testJSONRecords = []string{
+	`{"id":1,"msg":"braces } and ] in a string","nested":{"a":[1,2,{"b":"c"}]}}`,
+	`{"id":2,"msg":"escaped \" quote and \\"}`,
+	`{"id":3,"msg":"last"}`,
+}
+
+func openEngine(pth string, engine int, args string, idx int64) (Reader, error) {
+	fin, err := os.Open(pth)
+	if err != nil {
+		return nil, err
+	}
+	rdr, err := NewReader(ReaderConfig{
+		Fin:        fin,
+		MaxLineLen: defaultMaxLine,
+		StartIndex: idx,
+		Engine:     engine,
+		EngineArgs: args,
+	})
+	if err != nil {
+		fin.Close()
+		return nil, err
+	}
+	return rdr, nil
+}
+
+func TestJSONReaderArray(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `test.json`)
+	if err := os.WriteFile(pth, []byte(testJSONArray), 0640); err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := openEngine(pth, JSONEngine, ``, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, ok, _, err := rdr.ReadEntry()
+	if err != nil || !ok {
+		t.Fatal("failed to read first record", ok, err)
+	} else if string(ln) != testJSONRecords[0] {
+		t.Fatalf("bad record %q", ln)
+	}
+	idx := rdr.Index()
+	lines, err := readAll(rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, lines, testJSONRecords[1:])
+	//the closing bracket is consumed without an entry so the whole file is accounted for
+	if ln, err = rdr.ReadRemaining(); err != nil || len(ln) != 0 {
+		t.Fatalf("bad remaining %q %v", ln, err)
+	} else if rdr.Index() != int64(len(testJSONArray)) {
+		t.Fatalf("index %d did not reach the end of the file %d", rdr.Index(), len(testJSONArray))
+	}
+	rdr.Close()
+
+	//resume in the middle of the array
+	if rdr, err = openEngine(pth, JSONEngine, ``, idx); err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+	if lines, err = readAll(rdr); err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, lines, testJSONRecords[1:])
+}
+
+func TestJSONReaderStream(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `test.ndjson`)
+	fout, err := os.Create(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fout.Close()
+	rdr, err := openEngine(pth, JSONEngine, ``, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+
+	//partial writes do not produce an entry until the object is closed
+	if _, err = fout.WriteString("{\"id\":1}\n{\"id\":2,\"msg\":\"a {"); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := readAll(rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, lines, []string{`{"id":1}`})
+	if _, err = fout.WriteString("partial\"}{\"id\": 3}\n"); err != nil {
+		t.Fatal(err)
+	}
+	if lines, err = readAll(rdr); err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, lines, []string{`{"id":2,"msg":"a {partial"}`, `{"id":3}`})
+
+	//a truncated object is handed back as is
+	if _, err = fout.WriteString(`{"id": 4, "msg": "never fin`); err != nil {
+		t.Fatal(err)
+	} else if _, err = readAll(rdr); err != nil {
+		t.Fatal(err)
+	}
+	if ln, err := rdr.ReadRemaining(); err != nil {
+		t.Fatal(err)
+	} else if string(ln) != `{"id": 4, "msg": "never fin` {
+		t.Fatalf("bad remaining %q", ln)
+	}
+}
+
+func TestJSONReaderOversized(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `test.json`)
+	big := `{"id":1,"data":"` + strings.Repeat(`x`, 20000) + `"}`
+	if err := os.WriteFile(pth, []byte(big+"\n{\"id\":2}\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	fin, err := os.Open(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := NewJSONReader(ReaderConfig{Fin: fin, MaxLineLen: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+	lines, err := readAll(rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLines(t, lines, []string{big[:64], `{"id":2}`})
+}
+
+func TestJSONReaderStrayQuote(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `test.json`)
+	//an unbalanced quote between objects swallows everything up to the next quote
+	data := "{\"id\":1}\n\"" + strings.Repeat("x", 20000) + "\"\n{\"id\":2}\n"
+	if err := os.WriteFile(pth, []byte(data), 0640); err != nil {
+		t.Fatal(err)
+	}
+	fin, err := os.Open(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr, err := NewJSONReader(ReaderConfig{Fin: fin, MaxLineLen: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rdr.Close()
+	var lines []string
+	for {
+		ln, ok, eof, err := rdr.ReadEntry()
+		if err != nil {
+			t.Fatal(err)
+		} else if len(rdr.buff) > 64+8*1024 {
+			t.Fatalf("buffer grew to %d bytes", len(rdr.buff))
+		} else if ok {
+			lines = append(lines, string(ln))
+		} else if eof {
+			break
+		}
+	}
+	checkLines(t, lines, []string{`{"id":1}`, `{"id":2}`})
+}
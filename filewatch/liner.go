@@ -25,10 +25,15 @@ func NewLineReader(cfg ReaderConfig) (*LineReader, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newLineReader(br, cfg.Fin), nil
+}
+
+// newLineReader builds a line reader that pulls from an arbitrary stream rather than the base file
+func newLineReader(br baseReader, rdr io.Reader) *LineReader {
 	return &LineReader{
 		baseReader: br,
-		brdr:       bufio.NewReader(cfg.Fin),
-	}, nil
+		brdr:       bufio.NewReader(rdr),
+	}
 }
 
 func (lr *LineReader) ReadEntry() (ln []byte, ok bool, wasEOF bool, err error) {
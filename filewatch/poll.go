@@ -0,0 +1,260 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	// DefaultPollInterval is used when polling is enabled without an interval
+	DefaultPollInterval = 5 * time.Second
+	// MinPollInterval is the fastest we will poll a directory or file
+	MinPollInterval = 100 * time.Millisecond
+	// DefaultPollScanLimit is the maximum number of directory entries examined in a single poll pass
+	DefaultPollScanLimit = 4096
+
+	pollTickInterval = 100 * time.Millisecond
+)
+
+// pollStat is the subset of file info we use to decide if a polled file changed
+type pollStat struct {
+	size    int64
+	modTime time.Time
+}
+
+// dirPoller scans a directory on an interval rather than relying on filesystem notifications, which are
+// not delivered for changes made by other hosts on network filesystems.  Large directories are scanned
+// a limited number of entries at a time so that a single pass never costs more than the scan limit,
+// a complete scan cycle may span many passes.
+type dirPoller struct {
+	dir      string
+	interval time.Duration
+	limit    int
+	mtchs    []string
+	started  time.Time // when the poller was created
+	last     time.Time // when the last complete scan cycle finished
+	dh       *os.File  // open directory handle for an in progress scan cycle
+	primed   bool      // at least one complete scan cycle has finished
+	seen     map[string]pollStat
+	known    map[string]pollStat
+}
+
+// pollResults are the changes found during a poll pass
+type pollResults struct {
+	created []string // files that showed up since the last complete cycle
+	changed []string // files whose size or modification time changed
+	removed []string // files that disappeared, only populated when a cycle completes
+	dirs    []string // subdirectories
+}
+
+func newDirPoller(c WatchConfig, mtchs []string) *dirPoller {
+	dp := &dirPoller{
+		dir:     c.BaseDir,
+		started: time.Now(),
+		known:   map[string]pollStat{},
+	}
+	dp.merge(c, mtchs)
+	return dp
+}
+
+// merge folds another watch config on the same directory into the poller,
+// the fastest interval and smallest scan limit win
+func (dp *dirPoller) merge(c WatchConfig, mtchs []string) {
+	interval := c.PollInterval
+	if interval < MinPollInterval {
+		interval = MinPollInterval
+	}
+	limit := c.PollScanLimit
+	if limit <= 0 {
+		limit = DefaultPollScanLimit
+	}
+	if dp.interval == 0 || interval < dp.interval {
+		dp.interval = interval
+	}
+	if dp.limit == 0 || limit < dp.limit {
+		dp.limit = limit
+	}
+	dp.mtchs = append(dp.mtchs, mtchs...)
+}
+
+func (dp *dirPoller) due(now time.Time) bool {
+	//keep working through an in progress cycle, otherwise wait for the interval
+	return dp.dh != nil || now.Sub(dp.last) >= dp.interval
+}
+
+func (dp *dirPoller) match(name string) bool {
+	for _, m := range dp.mtchs {
+		if ok, err := filepath.Match(m, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (dp *dirPoller) close() {
+	if dp.dh != nil {
+		dp.dh.Close()
+		dp.dh = nil
+	}
+}
+
+// pass examines up to the scan limit of directory entries and reports what changed
+func (dp *dirPoller) pass(now time.Time) (pr pollResults, err error) {
+	if dp.dh == nil {
+		if dp.dh, err = os.Open(dp.dir); err != nil {
+			return
+		}
+		dp.seen = make(map[string]pollStat, len(dp.known))
+	}
+	ents, err := dp.dh.ReadDir(dp.limit)
+	if err != nil && err != io.EOF {
+		dp.close()
+		return
+	}
+	for _, ent := range ents {
+		pth := filepath.Join(dp.dir, ent.Name())
+		if ent.IsDir() {
+			pr.dirs = append(pr.dirs, pth)
+			continue
+		} else if !ent.Type().IsRegular() || !dp.match(ent.Name()) {
+			continue
+		}
+		fi, lerr := ent.Info()
+		if lerr != nil {
+			continue //file went away between the read and the stat
+		}
+		st := pollStat{size: fi.Size(), modTime: fi.ModTime()}
+		dp.seen[pth] = st
+		if prev, ok := dp.known[pth]; !ok {
+			if dp.primed {
+				pr.created = append(pr.created, pth)
+			} else if st.modTime.After(dp.started) {
+				//existing files were loaded at startup, but this one was written after that happened
+				pr.changed = append(pr.changed, pth)
+			}
+		} else if prev != st {
+			pr.changed = append(pr.changed, pth)
+		}
+		dp.known[pth] = st
+	}
+	if err == io.EOF || len(ents) < dp.limit {
+		//cycle is complete, anything we knew about that we didn't see is gone
+		err = nil
+		for k := range dp.known {
+			if _, ok := dp.seen[k]; !ok {
+				pr.removed = append(pr.removed, k)
+				delete(dp.known, k)
+			}
+		}
+		dp.seen = nil
+		dp.primed = true
+		dp.last = now
+		dp.close()
+	}
+	return
+}
+
+// addPollerNoLock registers a directory poller for a watch config, caller MUST HOLD THE LOCK
+func (wm *WatchManager) addPollerNoLock(c WatchConfig, mtchs []string) {
+	if dp, ok := wm.pollers[c.BaseDir]; ok {
+		dp.merge(c, mtchs)
+		return
+	}
+	wm.pollers[c.BaseDir] = newDirPoller(c, mtchs)
+}
+
+// removePollerNoLock stops polling a directory, caller MUST HOLD THE LOCK
+func (wm *WatchManager) removePollerNoLock(dir string) {
+	if dp, ok := wm.pollers[dir]; ok {
+		dp.close()
+		delete(wm.pollers, dir)
+	}
+}
+
+// poll runs a pass on every directory poller that is due and drives the followers the same way
+// filesystem notifications would
+func (wm *WatchManager) poll() {
+	wm.mtx.Lock()
+	defer wm.mtx.Unlock()
+	if wm.fman == nil {
+		return
+	}
+	now := time.Now()
+	for dir, dp := range wm.pollers {
+		if !dp.due(now) {
+			continue
+		}
+		pr, err := dp.pass(now)
+		if err != nil {
+			if os.IsNotExist(err) {
+				//directory is gone, hand it to the removed list so it gets picked up if it comes back
+				if err = wm.removeNoLock(dir); err != nil {
+					wm.logger.Error("error when trying to unwatch removed directory", log.KV("path", dir), log.KVErr(err))
+				}
+			} else {
+				wm.logger.Error("failed to poll directory", log.KV("path", dir), log.KVErr(err))
+			}
+			continue
+		}
+		wm.applyPollNoLock(dir, pr)
+	}
+}
+
+// applyPollNoLock handles the results of a poll pass, caller MUST HOLD THE LOCK
+func (wm *WatchManager) applyPollNoLock(dir string, pr pollResults) {
+	for _, pth := range pr.dirs {
+		if _, ok := wm.watched[pth]; ok {
+			continue
+		} else if _, ok = wm.removed[pth]; ok {
+			continue
+		}
+		for _, parent := range wm.watched[dir] {
+			if !parent.Recursive {
+				continue
+			}
+			parent.BaseDir = pth
+			wm.logger.Info("adding poller for subdirectory", log.KV("path", pth), log.KV("patterns", parent.FileFilter))
+			if err := wm.addNoLock(parent); err != nil {
+				wm.logger.Error("failed to add poller for new directory", log.KV("path", pth), log.KVErr(err))
+			}
+		}
+	}
+	for _, pth := range pr.created {
+		if ok, err := wm.fman.NewFollower(pth); err != nil {
+			wm.logger.Error("failed to watch new file", log.KV("path", pth), log.KVErr(err))
+		} else if ok {
+			wm.logger.Info("watching new file", log.KV("path", pth))
+		}
+	}
+	for _, pth := range pr.changed {
+		//followers poll their own files, we only need to pick up files that are not being followed,
+		//e.g. files that were expunged because we hit the maximum number of watched files
+		if wm.fman.IsWatched(pth) {
+			continue
+		}
+		if ok, err := wm.fman.LoadFile(pth); err != nil {
+			wm.logger.Error("failed to watch file", log.KV("path", pth), log.KVErr(err))
+		} else if ok {
+			wm.logger.Info("watching file", log.KV("path", pth))
+		}
+	}
+	for _, pth := range pr.removed {
+		if ok, err := wm.fman.RemoveFollower(pth); err != nil {
+			wm.logger.Error("failed to stop watching file", log.KV("path", pth), log.KVErr(err))
+		} else if ok {
+			wm.logger.Info("stopped watching file", log.KV("path", pth))
+		}
+	}
+}
@@ -0,0 +1,160 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package filewatch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testPollInterval = 100 * time.Millisecond
+
+func waitFor(cond func() bool) error {
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return errors.New("timed out")
+}
+
+func TestDirPollerScanLimit(t *testing.T) {
+	dir := t.TempDir()
+	dp := newDirPoller(WatchConfig{BaseDir: dir, PollScanLimit: 2}, []string{`*.log`})
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.log", i)), []byte("hello\n"), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, `skip.txt`), []byte("hello\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	//files that existed when the poller started are not new, the watcher loads them at startup
+	dp.started = time.Now().Add(time.Minute)
+
+	//6 entries at 2 per pass takes 3 passes, plus one to see the end of the directory
+	var passes int
+	for passes = 1; passes < 10; passes++ {
+		pr, err := dp.pass(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		} else if len(pr.created) != 0 || len(pr.changed) != 0 || len(pr.removed) != 0 {
+			t.Fatalf("unexpected results on initial scan: %+v", pr)
+		}
+		if dp.primed {
+			break
+		}
+	}
+	if passes < 3 {
+		t.Fatalf("scan limit was not honored, completed in %d passes", passes)
+	} else if len(dp.known) != 5 {
+		t.Fatalf("bad known count %d", len(dp.known))
+	}
+
+	//add a file, change a file, and remove a file then run a full cycle
+	if err := os.WriteFile(filepath.Join(dir, `5.log`), []byte("hello\n"), 0640); err != nil {
+		t.Fatal(err)
+	} else if err = appendFile(filepath.Join(dir, `1.log`), []byte("more\n")); err != nil {
+		t.Fatal(err)
+	} else if err = os.Remove(filepath.Join(dir, `2.log`)); err != nil {
+		t.Fatal(err)
+	}
+	var created, changed, removed []string
+	for {
+		pr, err := dp.pass(time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, pr.created...)
+		changed = append(changed, pr.changed...)
+		removed = append(removed, pr.removed...)
+		if dp.dh == nil {
+			break
+		}
+	}
+	if len(created) != 1 || filepath.Base(created[0]) != `5.log` {
+		t.Fatalf("bad created files %v", created)
+	}
+	if len(changed) != 1 || filepath.Base(changed[0]) != `1.log` {
+		t.Fatalf("bad changed files %v", changed)
+	}
+	if len(removed) != 1 || filepath.Base(removed[0]) != `2.log` {
+		t.Fatalf("bad removed files %v", removed)
+	}
+
+	//a fresh poller reports files written after it started as changed so that they get loaded
+	dp = newDirPoller(WatchConfig{BaseDir: dir}, []string{`*.log`})
+	dp.started = time.Now().Add(-time.Minute)
+	pr, err := dp.pass(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(pr.changed) != 5 || len(pr.created) != 0 {
+		t.Fatalf("bad initial scan results: %+v", pr)
+	}
+}
+
+func TestPollingWatcher(t *testing.T) {
+	lh := newSafeTrackingLH()
+	all := map[string]bool{}
+	fireWatcher(func(workingDir string, w *WatchManager) error {
+		watchCfg := WatchConfig{
+			ConfigName: bName,
+			BaseDir:    workingDir,
+			FileFilter: `paco*`,
+			Hnd:        lh,
+		}
+		watchCfg.PollInterval = testPollInterval
+		if err := w.Add(watchCfg); err != nil {
+			return err
+		}
+		if len(w.pollers) != 1 {
+			return errors.New("poller not installed")
+		}
+		return nil
+	},
+		nil,
+		func(workingDir string) error {
+			//a new file is picked up by the directory poller
+			first := filepath.Join(workingDir, `paco1`)
+			_, res, err := writeLines(first)
+			if err != nil {
+				return err
+			}
+			for k := range res {
+				all[k] = true
+			}
+			if err = waitFor(func() bool { return lh.Len() == len(all) }); err != nil {
+				return fmt.Errorf("new file: %w %d != %d", err, lh.Len(), len(all))
+			}
+
+			//appended data is picked up by the polling follower
+			if _, res, err = writeLines(first); err != nil {
+				return err
+			}
+			for k := range res {
+				all[k] = true
+			}
+			if err = waitFor(func() bool { return lh.Len() == len(all) }); err != nil {
+				return fmt.Errorf("appended data: %w %d != %d", err, lh.Len(), len(all))
+			}
+
+			//a removed file stops being followed
+			if err = os.Remove(first); err != nil {
+				return err
+			}
+			return nil
+		}, func(wm *WatchManager) error {
+			return waitFor(func() bool { return wm.Followers() == 0 })
+		}, t)
+}
@@ -23,6 +23,9 @@ const (
 const (
 	LineEngine  int = 0
 	RegexEngine int = 1
+	// 2 is the windows only EvtxEngine
+	JSONEngine int = 3
+	CSVEngine  int = 4
 )
 
 type Reader interface {
@@ -42,6 +45,8 @@ type ReaderConfig struct {
 	StartIndex int64
 	Engine     int
 	EngineArgs string
+
+	resolve func([]byte) int64 // optional hook used by compressed readers to find already ingested data
 }
 
 type baseReader struct {
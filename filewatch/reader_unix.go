@@ -15,14 +15,22 @@ import (
 	"errors"
 )
 
-// NewReader creates a new reader based on either the regex engine or line reader engine
+// NewReader creates a new reader based on the regex, JSON, CSV, or line reader engine
 // the Linux version of file follow does NOT support EVTX engines
+// compressed files are detected by their magic bytes and decompressed before being handed to the engine
 func NewReader(cfg ReaderConfig) (Reader, error) {
+	if isCompressedFile(cfg.Fin) {
+		return NewCompressedReader(cfg)
+	}
 	switch cfg.Engine {
 	case RegexEngine:
 		return NewRegexReader(cfg)
 	case LineEngine: //default/empty is line reader
 		return NewLineReader(cfg)
+	case JSONEngine:
+		return NewJSONReader(cfg)
+	case CSVEngine:
+		return NewCSVReader(cfg)
 	}
 	return nil, errors.New("Unknown engine")
 }
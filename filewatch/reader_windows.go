@@ -35,6 +35,9 @@ var (
 )
 
 func NewReader(cfg ReaderConfig) (Reader, error) {
+	if cfg.Engine != EvtxEngine && isCompressedFile(cfg.Fin) {
+		return NewCompressedReader(cfg)
+	}
 	switch cfg.Engine {
 	case RegexEngine:
 		return NewRegexReader(cfg)
@@ -48,6 +51,10 @@ func NewReader(cfg ReaderConfig) (Reader, error) {
 		return NewLineReader(cfg)
 	case EvtxEngine:
 		return NewEvtxReader(cfg)
+	case JSONEngine:
+		return NewJSONReader(cfg)
+	case CSVEngine:
+		return NewCSVReader(cfg)
 	}
 	return nil, errors.New("Unknown engine")
 }
@@ -32,14 +32,18 @@ func NewRegexReader(cfg ReaderConfig) (*RegexReader, error) {
 	if err != nil {
 		return nil, err
 	}
-	rr := &RegexReader{
+	return newRegexReader(br, rx, cfg.Fin), nil
+}
+
+// newRegexReader builds a regex reader that pulls from an arbitrary stream rather than the base file
+func newRegexReader(br baseReader, rx *regexp.Regexp, rdr io.Reader) *RegexReader {
+	return &RegexReader{
 		baseReader: br,
 		rx:         rx,
-		currLine:   make([]byte, 0, cfg.MaxLineLen),
-		brdr:       bufio.NewReader(cfg.Fin),
+		currLine:   make([]byte, 0, br.maxLine),
+		brdr:       bufio.NewReader(rdr),
 		lastRead:   time.Now(),
 	}
-	return rr, nil
 }
 
 func (rr *RegexReader) ReadEntry() (ln []byte, ok bool, wasEOF bool, err error) {
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 
 	// Embed tzdata so that we don't rely on potentially broken timezone DBs on the host
 	_ "time/tzdata"
@@ -21,7 +22,7 @@ import (
 )
 
 func main() {
-	if len(os.Args) != 4 {
+	if len(os.Args) != 4 && (len(os.Args) != 5 || os.Args[1] != `migrate`) {
 		showHelp(os.Args[0])
 		return
 	}
@@ -34,6 +35,22 @@ func main() {
 		if err := exportSet(os.Args[2], os.Args[3]); err != nil {
 			fmt.Printf("export failed - %v\n", err)
 		}
+	case `migrate`:
+		sz := filewatch.DefaultFingerprintSize
+		if len(os.Args) == 5 {
+			var err error
+			if sz, err = strconv.ParseInt(os.Args[4], 10, 64); err != nil || sz < filewatch.MinFingerprintSize {
+				fmt.Printf("Invalid fingerprint size %q, must be at least %d bytes\n", os.Args[4], filewatch.MinFingerprintSize)
+				os.Exit(-1)
+			}
+		}
+		if err := migrateSet(os.Args[2], os.Args[3], sz); err != nil {
+			fmt.Printf("migrate failed - %v\n", err)
+		}
+	case `revert`:
+		if err := revertSet(os.Args[2], os.Args[3]); err != nil {
+			fmt.Printf("revert failed - %v\n", err)
+		}
 	default:
 		fmt.Printf("Invalid action %q\n", os.Args[1])
 		os.Exit(-1)
@@ -44,6 +61,10 @@ func showHelp(app string) {
 	fmt.Printf("%s <action> <input file> <output file>\n", app)
 	fmt.Printf("\nExample Export: %s export /opt/gravwell/etc/file_follow.state /tmp/states.json\n", app)
 	fmt.Printf("\nExample Import: %s import /tmp/states.json /opt/gravwell/etc/file_follow.state\n", app)
+	fmt.Printf("\nMigrate to fingerprint file identity: %s migrate /opt/gravwell/etc/file_follow.state /tmp/file_follow.state [fingerprint size]\n", app)
+	fmt.Printf("\nRevert to path file identity: %s revert /opt/gravwell/etc/file_follow.state /tmp/file_follow.state\n", app)
+	fmt.Printf("\nMigrations fingerprint the first Fingerprint-Size bytes of each file (default %d), pass the Fingerprint-Size from the ingester config if it is set.\n", filewatch.DefaultFingerprintSize)
+	fmt.Printf("The ingester must be stopped and the output file moved into place\n")
 }
 
 func importSet(input, output string) (err error) {
@@ -104,3 +125,47 @@ func writeObject(wtr io.Writer, obj interface{}) (err error) {
 	}
 	return
 }
+
+// migrateSet converts a state file to fingerprint file identity by fingerprinting the files as they are right now
+// states for files that no longer exist are dropped, the ingester would discard them at startup anyway.
+// sz should match the Fingerprint-Size the ingester is configured with.
+func migrateSet(input, output string, sz int64) (err error) {
+	var fs []filewatch.FileState
+	if fs, err = filewatch.DecodeStateFile(input); err != nil {
+		err = fmt.Errorf("failed to decode state file %q - %w", input, err)
+		return
+	}
+	out := make([]filewatch.FileState, 0, len(fs))
+	for _, st := range fs {
+		if st.Fingerprint == nil {
+			var fp filewatch.Fingerprint
+			if fp, err = filewatch.FingerprintPath(st.FilePath, sz); err != nil {
+				if os.IsNotExist(err) {
+					err = nil
+					fmt.Printf("dropping state for missing file %q\n", st.FilePath)
+					continue
+				}
+				err = fmt.Errorf("failed to fingerprint %q - %w", st.FilePath, err)
+				return
+			}
+			st.Fingerprint = &fp
+		}
+		out = append(out, st)
+	}
+	err = filewatch.EncodeStateFile(output, out)
+	return
+}
+
+// revertSet converts a state file back to path file identity
+func revertSet(input, output string) (err error) {
+	var fs []filewatch.FileState
+	if fs, err = filewatch.DecodeStateFile(input); err != nil {
+		err = fmt.Errorf("failed to decode state file %q - %w", input, err)
+		return
+	}
+	for i := range fs {
+		fs[i].Fingerprint = nil
+	}
+	err = filewatch.EncodeStateFile(output, fs)
+	return
+}
@@ -14,17 +14,22 @@ import (
 )
 
 type FileState struct {
-	BaseName string
-	FilePath string
-	State    int64
+	BaseName    string
+	FilePath    string
+	State       int64
+	Fingerprint *Fingerprint `json:",omitempty"`
 }
 
+// DecodeStateFile reads a state file using either path or fingerprint file identity,
+// fingerprints are only populated for fingerprint identity state files.
 func DecodeStateFile(sf string) (states []FileState, err error) {
-	native := map[FileName]*int64{}
+	var native map[FileName]*int64
+	var prints map[FileName]Fingerprint
+	var fingerprinted bool
 	var fin *os.File
 	if fin, err = os.Open(sf); err != nil {
 		return
-	} else if err = gob.NewDecoder(fin).Decode(&native); err != nil {
+	} else if native, prints, fingerprinted, err = decodeStates(fin); err != nil {
 		fin.Close()
 		return
 	} else if err = fin.Close(); err != nil {
@@ -35,24 +40,56 @@ func DecodeStateFile(sf string) (states []FileState, err error) {
 		if v != nil {
 			st = *v
 		}
-		states = append(states, FileState{
+		fs := FileState{
 			BaseName: k.BaseName,
 			FilePath: k.FilePath,
 			State:    st,
-		})
+		}
+		if fingerprinted {
+			fp := prints[k]
+			fs.Fingerprint = &fp
+		}
+		states = append(states, fs)
 	}
 	return
 }
 
+// EncodeStateFile writes a state file, if any of the states carry a fingerprint the state file
+// is written using fingerprint file identity, otherwise path identity is used.
 func EncodeStateFile(sf string, states []FileState) (err error) {
-	native := make(map[FileName]*int64, len(states))
+	var obj interface{}
+	var fingerprinted bool
 	for _, s := range states {
-		native[FileName{BaseName: s.BaseName, FilePath: s.FilePath}] = &s.State
+		if s.Fingerprint != nil {
+			fingerprinted = true
+			break
+		}
+	}
+	if fingerprinted {
+		fsf := fingerprintStateFile{
+			Version: stateFileVersion,
+			States:  make(map[FileName]fingerprintState, len(states)),
+		}
+		for _, s := range states {
+			fst := fingerprintState{Offset: s.State}
+			if s.Fingerprint != nil {
+				fst.Fingerprint = *s.Fingerprint
+			}
+			fsf.States[FileName{BaseName: s.BaseName, FilePath: s.FilePath}] = fst
+		}
+		obj = fsf
+	} else {
+		native := make(map[FileName]*int64, len(states))
+		for _, s := range states {
+			s := s
+			native[FileName{BaseName: s.BaseName, FilePath: s.FilePath}] = &s.State
+		}
+		obj = native
 	}
 	var fout *os.File
 	if fout, err = os.Create(sf); err != nil {
 		return
-	} else if err = gob.NewEncoder(fout).Encode(native); err != nil {
+	} else if err = gob.NewEncoder(fout).Encode(obj); err != nil {
 		fout.Close()
 		return
 	}
@@ -126,10 +126,14 @@ type IngestStreamConfig struct {
 	Enable_Compression bool `json:",omitempty"`
 }
 
+// TimeFormat defines a custom time format, a TimeFormat that only specifies Locale adds those locales
+// to the built in time formats.
 type TimeFormat struct {
 	Format           string
+	Format_Syntax    string // go, strftime, or java
 	Regex            string
 	Extraction_Regex string
+	Locale           []string // month and weekday names from other languages, e.g. de, fr, or es
 }
 
 type CustomTimeFormat map[string]*TimeFormat
@@ -471,6 +475,10 @@ func writeFull(w io.Writer, b []byte) error {
 	return nil
 }
 
+func (tf *TimeFormat) localesOnly() bool {
+	return tf.Format == `` && tf.Regex == `` && tf.Extraction_Regex == `` && len(tf.Locale) > 0
+}
+
 func (ctf CustomTimeFormat) Validate() (err error) {
 	if len(ctf) == 0 {
 		return
@@ -478,12 +486,19 @@ func (ctf CustomTimeFormat) Validate() (err error) {
 	for k, v := range ctf {
 		if v == nil {
 			continue
+		} else if v.localesOnly() {
+			if err = timegrinder.ValidateLocales(v.Locale); err != nil {
+				return
+			}
+			continue
 		}
 		cf := timegrinder.CustomFormat{
 			Name:             k,
 			Format:           v.Format,
+			Format_Syntax:    v.Format_Syntax,
 			Regex:            v.Regex,
 			Extraction_Regex: v.Extraction_Regex,
+			Locale:           v.Locale,
 		}
 		if err = cf.Validate(); err != nil {
 			return
@@ -502,12 +517,20 @@ func (ctf CustomTimeFormat) LoadFormats(tg *timegrinder.TimeGrinder) (err error)
 		var p timegrinder.Processor
 		if v == nil {
 			continue
+		} else if v.localesOnly() {
+			//a section with nothing but locales applies them to the built in formats
+			if err = tg.AddLocales(v.Locale...); err != nil {
+				return
+			}
+			continue
 		}
 		cf := timegrinder.CustomFormat{
 			Name:             k,
 			Format:           v.Format,
+			Format_Syntax:    v.Format_Syntax,
 			Regex:            v.Regex,
 			Extraction_Regex: v.Extraction_Regex,
+			Locale:           v.Locale,
 		}
 		if p, err = timegrinder.NewCustomProcessor(cf); err != nil {
 			return
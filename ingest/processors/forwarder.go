@@ -90,6 +90,11 @@ type Forwarder struct {
 	enc          EntryEncoder
 	err          error
 	closed       bool
+	entryFilter
+}
+
+// entryFilter implements the optional tag, regex, and source filters shared by the forwarders
+type entryFilter struct {
 	tagFilters   map[entry.EntryTag]struct{}
 	regexFilters []*regexp.Regexp
 	srcFilters   []net.IPNet
@@ -108,27 +113,9 @@ func NewForwarder(cfg ForwarderConfig, tgr Tagger) (nf *Forwarder, err error) {
 		ForwarderConfig: cfg,
 		ch:              make(chan *entry.Entry, cfg.Buffer),
 		abrt:            make(chan struct{}),
-		tagFilters:      map[entry.EntryTag]struct{}{},
 		tgr:             tgr,
 	}
-
-	//build up our tag filter
-	for _, tn := range cfg.Tag {
-		var tg entry.EntryTag
-		if tg, err = tgr.NegotiateTag(tn); err != nil {
-			err = fmt.Errorf("Failed to negotiate tag %s: %v", tn, err)
-			return
-		}
-		nf.tagFilters[tg] = empty
-	}
-	//build up the source filter
-	if nf.srcFilters, err = parseIPNets(cfg.Source); err != nil {
-		err = fmt.Errorf("Invalid source filters: %v", err)
-		return
-	}
-	//build up the regex filters
-	if nf.regexFilters, err = parseRegex(cfg.Regex); err != nil {
-		err = fmt.Errorf("Invalid regex filters: %v", err)
+	if nf.entryFilter, err = newEntryFilter(cfg.Tag, cfg.Regex, cfg.Source, tgr); err != nil {
 		return
 	}
 
@@ -179,9 +166,33 @@ func (nf *Forwarder) nonblockingProcess(ent *entry.Entry) {
 	return
 }
 
+func newEntryFilter(tags, regex, src []string, tgr Tagger) (ef entryFilter, err error) {
+	ef.tagFilters = map[entry.EntryTag]struct{}{}
+	//build up our tag filter
+	for _, tn := range tags {
+		var tg entry.EntryTag
+		if tg, err = tgr.NegotiateTag(tn); err != nil {
+			err = fmt.Errorf("Failed to negotiate tag %s: %v", tn, err)
+			return
+		}
+		ef.tagFilters[tg] = empty
+	}
+	//build up the source filter
+	if ef.srcFilters, err = parseIPNets(src); err != nil {
+		err = fmt.Errorf("Invalid source filters: %v", err)
+		return
+	}
+	//build up the regex filters
+	if ef.regexFilters, err = parseRegex(regex); err != nil {
+		err = fmt.Errorf("Invalid regex filters: %v", err)
+		return
+	}
+	return
+}
+
 // filter applies the optional tag and regex filters against the data
 // returning true means drop the entry
-func (nf *Forwarder) filter(ent *entry.Entry) (drop bool) {
+func (nf *entryFilter) filter(ent *entry.Entry) (drop bool) {
 	if drop = nf.filterByTag(ent.Tag); drop {
 		return
 	} else if drop = nf.filterByRegex(ent.Data); drop {
@@ -192,7 +203,7 @@ func (nf *Forwarder) filter(ent *entry.Entry) (drop bool) {
 	return
 }
 
-func (nf *Forwarder) filterByTag(tag entry.EntryTag) (drop bool) {
+func (nf *entryFilter) filterByTag(tag entry.EntryTag) (drop bool) {
 	if len(nf.tagFilters) > 0 {
 		if _, ok := nf.tagFilters[tag]; !ok {
 			drop = true //NOT in our filter set
@@ -201,7 +212,7 @@ func (nf *Forwarder) filterByTag(tag entry.EntryTag) (drop bool) {
 	return
 }
 
-func (nf *Forwarder) filterBySrc(ip net.IP) (drop bool) {
+func (nf *entryFilter) filterBySrc(ip net.IP) (drop bool) {
 	if len(nf.srcFilters) > 0 {
 		for _, ipn := range nf.srcFilters {
 			if ipn.Contains(ip) {
@@ -213,7 +224,7 @@ func (nf *Forwarder) filterBySrc(ip net.IP) (drop bool) {
 	return
 }
 
-func (nf *Forwarder) filterByRegex(dt []byte) (drop bool) {
+func (nf *entryFilter) filterByRegex(dt []byte) (drop bool) {
 	if len(nf.regexFilters) > 0 {
 		for _, rx := range nf.regexFilters {
 			if rx.Match(dt) {
@@ -0,0 +1,110 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+
+	"github.com/IBM/sarama"
+	"github.com/xdg-go/scram"
+)
+
+const (
+	KafkaAuthPlain       = `plain`
+	KafkaAuthScramSHA256 = `scramsha256`
+	KafkaAuthScramSHA512 = `scramsha512`
+)
+
+// KafkaAuthConfig is the SASL configuration shared by the Kafka consumer and the Kafka forwarder
+type KafkaAuthConfig struct {
+	Auth_Type string
+	Username  string
+	Password  string
+}
+
+func (kac KafkaAuthConfig) Validate() (err error) {
+	switch strings.ToLower(kac.Auth_Type) {
+	case ``:
+		return //no auth
+	case KafkaAuthPlain:
+	case KafkaAuthScramSHA256:
+	case KafkaAuthScramSHA512:
+	default:
+		err = fmt.Errorf("Unknown auth type %q", kac.Auth_Type)
+		return
+	}
+	//auth is active
+	if kac.Username == `` {
+		err = fmt.Errorf("Missing Username")
+	} else if kac.Password == `` {
+		err = fmt.Errorf("Missing Password")
+	}
+	return
+}
+
+func (kac KafkaAuthConfig) SetAuth(cfg *sarama.Config) (err error) {
+	if err = kac.Validate(); err != nil {
+		return
+	} else if kac.Auth_Type == `` {
+		return
+	}
+	//enable the basics
+	cfg.Net.SASL.Enable = true
+	cfg.Net.SASL.Handshake = true
+	cfg.Net.SASL.User = kac.Username
+	cfg.Net.SASL.Password = kac.Password
+
+	switch strings.ToLower(kac.Auth_Type) {
+	case KafkaAuthPlain:
+		cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case KafkaAuthScramSHA256:
+		cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &XDGSCRAMClient{HashGeneratorFcn: SHA256}
+		}
+		cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+	case KafkaAuthScramSHA512:
+		cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &XDGSCRAMClient{HashGeneratorFcn: SHA512}
+		}
+		cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+	}
+	return
+}
+
+var (
+	SHA256 scram.HashGeneratorFcn = sha256.New
+	SHA512 scram.HashGeneratorFcn = sha512.New
+)
+
+type XDGSCRAMClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (x *XDGSCRAMClient) Begin(userName, password, authzID string) (err error) {
+	x.Client, err = x.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	x.ClientConversation = x.Client.NewConversation()
+	return nil
+}
+
+func (x *XDGSCRAMClient) Step(challenge string) (response string, err error) {
+	response, err = x.ClientConversation.Step(challenge)
+	return
+}
+
+func (x *XDGSCRAMClient) Done() bool {
+	return x.ClientConversation.Done()
+}
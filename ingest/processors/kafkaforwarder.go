@@ -0,0 +1,466 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+const (
+	KafkaForwarderProcessor string = `kafkaforwarder`
+
+	deliveryGuaranteed    string = `guaranteed`
+	deliveryFireAndForget string = `fire-and-forget`
+
+	defaultKafkaPort    uint16 = 9092
+	kafkaProducerVer           = `2.1.1` //zstd needs at least 2.1
+	maxKafkaTopicLength        = 249
+
+	defaultKafkaBatchInterval = 250 * time.Millisecond
+)
+
+var (
+	ErrMissingLeader       = errors.New("At least one Kafka Leader is required")
+	ErrUnknownDeliveryMode = errors.New("Unknown Delivery-Mode")
+	ErrUnknownCompression  = errors.New("Unknown Compression")
+	ErrInvalidTopic        = errors.New("Invalid Kafka topic")
+
+	// these are swapped out in tests
+	newKafkaSyncProducer  = sarama.NewSyncProducer
+	newKafkaAsyncProducer = sarama.NewAsyncProducer
+)
+
+type KafkaForwarderConfig struct {
+	Leader    []string
+	Topic     string   //topic for entries without a Tag-Topic mapping, the tag name is used if empty
+	Tag_Topic []string //tag:topic mappings
+	Format    string
+	Tag       []string
+	Regex     []string
+	Source    []string
+
+	Delivery_Mode  string //guaranteed or fire-and-forget
+	Compression    string //none, gzip, snappy, lz4, zstd
+	Batch_Size     int    //number of messages that triggers a send
+	Batch_Interval string //maximum time to hold messages before a send, defaults to 250ms when Batch-Size is set
+	Timeout        uint   //timeout in seconds for network operations
+
+	Use_TLS                  bool
+	Insecure_Skip_TLS_Verify bool
+	KafkaAuthConfig
+}
+
+func KafkaForwarderLoadConfig(vc *config.VariableConfig) (c KafkaForwarderConfig, err error) {
+	if err = vc.MapTo(&c); err != nil {
+		return
+	} else if err = vc.MapTo(&c.KafkaAuthConfig); err != nil { //MapTo does not descend into embedded structs
+		return
+	}
+	err = c.Validate()
+	return
+}
+
+func (kfc *KafkaForwarderConfig) Validate() (err error) {
+	if len(kfc.Leader) == 0 {
+		return ErrMissingLeader
+	}
+	for _, l := range kfc.Leader {
+		if _, _, err = net.SplitHostPort(config.AppendDefaultPort(l, defaultKafkaPort)); err != nil {
+			return fmt.Errorf("invalid Leader %q - %w", l, err)
+		}
+	}
+	if kfc.Topic != `` {
+		if err = checkKafkaTopic(kfc.Topic); err != nil {
+			return
+		}
+	}
+	if _, err = kfc.tagTopics(); err != nil {
+		return
+	}
+	if kfc.Format == `` {
+		kfc.Format = defaultFormat
+	} else {
+		kfc.Format = strings.ToLower(strings.TrimSpace(kfc.Format))
+	}
+	switch kfc.Format {
+	case encRaw, encJSON, encSYSLOG:
+	default:
+		return ErrUnknownFormat
+	}
+	if kfc.Delivery_Mode = strings.ToLower(strings.TrimSpace(kfc.Delivery_Mode)); kfc.Delivery_Mode == `` {
+		kfc.Delivery_Mode = deliveryGuaranteed
+	} else if kfc.Delivery_Mode != deliveryGuaranteed && kfc.Delivery_Mode != deliveryFireAndForget {
+		return ErrUnknownDeliveryMode
+	}
+	if _, err = kfc.codec(); err != nil {
+		return
+	}
+	if kfc.Batch_Size < 0 {
+		return errors.New("Batch-Size may not be negative")
+	}
+	if _, err = kfc.batchInterval(); err != nil {
+		return
+	}
+	if err = kfc.KafkaAuthConfig.Validate(); err != nil {
+		return
+	}
+	for _, tagname := range kfc.Tag {
+		if err = ingest.CheckTag(tagname); err != nil {
+			return fmt.Errorf("Invalid tag name: %v", err)
+		}
+	}
+	if _, err = parseIPNets(kfc.Source); err != nil {
+		return
+	}
+	_, err = parseRegex(kfc.Regex)
+	return
+}
+
+// tagTopics parses the Tag-Topic mappings which look like syslog:logs-syslog
+func (kfc *KafkaForwarderConfig) tagTopics() (mp map[string]string, err error) {
+	mp = make(map[string]string, len(kfc.Tag_Topic))
+	for _, v := range kfc.Tag_Topic {
+		tag, topic, ok := strings.Cut(v, `:`)
+		tag, topic = strings.TrimSpace(tag), strings.TrimSpace(topic)
+		if !ok {
+			return nil, fmt.Errorf("Invalid Tag-Topic %q, expected tag:topic", v)
+		} else if err = ingest.CheckTag(tag); err != nil {
+			return nil, fmt.Errorf("Invalid Tag-Topic tag %q: %v", tag, err)
+		} else if err = checkKafkaTopic(topic); err != nil {
+			return nil, err
+		} else if _, ok = mp[tag]; ok {
+			return nil, fmt.Errorf("Tag-Topic tag %q is duplicated", tag)
+		}
+		mp[tag] = topic
+	}
+	return
+}
+
+func (kfc *KafkaForwarderConfig) codec() (sarama.CompressionCodec, error) {
+	switch strings.ToLower(strings.TrimSpace(kfc.Compression)) {
+	case ``, `none`:
+		return sarama.CompressionNone, nil
+	case `gzip`:
+		return sarama.CompressionGZIP, nil
+	case `snappy`:
+		return sarama.CompressionSnappy, nil
+	case `lz4`:
+		return sarama.CompressionLZ4, nil
+	case `zstd`:
+		return sarama.CompressionZSTD, nil
+	}
+	return sarama.CompressionNone, ErrUnknownCompression
+}
+
+// batchInterval returns the flush frequency, a Batch-Size without a flush frequency would hold
+// a short batch forever and block the guaranteed mode send, so one is always set with a Batch-Size
+func (kfc *KafkaForwarderConfig) batchInterval() (d time.Duration, err error) {
+	if kfc.Batch_Interval == `` {
+		if kfc.Batch_Size > 0 {
+			d = defaultKafkaBatchInterval
+		}
+		return
+	} else if d, err = time.ParseDuration(kfc.Batch_Interval); err != nil {
+		err = fmt.Errorf("Invalid Batch-Interval %q: %v", kfc.Batch_Interval, err)
+	} else if d < 0 {
+		err = fmt.Errorf("Invalid Batch-Interval %q", kfc.Batch_Interval)
+	} else if d == 0 && kfc.Batch_Size > 0 {
+		err = fmt.Errorf("Invalid Batch-Interval %q: an interval is required with Batch-Size", kfc.Batch_Interval)
+	}
+	return
+}
+
+// checkKafkaTopic applies the broker's topic naming rules
+func checkKafkaTopic(t string) error {
+	if len(t) == 0 || len(t) > maxKafkaTopicLength || t == `.` || t == `..` {
+		return fmt.Errorf("%w %q", ErrInvalidTopic, t)
+	}
+	for _, r := range t {
+		if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '.' && r != '_' && r != '-' {
+			return fmt.Errorf("%w %q", ErrInvalidTopic, t)
+		}
+	}
+	return nil
+}
+
+func (kfc *KafkaForwarderConfig) saramaConfig() (cfg *sarama.Config, err error) {
+	cfg = sarama.NewConfig()
+	if cfg.Version, err = sarama.ParseKafkaVersion(kafkaProducerVer); err != nil {
+		return
+	}
+	cfg.ClientID = `gravwell`
+	if cfg.Producer.Compression, err = kfc.codec(); err != nil {
+		return
+	}
+	cfg.Producer.Flush.Messages = kfc.Batch_Size
+	if cfg.Producer.Flush.Frequency, err = kfc.batchInterval(); err != nil {
+		return
+	}
+	if kfc.Timeout > 0 {
+		to := time.Duration(kfc.Timeout) * time.Second
+		cfg.Net.DialTimeout = to
+		cfg.Net.ReadTimeout = to
+		cfg.Net.WriteTimeout = to
+	}
+	if kfc.Delivery_Mode == deliveryFireAndForget {
+		cfg.Producer.RequiredAcks = sarama.NoResponse
+		cfg.Producer.Return.Successes = false
+		cfg.Producer.Return.Errors = false //nobody is listening
+	} else {
+		cfg.Producer.RequiredAcks = sarama.WaitForAll
+		cfg.Producer.Return.Successes = true //required by the sync producer
+		cfg.Producer.Return.Errors = true
+	}
+	if kfc.Use_TLS {
+		cfg.Net.TLS.Enable = true
+		cfg.Net.TLS.Config = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: kfc.Insecure_Skip_TLS_Verify,
+		}
+	}
+	if err = kfc.KafkaAuthConfig.SetAuth(cfg); err != nil {
+		return
+	}
+	err = cfg.Validate()
+	return
+}
+
+func (kfc *KafkaForwarderConfig) leaders() (r []string) {
+	for _, l := range kfc.Leader {
+		r = append(r, config.AppendDefaultPort(l, defaultKafkaPort))
+	}
+	return
+}
+
+// KafkaForwarder publishes a copy of entries to Kafka topics chosen by tag.
+// In guaranteed mode Process blocks until the brokers acknowledge every message, retrying as needed.
+// In fire-and-forget mode messages are queued without waiting and dropped if the queue is full.
+type KafkaForwarder struct {
+	KafkaForwarderConfig
+	sync.Mutex
+	entryFilter
+	tgr       Tagger
+	ctx       context.Context
+	cf        context.CancelFunc
+	producer  sarama.SyncProducer
+	asyncProd sarama.AsyncProducer
+	enc       EntryEncoder
+	bb        *bytes.Buffer
+	tt        *tagTrans
+	topicMap  map[string]string
+	topics    map[entry.EntryTag]string
+	closed    bool
+}
+
+func NewKafkaForwarder(cfg KafkaForwarderConfig, tgr Tagger) (kf *KafkaForwarder, err error) {
+	if err = cfg.Validate(); err != nil {
+		return
+	} else if tgr == nil {
+		err = ErrNilTagger
+		return
+	}
+	kf = &KafkaForwarder{
+		KafkaForwarderConfig: cfg,
+		tgr:                  tgr,
+		bb:                   bytes.NewBuffer(nil),
+		tt:                   newTagTrans(tgr),
+		topics:               map[entry.EntryTag]string{},
+	}
+	if kf.entryFilter, err = newEntryFilter(cfg.Tag, cfg.Regex, cfg.Source, tgr); err != nil {
+		return nil, err
+	} else if kf.topicMap, err = cfg.tagTopics(); err != nil {
+		return nil, err
+	}
+	switch cfg.Format {
+	case encRaw:
+		kf.enc, err = newRawEncoder(kf.bb, nil) //each entry is its own message, no delimiter
+	case encJSON:
+		kf.enc, err = newJSONEncoder(kf.bb, tgr)
+	case encSYSLOG:
+		kf.enc, err = newSyslogEncoder(kf.bb, tgr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var scfg *sarama.Config
+	if scfg, err = cfg.saramaConfig(); err != nil {
+		return nil, err
+	}
+	if cfg.Delivery_Mode == deliveryFireAndForget {
+		kf.asyncProd, err = newKafkaAsyncProducer(cfg.leaders(), scfg)
+	} else {
+		kf.producer, err = newKafkaSyncProducer(cfg.leaders(), scfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create Kafka producer: %w", err)
+	}
+	kf.ctx, kf.cf = context.WithCancel(context.Background())
+	return
+}
+
+func (kf *KafkaForwarder) Process(ents []*entry.Entry) ([]*entry.Entry, error) {
+	kf.Lock()
+	defer kf.Unlock()
+	if kf.closed {
+		return ents, nil
+	}
+	var msgs []*sarama.ProducerMessage
+	for _, ent := range ents {
+		if ent == nil || kf.filter(ent) {
+			continue
+		}
+		msg, err := kf.message(ent)
+		if err != nil {
+			continue //can't encode it, nothing we can do about it
+		}
+		if kf.asyncProd != nil {
+			select {
+			case kf.asyncProd.Input() <- msg:
+			default: //queue is full, fire and forget means exactly that
+			}
+		} else {
+			msgs = append(msgs, msg)
+		}
+	}
+	if len(msgs) > 0 {
+		kf.send(msgs)
+	}
+	return ents, nil
+}
+
+// send delivers messages with the sync producer, retrying failed messages until they are
+// acknowledged, rejected outright, or the forwarder is closed
+func (kf *KafkaForwarder) send(msgs []*sarama.ProducerMessage) {
+	for len(msgs) > 0 {
+		err := kf.producer.SendMessages(msgs)
+		if err == nil {
+			return
+		}
+		var perrs sarama.ProducerErrors
+		if errors.As(err, &perrs) {
+			//only retry the messages that failed
+			msgs = msgs[:0]
+			for _, pe := range perrs {
+				if retriableKafkaError(pe.Err) {
+					msgs = append(msgs, pe.Msg)
+				}
+			}
+		} else if !retriableKafkaError(err) {
+			return
+		}
+		if len(msgs) > 0 && kf.sleep(redialInterval) {
+			return //closed
+		}
+	}
+}
+
+// retriableKafkaError returns false for errors that will never succeed no matter how many times we send
+func retriableKafkaError(err error) bool {
+	var cerr sarama.ConfigurationError
+	if errors.As(err, &cerr) {
+		return false
+	}
+	switch {
+	case errors.Is(err, sarama.ErrMessageSizeTooLarge),
+		errors.Is(err, sarama.ErrInvalidMessage),
+		errors.Is(err, sarama.ErrInvalidTopic),
+		errors.Is(err, sarama.ErrTopicAuthorizationFailed),
+		errors.Is(err, sarama.ErrClosedClient),
+		errors.Is(err, sarama.ErrShuttingDown):
+		return false
+	}
+	return true
+}
+
+func (kf *KafkaForwarder) sleep(d time.Duration) (cancelled bool) {
+	select {
+	case <-kf.ctx.Done():
+		cancelled = true
+	case <-time.After(d):
+	}
+	return
+}
+
+func (kf *KafkaForwarder) message(ent *entry.Entry) (msg *sarama.ProducerMessage, err error) {
+	kf.bb.Reset()
+	if err = kf.enc.Encode(ent); err != nil {
+		return
+	}
+	//the encoders are line oriented, a message doesn't need the trailing newline
+	val := bytes.Clone(bytes.TrimSuffix(kf.bb.Bytes(), []byte("\n")))
+	msg = &sarama.ProducerMessage{
+		Topic:     kf.topic(ent.Tag),
+		Value:     sarama.ByteEncoder(val),
+		Timestamp: ent.TS.StandardTime(),
+	}
+	return
+}
+
+// topic resolves the topic for a tag, falling back to the configured Topic and then the tag name
+func (kf *KafkaForwarder) topic(tag entry.EntryTag) (t string) {
+	var ok bool
+	if t, ok = kf.topics[tag]; ok {
+		return
+	}
+	name := kf.tt.TagName(tag)
+	if t, ok = kf.topicMap[name]; !ok {
+		if t = kf.Topic; t == `` {
+			if t = name; checkKafkaTopic(t) != nil {
+				t = entry.DefaultTagName //tag names allow characters that topics don't
+			}
+		}
+	}
+	kf.topics[tag] = t
+	return
+}
+
+func (kf *KafkaForwarder) Flush() []*entry.Entry {
+	return nil
+}
+
+func (kf *KafkaForwarder) Close() (err error) {
+	kf.cf() //abort any retries
+	kf.Lock()
+	defer kf.Unlock()
+	if kf.closed {
+		return ErrClosed
+	}
+	kf.closed = true
+	if kf.producer != nil {
+		err = kf.producer.Close()
+	} else if kf.asyncProd != nil {
+		//give queued messages a chance to go out, but don't hang on an unreachable broker
+		done := make(chan error, 1)
+		go func(p sarama.AsyncProducer) {
+			done <- p.Close()
+		}(kf.asyncProd)
+		to := time.Duration(kf.Timeout) * time.Second
+		if to == 0 {
+			to = time.Second
+		}
+		select {
+		case err = <-done:
+		case <-time.After(to):
+		}
+	}
+	return
+}
@@ -0,0 +1,241 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package processors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+// mockKafkaProducers swaps in mock producers for the life of a test
+func mockKafkaProducers(t *testing.T) (sp **mocks.SyncProducer, ap **mocks.AsyncProducer) {
+	sp = new(*mocks.SyncProducer)
+	ap = new(*mocks.AsyncProducer)
+	origSync, origAsync := newKafkaSyncProducer, newKafkaAsyncProducer
+	newKafkaSyncProducer = func(addrs []string, cfg *sarama.Config) (sarama.SyncProducer, error) {
+		if cfg.Producer.RequiredAcks != sarama.WaitForAll {
+			t.Fatalf("guaranteed delivery without waiting for acks: %v", cfg.Producer.RequiredAcks)
+		}
+		*sp = mocks.NewSyncProducer(t, cfg)
+		return *sp, nil
+	}
+	newKafkaAsyncProducer = func(addrs []string, cfg *sarama.Config) (sarama.AsyncProducer, error) {
+		*ap = mocks.NewAsyncProducer(t, cfg)
+		return *ap, nil
+	}
+	t.Cleanup(func() {
+		newKafkaSyncProducer, newKafkaAsyncProducer = origSync, origAsync
+	})
+	return
+}
+
+func checkKafkaMessage(topic, val string) mocks.MessageChecker {
+	return func(msg *sarama.ProducerMessage) error {
+		b, err := msg.Value.Encode()
+		if err != nil {
+			return err
+		} else if msg.Topic != topic {
+			return fmt.Errorf("bad topic %q != %q", msg.Topic, topic)
+		} else if string(b) != val {
+			return fmt.Errorf("bad value %q != %q", b, val)
+		}
+		return nil
+	}
+}
+
+func TestKafkaForwarderConfig(t *testing.T) {
+	mockKafkaProducers(t)
+	b := `
+	[preprocessor "kf"]
+		type = kafkaforwarder
+		Leader=127.0.0.1
+		Leader=10.0.0.1:9093
+		Tag-Topic="syslog:logs-syslog"
+		Tag-Topic="json: logs-json"
+		Compression=zstd
+		Batch-Size=100
+		Batch-Interval=250ms
+		Auth-Type=scramsha512
+		Username=user
+		Password=pass
+	`
+	p, err := testLoadPreprocessor(b, `kf`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kf, ok := p.(*KafkaForwarder)
+	if !ok {
+		t.Fatalf("preprocessor is the wrong type: %T != *KafkaForwarder", p)
+	} else if kf.Delivery_Mode != deliveryGuaranteed || kf.Format != encRaw {
+		t.Fatalf("bad defaults: %q %q", kf.Delivery_Mode, kf.Format)
+	} else if kf.topicMap[`json`] != `logs-json` || len(kf.topicMap) != 2 {
+		t.Fatalf("bad topic map: %v", kf.topicMap)
+	}
+	if ldrs := kf.leaders(); len(ldrs) != 2 || ldrs[0] != `127.0.0.1:9092` || ldrs[1] != `10.0.0.1:9093` {
+		t.Fatalf("bad leaders: %v", ldrs)
+	}
+	cfg, err := kf.saramaConfig()
+	if err != nil {
+		t.Fatal(err)
+	} else if cfg.Producer.Compression != sarama.CompressionZSTD || cfg.Producer.Flush.Messages != 100 ||
+		cfg.Producer.Flush.Frequency != 250*time.Millisecond || cfg.Net.SASL.Mechanism != sarama.SASLTypeSCRAMSHA512 {
+		t.Fatalf("bad sarama config: %+v", cfg.Producer)
+	}
+	if err = kf.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := []KafkaForwarderConfig{
+		{},
+		{Leader: []string{`127.0.0.1:99999:1`}},
+		{Leader: []string{`127.0.0.1`}, Topic: `bad topic`},
+		{Leader: []string{`127.0.0.1`}, Tag_Topic: []string{`syslog`}},
+		{Leader: []string{`127.0.0.1`}, Tag_Topic: []string{`syslog:a`, `syslog:b`}},
+		{Leader: []string{`127.0.0.1`}, Format: `xml`},
+		{Leader: []string{`127.0.0.1`}, Delivery_Mode: `maybe`},
+		{Leader: []string{`127.0.0.1`}, Compression: `brotli`},
+		{Leader: []string{`127.0.0.1`}, Batch_Interval: `soon`},
+		{Leader: []string{`127.0.0.1`}, Batch_Size: -1},
+		{Leader: []string{`127.0.0.1`}, Batch_Size: 10, Batch_Interval: `0s`},
+		{Leader: []string{`127.0.0.1`}, KafkaAuthConfig: KafkaAuthConfig{Auth_Type: `plain`}},
+		{Leader: []string{`127.0.0.1`}, Regex: []string{`(`}},
+	}
+	for i, c := range bad {
+		if err := c.Validate(); err == nil {
+			t.Fatalf("Failed to catch bad config %d (%+v)", i, c)
+		}
+	}
+}
+
+func TestKafkaForwarderGuaranteed(t *testing.T) {
+	sp, _ := mockKafkaProducers(t)
+	var tg testTagger
+	syslogTag, _ := tg.NegotiateTag(`syslog`)
+	otherTag, _ := tg.NegotiateTag(`other`)
+	dropTag, _ := tg.NegotiateTag(`dropped`)
+	kf, err := NewKafkaForwarder(KafkaForwarderConfig{
+		Leader:    []string{`127.0.0.1`},
+		Tag_Topic: []string{`syslog:logs-syslog`},
+		Tag:       []string{`syslog`, `other`},
+		Format:    `JSON`,
+	}, &tg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := entry.FromStandard(time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC))
+	ents := []*entry.Entry{
+		{TS: ts, Tag: syslogTag, SRC: net.ParseIP(`10.0.0.1`), Data: []byte(`hello`)},
+		{TS: ts, Tag: dropTag, Data: []byte(`filtered`)},
+		{TS: ts, Tag: otherTag, Data: []byte(`world`)},
+	}
+	jsonOf := func(ent *entry.Entry, tag string) string {
+		b, _ := json.Marshal(tagStringEntry{Entry: ent, Tag: tag})
+		return string(b)
+	}
+	(*sp).ExpectSendMessageWithMessageCheckerFunctionAndSucceed(checkKafkaMessage(`logs-syslog`, jsonOf(ents[0], `syslog`)))
+	(*sp).ExpectSendMessageWithMessageCheckerFunctionAndSucceed(checkKafkaMessage(`other`, jsonOf(ents[2], `other`)))
+	r, err := kf.Process(ents)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(r) != len(ents) {
+		t.Fatal("forwarder did not pass entries through")
+	}
+
+	//transient failures are retried, permanent ones are dropped
+	(*sp).ExpectSendMessageAndFail(sarama.ErrOutOfBrokers)
+	(*sp).ExpectSendMessageWithMessageCheckerFunctionAndSucceed(checkKafkaMessage(`logs-syslog`, jsonOf(ents[0], `syslog`)))
+	if _, err = kf.Process(ents[:1]); err != nil {
+		t.Fatal(err)
+	}
+	(*sp).ExpectSendMessageAndFail(sarama.ErrMessageSizeTooLarge)
+	if _, err = kf.Process(ents[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err = kf.Close(); err != nil {
+		t.Fatal(err)
+	} else if err = kf.Close(); err != ErrClosed {
+		t.Fatalf("bad double close error: %v", err)
+	}
+}
+
+// TestKafkaForwarderShortBatch sends fewer messages than the Batch-Size through a real producer,
+// the batch has to be flushed by the interval or the send never returns
+func TestKafkaForwarderShortBatch(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		`MetadataRequest`: sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader(`other`, 0, broker.BrokerID()),
+		`ProduceRequest`: sarama.NewMockProduceResponse(t).SetVersion(7),
+	})
+	var tg testTagger
+	tag, _ := tg.NegotiateTag(`other`)
+	kf, err := NewKafkaForwarder(KafkaForwarderConfig{
+		Leader:     []string{broker.Addr()},
+		Batch_Size: 100,
+	}, &tg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := kf.Process([]*entry.Entry{
+			{TS: entry.Now(), Tag: tag, Data: []byte(`hello`)},
+			{TS: entry.Now(), Tag: tag, Data: []byte(`world`)},
+		})
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("short batch was never flushed") //the stuck send holds the forwarder lock, so we can't close it
+	}
+	if err = kf.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKafkaForwarderFireAndForget(t *testing.T) {
+	_, ap := mockKafkaProducers(t)
+	var tg testTagger
+	tag, _ := tg.NegotiateTag(`bad:tag`) //not a legal topic
+	kf, err := NewKafkaForwarder(KafkaForwarderConfig{
+		Leader:        []string{`127.0.0.1`},
+		Delivery_Mode: `Fire-And-Forget`,
+		Regex:         []string{`keep`},
+	}, &tg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	(*ap).ExpectInputWithMessageCheckerFunctionAndSucceed(checkKafkaMessage(entry.DefaultTagName, `keep me`))
+	(*ap).ExpectInputWithMessageCheckerFunctionAndSucceed(checkKafkaMessage(entry.DefaultTagName, `keep me too`))
+	ents := []*entry.Entry{
+		{Tag: tag, Data: []byte(`keep me`)},
+		{Tag: tag, Data: []byte(`drop me`)},
+		nil,
+		{Tag: tag, Data: []byte(`keep me too`)},
+	}
+	if _, err = kf.Process(ents); err != nil {
+		t.Fatal(err)
+	}
+	if err = kf.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
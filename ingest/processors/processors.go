@@ -63,6 +63,7 @@ func CheckProcessor(id string) error {
 	case DropProcessor:
 	case ForwarderProcessor:
 	case GravwellForwarderProcessor:
+	case KafkaForwarderProcessor:
 	case GzipProcessor:
 	case JsonArraySplitProcessor:
 	case JsonExtractProcessor:
@@ -131,6 +132,8 @@ func ProcessorLoadConfig(vc *config.VariableConfig) (cfg interface{}, err error)
 		cfg, err = VpcLoadConfig(vc)
 	case GravwellForwarderProcessor:
 		cfg, err = GravwellForwarderLoadConfig(vc)
+	case KafkaForwarderProcessor:
+		cfg, err = KafkaForwarderLoadConfig(vc)
 	case CiscoISEProcessor:
 		cfg, err = CiscoISELoadConfig(vc)
 	case SrcRouterProcessor:
@@ -267,6 +270,12 @@ func newProcessor(vc *config.VariableConfig, tgr Tagger) (p Processor, err error
 			return
 		}
 		p, err = NewGravwellForwarder(cfg, tgr)
+	case KafkaForwarderProcessor:
+		var cfg KafkaForwarderConfig
+		if cfg, err = KafkaForwarderLoadConfig(vc); err != nil {
+			return
+		}
+		p, err = NewKafkaForwarder(cfg, tgr)
 	case CiscoISEProcessor:
 		var cfg CiscoISEConfig
 		if cfg, err = CiscoISELoadConfig(vc); err != nil {
@@ -37,6 +37,7 @@ const (
 	preToken authType = `preshared-token`
 	preParam authType = `preshared-parameter`
 	hdrToken authType = `preshared-header`
+	hmacSig  authType = `hmac-signature`
 
 	userFormValue string = `username`
 	passFormValue string = `password`
@@ -66,6 +67,19 @@ type auth struct {
 	LoginURL   string
 	TokenName  string
 	TokenValue string `json:"-"` // DO NOT send this when marshalling
+
+	//webhook signature verification, TokenValue is the signing secret
+	SignaturePreset    string //github, slack, stripe, zoom, or shopify
+	SignatureHeader    string //header carrying the signature
+	SignatureAlgorithm string //sha1 or sha256
+	SignatureEncoding  string //hex or base64
+	SignaturePrefix    string //prefix stripped from the signature, e.g. sha256=
+	SignatureField     string //key of the signature in a key=value signature header
+	SignaturePayload   string //template of the signed content using {timestamp} and {body}
+	TimestampHeader    string //header carrying the signed timestamp
+	TimestampField     string //key of the timestamp in a key=value signature header
+	TimestampTolerance string //maximum age of a signed timestamp
+	tolerance          time.Duration
 }
 
 type authHandler interface {
@@ -120,6 +134,10 @@ func (a *auth) Validate() (enabled bool, err error) {
 			return
 		}
 		enabled = true
+	case hmacSig:
+		if err = a.validateSignature(); err == nil {
+			enabled = true
+		}
 	}
 	return
 }
@@ -148,6 +166,8 @@ func (a auth) NewAuthHandler(lgr *log.Logger) (url string, hnd authHandler, err
 		hnd, err = newPresharedParamHandler(a.TokenName, a.TokenValue, lgr)
 	case hdrToken:
 		hnd, err = newPresharedHeaderTokenHandler(a.TokenName, a.TokenValue, lgr)
+	case hmacSig:
+		hnd, err = newSignatureHandler(a, lgr)
 	default:
 		err = fmt.Errorf("Unknown authentication type %q", a.AuthType)
 	}
@@ -166,6 +186,7 @@ func parseAuthType(v string) (r authType, err error) {
 	case preToken:
 	case preParam:
 	case hdrToken:
+	case hmacSig:
 	default:
 		r = none
 		err = ErrInvalidAuthType
@@ -16,7 +16,9 @@ import (
 	"net/url"
 	"path"
 	"sort"
+	"strings"
 
+	"github.com/gobwas/glob"
 	"github.com/gravwell/gravwell/v3/ingest"
 	"github.com/gravwell/gravwell/v3/ingest/attach"
 	"github.com/gravwell/gravwell/v3/ingest/config"
@@ -33,6 +35,8 @@ const (
 
 	defaultMaxConnections        = 1024 * 10 // about 10k connections, any modern OS should be able to handle this
 	defaultMaxConcurrentRequests = 1024 * 16 // HTTP2 means concurrent requests on a connection, 16k concurrent request is A LOT
+
+	globChars = `*?[]{}`
 )
 
 type gbl struct {
@@ -47,13 +51,15 @@ type gbl struct {
 }
 
 type cfgReadType struct {
-	Global                   gbl
-	Attach                   attach.AttachConfig
-	Listener                 map[string]*lst
-	HEC_Compatible_Listener  map[string]*hecCompatible
-	Amazon_Firehose_Listener map[string]*afh
-	Preprocessor             processors.ProcessorConfig
-	TimeFormat               config.CustomTimeFormat
+	Global                      gbl
+	Attach                      attach.AttachConfig
+	Listener                    map[string]*lst
+	HEC_Compatible_Listener     map[string]*hecCompatible
+	Amazon_Firehose_Listener    map[string]*afh
+	Elastic_Compatible_Listener map[string]*esCompatible
+	Loki_Compatible_Listener    map[string]*lokiCompatible
+	Preprocessor                processors.ProcessorConfig
+	TimeFormat                  config.CustomTimeFormat
 }
 
 type lst struct {
@@ -68,6 +74,7 @@ type lst struct {
 	Timestamp_Format_Override string //override the timestamp format
 	Attach_URL_Parameter      []string
 	Preprocessor              []string
+	Webhook_Challenge         string //answer the slack, zoom, or okta URL verification handshake
 }
 
 type cfgType struct {
@@ -76,6 +83,8 @@ type cfgType struct {
 	Listener     map[string]*lst
 	HECListener  map[string]*hecCompatible
 	AFHListener  map[string]*afh
+	ESListener   map[string]*esCompatible
+	LokiListener map[string]*lokiCompatible
 	Preprocessor processors.ProcessorConfig
 	TimeFormat   config.CustomTimeFormat
 }
@@ -93,6 +102,8 @@ func GetConfig(path, overlayPath string) (*cfgType, error) {
 		Listener:     cr.Listener,
 		HECListener:  cr.HEC_Compatible_Listener,
 		AFHListener:  cr.Amazon_Firehose_Listener,
+		ESListener:   cr.Elastic_Compatible_Listener,
+		LokiListener: cr.Loki_Compatible_Listener,
 		Preprocessor: cr.Preprocessor,
 		TimeFormat:   cr.TimeFormat,
 	}
@@ -121,7 +132,7 @@ func (c *cfgType) Verify() error {
 		c.Max_Concurrent_Requests = defaultMaxConcurrentRequests
 	}
 	urls := map[route]string{}
-	if len(c.Listener) == 0 && len(c.HECListener) == 0 && len(c.AFHListener) == 0 {
+	if len(c.Listener) == 0 && len(c.HECListener) == 0 && len(c.AFHListener) == 0 && len(c.ESListener) == 0 && len(c.LokiListener) == 0 {
 		return errors.New("No Listeners specified")
 	}
 	if err := c.Preprocessor.Validate(); err != nil {
@@ -151,6 +162,18 @@ func (c *cfgType) Verify() error {
 			}
 			urls[newRoute(http.MethodPost, v.LoginURL)] = k
 		}
+		if v.Webhook_Challenge == `` {
+			v.Webhook_Challenge = v.presetChallenge()
+		}
+		if _, err := newWebhookChallenge(v.Webhook_Challenge, v.auth); err != nil {
+			return fmt.Errorf("Webhook-Challenge for %s is invalid: %v", k, err)
+		} else if cm := challengeMethod(v.Webhook_Challenge); cm != `` && cm != v.Method {
+			crt := newRoute(cm, pth)
+			if orig, ok := urls[crt]; ok {
+				return fmt.Errorf("%s %s duplicated in %s (was in %s)", cm, v.URL, k, orig)
+			}
+			urls[crt] = k
+		}
 
 		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
 			return fmt.Errorf("HTTP Listener %s preprocessor invalid: %v", k, err)
@@ -190,6 +213,37 @@ func (c *cfgType) Verify() error {
 		c.AFHListener[k] = v
 	}
 
+	for k, v := range c.ESListener {
+		if _, err := v.validate(k); err != nil {
+			return err
+		}
+		rt := newRoute(http.MethodPost, v.bulkPath())
+		if orig, ok := urls[rt]; ok {
+			return fmt.Errorf("URL %s duplicated in %s (was in %s)", v.bulkPath(), k, orig)
+		}
+		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("HTTP Elastic-Compatible-Listener %s preprocessor invalid: %v", k, err)
+		}
+		urls[rt] = k
+		c.ESListener[k] = v
+	}
+
+	for k, v := range c.LokiListener {
+		pth, err := v.validate(k)
+		if err != nil {
+			return err
+		}
+		rt := newRoute(http.MethodPost, pth)
+		if orig, ok := urls[rt]; ok {
+			return fmt.Errorf("URL %s duplicated in %s (was in %s)", v.URL, k, orig)
+		}
+		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("HTTP Loki-Compatible-Listener %s preprocessor invalid: %v", k, err)
+		}
+		urls[rt] = k
+		c.LokiListener[k] = v
+	}
+
 	if len(urls) == 0 {
 		return fmt.Errorf("No listeners specified")
 	}
@@ -230,6 +284,32 @@ func (c *cfgType) Tags() (tags []string, err error) {
 			tagMp[v.Tag_Name] = true
 		}
 	}
+	for k, v := range c.ESListener {
+		var ltags []string
+		if ltags, err = v.tags(); err != nil {
+			err = fmt.Errorf("failed to get tags on Elastic-Compatible-Listener %s %w", k, err)
+			return
+		}
+		for _, lt := range ltags {
+			if _, ok := tagMp[lt]; !ok {
+				tags = append(tags, lt)
+				tagMp[lt] = true
+			}
+		}
+	}
+	for k, v := range c.LokiListener {
+		var ltags []string
+		if ltags, err = v.tags(); err != nil {
+			err = fmt.Errorf("failed to get tags on Loki-Compatible-Listener %s %w", k, err)
+			return
+		}
+		for _, lt := range ltags {
+			if _, ok := tagMp[lt]; !ok {
+				tags = append(tags, lt)
+				tagMp[lt] = true
+			}
+		}
+	}
 
 	if len(tags) == 0 {
 		err = errors.New("No tags specified")
@@ -404,8 +484,83 @@ func (pa *paramAttacher) processAll(vals url.Values) {
 	}
 }
 
+// wants returns true if the attacher was configured to attach the named parameter
+func (pa *paramAttacher) wants(name string) bool {
+	for _, p := range pa.params {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (pa *paramAttacher) attach(ent *entry.Entry) {
 	if pa.active && len(pa.exts) > 0 {
 		ent.AddEnumeratedValues(pa.exts)
 	}
 }
+
+type patternTagMatch struct {
+	g   glob.Glob
+	tag entry.EntryTag
+}
+
+// patternTagRouter maps a string value (index name, label value, etc.) to a tag
+// values may be exact matches or glob patterns, exact matches are always checked first
+type patternTagRouter struct {
+	exact    map[string]entry.EntryTag
+	patterns []patternTagMatch
+}
+
+func newPatternTagRouter(tms []tagMatcher, igst *ingest.IngestMuxer) (ptr patternTagRouter, err error) {
+	for _, tm := range tms {
+		var tg entry.EntryTag
+		if tg, err = igst.NegotiateTag(tm.Tag); err != nil {
+			return
+		}
+		if strings.ContainsAny(tm.Value, globChars) {
+			var g glob.Glob
+			if g, err = glob.Compile(tm.Value); err != nil {
+				return
+			}
+			ptr.patterns = append(ptr.patterns, patternTagMatch{g: g, tag: tg})
+		} else {
+			if ptr.exact == nil {
+				ptr.exact = map[string]entry.EntryTag{}
+			}
+			ptr.exact[tm.Value] = tg
+		}
+	}
+	return
+}
+
+func (ptr patternTagRouter) route(v string) (tg entry.EntryTag, ok bool) {
+	if tg, ok = ptr.exact[v]; ok {
+		return
+	}
+	for _, p := range ptr.patterns {
+		if p.g.Match(v) {
+			tg, ok = p.tag, true
+			break
+		}
+	}
+	return
+}
+
+// checkTagPatterns validates a set of Tag-Match specifications that may contain glob patterns
+func checkTagPatterns(vals []string) (tms []tagMatcher, err error) {
+	var tm tagMatcher
+	for _, v := range vals {
+		if tm.Value, tm.Tag, err = extractElementTag(v); err != nil {
+			return
+		}
+		if strings.ContainsAny(tm.Value, globChars) {
+			if _, err = glob.Compile(tm.Value); err != nil {
+				err = fmt.Errorf("Tag-Match %q has an invalid pattern: %w", v, err)
+				return
+			}
+		}
+		tms = append(tms, tm)
+	}
+	return
+}
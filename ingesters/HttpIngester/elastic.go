@@ -0,0 +1,486 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+	"github.com/gravwell/jsonparser"
+)
+
+const (
+	defaultESUrl            = `/`
+	defaultESTimestampField = `@timestamp`
+	defaultESVersion        = `8.11.0`
+	esClusterName           = `gravwell`
+	esProductHeader         = `X-Elastic-Product`
+	esProductName           = `Elasticsearch`
+	esBulkEndpoint          = `_bulk`
+
+	esActionIndex  = `index`
+	esActionCreate = `create`
+	esActionUpdate = `update`
+	esActionDelete = `delete`
+)
+
+type esCompatible struct {
+	auth                             //authentication information
+	URL                       string //base URL, the _bulk endpoints are relative to this, defaults to "/"
+	Tag_Name                  string //the default tag
+	Tag_Match                 []string
+	Timestamp_Field           string //name of the JSON field holding the document timestamp, defaults to @timestamp
+	Ignore_Timestamps         bool
+	Timestamp_Format_Override string
+	Reported_Version          string //version number reported to clients that probe the cluster
+	Preprocessor              []string
+}
+
+func (v *esCompatible) validate(name string) (string, error) {
+	if len(v.URL) == 0 {
+		v.URL = defaultESUrl
+	}
+	p, err := url.Parse(v.URL)
+	if err != nil {
+		return ``, fmt.Errorf("URL structure is invalid: %v", err)
+	}
+	if p.Scheme != `` {
+		return ``, errors.New("May not specify scheme in listening URL")
+	} else if p.Host != `` {
+		return ``, errors.New("May not specify host in listening URL")
+	}
+	if len(v.Tag_Name) == 0 {
+		v.Tag_Name = entry.DefaultTagName
+	}
+	if ingest.CheckTag(v.Tag_Name) != nil {
+		return ``, errors.New("Invalid characters in the \"" + v.Tag_Name + "\"Tag-Name for " + name)
+	}
+	if _, err = checkTagPatterns(v.Tag_Match); err != nil {
+		return ``, fmt.Errorf("Elastic-Compatible-Listener %s has invalid Tag-Match %w", name, err)
+	}
+	if _, err = v.auth.Validate(); err != nil {
+		return ``, fmt.Errorf("Auth for %s is invalid: %v", name, err)
+	} else if v.AuthType == jwtT || v.AuthType == cookie || v.AuthType == hmacSig {
+		return ``, fmt.Errorf("Elastic-Compatible-Listener %s does not support %s authentication", name, v.AuthType)
+	}
+	if v.Timestamp_Field == `` {
+		v.Timestamp_Field = defaultESTimestampField
+	}
+	if v.Reported_Version == `` {
+		v.Reported_Version = defaultESVersion
+	}
+	//normalize the path
+	v.URL = path.Clean(p.Path)
+	return v.URL, nil
+}
+
+// bulkPath is the primary ingest route
+func (v *esCompatible) bulkPath() string {
+	return path.Join(v.URL, esBulkEndpoint)
+}
+
+// indexBulkPath is the route pattern used for the /<index>/_bulk form
+func (v *esCompatible) indexBulkPath() string {
+	return path.Join(v.URL, `*`, esBulkEndpoint)
+}
+
+func (v *esCompatible) tags() (tags []string, err error) {
+	var tms []tagMatcher
+	if tms, err = checkTagPatterns(v.Tag_Match); err != nil {
+		return
+	}
+	tags = []string{v.Tag_Name}
+	for _, tm := range tms {
+		tags = append(tags, tm.Tag)
+	}
+	return
+}
+
+type esHandler struct {
+	name      string
+	bulkPath  string
+	tsField   string
+	tagRouter patternTagRouter
+}
+
+type esBulkMeta struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+type esShards struct {
+	Total      int `json:"total"`
+	Successful int `json:"successful"`
+	Failed     int `json:"failed"`
+}
+
+type esError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+type esBulkItem struct {
+	Index       string    `json:"_index"`
+	ID          string    `json:"_id"`
+	Version     int       `json:"_version,omitempty"`
+	Result      string    `json:"result,omitempty"`
+	Shards      *esShards `json:"_shards,omitempty"`
+	SeqNo       *int      `json:"_seq_no,omitempty"`
+	PrimaryTerm int       `json:"_primary_term,omitempty"`
+	Status      int       `json:"status"`
+	Error       *esError  `json:"error,omitempty"`
+}
+
+type esBulkResponse struct {
+	Took   int64                   `json:"took"`
+	Errors bool                    `json:"errors"`
+	Items  []map[string]esBulkItem `json:"items"`
+}
+
+func (eh *esHandler) handle(h *handler, cfg routeHandler, w http.ResponseWriter, r *http.Request, rdr io.Reader, ip net.IP) {
+	start := time.Now()
+	ll := log.NewLoggerWithKV(h.lgr,
+		log.KV("Elastic-Listener", eh.name),
+		log.KV("remoteaddress", ip.String()),
+		log.KV("url", r.URL.RequestURI()),
+	)
+
+	//default index comes from either the /<index>/_bulk path or an index parameter
+	defIndex := r.URL.Query().Get(`index`)
+	if pth := path.Clean(r.URL.Path); pth != eh.bulkPath {
+		defIndex = path.Base(path.Dir(pth))
+	}
+
+	//the whole request is parsed before anything is ingested, an oversized or malformed
+	//request must not leave a partial ingest behind that the client will retry
+	ops, err := parseBulk(rdr, defIndex, maxBody)
+	if err != nil {
+		var rerr *esRequestError
+		if !errors.As(err, &rerr) {
+			rerr = &esRequestError{code: http.StatusBadRequest, tp: `parse_exception`, reason: err.Error()}
+		}
+		ll.Info("rejected bulk request", log.KV("status", rerr.code), log.KVErr(err))
+		sendESError(w, rerr.code, rerr.tp, rerr.reason)
+		return
+	}
+
+	resp := esBulkResponse{
+		Items: make([]map[string]esBulkItem, 0, len(ops)),
+	}
+	var seq int
+	var ingestErr error
+	for _, op := range ops {
+		item := esBulkItem{
+			Index: op.meta.Index,
+			ID:    op.meta.ID,
+		}
+		switch {
+		case op.action == esActionDelete:
+			//deletes have no source document and we can't delete anything, just tell them it wasn't there
+			item.Result = `not_found`
+			item.Status = http.StatusNotFound
+		case !json.Valid(op.doc):
+			item.Status = http.StatusBadRequest
+			item.Error = &esError{Type: `mapper_parsing_exception`, Reason: `failed to parse`}
+			resp.Errors = true
+		case ingestErr != nil:
+			//once the muxer fails the rest of the documents are rejected individually so the client
+			//only retries the documents we did not take
+			item.Status = http.StatusTooManyRequests
+			item.Error = &esError{Type: `es_rejected_execution_exception`, Reason: `failed to ingest document`}
+			resp.Errors = true
+		default:
+			if ingestErr = h.handleEntryEx(cfg, eh.buildEntry(cfg, op, ip)); ingestErr != nil {
+				ll.Error("failed to send entry", log.KVErr(ingestErr))
+				item.Status = http.StatusTooManyRequests
+				item.Error = &esError{Type: `es_rejected_execution_exception`, Reason: `failed to ingest document`}
+				resp.Errors = true
+				break
+			}
+			sn := seq
+			seq++
+			item.Version = 1
+			item.Result = `created`
+			item.Shards = &esShards{Total: 1, Successful: 1}
+			item.SeqNo = &sn
+			item.PrimaryTerm = 1
+			item.Status = http.StatusCreated
+			if op.action == esActionUpdate {
+				item.Result = `updated`
+				item.Status = http.StatusOK
+			}
+		}
+		resp.Items = append(resp.Items, map[string]esBulkItem{op.action: item})
+	}
+	resp.Took = time.Since(start).Milliseconds()
+	writeESResponse(w, http.StatusOK, resp)
+}
+
+func (eh *esHandler) buildEntry(cfg routeHandler, op esBulkOp, ip net.IP) *entry.Entry {
+	ent := &entry.Entry{
+		TS:   eh.timestamp(cfg, op.doc),
+		SRC:  ip,
+		Tag:  cfg.tag,
+		Data: op.doc,
+	}
+	if op.meta.Index != `` {
+		if tg, ok := eh.tagRouter.route(op.meta.Index); ok {
+			ent.Tag = tg
+		}
+		ent.AddEnumeratedValueEx(`index`, op.meta.Index)
+	}
+	cfg.paramAttacher.attach(ent)
+	return ent
+}
+
+// esBulkOp is a single parsed action from a bulk request, doc is nil for deletes
+type esBulkOp struct {
+	action string
+	meta   esBulkMeta
+	doc    []byte
+}
+
+var errBulkTooLarge = &esRequestError{code: http.StatusRequestEntityTooLarge, tp: `content_too_long_exception`, reason: `request body is too large`}
+
+// esRequestError rejects an entire bulk request
+type esRequestError struct {
+	code   int
+	tp     string
+	reason string
+}
+
+func (e *esRequestError) Error() string {
+	return e.reason
+}
+
+// parseBulk reads and validates every action in a bulk request, the request is rejected
+// as a whole if it is larger than max or structurally malformed
+func parseBulk(rdr io.Reader, defIndex string, max int) (ops []esBulkOp, err error) {
+	lr := &io.LimitedReader{R: rdr, N: int64(max + 1)}
+	brdr := bufio.NewReader(lr)
+	for {
+		var action []byte
+		if action, err = readBulkLine(brdr); err != nil {
+			if err == io.EOF {
+				err = nil
+				if lr.N == 0 {
+					err = errBulkTooLarge
+				}
+			}
+			return
+		} else if len(action) == 0 {
+			continue
+		}
+		var act map[string]esBulkMeta
+		if err = json.Unmarshal(action, &act); err != nil || len(act) != 1 {
+			if lr.N == 0 {
+				err = errBulkTooLarge
+			} else {
+				err = &esRequestError{code: http.StatusBadRequest, tp: `illegal_argument_exception`, reason: `Malformed action/metadata line`}
+			}
+			return
+		}
+		var op esBulkOp
+		for op.action, op.meta = range act {
+		}
+		if op.meta.Index == `` {
+			op.meta.Index = defIndex
+		}
+		switch op.action {
+		case esActionIndex, esActionCreate, esActionUpdate:
+			if op.meta.ID == `` {
+				op.meta.ID = uuid.NewString()
+			}
+			if op.doc, err = readBulkLine(brdr); err != nil || len(op.doc) == 0 {
+				if lr.N == 0 {
+					err = errBulkTooLarge
+				} else {
+					err = &esRequestError{code: http.StatusBadRequest, tp: `illegal_argument_exception`, reason: `The bulk request must be terminated by a newline [\n]`}
+				}
+				return
+			}
+		case esActionDelete:
+		default:
+			err = &esRequestError{code: http.StatusBadRequest, tp: `illegal_argument_exception`,
+				reason: fmt.Sprintf("Malformed action/metadata line, expected one of [create, delete, index, update] but found [%s]", op.action)}
+			return
+		}
+		ops = append(ops, op)
+	}
+}
+
+// timestamp pulls the timestamp from the configured field, falling back to timegrinder on the whole document
+func (eh *esHandler) timestamp(cfg routeHandler, doc []byte) entry.Timestamp {
+	if cfg.ignoreTs || cfg.tg == nil {
+		return entry.Now()
+	}
+	if v, _, _, err := jsonparser.Get(doc, eh.tsField); err == nil && len(v) > 0 {
+		if ts, ok, err := cfg.tg.Extract(v); err == nil && ok {
+			return entry.FromStandard(ts)
+		}
+	}
+	if ts, ok, err := cfg.tg.Extract(doc); err == nil && ok {
+		return entry.FromStandard(ts)
+	}
+	return entry.Now()
+}
+
+// readBulkLine reads a single newline delimited line, the returned buffer is safe to hand to the muxer
+func readBulkLine(brdr *bufio.Reader) (ln []byte, err error) {
+	if ln, err = brdr.ReadBytes('\n'); err == io.EOF && len(ln) > 0 {
+		err = nil
+	}
+	ln = bytes.TrimSpace(ln)
+	return
+}
+
+func writeESResponse(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set(esProductHeader, esProductName)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func sendESError(w http.ResponseWriter, code int, tp, reason string) {
+	type errBody struct {
+		Root   []esError `json:"root_cause"`
+		Type   string    `json:"type"`
+		Reason string    `json:"reason"`
+	}
+	writeESResponse(w, code, struct {
+		Error  errBody `json:"error"`
+		Status int     `json:"status"`
+	}{
+		Error: errBody{
+			Root:   []esError{{Type: tp, Reason: reason}},
+			Type:   tp,
+			Reason: reason,
+		},
+		Status: code,
+	})
+}
+
+// esInfoHandler answers the cluster probes that most shippers send before they start posting bulk requests
+type esInfoHandler struct {
+	version string
+	health  bool
+}
+
+func (ei *esInfoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ei.health {
+		writeESResponse(w, http.StatusOK, map[string]interface{}{
+			"cluster_name": esClusterName,
+			"status":       "green",
+			"timed_out":    false,
+		})
+		return
+	}
+	writeESResponse(w, http.StatusOK, map[string]interface{}{
+		"name":         esClusterName,
+		"cluster_name": esClusterName,
+		"cluster_uuid": esClusterName,
+		"version": map[string]interface{}{
+			"number":                              ei.version,
+			"build_flavor":                        "default",
+			"lucene_version":                      "9.8.0",
+			"minimum_wire_compatibility_version":  "7.17.0",
+			"minimum_index_compatibility_version": "7.0.0",
+		},
+		"tagline": "You Know, for Search",
+	})
+}
+
+func includeESListeners(hnd *handler, igst *ingest.IngestMuxer, cfg *cfgType, lgr *log.Logger) (err error) {
+	for k, v := range cfg.ESListener {
+		eh := &esHandler{
+			name:     k,
+			bulkPath: v.bulkPath(),
+			tsField:  v.Timestamp_Field,
+		}
+		var tms []tagMatcher
+		if tms, err = checkTagPatterns(v.Tag_Match); err != nil {
+			lg.Error("invalid Tag-Match", log.KV("listener", k), log.KVErr(err))
+			return
+		} else if eh.tagRouter, err = newPatternTagRouter(tms, igst); err != nil {
+			lg.Error("failed to negotiate Tag-Match tags", log.KV("listener", k), log.KVErr(err))
+			return
+		}
+		hcfg := routeHandler{
+			handler: eh.handle,
+		}
+		if hcfg.tag, err = igst.NegotiateTag(v.Tag_Name); err != nil {
+			lg.Error("failed to pull tag", log.KV("tag", v.Tag_Name), log.KVErr(err))
+			return
+		}
+		if v.Ignore_Timestamps {
+			hcfg.ignoreTs = true
+		} else {
+			if hcfg.tg, err = timegrinder.New(timegrinder.Config{}); err != nil {
+				lg.Error("Failed to create timegrinder", log.KVErr(err))
+				return
+			} else if err = cfg.TimeFormat.LoadFormats(hcfg.tg); err != nil {
+				lg.Error("failed to load custom time formats", log.KVErr(err))
+				return
+			}
+			if v.Timestamp_Format_Override != `` {
+				if err = hcfg.tg.SetFormatOverride(v.Timestamp_Format_Override); err != nil {
+					lg.Error("Failed to set override timestamp", log.KVErr(err))
+					return
+				}
+			}
+		}
+		if hcfg.pproc, err = cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor); err != nil {
+			lg.Error("preprocessor construction error", log.KVErr(err))
+			return
+		}
+		if _, hcfg.auth, err = v.NewAuthHandler(lgr); err != nil {
+			lg.Error("failed to get a new authentication handler", log.KVErr(err))
+			return
+		}
+
+		// bulk requests may come in as POST or PUT on either the root or an index
+		for _, method := range []string{http.MethodPost, http.MethodPut} {
+			if err = hnd.addHandler(method, v.bulkPath(), hcfg); err != nil {
+				lg.Error("failed to add Elastic-Compatible-Listener handler", log.KVErr(err))
+				return
+			} else if err = hnd.addPatternHandler(method, v.indexBulkPath(), hcfg); err != nil {
+				lg.Error("failed to add Elastic-Compatible-Listener handler", log.KVErr(err))
+				return
+			}
+		}
+		// add the cluster info and health handlers that clients use to probe the "cluster"
+		info := &esInfoHandler{version: v.Reported_Version}
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			if err = hnd.addCustomHandler(method, v.URL, info); err != nil {
+				lg.Error("failed to add Elastic-Compatible-Listener info handler", log.KVErr(err))
+				return
+			}
+		}
+		if err = hnd.addCustomHandler(http.MethodGet, path.Join(v.URL, `_cluster`, `health`), &esInfoHandler{health: true}); err != nil {
+			lg.Error("failed to add Elastic-Compatible-Listener health handler", log.KVErr(err))
+			return
+		}
+		debugout("Elastic Handler URL %s handling %s\n", v.bulkPath(), v.Tag_Name)
+	}
+	return
+}
@@ -0,0 +1,108 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseBulk(t *testing.T) {
+	type op struct {
+		action, index, id, doc string
+	}
+	tests := []struct {
+		name string
+		body string
+		want []op
+	}{
+		{`empty`, ``, nil},
+		{`blank lines`, "\n\n  \n", nil},
+		{
+			name: `index`,
+			body: "{\"index\":{\"_index\":\"foo\",\"_id\":\"1\"}}\n{\"a\":1}\n",
+			want: []op{{esActionIndex, `foo`, `1`, `{"a":1}`}},
+		},
+		{
+			name: `default index`,
+			body: "{\"create\":{}}\n{\"a\":1}\n{\"update\":{\"_index\":\"bar\",\"_id\":\"2\"}}\n{\"doc\":{\"a\":2}}\n",
+			want: []op{{esActionCreate, `def`, ``, `{"a":1}`}, {esActionUpdate, `bar`, `2`, `{"doc":{"a":2}}`}},
+		},
+		{
+			name: `delete has no doc`,
+			body: "{\"delete\":{\"_index\":\"foo\",\"_id\":\"1\"}}\n{\"index\":{\"_id\":\"2\"}}\n{\"b\":2}\n",
+			want: []op{{esActionDelete, `foo`, `1`, ``}, {esActionIndex, `def`, `2`, `{"b":2}`}},
+		},
+		{
+			name: `no trailing newline`,
+			body: "{\"index\":{\"_id\":\"1\"}}\r\n  {\"a\":1}  ",
+			want: []op{{esActionIndex, `def`, `1`, `{"a":1}`}},
+		},
+		{
+			//invalid documents are rejected per item by the handler, not by the parser
+			name: `invalid doc`,
+			body: "{\"index\":{\"_id\":\"1\"}}\nnot json\n",
+			want: []op{{esActionIndex, `def`, `1`, `not json`}},
+		},
+	}
+	for _, tt := range tests {
+		ops, err := parseBulk(strings.NewReader(tt.body), `def`, 1024)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		} else if len(ops) != len(tt.want) {
+			t.Fatalf("%s: bad op count %d != %d", tt.name, len(ops), len(tt.want))
+		}
+		for i, w := range tt.want {
+			o := ops[i]
+			if o.action != w.action || o.meta.Index != w.index || string(o.doc) != w.doc {
+				t.Fatalf("%s: bad op %d %+v != %+v", tt.name, i, o, w)
+			} else if w.id != `` && o.meta.ID != w.id {
+				t.Fatalf("%s: bad id %q != %q", tt.name, o.meta.ID, w.id)
+			} else if o.meta.ID == `` {
+				t.Fatalf("%s: no id generated for op %d", tt.name, i)
+			}
+		}
+	}
+}
+
+func TestParseBulkErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+		tp   string
+	}{
+		{`not json`, "index\n{}\n", http.StatusBadRequest, `illegal_argument_exception`},
+		{`two actions`, "{\"index\":{},\"create\":{}}\n{}\n", http.StatusBadRequest, `illegal_argument_exception`},
+		{`no action`, "{}\n{}\n", http.StatusBadRequest, `illegal_argument_exception`},
+		{`bad meta`, "{\"index\":{\"_index\":5}}\n{}\n", http.StatusBadRequest, `illegal_argument_exception`},
+		{`missing doc`, "{\"index\":{}}\n", http.StatusBadRequest, `illegal_argument_exception`},
+		{`empty doc`, "{\"index\":{}}\n\n{\"a\":1}\n", http.StatusBadRequest, `illegal_argument_exception`},
+		{`unknown action`, "{\"upsert\":{}}\n{}\n", http.StatusBadRequest, `illegal_argument_exception`},
+		{`too large`, "{\"index\":{}}\n{\"a\":\"" + strings.Repeat("x", 64) + "\"}\n", http.StatusRequestEntityTooLarge, `content_too_long_exception`},
+		{`too large at action`, strings.Repeat("{\"delete\":{}}\n", 8), http.StatusRequestEntityTooLarge, `content_too_long_exception`},
+	}
+	for _, tt := range tests {
+		ops, err := parseBulk(strings.NewReader(tt.body), ``, 64)
+		var rerr *esRequestError
+		if !errors.As(err, &rerr) {
+			t.Fatalf("%s: bad error %v %v", tt.name, ops, err)
+		} else if rerr.code != tt.code || rerr.tp != tt.tp {
+			t.Fatalf("%s: bad error %d %s != %d %s", tt.name, rerr.code, rerr.tp, tt.code, tt.tp)
+		}
+	}
+
+	//a request of exactly the limit is fine
+	body := "{\"delete\":{}}\n"
+	if ops, err := parseBulk(strings.NewReader(body), ``, len(body)); err != nil || len(ops) != 1 {
+		t.Fatalf("request at limit rejected %v %v", ops, err)
+	}
+}
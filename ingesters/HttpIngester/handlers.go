@@ -47,6 +47,7 @@ type routeHandler struct {
 	tg            *timegrinder.TimeGrinder
 	handler       handleFunc
 	auth          authHandler
+	challenge     webhookChallenge
 	pproc         *processors.ProcessorSet
 	paramAttacher paramAttacher
 }
@@ -61,6 +62,7 @@ type handler struct {
 	mp                    map[route]routeHandler
 	auth                  map[route]authHandler
 	custom                map[route]http.Handler
+	patterns              map[route]routeHandler // routes containing path wildcards, checked after exact matches
 	rawLineBreaker        string
 	healthCheckURL        string
 	maxConcurrentRequests int64
@@ -89,6 +91,7 @@ func newHandler(igst *ingest.IngestMuxer, lgr *log.Logger, reqSI, entSI, bytesSI
 			mp:                    map[route]routeHandler{},
 			auth:                  map[route]authHandler{},
 			custom:                map[route]http.Handler{},
+			patterns:              map[route]routeHandler{},
 			igst:                  igst,
 			lgr:                   lgr,
 			reqSI:                 reqSI,
@@ -119,6 +122,10 @@ func (h *handler) checkConflict(r route) error {
 	if _, ok := h.custom[r]; ok {
 		return errors.New("route conflicts with custom handler")
 	}
+	//check pattern handlers
+	if _, ok := h.patterns[r]; ok {
+		return errors.New("route conflicts with pattern handler")
+	}
 	return nil
 }
 
@@ -156,6 +163,36 @@ func (h *handler) addCustomHandler(method, pth string, ah http.Handler) (err err
 	return
 }
 
+// addPatternHandler adds a handler for a route whose path contains path.Match wildcards
+// pattern routes are only checked when there is no exact match for a request
+func (h *handler) addPatternHandler(method, pth string, cfg routeHandler) (err error) {
+	r := newRoute(method, pth)
+	if _, err = path.Match(r.uri, ``); err != nil {
+		return
+	}
+	//check if there is a conflict
+	if err = h.checkConflict(r); err == nil {
+		h.Lock()
+		h.patterns[r] = cfg
+		h.Unlock()
+	}
+	return
+}
+
+// lookupPattern attempts to match a route against the pattern handlers, the caller must hold the lock
+func (h *handler) lookupPattern(rt route) (rh routeHandler, ok bool) {
+	for k, v := range h.patterns {
+		if k.method != rt.method {
+			continue
+		}
+		if matched, err := path.Match(k.uri, rt.uri); err == nil && matched {
+			rh, ok = v, true
+			return
+		}
+	}
+	return
+}
+
 type ew struct {
 }
 
@@ -230,13 +267,6 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		}(w, r)
 	}
 	ip := getRemoteIP(r)
-	rdr, err := getReadableBody(r)
-	if err != nil {
-		h.lgr.Error("failed to get body reader", log.KV("address", ip), log.KVErr(err))
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	defer rdr.Close()
 
 	if r.ProtoMajor == 1 {
 		//we are in HTTP 1.X, we may need to set keep alives for stupid clients
@@ -274,6 +304,9 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 
 	//not an auth, try the actual post URL
 	rh, ok := h.mp[rt]
+	if !ok {
+		rh, ok = h.lookupPattern(rt)
+	}
 	h.RUnlock()
 	debugout("LOOKUP UP ROUTE: %s %s\n", rt.method, rt.uri)
 	if !ok {
@@ -293,10 +326,30 @@ func (h *handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	if rh.challenge != nil {
+		if ok, err := rh.challenge.Respond(w, r); err != nil {
+			h.lgr.Info("bad webhook challenge", log.KV("address", ip), log.KV("url", rt.uri), log.KVErr(err))
+			if !ok {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			return
+		} else if ok {
+			h.lgr.Info("answered webhook challenge", log.KV("address", ip), log.KV("url", rt.uri))
+			return
+		}
+	}
 	if h.igst.WillBlock() {
 		w.WriteHeader(http.StatusInsufficientStorage)
 		return
 	}
+	//the body reader is set up after authentication, signature verification must see the raw body
+	rdr, err := getReadableBody(r)
+	if err != nil {
+		h.lgr.Error("failed to get body reader", log.KV("address", ip), log.KVErr(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer rdr.Close()
 	rh.handle(h, w, r, rdr, ip)
 }
 func (h *handler) handleEntry(cfg routeHandler, b []byte, ip net.IP, tag entry.EntryTag) (err error) {
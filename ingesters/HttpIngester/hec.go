@@ -20,7 +20,6 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/crewjam/rfc5424"
@@ -45,8 +44,7 @@ type hecHandler struct {
 	hecHealth
 	auth           *hecAuthHandler
 	name           string
-	ackLock        sync.Mutex
-	ackIds         map[string]uint64
+	acks           *ackTracker
 	tagRouter      map[string]entry.EntryTag
 	tokenRouter    map[string]entry.EntryTag
 	rawLineBreaker string
@@ -181,6 +179,12 @@ func (hh *hecHandler) handle(h *handler, cfg routeHandler, w http.ResponseWriter
 		tgo = override
 		defaultTag = tg
 	}
+	doAck, ch := ackRequested(r)
+	if doAck && !hh.acks.available(ch) {
+		ll.Warn("too many pending acks on channel", log.KV("channel", ch))
+		hh.respServerBusy(w)
+		return
+	}
 
 	dec, err := utils.NewJsonLimitedDecoder(rdr, int64(maxBody+256)) //give some slack for the extra splunk garbage
 	if err != nil {
@@ -277,8 +281,12 @@ loop:
 		hh.respNoData(w)
 		return
 	}
-	if doAck, ch := ackRequested(r); doAck {
-		hh.setAck(ch, resp)
+	if doAck {
+		if resp, err = hh.setAck(ch, resp); err != nil {
+			ll.Warn("failed to issue ack", log.KV("channel", ch), log.KVErr(err))
+			hh.respServerBusy(w)
+			return
+		}
 	}
 
 	hh.writeResponse(w, resp)
@@ -297,14 +305,14 @@ loop:
 
 }
 
-func (hh *hecHandler) setAck(channel string, resp ack) {
-	hh.ackLock.Lock()
-	if _, ok := hh.ackIds[channel]; !ok {
-		hh.ackIds[channel] = 0
+// setAck issues an ack ID on the channel and attaches it to the response
+// the ack will not resolve to true until the muxer has synced the entries from this request
+func (hh *hecHandler) setAck(channel string, resp ack) (ack, error) {
+	id, err := hh.acks.issue(channel)
+	if err == nil {
+		resp.ID = &id
 	}
-	val := hh.ackIds[channel] + 1
-	hh.ackIds[channel] = val
-	hh.ackLock.Unlock()
+	return resp, err
 }
 
 func (hh *hecHandler) writeResponse(w http.ResponseWriter, resp ack) {
@@ -324,6 +332,12 @@ func (hh *hecHandler) respInternalServerError(w http.ResponseWriter) {
 	json.NewEncoder(w).Encode(ack{Code: 8, Text: "Internal server error"})
 }
 
+func (hh *hecHandler) respServerBusy(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(ack{Code: 9, Text: "Server is busy"})
+}
+
 func (hh *hecHandler) respInvalidDataFormat(w http.ResponseWriter, index int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusBadRequest)
@@ -356,6 +370,12 @@ func (hh *hecHandler) handleRaw(h *handler, cfg routeHandler, w http.ResponseWri
 		tgo = override
 		defaultTag = tg
 	}
+	doAck, ch := ackRequested(r)
+	if doAck && !hh.acks.available(ch) {
+		ll.Warn("too many pending acks on channel", log.KV("channel", ch))
+		hh.respServerBusy(w)
+		return
+	}
 
 	brdr := bufio.NewReader(rdr)
 	var done bool
@@ -388,8 +408,13 @@ func (hh *hecHandler) handleRaw(h *handler, cfg routeHandler, w http.ResponseWri
 		hh.respNoData(w)
 		return
 	}
-	if doAck, ch := ackRequested(r); doAck {
-		hh.setAck(ch, resp)
+	if doAck {
+		var err error
+		if resp, err = hh.setAck(ch, resp); err != nil {
+			ll.Warn("failed to issue ack", log.KV("channel", ch), log.KVErr(err))
+			hh.respServerBusy(w)
+			return
+		}
 	}
 	hh.writeResponse(w, resp)
 	if hh.debugPosts {
@@ -414,15 +439,14 @@ func (hh *hecHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	// Figure out which channel
 	_, ch := ackRequested(r)
-	hh.ackLock.Lock()
-	curr := hh.ackIds[ch]
-	hh.ackLock.Unlock()
+	status := hh.acks.query(ch, arq.IDs)
 	resp := ackResp{
-		IDs: make(map[string]bool, len(arq.IDs)),
+		IDs: make(map[string]bool, len(status)),
 	}
-	for _, id := range arq.IDs {
-		resp.IDs[strconv.FormatUint(id, 10)] = id <= curr
+	for id, durable := range status {
+		resp.IDs[strconv.FormatUint(id, 10)] = durable
 	}
+	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
@@ -444,10 +468,10 @@ func (hh *hecHealth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 type ack struct {
-	Text               string `json:"text"`
-	Code               int    `json:"code"`
-	InvalidEventNumber int    `json:"invalid-event-number"`
-	ID                 string `json:"ackID"`
+	Text               string  `json:"text"`
+	Code               int     `json:"code"`
+	InvalidEventNumber int     `json:"invalid-event-number"`
+	ID                 *uint64 `json:"ackId,omitempty"`
 }
 
 type ackReq struct {
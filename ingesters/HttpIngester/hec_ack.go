@@ -0,0 +1,228 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	defaultMaxPendingAcks  int           = 10000
+	defaultAckTimeout      time.Duration = 10 * time.Minute
+	defaultAckSyncInterval time.Duration = 500 * time.Millisecond
+	defaultAckSyncTimeout  time.Duration = 10 * time.Second
+)
+
+var (
+	ErrTooManyPendingAcks = errors.New("too many pending acks on channel")
+)
+
+// syncer is the subset of the ingest muxer that the ack tracker needs, any time a sync completes
+// every entry handed to the muxer prior to the call to Sync is known to be durable.
+type syncer interface {
+	SyncContext(context.Context, time.Duration) error
+}
+
+// ackTracker hands out HEC ack IDs per channel and only resolves them once a muxer sync
+// that started after the ack was issued completes successfully.
+// Acks that are never made durable or never queried are expired after the timeout.
+type ackTracker struct {
+	sync.Mutex
+	igst       syncer
+	lgr        *log.KVLogger
+	maxPending int
+	timeout    time.Duration
+	seq        uint64 // monotonic sequence across all channels, used to figure out what a sync covers
+	pending    int    // total number of acks waiting on a sync
+	channels   map[string]*ackChannel
+	kick       chan struct{}
+}
+
+type ackChannel struct {
+	next     uint64
+	lastUsed time.Time
+	pending  map[uint64]pendingAck // ack ids waiting for a sync
+	durable  map[uint64]time.Time  // ack ids that have been synced but not queried
+}
+
+type pendingAck struct {
+	seq    uint64
+	issued time.Time
+}
+
+func newAckTracker(igst syncer, lgr *log.KVLogger, maxPending int, timeout time.Duration) *ackTracker {
+	if maxPending <= 0 {
+		maxPending = defaultMaxPendingAcks
+	}
+	if timeout <= 0 {
+		timeout = defaultAckTimeout
+	}
+	return &ackTracker{
+		igst:       igst,
+		lgr:        lgr,
+		maxPending: maxPending,
+		timeout:    timeout,
+		channels:   map[string]*ackChannel{},
+		kick:       make(chan struct{}, 1),
+	}
+}
+
+// available returns true if the channel can accept another ack.
+// This is checked prior to handling a request so that we don't ingest data we can't acknowledge.
+func (at *ackTracker) available(channel string) (ok bool) {
+	at.Lock()
+	if ac, found := at.channels[channel]; !found {
+		ok = true
+	} else {
+		ok = (len(ac.pending) + len(ac.durable)) < at.maxPending
+	}
+	at.Unlock()
+	return
+}
+
+// issue generates a new ack ID for the channel, the caller MUST have already handed every
+// entry associated with the request to the muxer.
+func (at *ackTracker) issue(channel string) (id uint64, err error) {
+	now := time.Now()
+	at.Lock()
+	ac, ok := at.channels[channel]
+	if !ok {
+		ac = &ackChannel{
+			pending: map[uint64]pendingAck{},
+			durable: map[uint64]time.Time{},
+		}
+		at.channels[channel] = ac
+	}
+	if (len(ac.pending) + len(ac.durable)) >= at.maxPending {
+		err = ErrTooManyPendingAcks
+	} else {
+		at.seq++
+		id = ac.next
+		ac.next++
+		ac.lastUsed = now
+		ac.pending[id] = pendingAck{seq: at.seq, issued: now}
+		at.pending++
+	}
+	at.Unlock()
+	if err == nil {
+		//let the sync routine know there is work to do, never block
+		select {
+		case at.kick <- struct{}{}:
+		default:
+		}
+	}
+	return
+}
+
+// query returns the durable status of a set of ack IDs on a channel.
+// Acks that are reported as durable are released, this matches the HEC behavior.
+func (at *ackTracker) query(channel string, ids []uint64) (r map[uint64]bool) {
+	r = make(map[uint64]bool, len(ids))
+	at.Lock()
+	ac, ok := at.channels[channel]
+	if ok {
+		ac.lastUsed = time.Now()
+	}
+	for _, id := range ids {
+		if ok {
+			if _, durable := ac.durable[id]; durable {
+				delete(ac.durable, id)
+				r[id] = true
+				continue
+			}
+		}
+		r[id] = false
+	}
+	at.Unlock()
+	return
+}
+
+// routine periodically syncs the muxer while there are pending acks and expires stale acks and channels.
+func (at *ackTracker) routine(ctx context.Context) {
+	tckr := time.NewTicker(defaultAckSyncInterval)
+	defer tckr.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-at.kick:
+		case <-tckr.C:
+			at.expire(time.Now())
+		}
+		if err := at.syncPending(ctx); err != nil && ctx.Err() == nil {
+			at.lgr.Warn("failed to sync muxer for HEC acks", log.KVErr(err))
+			//backoff a little so we don't hammer a muxer that has no hot connections
+			select {
+			case <-ctx.Done():
+				return
+			case <-tckr.C:
+			}
+		}
+	}
+}
+
+// syncPending syncs the muxer and promotes every ack issued before the sync started to durable.
+func (at *ackTracker) syncPending(ctx context.Context) (err error) {
+	at.Lock()
+	hwm := at.seq
+	cnt := at.pending
+	at.Unlock()
+	if cnt == 0 {
+		return
+	}
+	if err = at.igst.SyncContext(ctx, defaultAckSyncTimeout); err != nil {
+		return
+	}
+	now := time.Now()
+	at.Lock()
+	for _, ac := range at.channels {
+		for id, pa := range ac.pending {
+			if pa.seq <= hwm {
+				delete(ac.pending, id)
+				ac.durable[id] = now
+				at.pending--
+			}
+		}
+	}
+	at.Unlock()
+	return
+}
+
+// expire removes acks that have outlived the timeout, both those that never became durable
+// and those that were never queried, and drops idle channels.
+func (at *ackTracker) expire(now time.Time) {
+	var dropped int
+	at.Lock()
+	for name, ac := range at.channels {
+		for id, pa := range ac.pending {
+			if now.Sub(pa.issued) > at.timeout {
+				delete(ac.pending, id)
+				at.pending--
+				dropped++
+			}
+		}
+		for id, ts := range ac.durable {
+			if now.Sub(ts) > at.timeout {
+				delete(ac.durable, id)
+			}
+		}
+		if len(ac.pending) == 0 && len(ac.durable) == 0 && now.Sub(ac.lastUsed) > at.timeout {
+			delete(at.channels, name)
+		}
+	}
+	at.Unlock()
+	if dropped > 0 {
+		at.lgr.Warn("expired HEC acks that were never synced", log.KV("count", dropped))
+	}
+}
@@ -0,0 +1,206 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+// testSyncer is a fake muxer whose syncs can be made to fail or block
+type testSyncer struct {
+	sync.Mutex
+	calls int
+	err   error
+	hook  func() // called while a sync is in progress
+}
+
+func (ts *testSyncer) SyncContext(ctx context.Context, to time.Duration) error {
+	ts.Lock()
+	ts.calls++
+	err, hook := ts.err, ts.hook
+	ts.Unlock()
+	if hook != nil {
+		hook()
+	}
+	return err
+}
+
+func newTestAckTracker(ts *testSyncer, maxPending int, timeout time.Duration) *ackTracker {
+	return newAckTracker(ts, log.NewLoggerWithKV(log.NewDiscardLogger()), maxPending, timeout)
+}
+
+func TestAckIssue(t *testing.T) {
+	at := newTestAckTracker(&testSyncer{}, 0, 0)
+	if at.maxPending != defaultMaxPendingAcks || at.timeout != defaultAckTimeout {
+		t.Fatalf("bad defaults %d %v", at.maxPending, at.timeout)
+	}
+	//ack ids are sequential per channel
+	for i := uint64(0); i < 3; i++ {
+		if id, err := at.issue(`a`); err != nil || id != i {
+			t.Fatalf("bad id %d != %d %v", id, i, err)
+		}
+	}
+	if id, err := at.issue(`b`); err != nil || id != 0 {
+		t.Fatalf("bad id on new channel %d %v", id, err)
+	}
+	if at.pending != 4 {
+		t.Fatalf("bad pending count %d", at.pending)
+	}
+	//nothing is durable before a sync
+	if r := at.query(`a`, []uint64{0, 1, 2}); r[0] || r[1] || r[2] {
+		t.Fatalf("acks reported durable before sync: %v", r)
+	}
+	//unknown channels and ids are never durable
+	if r := at.query(`nope`, []uint64{0}); len(r) != 1 || r[0] {
+		t.Fatalf("bad unknown channel query: %v", r)
+	}
+}
+
+func TestAckSync(t *testing.T) {
+	ts := &testSyncer{}
+	at := newTestAckTracker(ts, 0, 0)
+	ctx := context.Background()
+
+	//no pending acks means no sync
+	if err := at.syncPending(ctx); err != nil || ts.calls != 0 {
+		t.Fatalf("synced with nothing pending %d %v", ts.calls, err)
+	}
+	at.issue(`a`)
+	at.issue(`a`)
+	//an ack issued while the sync is in flight is not covered by it
+	ts.hook = func() {
+		ts.hook = nil
+		if id, err := at.issue(`a`); err != nil || id != 2 {
+			t.Errorf("bad id during sync %d %v", id, err)
+		}
+	}
+	if err := at.syncPending(ctx); err != nil || ts.calls != 1 {
+		t.Fatalf("bad sync %d %v", ts.calls, err)
+	}
+	if r := at.query(`a`, []uint64{0, 1, 2}); !r[0] || !r[1] || r[2] {
+		t.Fatalf("bad ack status after sync: %v", r)
+	}
+	//durable acks are released once they are reported
+	if r := at.query(`a`, []uint64{0, 1}); r[0] || r[1] {
+		t.Fatalf("acks were not released after query: %v", r)
+	}
+	if err := at.syncPending(ctx); err != nil {
+		t.Fatal(err)
+	} else if r := at.query(`a`, []uint64{2}); !r[2] {
+		t.Fatalf("late ack not durable after second sync: %v", r)
+	} else if at.pending != 0 {
+		t.Fatalf("bad pending count %d", at.pending)
+	}
+}
+
+func TestAckSyncFailure(t *testing.T) {
+	ts := &testSyncer{err: errors.New("no hot connections")}
+	at := newTestAckTracker(ts, 0, 0)
+	ctx := context.Background()
+	at.issue(`a`)
+	if err := at.syncPending(ctx); err == nil {
+		t.Fatal("sync failure was not returned")
+	}
+	//a failed sync must not promote anything
+	if r := at.query(`a`, []uint64{0}); r[0] {
+		t.Fatal("ack promoted by failed sync")
+	} else if at.pending != 1 {
+		t.Fatalf("bad pending count %d", at.pending)
+	}
+	ts.err = nil
+	if err := at.syncPending(ctx); err != nil {
+		t.Fatal(err)
+	} else if r := at.query(`a`, []uint64{0}); !r[0] {
+		t.Fatal("ack not promoted after recovery")
+	}
+}
+
+func TestAckLimit(t *testing.T) {
+	at := newTestAckTracker(&testSyncer{}, 2, 0)
+	at.issue(`a`)
+	at.issue(`a`)
+	if at.available(`a`) {
+		t.Fatal("full channel reported available")
+	} else if _, err := at.issue(`a`); err != ErrTooManyPendingAcks {
+		t.Fatalf("bad error on full channel %v", err)
+	} else if !at.available(`b`) {
+		t.Fatal("limit is not per channel")
+	}
+	//durable but unqueried acks still count against the limit
+	if err := at.syncPending(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if at.available(`a`) {
+		t.Fatal("unqueried durable acks were not counted")
+	}
+	at.query(`a`, []uint64{0})
+	if !at.available(`a`) {
+		t.Fatal("channel not available after query")
+	} else if id, err := at.issue(`a`); err != nil || id != 2 {
+		t.Fatalf("bad id after release %d %v", id, err)
+	}
+}
+
+func TestAckExpire(t *testing.T) {
+	ts := &testSyncer{}
+	at := newTestAckTracker(ts, 0, time.Minute)
+	at.issue(`pending`)
+	at.issue(`durable`)
+	ts.hook = func() {
+		ts.hook = nil
+		at.issue(`pending`)
+	}
+	if err := at.syncPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	//the durable channel has a synced ack, the pending channel has one synced and one not
+	now := time.Now()
+	at.expire(now)
+	if len(at.channels) != 2 || at.pending != 1 {
+		t.Fatalf("expired too early %d %d", len(at.channels), at.pending)
+	}
+	at.expire(now.Add(2 * time.Minute))
+	if at.pending != 0 {
+		t.Fatalf("unsynced ack was not expired %d", at.pending)
+	} else if len(at.channels) != 0 {
+		t.Fatalf("idle channels were not dropped %d", len(at.channels))
+	}
+	if r := at.query(`durable`, []uint64{0}); r[0] {
+		t.Fatal("expired durable ack was reported")
+	}
+}
+
+func TestAckRoutine(t *testing.T) {
+	ts := &testSyncer{}
+	at := newTestAckTracker(ts, 0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		at.routine(ctx)
+		close(done)
+	}()
+	at.issue(`a`)
+	//issuing kicks the routine, it should not have to wait for the ticker
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if r := at.query(`a`, []uint64{0}); r[0] {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("routine never synced the pending ack")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
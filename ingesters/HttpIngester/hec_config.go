@@ -16,6 +16,7 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/gravwell/gravwell/v3/ingest"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
@@ -40,6 +41,8 @@ type hecCompatible struct {
 	Ignore_Timestamps         bool
 	Timestamp_Format_Override string //override the timestamp format (only used for raw)
 	Ack                       bool
+	Max_Pending_Acks          int    // maximum number of outstanding acks per channel
+	Ack_Timeout               string // how long an ack may remain unsynced or unqueried before it is dropped
 	Max_Size                  int
 	Debug_Posts               bool // whether we are going to log on the gravwell tag about posts
 	Attach_URL_Parameter      []string
@@ -87,11 +90,27 @@ func (v *hecCompatible) validate(name string) (string, error) {
 		return ``, fmt.Errorf("HEC-Compatible-Listener %s has an invalid tag in Routed-Token-Value: %w", name, err)
 	}
 
+	if v.Max_Pending_Acks < 0 {
+		return ``, fmt.Errorf("HEC-Compatible-Listener %s has an invalid Max-Pending-Acks %d", name, v.Max_Pending_Acks)
+	}
+	if _, err = v.ackTimeout(); err != nil {
+		return ``, fmt.Errorf("HEC-Compatible-Listener %s has an invalid Ack-Timeout %w", name, err)
+	}
+
 	//normalize the path
 	v.URL = pth
 	return pth, nil
 }
 
+func (h *hecCompatible) ackTimeout() (d time.Duration, err error) {
+	if h.Ack_Timeout == `` {
+		d = defaultAckTimeout
+	} else if d, err = time.ParseDuration(h.Ack_Timeout); err == nil && d <= 0 {
+		err = fmt.Errorf("%q must be a positive duration", h.Ack_Timeout)
+	}
+	return
+}
+
 type tagMatcher struct {
 	Value string
 	Tag   string
@@ -221,8 +240,13 @@ func extractElementTag(v string) (match, tag string, err error) {
 
 func includeHecListeners(hnd *handler, igst *ingest.IngestMuxer, cfg *cfgType, lgr *log.Logger) (err error) {
 	for k, v := range cfg.HECListener {
+		var ackTimeout time.Duration
+		if ackTimeout, err = v.ackTimeout(); err != nil {
+			lg.Error("invalid HEC ack timeout", log.KVErr(err))
+			return
+		}
 		hh := &hecHandler{
-			ackIds: map[string]uint64{},
+			acks: newAckTracker(igst, log.NewLoggerWithKV(lgr, log.KV("HEC-Listener", k)), v.Max_Pending_Acks, ackTimeout),
 			hecHealth: hecHealth{
 				igst:  hnd.igst,
 				token: v.TokenValue,
@@ -299,6 +323,8 @@ func includeHecListeners(hnd *handler, igst *ingest.IngestMuxer, cfg *cfgType, l
 			return
 		}
 
+		go hh.acks.routine(exitCtx)
+
 		debugout("HEC Handler URL %s handling %s\n", v.URL, v.Tag_Name)
 	}
 	return
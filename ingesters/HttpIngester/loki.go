@@ -0,0 +1,540 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	defaultLokiUrl = `/loki/api/v1/push`
+
+	lokiContentProtobuf = `application/x-protobuf`
+	lokiContentJSON     = `application/json`
+	lokiTenantHeader    = `X-Scope-OrgID`
+
+	// maximum expansion we will allow on a snappy compressed push request relative to Max-Body
+	lokiMaxCompressionRatio = 16
+)
+
+var (
+	ErrInvalidLokiLabels = errors.New("invalid stream label set")
+)
+
+type lokiCompatible struct {
+	auth                       //authentication information
+	URL               string   //override the push URL, defaults to /loki/api/v1/push
+	Tag_Name          string   //the default tag
+	Tag_Match         []string //label=value:tag specifications
+	Attach_Labels     []string //stream labels and structured metadata to attach as enumerated values, "*" attaches all
+	Ignore_Timestamps bool
+	Preprocessor      []string
+}
+
+func (v *lokiCompatible) validate(name string) (string, error) {
+	if len(v.URL) == 0 {
+		v.URL = defaultLokiUrl
+	}
+	p, err := url.Parse(v.URL)
+	if err != nil {
+		return ``, fmt.Errorf("URL structure is invalid: %v", err)
+	}
+	if p.Scheme != `` {
+		return ``, errors.New("May not specify scheme in listening URL")
+	} else if p.Host != `` {
+		return ``, errors.New("May not specify host in listening URL")
+	}
+	if len(v.Tag_Name) == 0 {
+		v.Tag_Name = entry.DefaultTagName
+	}
+	if ingest.CheckTag(v.Tag_Name) != nil {
+		return ``, errors.New("Invalid characters in the \"" + v.Tag_Name + "\"Tag-Name for " + name)
+	}
+	if _, err = v.labelTagMatchers(); err != nil {
+		return ``, fmt.Errorf("Loki-Compatible-Listener %s has invalid Tag-Match %w", name, err)
+	}
+	if _, err = v.auth.Validate(); err != nil {
+		return ``, fmt.Errorf("Auth for %s is invalid: %v", name, err)
+	} else if v.AuthType == jwtT || v.AuthType == cookie || v.AuthType == hmacSig {
+		return ``, fmt.Errorf("Loki-Compatible-Listener %s does not support %s authentication", name, v.AuthType)
+	}
+	//normalize the path
+	v.URL = p.Path
+	return v.URL, nil
+}
+
+type labelTagMatcher struct {
+	label string
+	tagMatcher
+}
+
+// labelTagMatchers parses the Tag-Match values which take the form label=value:tag
+// the value portion may be a glob pattern
+func (v *lokiCompatible) labelTagMatchers() (ltms []labelTagMatcher, err error) {
+	var tms []tagMatcher
+	if tms, err = checkTagPatterns(v.Tag_Match); err != nil {
+		return
+	}
+	for _, tm := range tms {
+		label, val, ok := strings.Cut(tm.Value, `=`)
+		if label = strings.TrimSpace(label); !ok || label == `` {
+			err = fmt.Errorf("Tag-Match %q is not in the form label=value:tag", tm.Value)
+			return
+		}
+		ltms = append(ltms, labelTagMatcher{
+			label: label,
+			tagMatcher: tagMatcher{
+				Value: strings.Trim(strings.TrimSpace(val), `"`),
+				Tag:   tm.Tag,
+			},
+		})
+	}
+	return
+}
+
+func (v *lokiCompatible) tags() (tags []string, err error) {
+	var ltms []labelTagMatcher
+	if ltms, err = v.labelTagMatchers(); err != nil {
+		return
+	}
+	tags = []string{v.Tag_Name}
+	for _, ltm := range ltms {
+		tags = append(tags, ltm.Tag)
+	}
+	return
+}
+
+type lokiLabelRouter struct {
+	label  string
+	router patternTagRouter
+}
+
+type lokiHandler struct {
+	name    string
+	routers []lokiLabelRouter
+	attach  paramAttacher // we reuse the attacher rules for labels, it is never processed against a request
+}
+
+type lokiLabel struct {
+	Name  string
+	Value string
+}
+
+type lokiEntry struct {
+	ts       time.Time
+	line     []byte
+	metadata []lokiLabel
+}
+
+type lokiStream struct {
+	labels  []lokiLabel
+	entries []lokiEntry
+}
+
+func (lh *lokiHandler) handle(h *handler, cfg routeHandler, w http.ResponseWriter, r *http.Request, rdr io.Reader, ip net.IP) {
+	ll := log.NewLoggerWithKV(h.lgr,
+		log.KV("Loki-Listener", lh.name),
+		log.KV("remoteaddress", ip.String()),
+		log.KV("url", r.URL.RequestURI()),
+	)
+	lr := io.LimitedReader{R: rdr, N: int64(maxBody + 1)}
+	body, err := io.ReadAll(&lr)
+	if err != nil {
+		ll.Info("failed to read push request", log.KVErr(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if lr.N == 0 {
+		ll.Info("push request too large", log.KV("max-body", maxBody))
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var streams []lokiStream
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch ct {
+	case lokiContentJSON:
+		streams, err = decodeLokiJSON(body)
+	case lokiContentProtobuf, ``: // promtail and friends send protobuf by default
+		streams, err = decodeLokiProtobuf(body, maxBody*lokiMaxCompressionRatio)
+	default:
+		err = fmt.Errorf("unsupported Content-Type %q", ct)
+	}
+	if err != nil {
+		//a 400 tells the client that retrying will not help
+		ll.Info("invalid push request", log.KV("content-type", ct), log.KVErr(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenant := r.Header.Get(lokiTenantHeader)
+	for _, s := range streams {
+		tag := lh.routeTag(cfg.tag, s.labels)
+		var evs []entry.EnumeratedValue
+		if tenant != `` {
+			evs = append(evs, entry.EnumeratedValue{Name: `tenant`, Value: entry.StringEnumData(tenant)})
+		}
+		evs = lh.appendLabels(evs, s.labels)
+		for _, le := range s.entries {
+			ent := &entry.Entry{
+				TS:   entry.FromStandard(le.ts),
+				SRC:  ip,
+				Tag:  tag,
+				Data: le.line,
+			}
+			if cfg.ignoreTs || le.ts.IsZero() {
+				ent.TS = entry.Now()
+			}
+			if len(evs) > 0 {
+				ent.AddEnumeratedValues(evs)
+			}
+			if len(le.metadata) > 0 {
+				ent.AddEnumeratedValues(lh.appendLabels(nil, le.metadata))
+			}
+			cfg.paramAttacher.attach(ent)
+			if err = h.handleEntryEx(cfg, ent); err != nil {
+				//a 5XX tells the client to back off and retry
+				ll.Error("failed to send entry", log.KVErr(err))
+				http.Error(w, "failed to ingest entries", http.StatusServiceUnavailable)
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (lh *lokiHandler) routeTag(def entry.EntryTag, labels []lokiLabel) entry.EntryTag {
+	for _, lr := range lh.routers {
+		for _, l := range labels {
+			if l.Name != lr.label {
+				continue
+			}
+			if tg, ok := lr.router.route(l.Value); ok {
+				return tg
+			}
+		}
+	}
+	return def
+}
+
+func (lh *lokiHandler) appendLabels(evs []entry.EnumeratedValue, labels []lokiLabel) []entry.EnumeratedValue {
+	if !lh.attach.active {
+		return evs
+	}
+	for _, l := range labels {
+		if lh.attach.all || lh.attach.wants(l.Name) {
+			evs = append(evs, entry.EnumeratedValue{Name: l.Name, Value: entry.StringEnumData(l.Value)})
+		}
+	}
+	return evs
+}
+
+type lokiJSONStream struct {
+	Stream map[string]string   `json:"stream"`
+	Values [][]json.RawMessage `json:"values"`
+}
+
+type lokiJSONPush struct {
+	Streams []lokiJSONStream `json:"streams"`
+}
+
+func decodeLokiJSON(body []byte) (streams []lokiStream, err error) {
+	var push lokiJSONPush
+	if err = json.Unmarshal(body, &push); err != nil {
+		return
+	}
+	for _, js := range push.Streams {
+		s := lokiStream{
+			labels:  make([]lokiLabel, 0, len(js.Stream)),
+			entries: make([]lokiEntry, 0, len(js.Values)),
+		}
+		for k, v := range js.Stream {
+			s.labels = append(s.labels, lokiLabel{Name: k, Value: v})
+		}
+		for _, vals := range js.Values {
+			var le lokiEntry
+			var ts, line string
+			if len(vals) < 2 || len(vals) > 3 {
+				err = fmt.Errorf("invalid value, expected [timestamp, line] got %d elements", len(vals))
+				return
+			} else if err = json.Unmarshal(vals[0], &ts); err != nil {
+				err = fmt.Errorf("invalid timestamp %w", err)
+				return
+			} else if err = json.Unmarshal(vals[1], &line); err != nil {
+				err = fmt.Errorf("invalid log line %w", err)
+				return
+			}
+			if le.ts, err = parseLokiNanos(ts); err != nil {
+				return
+			}
+			le.line = []byte(line)
+			if len(vals) == 3 {
+				var md map[string]string
+				if err = json.Unmarshal(vals[2], &md); err != nil {
+					err = fmt.Errorf("invalid structured metadata %w", err)
+					return
+				}
+				for k, v := range md {
+					le.metadata = append(le.metadata, lokiLabel{Name: k, Value: v})
+				}
+			}
+			s.entries = append(s.entries, le)
+		}
+		streams = append(streams, s)
+	}
+	return
+}
+
+func parseLokiNanos(v string) (ts time.Time, err error) {
+	var ns int64
+	if ns, err = strconv.ParseInt(v, 10, 64); err != nil {
+		err = fmt.Errorf("invalid timestamp %q", v)
+	} else {
+		ts = time.Unix(0, ns)
+	}
+	return
+}
+
+// decodeLokiProtobuf decodes a snappy compressed logproto.PushRequest
+//
+//	PushRequest { repeated StreamAdapter streams = 1; }
+//	StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; uint64 hash = 3; }
+//	EntryAdapter { Timestamp timestamp = 1; string line = 2; repeated LabelPairAdapter structuredMetadata = 3; }
+//	LabelPairAdapter { string name = 1; string value = 2; }
+func decodeLokiProtobuf(body []byte, maxDecoded int) (streams []lokiStream, err error) {
+	var n int
+	var buff []byte
+	if n, err = snappy.DecodedLen(body); err != nil {
+		return
+	} else if n > maxDecoded {
+		err = fmt.Errorf("decoded request size %d exceeds maximum of %d", n, maxDecoded)
+		return
+	} else if buff, err = snappy.Decode(nil, body); err != nil {
+		return
+	}
+	err = protoEach(buff, func(num protowire.Number, v []byte) (err error) {
+		if num == 1 {
+			var s lokiStream
+			if s, err = decodeLokiStream(v); err == nil {
+				streams = append(streams, s)
+			}
+		}
+		return
+	})
+	return
+}
+
+func decodeLokiStream(b []byte) (s lokiStream, err error) {
+	err = protoEach(b, func(num protowire.Number, v []byte) (err error) {
+		switch num {
+		case 1:
+			s.labels, err = parseLokiLabels(string(v))
+		case 2:
+			var le lokiEntry
+			if le, err = decodeLokiEntry(v); err == nil {
+				s.entries = append(s.entries, le)
+			}
+		}
+		return
+	})
+	return
+}
+
+func decodeLokiEntry(b []byte) (le lokiEntry, err error) {
+	err = protoEach(b, func(num protowire.Number, v []byte) (err error) {
+		switch num {
+		case 1:
+			le.ts, err = decodeProtoTimestamp(v)
+		case 2:
+			le.line = v // the decoded buffer is freshly allocated, no need to copy
+		case 3:
+			var l lokiLabel
+			err = protoEach(v, func(num protowire.Number, v []byte) error {
+				switch num {
+				case 1:
+					l.Name = string(v)
+				case 2:
+					l.Value = string(v)
+				}
+				return nil
+			})
+			le.metadata = append(le.metadata, l)
+		}
+		return
+	})
+	return
+}
+
+func decodeProtoTimestamp(b []byte) (ts time.Time, err error) {
+	var sec, nsec uint64
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return ts, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.VarintType {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return ts, protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		var v uint64
+		if v, n = protowire.ConsumeVarint(b); n < 0 {
+			return ts, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch num {
+		case 1:
+			sec = v
+		case 2:
+			nsec = v
+		}
+	}
+	ts = time.Unix(int64(sec), int64(int32(nsec)))
+	return
+}
+
+// protoEach walks a protobuf message and hands every length delimited field to the callback
+// other wire types are skipped, none of the fields we care about use them
+func protoEach(b []byte, cb func(protowire.Number, []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			if n = protowire.ConsumeFieldValue(num, typ, b); n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := cb(num, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseLokiLabels parses a prometheus style label set such as {job="varlogs", host="foo"}
+func parseLokiLabels(v string) (labels []lokiLabel, err error) {
+	v = strings.TrimSpace(v)
+	if len(v) < 2 || v[0] != '{' || v[len(v)-1] != '}' {
+		return nil, ErrInvalidLokiLabels
+	}
+	v = v[1 : len(v)-1]
+	for {
+		if v = strings.TrimLeft(v, " ,"); v == `` {
+			break
+		}
+		idx := strings.IndexByte(v, '=')
+		if idx <= 0 {
+			return nil, ErrInvalidLokiLabels
+		}
+		var l lokiLabel
+		l.Name = strings.TrimSpace(v[:idx])
+		v = strings.TrimSpace(v[idx+1:])
+		var quoted string
+		if !validLokiLabelName(l.Name) {
+			return nil, ErrInvalidLokiLabels
+		} else if quoted, err = strconv.QuotedPrefix(v); err != nil {
+			return nil, ErrInvalidLokiLabels
+		} else if l.Value, err = strconv.Unquote(quoted); err != nil {
+			return nil, ErrInvalidLokiLabels
+		}
+		//labels must be separated by a comma
+		if v = strings.TrimSpace(v[len(quoted):]); v != `` && v[0] != ',' {
+			return nil, ErrInvalidLokiLabels
+		}
+		labels = append(labels, l)
+	}
+	return
+}
+
+// validLokiLabelName checks a label name against the prometheus rules [a-zA-Z_][a-zA-Z0-9_]*
+func validLokiLabelName(v string) bool {
+	if v == `` {
+		return false
+	}
+	for i, c := range v {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func includeLokiListeners(hnd *handler, igst *ingest.IngestMuxer, cfg *cfgType, lgr *log.Logger) (err error) {
+	for k, v := range cfg.LokiListener {
+		lh := &lokiHandler{
+			name:   k,
+			attach: getAttacher(v.Attach_Labels),
+		}
+		var ltms []labelTagMatcher
+		if ltms, err = v.labelTagMatchers(); err != nil {
+			lg.Error("invalid Tag-Match", log.KV("listener", k), log.KVErr(err))
+			return
+		}
+		// matchers are evaluated in the order they were specified
+		for _, ltm := range ltms {
+			var ptr patternTagRouter
+			if ptr, err = newPatternTagRouter([]tagMatcher{ltm.tagMatcher}, igst); err != nil {
+				lg.Error("failed to negotiate Tag-Match tags", log.KV("listener", k), log.KVErr(err))
+				return
+			}
+			lh.routers = append(lh.routers, lokiLabelRouter{label: ltm.label, router: ptr})
+		}
+		hcfg := routeHandler{
+			handler:  lh.handle,
+			ignoreTs: v.Ignore_Timestamps,
+		}
+		if hcfg.tag, err = igst.NegotiateTag(v.Tag_Name); err != nil {
+			lg.Error("failed to pull tag", log.KV("tag", v.Tag_Name), log.KVErr(err))
+			return
+		}
+		if hcfg.pproc, err = cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor); err != nil {
+			lg.Error("preprocessor construction error", log.KVErr(err))
+			return
+		}
+		if _, hcfg.auth, err = v.NewAuthHandler(lgr); err != nil {
+			lg.Error("failed to get a new authentication handler", log.KVErr(err))
+			return
+		}
+		if err = hnd.addHandler(http.MethodPost, v.URL, hcfg); err != nil {
+			lg.Error("failed to add Loki-Compatible-Listener handler", log.KVErr(err))
+			return
+		}
+		debugout("Loki Handler URL %s handling %s\n", v.URL, v.Tag_Name)
+	}
+	return
+}
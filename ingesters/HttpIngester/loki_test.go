@@ -0,0 +1,204 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestParseLokiLabels(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []lokiLabel
+	}{
+		{`{}`, nil},
+		{` { } `, nil},
+		{`{job="varlogs"}`, []lokiLabel{{`job`, `varlogs`}}},
+		{`{job="varlogs", host="foo"}`, []lokiLabel{{`job`, `varlogs`}, {`host`, `foo`}}},
+		{`{job = "a" ,host="b",}`, []lokiLabel{{`job`, `a`}, {`host`, `b`}}},
+		{`{msg="a \"quoted\", value=x"}`, []lokiLabel{{`msg`, `a "quoted", value=x`}}},
+		{`{_private="1",a1="é"}`, []lokiLabel{{`_private`, `1`}, {`a1`, `é`}}},
+	}
+	for _, tt := range tests {
+		got, err := parseLokiLabels(tt.in)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.in, err)
+		} else if len(got) != len(tt.want) {
+			t.Fatalf("%q: bad labels %v != %v", tt.in, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%q: bad label %d %v != %v", tt.in, i, got[i], tt.want[i])
+			}
+		}
+	}
+
+	bad := []string{
+		``,
+		`job="varlogs"`,    //no braces
+		`{job="varlogs"`,   //unterminated
+		`{job=varlogs}`,    //unquoted value
+		`{job="varlogs}`,   //unterminated value
+		`{="varlogs"}`,     //no name
+		`{job}`,            //no value
+		`{1job="a"}`,       //bad name
+		`{job-name="a"}`,   //bad name
+		`{a b="c"}`,        //bad name
+		`{a="b" c="d"}`,    //no separator
+		`{a="\q"}`,         //bad escape
+		`{a="b"}, {c="d"}`, //trailing garbage
+		`{a="b",c=}`,       //missing value
+		`{a="b",="c"}`,     //missing name
+		`{a="b"} extra`,    //trailing garbage
+	}
+	for _, v := range bad {
+		if l, err := parseLokiLabels(v); err != ErrInvalidLokiLabels {
+			t.Fatalf("failed to catch bad labels %q: %v %v", v, l, err)
+		}
+	}
+}
+
+func sortLabels(l []lokiLabel) []lokiLabel {
+	sort.Slice(l, func(i, j int) bool { return l[i].Name < l[j].Name })
+	return l
+}
+
+func TestDecodeLokiJSON(t *testing.T) {
+	streams, err := decodeLokiJSON([]byte(`{"streams":[
+		{"stream":{"job":"varlogs","host":"foo"},"values":[
+			["1700000000000000001","line one"],
+			["1700000000500000000","line two",{"trace_id":"abc"}]
+		]},
+		{"stream":{},"values":[]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(streams) != 2 {
+		t.Fatalf("bad stream count %d", len(streams))
+	}
+	s := streams[0]
+	if l := sortLabels(s.labels); len(l) != 2 || l[0] != (lokiLabel{`host`, `foo`}) || l[1] != (lokiLabel{`job`, `varlogs`}) {
+		t.Fatalf("bad labels %v", l)
+	} else if len(s.entries) != 2 {
+		t.Fatalf("bad entry count %d", len(s.entries))
+	}
+	if e := s.entries[0]; !e.ts.Equal(time.Unix(1700000000, 1)) || string(e.line) != `line one` || len(e.metadata) != 0 {
+		t.Fatalf("bad entry %+v", e)
+	} else if e = s.entries[1]; !e.ts.Equal(time.Unix(1700000000, 500000000)) || len(e.metadata) != 1 || e.metadata[0] != (lokiLabel{`trace_id`, `abc`}) {
+		t.Fatalf("bad entry %+v", e)
+	}
+
+	bad := []string{
+		`not json`,
+		`{"streams":[{"stream":{},"values":[["1"]]}]}`,                        //too few elements
+		`{"streams":[{"stream":{},"values":[["1","a",{},"x"]]}]}`,             //too many elements
+		`{"streams":[{"stream":{},"values":[[1,"a"]]}]}`,                      //numeric timestamp
+		`{"streams":[{"stream":{},"values":[["abc","a"]]}]}`,                  //bad timestamp
+		`{"streams":[{"stream":{},"values":[["1",{"a":1}]]}]}`,                //line is not a string
+		`{"streams":[{"stream":{},"values":[["1","a",{"k":1}]]}]}`,            //bad metadata
+		`{"streams":[{"stream":{"job":1},"values":[]}]}`,                      //bad labels
+		`{"streams":[{"stream":{},"values":[["99999999999999999999","a"]]}]}`, //timestamp overflow
+	}
+	for _, v := range bad {
+		if _, err := decodeLokiJSON([]byte(v)); err == nil {
+			t.Fatalf("failed to catch bad push %s", v)
+		}
+	}
+}
+
+// protobuf builders for the logproto.PushRequest wire format
+func pbBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func pbVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func pbTimestamp(ts time.Time) []byte {
+	return pbVarint(pbVarint(nil, 1, uint64(ts.Unix())), 2, uint64(ts.Nanosecond()))
+}
+
+func pbEntry(ts time.Time, line string, md ...lokiLabel) []byte {
+	b := pbBytes(nil, 1, pbTimestamp(ts))
+	b = pbBytes(b, 2, []byte(line))
+	for _, l := range md {
+		b = pbBytes(b, 3, pbBytes(pbBytes(nil, 1, []byte(l.Name)), 2, []byte(l.Value)))
+	}
+	return b
+}
+
+func pbStream(labels string, entries ...[]byte) []byte {
+	b := pbBytes(nil, 1, []byte(labels))
+	for _, e := range entries {
+		b = pbBytes(b, 2, e)
+	}
+	return pbVarint(b, 3, 12345) //hash, ignored
+}
+
+func TestDecodeLokiProtobuf(t *testing.T) {
+	ts1 := time.Unix(1700000000, 123)
+	ts2 := time.Unix(1700000001, 999999999)
+	var req []byte
+	req = pbBytes(req, 1, pbStream(`{job="varlogs", host="foo"}`,
+		pbEntry(ts1, `line one`),
+		pbEntry(ts2, `line two`, lokiLabel{`trace_id`, `abc`}, lokiLabel{`span`, `1`}),
+	))
+	req = pbBytes(req, 1, pbStream(`{}`))
+	req = pbVarint(req, 9, 1) //unknown fields are skipped
+
+	streams, err := decodeLokiProtobuf(snappy.Encode(nil, req), 1024*1024)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(streams) != 2 {
+		t.Fatalf("bad stream count %d", len(streams))
+	}
+	s := streams[0]
+	if len(s.labels) != 2 || s.labels[0] != (lokiLabel{`job`, `varlogs`}) || s.labels[1] != (lokiLabel{`host`, `foo`}) {
+		t.Fatalf("bad labels %v", s.labels)
+	} else if len(s.entries) != 2 {
+		t.Fatalf("bad entries %d", len(s.entries))
+	}
+	if e := s.entries[0]; !e.ts.Equal(ts1) || string(e.line) != `line one` || len(e.metadata) != 0 {
+		t.Fatalf("bad entry %+v", e)
+	} else if e = s.entries[1]; !e.ts.Equal(ts2) || string(e.line) != `line two` || len(e.metadata) != 2 || e.metadata[1] != (lokiLabel{`span`, `1`}) {
+		t.Fatalf("bad entry %+v", e)
+	}
+	if len(streams[1].labels) != 0 || len(streams[1].entries) != 0 {
+		t.Fatalf("bad empty stream %+v", streams[1])
+	}
+
+	//the decoded size limit is checked before decompressing
+	if _, err = decodeLokiProtobuf(snappy.Encode(nil, req), len(req)-1); err == nil {
+		t.Fatal("failed to enforce the decoded size limit")
+	}
+
+	bad := map[string][]byte{
+		`not snappy`:     []byte("\xff\xff\xff\xff\xff\xff"),
+		`uncompressed`:   req,
+		`truncated`:      snappy.Encode(nil, req[:len(req)-3]),
+		`bad tag`:        snappy.Encode(nil, []byte{0x00}),
+		`bad labels`:     snappy.Encode(nil, pbBytes(nil, 1, pbStream(`job="x"`))),
+		`bad timestamp`:  snappy.Encode(nil, pbBytes(nil, 1, pbBytes(nil, 2, pbBytes(nil, 1, []byte{0x08})))),
+		`bad entry len`:  snappy.Encode(nil, pbBytes(nil, 1, []byte{0x12, 0x10, 0x01})),
+		`bad stream len`: snappy.Encode(nil, []byte{0x0a, 0x7f}),
+	}
+	for name, v := range bad {
+		if _, err := decodeLokiProtobuf(v, 1024*1024); err == nil {
+			t.Fatalf("failed to catch %s", name)
+		}
+	}
+}
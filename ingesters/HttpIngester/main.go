@@ -165,9 +165,20 @@ func main() {
 			}
 			hcfg.auth = ah
 		}
+		if hcfg.challenge, err = newWebhookChallenge(v.Webhook_Challenge, v.auth); err != nil {
+			lg.Fatal("failed to get webhook challenge handler", log.KVErr(err))
+		}
 		if err = hnd.addHandler(v.Method, v.URL, hcfg); err != nil {
 			lg.Fatal("failed to add handler", log.KV("url", v.URL), log.KVErr(err))
 		}
+		if cm := challengeMethod(v.Webhook_Challenge); cm != `` && cm != v.Method {
+			//the handshake arrives on a different method, accept it without ingesting anything
+			ccfg := hcfg
+			ccfg.handler = handleChallengeOnly
+			if err = hnd.addHandler(cm, v.URL, ccfg); err != nil {
+				lg.Fatal("failed to add webhook challenge handler", log.KV("url", v.URL), log.KVErr(err))
+			}
+		}
 		debugout("URL %s handling %s\n", v.URL, v.Tag_Name)
 	}
 
@@ -177,6 +188,12 @@ func main() {
 	if err = includeAFHListeners(hnd, igst, cfg, lg); err != nil {
 		lg.Fatal("failed to include Amazon Firehose Listeners", log.KVErr(err))
 	}
+	if err = includeESListeners(hnd, igst, cfg, lg); err != nil {
+		lg.Fatal("failed to include Elastic Compatible Listeners", log.KVErr(err))
+	}
+	if err = includeLokiListeners(hnd, igst, cfg, lg); err != nil {
+		lg.Fatal("failed to include Loki Compatible Listeners", log.KVErr(err))
+	}
 	var httpLogger *dlog.Logger
 	if debugOn || cfg.LogLevel() == `INFO` {
 		httpLogger = lg.StandardLogger()
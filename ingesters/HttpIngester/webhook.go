@@ -0,0 +1,451 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	sigSHA1   = `sha1`
+	sigSHA256 = `sha256`
+
+	sigHex    = `hex`
+	sigBase64 = `base64`
+
+	sigBodyField = `{body}`
+	sigTSField   = `{timestamp}`
+
+	defaultTimestampTolerance = 5 * time.Minute
+	epochMilliThreshold       = 1000000000000 // timestamps larger than this are in milliseconds
+
+	challengeSlack = `slack`
+	challengeZoom  = `zoom`
+	challengeOkta  = `okta`
+
+	oktaChallengeHeader = `X-Okta-Verification-Challenge`
+)
+
+var (
+	ErrMissingSignature  = errors.New("Signature header not found")
+	ErrBadSignature      = errors.New("Signature does not match")
+	ErrMissingTimestamp  = errors.New("Signature timestamp not found")
+	ErrStaleTimestamp    = errors.New("Signature timestamp is outside the allowed tolerance")
+	ErrBodyTooLarge      = errors.New("Request body is too large")
+	ErrInvalidChallenge  = errors.New("Invalid webhook challenge type")
+	ErrChallengeNoSecret = errors.New("Webhook challenge requires a TokenValue")
+)
+
+// signaturePreset holds the signature settings used by well known webhook sources
+type signaturePreset struct {
+	header    string
+	algorithm string
+	encoding  string
+	prefix    string
+	field     string
+	payload   string
+	tsHeader  string
+	tsField   string
+	challenge string
+}
+
+var signaturePresets = map[string]signaturePreset{
+	`github`: {
+		header:    `X-Hub-Signature-256`,
+		algorithm: sigSHA256,
+		prefix:    `sha256=`,
+	},
+	`slack`: {
+		header:    `X-Slack-Signature`,
+		algorithm: sigSHA256,
+		prefix:    `v0=`,
+		payload:   `v0:{timestamp}:{body}`,
+		tsHeader:  `X-Slack-Request-Timestamp`,
+		challenge: challengeSlack,
+	},
+	`stripe`: {
+		header:    `Stripe-Signature`,
+		algorithm: sigSHA256,
+		field:     `v1`,
+		payload:   `{timestamp}.{body}`,
+		tsField:   `t`,
+	},
+	`zoom`: {
+		header:    `X-Zm-Signature`,
+		algorithm: sigSHA256,
+		prefix:    `v0=`,
+		payload:   `v0:{timestamp}:{body}`,
+		tsHeader:  `X-Zm-Request-Timestamp`,
+		challenge: challengeZoom,
+	},
+	`shopify`: {
+		header:    `X-Shopify-Hmac-Sha256`,
+		algorithm: sigSHA256,
+		encoding:  sigBase64,
+	},
+}
+
+// validateSignature applies any preset and checks the signature settings
+func (a *auth) validateSignature() (err error) {
+	if a.TokenValue == `` {
+		return fmt.Errorf("Missing TokenValue (the signing secret) for auth type %s", a.AuthType)
+	}
+	if a.SignaturePreset != `` {
+		p, ok := signaturePresets[strings.ToLower(a.SignaturePreset)]
+		if !ok {
+			return fmt.Errorf("Unknown SignaturePreset %q", a.SignaturePreset)
+		}
+		//explicit settings override the preset
+		setDefault(&a.SignatureHeader, p.header)
+		setDefault(&a.SignatureAlgorithm, p.algorithm)
+		setDefault(&a.SignatureEncoding, p.encoding)
+		setDefault(&a.SignaturePrefix, p.prefix)
+		setDefault(&a.SignatureField, p.field)
+		setDefault(&a.SignaturePayload, p.payload)
+		setDefault(&a.TimestampHeader, p.tsHeader)
+		setDefault(&a.TimestampField, p.tsField)
+	}
+	setDefault(&a.SignatureAlgorithm, sigSHA256)
+	setDefault(&a.SignatureEncoding, sigHex)
+	setDefault(&a.SignaturePayload, sigBodyField)
+	a.SignatureAlgorithm = strings.ToLower(a.SignatureAlgorithm)
+	a.SignatureEncoding = strings.ToLower(a.SignatureEncoding)
+
+	if a.SignatureHeader == `` {
+		return errors.New("Missing SignatureHeader")
+	} else if a.SignatureAlgorithm != sigSHA1 && a.SignatureAlgorithm != sigSHA256 {
+		return fmt.Errorf("Invalid SignatureAlgorithm %q, must be %s or %s", a.SignatureAlgorithm, sigSHA1, sigSHA256)
+	} else if a.SignatureEncoding != sigHex && a.SignatureEncoding != sigBase64 {
+		return fmt.Errorf("Invalid SignatureEncoding %q, must be %s or %s", a.SignatureEncoding, sigHex, sigBase64)
+	} else if strings.Count(a.SignaturePayload, sigBodyField) != 1 {
+		return fmt.Errorf("SignaturePayload %q must contain %s exactly once", a.SignaturePayload, sigBodyField)
+	} else if a.TimestampHeader != `` && a.TimestampField != `` {
+		return errors.New("TimestampHeader and TimestampField are mutually exclusive")
+	} else if a.TimestampField != `` && a.SignatureField == `` {
+		return errors.New("TimestampField requires a SignatureField")
+	}
+	hasTS := a.TimestampHeader != `` || a.TimestampField != ``
+	if strings.Contains(a.SignaturePayload, sigTSField) && !hasTS {
+		return fmt.Errorf("SignaturePayload %q uses %s without a TimestampHeader or TimestampField", a.SignaturePayload, sigTSField)
+	}
+	a.tolerance = defaultTimestampTolerance
+	if a.TimestampTolerance != `` {
+		if !hasTS {
+			return errors.New("TimestampTolerance requires a TimestampHeader or TimestampField")
+		} else if a.tolerance, err = time.ParseDuration(a.TimestampTolerance); err != nil {
+			return fmt.Errorf("Invalid TimestampTolerance %q: %w", a.TimestampTolerance, err)
+		} else if a.tolerance <= 0 {
+			return fmt.Errorf("Invalid TimestampTolerance %q, must be positive", a.TimestampTolerance)
+		}
+	}
+	return
+}
+
+func setDefault(v *string, def string) {
+	if *v == `` {
+		*v = def
+	}
+}
+
+// presetChallenge returns the handshake required by the signature preset, if any
+func (a *auth) presetChallenge() string {
+	if a.AuthType != hmacSig || a.SignaturePreset == `` {
+		return ``
+	}
+	return signaturePresets[strings.ToLower(a.SignaturePreset)].challenge
+}
+
+// signatureHandler verifies an HMAC signature over the request body, and optionally a signed
+// timestamp, as sent by SaaS webhook sources
+type signatureHandler struct {
+	noLogin
+	lgr       *log.Logger
+	secret    []byte
+	newHash   func() hash.Hash
+	header    string
+	encoding  string
+	prefix    string
+	field     string
+	pre, post string // signed payload around the body
+	tsHeader  string
+	tsField   string
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+func newSignatureHandler(a auth, lgr *log.Logger) (hnd authHandler, err error) {
+	if err = a.validateSignature(); err != nil {
+		return
+	}
+	sh := &signatureHandler{
+		lgr:       lgr,
+		secret:    []byte(a.TokenValue),
+		newHash:   sha256.New,
+		header:    a.SignatureHeader,
+		encoding:  a.SignatureEncoding,
+		prefix:    a.SignaturePrefix,
+		field:     a.SignatureField,
+		tsHeader:  a.TimestampHeader,
+		tsField:   a.TimestampField,
+		tolerance: a.tolerance,
+		now:       time.Now,
+	}
+	if a.SignatureAlgorithm == sigSHA1 {
+		sh.newHash = sha1.New
+	}
+	sh.pre, sh.post, _ = strings.Cut(a.SignaturePayload, sigBodyField)
+	hnd = sh
+	return
+}
+
+// AuthRequest reads the body to verify the signature and replaces it so that it can be read again
+func (sh *signatureHandler) AuthRequest(r *http.Request) error {
+	body, err := readBufferedBody(r)
+	if err != nil {
+		return err
+	}
+	sigs, ts, err := sh.signatures(r.Header.Get(sh.header))
+	if err != nil {
+		return err
+	}
+	if sh.tsHeader != `` {
+		ts = strings.TrimSpace(r.Header.Get(sh.tsHeader))
+	}
+	if sh.tsHeader != `` || sh.tsField != `` {
+		if err = sh.checkTimestamp(ts); err != nil {
+			return err
+		}
+	}
+	expected := sh.sign(ts, body)
+	for _, sig := range sigs {
+		if v, err := sh.decode(sig); err == nil && hmac.Equal(v, expected) {
+			return nil
+		}
+	}
+	return ErrBadSignature
+}
+
+// signatures extracts the candidate signatures, and the timestamp for key=value headers,
+// from the signature header value
+func (sh *signatureHandler) signatures(hv string) (sigs []string, ts string, err error) {
+	if hv = strings.TrimSpace(hv); hv == `` {
+		err = ErrMissingSignature
+		return
+	}
+	if sh.field == `` {
+		if !strings.HasPrefix(hv, sh.prefix) {
+			err = ErrMissingSignature
+			return
+		}
+		sigs = append(sigs, strings.TrimPrefix(hv, sh.prefix))
+		return
+	}
+	//headers like Stripe-Signature carry multiple comma separated key=value pairs
+	for _, kv := range strings.Split(hv, `,`) {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), `=`)
+		if !ok {
+			continue
+		}
+		if k == sh.field {
+			sigs = append(sigs, strings.TrimPrefix(v, sh.prefix))
+		} else if sh.tsField != `` && k == sh.tsField {
+			ts = v
+		}
+	}
+	if len(sigs) == 0 {
+		err = ErrMissingSignature
+	}
+	return
+}
+
+// checkTimestamp rejects replayed requests whose signed timestamp is too far from now
+func (sh *signatureHandler) checkTimestamp(v string) error {
+	if v == `` {
+		return ErrMissingTimestamp
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid signature timestamp %q", v)
+	}
+	var ts time.Time
+	if n >= epochMilliThreshold {
+		ts = time.UnixMilli(n)
+	} else {
+		ts = time.Unix(n, 0)
+	}
+	if d := sh.now().Sub(ts); d > sh.tolerance || d < -sh.tolerance {
+		return ErrStaleTimestamp
+	}
+	return nil
+}
+
+func (sh *signatureHandler) sign(ts string, body []byte) []byte {
+	mac := hmac.New(sh.newHash, sh.secret)
+	io.WriteString(mac, strings.ReplaceAll(sh.pre, sigTSField, ts))
+	mac.Write(body)
+	io.WriteString(mac, strings.ReplaceAll(sh.post, sigTSField, ts))
+	return mac.Sum(nil)
+}
+
+func (sh *signatureHandler) decode(sig string) ([]byte, error) {
+	sig = strings.TrimSpace(sig)
+	if sh.encoding == sigBase64 {
+		return base64.StdEncoding.DecodeString(sig)
+	}
+	return hex.DecodeString(sig)
+}
+
+// readBufferedBody reads the raw request body, up to the maximum body size, and swaps in a
+// buffered copy so that handlers read the same bytes that were verified
+func readBufferedBody(r *http.Request) (body []byte, err error) {
+	lr := io.LimitedReader{R: r.Body, N: int64(maxBody + 1)}
+	if body, err = io.ReadAll(&lr); err != nil {
+		return
+	} else if len(body) > maxBody {
+		err = ErrBodyTooLarge
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return
+}
+
+// webhookChallenge answers the verification handshake a webhook source sends when a URL is registered
+type webhookChallenge interface {
+	// Respond writes the handshake response and returns true if the request was a handshake
+	Respond(http.ResponseWriter, *http.Request) (bool, error)
+}
+
+func newWebhookChallenge(typ string, a auth) (wc webhookChallenge, err error) {
+	switch strings.ToLower(typ) {
+	case ``:
+	case challengeSlack:
+		wc = &slackChallenge{}
+	case challengeZoom:
+		if a.TokenValue == `` {
+			err = ErrChallengeNoSecret
+		} else {
+			wc = &zoomChallenge{secret: []byte(a.TokenValue)}
+		}
+	case challengeOkta:
+		wc = &oktaChallenge{}
+	default:
+		err = ErrInvalidChallenge
+	}
+	return
+}
+
+// challengeMethod returns the additional method the handshake arrives on, if it differs from the listener method
+func challengeMethod(typ string) string {
+	if strings.ToLower(typ) == challengeOkta {
+		return http.MethodGet
+	}
+	return ``
+}
+
+// peekJSONBody buffers small JSON bodies so handshakes can be detected without consuming the request
+func peekJSONBody(r *http.Request) (body []byte, err error) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get(`Content-Type`), `application/json`) {
+		return
+	} else if r.Header.Get(`Content-Encoding`) != `` {
+		return
+	}
+	return readBufferedBody(r)
+}
+
+// slackChallenge answers the Events API url_verification request by echoing the challenge
+type slackChallenge struct{}
+
+func (sc *slackChallenge) Respond(w http.ResponseWriter, r *http.Request) (bool, error) {
+	body, err := peekJSONBody(r)
+	if err != nil || len(body) == 0 {
+		return false, err
+	}
+	var req struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.Type != `url_verification` {
+		return false, nil
+	}
+	w.Header().Set(`Content-Type`, `text/plain`)
+	_, err = io.WriteString(w, req.Challenge)
+	return true, err
+}
+
+// zoomChallenge answers the endpoint.url_validation event with the HMAC of the plain token
+type zoomChallenge struct {
+	secret []byte
+}
+
+func (zc *zoomChallenge) Respond(w http.ResponseWriter, r *http.Request) (bool, error) {
+	body, err := peekJSONBody(r)
+	if err != nil || len(body) == 0 {
+		return false, err
+	}
+	var req struct {
+		Event   string `json:"event"`
+		Payload struct {
+			PlainToken string `json:"plainToken"`
+		} `json:"payload"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.Event != `endpoint.url_validation` {
+		return false, nil
+	}
+	mac := hmac.New(sha256.New, zc.secret)
+	io.WriteString(mac, req.Payload.PlainToken)
+	resp := struct {
+		PlainToken     string `json:"plainToken"`
+		EncryptedToken string `json:"encryptedToken"`
+	}{
+		PlainToken:     req.Payload.PlainToken,
+		EncryptedToken: hex.EncodeToString(mac.Sum(nil)),
+	}
+	w.Header().Set(`Content-Type`, `application/json`)
+	return true, json.NewEncoder(w).Encode(resp)
+}
+
+// oktaChallenge answers the one-time GET verification request for event hooks
+type oktaChallenge struct{}
+
+func (oc *oktaChallenge) Respond(w http.ResponseWriter, r *http.Request) (bool, error) {
+	v := r.Header.Get(oktaChallengeHeader)
+	if r.Method != http.MethodGet || v == `` {
+		return false, nil
+	}
+	resp := struct {
+		Verification string `json:"verification"`
+	}{
+		Verification: v,
+	}
+	w.Header().Set(`Content-Type`, `application/json`)
+	return true, json.NewEncoder(w).Encode(resp)
+}
+
+// handleChallengeOnly rejects requests on handshake routes that were not a handshake
+func handleChallengeOnly(h *handler, cfg routeHandler, w http.ResponseWriter, r *http.Request, rdr io.Reader, ip net.IP) {
+	h.lgr.Info("unexpected request on webhook challenge route", log.KV("address", ip), log.KV("method", r.Method))
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
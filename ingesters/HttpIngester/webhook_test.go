@@ -0,0 +1,317 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	testSecret  = `webhook-secret`
+	testHookTS  = 1700000000
+	testPayload = `{"event":"push","ref":"refs/heads/main"}`
+)
+
+func init() {
+	//maxBody is normally set from the config in main
+	maxBody = defaultMaxBody
+}
+
+func testMAC(newHash func() hash.Hash, secret string, parts ...string) []byte {
+	mac := hmac.New(newHash, []byte(secret))
+	for _, p := range parts {
+		io.WriteString(mac, p)
+	}
+	return mac.Sum(nil)
+}
+
+func newTestSignatureHandler(t *testing.T, a auth) *signatureHandler {
+	t.Helper()
+	a.AuthType = hmacSig
+	hnd, err := newSignatureHandler(a, log.NewDiscardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh := hnd.(*signatureHandler)
+	sh.now = func() time.Time { return time.Unix(testHookTS, 0) }
+	return sh
+}
+
+func signedRequest(body string, hdr http.Header) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, `/hook`, strings.NewReader(body))
+	for k, v := range hdr {
+		r.Header[k] = v
+	}
+	return r
+}
+
+func TestSignaturePresets(t *testing.T) {
+	tests := []struct {
+		name  string
+		a     auth
+		tsHdr string // header carrying the timestamp, if it is not part of the signature header
+		// sign returns the headers for a body signed with secret at the timestamp
+		sign func(secret, ts, body string) http.Header
+	}{
+		{
+			name: `github-sha256`,
+			a:    auth{SignaturePreset: `github`},
+			sign: func(secret, ts, body string) http.Header {
+				return http.Header{`X-Hub-Signature-256`: {`sha256=` + hex.EncodeToString(testMAC(sha256.New, secret, body))}}
+			},
+		},
+		{
+			name: `github-sha1`,
+			a:    auth{SignaturePreset: `github`, SignatureHeader: `X-Hub-Signature`, SignatureAlgorithm: `SHA1`, SignaturePrefix: `sha1=`},
+			sign: func(secret, ts, body string) http.Header {
+				return http.Header{`X-Hub-Signature`: {`sha1=` + hex.EncodeToString(testMAC(sha1.New, secret, body))}}
+			},
+		},
+		{
+			name:  `slack`,
+			a:     auth{SignaturePreset: `Slack`},
+			tsHdr: `X-Slack-Request-Timestamp`,
+			sign: func(secret, ts, body string) http.Header {
+				return http.Header{
+					`X-Slack-Signature`:         {`v0=` + hex.EncodeToString(testMAC(sha256.New, secret, `v0:`+ts+`:`, body))},
+					`X-Slack-Request-Timestamp`: {ts},
+				}
+			},
+		},
+		{
+			name: `stripe`,
+			a:    auth{SignaturePreset: `stripe`},
+			sign: func(secret, ts, body string) http.Header {
+				//during secret rotation stripe sends a signature for each secret
+				old := hex.EncodeToString(testMAC(sha256.New, `old-secret`, ts+`.`, body))
+				cur := hex.EncodeToString(testMAC(sha256.New, secret, ts+`.`, body))
+				return http.Header{`Stripe-Signature`: {`t=` + ts + `,v1=` + old + `, v1=` + cur + `,v0=ignored`}}
+			},
+		},
+		{
+			name:  `zoom`,
+			a:     auth{SignaturePreset: `zoom`},
+			tsHdr: `X-Zm-Request-Timestamp`,
+			sign: func(secret, ts, body string) http.Header {
+				return http.Header{
+					`X-Zm-Signature`:         {`v0=` + hex.EncodeToString(testMAC(sha256.New, secret, `v0:`+ts+`:`, body))},
+					`X-Zm-Request-Timestamp`: {ts},
+				}
+			},
+		},
+		{
+			name: `shopify`,
+			a:    auth{SignaturePreset: `shopify`},
+			sign: func(secret, ts, body string) http.Header {
+				return http.Header{`X-Shopify-Hmac-Sha256`: {base64.StdEncoding.EncodeToString(testMAC(sha256.New, secret, body))}}
+			},
+		},
+	}
+	ts := strconv.Itoa(testHookTS)
+	for _, tt := range tests {
+		tt.a.TokenValue = testSecret
+		sh := newTestSignatureHandler(t, tt.a)
+		timestamped := sh.tsHeader != `` || sh.tsField != ``
+
+		//a good signature passes and the body can still be read by the handler
+		r := signedRequest(testPayload, tt.sign(testSecret, ts, testPayload))
+		if err := sh.AuthRequest(r); err != nil {
+			t.Fatalf("%s: good signature rejected: %v", tt.name, err)
+		} else if b, err := io.ReadAll(r.Body); err != nil || string(b) != testPayload {
+			t.Fatalf("%s: bad body after auth %q %v", tt.name, b, err)
+		}
+
+		//the wrong secret or a modified body fails
+		if err := sh.AuthRequest(signedRequest(testPayload, tt.sign(`wrong`, ts, testPayload))); !errors.Is(err, ErrBadSignature) {
+			t.Fatalf("%s: bad error for the wrong secret %v", tt.name, err)
+		} else if err = sh.AuthRequest(signedRequest(testPayload+` `, tt.sign(testSecret, ts, testPayload))); !errors.Is(err, ErrBadSignature) {
+			t.Fatalf("%s: bad error for a modified body %v", tt.name, err)
+		}
+
+		//as does a missing signature
+		hdr := tt.sign(testSecret, ts, testPayload)
+		hdr.Del(sh.header)
+		if err := sh.AuthRequest(signedRequest(testPayload, hdr)); !errors.Is(err, ErrMissingSignature) {
+			t.Fatalf("%s: bad error for a missing signature %v", tt.name, err)
+		}
+
+		if !timestamped {
+			continue
+		}
+		//millisecond timestamps are accepted
+		ms := strconv.Itoa(testHookTS*1000 + 1500)
+		if err := sh.AuthRequest(signedRequest(testPayload, tt.sign(testSecret, ms, testPayload))); err != nil {
+			t.Fatalf("%s: millisecond timestamp rejected: %v", tt.name, err)
+		}
+		//stale and future timestamps are rejected even when correctly signed
+		for _, d := range []int{-301, 301} {
+			v := strconv.Itoa(testHookTS + d)
+			if err := sh.AuthRequest(signedRequest(testPayload, tt.sign(testSecret, v, testPayload))); !errors.Is(err, ErrStaleTimestamp) {
+				t.Fatalf("%s: bad error for a timestamp %ds off %v", tt.name, d, err)
+			}
+		}
+		//as are missing and garbage timestamps
+		if err := sh.AuthRequest(signedRequest(testPayload, tt.sign(testSecret, ``, testPayload))); !errors.Is(err, ErrMissingTimestamp) {
+			t.Fatalf("%s: bad error for a missing timestamp %v", tt.name, err)
+		} else if err = sh.AuthRequest(signedRequest(testPayload, tt.sign(testSecret, `yesterday`, testPayload))); err == nil {
+			t.Fatalf("%s: garbage timestamp accepted", tt.name)
+		}
+		if tt.tsHdr != `` {
+			hdr = tt.sign(testSecret, ts, testPayload)
+			hdr.Del(tt.tsHdr)
+			if err := sh.AuthRequest(signedRequest(testPayload, hdr)); !errors.Is(err, ErrMissingTimestamp) {
+				t.Fatalf("%s: bad error for a missing timestamp header %v", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestSignatureConfig(t *testing.T) {
+	a := auth{AuthType: hmacSig, SignaturePreset: `slack`, TokenValue: testSecret, TimestampTolerance: `30s`}
+	if err := a.validateSignature(); err != nil {
+		t.Fatal(err)
+	} else if a.tolerance != 30*time.Second || a.presetChallenge() != challengeSlack {
+		t.Fatalf("bad preset settings %+v", a)
+	}
+	bad := []auth{
+		{SignaturePreset: `github`},                         //no secret
+		{SignaturePreset: `gitlab`, TokenValue: testSecret}, //unknown preset
+		{TokenValue: testSecret},                            //no header
+		{SignatureHeader: `X-Sig`, SignatureAlgorithm: `md5`, TokenValue: testSecret},
+		{SignatureHeader: `X-Sig`, SignatureEncoding: `base32`, TokenValue: testSecret},
+		{SignatureHeader: `X-Sig`, SignaturePayload: `{timestamp}`, TokenValue: testSecret},        //no body
+		{SignatureHeader: `X-Sig`, SignaturePayload: `{timestamp}.{body}`, TokenValue: testSecret}, //no timestamp source
+		{SignaturePreset: `slack`, TimestampTolerance: `-1s`, TokenValue: testSecret},              //negative tolerance
+		{SignatureHeader: `X-Sig`, TimestampTolerance: `1m`, TokenValue: testSecret},               //tolerance without a timestamp
+		{SignaturePreset: `stripe`, TimestampHeader: `X-Timestamp`, TokenValue: testSecret},        //two timestamp sources
+		{SignatureHeader: `X-Sig`, TimestampField: `t`, TokenValue: testSecret},                    //field without key=value signatures
+	}
+	for i, a := range bad {
+		a.AuthType = hmacSig
+		if err := a.validateSignature(); err == nil {
+			t.Fatalf("failed to catch bad config %d", i)
+		}
+	}
+}
+
+func challengeRequest(method, body string) *http.Request {
+	r := httptest.NewRequest(method, `/hook`, strings.NewReader(body))
+	if body != `` {
+		r.Header.Set(`Content-Type`, `application/json; charset=utf-8`)
+	}
+	return r
+}
+
+func TestWebhookChallenges(t *testing.T) {
+	//slack echoes the challenge
+	wc, err := newWebhookChallenge(`Slack`, auth{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	if ok, err := wc.Respond(w, challengeRequest(http.MethodPost, `{"token":"x","challenge":"3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P","type":"url_verification"}`)); !ok || err != nil {
+		t.Fatalf("slack handshake not answered %v %v", ok, err)
+	} else if w.Body.String() != `3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P` || w.Header().Get(`Content-Type`) != `text/plain` {
+		t.Fatalf("bad slack response %q %v", w.Body.String(), w.Header())
+	}
+
+	//zoom returns the HMAC of the plain token
+	if wc, err = newWebhookChallenge(`zoom`, auth{TokenValue: testSecret}); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	if ok, err := wc.Respond(w, challengeRequest(http.MethodPost, `{"payload":{"plainToken":"qgg8vlvZRS6UYooatFL8Aw"},"event_ts":1654503849680,"event":"endpoint.url_validation"}`)); !ok || err != nil {
+		t.Fatalf("zoom handshake not answered %v %v", ok, err)
+	}
+	var zr struct {
+		PlainToken     string `json:"plainToken"`
+		EncryptedToken string `json:"encryptedToken"`
+	}
+	if err = json.Unmarshal(w.Body.Bytes(), &zr); err != nil {
+		t.Fatal(err)
+	} else if zr.PlainToken != `qgg8vlvZRS6UYooatFL8Aw` || zr.EncryptedToken != hex.EncodeToString(testMAC(sha256.New, testSecret, zr.PlainToken)) {
+		t.Fatalf("bad zoom response %+v", zr)
+	}
+	if _, err = newWebhookChallenge(`zoom`, auth{}); !errors.Is(err, ErrChallengeNoSecret) {
+		t.Fatalf("bad error for zoom without a secret %v", err)
+	}
+
+	//okta echoes the verification header on a GET
+	if wc, err = newWebhookChallenge(`okta`, auth{}); err != nil {
+		t.Fatal(err)
+	} else if challengeMethod(`OKTA`) != http.MethodGet {
+		t.Fatal("okta handshake is not a GET")
+	}
+	w = httptest.NewRecorder()
+	r := challengeRequest(http.MethodGet, ``)
+	r.Header.Set(oktaChallengeHeader, `verify-me`)
+	if ok, err := wc.Respond(w, r); !ok || err != nil {
+		t.Fatalf("okta handshake not answered %v %v", ok, err)
+	} else if strings.TrimSpace(w.Body.String()) != `{"verification":"verify-me"}` {
+		t.Fatalf("bad okta response %q", w.Body.String())
+	}
+	if ok, _ := wc.Respond(httptest.NewRecorder(), challengeRequest(http.MethodGet, ``)); ok {
+		t.Fatal("okta answered a GET without the verification header")
+	}
+
+	if _, err = newWebhookChallenge(`github`, auth{}); !errors.Is(err, ErrInvalidChallenge) {
+		t.Fatalf("bad error for an unknown challenge %v", err)
+	} else if wc, err = newWebhookChallenge(``, auth{}); wc != nil || err != nil {
+		t.Fatalf("empty challenge type returned %v %v", wc, err)
+	}
+}
+
+func TestWebhookChallengePassthrough(t *testing.T) {
+	slack, _ := newWebhookChallenge(`slack`, auth{})
+	zoom, _ := newWebhookChallenge(`zoom`, auth{TokenValue: testSecret})
+	tests := []struct {
+		name string
+		wc   webhookChallenge
+		r    *http.Request
+	}{
+		{`slack event`, slack, challengeRequest(http.MethodPost, `{"type":"event_callback","event":{}}`)},
+		{`zoom event`, zoom, challengeRequest(http.MethodPost, `{"event":"meeting.started","payload":{}}`)},
+		{`not json`, slack, httptest.NewRequest(http.MethodPost, `/hook`, strings.NewReader(`{"type":"url_verification"}`))},
+		{`bad json`, zoom, challengeRequest(http.MethodPost, `{"event":`)},
+	}
+	for _, tt := range tests {
+		body := ``
+		if tt.r.Body != nil {
+			b, _ := io.ReadAll(tt.r.Body)
+			body = string(b)
+			tt.r.Body = io.NopCloser(strings.NewReader(body))
+		}
+		w := httptest.NewRecorder()
+		if ok, err := tt.wc.Respond(w, tt.r); ok || err != nil {
+			t.Fatalf("%s: treated as a handshake %v %v", tt.name, ok, err)
+		} else if w.Body.Len() != 0 {
+			t.Fatalf("%s: wrote a response %q", tt.name, w.Body.String())
+		}
+		//the ingest handler still gets the whole body
+		if b, err := io.ReadAll(tt.r.Body); err != nil || string(b) != body {
+			t.Fatalf("%s: body consumed %q", tt.name, b)
+		}
+	}
+}
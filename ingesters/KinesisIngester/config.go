@@ -21,6 +21,7 @@ import (
 
 const (
 	defaultStateStore = `/opt/gravwell/etc/kinesis_ingest.state`
+	defaultLeaseFile  = `/opt/gravwell/etc/kinesis_ingest.leases`
 	defaultLogFile    = `/opt/gravwell/log/kinesis.log`
 )
 
@@ -29,10 +30,19 @@ type readerType int
 
 type global struct {
 	config.IngestConfig
-	State_Store_Location  string
+	State_Store_Location  string // legacy sequence numbers, only read to seed new leases
 	Credentials_Type      string
 	AWS_Access_Key_ID     string `json:"-"` // DO NOT send this when marshalling
 	AWS_Secret_Access_Key string `json:"-"` // DO NOT send this when marshalling
+
+	//shard leases, instances sharing a lease store split the shards between them
+	Lease_Store    string // file or dynamodb
+	Lease_File     string // path of the file lease store, may be on shared storage
+	Lease_Table    string // DynamoDB table, created if it does not exist
+	Lease_Region   string
+	Lease_Endpoint string
+	Lease_Duration string // how long a lease survives without renewal
+	Worker_ID      string // unique name of this instance, defaults to the ingester UUID
 }
 
 type streamDef struct {
@@ -71,6 +81,12 @@ func GetConfig(path, overlayPath string) (*cfgType, error) {
 	if c.Global.Log_File == `` {
 		c.Global.Log_File = defaultLogFile
 	}
+	if c.Global.Lease_File == `` {
+		c.Global.Lease_File = defaultLeaseFile
+	}
+	if c.Global.Lease_Store = strings.ToLower(strings.TrimSpace(c.Global.Lease_Store)); c.Global.Lease_Store == `` {
+		c.Global.Lease_Store = leaseStoreFile
+	}
 	if err := c.Verify(); err != nil {
 		return nil, err
 	}
@@ -99,6 +115,20 @@ func (c cfgType) Verify() error {
 	if connCount == 0 {
 		return errors.New("No backend targets specified")
 	}
+	switch c.Global.Lease_Store {
+	case leaseStoreFile:
+	case leaseStoreDynamoDB:
+		if c.Global.Lease_Table == `` {
+			return errors.New("Lease-Table is required with the dynamodb Lease-Store")
+		} else if c.Global.Lease_Region == `` {
+			return errors.New("Lease-Region is required with the dynamodb Lease-Store")
+		}
+	default:
+		return fmt.Errorf("Invalid Lease-Store %q, options are file or dynamodb", c.Global.Lease_Store)
+	}
+	if _, err := c.leaseDuration(); err != nil {
+		return err
+	}
 	if len(c.KinesisStream) == 0 {
 		return errors.New("At least one Kinesis stream required.")
 	}
@@ -122,6 +152,18 @@ func (c cfgType) Verify() error {
 	return nil
 }
 
+func (c *cfgType) leaseDuration() (d time.Duration, err error) {
+	if c.Global.Lease_Duration == `` {
+		return defaultLeaseDuration, nil
+	}
+	if d, err = time.ParseDuration(c.Global.Lease_Duration); err != nil {
+		err = fmt.Errorf("Invalid Lease-Duration %q: %w", c.Global.Lease_Duration, err)
+	} else if d < minLeaseDuration {
+		err = fmt.Errorf("Lease-Duration %v is shorter than the minimum %v", d, minLeaseDuration)
+	}
+	return
+}
+
 func (c *cfgType) Targets() ([]string, error) {
 	return c.Global.Targets()
 }
@@ -0,0 +1,462 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	defaultLeaseDuration = 30 * time.Second
+	minLeaseDuration     = 5 * time.Second
+	shardSyncInterval    = time.Minute
+	releaseTimeout       = 5 * time.Second
+)
+
+type shardInfo struct {
+	ID      string
+	Parents []string
+}
+
+// shardLister returns every shard in a stream, including closed shards still within retention
+type shardLister interface {
+	ListShards(ctx context.Context, stream string) ([]shardInfo, error)
+}
+
+// shardProcessor reads a shard starting after the lease checkpoint, it returns nil once the
+// shard is closed and completely read and otherwise runs until the context is cancelled
+type shardProcessor func(ctx context.Context, sl *shardLease) error
+
+// shardLease is the handle a shard processor uses to record progress
+type shardLease struct {
+	shard string
+	start string
+
+	mtx        sync.Mutex
+	checkpoint string
+	cancel     context.CancelFunc
+	done       chan struct{}
+	err        error
+}
+
+// Shard returns the shard ID
+func (sl *shardLease) Shard() string {
+	return sl.shard
+}
+
+// Start returns the sequence number to resume after, empty if the shard has never been read
+func (sl *shardLease) Start() string {
+	return sl.start
+}
+
+// Checkpoint records progress, it is persisted when the lease is next renewed
+func (sl *shardLease) Checkpoint(seq string) {
+	sl.mtx.Lock()
+	sl.checkpoint = seq
+	sl.mtx.Unlock()
+}
+
+func (sl *shardLease) current() string {
+	sl.mtx.Lock()
+	defer sl.mtx.Unlock()
+	return sl.checkpoint
+}
+
+// exited returns true and the processor result once the processor has returned
+func (sl *shardLease) exited() (bool, error) {
+	select {
+	case <-sl.done:
+		return true, sl.err
+	default:
+	}
+	return false, nil
+}
+
+// observation tracks when a lease counter last changed, leases whose counters stop moving for a
+// lease duration are expired. Using local observation times means clocks need not agree.
+type observation struct {
+	counter uint64
+	seen    time.Time
+}
+
+// coordinator balances the shards of a stream across every instance sharing a lease store
+type coordinator struct {
+	store    LeaseStore
+	lister   shardLister
+	stream   string
+	id       string
+	duration time.Duration
+	process  shardProcessor
+	seeds    map[string]string // shard to sequence number from the legacy state file
+	lg       *log.Logger
+	now      func() time.Time
+
+	held      map[string]Lease       // our last write of each lease we hold
+	running   map[string]*shardLease // processors for held leases
+	observed  map[string]observation
+	lastSync  time.Time
+	lastRenew time.Time
+	resync    bool
+	wg        sync.WaitGroup
+}
+
+func newCoordinator(store LeaseStore, lister shardLister, stream, id string, duration time.Duration, process shardProcessor, lg *log.Logger) *coordinator {
+	if duration <= 0 {
+		duration = defaultLeaseDuration
+	}
+	return &coordinator{
+		store:    store,
+		lister:   lister,
+		stream:   stream,
+		id:       id,
+		duration: duration,
+		process:  process,
+		lg:       lg,
+		now:      time.Now,
+		held:     map[string]Lease{},
+		running:  map[string]*shardLease{},
+		observed: map[string]observation{},
+		resync:   true,
+	}
+}
+
+// run ticks until the context is cancelled and then releases every lease we hold
+func (c *coordinator) run(ctx context.Context) {
+	tckr := time.NewTicker(c.duration / 3)
+	defer tckr.Stop()
+	for {
+		if err := c.tick(ctx); err != nil && ctx.Err() == nil {
+			c.lg.Warn("lease coordination failed", log.KV("stream", c.stream), log.KVErr(err))
+		}
+		select {
+		case <-ctx.Done():
+			c.shutdown()
+			return
+		case <-tckr.C:
+		}
+	}
+}
+
+// tick runs a single round of shard discovery, renewal, and lease acquisition
+func (c *coordinator) tick(ctx context.Context) (err error) {
+	now := c.now()
+	if c.resync || now.Sub(c.lastSync) >= shardSyncInterval {
+		if err = c.syncShards(ctx); err != nil {
+			return
+		}
+		c.lastSync, c.resync = now, false
+	}
+	c.reap(ctx)
+	if err = c.renew(ctx); err != nil {
+		if now.Sub(c.lastRenew) > c.duration {
+			//other workers will have taken our leases by now
+			c.lg.Warn("unable to renew shard leases, stopping", log.KV("stream", c.stream), log.KVErr(err))
+			for shard := range c.held {
+				c.stop(shard)
+			}
+			c.held = map[string]Lease{}
+		}
+		return
+	}
+	c.lastRenew = now
+	var leases []Lease
+	if leases, err = c.store.List(ctx, c.stream); err != nil {
+		return
+	}
+	c.observe(leases, now)
+	c.acquire(ctx, leases, now)
+	c.startProcessors(ctx)
+	return
+}
+
+// syncShards creates leases for new shards, parents first, and removes finished leases for
+// shards that have aged out of the stream
+func (c *coordinator) syncShards(ctx context.Context) (err error) {
+	var shards []shardInfo
+	if shards, err = c.lister.ListShards(ctx, c.stream); err != nil {
+		return
+	}
+	var leases []Lease
+	if leases, err = c.store.List(ctx, c.stream); err != nil {
+		return
+	}
+	existing := make(map[string]Lease, len(leases))
+	for _, l := range leases {
+		existing[l.Shard] = l
+	}
+	live := make(map[string]bool, len(shards))
+	for _, s := range orderShards(shards) {
+		live[s.ID] = true
+		if _, ok := existing[s.ID]; ok {
+			continue
+		}
+		l := Lease{
+			Stream:     c.stream,
+			Shard:      s.ID,
+			Checkpoint: c.seeds[s.ID],
+			Parents:    s.Parents,
+		}
+		//stop on failure so a child never exists without its parents
+		if err = c.store.Create(ctx, l); err != nil && !errors.Is(err, ErrLeaseExists) {
+			return
+		}
+		err = nil
+	}
+	for _, l := range leases {
+		if !live[l.Shard] && l.finished() {
+			if err = c.store.Delete(ctx, c.stream, l.Shard); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// orderShards sorts shards so that parents come before their children
+func orderShards(shards []shardInfo) (out []shardInfo) {
+	byID := make(map[string]shardInfo, len(shards))
+	for _, s := range shards {
+		byID[s.ID] = s
+	}
+	visited := make(map[string]bool, len(shards))
+	var visit func(s shardInfo)
+	visit = func(s shardInfo) {
+		if visited[s.ID] {
+			return
+		}
+		visited[s.ID] = true
+		for _, p := range s.Parents {
+			if ps, ok := byID[p]; ok {
+				visit(ps)
+			}
+		}
+		out = append(out, s)
+	}
+	for _, s := range shards {
+		visit(s)
+	}
+	return
+}
+
+// reap handles processors that have exited, finished shards are checkpointed and released
+func (c *coordinator) reap(ctx context.Context) {
+	for shard, sl := range c.running {
+		exited, err := sl.exited()
+		if !exited {
+			continue
+		}
+		delete(c.running, shard)
+		if err != nil {
+			//we still hold the lease so the processor is restarted below from its last checkpoint
+			if cp := sl.current(); cp != `` {
+				l := c.held[shard]
+				l.Checkpoint = cp
+				c.held[shard] = l
+			}
+			c.lg.Warn("shard processor failed", log.KV("stream", c.stream), log.KV("shard", shard), log.KVErr(err))
+			continue
+		}
+		l := c.held[shard]
+		l.Checkpoint = shardEnd
+		l.Owner = ``
+		if _, err = c.store.Update(ctx, l); err != nil {
+			c.lg.Warn("failed to mark shard finished", log.KV("stream", c.stream), log.KV("shard", shard), log.KVErr(err))
+		} else {
+			c.lg.Info("shard finished", log.KV("stream", c.stream), log.KV("shard", shard))
+		}
+		delete(c.held, shard)
+		//children may now be eligible and the stream may have new shards
+		c.resync = true
+	}
+}
+
+// renew writes our checkpoints, any lease another worker has written since we last did is lost
+func (c *coordinator) renew(ctx context.Context) error {
+	for shard, l := range c.held {
+		if sl, ok := c.running[shard]; ok {
+			if cp := sl.current(); cp != `` {
+				l.Checkpoint = cp
+			}
+		}
+		nl, err := c.store.Update(ctx, l)
+		if errors.Is(err, ErrLeaseConflict) || errors.Is(err, ErrLeaseNotFound) {
+			c.lg.Info("lost shard lease", log.KV("stream", c.stream), log.KV("shard", shard))
+			c.stop(shard)
+			delete(c.held, shard)
+			continue
+		} else if err != nil {
+			//the lease store is unreachable, keep reading and let the lease expire if this persists
+			return err
+		}
+		c.held[shard] = nl
+	}
+	return nil
+}
+
+func (c *coordinator) observe(leases []Lease, now time.Time) {
+	for _, l := range leases {
+		if o, ok := c.observed[l.Shard]; !ok || o.counter != l.Counter {
+			c.observed[l.Shard] = observation{counter: l.Counter, seen: now}
+		}
+	}
+}
+
+func (c *coordinator) expired(l Lease, now time.Time) bool {
+	o, ok := c.observed[l.Shard]
+	return ok && o.counter == l.Counter && now.Sub(o.seen) > c.duration
+}
+
+// acquire takes free and expired leases until we have our share, stealing a single lease from
+// the busiest worker if there is nothing free
+func (c *coordinator) acquire(ctx context.Context, leases []Lease, now time.Time) {
+	finished := map[string]bool{}
+	present := map[string]bool{}
+	for _, l := range leases {
+		present[l.Shard] = true
+		if l.finished() {
+			finished[l.Shard] = true
+		}
+	}
+	var free []Lease
+	counts := map[string]int{c.id: len(c.held)}
+	var eligible int
+	for _, l := range leases {
+		if l.finished() {
+			continue
+		}
+		//parents must be finished first, parents that have aged out of the stream are done
+		ready := true
+		for _, p := range l.Parents {
+			if present[p] && !finished[p] {
+				ready = false
+			}
+		}
+		if !ready {
+			continue
+		}
+		eligible++
+		if _, ok := c.held[l.Shard]; ok {
+			continue
+		}
+		//a lease with our ID that we do not hold is left over from a previous run
+		if l.Owner == `` || l.Owner == c.id || c.expired(l, now) {
+			free = append(free, l)
+		} else {
+			counts[l.Owner]++
+		}
+	}
+	if eligible == 0 {
+		return
+	}
+	target := (eligible + len(counts) - 1) / len(counts)
+	for _, l := range free {
+		if len(c.held) >= target {
+			return
+		}
+		c.take(ctx, l, `free`)
+	}
+	if len(c.held) >= target {
+		return
+	}
+	var victim string
+	for owner, n := range counts {
+		if owner != c.id && n > target && (victim == `` || n > counts[victim]) {
+			victim = owner
+		}
+	}
+	if victim == `` {
+		return
+	}
+	for _, l := range leases {
+		if l.Owner == victim && !l.finished() {
+			if _, ok := c.held[l.Shard]; !ok {
+				c.take(ctx, l, `stolen`)
+				return
+			}
+		}
+	}
+}
+
+func (c *coordinator) take(ctx context.Context, l Lease, how string) {
+	prev := l.Owner
+	l.Owner = c.id
+	nl, err := c.store.Update(ctx, l)
+	if err != nil {
+		if !errors.Is(err, ErrLeaseConflict) {
+			c.lg.Warn("failed to take shard lease", log.KV("stream", c.stream), log.KV("shard", l.Shard), log.KVErr(err))
+		}
+		return
+	}
+	c.lg.Info("took shard lease", log.KV("stream", c.stream), log.KV("shard", l.Shard),
+		log.KV("lease", how), log.KV("previous", prev))
+	c.held[l.Shard] = nl
+}
+
+func (c *coordinator) startProcessors(ctx context.Context) {
+	for shard, l := range c.held {
+		if _, ok := c.running[shard]; ok {
+			continue
+		}
+		pctx, cancel := context.WithCancel(ctx)
+		sl := &shardLease{
+			shard:  shard,
+			start:  l.Checkpoint,
+			cancel: cancel,
+			done:   make(chan struct{}),
+		}
+		c.running[shard] = sl
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			defer close(sl.done)
+			sl.err = c.process(pctx, sl)
+		}()
+	}
+}
+
+// stop cancels a processor and waits for it to exit
+func (c *coordinator) stop(shard string) {
+	if sl, ok := c.running[shard]; ok {
+		sl.cancel()
+		<-sl.done
+		delete(c.running, shard)
+	}
+}
+
+// shutdown stops every processor and releases our leases with their final checkpoints so
+// other instances can pick them up right away
+func (c *coordinator) shutdown() {
+	for _, sl := range c.running {
+		sl.cancel()
+	}
+	c.wg.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+	defer cancel()
+	for shard, l := range c.held {
+		if sl, ok := c.running[shard]; ok {
+			if cp := sl.current(); cp != `` {
+				l.Checkpoint = cp
+			}
+			if exited, err := sl.exited(); exited && err == nil {
+				l.Checkpoint = shardEnd
+			}
+		}
+		l.Owner = ``
+		if _, err := c.store.Update(ctx, l); err != nil {
+			c.lg.Warn("failed to release shard lease", log.KV("stream", c.stream), log.KV("shard", shard), log.KVErr(err))
+		}
+	}
+	c.held = map[string]Lease{}
+	c.running = map[string]*shardLease{}
+}
@@ -0,0 +1,499 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	testStream   = `test-stream`
+	testDuration = 30 * time.Second
+)
+
+// memLeaseStore is an in memory lease store shared by every coordinator in a test
+type memLeaseStore struct {
+	sync.Mutex
+	leases map[string]Lease
+	fail   bool
+}
+
+func newMemLeaseStore() *memLeaseStore {
+	return &memLeaseStore{leases: map[string]Lease{}}
+}
+
+var errStoreDown = errors.New("store unavailable")
+
+func (ms *memLeaseStore) List(ctx context.Context, stream string) (ls []Lease, err error) {
+	ms.Lock()
+	defer ms.Unlock()
+	if ms.fail {
+		return nil, errStoreDown
+	}
+	for _, l := range ms.leases {
+		if l.Stream == stream {
+			ls = append(ls, l)
+		}
+	}
+	slices.SortFunc(ls, func(a, b Lease) int {
+		return strings.Compare(a.Shard, b.Shard)
+	})
+	return
+}
+
+func (ms *memLeaseStore) Create(ctx context.Context, l Lease) error {
+	ms.Lock()
+	defer ms.Unlock()
+	if ms.fail {
+		return errStoreDown
+	} else if _, ok := ms.leases[l.Shard]; ok {
+		return ErrLeaseExists
+	}
+	ms.leases[l.Shard] = l
+	return nil
+}
+
+func (ms *memLeaseStore) Update(ctx context.Context, l Lease) (Lease, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	if ms.fail {
+		return l, errStoreDown
+	}
+	cur, ok := ms.leases[l.Shard]
+	if !ok {
+		return l, ErrLeaseNotFound
+	} else if cur.Counter != l.Counter {
+		return l, ErrLeaseConflict
+	}
+	l.Counter++
+	ms.leases[l.Shard] = l
+	return l, nil
+}
+
+func (ms *memLeaseStore) Delete(ctx context.Context, stream, shard string) error {
+	ms.Lock()
+	defer ms.Unlock()
+	delete(ms.leases, shard)
+	return nil
+}
+
+func (ms *memLeaseStore) Close() error {
+	return nil
+}
+
+func (ms *memLeaseStore) get(shard string) Lease {
+	ms.Lock()
+	defer ms.Unlock()
+	return ms.leases[shard]
+}
+
+type testLister []shardInfo
+
+func (tl testLister) ListShards(ctx context.Context, stream string) ([]shardInfo, error) {
+	return tl, nil
+}
+
+// testProcessor records which shards are being read and lets tests finish them
+type testProcessor struct {
+	sync.Mutex
+	starts map[string]string // shard to start sequence
+	active map[*shardLease]bool
+	finish map[string]chan struct{}
+}
+
+func newTestProcessor() *testProcessor {
+	return &testProcessor{
+		starts: map[string]string{},
+		active: map[*shardLease]bool{},
+		finish: map[string]chan struct{}{},
+	}
+}
+
+func (tp *testProcessor) finishCh(shard string) chan struct{} {
+	tp.Lock()
+	defer tp.Unlock()
+	ch, ok := tp.finish[shard]
+	if !ok {
+		ch = make(chan struct{})
+		tp.finish[shard] = ch
+	}
+	return ch
+}
+
+func (tp *testProcessor) process(ctx context.Context, sl *shardLease) error {
+	finish := tp.finishCh(sl.Shard())
+	sl.Checkpoint(sl.Shard() + `-seq`)
+	tp.Lock()
+	tp.starts[sl.Shard()] = sl.Start()
+	tp.active[sl] = true
+	tp.Unlock()
+	defer func() {
+		tp.Lock()
+		delete(tp.active, sl)
+		tp.Unlock()
+	}()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-finish:
+		return nil
+	}
+}
+
+// isActive returns true if any coordinator is still reading the shard
+func (tp *testProcessor) isActive(shard string) bool {
+	tp.Lock()
+	defer tp.Unlock()
+	for sl := range tp.active {
+		if sl.Shard() == shard {
+			return true
+		}
+	}
+	return false
+}
+
+func (tp *testProcessor) started(sl *shardLease) bool {
+	tp.Lock()
+	defer tp.Unlock()
+	return tp.active[sl]
+}
+
+func (tp *testProcessor) startOf(shard string) string {
+	tp.Lock()
+	defer tp.Unlock()
+	return tp.starts[shard]
+}
+
+// settle waits for every processor the coordinators started to begin reading
+func (tp *testProcessor) settle(t *testing.T, cs ...*coordinator) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for _, c := range cs {
+		for shard, sl := range c.running {
+			for !tp.started(sl) {
+				if time.Now().After(deadline) {
+					t.Fatalf("processor for %s never started", shard)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+}
+
+type testClock struct {
+	sync.Mutex
+	t time.Time
+}
+
+func (tc *testClock) now() time.Time {
+	tc.Lock()
+	defer tc.Unlock()
+	return tc.t
+}
+
+func (tc *testClock) advance(d time.Duration) {
+	tc.Lock()
+	tc.t = tc.t.Add(d)
+	tc.Unlock()
+}
+
+func newTestCoordinator(store LeaseStore, shards []shardInfo, id string, clk *testClock, tp *testProcessor) *coordinator {
+	c := newCoordinator(store, testLister(shards), testStream, id, testDuration, tp.process, log.NewDiscardLogger())
+	c.now = clk.now
+	return c
+}
+
+func flatShards(ids ...string) (shards []shardInfo) {
+	for _, id := range ids {
+		shards = append(shards, shardInfo{ID: id})
+	}
+	return
+}
+
+func tickAll(t *testing.T, tp *testProcessor, cs ...*coordinator) {
+	t.Helper()
+	for _, c := range cs {
+		if err := c.tick(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		tp.settle(t, c)
+	}
+}
+
+// checkOwners ensures every unfinished shard is held by exactly one coordinator
+func checkOwners(t *testing.T, store *memLeaseStore, shards []shardInfo, cs ...*coordinator) {
+	t.Helper()
+	for _, s := range shards {
+		var holders []string
+		for _, c := range cs {
+			if _, ok := c.held[s.ID]; ok {
+				holders = append(holders, c.id)
+			}
+			if _, ok := c.running[s.ID]; ok {
+				if _, ok = c.held[s.ID]; !ok {
+					t.Fatalf("%s is reading %s without a lease", c.id, s.ID)
+				}
+			}
+		}
+		if len(holders) != 1 {
+			t.Fatalf("shard %s held by %v", s.ID, holders)
+		} else if l := store.get(s.ID); l.Owner != holders[0] {
+			t.Fatalf("shard %s held by %s but owned by %q", s.ID, holders[0], l.Owner)
+		}
+	}
+}
+
+func TestBalance(t *testing.T) {
+	store := newMemLeaseStore()
+	clk := &testClock{t: time.Unix(1700000000, 0)}
+	tp := newTestProcessor()
+	shards := flatShards(`s0`, `s1`, `s2`, `s3`)
+	a := newTestCoordinator(store, shards, `a`, clk, tp)
+	defer a.shutdown()
+	tickAll(t, tp, a)
+	if len(a.held) != 4 {
+		t.Fatalf("a single worker should take every shard, got %d", len(a.held))
+	}
+
+	b := newTestCoordinator(store, shards, `b`, clk, tp)
+	defer b.shutdown()
+	for i := 0; i < 6; i++ {
+		clk.advance(testDuration / 3)
+		tickAll(t, tp, a, b)
+	}
+	if len(a.held) != 2 || len(b.held) != 2 {
+		t.Fatalf("shards not balanced: a %d b %d", len(a.held), len(b.held))
+	}
+	checkOwners(t, store, shards, a, b)
+	//stolen shards resume from the checkpoint the previous owner wrote
+	for shard := range b.held {
+		if start := tp.startOf(shard); start != shard+`-seq` {
+			t.Fatalf("stolen shard %s started at %q", shard, start)
+		}
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	store := newMemLeaseStore()
+	clk := &testClock{t: time.Unix(1700000000, 0)}
+	tp := newTestProcessor()
+	shards := flatShards(`s0`, `s1`)
+	a := newTestCoordinator(store, shards, `a`, clk, tp)
+	defer a.shutdown()
+	b := newTestCoordinator(store, shards, `b`, clk, tp)
+	defer b.shutdown()
+	tickAll(t, tp, a, b)
+	for i := 0; i < 3; i++ {
+		clk.advance(testDuration / 3)
+		tickAll(t, tp, a, b)
+	}
+	if len(a.held) != 1 || len(b.held) != 1 {
+		t.Fatalf("shards not balanced: a %d b %d", len(a.held), len(b.held))
+	}
+	//b dies without releasing its lease, a waits out the lease duration before taking it
+	for shard := range b.running {
+		b.stop(shard)
+	}
+	clk.advance(testDuration / 3)
+	tickAll(t, tp, a)
+	if len(a.held) != 1 {
+		t.Fatal("took a lease before it expired")
+	}
+	for i := 0; i < 4; i++ {
+		clk.advance(testDuration / 3)
+		tickAll(t, tp, a)
+	}
+	if len(a.held) != 2 {
+		t.Fatalf("expired lease was not taken: %d", len(a.held))
+	}
+	//b comes back, finds its lease gone, and has to steal its share back
+	tickAll(t, tp, b)
+	tickAll(t, tp, a)
+	checkOwners(t, store, shards, a, b)
+	if len(a.held) != 1 || len(b.held) != 1 {
+		t.Fatalf("shards not rebalanced: a %d b %d", len(a.held), len(b.held))
+	}
+}
+
+func TestParentsFirst(t *testing.T) {
+	store := newMemLeaseStore()
+	clk := &testClock{t: time.Unix(1700000000, 0)}
+	tp := newTestProcessor()
+	// p split into c0 and c1, which were then merged into m
+	shards := []shardInfo{
+		{ID: `m`, Parents: []string{`c0`, `c1`}},
+		{ID: `c0`, Parents: []string{`p`}},
+		{ID: `c1`, Parents: []string{`p`}},
+		{ID: `p`},
+		{ID: `orphan`, Parents: []string{`trimmed`}},
+	}
+	c := newTestCoordinator(store, shards, `a`, clk, tp)
+	defer c.shutdown()
+	tickAll(t, tp, c)
+	if _, ok := c.held[`p`]; !ok {
+		t.Fatal("parent shard not taken")
+	} else if _, ok := c.held[`orphan`]; !ok {
+		t.Fatal("shard with a trimmed parent not taken")
+	}
+	for _, s := range []string{`c0`, `c1`, `m`} {
+		if _, ok := c.held[s]; ok {
+			t.Fatalf("child %s taken before its parent finished", s)
+		}
+	}
+
+	finishShard := func(shard string) {
+		t.Helper()
+		sl := c.running[shard]
+		close(tp.finishCh(shard))
+		<-sl.done
+		clk.advance(testDuration / 3)
+		tickAll(t, tp, c)
+		if l := store.get(shard); !l.finished() || l.Owner != `` {
+			t.Fatalf("finished shard %s not released: %+v", shard, l)
+		}
+	}
+	finishShard(`p`)
+	if _, ok := c.held[`c0`]; !ok {
+		t.Fatal("c0 not taken after its parent finished")
+	} else if _, ok := c.held[`c1`]; !ok {
+		t.Fatal("c1 not taken after its parent finished")
+	} else if _, ok := c.held[`m`]; ok {
+		t.Fatal("m taken before both parents finished")
+	}
+	finishShard(`c0`)
+	if _, ok := c.held[`m`]; ok {
+		t.Fatal("m taken with one parent still open")
+	}
+	finishShard(`c1`)
+	if _, ok := c.held[`m`]; !ok {
+		t.Fatal("m not taken after both parents finished")
+	}
+
+	//once the parent ages out of the stream its finished lease is cleaned up
+	c.lister = testLister(shards[:3])
+	c.resync = true
+	tickAll(t, tp, c)
+	if l := store.get(`p`); l.Shard != `` {
+		t.Fatal("finished lease for a trimmed shard not removed")
+	}
+}
+
+func TestShutdownRelease(t *testing.T) {
+	store := newMemLeaseStore()
+	clk := &testClock{t: time.Unix(1700000000, 0)}
+	tp := newTestProcessor()
+	shards := flatShards(`s0`, `s1`)
+	a := newTestCoordinator(store, shards, `a`, clk, tp)
+	a.seeds = map[string]string{`s1`: `legacy`}
+	tickAll(t, tp, a)
+	if tp.startOf(`s1`) != `legacy` || tp.startOf(`s0`) != `` {
+		t.Fatal("legacy checkpoints not used")
+	}
+	a.shutdown()
+	for _, s := range shards {
+		if l := store.get(s.ID); l.Owner != `` || l.Checkpoint != s.ID+`-seq` {
+			t.Fatalf("lease not released with its checkpoint: %+v", l)
+		} else if tp.isActive(s.ID) {
+			t.Fatalf("shard %s still being read", s.ID)
+		}
+	}
+	//released leases are taken immediately without waiting for expiry
+	b := newTestCoordinator(store, shards, `b`, clk, tp)
+	defer b.shutdown()
+	tickAll(t, tp, b)
+	if len(b.held) != 2 || tp.startOf(`s0`) != `s0-seq` {
+		t.Fatalf("released leases not taken: %d %q", len(b.held), tp.startOf(`s0`))
+	}
+}
+
+func TestStoreOutage(t *testing.T) {
+	store := newMemLeaseStore()
+	clk := &testClock{t: time.Unix(1700000000, 0)}
+	tp := newTestProcessor()
+	a := newTestCoordinator(store, flatShards(`s0`), `a`, clk, tp)
+	defer a.shutdown()
+	tickAll(t, tp, a)
+	store.Lock()
+	store.fail = true
+	store.Unlock()
+	//keep reading through a short outage
+	clk.advance(testDuration / 3)
+	a.tick(context.Background())
+	if len(a.running) != 1 {
+		t.Fatal("stopped reading during a short store outage")
+	}
+	//stop once the lease has certainly expired for everyone else
+	clk.advance(testDuration)
+	a.tick(context.Background())
+	if len(a.running) != 0 || len(a.held) != 0 || tp.isActive(`s0`) {
+		t.Fatal("kept reading after the lease expired")
+	}
+}
+
+func TestFileLeaseStore(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `leases`)
+	s1, err := newFileLeaseStore(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s1.Close()
+	s2, err := newFileLeaseStore(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	ctx := context.Background()
+	if err = s1.Create(ctx, Lease{Stream: testStream, Shard: `s0`, Parents: []string{`p`}}); err != nil {
+		t.Fatal(err)
+	} else if err = s2.Create(ctx, Lease{Stream: testStream, Shard: `s0`}); !errors.Is(err, ErrLeaseExists) {
+		t.Fatalf("duplicate create: %v", err)
+	} else if err = s2.Create(ctx, Lease{Stream: `other`, Shard: `s0`}); err != nil {
+		t.Fatal(err)
+	}
+	ls, err := s2.List(ctx, testStream)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ls) != 1 || ls[0].Shard != `s0` || len(ls[0].Parents) != 1 {
+		t.Fatalf("bad leases %+v", ls)
+	}
+	l := ls[0]
+	l.Owner = `a`
+	nl, err := s1.Update(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	} else if nl.Counter != 1 {
+		t.Fatalf("counter not incremented: %d", nl.Counter)
+	}
+	//a stale writer loses
+	l.Owner = `b`
+	if _, err = s2.Update(ctx, l); !errors.Is(err, ErrLeaseConflict) {
+		t.Fatalf("stale update: %v", err)
+	}
+	if ls, err = s2.List(ctx, testStream); err != nil {
+		t.Fatal(err)
+	} else if ls[0].Owner != `a` {
+		t.Fatalf("bad owner %q", ls[0].Owner)
+	}
+	if err = s2.Delete(ctx, testStream, `s0`); err != nil {
+		t.Fatal(err)
+	} else if ls, err = s1.List(ctx, testStream); err != nil || len(ls) != 0 {
+		t.Fatalf("lease not deleted: %v %v", ls, err)
+	} else if ls, err = s1.List(ctx, `other`); err != nil || len(ls) != 1 {
+		t.Fatalf("wrong lease deleted: %v %v", ls, err)
+	}
+}
@@ -0,0 +1,182 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dchest/safefile"
+	"github.com/gofrs/flock"
+)
+
+const (
+	// shardEnd is the checkpoint of a shard that has been read to completion
+	shardEnd = `SHARD_END`
+
+	leaseStoreFile     = `file`
+	leaseStoreDynamoDB = `dynamodb`
+
+	lockRetryInterval = 50 * time.Millisecond
+)
+
+var (
+	ErrLeaseExists   = errors.New("Lease already exists")
+	ErrLeaseConflict = errors.New("Lease was modified by another worker")
+	ErrLeaseNotFound = errors.New("Lease not found")
+)
+
+// Lease grants a single worker the right to read a shard. Every write bumps the counter, workers
+// use it both for conditional updates and to notice when a holder stops renewing.
+type Lease struct {
+	Stream     string
+	Shard      string
+	Owner      string   // worker ID of the holder, empty when the lease is free
+	Counter    uint64   // incremented on every write
+	Checkpoint string   // last processed sequence number, SHARD_END once the shard is finished
+	Parents    []string `json:",omitempty"` // shards that must be finished before this one is read
+}
+
+func (l Lease) finished() bool {
+	return l.Checkpoint == shardEnd
+}
+
+// LeaseStore persists leases, it must be shared by every instance reading the same streams
+type LeaseStore interface {
+	// List returns every lease for a stream
+	List(ctx context.Context, stream string) ([]Lease, error)
+	// Create adds a new lease, returning ErrLeaseExists if the shard already has one
+	Create(ctx context.Context, l Lease) error
+	// Update replaces a lease only if the stored counter matches l.Counter, returning
+	// ErrLeaseConflict otherwise. The stored lease, with its counter incremented, is returned.
+	Update(ctx context.Context, l Lease) (Lease, error)
+	// Delete removes a lease
+	Delete(ctx context.Context, stream, shard string) error
+	Close() error
+}
+
+// fileLeaseStore keeps leases in a JSON file guarded by a lock file, the file may live on
+// shared storage so long as the filesystem supports flock
+type fileLeaseStore struct {
+	sync.Mutex // flock does not exclude other goroutines sharing the same handle
+	pth        string
+	lock       *flock.Flock
+}
+
+type leaseFile map[string]map[string]Lease // stream to shard to lease
+
+func newFileLeaseStore(pth string) (*fileLeaseStore, error) {
+	if pth = filepath.Clean(pth); pth == `.` {
+		return nil, errors.New("Invalid lease file path")
+	}
+	if err := os.MkdirAll(filepath.Dir(pth), 0750); err != nil {
+		return nil, err
+	}
+	return &fileLeaseStore{
+		pth:  pth,
+		lock: flock.New(pth + `.lock`),
+	}, nil
+}
+
+// update runs a function against the current contents with the lock held, writing the
+// contents back if the function asks for it
+func (fs *fileLeaseStore) update(ctx context.Context, f func(leaseFile) (bool, error)) (err error) {
+	fs.Lock()
+	defer fs.Unlock()
+	if _, err = fs.lock.TryLockContext(ctx, lockRetryInterval); err != nil {
+		return
+	}
+	defer fs.lock.Unlock()
+	lf := leaseFile{}
+	var b []byte
+	if b, err = os.ReadFile(fs.pth); err == nil {
+		if err = json.Unmarshal(b, &lf); err != nil {
+			return
+		}
+	} else if !os.IsNotExist(err) {
+		return
+	}
+	var write bool
+	if write, err = f(lf); err != nil || !write {
+		return
+	}
+	if b, err = json.MarshalIndent(lf, ``, "\t"); err != nil {
+		return
+	}
+	var fout *safefile.File
+	if fout, err = safefile.Create(fs.pth, 0640); err != nil {
+		return
+	}
+	defer fout.Close()
+	if _, err = fout.Write(b); err != nil {
+		return
+	}
+	return fout.Commit()
+}
+
+func (fs *fileLeaseStore) List(ctx context.Context, stream string) (ls []Lease, err error) {
+	err = fs.update(ctx, func(lf leaseFile) (bool, error) {
+		for _, l := range lf[stream] {
+			ls = append(ls, l)
+		}
+		return false, nil
+	})
+	slices.SortFunc(ls, func(a, b Lease) int {
+		return strings.Compare(a.Shard, b.Shard)
+	})
+	return
+}
+
+func (fs *fileLeaseStore) Create(ctx context.Context, l Lease) error {
+	return fs.update(ctx, func(lf leaseFile) (bool, error) {
+		if _, ok := lf[l.Stream][l.Shard]; ok {
+			return false, ErrLeaseExists
+		} else if lf[l.Stream] == nil {
+			lf[l.Stream] = map[string]Lease{}
+		}
+		lf[l.Stream][l.Shard] = l
+		return true, nil
+	})
+}
+
+func (fs *fileLeaseStore) Update(ctx context.Context, l Lease) (nl Lease, err error) {
+	err = fs.update(ctx, func(lf leaseFile) (bool, error) {
+		if cur, ok := lf[l.Stream][l.Shard]; !ok {
+			return false, ErrLeaseNotFound
+		} else if cur.Counter != l.Counter {
+			return false, ErrLeaseConflict
+		}
+		nl = l
+		nl.Counter++
+		lf[l.Stream][l.Shard] = nl
+		return true, nil
+	})
+	return
+}
+
+func (fs *fileLeaseStore) Delete(ctx context.Context, stream, shard string) error {
+	return fs.update(ctx, func(lf leaseFile) (bool, error) {
+		if _, ok := lf[stream][shard]; !ok {
+			return false, nil
+		}
+		delete(lf[stream], shard)
+		return true, nil
+	})
+}
+
+func (fs *fileLeaseStore) Close() error {
+	return fs.lock.Close()
+}
@@ -0,0 +1,153 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// dynamoLeaseStore keeps leases in a DynamoDB table keyed by stream and shard, conditional
+// writes on the counter make updates safe across instances
+type dynamoLeaseStore struct {
+	svc   dynamodbiface.DynamoDBAPI
+	table string
+}
+
+// newDynamoLeaseStore connects to the lease table, creating it if it does not exist
+func newDynamoLeaseStore(ctx context.Context, sess client.ConfigProvider, table string) (ds *dynamoLeaseStore, err error) {
+	ds = &dynamoLeaseStore{
+		svc:   dynamodb.New(sess),
+		table: table,
+	}
+	dti := &dynamodb.DescribeTableInput{TableName: aws.String(table)}
+	if _, err = ds.svc.DescribeTableWithContext(ctx, dti); err == nil {
+		return
+	} else if !isAWSCode(err, dynamodb.ErrCodeResourceNotFoundException) {
+		return nil, err
+	}
+	cti := &dynamodb.CreateTableInput{
+		TableName:   aws.String(table),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String(`Stream`), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			{AttributeName: aws.String(`Shard`), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(`Stream`), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String(`Shard`), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+	}
+	//another instance may be creating the table at the same time
+	if _, err = ds.svc.CreateTableWithContext(ctx, cti); err != nil && !isAWSCode(err, dynamodb.ErrCodeResourceInUseException) {
+		return nil, err
+	}
+	if err = ds.svc.WaitUntilTableExistsWithContext(ctx, dti); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func isAWSCode(err error, code string) bool {
+	if awsErr, ok := err.(awserr.Error); ok {
+		return awsErr.Code() == code
+	}
+	return false
+}
+
+func (ds *dynamoLeaseStore) List(ctx context.Context, stream string) (ls []Lease, err error) {
+	qi := &dynamodb.QueryInput{
+		TableName:                aws.String(ds.table),
+		ConsistentRead:           aws.Bool(true),
+		KeyConditionExpression:   aws.String(`#s = :s`),
+		ExpressionAttributeNames: map[string]*string{`#s`: aws.String(`Stream`)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			`:s`: {S: aws.String(stream)},
+		},
+	}
+	//a failed unmarshal stops the paging without the query itself failing
+	var uerr error
+	err = ds.svc.QueryPagesWithContext(ctx, qi, func(out *dynamodb.QueryOutput, last bool) bool {
+		var page []Lease
+		if uerr = dynamodbattribute.UnmarshalListOfMaps(out.Items, &page); uerr != nil {
+			return false
+		}
+		ls = append(ls, page...)
+		return true
+	})
+	if err == nil {
+		err = uerr
+	}
+	if err != nil {
+		ls = nil
+	}
+	return
+}
+
+func (ds *dynamoLeaseStore) Create(ctx context.Context, l Lease) (err error) {
+	var item map[string]*dynamodb.AttributeValue
+	if item, err = dynamodbattribute.MarshalMap(l); err != nil {
+		return
+	}
+	pi := &dynamodb.PutItemInput{
+		TableName:                aws.String(ds.table),
+		Item:                     item,
+		ConditionExpression:      aws.String(`attribute_not_exists(#sh)`),
+		ExpressionAttributeNames: map[string]*string{`#sh`: aws.String(`Shard`)},
+	}
+	if _, err = ds.svc.PutItemWithContext(ctx, pi); isAWSCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
+		err = ErrLeaseExists
+	}
+	return
+}
+
+func (ds *dynamoLeaseStore) Update(ctx context.Context, l Lease) (nl Lease, err error) {
+	nl = l
+	nl.Counter++
+	var item map[string]*dynamodb.AttributeValue
+	if item, err = dynamodbattribute.MarshalMap(nl); err != nil {
+		return
+	}
+	pi := &dynamodb.PutItemInput{
+		TableName:                aws.String(ds.table),
+		Item:                     item,
+		ConditionExpression:      aws.String(`attribute_exists(#sh) AND #c = :c`),
+		ExpressionAttributeNames: map[string]*string{`#sh`: aws.String(`Shard`), `#c`: aws.String(`Counter`)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			`:c`: {N: aws.String(strconv.FormatUint(l.Counter, 10))},
+		},
+	}
+	if _, err = ds.svc.PutItemWithContext(ctx, pi); isAWSCode(err, dynamodb.ErrCodeConditionalCheckFailedException) {
+		err = ErrLeaseConflict
+	}
+	return
+}
+
+func (ds *dynamoLeaseStore) Delete(ctx context.Context, stream, shard string) (err error) {
+	di := &dynamodb.DeleteItemInput{
+		TableName: aws.String(ds.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			`Stream`: {S: aws.String(stream)},
+			`Shard`:  {S: aws.String(shard)},
+		},
+	}
+	_, err = ds.svc.DeleteItemWithContext(ctx, di)
+	return
+}
+
+func (ds *dynamoLeaseStore) Close() error {
+	return nil
+}
@@ -0,0 +1,142 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeDynamo serves a single lease table one item per page, it understands just enough of the
+// condition expressions used by dynamoLeaseStore.  Calling anything else panics on the nil interface.
+type fakeDynamo struct {
+	dynamodbiface.DynamoDBAPI
+	items    map[string]map[string]*dynamodb.AttributeValue // stream/shard to item
+	queryErr error
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{items: map[string]map[string]*dynamodb.AttributeValue{}}
+}
+
+func itemKey(item map[string]*dynamodb.AttributeValue) string {
+	return aws.StringValue(item[`Stream`].S) + `/` + aws.StringValue(item[`Shard`].S)
+}
+
+func (fd *fakeDynamo) QueryPagesWithContext(ctx aws.Context, qi *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool, opts ...request.Option) error {
+	if fd.queryErr != nil {
+		return fd.queryErr
+	}
+	stream := aws.StringValue(qi.ExpressionAttributeValues[`:s`].S)
+	var keys []string
+	for k := range fd.items {
+		if strings.HasPrefix(k, stream+`/`) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		out := &dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{fd.items[k]}}
+		if !fn(out, i == len(keys)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func (fd *fakeDynamo) PutItemWithContext(ctx aws.Context, pi *dynamodb.PutItemInput, opts ...request.Option) (*dynamodb.PutItemOutput, error) {
+	key := itemKey(pi.Item)
+	cur, exists := fd.items[key]
+	cond := aws.StringValue(pi.ConditionExpression)
+	ok := true
+	if strings.Contains(cond, `attribute_not_exists`) {
+		ok = !exists
+	} else if strings.Contains(cond, `attribute_exists`) {
+		ok = exists && aws.StringValue(cur[`Counter`].N) == aws.StringValue(pi.ExpressionAttributeValues[`:c`].N)
+	}
+	if !ok {
+		return nil, awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, `The conditional request failed`, nil)
+	}
+	fd.items[key] = pi.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (fd *fakeDynamo) DeleteItemWithContext(ctx aws.Context, di *dynamodb.DeleteItemInput, opts ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	delete(fd.items, itemKey(di.Key))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDynamoLeaseStore(t *testing.T) {
+	ctx := context.Background()
+	fd := newFakeDynamo()
+	ds := &dynamoLeaseStore{svc: fd, table: `leases`}
+	for _, shard := range []string{`shard-0`, `shard-1`} {
+		if err := ds.Create(ctx, Lease{Stream: testStream, Shard: shard, Parents: []string{`shard-p`}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ds.Create(ctx, Lease{Stream: `other`, Shard: `shard-0`}); err != nil {
+		t.Fatal(err)
+	} else if err = ds.Create(ctx, Lease{Stream: testStream, Shard: `shard-0`}); !errors.Is(err, ErrLeaseExists) {
+		t.Fatalf("bad error on a duplicate lease %v", err)
+	}
+
+	ls, err := ds.List(ctx, testStream)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(ls) != 2 || ls[0].Shard != `shard-0` || ls[1].Shard != `shard-1` || len(ls[1].Parents) != 1 {
+		t.Fatalf("bad leases %+v", ls)
+	}
+
+	l := ls[0]
+	l.Owner = `worker-a`
+	nl, err := ds.Update(ctx, l)
+	if err != nil {
+		t.Fatal(err)
+	} else if nl.Counter != 1 || nl.Owner != `worker-a` {
+		t.Fatalf("bad updated lease %+v", nl)
+	}
+	//l still holds the old counter
+	if _, err = ds.Update(ctx, l); !errors.Is(err, ErrLeaseConflict) {
+		t.Fatalf("bad error on a stale update %v", err)
+	}
+	if err = ds.Delete(ctx, testStream, `shard-1`); err != nil {
+		t.Fatal(err)
+	} else if ls, err = ds.List(ctx, testStream); err != nil {
+		t.Fatal(err)
+	} else if len(ls) != 1 || ls[0].Counter != 1 {
+		t.Fatalf("bad leases after delete %+v", ls)
+	}
+
+	//an item that does not unmarshal must fail the list rather than quietly end it
+	fd.items[testStream+`/shard-9`] = map[string]*dynamodb.AttributeValue{
+		`Stream`:  {S: aws.String(testStream)},
+		`Shard`:   {S: aws.String(`shard-9`)},
+		`Counter`: {S: aws.String(`not a number`)},
+	}
+	if ls, err = ds.List(ctx, testStream); err == nil {
+		t.Fatalf("bad item did not fail the list %+v", ls)
+	} else if ls != nil {
+		t.Fatalf("partial list returned with an error %+v", ls)
+	}
+
+	fd.queryErr = errors.New("throttled")
+	if _, err = ds.List(ctx, `other`); err != fd.queryErr {
+		t.Fatalf("bad error %v", err)
+	}
+}
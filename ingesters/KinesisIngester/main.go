@@ -21,6 +21,7 @@ import (
 	_ "time/tzdata"
 
 	"github.com/gravwell/gravwell/v3/debug"
+	"github.com/gravwell/gravwell/v3/ingest"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/log"
 	"github.com/gravwell/gravwell/v3/ingesters/base"
@@ -30,6 +31,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/kinesis"
 )
@@ -50,7 +52,6 @@ func main() {
 
 	var wg sync.WaitGroup
 	var cfg *cfgType
-	running := true
 
 	ibc := base.IngesterBaseConfig{
 		IngesterName:                 appName,
@@ -79,25 +80,32 @@ func main() {
 
 	debugout("Started ingester muxer\n")
 
-	// Get the state file
-	stateFile, err := utils.NewState(cfg.Global.State_Store_Location, 0600)
-	if err != nil {
-		lg.Fatal("failed to open state file", log.KV("path", cfg.Global.State_Store_Location), log.KVErr(err))
+	workerID := cfg.Global.Worker_ID
+	if workerID == `` {
+		id, ok := cfg.Global.IngesterUUID()
+		if !ok {
+			lg.FatalCode(0, "could not read ingester UUID")
+		}
+		workerID = id.String()
 	}
 
-	stateMan := NewStateman(stateFile)
-	stateMan.Start()
-	defer stateMan.Close()
-
 	c, err := sqs_common.GetCredentials(cfg.Global.Credentials_Type, cfg.Global.AWS_Access_Key_ID, cfg.Global.AWS_Secret_Access_Key)
 	if err != nil {
 		lg.Fatal("obtaining credentials", log.KVErr(err))
 	}
 
-	dieChan := make(chan bool)
-
 	ctx, cancel := context.WithCancel(context.Background())
 
+	store, err := newLeaseStore(ctx, cfg, c)
+	if err != nil {
+		lg.Fatal("failed to open lease store", log.KV("store", cfg.Global.Lease_Store), log.KVErr(err))
+	}
+	defer store.Close()
+	leaseDuration, _ := cfg.leaseDuration()
+
+	// sequence numbers from the old state file seed new leases so upgrades pick up where they left off
+	seeds := loadLegacyState(cfg.Global.State_Store_Location)
+
 	for _, stream := range cfg.KinesisStream {
 		tagid, err := igst.GetTag(stream.Tag_Name)
 		if err != nil {
@@ -117,51 +125,18 @@ func main() {
 		// get a handle on kinesis
 		svc := kinesis.New(sess, aws.NewConfig().WithRegion(stream.Region))
 
-		// Get the list of shards
-		shards := []*kinesis.Shard{}
-		dsi := &kinesis.DescribeStreamInput{}
-		dsi.SetStreamName(stream.Stream_Name)
-		count := 0
-		for {
-			streamdesc, err := svc.DescribeStream(dsi)
-			if err != nil {
-				count++
-				lg.Error("failed to get stream description", log.KV("stream", stream.Stream_Name), log.KVErr(err))
-				if count >= 5 {
-					// give up and LOUDLY quit
-					lg.Fatal("giving up fetch stream description for stream after 5 attempts, exiting.", log.KV("stream", stream.Stream_Name))
-				}
-				time.Sleep(1 * time.Second)
-				continue
-			}
-			newshards := streamdesc.StreamDescription.Shards
-			shards = append(shards, newshards...)
-			if *streamdesc.StreamDescription.HasMoreShards {
-				dsi.SetExclusiveStartShardId(*(newshards[len(newshards)-1].ShardId))
-			} else {
-				break
-			}
-		}
-		debugout("Read %d shards from stream %s\n", len(shards), stream.Stream_Name)
-
 		// Now start up the metrics reporter
-		var metricsTrackers []*shardMetrics
+		metrics := &streamMetrics{trackers: map[string]*shardMetrics{}}
 		if stream.Metrics_Interval > 0 {
+			wg.Add(1)
 			go func(stream streamDef) {
+				defer wg.Done()
 				for {
 					select {
-					case <-dieChan:
+					case <-ctx.Done():
 						return
 					case <-time.After(time.Duration(stream.Metrics_Interval) * time.Second):
-						report := metricsReport{StreamName: stream.Stream_Name, ShardCount: len(shards)}
-						for i := range metricsTrackers {
-							l, b, e, r := metricsTrackers[i].ReadAndReset()
-							report.AverageLag += l
-							report.CompressedDataSize += b
-							report.EntryDataSize += e
-							report.KinesisRequests += r
-						}
-						report.AverageLag = report.AverageLag / int64(len(metricsTrackers))
+						report := metrics.report(stream.Stream_Name)
 						if stream.JSON_Metrics {
 							jr, err := json.Marshal(report)
 							if err == nil {
@@ -170,7 +145,7 @@ func main() {
 						} else {
 							lg.Info("stream stats",
 								log.KV("stream", stream.Stream_Name),
-								log.KV("shards", len(shards)),
+								log.KV("shards", report.ShardCount),
 								log.KV("delay", report.AverageLag),
 								log.KV("compressedsize", report.CompressedDataSize),
 								log.KV("requestcount", report.KinesisRequests),
@@ -181,183 +156,276 @@ func main() {
 			}(*stream)
 		}
 
-		for i, shard := range shards {
-			// Detect and skip closed shards
-			if shard.SequenceNumberRange != nil && shard.SequenceNumberRange.EndingSequenceNumber != nil {
-				lg.Info("shard appears closed, skipping", log.KV("shard", *shard.ShardId), log.KV("stream", stream.Stream_Name))
-				continue
-			}
-			//get timegrinder stood up
-			tcfg := timegrinder.Config{
-				EnableLeftMostSeed: true,
-			}
-			tgr, err := timegrinder.NewTimeGrinder(tcfg)
-			if err != nil {
-				lg.FatalCode(0, "failed to create timegrinder", log.KVErr(err))
-				return
-			} else if err := cfg.TimeFormat.LoadFormats(tgr); err != nil {
-				lg.FatalCode(0, "failed to load custom time formats", log.KVErr(err))
-				return
-			}
-			if stream.Assume_Local_Timezone {
-				tgr.SetLocalTime()
-			}
-			if stream.Timezone_Override != `` {
-				if err = tgr.SetTimezone(stream.Timezone_Override); err != nil {
-					lg.FatalCode(0, "failed to set timezone", log.KV("timezone", stream.Timezone_Override), log.KVErr(err))
-					return
-				}
-			}
+		sr := &shardReader{
+			svc:     svc,
+			stream:  *stream,
+			tagid:   tagid,
+			cfg:     cfg,
+			igst:    igst,
+			metrics: metrics,
+		}
+		coord := newCoordinator(store, kinesisLister{svc: svc}, stream.Stream_Name, workerID, leaseDuration, sr.process, lg)
+		coord.seeds = seeds[stream.Stream_Name]
+		lg.Info("starting stream coordinator", log.KV("stream", stream.Stream_Name), log.KV("worker", workerID),
+			log.KV("leasestore", cfg.Global.Lease_Store))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			coord.run(ctx)
+		}()
+	}
 
-			go func(stream streamDef, shard kinesis.Shard, tagid entry.EntryTag, shardid int, tg *timegrinder.TimeGrinder) {
-				wg.Add(1)
-				defer wg.Done()
-				// set up timegrinder and other long-lived stuff
-				var src net.IP
-				if cfg.Global.Source_Override != `` {
-					// global override
-					src = net.ParseIP(cfg.Global.Source_Override)
-					if src == nil {
-						lg.Fatal("Global Source-Override is invalid")
-					}
-				}
+	utils.WaitForQuit()
+	ib.AnnounceShutdown()
 
-				// one processor set per shard
-				procset, err := cfg.Preprocessor.ProcessorSet(igst, stream.Preprocessor)
-				if err != nil {
-					lg.Fatal("preprocessor construction error", log.KVErr(err))
-				}
+	// the coordinators stop their shard readers and release their leases
+	cancel()
+	wg.Wait()
+}
 
-				// make the shardMetrics and add it to the array
-				tracker := shardMetrics{}
-				metricsTrackers = append(metricsTrackers, &tracker)
-				if stream.Metrics_Interval == 0 {
-					// disable it
-					tracker.Disabled = true
-				}
+// shardReader reads the shards of a single stream
+type shardReader struct {
+	svc     *kinesis.Kinesis
+	stream  streamDef
+	tagid   entry.EntryTag
+	cfg     *cfgType
+	igst    *ingest.IngestMuxer
+	metrics *streamMetrics
+}
 
-			reconnectLoop:
-				for {
-					gsii := &kinesis.GetShardIteratorInput{}
-					gsii.SetShardId(*shard.ShardId)
-					gsii.SetStreamName(stream.Stream_Name)
-					seqnum := stateMan.GetSequenceNum(stream.Stream_Name, *shard.ShardId)
-					if seqnum == `` {
-						// we don't have a previous state
-						debugout("No previous sequence number for stream %v shard %v, defaulting to %v\n", stream.Stream_Name, *shard.ShardId, stream.Iterator_Type)
-						gsii.SetShardIteratorType(stream.Iterator_Type)
-					} else {
-						gsii.SetShardIteratorType(`AFTER_SEQUENCE_NUMBER`)
-						gsii.SetStartingSequenceNumber(seqnum)
-					}
+// process reads a shard until it is closed and fully consumed or the context is cancelled
+func (sr *shardReader) process(ctx context.Context, sl *shardLease) (err error) {
+	stream := sr.stream
+	shardId := sl.Shard()
+	//get timegrinder stood up
+	tcfg := timegrinder.Config{
+		EnableLeftMostSeed: true,
+	}
+	tg, err := timegrinder.NewTimeGrinder(tcfg)
+	if err != nil {
+		return err
+	} else if err = sr.cfg.TimeFormat.LoadFormats(tg); err != nil {
+		return err
+	}
+	if stream.Assume_Local_Timezone {
+		tg.SetLocalTime()
+	}
+	if stream.Timezone_Override != `` {
+		if err = tg.SetTimezone(stream.Timezone_Override); err != nil {
+			return err
+		}
+	}
+	var src net.IP
+	if sr.cfg.Global.Source_Override != `` {
+		// global override
+		if src = net.ParseIP(sr.cfg.Global.Source_Override); src == nil {
+			return fmt.Errorf("Global Source-Override %q is invalid", sr.cfg.Global.Source_Override)
+		}
+	}
 
-					output, err := svc.GetShardIterator(gsii)
-					if err != nil {
-						lg.Error("error on shard", log.KV("number", shardid), log.KV("stream", stream.Stream_Name), log.KV("shard", *shard.ShardId), log.KVErr(err))
-						time.Sleep(5 * time.Second)
-						continue
-					}
-					if output.ShardIterator == nil {
-						// this is weird, we are going to bail out
-						lg.Error("got nil initial shard iterator, sleeping and retrying")
-						time.Sleep(5 * time.Second)
-						continue
-					}
-					iter := *output.ShardIterator
-
-					var lastSeqNum string
-					for running {
-						gri := &kinesis.GetRecordsInput{}
-						gri.SetLimit(5000)
-						gri.SetShardIterator(iter)
-						var res *kinesis.GetRecordsOutput
-						var err error
-						for {
-							res, err = svc.GetRecords(gri)
-							if res != nil {
-								if res.NextShardIterator != nil {
-									iter = *res.NextShardIterator
-								}
-							}
-							if err != nil {
-								if awsErr, ok := err.(awserr.Error); ok {
-									// process SDK error
-									if awsErr.Code() == kinesis.ErrCodeProvisionedThroughputExceededException {
-										lg.Warn("throughput exceeded, trying again", log.KV("shard", *shard.ShardId), log.KV("stream", stream.Stream_Name))
-										time.Sleep(500 * time.Millisecond)
-									} else if awsErr.Code() == kinesis.ErrCodeExpiredIteratorException {
-										lg.Info("Iterator expired, re-initializing", log.KV("shard", *shard.ShardId), log.KV("stream", stream.Stream_Name))
-										time.Sleep(100 * time.Millisecond)
-										continue reconnectLoop
-									} else {
-										lg.Error("answer error", log.KV("code", awsErr.Code()), log.KV("message", awsErr.Message()), log.KV("shard", *shard.ShardId), log.KV("stream", stream.Stream_Name))
-										time.Sleep(500 * time.Millisecond)
-									}
-								} else {
-									lg.Error("unknown error", log.KVErr(err))
-								}
-							} else {
-								// if we got no records, chill for a sec before we hit it again
-								if len(res.Records) == 0 {
-									time.Sleep(100 * time.Millisecond)
-								}
-								break
-							}
-						}
+	// one processor set per shard
+	procset, err := sr.cfg.Preprocessor.ProcessorSet(sr.igst, stream.Preprocessor)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if lerr := procset.Close(); lerr != nil {
+			lg.Error("Failed to close processor set", log.KVErr(lerr))
+		}
+	}()
 
-						var entrySize int
-						for _, r := range res.Records {
-							lastSeqNum = *r.SequenceNumber
-							ent := &entry.Entry{
-								Tag:  tagid,
-								SRC:  src,
-								Data: r.Data,
-							}
-							if stream.Parse_Time == false {
-								ent.TS = entry.FromStandard(*r.ApproximateArrivalTimestamp)
-							} else {
-								ts, ok, err := tg.Extract(ent.Data)
-								if !ok || err != nil {
-									// something went wrong, switch to using kinesis timestamps
-									stream.Parse_Time = false
-									ent.TS = entry.FromStandard(*r.ApproximateArrivalTimestamp)
-								} else {
-									ent.TS = entry.FromStandard(ts)
-								}
-							}
-							if err = procset.ProcessContext(ent, ctx); err != nil {
-								lg.Error("Failed to handle entry", log.KVErr(err))
-							}
-							entrySize += int(ent.Size())
-						}
-						tracker.Update(res, entrySize)
-						// Now update the most recent sequence number
-						if lastSeqNum != `` {
-							stateMan.UpdateSequenceNum(stream.Stream_Name, *shard.ShardId, lastSeqNum)
+	// make the shardMetrics and add it to the stream
+	tracker := sr.metrics.add(shardId)
+	defer sr.metrics.remove(shardId)
+	if stream.Metrics_Interval == 0 {
+		// disable it
+		tracker.Disabled = true
+	}
+
+	lastSeqNum := sl.Start()
+reconnectLoop:
+	for ctx.Err() == nil {
+		gsii := &kinesis.GetShardIteratorInput{}
+		gsii.SetShardId(shardId)
+		gsii.SetStreamName(stream.Stream_Name)
+		if lastSeqNum == `` {
+			// we don't have a previous state
+			debugout("No previous sequence number for stream %v shard %v, defaulting to %v\n", stream.Stream_Name, shardId, stream.Iterator_Type)
+			gsii.SetShardIteratorType(stream.Iterator_Type)
+		} else {
+			gsii.SetShardIteratorType(`AFTER_SEQUENCE_NUMBER`)
+			gsii.SetStartingSequenceNumber(lastSeqNum)
+		}
+
+		output, err := sr.svc.GetShardIteratorWithContext(ctx, gsii)
+		if err != nil {
+			lg.Error("error on shard", log.KV("stream", stream.Stream_Name), log.KV("shard", shardId), log.KVErr(err))
+			sleepContext(ctx, 5*time.Second)
+			continue
+		}
+		if output.ShardIterator == nil {
+			// this is weird, we are going to bail out
+			lg.Error("got nil initial shard iterator, sleeping and retrying")
+			sleepContext(ctx, 5*time.Second)
+			continue
+		}
+		iter := output.ShardIterator
+
+		for iter != nil && ctx.Err() == nil {
+			gri := &kinesis.GetRecordsInput{}
+			gri.SetLimit(5000)
+			gri.SetShardIterator(*iter)
+			var res *kinesis.GetRecordsOutput
+			var err error
+			for ctx.Err() == nil {
+				res, err = sr.svc.GetRecordsWithContext(ctx, gri)
+				if err != nil {
+					if awsErr, ok := err.(awserr.Error); ok {
+						// process SDK error
+						if awsErr.Code() == kinesis.ErrCodeProvisionedThroughputExceededException {
+							lg.Warn("throughput exceeded, trying again", log.KV("shard", shardId), log.KV("stream", stream.Stream_Name))
+							sleepContext(ctx, 500*time.Millisecond)
+						} else if awsErr.Code() == kinesis.ErrCodeExpiredIteratorException {
+							lg.Info("Iterator expired, re-initializing", log.KV("shard", shardId), log.KV("stream", stream.Stream_Name))
+							sleepContext(ctx, 100*time.Millisecond)
+							continue reconnectLoop
+						} else {
+							lg.Error("answer error", log.KV("code", awsErr.Code()), log.KV("message", awsErr.Message()), log.KV("shard", shardId), log.KV("stream", stream.Stream_Name))
+							sleepContext(ctx, 500*time.Millisecond)
 						}
+					} else {
+						lg.Error("unknown error", log.KVErr(err))
+						sleepContext(ctx, 500*time.Millisecond)
 					}
-					if err = procset.Close(); err != nil {
-						lg.Error("Failed to close processor set", log.KVErr(err))
-					}
-					// if we get to this point, exit the for loop
+				} else {
 					break
 				}
-			}(*stream, *shard, tagid, i, tgr)
+			}
+			if res == nil || err != nil {
+				break
+			}
+
+			var entrySize int
+			for _, r := range res.Records {
+				ent := &entry.Entry{
+					Tag:  sr.tagid,
+					SRC:  src,
+					Data: r.Data,
+				}
+				if stream.Parse_Time == false {
+					ent.TS = entry.FromStandard(*r.ApproximateArrivalTimestamp)
+				} else {
+					ts, ok, err := tg.Extract(ent.Data)
+					if !ok || err != nil {
+						// something went wrong, switch to using kinesis timestamps
+						stream.Parse_Time = false
+						ent.TS = entry.FromStandard(*r.ApproximateArrivalTimestamp)
+					} else {
+						ent.TS = entry.FromStandard(ts)
+					}
+				}
+				if err = procset.ProcessContext(ent, ctx); err != nil {
+					lg.Error("Failed to handle entry", log.KVErr(err))
+				}
+				entrySize += int(ent.Size())
+				lastSeqNum = *r.SequenceNumber
+			}
+			tracker.Update(res, entrySize)
+			// Now update the most recent sequence number
+			if lastSeqNum != `` {
+				sl.Checkpoint(lastSeqNum)
+			}
+			// a nil iterator means the shard was closed by a split or merge and we have read all of it
+			if iter = res.NextShardIterator; iter == nil {
+				return nil
+			}
+			// if we got no records, chill for a sec before we hit it again
+			if len(res.Records) == 0 {
+				sleepContext(ctx, 100*time.Millisecond)
+			}
 		}
 	}
+	return ctx.Err()
+}
 
-	utils.WaitForQuit()
-	ib.AnnounceShutdown()
+func sleepContext(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
 
-	running = false
-	close(dieChan)
+// kinesisLister lists stream shards with their parents
+type kinesisLister struct {
+	svc *kinesis.Kinesis
+}
 
-	go func() {
-		time.Sleep(time.Second)
-		cancel()
-	}()
+func (kl kinesisLister) ListShards(ctx context.Context, stream string) (shards []shardInfo, err error) {
+	lsi := &kinesis.ListShardsInput{}
+	lsi.SetStreamName(stream)
+	for {
+		var out *kinesis.ListShardsOutput
+		if out, err = kl.svc.ListShardsWithContext(ctx, lsi); err != nil {
+			return
+		}
+		for _, s := range out.Shards {
+			if s.ShardId == nil {
+				continue
+			}
+			si := shardInfo{ID: *s.ShardId}
+			if s.ParentShardId != nil && *s.ParentShardId != `` {
+				si.Parents = append(si.Parents, *s.ParentShardId)
+			}
+			if s.AdjacentParentShardId != nil && *s.AdjacentParentShardId != `` {
+				si.Parents = append(si.Parents, *s.AdjacentParentShardId)
+			}
+			shards = append(shards, si)
+		}
+		if out.NextToken == nil || *out.NextToken == `` {
+			break
+		}
+		// the stream name may not be set along with a token
+		lsi = &kinesis.ListShardsInput{}
+		lsi.SetNextToken(*out.NextToken)
+	}
+	debugout("Read %d shards from stream %s\n", len(shards), stream)
+	return
+}
 
-	wg.Wait()
+// newLeaseStore opens the configured lease store
+func newLeaseStore(ctx context.Context, cfg *cfgType, c *credentials.Credentials) (LeaseStore, error) {
+	switch cfg.Global.Lease_Store {
+	case leaseStoreDynamoDB:
+		ac := &aws.Config{
+			Credentials: c,
+			Region:      aws.String(cfg.Global.Lease_Region),
+		}
+		if cfg.Global.Lease_Endpoint != `` {
+			ac.Endpoint = aws.String(cfg.Global.Lease_Endpoint)
+		}
+		sess, err := session.NewSession(ac)
+		if err != nil {
+			return nil, err
+		}
+		return newDynamoLeaseStore(ctx, sess, cfg.Global.Lease_Table)
+	}
+	return newFileLeaseStore(cfg.Global.Lease_File)
+}
+
+// loadLegacyState reads the stream to shard to sequence number map written by older versions
+func loadLegacyState(pth string) (states map[string]map[string]string) {
+	states = map[string]map[string]string{}
+	if pth == `` {
+		return
+	}
+	stateFile, err := utils.NewState(pth, 0600)
+	if err != nil {
+		return
+	}
+	if err = stateFile.Read(&states); err != nil && err != utils.ErrNoState {
+		lg.Warn("failed to read legacy state file", log.KV("path", pth), log.KVErr(err))
+	}
+	return
 }
 
 func debugout(format string, args ...interface{}) {
@@ -375,6 +443,43 @@ type metricsReport struct {
 	KinesisRequests    uint64
 }
 
+// streamMetrics tracks the shards of a stream this instance is reading
+type streamMetrics struct {
+	sync.Mutex
+	trackers map[string]*shardMetrics
+}
+
+func (sm *streamMetrics) add(shard string) *shardMetrics {
+	sm.Lock()
+	defer sm.Unlock()
+	t := &shardMetrics{}
+	sm.trackers[shard] = t
+	return t
+}
+
+func (sm *streamMetrics) remove(shard string) {
+	sm.Lock()
+	delete(sm.trackers, shard)
+	sm.Unlock()
+}
+
+func (sm *streamMetrics) report(stream string) (report metricsReport) {
+	sm.Lock()
+	defer sm.Unlock()
+	report = metricsReport{StreamName: stream, ShardCount: len(sm.trackers)}
+	for _, t := range sm.trackers {
+		l, b, e, r := t.ReadAndReset()
+		report.AverageLag += l
+		report.CompressedDataSize += b
+		report.EntryDataSize += e
+		report.KinesisRequests += r
+	}
+	if len(sm.trackers) > 0 {
+		report.AverageLag = report.AverageLag / int64(len(sm.trackers))
+	}
+	return
+}
+
 type shardMetrics struct {
 	sync.Mutex
 	Disabled     bool
@@ -412,63 +517,3 @@ func (s *shardMetrics) ReadAndReset() (millis int64, datasize uint64, entrysize
 	s.requests = 0
 	return
 }
-
-type stateman struct {
-	sync.Mutex
-	states    map[string]map[string]string // map of stream name to shard name to sequence number
-	stateFile *utils.State
-}
-
-func NewStateman(stateFile *utils.State) *stateman {
-	sm := stateman{
-		states:    make(map[string]map[string]string),
-		stateFile: stateFile,
-	}
-	stateFile.Read(&sm.states)
-	return &sm
-}
-
-func (s *stateman) Start() {
-	go func() {
-		for {
-			select {
-			case <-time.After(15 * time.Second):
-				s.Flush()
-			}
-		}
-	}()
-}
-
-func (s *stateman) Close() {
-	s.Flush()
-}
-
-func (s *stateman) Flush() {
-	s.Lock()
-	defer s.Unlock()
-	s.stateFile.Write(s.states)
-}
-
-func (s *stateman) UpdateSequenceNum(stream, shard, seq string) {
-	s.Lock()
-	defer s.Unlock()
-
-	_, ok := s.states[stream]
-	if !ok {
-		// initialize the stream
-		s.states[stream] = make(map[string]string)
-	}
-	s.states[stream][shard] = seq
-}
-
-func (s *stateman) GetSequenceNum(stream, shard string) string {
-	s.Lock()
-	defer s.Unlock()
-
-	_, ok := s.states[stream]
-	if !ok {
-		// initialize the stream
-		s.states[stream] = make(map[string]string)
-	}
-	return s.states[stream][shard]
-}
@@ -0,0 +1,210 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	routingKeyEVName = `amqp_routing_key`
+)
+
+type muxer interface {
+	GetTag(string) (entry.EntryTag, error)
+	SyncContext(context.Context, time.Duration) error
+}
+
+// acker is the part of an AMQP channel used to settle a batch
+type acker interface {
+	Ack(tag uint64, multiple bool) error
+	Nack(tag uint64, multiple, requeue bool) error
+}
+
+type consumer struct {
+	name string
+	cfg  *consumerCfg
+	dc   dialConfig
+	tags map[string]entry.EntryTag
+	igst muxer
+	proc *processors.ProcessorSet
+	lg   *log.Logger
+	ctx  context.Context
+}
+
+func newConsumer(ctx context.Context, name string, cfg *consumerCfg, igst muxer, proc *processors.ProcessorSet, lg *log.Logger) (c *consumer, err error) {
+	c = &consumer{
+		name: name,
+		cfg:  cfg,
+		tags: map[string]entry.EntryTag{},
+		igst: igst,
+		proc: proc,
+		lg:   lg,
+		ctx:  ctx,
+	}
+	for _, t := range cfg.tags() {
+		if c.tags[t], err = igst.GetTag(t); err != nil {
+			return nil, err
+		}
+	}
+	if c.dc, err = cfg.clientConfig(name); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// run keeps the consumer connected until the context is cancelled, backing off between failures
+func (c *consumer) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	utils.RunWithRetry(c.ctx, c.consume, func(err error, retry time.Duration) {
+		c.lg.Warn("AMQP consumer disconnected", log.KV("consumer", c.name), log.KV("address", c.dc.addr),
+			log.KV("retry", retry), log.KVErr(err))
+	})
+}
+
+// consume connects, declares and binds the queue, and handles batches until something fails
+func (c *consumer) consume() (err error) {
+	var conn *amqp.Connection
+	if conn, err = amqp.DialConfig(c.dc.url, c.dc.cfg); err != nil {
+		return
+	}
+	defer conn.Close()
+	var ch *amqp.Channel
+	if ch, err = conn.Channel(); err != nil {
+		return
+	}
+	closed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	//the prefetch count bounds unacknowledged deliveries to a single batch
+	if err = ch.Qos(c.cfg.Batch_Size, 0, false); err != nil {
+		return
+	}
+	if c.cfg.Passive_Declare {
+		_, err = ch.QueueDeclarePassive(c.cfg.Queue, true, false, false, false, nil)
+	} else {
+		_, err = ch.QueueDeclare(c.cfg.Queue, true, false, false, false, nil)
+	}
+	if err != nil {
+		return
+	}
+	for _, k := range c.cfg.Binding_Key {
+		if err = ch.QueueBind(c.cfg.Queue, k, c.cfg.Exchange, false, nil); err != nil {
+			return
+		}
+	}
+	var dlvs <-chan amqp.Delivery
+	if dlvs, err = ch.Consume(c.cfg.Queue, `gravwell-`+c.name, false, false, false, false, nil); err != nil {
+		return
+	}
+	c.lg.Info("AMQP consumer connected", log.KV("consumer", c.name), log.KV("address", c.dc.addr),
+		log.KV("queue", c.cfg.Queue))
+	batch := make([]amqp.Delivery, 0, c.cfg.Batch_Size)
+	for {
+		if batch, err = c.fill(dlvs, batch[:0]); err != nil {
+			if errors.Is(err, amqp.ErrClosed) {
+				//the delivery channel closed, report why if the server said
+				select {
+				case cerr := <-closed:
+					if cerr != nil {
+						err = cerr
+					}
+				default:
+				}
+			}
+			return
+		}
+		if err = c.flush(ch, batch); err != nil {
+			return
+		}
+	}
+}
+
+// fill waits for a delivery and then gathers more until the batch is full or the batch timeout expires
+func (c *consumer) fill(dlvs <-chan amqp.Delivery, batch []amqp.Delivery) ([]amqp.Delivery, error) {
+	select {
+	case d, ok := <-dlvs:
+		if !ok {
+			return batch, amqp.ErrClosed
+		}
+		batch = append(batch, d)
+	case <-c.ctx.Done():
+		return batch, c.ctx.Err()
+	}
+	tmr := time.NewTimer(c.cfg.batchTimeout)
+	defer tmr.Stop()
+	for len(batch) < c.cfg.Batch_Size {
+		select {
+		case d, ok := <-dlvs:
+			if !ok {
+				return batch, amqp.ErrClosed
+			}
+			batch = append(batch, d)
+		case <-tmr.C:
+			return batch, nil
+		case <-c.ctx.Done():
+			return batch, c.ctx.Err()
+		}
+	}
+	return batch, nil
+}
+
+// flush hands a batch to the muxer and acks it, if Synchronous is set the ack waits until the
+// indexers have the entries.  Failed batches are requeued.
+func (c *consumer) flush(ch acker, batch []amqp.Delivery) (err error) {
+	if len(batch) == 0 {
+		return
+	}
+	for i := range batch {
+		if err = c.proc.ProcessContext(c.buildEntry(&batch[i]), c.ctx); err != nil {
+			break
+		}
+	}
+	if err == nil && c.cfg.Synchronous {
+		err = c.igst.SyncContext(c.ctx, 0)
+	}
+	//delivery tags only increase on a channel, so one multiple ack settles the whole batch
+	last := batch[len(batch)-1].DeliveryTag
+	if err != nil {
+		ch.Nack(last, true, true)
+		return
+	}
+	return ch.Ack(last, true)
+}
+
+func (c *consumer) buildEntry(d *amqp.Delivery) *entry.Entry {
+	ent := &entry.Entry{
+		TS:   entry.Now(),
+		SRC:  c.cfg.src,
+		Tag:  c.tags[c.cfg.tagFor(d.RoutingKey)],
+		Data: d.Body,
+	}
+	if !c.cfg.Ignore_Timestamps {
+		if !d.Timestamp.IsZero() {
+			ent.TS = entry.FromStandard(d.Timestamp)
+		}
+		if c.cfg.tg != nil {
+			if ts, ok, err := c.cfg.tg.Extract(d.Body); err != nil {
+				c.lg.Warn("catastrophic timegrinder error", log.KV("consumer", c.name), log.KVErr(err))
+			} else if ok {
+				ent.TS = entry.FromStandard(ts)
+			}
+			// if not ok, we'll just use the message timestamp
+		}
+	}
+	ent.AddEnumeratedValueEx(routingKeyEVName, d.RoutingKey)
+	return ent
+}
@@ -0,0 +1,122 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// The amqp_consumer ingester consumes messages from AMQP 0-9-1 brokers such as RabbitMQ
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	// Embed tzdata so that we don't rely on potentially broken timezone DBs on the host
+	_ "time/tzdata"
+
+	"github.com/gravwell/gravwell/v3/debug"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+)
+
+const (
+	defaultConfigLoc  = `/opt/gravwell/etc/amqp.conf`
+	defaultConfigDLoc = `/opt/gravwell/etc/amqp.conf.d`
+	ingesterName      = `amqp_consumer`
+	appName           = `amqp`
+)
+
+var (
+	lg *log.Logger
+)
+
+func main() {
+	go debug.HandleDebugSignals(ingesterName)
+
+	var cfg *cfgType
+	ibc := base.IngesterBaseConfig{
+		IngesterName:                 ingesterName,
+		AppName:                      appName,
+		DefaultConfigLocation:        defaultConfigLoc,
+		DefaultConfigOverlayLocation: defaultConfigDLoc,
+		GetConfigFunc:                GetConfig,
+	}
+	ib, err := base.Init(ibc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get configuration %v\n", err)
+		return
+	} else if err = ib.AssignConfig(&cfg); err != nil || cfg == nil {
+		fmt.Fprintf(os.Stderr, "failed to assign configuration %v %v\n", err, cfg == nil)
+		return
+	}
+	lg = ib.Logger
+
+	id, ok := cfg.Global.IngesterUUID()
+	if !ok {
+		lg.FatalCode(0, "could not read ingester UUID")
+	}
+
+	igst, err := ib.GetMuxer()
+	if err != nil {
+		lg.FatalCode(0, "failed to get ingest connection", log.KVErr(err))
+		return
+	}
+	defer igst.Close()
+	ib.AnnounceStartup()
+
+	var globalSrc net.IP
+	if cfg.Global.Source_Override != `` {
+		if globalSrc = net.ParseIP(cfg.Global.Source_Override); globalSrc == nil {
+			lg.FatalCode(0, "Global Source-Override is invalid", log.KV("sourceoverride", cfg.Global.Source_Override))
+		}
+	}
+
+	var wg sync.WaitGroup
+	var procs []*processors.ProcessorSet
+	ctx, cancel := context.WithCancel(context.Background())
+	for k, v := range cfg.Consumer {
+		if v.src == nil {
+			v.src = globalSrc
+		}
+		proc, err := cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor)
+		if err != nil {
+			lg.FatalCode(0, "preprocessor construction error", log.KV("consumer", k), log.KVErr(err))
+		}
+		procs = append(procs, proc)
+		cons, err := newConsumer(ctx, k, v, igst, proc, lg)
+		if err != nil {
+			lg.FatalCode(0, "failed to create consumer", log.KV("consumer", k), log.KV("url", v.redactedURL()), log.KVErr(err))
+		}
+		lg.Info("starting consumer", log.KV("consumer", k), log.KV("url", v.redactedURL()),
+			log.KV("queue", v.Queue))
+		wg.Add(1)
+		go cons.run(&wg)
+	}
+
+	utils.WaitForQuit()
+	ib.AnnounceShutdown()
+
+	cancel()
+	wg.Wait()
+
+	for _, p := range procs {
+		if err := p.Close(); err != nil {
+			lg.Error("failed to close preprocessors", log.KVErr(err))
+		}
+	}
+
+	lg.Info("amqp consumer exiting", log.KV("ingesteruuid", id))
+	if err := igst.Sync(utils.ExitSyncTimeout); err != nil {
+		lg.Error("failed to sync", log.KVErr(err))
+	}
+	if err := igst.Close(); err != nil {
+		lg.Error("failed to close", log.KVErr(err))
+	}
+}
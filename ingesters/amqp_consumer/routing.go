@@ -0,0 +1,63 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidPattern = errors.New("Invalid AMQP routing pattern")
+)
+
+// validatePattern checks a topic exchange binding pattern, * matches exactly one word and
+// # matches zero or more words
+func validatePattern(p string) error {
+	if len(p) > 255 {
+		return fmt.Errorf("%w %q: longer than 255 bytes", ErrInvalidPattern, p)
+	}
+	for _, w := range strings.Split(p, `.`) {
+		if len(w) > 1 && strings.ContainsAny(w, `*#`) {
+			return fmt.Errorf("%w %q: wildcards must be entire words", ErrInvalidPattern, p)
+		}
+	}
+	return nil
+}
+
+// matchRoutingKey checks if a routing key matches a topic exchange pattern
+func matchRoutingKey(pattern, key string) bool {
+	return matchWords(strings.Split(pattern, `.`), strings.Split(key, `.`))
+}
+
+func matchWords(p, k []string) bool {
+	for len(p) > 0 {
+		if p[0] == `#` {
+			//collapse runs of # and try every possible split point
+			for len(p) > 0 && p[0] == `#` {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				return true
+			}
+			for i := range k {
+				if matchWords(p, k[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(k) == 0 || (p[0] != `*` && p[0] != k[0]) {
+			return false
+		}
+		p, k = p[1:], k[1:]
+	}
+	return len(k) == 0
+}
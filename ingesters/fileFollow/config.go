@@ -30,6 +30,9 @@ import (
 const (
 	MAX_CONFIG_SIZE        int64 = (1024 * 1024 * 2) //2MB, even this is crazy large
 	defaultMaxWatchedFiles       = 1024
+
+	identityPath        = `path`
+	identityFingerprint = `fingerprint`
 )
 
 var (
@@ -63,7 +66,16 @@ type follower struct {
 	Timestamp_Delimited       bool
 	Timezone_Override         string
 	Regex_Delimiter           string
+	Record_Format             string // json or csv, read structured records rather than lines
+	CSV_Delimiter             string // delimiter for the csv record format, defaults to a comma
 	Preprocessor              []string
+	Poll                      bool   // poll for changes rather than relying on filesystem notifications, e.g. on network filesystems
+	Poll_Interval             string // how often to poll, defaults to 5s
+	Poll_Scan_Limit           int    // maximum number of directory entries examined per poll pass
+	Disposition               string // what to do with files once they are ingested: delete, move, or rename
+	Disposition_Directory     string // archive directory files are moved into
+	Disposition_Suffix        string // suffix added to files when renaming
+	Disposition_Idle          string // how long an ingested file must be idle before its disposition runs
 	// these two must be used together
 	Timestamp_Regex         string
 	Timestamp_Format_String string
@@ -75,6 +87,8 @@ type global struct {
 	config.IngestConfig
 	Max_Files_Watched    int
 	State_Store_Location string
+	File_Identity        string // path (default) or fingerprint
+	Fingerprint_Size     int64  // number of leading bytes used to fingerprint files
 }
 
 type cfgType struct {
@@ -113,6 +127,8 @@ func (c *cfgType) Verify() error {
 		return err
 	} else if err = c.global.verifyStateStore(); err != nil {
 		return err
+	} else if err = c.global.verifyIdentity(); err != nil {
+		return err
 	} else if c.global.Max_Files_Watched <= 0 {
 		c.global.Max_Files_Watched = defaultMaxWatchedFiles
 	}
@@ -159,6 +175,17 @@ func (c *cfgType) Verify() error {
 		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
 			return fmt.Errorf("Follower %s preprocessor invalid: %v", k, err)
 		}
+		if _, err := v.PollInterval(); err != nil {
+			return fmt.Errorf("Follower %s %v", k, err)
+		} else if v.Poll_Scan_Limit < 0 {
+			return fmt.Errorf("Follower %s Poll-Scan-Limit cannot be negative", k)
+		}
+		if _, err := v.DispositionConfig(); err != nil {
+			return fmt.Errorf("Follower %s %v", k, err)
+		}
+		if _, _, _, err := v.RecordEngine(); err != nil {
+			return fmt.Errorf("Follower %s %v", k, err)
+		}
 	}
 	return nil
 }
@@ -201,6 +228,104 @@ func (cfg *cfgType) Followers() map[string]follower {
 	}
 	return mp
 }
+
+// PollInterval returns the polling interval, zero means polling is disabled
+func (f follower) PollInterval() (d time.Duration, err error) {
+	if !f.Poll {
+		if f.Poll_Interval != `` || f.Poll_Scan_Limit != 0 {
+			err = errors.New("Poll-Interval and Poll-Scan-Limit require Poll=true")
+		}
+		return
+	}
+	d = filewatch.DefaultPollInterval
+	if f.Poll_Interval != `` {
+		if d, err = time.ParseDuration(f.Poll_Interval); err != nil {
+			err = fmt.Errorf("invalid Poll-Interval %q: %w", f.Poll_Interval, err)
+		} else if d < filewatch.MinPollInterval {
+			err = fmt.Errorf("Poll-Interval must be at least %v", filewatch.MinPollInterval)
+		}
+	}
+	return
+}
+
+// DispositionConfig returns what happens to files once they are completely ingested
+func (f follower) DispositionConfig() (dc filewatch.DispositionConfig, err error) {
+	switch strings.ToLower(strings.TrimSpace(f.Disposition)) {
+	case ``, `none`:
+		if f.Disposition_Directory != `` || f.Disposition_Suffix != `` || f.Disposition_Idle != `` {
+			err = errors.New("Disposition-Directory, Disposition-Suffix, and Disposition-Idle require a Disposition")
+		}
+		return
+	case `delete`:
+		dc.Action = filewatch.DispositionDelete
+	case `move`:
+		if f.Disposition_Directory == `` {
+			err = errors.New("Disposition=move requires a Disposition-Directory")
+			return
+		}
+		dc.Action = filewatch.DispositionMove
+		dc.Target = filepath.Clean(f.Disposition_Directory)
+		//archiving into a watched directory would just ingest everything again
+		if rel, lerr := filepath.Rel(filepath.Clean(f.Base_Directory), dc.Target); lerr == nil {
+			if rel == `.` || (f.Recursive && !strings.HasPrefix(rel, `..`)) {
+				err = errors.New("Disposition-Directory cannot be a watched directory")
+				return
+			}
+		}
+	case `rename`:
+		dc.Action = filewatch.DispositionRename
+		dc.Target = f.Disposition_Suffix
+	default:
+		err = fmt.Errorf("invalid Disposition %q, options are delete, move, or rename", f.Disposition)
+		return
+	}
+	if f.Disposition_Suffix != `` && dc.Action != filewatch.DispositionRename {
+		err = errors.New("Disposition-Suffix requires Disposition=rename")
+		return
+	} else if f.Disposition_Directory != `` && dc.Action != filewatch.DispositionMove {
+		err = errors.New("Disposition-Directory requires Disposition=move")
+		return
+	}
+	if f.Disposition_Idle != `` {
+		if dc.Idle, err = time.ParseDuration(f.Disposition_Idle); err != nil {
+			err = fmt.Errorf("invalid Disposition-Idle %q: %w", f.Disposition_Idle, err)
+			return
+		}
+	}
+	err = dc.Validate()
+	return
+}
+
+// RecordEngine returns the filewatch engine for structured records, ok is false if the file is read by line or delimiter
+func (f follower) RecordEngine() (engine int, args string, ok bool, err error) {
+	switch strings.ToLower(strings.TrimSpace(f.Record_Format)) {
+	case ``:
+		if f.CSV_Delimiter != `` {
+			err = errors.New("CSV-Delimiter requires Record-Format=csv")
+		}
+		return
+	case `json`:
+		engine = filewatch.JSONEngine
+	case `csv`:
+		engine = filewatch.CSVEngine
+		args = f.CSV_Delimiter
+		if err = filewatch.ValidateCSVDelimiter(args); err != nil {
+			return
+		}
+	default:
+		err = fmt.Errorf("invalid Record-Format %q, options are json or csv", f.Record_Format)
+		return
+	}
+	if engine != filewatch.CSVEngine && f.CSV_Delimiter != `` {
+		err = errors.New("CSV-Delimiter requires Record-Format=csv")
+	} else if f.Timestamp_Delimited || f.Regex_Delimiter != `` {
+		err = errors.New("Record-Format cannot be combined with Timestamp-Delimited or Regex-Delimiter")
+	} else {
+		ok = true
+	}
+	return
+}
+
 func (f follower) TimestampOverride() (v string, err error) {
 	v = strings.TrimSpace(f.Timestamp_Format_Override)
 	return
@@ -256,7 +381,30 @@ func (g *global) Verify() (err error) {
 	if err = g.IngestConfig.Verify(); err != nil {
 		return
 	}
-	err = g.verifyStateStore()
+	if err = g.verifyStateStore(); err != nil {
+		return
+	}
+	err = g.verifyIdentity()
+	return
+}
+
+func (g *global) verifyIdentity() (err error) {
+	switch strings.ToLower(strings.TrimSpace(g.File_Identity)) {
+	case ``, identityPath:
+		g.File_Identity = identityPath
+		if g.Fingerprint_Size != 0 {
+			err = errors.New("Fingerprint-Size requires File-Identity=fingerprint")
+		}
+	case identityFingerprint:
+		g.File_Identity = identityFingerprint
+		if g.Fingerprint_Size == 0 {
+			g.Fingerprint_Size = filewatch.DefaultFingerprintSize
+		} else if g.Fingerprint_Size < filewatch.MinFingerprintSize {
+			err = fmt.Errorf("Fingerprint-Size must be at least %d bytes", filewatch.MinFingerprintSize)
+		}
+	default:
+		err = fmt.Errorf("Invalid File-Identity %q, must be %q or %q", g.File_Identity, identityPath, identityFingerprint)
+	}
 	return
 }
 
@@ -264,6 +412,14 @@ func (g *global) StatePath() string {
 	return g.State_Store_Location
 }
 
+// Identity returns how followed files are identified in the state file
+func (g *global) Identity() filewatch.IdentityConfig {
+	return filewatch.IdentityConfig{
+		Fingerprint:     g.File_Identity == identityFingerprint,
+		FingerprintSize: g.Fingerprint_Size,
+	}
+}
+
 func dumpStateFile(pth string) {
 	states, err := filewatch.DecodeStateFile(pth)
 	if err != nil {
@@ -273,5 +429,8 @@ func dumpStateFile(pth string) {
 	fmt.Printf("%-24s %-16s %s\n", "Listener Name", "File Offset", "File Path")
 	for _, state := range states {
 		fmt.Printf("%-24s %-16d %s\n", state.BaseName, state.State, state.FilePath)
+		if state.Fingerprint != nil {
+			fmt.Printf("%-24s %-16s %s (%d bytes)\n", "", "", state.Fingerprint.Hash, state.Fingerprint.Size)
+		}
 	}
 }
@@ -93,7 +93,7 @@ func main() {
 		src, _ = igst.SourceIP()
 	}
 
-	wtcher, err := filewatch.NewWatcher(cfg.StatePath())
+	wtcher, err := filewatch.NewWatcherWithIdentity(cfg.StatePath(), cfg.Identity())
 	if err != nil {
 		lg.Fatal("failed to create notification watcher", log.KVErr(err))
 	}
@@ -101,6 +101,8 @@ func main() {
 	//pass in the ingest muxer to the file watcher so it can throw info and errors down the muxer chan
 	wtcher.SetLogger(igst)
 	wtcher.SetMaxFilesWatched(cfg.Max_Files_Watched)
+	//file dispositions only run once the muxer has synced the entries
+	wtcher.SetSyncer(igst)
 
 	var procs []*processors.ProcessorSet
 
@@ -155,7 +157,19 @@ func main() {
 			Hnd:        lh,
 			Recursive:  val.Recursive,
 		}
-		if rex, ok, err := val.TimestampDelimited(); err != nil {
+		if c.PollInterval, err = val.PollInterval(); err != nil {
+			lg.FatalCode(0, "invalid poll interval", log.KVErr(err))
+		}
+		c.PollScanLimit = val.Poll_Scan_Limit
+		if c.Disposition, err = val.DispositionConfig(); err != nil {
+			lg.FatalCode(0, "invalid file disposition", log.KVErr(err))
+		}
+		if eng, args, ok, err := val.RecordEngine(); err != nil {
+			lg.FatalCode(0, "invalid record format", log.KVErr(err))
+		} else if ok {
+			c.Engine = eng
+			c.EngineArgs = args
+		} else if rex, ok, err := val.TimestampDelimited(); err != nil {
 			lg.FatalCode(0, "invalid timestamp delimiter", log.KVErr(err))
 		} else if ok {
 			c.Engine = filewatch.RegexEngine
@@ -76,7 +76,7 @@ func NewService(cfg *cfgType) (*mainService, error) {
 
 	debugout("Acquired tags and targets\n")
 	//fire up the watch manager
-	wtchr, err := filewatch.NewWatcher(cfg.StatePath())
+	wtchr, err := filewatch.NewWatcherWithIdentity(cfg.StatePath(), cfg.Identity())
 	if err != nil {
 		errorout("failed to open config path %s %v", cfg.StatePath(), err)
 		return nil, err
@@ -312,6 +312,8 @@ func (m *mainService) init(ctx context.Context) error {
 	}
 	infoout("Ingester established %d connections\n", hot)
 	m.wtchr.SetLogger(igst)
+	//file dispositions only run once the muxer has synced the entries
+	m.wtchr.SetSyncer(igst)
 
 	var src net.IP
 	if m.srcOverride != "" {
@@ -377,7 +379,22 @@ func (m *mainService) init(ctx context.Context) error {
 			Hnd:        lh,
 			Recursive:  val.Recursive,
 		}
-		if rex, ok, err := val.TimestampDelimited(); err != nil {
+		if c.PollInterval, err = val.PollInterval(); err != nil {
+			errorout("Invalid poll interval: %v\n", err)
+			return err
+		}
+		c.PollScanLimit = val.Poll_Scan_Limit
+		if c.Disposition, err = val.DispositionConfig(); err != nil {
+			errorout("Invalid file disposition: %v\n", err)
+			return err
+		}
+		if eng, args, ok, err := val.RecordEngine(); err != nil {
+			errorout("Invalid record format: %v\n", err)
+			return err
+		} else if ok {
+			c.Engine = eng
+			c.EngineArgs = args
+		} else if rex, ok, err := val.TimestampDelimited(); err != nil {
 			errorout("Invalid timestamp delimiter: %v\n", err)
 			return err
 		} else if ok {
@@ -0,0 +1,188 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/attach"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+)
+
+const (
+	defaultJournalPath  = `/var/log/journal`
+	defaultPollInterval = time.Second
+	minPollInterval     = 100 * time.Millisecond
+
+	formatJSON    = `json`
+	formatMessage = `message`
+
+	attachAll = `*`
+)
+
+type journalCfg struct {
+	Path              string   // journal directory or file, directories are searched for *.journal files
+	Tag_Name          string   // tag to ingest into
+	Format            string   // json or message
+	Attach_Fields     []string // fields attached as enumerated values when using the message format, defaults to all
+	Poll_Interval     string   // how often to check for new entries
+	Start_At_End      bool     // only ingest entries written after we first see a journal file
+	Ignore_Timestamps bool     // use the current time rather than __REALTIME_TIMESTAMP
+	Source_Override   string
+	Preprocessor      []string
+
+	src      net.IP
+	interval time.Duration
+}
+
+type global struct {
+	config.IngestConfig
+	State_Store_Location string
+}
+
+type cfgType struct {
+	Global       global
+	Attach       attach.AttachConfig
+	Journal      map[string]*journalCfg
+	Preprocessor processors.ProcessorConfig
+}
+
+func GetConfig(path, overlayPath string) (*cfgType, error) {
+	var c cfgType
+	if err := config.LoadConfigFile(&c, path); err != nil {
+		return nil, err
+	} else if err = config.LoadConfigOverlays(&c, overlayPath); err != nil {
+		return nil, err
+	}
+
+	if err := c.Verify(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *cfgType) Verify() error {
+	//verify the global parameters
+	if err := c.Global.Verify(); err != nil {
+		return err
+	} else if err = c.Attach.Verify(); err != nil {
+		return err
+	}
+	if c.Global.State_Store_Location == `` {
+		c.Global.State_Store_Location = defaultStateLoc
+	}
+
+	if len(c.Journal) == 0 {
+		return errors.New("No Journal readers specified")
+	}
+
+	if err := c.Preprocessor.Validate(); err != nil {
+		return err
+	}
+
+	for k, v := range c.Journal {
+		if err := v.verify(); err != nil {
+			return fmt.Errorf("Journal %s %w", k, err)
+		}
+		if err := c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("Journal %s preprocessor invalid: %v", k, err)
+		}
+	}
+
+	return nil
+}
+
+func (j *journalCfg) verify() (err error) {
+	if j.Path == `` {
+		j.Path = defaultJournalPath
+	}
+	j.Path = filepath.Clean(j.Path)
+	if len(j.Tag_Name) == 0 {
+		j.Tag_Name = entry.DefaultTagName
+	}
+	if ingest.CheckTag(j.Tag_Name) != nil {
+		return errors.New("Invalid characters in the Tag-Name")
+	}
+	switch j.Format = strings.ToLower(strings.TrimSpace(j.Format)); j.Format {
+	case ``:
+		j.Format = formatMessage
+	case formatMessage:
+	case formatJSON:
+		if len(j.Attach_Fields) > 0 {
+			return errors.New("Attach-Fields is only valid with the message format")
+		}
+	default:
+		return fmt.Errorf("invalid Format %q, options are json or message", j.Format)
+	}
+	j.interval = defaultPollInterval
+	if j.Poll_Interval != `` {
+		if j.interval, err = time.ParseDuration(j.Poll_Interval); err != nil {
+			return fmt.Errorf("invalid Poll-Interval %q: %w", j.Poll_Interval, err)
+		} else if j.interval < minPollInterval {
+			return fmt.Errorf("Poll-Interval must be at least %v", minPollInterval)
+		}
+	}
+	if j.Source_Override != `` {
+		if j.src = net.ParseIP(j.Source_Override); j.src == nil {
+			return fmt.Errorf("Invalid Source-Override %q", j.Source_Override)
+		}
+	}
+	return
+}
+
+// attachAll returns true if every field should be attached as an enumerated value
+func (j *journalCfg) attachAll() bool {
+	if len(j.Attach_Fields) == 0 {
+		return true
+	}
+	for _, v := range j.Attach_Fields {
+		if v == attachAll {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cfgType) Tags() ([]string, error) {
+	var tags []string
+	tagMp := make(map[string]bool, 1)
+
+	for _, v := range c.Journal {
+		if len(v.Tag_Name) == 0 {
+			continue
+		}
+		if _, ok := tagMp[v.Tag_Name]; !ok {
+			tags = append(tags, v.Tag_Name)
+			tagMp[v.Tag_Name] = true
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil, errors.New("No tags specified")
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func (c *cfgType) IngestBaseConfig() config.IngestConfig {
+	return c.Global.IngestConfig
+}
+
+func (c *cfgType) AttachConfig() attach.AttachConfig {
+	return c.Attach
+}
@@ -0,0 +1,306 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils/ingesttest"
+	"github.com/gravwell/gravwell/v3/journal"
+	"github.com/gravwell/gravwell/v3/journal/journaltest"
+)
+
+func TestMain(m *testing.M) {
+	lg = log.NewDiscardLogger()
+	os.Exit(m.Run())
+}
+
+func addMessages(jw *journaltest.Writer, start, cnt int) {
+	for i := start; i < start+cnt; i++ {
+		jw.Add(fmt.Sprintf("MESSAGE=message %d", i), `_SYSTEMD_UNIT=test.service`, `PRIORITY=6`)
+	}
+}
+
+func checkMessages(t *testing.T, got []string, start, cnt int) {
+	t.Helper()
+	if len(got) != cnt {
+		t.Fatalf("got %d entries, expected %d: %v", len(got), cnt, got)
+	}
+	for i, v := range got {
+		if v != fmt.Sprintf("message %d", start+i) {
+			t.Fatalf("bad entry %d: %q", i, v)
+		}
+	}
+}
+
+func stateKeys(s *stateTracker) (r []string) {
+	s.Lock()
+	for k := range s.cursors {
+		r = append(r, k)
+	}
+	s.Unlock()
+	return
+}
+
+type testJReader struct {
+	*jreader
+	tw *ingesttest.Writer
+}
+
+func newTestJReader(t *testing.T, cfg *journalCfg, statePath string) testJReader {
+	if err := cfg.verify(); err != nil {
+		t.Fatal(err)
+	}
+	st, err := newStateTracker(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := &ingesttest.Writer{}
+	jr := newJReader(`test`, cfg, entry.EntryTag(1), processors.NewProcessorSet(tw), st, context.Background(), &sync.WaitGroup{})
+	return testJReader{jreader: jr, tw: tw}
+}
+
+// poll does a single pass the same way run does
+func (tj testJReader) poll(initial bool) []string {
+	tj.scan(initial)
+	tj.readAll()
+	return messages(tj.tw.Take())
+}
+
+func messages(ents []*entry.Entry) (r []string) {
+	for _, ent := range ents {
+		r = append(r, string(ent.Data))
+	}
+	return
+}
+
+func TestReaderCursor(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(t.TempDir(), `journald.state`)
+	pth := filepath.Join(dir, `system.journal`)
+	jw := journaltest.NewWriter(false, journaltest.None, 4)
+	addMessages(jw, 0, 10)
+	if err := jw.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+
+	tj := newTestJReader(t, &journalCfg{Path: dir}, statePath)
+	checkMessages(t, tj.poll(true), 0, 10)
+	//the active file keeps growing
+	addMessages(jw, 10, 5)
+	if err := jw.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+	checkMessages(t, tj.poll(false), 10, 5)
+	if cur, ok := tj.state.get(`test/` + journal.ID128{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}.String()); !ok || cur.Seqnum != 115 {
+		t.Fatalf("bad cursor %v %v", cur, ok)
+	} else if err := tj.state.flush(); err != nil {
+		t.Fatal(err)
+	}
+	tj.close()
+
+	//a restart resumes after the saved cursor, even when told to start at the end
+	addMessages(jw, 15, 3)
+	if err := jw.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+	tj = newTestJReader(t, &journalCfg{Path: dir, Start_At_End: true}, statePath)
+	checkMessages(t, tj.poll(true), 15, 3)
+	tj.close()
+
+	//a corrupt cursor is dropped and the file is read from the beginning
+	if err := tj.state.st.Write(map[string]string{stateKeys(tj.state)[0]: `garbage`}); err != nil {
+		t.Fatal(err)
+	}
+	tj = newTestJReader(t, &journalCfg{Path: dir}, statePath)
+	checkMessages(t, tj.poll(true), 0, 18)
+	tj.close()
+}
+
+func TestReaderStartAtEnd(t *testing.T) {
+	dir := t.TempDir()
+	pth := filepath.Join(dir, `system.journal`)
+	jw := journaltest.NewWriter(true, journaltest.ZSTD, 4)
+	addMessages(jw, 0, 10)
+	if err := jw.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+	tj := newTestJReader(t, &journalCfg{Path: dir, Start_At_End: true}, filepath.Join(t.TempDir(), `state`))
+	defer tj.close()
+	if got := tj.poll(true); len(got) != 0 {
+		t.Fatalf("read existing entries when starting at the end: %v", got)
+	}
+	addMessages(jw, 10, 2)
+	if err := jw.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+	checkMessages(t, tj.poll(false), 10, 2)
+
+	//files that show up after startup are new and read from the beginning
+	other := journaltest.NewWriter(true, journaltest.ZSTD, 4)
+	other.SetFileID([16]byte{0xff})
+	addMessages(other, 100, 3)
+	if err := other.Flush(filepath.Join(dir, `user-1000.journal`)); err != nil {
+		t.Fatal(err)
+	}
+	checkMessages(t, tj.poll(false), 100, 3)
+}
+
+func TestReaderArchive(t *testing.T) {
+	dir := t.TempDir()
+	pth := filepath.Join(dir, `system.journal`)
+	jw := journaltest.NewWriter(false, journaltest.LZ4, 4)
+	addMessages(jw, 0, 5)
+	if err := jw.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+	tj := newTestJReader(t, &journalCfg{Path: dir}, filepath.Join(t.TempDir(), `state`))
+	defer tj.close()
+	checkMessages(t, tj.poll(true), 0, 5)
+
+	//journald writes a few more entries, archives the file by renaming it, and starts a new active file
+	addMessages(jw, 5, 2)
+	jw.SetState(journaltest.StateArchived)
+	if err := jw.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+	archived := filepath.Join(dir, `system@0001-0002.journal`)
+	if err := os.Rename(pth, archived); err != nil {
+		t.Fatal(err)
+	}
+	next := journaltest.NewWriter(false, journaltest.LZ4, 4)
+	next.SetFileID([16]byte{0xaa})
+	next.SetSeqnum(107)
+	addMessages(next, 7, 3)
+	if err := next.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+	checkMessages(t, tj.poll(false), 5, 5)
+	if len(tj.files) != 1 || len(tj.done) != 1 {
+		t.Fatalf("archived file was not retired: %d open %d done", len(tj.files), len(tj.done))
+	}
+	//the archive is never read again
+	if got := tj.poll(false); len(got) != 0 {
+		t.Fatalf("archived file was re-read: %v", got)
+	}
+
+	//vacuuming the archive drops its state
+	if err := os.Remove(archived); err != nil {
+		t.Fatal(err)
+	}
+	tj.poll(false)
+	if len(tj.done) != 0 || len(stateKeys(tj.state)) != 1 {
+		t.Fatalf("vacuumed file state was not dropped: %d done %v", len(tj.done), stateKeys(tj.state))
+	}
+}
+
+func TestReaderMessageFormat(t *testing.T) {
+	dir := t.TempDir()
+	jw := journaltest.NewWriter(false, journaltest.XZ, 4)
+	jw.Add(`MESSAGE=hello`, `_SYSTEMD_UNIT=test.service`, `PRIORITY=6`, `_PID=1`)
+	jw.Add(`_SYSTEMD_UNIT=test.service`) //no message, nothing to ingest
+	jw.Add(`MESSAGE=`, `PRIORITY=3`)     //empty message
+	jw.Add(`MESSAGE=world`, `PRIORITY=3`)
+	if err := jw.Flush(filepath.Join(dir, `system.journal`)); err != nil {
+		t.Fatal(err)
+	}
+	tj := newTestJReader(t, &journalCfg{Path: dir, Attach_Fields: []string{`PRIORITY`, `_SYSTEMD_UNIT`}}, filepath.Join(t.TempDir(), `state`))
+	defer tj.close()
+	tj.scan(true)
+	tj.readAll()
+	ents := tj.tw.Take()
+	if len(ents) != 2 {
+		t.Fatalf("got %d entries, expected 2", len(ents))
+	}
+	ent := ents[0]
+	if string(ent.Data) != `hello` || ent.Tag != 1 {
+		t.Fatalf("bad entry %q %v", ent.Data, ent.Tag)
+	} else if !ent.TS.StandardTime().Equal(time.UnixMicro(journaltest.BaseRealtime + 101)) {
+		t.Fatalf("bad timestamp %v", ent.TS)
+	}
+	evs := ent.EnumeratedValues()
+	if len(evs) != 2 || evs[0].Name != `_SYSTEMD_UNIT` || evs[0].Value.String() != `test.service` || evs[1].Name != `PRIORITY` {
+		t.Fatalf("bad enumerated values %v", evs)
+	}
+
+	//fields that can't be decompressed are dropped and counted without stalling the reader
+	dir = t.TempDir()
+	jw = journaltest.NewWriter(false, journaltest.Unknown, 4)
+	jw.Add(`MESSAGE=lost`, `PRIORITY=6`)
+	if err := jw.Flush(filepath.Join(dir, `system.journal`)); err != nil {
+		t.Fatal(err)
+	}
+	tj = newTestJReader(t, &journalCfg{Path: dir}, filepath.Join(t.TempDir(), `state`))
+	defer tj.close()
+	if got := tj.poll(true); len(got) != 0 {
+		t.Fatalf("ingested entry without a message: %v", got)
+	} else if tj.dropped != 2 {
+		t.Fatalf("bad dropped count %d", tj.dropped)
+	} else if len(stateKeys(tj.state)) != 1 {
+		t.Fatal("cursor did not advance past the entry with dropped fields")
+	}
+}
+
+func TestJSONEntry(t *testing.T) {
+	ent := journal.Entry{
+		Seqnum:    7,
+		Realtime:  1700000000000123,
+		Monotonic: 4567,
+		BootID:    journal.ID128{0xab},
+		Fields: []journal.Field{
+			{Name: `MESSAGE`, Value: []byte(`hello`)},
+			{Name: `TAG`, Value: []byte(`a`)},
+			{Name: `TAG`, Value: []byte(`b`)},
+			{Name: `TAG`, Value: []byte(`c`)},
+			{Name: `BINARY`, Value: []byte{0xff, 0x00, 'a'}},
+		},
+	}
+	cur := journal.Cursor{Seqnum: 7, Realtime: ent.Realtime}
+	b, err := json.Marshal(jsonEntry(ent, cur))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err = json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		`__CURSOR`:              cur.String(),
+		`__REALTIME_TIMESTAMP`:  `1700000000000123`,
+		`__MONOTONIC_TIMESTAMP`: `4567`,
+		`_BOOT_ID`:              journal.ID128{0xab}.String(),
+		`MESSAGE`:               `hello`,
+		`TAG`:                   []interface{}{`a`, `b`, `c`},
+		`BINARY`:                []interface{}{float64(255), float64(0), float64('a')},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("bad field count %d != %d: %s", len(got), len(want), b)
+	}
+	for k, v := range want {
+		if fmt.Sprint(got[k]) != fmt.Sprint(v) {
+			t.Fatalf("bad %s: %v != %v", k, got[k], v)
+		}
+	}
+
+	//an explicit boot ID field wins over the entry header
+	ent.Fields = append(ent.Fields, journal.Field{Name: `_BOOT_ID`, Value: []byte(`explicit`)})
+	if mp := jsonEntry(ent, cur); mp[`_BOOT_ID`] != `explicit` {
+		t.Fatalf("bad boot id %v", mp[`_BOOT_ID`])
+	}
+}
@@ -0,0 +1,147 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// The journald ingester reads systemd journal files directly, no journalctl or libsystemd required
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/debug"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/base"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+)
+
+const (
+	defaultConfigLoc  = `/opt/gravwell/etc/journald.conf`
+	defaultConfigDLoc = `/opt/gravwell/etc/journald.conf.d`
+	defaultStateLoc   = `/opt/gravwell/etc/journald.state`
+	ingesterName      = `journald`
+
+	stateFlushInterval = 10 * time.Second
+)
+
+var (
+	lg *log.Logger
+)
+
+func main() {
+	go debug.HandleDebugSignals(ingesterName)
+
+	var cfg *cfgType
+	ibc := base.IngesterBaseConfig{
+		IngesterName:                 ingesterName,
+		AppName:                      ingesterName,
+		DefaultConfigLocation:        defaultConfigLoc,
+		DefaultConfigOverlayLocation: defaultConfigDLoc,
+		GetConfigFunc:                GetConfig,
+	}
+	ib, err := base.Init(ibc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to get configuration %v\n", err)
+		return
+	} else if err = ib.AssignConfig(&cfg); err != nil || cfg == nil {
+		fmt.Fprintf(os.Stderr, "failed to assign configuration %v %v\n", err, cfg == nil)
+		return
+	}
+	lg = ib.Logger
+
+	id, ok := cfg.Global.IngesterUUID()
+	if !ok {
+		lg.FatalCode(0, "could not read ingester UUID")
+	}
+
+	state, err := newStateTracker(cfg.Global.State_Store_Location)
+	if err != nil {
+		lg.FatalCode(0, "failed to open state file", log.KV("path", cfg.Global.State_Store_Location), log.KVErr(err))
+	}
+
+	igst, err := ib.GetMuxer()
+	if err != nil {
+		lg.FatalCode(0, "failed to get ingest connection", log.KVErr(err))
+		return
+	}
+	defer igst.Close()
+	ib.AnnounceStartup()
+
+	var globalSrc net.IP
+	if cfg.Global.Source_Override != `` {
+		if globalSrc = net.ParseIP(cfg.Global.Source_Override); globalSrc == nil {
+			lg.FatalCode(0, "Global Source-Override is invalid", log.KV("sourceoverride", cfg.Global.Source_Override))
+		}
+	}
+
+	var wg sync.WaitGroup
+	var procs []*processors.ProcessorSet
+	ctx, cancel := context.WithCancel(context.Background())
+	for k, v := range cfg.Journal {
+		if v.src == nil {
+			v.src = globalSrc
+		}
+		tag, err := igst.GetTag(v.Tag_Name)
+		if err != nil {
+			lg.FatalCode(0, "failed to resolve tag", log.KV("journal", k), log.KV("tag", v.Tag_Name), log.KVErr(err))
+		}
+		proc, err := cfg.Preprocessor.ProcessorSet(igst, v.Preprocessor)
+		if err != nil {
+			lg.FatalCode(0, "preprocessor construction error", log.KV("journal", k), log.KVErr(err))
+		}
+		procs = append(procs, proc)
+		wg.Add(1)
+		go newJReader(k, v, tag, proc, state, ctx, &wg).run()
+	}
+
+	//periodically push cursors out so a crash does not re-ingest everything
+	flushDone := make(chan bool)
+	go func() {
+		defer close(flushDone)
+		tckr := time.NewTicker(stateFlushInterval)
+		defer tckr.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tckr.C:
+				if err := state.flush(); err != nil {
+					lg.Error("failed to write state file", log.KV("path", cfg.Global.State_Store_Location), log.KVErr(err))
+				}
+			}
+		}
+	}()
+
+	utils.WaitForQuit()
+	ib.AnnounceShutdown()
+
+	cancel()
+	wg.Wait()
+	<-flushDone
+
+	for _, p := range procs {
+		if err := p.Close(); err != nil {
+			lg.Error("failed to close preprocessors", log.KVErr(err))
+		}
+	}
+
+	lg.Info("journald ingester exiting", log.KV("ingesteruuid", id))
+	if err := igst.Sync(utils.ExitSyncTimeout); err != nil {
+		lg.Error("failed to sync", log.KVErr(err))
+	}
+	if err := state.flush(); err != nil {
+		lg.Error("failed to write state file", log.KV("path", cfg.Global.State_Store_Location), log.KVErr(err))
+	}
+	if err := igst.Close(); err != nil {
+		lg.Error("failed to close", log.KVErr(err))
+	}
+}
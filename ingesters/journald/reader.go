@@ -0,0 +1,335 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/journal"
+)
+
+const (
+	journalSuffix = `.journal`
+	messageField  = `MESSAGE`
+)
+
+// journalFile is a journal file we are reading entries out of
+type journalFile struct {
+	pth string
+	key string
+	jf  *journal.File
+	rdr *journal.Reader
+}
+
+// jreader follows all of the journal files under a configured path
+type jreader struct {
+	name   string
+	cfg    *journalCfg
+	tag    entry.EntryTag
+	proc   *processors.ProcessorSet
+	state  *stateTracker
+	ctx    context.Context
+	wg     *sync.WaitGroup
+	attach map[string]bool
+
+	files   map[string]*journalFile // open files keyed by state key
+	done    map[string]bool         // archived files we have finished reading, keyed by state key
+	paths   map[string]knownPath    // every known path and the file it pointed at
+	dropped uint64                  // fields dropped because they could not be decompressed
+}
+
+type knownPath struct {
+	key string
+	fi  os.FileInfo
+}
+
+func newJReader(name string, cfg *journalCfg, tag entry.EntryTag, proc *processors.ProcessorSet, st *stateTracker, ctx context.Context, wg *sync.WaitGroup) *jreader {
+	jr := &jreader{
+		name:  name,
+		cfg:   cfg,
+		tag:   tag,
+		proc:  proc,
+		state: st,
+		ctx:   ctx,
+		wg:    wg,
+		files: map[string]*journalFile{},
+		done:  map[string]bool{},
+		paths: map[string]knownPath{},
+	}
+	if !cfg.attachAll() {
+		jr.attach = make(map[string]bool, len(cfg.Attach_Fields))
+		for _, v := range cfg.Attach_Fields {
+			jr.attach[v] = true
+		}
+	}
+	return jr
+}
+
+func (jr *jreader) run() {
+	defer jr.wg.Done()
+	defer jr.close()
+	tckr := time.NewTicker(jr.cfg.interval)
+	defer tckr.Stop()
+	initial := true
+	for {
+		jr.scan(initial)
+		jr.readAll()
+		initial = false
+		select {
+		case <-jr.ctx.Done():
+			return
+		case <-tckr.C:
+		}
+	}
+}
+
+func (jr *jreader) close() {
+	for k, v := range jr.files {
+		v.jf.Close()
+		delete(jr.files, k)
+	}
+}
+
+// scan looks for new, renamed, and removed journal files
+func (jr *jreader) scan(initial bool) {
+	found := map[string]os.FileInfo{}
+	err := filepath.WalkDir(jr.cfg.Path, func(pth string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if pth == jr.cfg.Path {
+				return err
+			}
+			return nil //a subdirectory went away or isn't readable, just skip it
+		}
+		//journald appends a ~ to files it could not cleanly close, those are skipped just like journalctl
+		if d.Type().IsRegular() && strings.HasSuffix(d.Name(), journalSuffix) {
+			if fi, err := d.Info(); err == nil {
+				found[pth] = fi
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		lg.Warn("failed to scan journal path", log.KV("journal", jr.name), log.KV("path", jr.cfg.Path), log.KVErr(err))
+		return
+	}
+	//forget paths that went away or now point at a different file, journald replaces the active file when archiving
+	for pth, kp := range jr.paths {
+		if fi, ok := found[pth]; !ok || !os.SameFile(fi, kp.fi) {
+			delete(jr.paths, pth)
+		}
+	}
+	var opened []*journalFile
+	for pth, fi := range found {
+		if _, ok := jr.paths[pth]; ok {
+			continue
+		}
+		jf, err := journal.Open(pth)
+		if err != nil {
+			lg.Warn("failed to open journal file", log.KV("journal", jr.name), log.KV("path", pth), log.KVErr(err))
+			continue
+		}
+		key := jr.name + `/` + jf.Header().FileID.String()
+		jr.paths[pth] = knownPath{key: key, fi: fi}
+		if f, ok := jr.files[key]; ok {
+			//journald renamed the file when it was archived, keep using the handle we have
+			jf.Close()
+			f.pth = pth
+			continue
+		} else if jr.done[key] {
+			//renamed archive we have already finished with
+			jf.Close()
+			continue
+		}
+		f := &journalFile{pth: pth, key: key, jf: jf, rdr: journal.NewReader(jf)}
+		if err = jr.seek(f, initial); err != nil {
+			lg.Warn("failed to seek journal file", log.KV("journal", jr.name), log.KV("path", pth), log.KVErr(err))
+			jf.Close()
+			delete(jr.paths, pth)
+			continue
+		}
+		opened = append(opened, f)
+	}
+	//older files first so that archived entries are ingested in order
+	sort.Slice(opened, func(i, j int) bool {
+		return opened[i].jf.Header().HeadEntryRealtime < opened[j].jf.Header().HeadEntryRealtime
+	})
+	for _, f := range opened {
+		lg.Info("following journal file", log.KV("journal", jr.name), log.KV("path", f.pth))
+		jr.files[f.key] = f
+	}
+	//anything that no longer has a path was vacuumed or moved away
+	present := make(map[string]bool, len(jr.paths))
+	for _, kp := range jr.paths {
+		present[kp.key] = true
+	}
+	for key, f := range jr.files {
+		if !present[key] {
+			lg.Info("journal file removed", log.KV("journal", jr.name), log.KV("path", f.pth))
+			f.jf.Close()
+			delete(jr.files, key)
+			jr.state.remove(key)
+		}
+	}
+	for key := range jr.done {
+		if !present[key] {
+			delete(jr.done, key)
+			jr.state.remove(key)
+		}
+	}
+}
+
+// seek positions a newly opened file using the saved cursor
+func (jr *jreader) seek(f *journalFile, initial bool) error {
+	if cur, ok := jr.state.get(f.key); ok {
+		return f.rdr.SeekSeqnum(cur.Seqnum)
+	} else if initial && jr.cfg.Start_At_End {
+		return f.rdr.SeekTail()
+	}
+	return nil //new file, start at the beginning
+}
+
+// readAll drains every open file
+func (jr *jreader) readAll() {
+	keys := make([]string, 0, len(jr.files))
+	for k := range jr.files {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return jr.files[keys[i]].jf.Header().HeadEntryRealtime < jr.files[keys[j]].jf.Header().HeadEntryRealtime
+	})
+	for _, k := range keys {
+		if jr.ctx.Err() != nil {
+			return
+		}
+		f := jr.files[k]
+		if err := f.jf.Refresh(); err != nil {
+			lg.Warn("failed to read journal header", log.KV("journal", jr.name), log.KV("path", f.pth), log.KVErr(err))
+			continue
+		}
+		archived := f.jf.Header().State == journal.StateArchived
+		if err := jr.read(f); err != nil {
+			lg.Warn("failed to read journal entry", log.KV("journal", jr.name), log.KV("path", f.pth), log.KVErr(err))
+			continue
+		}
+		if archived {
+			//archived files never change, we are done with it
+			f.jf.Close()
+			delete(jr.files, k)
+			jr.done[k] = true
+		}
+	}
+}
+
+func (jr *jreader) read(f *journalFile) error {
+	hdr := f.jf.Header()
+	for jr.ctx.Err() == nil {
+		ent, ok, err := f.rdr.Next()
+		if err != nil {
+			return err
+		} else if !ok {
+			break
+		}
+		cur := journal.CursorOf(hdr, ent)
+		if ent.Dropped > 0 {
+			jr.dropped += uint64(ent.Dropped)
+			lg.Warn("dropped journal fields that could not be decompressed",
+				log.KV("journal", jr.name), log.KV("path", f.pth), log.KV("cursor", cur.String()),
+				log.KV("dropped", ent.Dropped), log.KV("total-dropped", jr.dropped))
+		}
+		if e := jr.buildEntry(ent, cur); e != nil {
+			if err = jr.proc.ProcessContext(e, jr.ctx); err != nil {
+				return err
+			}
+		}
+		jr.state.set(f.key, cur)
+	}
+	return nil
+}
+
+// buildEntry turns a journal entry into a gravwell entry, nil means there was nothing to ingest
+func (jr *jreader) buildEntry(ent journal.Entry, cur journal.Cursor) *entry.Entry {
+	e := &entry.Entry{
+		Tag: jr.tag,
+		SRC: jr.cfg.src,
+	}
+	if jr.cfg.Ignore_Timestamps {
+		e.TS = entry.Now()
+	} else {
+		e.TS = entry.FromStandard(time.UnixMicro(int64(ent.Realtime)))
+	}
+	if jr.cfg.Format == formatJSON {
+		b, err := json.Marshal(jsonEntry(ent, cur))
+		if err != nil {
+			lg.Warn("failed to encode journal entry", log.KV("journal", jr.name), log.KVErr(err))
+			return nil
+		}
+		e.Data = b
+		return e
+	}
+	msg, ok := ent.Get(messageField)
+	if !ok || len(msg) == 0 {
+		return nil
+	}
+	e.Data = append([]byte(nil), msg...)
+	for _, fld := range ent.Fields {
+		if fld.Name == messageField || (jr.attach != nil && !jr.attach[fld.Name]) {
+			continue
+		}
+		e.AddEnumeratedValue(entry.EnumeratedValue{Name: fld.Name, Value: entry.StringEnumData(string(fld.Value))})
+	}
+	return e
+}
+
+// jsonEntry renders an entry the same way journalctl -o json does, fields that show up more than
+// once become arrays and values that are not valid UTF-8 become arrays of byte values
+func jsonEntry(ent journal.Entry, cur journal.Cursor) map[string]interface{} {
+	mp := make(map[string]interface{}, len(ent.Fields)+4)
+	mp[`__CURSOR`] = cur.String()
+	mp[`__REALTIME_TIMESTAMP`] = strconv.FormatUint(ent.Realtime, 10)
+	mp[`__MONOTONIC_TIMESTAMP`] = strconv.FormatUint(ent.Monotonic, 10)
+	for _, fld := range ent.Fields {
+		v := jsonValue(fld.Value)
+		switch x := mp[fld.Name].(type) {
+		case nil:
+			mp[fld.Name] = v
+		case []interface{}:
+			mp[fld.Name] = append(x, v)
+		default:
+			mp[fld.Name] = []interface{}{x, v}
+		}
+	}
+	if _, ok := mp[`_BOOT_ID`]; !ok {
+		mp[`_BOOT_ID`] = ent.BootID.String()
+	}
+	return mp
+}
+
+func jsonValue(b []byte) interface{} {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	vals := make([]int, len(b))
+	for i := range b {
+		vals[i] = int(b[i])
+	}
+	return vals
+}
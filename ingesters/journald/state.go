@@ -0,0 +1,92 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"sync"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/gravwell/gravwell/v3/journal"
+)
+
+// stateTracker holds the cursor of the last entry ingested from each journal file.
+// Files are keyed by the journal config name and the file ID in the journal header, the file ID
+// survives journald renaming the active journal when it is archived.
+type stateTracker struct {
+	sync.Mutex
+	st      *utils.State
+	cursors map[string]journal.Cursor
+	dirty   bool
+}
+
+func newStateTracker(pth string) (*stateTracker, error) {
+	st, err := utils.NewState(pth, 0600)
+	if err != nil {
+		return nil, err
+	}
+	saved := map[string]string{}
+	if err = st.Read(&saved); err != nil && err != utils.ErrNoState {
+		return nil, err
+	}
+	s := &stateTracker{
+		st:      st,
+		cursors: make(map[string]journal.Cursor, len(saved)),
+	}
+	for k, v := range saved {
+		cur, err := journal.ParseCursor(v)
+		if err != nil {
+			lg.Warn("dropping invalid journal cursor", log.KV("file", k), log.KVErr(err))
+			continue
+		}
+		s.cursors[k] = cur
+	}
+	return s, nil
+}
+
+func (s *stateTracker) get(key string) (cur journal.Cursor, ok bool) {
+	s.Lock()
+	cur, ok = s.cursors[key]
+	s.Unlock()
+	return
+}
+
+func (s *stateTracker) set(key string, cur journal.Cursor) {
+	s.Lock()
+	s.cursors[key] = cur
+	s.dirty = true
+	s.Unlock()
+}
+
+func (s *stateTracker) remove(key string) {
+	s.Lock()
+	if _, ok := s.cursors[key]; ok {
+		delete(s.cursors, key)
+		s.dirty = true
+	}
+	s.Unlock()
+}
+
+// flush writes the cursors out if anything changed since the last flush
+func (s *stateTracker) flush() error {
+	s.Lock()
+	defer s.Unlock()
+	if !s.dirty {
+		return nil
+	}
+	saved := make(map[string]string, len(s.cursors))
+	for k, v := range s.cursors {
+		saved[k] = v.String()
+	}
+	if err := s.st.Write(saved); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
@@ -0,0 +1,229 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+const (
+	maxAvroItems = 1 << 20 // cap on array lengths so a bad block count can't allocate without bound
+	maxAvroBytes = 64 * mb // cap on bytes and string lengths
+)
+
+var (
+	ErrInvalidAvroSchema = errors.New("Invalid Avro schema")
+	ErrInvalidAvroData   = errors.New("Invalid Avro data")
+
+	//unions with primitive branches decode to the bare value, anything else is keyed by the branch name
+	avroAPI = avro.Config{
+		PartialUnionTypeResolution: true,
+		MaxByteSliceSize:           maxAvroBytes,
+		MaxSliceAllocSize:          maxAvroItems,
+	}.Freeze()
+)
+
+// parseAvroSchema parses a JSON schema, named types are added to names so that later schemas can reference them
+func parseAvroSchema(js []byte, names *avro.SchemaCache) (avro.Schema, error) {
+	s, err := avro.ParseBytesWithCache(js, ``, names)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAvroSchema, err)
+	}
+	return s, nil
+}
+
+// decodeAvro decodes a single datum to JSON
+func decodeAvro(s avro.Schema, b []byte) ([]byte, error) {
+	var v interface{}
+	//the reader reports running off the end of the data as an error, avro.Unmarshal does not
+	r := avro.NewReader(nil, 0, avro.WithReaderConfig(avroAPI)).Reset(b)
+	if r.ReadVal(s, &v); r.Error != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAvroData, r.Error)
+	} else if r.Peek(); r.Error == nil {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidAvroData)
+	}
+	return json.Marshal(avroJSON(s, v))
+}
+
+// avroField is a single named value in a record
+type avroField struct {
+	name  string
+	value interface{}
+}
+
+// avroRecord is a decoded record with its fields in schema order
+type avroRecord []avroField
+
+// MarshalJSON renders the record as an object, preserving the field order
+func (r avroRecord) MarshalJSON() ([]byte, error) {
+	bb := bytes.NewBuffer(make([]byte, 0, 32*len(r)))
+	bb.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			bb.WriteByte(',')
+		}
+		k, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		bb.Write(k)
+		bb.WriteByte(':')
+		bb.Write(v)
+	}
+	bb.WriteByte('}')
+	return bb.Bytes(), nil
+}
+
+// avroJSON converts a decoded datum into something that renders as JSON, records keep their
+// field order and unions are written as the bare value rather than wrapped in an object naming the branch.
+// The schema may be nil when a union branch can't be identified, the value is then rendered on its own.
+func avroJSON(s avro.Schema, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if ref, ok := s.(*avro.RefSchema); ok {
+		s = ref.Schema()
+	}
+	switch st := s.(type) {
+	case *avro.RecordSchema:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+		r := make(avroRecord, 0, len(st.Fields()))
+		for _, f := range st.Fields() {
+			r = append(r, avroField{name: f.Name(), value: avroJSON(f.Type(), m[f.Name()])})
+		}
+		return r
+	case *avro.UnionSchema:
+		return avroJSON(unionBranch(st, v))
+	case *avro.ArraySchema:
+		l, ok := v.([]interface{})
+		if !ok {
+			break
+		}
+		for i := range l {
+			l[i] = avroJSON(st.Items(), l[i])
+		}
+		return l
+	case *avro.MapSchema:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for k, x := range m {
+			m[k] = avroJSON(st.Values(), x)
+		}
+		return m
+	}
+
+	switch x := v.(type) {
+	case time.Time:
+		switch avroLogical(s) {
+		case avro.Date:
+			return x.UTC().Format(`2006-01-02`)
+		case avro.LocalTimestampMillis, avro.LocalTimestampMicros:
+			return x.UTC().Format(`2006-01-02T15:04:05.999999999`)
+		}
+		return x.UTC().Format(time.RFC3339Nano)
+	case time.Duration:
+		//times of day
+		return time.Time{}.Add(x).Format(`15:04:05.999999`)
+	case *big.Rat:
+		var scale int
+		if ls, ok := s.(avro.LogicalTypeSchema); ok {
+			if dec, ok := ls.Logical().(*avro.DecimalLogicalSchema); ok {
+				scale = dec.Scale()
+			}
+		}
+		return json.Number(x.FloatString(scale))
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return nil //JSON has no way to say these
+		}
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil
+		}
+	}
+	//fixed values decode to byte arrays, render them base64 encoded like bytes
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return b
+	}
+	return v
+}
+
+// unionBranch finds the branch a decoded union value came from and unwraps it
+func unionBranch(u *avro.UnionSchema, v interface{}) (avro.Schema, interface{}) {
+	if m, ok := v.(map[string]interface{}); ok && len(m) == 1 {
+		for k, x := range m {
+			for _, b := range u.Types() {
+				if avroTypeName(b) == k {
+					return b, x
+				}
+			}
+		}
+	}
+	//primitive branches are bare, only logical types need the schema to render
+	for _, b := range u.Types() {
+		switch avroLogical(b) {
+		case avro.Decimal:
+			if _, ok := v.(*big.Rat); ok {
+				return b, v
+			}
+		case avro.Date, avro.TimestampMillis, avro.TimestampMicros, avro.LocalTimestampMillis, avro.LocalTimestampMicros:
+			if _, ok := v.(time.Time); ok {
+				return b, v
+			}
+		}
+	}
+	return nil, v
+}
+
+// avroTypeName is the name a union branch is keyed by
+func avroTypeName(s avro.Schema) string {
+	if ref, ok := s.(*avro.RefSchema); ok {
+		s = ref.Schema()
+	}
+	if n, ok := s.(avro.NamedSchema); ok {
+		return n.FullName()
+	}
+	name := string(s.Type())
+	if lt := avroLogical(s); lt != `` {
+		name += `.` + string(lt)
+	}
+	return name
+}
+
+func avroLogical(s avro.Schema) avro.LogicalType {
+	if ref, ok := s.(*avro.RefSchema); ok {
+		s = ref.Schema()
+	}
+	if ls, ok := s.(avro.LogicalTypeSchema); ok {
+		if l := ls.Logical(); l != nil && !reflect.ValueOf(l).IsNil() {
+			return l.Type()
+		}
+	}
+	return ``
+}
@@ -0,0 +1,170 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/hamba/avro/v2"
+)
+
+const testAvroSchema = `{
+	"type": "record", "name": "Event", "namespace": "com.example",
+	"fields": [
+		{"name": "id", "type": "long"},
+		{"name": "host", "type": ["null", "string"]},
+		{"name": "ok", "type": "boolean"},
+		{"name": "score", "type": "double"},
+		{"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["INFO", "WARN", "ERROR"]}},
+		{"name": "tags", "type": {"type": "array", "items": "string"}},
+		{"name": "attrs", "type": {"type": "map", "values": "int"}},
+		{"name": "ts", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+		{"name": "day", "type": {"type": "int", "logicalType": "date"}},
+		{"name": "cost", "type": {"type": "bytes", "logicalType": "decimal", "precision": 8, "scale": 2}},
+		{"name": "raw", "type": {"type": "fixed", "name": "Raw", "size": 2}},
+		{"name": "next", "type": ["null", "Event"]}
+	]
+}`
+
+// test encoders, avro longs are zig-zag varints which is what encoding/binary does for signed values
+func avLong(v int64) []byte {
+	return binary.AppendVarint(nil, v)
+}
+
+func avString(s string) []byte {
+	return append(avLong(int64(len(s))), s...)
+}
+
+func avDouble(v float64) []byte {
+	return binary.LittleEndian.AppendUint64(nil, math.Float64bits(v))
+}
+
+func cat(parts ...[]byte) (r []byte) {
+	for _, p := range parts {
+		r = append(r, p...)
+	}
+	return
+}
+
+func testAvroEvent(id int64, next []byte) []byte {
+	var nextB []byte
+	if next == nil {
+		nextB = avLong(0)
+	} else {
+		nextB = cat(avLong(1), next)
+	}
+	return cat(
+		avLong(id),
+		avLong(1), avString(`web1`),
+		[]byte{1},
+		avDouble(1.5),
+		avLong(2),
+		avLong(2), avString(`a`), avString(`b`), avLong(0),
+		avLong(-1), avLong(6), avString(`k`), avLong(-3), avLong(0), //negative block counts carry a byte size
+		avLong(1709528767000),
+		avLong(19786),
+		avLong(2), []byte{0xfe, 0x0c}, //-500
+		[]byte{0xde, 0xad},
+		nextB,
+	)
+}
+
+func TestAvroDecode(t *testing.T) {
+	s, err := parseAvroSchema([]byte(testAvroSchema), &avro.SchemaCache{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := testAvroEvent(2, nil)
+	js, err := decodeAvro(s, testAvroEvent(1, inner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ev := `"host":"web1","ok":true,"score":1.5,"level":"ERROR","tags":["a","b"],"attrs":{"k":-3},"ts":"2024-03-04T05:06:07Z","day":"2024-03-04","cost":-5.00,"raw":"3q0="`
+	exp := `{"id":1,` + ev + `,"next":{"id":2,` + ev + `,"next":null}}`
+	if string(js) != exp {
+		t.Fatalf("bad decode\n%s\n%s", js, exp)
+	}
+
+	//truncated, trailing, and garbage data must all fail without panicking
+	good := testAvroEvent(1, nil)
+	for i := 0; i < len(good); i++ {
+		if _, err = decodeAvro(s, good[:i]); err == nil {
+			t.Fatalf("failed to catch truncation at %d", i)
+		}
+	}
+	if _, err = decodeAvro(s, append(good, 0)); err == nil {
+		t.Fatal("failed to catch trailing data")
+	}
+	bad := append([]byte(nil), good...)
+	bad[len(avLong(1))] = 0x08 //union index 4
+	if _, err = decodeAvro(s, bad); err == nil {
+		t.Fatal("failed to catch bad union index")
+	}
+}
+
+func TestAvroReferences(t *testing.T) {
+	names := &avro.SchemaCache{}
+	if _, err := parseAvroSchema([]byte(`{"type":"fixed","name":"com.other.Id","size":1}`), names); err != nil {
+		t.Fatal(err)
+	}
+	s, err := parseAvroSchema([]byte(`{"type":"record","name":"Wrap","namespace":"com.other","fields":[{"name":"id","type":"Id"},{"name":"f","type":"float"}]}`), names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := decodeAvro(s, cat([]byte{0xff}, binary.LittleEndian.AppendUint32(nil, math.Float32bits(float32(math.NaN())))))
+	if err != nil {
+		t.Fatal(err)
+	} else if string(js) != `{"id":"/w==","f":null}` {
+		t.Fatalf("bad decode %s", js)
+	}
+}
+
+func TestAvroBadSchemas(t *testing.T) {
+	for _, v := range []string{
+		``,
+		`"nope"`,
+		`[["int"]]`,
+		`{"type":"record","fields":[]}`,
+		`{"type":"record","name":"x"}`,
+		`{"type":"record","name":"x","fields":[{"type":"int"}]}`,
+		`{"type":"enum","name":"e","symbols":[1]}`,
+		`{"type":"array","items":"Missing"}`,
+	} {
+		if _, err := parseAvroSchema([]byte(v), &avro.SchemaCache{}); err == nil {
+			t.Fatalf("failed to catch bad schema %q", v)
+		}
+	}
+}
+
+func TestAvroUnions(t *testing.T) {
+	s, err := parseAvroSchema([]byte(`{"type":"record","name":"U","fields":[{"name":"v","type":["null","int","string",{"type":"long","logicalType":"timestamp-micros"},{"type":"array","items":"string"},{"type":"bytes","logicalType":"decimal","precision":4,"scale":1}]}]}`), &avro.SchemaCache{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		b   []byte
+		exp string
+	}{
+		{avLong(0), `{"v":null}`},
+		{cat(avLong(1), avLong(-7)), `{"v":-7}`},
+		{cat(avLong(2), avString(`x`)), `{"v":"x"}`},
+		{cat(avLong(3), avLong(1709528767000001)), `{"v":"2024-03-04T05:06:07.000001Z"}`},
+		{cat(avLong(4), avLong(1), avString(`a`), avLong(0)), `{"v":["a"]}`},
+		{cat(avLong(5), avLong(1), []byte{0x7b}), `{"v":12.3}`},
+	} {
+		js, err := decodeAvro(s, v.b)
+		if err != nil {
+			t.Fatal(err)
+		} else if string(js) != v.exp {
+			t.Fatalf("bad union decode %s != %s", js, v.exp)
+		}
+	}
+}
@@ -9,8 +9,6 @@
 package main
 
 import (
-	"crypto/sha256"
-	"crypto/sha512"
 	"errors"
 	"fmt"
 	"net"
@@ -25,7 +23,6 @@ import (
 	"github.com/gravwell/gravwell/v3/ingest/processors"
 	"github.com/gravwell/gravwell/v3/ingest/processors/tags"
 	"github.com/gravwell/gravwell/v3/timegrinder"
-	"github.com/xdg-go/scram"
 )
 
 const (
@@ -39,17 +36,10 @@ const (
 	defaultConsumerGroup string = `gravwell`
 	defaultSRCHeader            = `SRC`
 	defaultTagHeader            = `TAG`
-
-	authPlain       = `plain`
-	authScramSHA256 = `scramsha256`
-	authScramSHA512 = `scramsha512`
 )
 
-type KafkaAuthConfig struct {
-	Auth_Type string
-	Username  string
-	Password  string
-}
+// KafkaAuthConfig is shared with the kafka forwarder preprocessor
+type KafkaAuthConfig = processors.KafkaAuthConfig
 
 type ConfigConsumer struct {
 	Leader             []string
@@ -79,6 +69,20 @@ type ConfigConsumer struct {
 	Timezone_Override         string
 	Timestamp_Format_Override string //override the timestamp format
 
+	//message metadata attached as enumerated values
+	Attach_Key              bool
+	Attach_Partition_Offset bool
+	Attach_Header           []string //header keys to attach, * attaches every header
+
+	//payload decoding
+	Decoder                  string //avro or protobuf
+	Wire_Format              string //confluent or raw
+	Schema_Registry_URL      string
+	Schema_Registry_Username string
+	Schema_Registry_Password string
+	Schema_File              []string
+	Protobuf_Message         string
+
 	//list of preprocessors to run
 	Preprocessor []string
 }
@@ -108,6 +112,13 @@ type consumerCfg struct {
 	extractTS    bool
 	tg           *timegrinder.TimeGrinder
 	preprocessor []string
+
+	//message metadata and decoding
+	attachKey     bool
+	attachOffset  bool
+	attachHeaders map[string]bool
+	attachAllHdrs bool
+	dec           *payloadDecoder
 }
 
 type cfgReadType struct {
@@ -344,6 +355,25 @@ func (cc ConfigConsumer) validateAndProcess() (c consumerCfg, err error) {
 
 	c.preprocessor = cc.Preprocessor
 
+	c.attachKey = cc.Attach_Key
+	c.attachOffset = cc.Attach_Partition_Offset
+	for _, h := range cc.Attach_Header {
+		if h = strings.TrimSpace(h); h == `*` {
+			c.attachAllHdrs = true
+		} else if h == `` {
+			err = errors.New("Empty Attach-Header")
+			return
+		} else {
+			if c.attachHeaders == nil {
+				c.attachHeaders = map[string]bool{}
+			}
+			c.attachHeaders[h] = true
+		}
+	}
+	if c.dec, err = newPayloadDecoder(cc); err != nil {
+		return
+	}
+
 	c.strats, err = cc.balanceStrats()
 	return
 }
@@ -372,83 +402,3 @@ func (cc ConfigConsumer) balanceStrats() (st []sarama.BalanceStrategy, err error
 	}
 	return
 }
-
-func (kac KafkaAuthConfig) Validate() (err error) {
-	if kac.Auth_Type == `` {
-	}
-	switch strings.ToLower(kac.Auth_Type) {
-	case ``:
-		return //no auth
-	case authPlain:
-	case authScramSHA256:
-	case authScramSHA512:
-	default:
-		err = fmt.Errorf("Unknown auth type %q", kac.Auth_Type)
-		return
-	}
-	//auth is active
-	if kac.Username == `` {
-		err = fmt.Errorf("Missing Username")
-	} else if kac.Password == `` {
-		err = fmt.Errorf("Missing Password")
-	}
-	return
-}
-
-func (kac KafkaAuthConfig) SetAuth(cfg *sarama.Config) (err error) {
-	if err = kac.Validate(); err != nil {
-		return
-	} else if kac.Auth_Type == `` {
-		return
-	}
-	//enable the basics
-	cfg.Net.SASL.Enable = true
-	cfg.Net.SASL.Handshake = true
-	cfg.Net.SASL.User = kac.Username
-	cfg.Net.SASL.Password = kac.Password
-
-	switch strings.ToLower(kac.Auth_Type) {
-	case authPlain:
-		cfg.Net.SASL.Mechanism = sarama.SASLTypePlaintext
-	case authScramSHA256:
-		cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
-			return &XDGSCRAMClient{HashGeneratorFcn: SHA256}
-		}
-		cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
-	case authScramSHA512:
-		cfg.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
-			return &XDGSCRAMClient{HashGeneratorFcn: SHA512}
-		}
-		cfg.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
-	}
-	return
-}
-
-var (
-	SHA256 scram.HashGeneratorFcn = sha256.New
-	SHA512 scram.HashGeneratorFcn = sha512.New
-)
-
-type XDGSCRAMClient struct {
-	*scram.Client
-	*scram.ClientConversation
-	scram.HashGeneratorFcn
-}
-
-func (x *XDGSCRAMClient) Begin(userName, password, authzID string) (err error) {
-	x.Client, err = x.HashGeneratorFcn.NewClient(userName, password, authzID)
-	if err != nil {
-		return err
-	}
-	x.ClientConversation = x.Client.NewConversation()
-	return nil
-}
-
-func (x *XDGSCRAMClient) Step(challenge string) (response string, err error) {
-	response, err = x.ClientConversation.Step(challenge)
-	return
-}
-
-func (x *XDGSCRAMClient) Done() bool {
-	return x.ClientConversation.Done()
-}
@@ -9,6 +9,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"errors"
@@ -16,6 +17,7 @@ import (
 	"net"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/IBM/sarama"
 	"github.com/gravwell/gravwell/v3/ingest"
@@ -30,6 +32,11 @@ const (
 	ipv6Len          = 16
 	currKafkaVersion = `2.1.1`
 	minTLSVersion    = tls.VersionTLS12
+
+	keyEVName         = `kafka_key`
+	partitionEVName   = `kafka_partition`
+	offsetEVName      = `kafka_offset`
+	decodeErrorEVName = `decode_error`
 )
 
 type closer interface {
@@ -338,6 +345,14 @@ func (kc *kafkaConsumer) flush(session sarama.ConsumerGroupSession, msgs []*sara
 			TS:   entry.FromStandard(m.Timestamp),
 			Data: m.Value,
 		}
+		if kc.dec != nil {
+			//on failure the raw value goes in with the reason attached so nothing is lost
+			if js, lerr := kc.dec.decode(m.Value); lerr != nil {
+				ent.AddEnumeratedValueEx(decodeErrorEVName, lerr.Error())
+			} else {
+				ent.Data = js
+			}
+		}
 		if kc.ignoreTS {
 			ent.TS = entry.Now()
 		} else if kc.extractTS && kc.tg != nil {
@@ -353,6 +368,7 @@ func (kc *kafkaConsumer) flush(session sarama.ConsumerGroupSession, msgs []*sara
 		if ent.Tag, ent.SRC, err = kc.resolveSourceAndTag(m); err != nil {
 			return
 		}
+		kc.attachMetadata(ent, m)
 		if err = kc.pproc.ProcessContext(ent, kc.ctx); err != nil {
 			return
 		}
@@ -373,6 +389,35 @@ func (kc *kafkaConsumer) flush(session sarama.ConsumerGroupSession, msgs []*sara
 	return
 }
 
+// attachMetadata adds the requested message key, partition, offset, and headers as enumerated values.
+// Values that can't be attached, such as oversized headers, are skipped.
+func (kc *kafkaConsumer) attachMetadata(ent *entry.Entry, m *sarama.ConsumerMessage) {
+	if kc.attachKey && len(m.Key) > 0 {
+		ent.AddEnumeratedValueEx(keyEVName, evValue(m.Key))
+	}
+	if kc.attachOffset {
+		ent.AddEnumeratedValueEx(partitionEVName, m.Partition)
+		ent.AddEnumeratedValueEx(offsetEVName, m.Offset)
+	}
+	if kc.attachAllHdrs || len(kc.attachHeaders) > 0 {
+		for _, rh := range m.Headers {
+			if rh == nil || len(rh.Key) == 0 || len(rh.Key) > entry.MaxEvNameLength {
+				continue
+			} else if kc.attachAllHdrs || kc.attachHeaders[string(rh.Key)] {
+				ent.AddEnumeratedValueEx(string(rh.Key), evValue(rh.Value))
+			}
+		}
+	}
+}
+
+// evValue attaches printable values as strings and everything else as raw bytes
+func evValue(v []byte) interface{} {
+	if utf8.Valid(v) {
+		return string(v)
+	}
+	return bytes.Clone(v)
+}
+
 func (kc *kafkaConsumer) resolveSourceAndTag(m *sarama.ConsumerMessage) (tag entry.EntryTag, ip net.IP, err error) {
 	//short circuit out
 	if m == nil {
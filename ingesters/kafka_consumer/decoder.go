@@ -0,0 +1,414 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	decoderAvro     = `avro`
+	decoderProtobuf = `protobuf`
+
+	wireConfluent = `confluent`
+	wireRaw       = `raw`
+
+	confluentMagic     byte = 0
+	confluentHeaderLen      = 5 // magic byte and a big endian 32bit schema ID
+
+	registryTimeout    = 10 * time.Second
+	registryRetry      = 30 * time.Second // how long a failed schema lookup is cached before asking the registry again
+	maxRegistryRefs    = 16               // how deep schema references may nest
+	maxRegistryRespLen = 16 * mb
+
+	schemaTypeAvro     = `AVRO`
+	schemaTypeProtobuf = `PROTOBUF`
+)
+
+var (
+	ErrMissingWireHeader = errors.New("Message is missing the Confluent wire format header")
+	ErrNoSchema          = errors.New("No schema available for message")
+)
+
+// payloadDecoder converts Avro and Protobuf message values to JSON.  Schemas come from a Confluent
+// compatible schema registry using the ID in the wire format header, or from local schema files.
+type payloadDecoder struct {
+	kind  string
+	raw   bool // messages are bare payloads without the wire format header
+	reg   *schemaRegistry
+	avro  avro.Schema
+	proto protoreflect.MessageDescriptor
+}
+
+func newPayloadDecoder(cc ConfigConsumer) (pd *payloadDecoder, err error) {
+	kind := strings.ToLower(strings.TrimSpace(cc.Decoder))
+	if kind == `` {
+		return //no decoding
+	} else if kind != decoderAvro && kind != decoderProtobuf {
+		return nil, fmt.Errorf("Unknown Decoder %q", cc.Decoder)
+	}
+	pd = &payloadDecoder{kind: kind}
+	switch strings.ToLower(strings.TrimSpace(cc.Wire_Format)) {
+	case ``, wireConfluent:
+	case wireRaw:
+		pd.raw = true
+	default:
+		return nil, fmt.Errorf("Unknown Wire-Format %q", cc.Wire_Format)
+	}
+	if cc.Schema_Registry_URL != `` {
+		if pd.raw {
+			return nil, errors.New("Schema-Registry-URL requires the confluent Wire-Format")
+		} else if pd.reg, err = newSchemaRegistry(cc.Schema_Registry_URL, cc.Schema_Registry_Username, cc.Schema_Registry_Password); err != nil {
+			return nil, err
+		}
+	}
+	if len(cc.Schema_File) > 0 {
+		if err = pd.loadFiles(cc.Schema_File, cc.Protobuf_Message); err != nil {
+			return nil, err
+		}
+	} else if pd.reg == nil {
+		return nil, fmt.Errorf("Decoder %s requires a Schema-Registry-URL or Schema-File", kind)
+	}
+	return
+}
+
+// loadFiles loads local schemas.  Avro files are parsed in order so that later schemas can use the named
+// types of earlier ones, the last schema is the one used for messages.  Protobuf files are compiled
+// descriptor sets and the message must be named.
+func (pd *payloadDecoder) loadFiles(pths []string, msg string) (err error) {
+	var files *protoregistry.Files
+	names := &avro.SchemaCache{}
+	for _, p := range pths {
+		var b []byte
+		if b, err = os.ReadFile(p); err != nil {
+			return
+		}
+		switch pd.kind {
+		case decoderAvro:
+			if pd.avro, err = parseAvroSchema(b, names); err != nil {
+				return fmt.Errorf("Schema-File %s: %w", p, err)
+			}
+		case decoderProtobuf:
+			var set *protoregistry.Files
+			if set, err = loadDescriptorSet(b); err != nil {
+				return fmt.Errorf("Schema-File %s: %w", p, err)
+			}
+			if files == nil {
+				files = set
+			} else {
+				set.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+					if _, lerr := files.FindFileByPath(fd.Path()); lerr != nil {
+						err = files.RegisterFile(fd)
+					}
+					return err == nil
+				})
+				if err != nil {
+					return fmt.Errorf("Schema-File %s: %w", p, err)
+				}
+			}
+		}
+	}
+	if pd.kind == decoderProtobuf {
+		if msg == `` {
+			return errors.New("Protobuf-Message is required with a Protobuf Schema-File")
+		} else if pd.proto, err = findMessage(files, msg); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// decode converts a message value to JSON
+func (pd *payloadDecoder) decode(v []byte) ([]byte, error) {
+	var id uint32
+	framed := !pd.raw
+	if framed {
+		if len(v) < confluentHeaderLen || v[0] != confluentMagic {
+			return nil, ErrMissingWireHeader
+		}
+		id = binary.BigEndian.Uint32(v[1:])
+		v = v[confluentHeaderLen:]
+	}
+	switch pd.kind {
+	case decoderAvro:
+		s := pd.avro
+		if framed && pd.reg != nil {
+			var err error
+			if s, err = pd.reg.avroSchema(id); err != nil {
+				return nil, err
+			}
+		}
+		if s == nil {
+			return nil, ErrNoSchema
+		}
+		return decodeAvro(s, v)
+	case decoderProtobuf:
+		md := pd.proto
+		if framed {
+			idx, rest, err := readMessageIndexes(v)
+			if err != nil {
+				return nil, err
+			}
+			v = rest
+			if pd.reg != nil {
+				var ps *protoSchema
+				if ps, err = pd.reg.protoSchema(id); err != nil {
+					return nil, err
+				} else if md, err = ps.message(idx); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if md == nil {
+			return nil, ErrNoSchema
+		}
+		return decodeProto(md, v)
+	}
+	return nil, ErrNoSchema
+}
+
+// schemaRegistry is a caching client for a Confluent compatible schema registry.
+// Failed lookups are cached for the retry interval so that messages with a bad or missing schema ID
+// don't hit the registry one at a time, and concurrent lookups of the same ID share a single fetch.
+type schemaRegistry struct {
+	base    string
+	user    string
+	pass    string
+	clnt    *http.Client
+	retry   time.Duration
+	mtx     sync.Mutex
+	schemas map[string]registryLookup
+	sf      singleflight.Group
+}
+
+// registryLookup is the cached result of fetching and compiling a schema
+type registryLookup struct {
+	v   interface{}
+	err error
+	ts  time.Time
+}
+
+type registrySchema struct {
+	Schema     string              `json:"schema"`
+	SchemaType string              `json:"schemaType"`
+	References []registryReference `json:"references"`
+}
+
+type registryReference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+func newSchemaRegistry(u, user, pass string) (*schemaRegistry, error) {
+	uri, err := url.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid Schema-Registry-URL %q: %w", u, err)
+	} else if uri.Scheme != `http` && uri.Scheme != `https` {
+		return nil, fmt.Errorf("Invalid Schema-Registry-URL %q: scheme must be http or https", u)
+	}
+	return &schemaRegistry{
+		base:    strings.TrimSuffix(u, `/`),
+		user:    user,
+		pass:    pass,
+		clnt:    &http.Client{Timeout: registryTimeout},
+		retry:   registryRetry,
+		schemas: map[string]registryLookup{},
+	}, nil
+}
+
+// cached returns a cached lookup, failures are only returned until the retry interval expires
+func (sr *schemaRegistry) cached(key string) (l registryLookup, ok bool) {
+	sr.mtx.Lock()
+	defer sr.mtx.Unlock()
+	if l, ok = sr.schemas[key]; ok && l.err != nil && time.Since(l.ts) >= sr.retry {
+		ok = false
+	}
+	return
+}
+
+// lookup returns a cached schema or fetches it, the lock is not held while talking to the registry
+func (sr *schemaRegistry) lookup(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	if l, ok := sr.cached(key); ok {
+		return l.v, l.err
+	}
+	v, err, _ := sr.sf.Do(key, func() (interface{}, error) {
+		//another lookup may have finished between checking the cache and starting this one
+		if l, ok := sr.cached(key); ok {
+			return l.v, l.err
+		}
+		v, err := fetch()
+		sr.mtx.Lock()
+		sr.schemas[key] = registryLookup{v: v, err: err, ts: time.Now()}
+		sr.mtx.Unlock()
+		return v, err
+	})
+	return v, err
+}
+
+func (sr *schemaRegistry) get(pth string, serialized bool) (rs registrySchema, err error) {
+	u := sr.base + pth
+	if serialized {
+		//protobuf schemas come back as a base64 encoded file descriptor rather than .proto source
+		u += `?format=serialized`
+	}
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodGet, u, nil); err != nil {
+		return
+	}
+	req.Header.Set(`Accept`, `application/vnd.schemaregistry.v1+json, application/json`)
+	if sr.user != `` {
+		req.SetBasicAuth(sr.user, sr.pass)
+	}
+	var resp *http.Response
+	if resp, err = sr.clnt.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("schema registry request %s failed: %s", pth, resp.Status)
+		return
+	}
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxRegistryRespLen)).Decode(&rs)
+	return
+}
+
+func refPath(ref registryReference) string {
+	return fmt.Sprintf("/subjects/%s/versions/%d", url.PathEscape(ref.Subject), ref.Version)
+}
+
+// avroSchema returns the schema for an ID, fetching it and any referenced schemas if we don't have it
+func (sr *schemaRegistry) avroSchema(id uint32) (avro.Schema, error) {
+	v, err := sr.lookup(fmt.Sprintf("avro/%d", id), func() (interface{}, error) {
+		return sr.fetchAvro(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(avro.Schema), nil
+}
+
+func (sr *schemaRegistry) fetchAvro(id uint32) (s avro.Schema, err error) {
+	var rs registrySchema
+	if rs, err = sr.get(fmt.Sprintf("/schemas/ids/%d", id), false); err != nil {
+		return
+	} else if rs.SchemaType != `` && rs.SchemaType != schemaTypeAvro {
+		return nil, fmt.Errorf("schema %d is %s, not Avro", id, rs.SchemaType)
+	}
+	names := &avro.SchemaCache{}
+	if err = sr.avroRefs(rs.References, names, 0); err != nil {
+		return
+	} else if s, err = parseAvroSchema([]byte(rs.Schema), names); err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	return
+}
+
+func (sr *schemaRegistry) avroRefs(refs []registryReference, names *avro.SchemaCache, depth int) error {
+	if depth > maxRegistryRefs {
+		return errors.New("schema references nested too deep")
+	}
+	for _, ref := range refs {
+		rs, err := sr.get(refPath(ref), false)
+		if err != nil {
+			return err
+		} else if err = sr.avroRefs(rs.References, names, depth+1); err != nil {
+			return err
+		} else if _, err = parseAvroSchema([]byte(rs.Schema), names); err != nil {
+			return fmt.Errorf("schema reference %s: %w", ref.Name, err)
+		}
+	}
+	return nil
+}
+
+// protoSchema returns the compiled file for an ID, fetching it and any referenced files if we don't have it
+func (sr *schemaRegistry) protoSchema(id uint32) (*protoSchema, error) {
+	v, err := sr.lookup(fmt.Sprintf("proto/%d", id), func() (interface{}, error) {
+		return sr.fetchProto(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*protoSchema), nil
+}
+
+func (sr *schemaRegistry) fetchProto(id uint32) (ps *protoSchema, err error) {
+	var rs registrySchema
+	if rs, err = sr.get(fmt.Sprintf("/schemas/ids/%d", id), true); err != nil {
+		return
+	} else if rs.SchemaType != schemaTypeProtobuf {
+		return nil, fmt.Errorf("schema %d is %s, not Protobuf", id, rs.SchemaType)
+	}
+	files := &protoregistry.Files{}
+	if err = sr.protoRefs(rs.References, files, 0); err != nil {
+		return
+	}
+	ps = &protoSchema{files: files}
+	if ps.file, err = addSerializedProto(files, rs.Schema); err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	return
+}
+
+func (sr *schemaRegistry) protoRefs(refs []registryReference, files *protoregistry.Files, depth int) error {
+	if depth > maxRegistryRefs {
+		return errors.New("schema references nested too deep")
+	}
+	for _, ref := range refs {
+		if _, err := files.FindFileByPath(ref.Name); err == nil {
+			continue
+		} else if _, err = protoregistry.GlobalFiles.FindFileByPath(ref.Name); err == nil {
+			continue //well known types
+		}
+		rs, err := sr.get(refPath(ref), true)
+		if err != nil {
+			return err
+		} else if err = sr.protoRefs(rs.References, files, depth+1); err != nil {
+			return err
+		} else if _, err = addSerializedProto(files, rs.Schema, ref.Name); err != nil {
+			return fmt.Errorf("schema reference %s: %w", ref.Name, err)
+		}
+	}
+	return nil
+}
+
+// addSerializedProto compiles a base64 encoded file descriptor from the registry, referenced files are
+// registered under the name the importing file uses for them
+func addSerializedProto(files *protoregistry.Files, s string, name ...string) (protoreflect.FileDescriptor, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProtoSchema, err)
+	}
+	var fdp descriptorpb.FileDescriptorProto
+	if err = proto.Unmarshal(b, &fdp); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProtoSchema, err)
+	}
+	if len(name) > 0 {
+		fdp.Name = proto.String(name[0])
+	}
+	return addProtoFile(files, &fdp)
+}
@@ -0,0 +1,395 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// testProtoFile is equivalent to
+//
+//	package example;
+//	message Metric { string name = 1; int64 value = 2; message Tag { string k = 1; } repeated Tag tags = 3; }
+//	message Other { int32 x = 1; }
+func testProtoFile() *descriptorpb.FileDescriptorProto {
+	field := func(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, lbl descriptorpb.FieldDescriptorProto_Label, tn string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(num),
+			Type:     typ.Enum(),
+			Label:    lbl.Enum(),
+		}
+		if tn != `` {
+			f.TypeName = proto.String(tn)
+		}
+		return f
+	}
+	opt := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String(`example.proto`),
+		Package: proto.String(`example`),
+		Syntax:  proto.String(`proto3`),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String(`Metric`),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field(`name`, 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, opt, ``),
+					field(`value`, 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, opt, ``),
+					field(`tags`, 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_LABEL_REPEATED, `.example.Metric.Tag`),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name:  proto.String(`Tag`),
+						Field: []*descriptorpb.FieldDescriptorProto{field(`k`, 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, opt, ``)},
+					},
+				},
+			},
+			{
+				Name:  proto.String(`Other`),
+				Field: []*descriptorpb.FieldDescriptorProto{field(`x`, 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, opt, ``)},
+			},
+		},
+	}
+}
+
+// testProtoMessages builds serialized Metric and Other messages
+func testProtoMessages(t *testing.T) (metric, other []byte) {
+	files := &protoregistry.Files{}
+	fd, err := addProtoFile(files, testProtoFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmd := fd.Messages().Get(0)
+	m := dynamicpb.NewMessage(mmd)
+	m.Set(mmd.Fields().ByName(`name`), protoreflect.ValueOfString(`cpu`))
+	m.Set(mmd.Fields().ByName(`value`), protoreflect.ValueOfInt64(42))
+	tmd := mmd.Messages().Get(0)
+	tag := dynamicpb.NewMessage(tmd)
+	tag.Set(tmd.Fields().ByName(`k`), protoreflect.ValueOfString(`a`))
+	lst := m.Mutable(mmd.Fields().ByName(`tags`)).List()
+	lst.Append(protoreflect.ValueOfMessage(tag))
+	if metric, err = proto.Marshal(m); err != nil {
+		t.Fatal(err)
+	}
+	omd := fd.Messages().Get(1)
+	o := dynamicpb.NewMessage(omd)
+	o.Set(omd.Fields().ByName(`x`), protoreflect.ValueOfInt32(-7))
+	if other, err = proto.Marshal(o); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func confluentFrame(id uint32, idx []int64, payload []byte) []byte {
+	b := binary.BigEndian.AppendUint32([]byte{confluentMagic}, id)
+	if idx != nil {
+		b = binary.AppendVarint(b, int64(len(idx)))
+		for _, i := range idx {
+			b = binary.AppendVarint(b, i)
+		}
+	}
+	return append(b, payload...)
+}
+
+// stubRegistry serves a handful of schemas the way a Confluent schema registry does
+func stubRegistry(t *testing.T, hits *int32) *httptest.Server {
+	fdb, err := proto.Marshal(testProtoFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := map[string]registrySchema{
+		`/schemas/ids/1`: {Schema: testAvroSchema},
+		`/schemas/ids/2`: {
+			Schema:     `{"type":"record","name":"Wrap","namespace":"com.other","fields":[{"name":"id","type":"Id"}]}`,
+			SchemaType: schemaTypeAvro,
+			References: []registryReference{{Name: `com.other.Id`, Subject: `ids`, Version: 3}},
+		},
+		`/subjects/ids/versions/3`: {Schema: `{"type":"fixed","name":"com.other.Id","size":1}`},
+		`/schemas/ids/3`:           {Schema: base64.StdEncoding.EncodeToString(fdb), SchemaType: schemaTypeProtobuf},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if u, p, ok := r.BasicAuth(); !ok || u != `user` || p != `pass` {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		rs, ok := resp[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if (rs.SchemaType == schemaTypeProtobuf) != (r.URL.Query().Get(`format`) == `serialized`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(rs)
+	}))
+}
+
+func TestRegistryDecode(t *testing.T) {
+	var hits int32
+	srv := stubRegistry(t, &hits)
+	defer srv.Close()
+	avroDec, err := newPayloadDecoder(ConfigConsumer{
+		Decoder:                  `Avro`,
+		Schema_Registry_URL:      srv.URL + `/`,
+		Schema_Registry_Username: `user`,
+		Schema_Registry_Password: `pass`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		js, err := avroDec.decode(confluentFrame(2, nil, []byte{0x01}))
+		if err != nil {
+			t.Fatal(err)
+		} else if string(js) != `{"id":"AQ=="}` {
+			t.Fatalf("bad decode %s", js)
+		}
+	}
+	if hits != 2 {
+		t.Fatalf("schemas were not cached: %d requests", hits)
+	}
+	if _, err = avroDec.decode(confluentFrame(1, nil, testAvroEvent(1, nil))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = avroDec.decode(confluentFrame(99, nil, []byte{0})); err == nil {
+		t.Fatal("failed to catch missing schema")
+	} else if _, err = avroDec.decode([]byte{0x01}); err != ErrMissingWireHeader {
+		t.Fatalf("bad error on missing header: %v", err)
+	} else if _, err = avroDec.decode(confluentFrame(3, nil, []byte{0})); err == nil {
+		t.Fatal("failed to catch protobuf schema in avro decoder")
+	}
+
+	protoDec, err := newPayloadDecoder(ConfigConsumer{
+		Decoder:                  `protobuf`,
+		Schema_Registry_URL:      srv.URL,
+		Schema_Registry_Username: `user`,
+		Schema_Registry_Password: `pass`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metric, other := testProtoMessages(t)
+	tests := []struct {
+		idx  []int64
+		data []byte
+		exp  string
+	}{
+		{idx: []int64{}, data: metric, exp: `{"name":"cpu","value":"42","tags":[{"k":"a"}]}`},
+		{idx: []int64{0}, data: metric, exp: `{"name":"cpu","value":"42","tags":[{"k":"a"}]}`},
+		{idx: []int64{1}, data: other, exp: `{"x":-7}`},
+		{idx: []int64{0, 0}, data: []byte{0x0a, 0x01, 'z'}, exp: `{"k":"z"}`},
+	}
+	for _, tc := range tests {
+		js, err := protoDec.decode(confluentFrame(3, tc.idx, tc.data))
+		if err != nil {
+			t.Fatal(err)
+		} else if string(js) != tc.exp {
+			t.Fatalf("bad decode for %v\n%s\n%s", tc.idx, js, tc.exp)
+		}
+	}
+	if _, err = protoDec.decode(confluentFrame(3, []int64{5}, other)); err == nil {
+		t.Fatal("failed to catch bad message index")
+	} else if _, err = protoDec.decode(confluentFrame(3, []int64{0}, []byte{0xff})); err == nil {
+		t.Fatal("failed to catch bad protobuf data")
+	}
+
+	//bad credentials
+	bad, err := newPayloadDecoder(ConfigConsumer{Decoder: `avro`, Schema_Registry_URL: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err = bad.decode(confluentFrame(1, nil, nil)); err == nil {
+		t.Fatal("failed to catch registry auth failure")
+	}
+}
+
+func TestRegistryFailureCache(t *testing.T) {
+	var hits int32
+	srv := stubRegistry(t, &hits)
+	defer srv.Close()
+	sr, err := newSchemaRegistry(srv.URL, `user`, `pass`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//failed lookups are not retried until the retry interval expires
+	for i := 0; i < 5; i++ {
+		if _, err = sr.avroSchema(99); err == nil {
+			t.Fatal("failed to catch missing schema")
+		}
+	}
+	if hits != 1 {
+		t.Fatalf("failed lookup was not cached: %d requests", hits)
+	}
+	sr.retry = time.Millisecond
+	time.Sleep(5 * time.Millisecond)
+	if _, err = sr.avroSchema(99); err == nil {
+		t.Fatal("failed to catch missing schema")
+	} else if hits != 2 {
+		t.Fatalf("failed lookup was not retried: %d requests", hits)
+	}
+	//avro and protobuf lookups of the same ID are cached separately
+	if _, err = sr.protoSchema(99); err == nil {
+		t.Fatal("failed to catch missing schema")
+	} else if hits != 3 {
+		t.Fatalf("bad request count %d", hits)
+	}
+
+	//concurrent lookups of one ID share a single fetch
+	atomic.StoreInt32(&hits, 0)
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := sr.protoSchema(3); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Fatalf("concurrent lookups were not shared: %d requests", n)
+	}
+}
+
+func TestLocalSchemaDecode(t *testing.T) {
+	dir := t.TempDir()
+	avsc := filepath.Join(dir, `event.avsc`)
+	if err := os.WriteFile(avsc, []byte(testAvroSchema), 0640); err != nil {
+		t.Fatal(err)
+	}
+	fdb, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{testProtoFile()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := filepath.Join(dir, `example.desc`)
+	if err = os.WriteFile(desc, fdb, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	dec, err := newPayloadDecoder(ConfigConsumer{Decoder: `avro`, Wire_Format: `raw`, Schema_File: []string{avsc}})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err = dec.decode(testAvroEvent(1, nil)); err != nil {
+		t.Fatal(err)
+	}
+	//framed messages use the local schema regardless of ID
+	if dec, err = newPayloadDecoder(ConfigConsumer{Decoder: `avro`, Schema_File: []string{avsc}}); err != nil {
+		t.Fatal(err)
+	} else if _, err = dec.decode(confluentFrame(77, nil, testAvroEvent(1, nil))); err != nil {
+		t.Fatal(err)
+	}
+
+	_, other := testProtoMessages(t)
+	dec, err = newPayloadDecoder(ConfigConsumer{Decoder: `protobuf`, Wire_Format: `raw`, Schema_File: []string{desc}, Protobuf_Message: `example.Other`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if js, err := dec.decode(other); err != nil {
+		t.Fatal(err)
+	} else if string(js) != `{"x":-7}` {
+		t.Fatalf("bad decode %s", js)
+	}
+
+	for _, cc := range []ConfigConsumer{
+		{Decoder: `thrift`, Schema_File: []string{avsc}},
+		{Decoder: `avro`},
+		{Decoder: `avro`, Wire_Format: `magic`, Schema_File: []string{avsc}},
+		{Decoder: `avro`, Wire_Format: `raw`, Schema_Registry_URL: `http://127.0.0.1:8081`},
+		{Decoder: `avro`, Schema_Registry_URL: `ftp://127.0.0.1`},
+		{Decoder: `avro`, Schema_File: []string{desc}},
+		{Decoder: `avro`, Schema_File: []string{filepath.Join(dir, `missing.avsc`)}},
+		{Decoder: `protobuf`, Schema_File: []string{desc}},
+		{Decoder: `protobuf`, Schema_File: []string{desc}, Protobuf_Message: `example.Missing`},
+		{Decoder: `protobuf`, Schema_File: []string{avsc}, Protobuf_Message: `example.Other`},
+	} {
+		if _, err = newPayloadDecoder(cc); err == nil {
+			t.Fatalf("failed to catch bad config %+v", cc)
+		}
+	}
+	if dec, err = newPayloadDecoder(ConfigConsumer{}); err != nil || dec != nil {
+		t.Fatalf("empty decoder config should not decode: %v", err)
+	}
+}
+
+func TestAttachMetadata(t *testing.T) {
+	m := &sarama.ConsumerMessage{
+		Key:       []byte(`key1`),
+		Partition: 3,
+		Offset:    12345,
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte(`trace`), Value: []byte(`abc`)},
+			{Key: []byte(`bin`), Value: []byte{0xff, 0x00}},
+			{Key: []byte(`skip`), Value: []byte(`x`)},
+			nil,
+		},
+	}
+	var kc kafkaConsumer
+	kc.attachKey = true
+	kc.attachOffset = true
+	kc.attachHeaders = map[string]bool{`trace`: true, `bin`: true}
+	var ent entry.Entry
+	kc.attachMetadata(&ent, m)
+	exp := map[string]interface{}{
+		keyEVName:       `key1`,
+		partitionEVName: int32(3),
+		offsetEVName:    int64(12345),
+		`trace`:         `abc`,
+		`bin`:           []byte{0xff, 0x00},
+	}
+	if evs := ent.EnumeratedValues(); len(evs) != len(exp) {
+		t.Fatalf("bad EV count %d != %d", len(evs), len(exp))
+	}
+	for k, v := range exp {
+		if ev, ok := ent.GetEnumeratedValue(k); !ok {
+			t.Fatalf("missing EV %s", k)
+		} else if b, ok := v.([]byte); ok {
+			if string(ev.([]byte)) != string(b) {
+				t.Fatalf("bad EV %s %v", k, ev)
+			}
+		} else if ev != v {
+			t.Fatalf("bad EV %s %v(%T) != %v(%T)", k, ev, ev, v, v)
+		}
+	}
+
+	//wildcard gets every header, nothing else is attached when not asked for
+	kc = kafkaConsumer{}
+	kc.attachAllHdrs = true
+	ent = entry.Entry{}
+	kc.attachMetadata(&ent, m)
+	if evs := ent.EnumeratedValues(); len(evs) != 3 {
+		t.Fatalf("bad EV count %d", len(evs))
+	} else if _, ok := ent.GetEnumeratedValue(keyEVName); ok {
+		t.Fatal("attached key without being asked")
+	}
+}
@@ -0,0 +1,178 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	//well known types that schemas commonly import
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const maxMessageIndexes = 64
+
+var (
+	ErrInvalidProtoSchema = errors.New("Invalid Protobuf schema")
+	ErrInvalidProtoData   = errors.New("Invalid Protobuf data")
+)
+
+var protoMarshaler = protojson.MarshalOptions{UseProtoNames: true}
+
+// protoSchema is a compiled protobuf file and its dependencies
+type protoSchema struct {
+	file  protoreflect.FileDescriptor
+	files *protoregistry.Files
+}
+
+// fallbackResolver looks up files in our own set first and then the well known types linked into the binary
+type fallbackResolver struct {
+	files *protoregistry.Files
+}
+
+func (fr fallbackResolver) FindFileByPath(p string) (protoreflect.FileDescriptor, error) {
+	if fd, err := fr.files.FindFileByPath(p); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(p)
+}
+
+func (fr fallbackResolver) FindDescriptorByName(n protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := fr.files.FindDescriptorByName(n); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(n)
+}
+
+// addProtoFile compiles a file descriptor, its dependencies must already be in the file set
+func addProtoFile(files *protoregistry.Files, fdp *descriptorpb.FileDescriptorProto) (protoreflect.FileDescriptor, error) {
+	if fd, err := files.FindFileByPath(fdp.GetName()); err == nil {
+		return fd, nil //already have it
+	}
+	fd, err := protodesc.NewFile(fdp, fallbackResolver{files: files})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProtoSchema, err)
+	} else if err = files.RegisterFile(fd); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProtoSchema, err)
+	}
+	return fd, nil
+}
+
+// loadDescriptorSet loads a compiled descriptor set such as the output of protoc --include_imports --descriptor_set_out
+func loadDescriptorSet(b []byte) (*protoregistry.Files, error) {
+	var fds descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(b, &fds); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProtoSchema, err)
+	}
+	files := &protoregistry.Files{}
+	//protoc emits dependencies first, but don't count on it
+	pending := fds.GetFile()
+	for len(pending) > 0 {
+		var next []*descriptorpb.FileDescriptorProto
+		var lastErr error
+		for _, fdp := range pending {
+			if _, err := addProtoFile(files, fdp); err != nil {
+				next = append(next, fdp)
+				lastErr = err
+			}
+		}
+		if len(next) == len(pending) {
+			return nil, lastErr
+		}
+		pending = next
+	}
+	return files, nil
+}
+
+// findMessage resolves a message by its full name
+func findMessage(files *protoregistry.Files, name string) (protoreflect.MessageDescriptor, error) {
+	d, err := fallbackResolver{files: files}.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("%w: message %q not found", ErrInvalidProtoSchema, name)
+	}
+	md, ok := d.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is not a message", ErrInvalidProtoSchema, name)
+	}
+	return md, nil
+}
+
+// message walks the message indexes from the wire format, the first index selects a top level message
+// in the file and every following index selects a nested message
+func (ps *protoSchema) message(idx []int) (md protoreflect.MessageDescriptor, err error) {
+	if len(idx) == 0 {
+		idx = []int{0}
+	}
+	msgs := ps.file.Messages()
+	for _, i := range idx {
+		if i < 0 || i >= msgs.Len() {
+			return nil, fmt.Errorf("%w: message index %d out of range", ErrInvalidProtoData, i)
+		}
+		md = msgs.Get(i)
+		msgs = md.Messages()
+	}
+	return
+}
+
+// readMessageIndexes reads the Confluent message index list that precedes protobuf payloads.
+// The list is a count followed by that many indexes, all zig-zag varints, an empty list means the first message.
+func readMessageIndexes(b []byte) (idx []int, rest []byte, err error) {
+	v, n := protowire.ConsumeVarint(b)
+	if n < 0 {
+		return nil, nil, fmt.Errorf("%w: bad message index count", ErrInvalidProtoData)
+	}
+	b = b[n:]
+	cnt := protowire.DecodeZigZag(v)
+	if cnt < 0 || cnt > maxMessageIndexes {
+		return nil, nil, fmt.Errorf("%w: bad message index count %d", ErrInvalidProtoData, cnt)
+	}
+	for i := int64(0); i < cnt; i++ {
+		if v, n = protowire.ConsumeVarint(b); n < 0 {
+			return nil, nil, fmt.Errorf("%w: bad message index", ErrInvalidProtoData)
+		}
+		b = b[n:]
+		idx = append(idx, int(protowire.DecodeZigZag(v)))
+	}
+	return idx, b, nil
+}
+
+// decodeProto decodes a message to compact JSON
+func decodeProto(md protoreflect.MessageDescriptor, b []byte) ([]byte, error) {
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(b, msg); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProtoData, err)
+	}
+	js, err := protoMarshaler.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	//protojson randomly adds whitespace to discourage depending on its output, squash it
+	bb := bytes.NewBuffer(make([]byte, 0, len(js)))
+	if err = json.Compact(bb, js); err != nil {
+		return nil, err
+	}
+	return bb.Bytes(), nil
+}
@@ -0,0 +1,390 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/attach"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/gravwell/gravwell/v3/internal/mqtt"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+const (
+	defaultPort    uint16 = 1883
+	defaultTLSPort uint16 = 8883
+
+	version311 = `3.1.1`
+	version5   = `5`
+)
+
+type subscriberCfg struct {
+	Broker                    string // host:port, tls://, ssl://, and mqtts:// schemes enable TLS
+	Protocol_Version          string // 3.1.1 or 5
+	Client_ID                 string // defaults to gravwell-<hostname>-<subscriber name>
+	Clean_Session             bool   // discard the broker side session on connect, unacknowledged messages are lost
+	Session_Expiry            int    // seconds an MQTT 5 broker keeps our session after we disconnect
+	Username                  string
+	Password                  string
+	Use_TLS                   bool
+	CA_File                   string
+	Client_Cert               string
+	Client_Key                string
+	Insecure_Skip_TLS_Verify  bool
+	Topic_Filter              []string // topic filters to subscribe to, + and # wildcards are allowed
+	QoS                       int      // maximum QoS to request, 0, 1, or 2
+	Shared_Group              string   // subscribe as a member of a shared subscription group
+	Keep_Alive                string
+	Default_Tag               string
+	Tag_Match                 []string // filter:tag pairs, the first matching filter picks the tag
+	Ignore_Timestamps         bool
+	Assume_Local_Timezone     bool
+	Timezone_Override         string
+	Timestamp_Format_Override string
+	Source_Override           string
+	Preprocessor              []string
+
+	src      net.IP
+	tagMatch []tagMatch
+	tg       *timegrinder.TimeGrinder
+}
+
+type tagMatch struct {
+	filter string
+	tag    string
+}
+
+type cfgType struct {
+	Global       config.IngestConfig
+	Attach       attach.AttachConfig
+	Subscriber   map[string]*subscriberCfg
+	Preprocessor processors.ProcessorConfig
+	TimeFormat   config.CustomTimeFormat
+}
+
+func GetConfig(path, overlayPath string) (*cfgType, error) {
+	var c cfgType
+	if err := config.LoadConfigFile(&c, path); err != nil {
+		return nil, err
+	} else if err = config.LoadConfigOverlays(&c, overlayPath); err != nil {
+		return nil, err
+	}
+
+	if err := c.Verify(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *cfgType) Verify() error {
+	//verify the global parameters
+	if err := c.Global.Verify(); err != nil {
+		return err
+	} else if err = c.Attach.Verify(); err != nil {
+		return err
+	} else if err = c.TimeFormat.Validate(); err != nil {
+		return err
+	}
+
+	if len(c.Subscriber) == 0 {
+		return errors.New("No Subscribers specified")
+	}
+
+	if err := c.Preprocessor.Validate(); err != nil {
+		return err
+	}
+
+	for k, v := range c.Subscriber {
+		if err := v.verify(k); err != nil {
+			return fmt.Errorf("Subscriber %s %w", k, err)
+		} else if err = c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("Subscriber %s preprocessor invalid: %v", k, err)
+		}
+		if v.tg != nil {
+			//custom formats must be loaded before the override so it can reference them
+			if err := c.TimeFormat.LoadFormats(v.tg); err != nil {
+				return fmt.Errorf("Subscriber %s %w", k, err)
+			} else if v.Timestamp_Format_Override != `` {
+				if err = v.tg.SetFormatOverride(v.Timestamp_Format_Override); err != nil {
+					return fmt.Errorf("Subscriber %s invalid Timestamp-Format-Override %q: %w", k, v.Timestamp_Format_Override, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *subscriberCfg) verify(name string) (err error) {
+	if s.Broker == `` {
+		return errors.New("missing Broker")
+	}
+	if _, err = s.brokerAddr(); err != nil {
+		return
+	}
+	switch s.Protocol_Version = strings.TrimSpace(s.Protocol_Version); s.Protocol_Version {
+	case ``, `3`, `4`, version311:
+		s.Protocol_Version = version311
+	case version5, `5.0`:
+		s.Protocol_Version = version5
+	default:
+		return fmt.Errorf("invalid Protocol-Version %q, options are 3.1.1 or 5", s.Protocol_Version)
+	}
+	if s.Client_ID == `` {
+		host, _ := os.Hostname()
+		s.Client_ID = fmt.Sprintf("gravwell-%s-%s", host, name)
+	}
+	if s.Session_Expiry < 0 {
+		return errors.New("Session-Expiry may not be negative")
+	}
+	if s.Password != `` && s.Username == `` {
+		return errors.New("Password requires a Username")
+	}
+	if (s.Client_Cert == ``) != (s.Client_Key == ``) {
+		return errors.New("Client-Cert and Client-Key must be specified together")
+	}
+	if s.QoS < 0 || s.QoS > 2 {
+		return fmt.Errorf("invalid QoS %d, options are 0, 1, or 2", s.QoS)
+	}
+	if len(s.Topic_Filter) == 0 {
+		return errors.New("missing Topic-Filter")
+	}
+	for i, f := range s.Topic_Filter {
+		s.Topic_Filter[i] = strings.TrimSpace(f)
+		if g, _, err := mqtt.SplitShared(s.Topic_Filter[i]); err != nil {
+			return err
+		} else if g != `` && s.Shared_Group != `` {
+			return fmt.Errorf("Topic-Filter %q is already shared, it cannot be used with Shared-Group", f)
+		}
+		if err = mqtt.ValidateFilter(s.subscribeFilter(s.Topic_Filter[i])); err != nil {
+			return
+		}
+	}
+	if _, err = s.keepAlive(); err != nil {
+		return
+	}
+
+	//tags
+	if s.Default_Tag == `` {
+		return errors.New("missing Default-Tag")
+	} else if err = ingest.CheckTag(s.Default_Tag); err != nil {
+		return fmt.Errorf("invalid Default-Tag %q: %w", s.Default_Tag, err)
+	}
+	s.tagMatch = s.tagMatch[:0]
+	for _, tm := range s.Tag_Match {
+		//topics may contain colons but tags cannot, so split on the last one
+		idx := strings.LastIndex(tm, `:`)
+		if idx < 0 {
+			return fmt.Errorf("invalid Tag-Match %q, the format is filter:tag", tm)
+		}
+		m := tagMatch{
+			filter: strings.TrimSpace(tm[:idx]),
+			tag:    strings.TrimSpace(tm[idx+1:]),
+		}
+		if g, _, _ := mqtt.SplitShared(m.filter); g != `` {
+			return fmt.Errorf("Tag-Match %q may not use a shared subscription filter", tm)
+		} else if err = mqtt.ValidateFilter(m.filter); err != nil {
+			return fmt.Errorf("invalid Tag-Match %q: %w", tm, err)
+		} else if err = ingest.CheckTag(m.tag); err != nil {
+			return fmt.Errorf("invalid Tag-Match %q: %w", tm, err)
+		}
+		s.tagMatch = append(s.tagMatch, m)
+	}
+
+	//timestamps
+	if s.Timezone_Override != `` && s.Assume_Local_Timezone {
+		return errors.New("Cannot specify Assume-Local-Timezone and Timezone-Override in the same subscriber")
+	}
+	if !s.Ignore_Timestamps {
+		tcfg := timegrinder.Config{
+			EnableLeftMostSeed: true,
+		}
+		if s.tg, err = timegrinder.NewTimeGrinder(tcfg); err != nil {
+			return fmt.Errorf("failed to generate new timegrinder: %w", err)
+		}
+		if s.Assume_Local_Timezone {
+			s.tg.SetLocalTime()
+		}
+		if s.Timezone_Override != `` {
+			if err = s.tg.SetTimezone(s.Timezone_Override); err != nil {
+				return fmt.Errorf("invalid Timezone-Override %q: %w", s.Timezone_Override, err)
+			}
+		}
+	}
+
+	if s.Source_Override != `` {
+		if s.src = net.ParseIP(s.Source_Override); s.src == nil {
+			return fmt.Errorf("Invalid Source-Override %q", s.Source_Override)
+		}
+	}
+	return
+}
+
+// useTLS returns true if the broker scheme or the TLS options ask for an encrypted connection
+func (s *subscriberCfg) useTLS() bool {
+	if s.Use_TLS || s.CA_File != `` || s.Client_Cert != `` {
+		return true
+	}
+	scheme, _, ok := strings.Cut(s.Broker, `://`)
+	if !ok {
+		return false
+	}
+	switch strings.ToLower(scheme) {
+	case `tls`, `ssl`, `mqtts`:
+		return true
+	}
+	return false
+}
+
+// brokerAddr strips any scheme from the broker and applies the default port
+func (s *subscriberCfg) brokerAddr() (addr string, err error) {
+	addr = strings.TrimSpace(s.Broker)
+	if scheme, rest, ok := strings.Cut(addr, `://`); ok {
+		switch strings.ToLower(scheme) {
+		case `tcp`, `mqtt`, `tls`, `ssl`, `mqtts`:
+		default:
+			err = fmt.Errorf("invalid Broker %q, unsupported scheme %q", s.Broker, scheme)
+			return
+		}
+		addr = rest
+	}
+	port := defaultPort
+	if s.useTLS() {
+		port = defaultTLSPort
+	}
+	if _, _, lerr := net.SplitHostPort(addr); lerr != nil {
+		addr = net.JoinHostPort(addr, fmt.Sprintf("%d", port))
+	}
+	if _, _, err = net.SplitHostPort(addr); err != nil {
+		err = fmt.Errorf("invalid Broker %q: %w", s.Broker, err)
+	}
+	return
+}
+
+func (s *subscriberCfg) keepAlive() (d time.Duration, err error) {
+	if s.Keep_Alive == `` {
+		return mqtt.DefaultKeepAlive, nil
+	}
+	if d, err = time.ParseDuration(s.Keep_Alive); err != nil {
+		err = fmt.Errorf("invalid Keep-Alive %q: %w", s.Keep_Alive, err)
+	} else if d < time.Second || d > 65535*time.Second {
+		err = fmt.Errorf("Keep-Alive %v must be between 1s and 65535s", d)
+	}
+	return
+}
+
+// subscribeFilter applies the shared group to a filter
+func (s *subscriberCfg) subscribeFilter(f string) string {
+	if s.Shared_Group == `` {
+		return f
+	}
+	return `$share/` + s.Shared_Group + `/` + f
+}
+
+func (s *subscriberCfg) tlsConfig() (tc *tls.Config, err error) {
+	if !s.useTLS() {
+		return
+	}
+	var addr, host string
+	if addr, err = s.brokerAddr(); err != nil {
+		return
+	} else if host, _, err = net.SplitHostPort(addr); err != nil {
+		return
+	}
+	return utils.TLSClientConfig{
+		ServerName:         host,
+		CAFile:             s.CA_File,
+		CertFile:           s.Client_Cert,
+		KeyFile:            s.Client_Key,
+		InsecureSkipVerify: s.Insecure_Skip_TLS_Verify,
+	}.Load()
+}
+
+// clientConfig builds the MQTT client configuration, TLS files are loaded here
+func (s *subscriberCfg) clientConfig() (cc mqtt.Config, err error) {
+	if cc.Address, err = s.brokerAddr(); err != nil {
+		return
+	} else if cc.TLS, err = s.tlsConfig(); err != nil {
+		return
+	} else if cc.KeepAlive, err = s.keepAlive(); err != nil {
+		return
+	}
+	cc.Version = mqtt.Version311
+	if s.Protocol_Version == version5 {
+		cc.Version = mqtt.Version5
+	}
+	cc.ClientID = s.Client_ID
+	cc.CleanSession = s.Clean_Session
+	cc.SessionExpiry = uint32(s.Session_Expiry)
+	cc.Username = s.Username
+	cc.Password = s.Password
+	for _, f := range s.Topic_Filter {
+		cc.Subscriptions = append(cc.Subscriptions, mqtt.Subscription{
+			Filter: s.subscribeFilter(f),
+			QoS:    byte(s.QoS),
+		})
+	}
+	return
+}
+
+// tags returns every tag this subscriber may produce
+func (s *subscriberCfg) tags() (tags []string) {
+	tags = append(tags, s.Default_Tag)
+	for _, tm := range s.tagMatch {
+		tags = append(tags, tm.tag)
+	}
+	return
+}
+
+// tagFor returns the tag name for a topic
+func (s *subscriberCfg) tagFor(topic string) string {
+	for _, tm := range s.tagMatch {
+		if mqtt.MatchTopic(tm.filter, topic) {
+			return tm.tag
+		}
+	}
+	return s.Default_Tag
+}
+
+func (c *cfgType) Tags() ([]string, error) {
+	var tags []string
+	tagMp := map[string]bool{}
+	for _, v := range c.Subscriber {
+		for _, t := range v.tags() {
+			if !tagMp[t] {
+				tags = append(tags, t)
+				tagMp[t] = true
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("No tags specified")
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func (c *cfgType) IngestBaseConfig() config.IngestConfig {
+	return c.Global
+}
+
+func (c *cfgType) AttachConfig() attach.AttachConfig {
+	return c.Attach
+}
@@ -0,0 +1,208 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils/ingesttest"
+	"github.com/gravwell/gravwell/v3/internal/mqtt"
+	"github.com/gravwell/gravwell/v3/internal/mqtt/mqtttest"
+)
+
+const testTimeout = 10 * time.Second
+
+func loadTestConfig(t *testing.T, body string) (*cfgType, error) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), `mqtt.conf`)
+	if err := os.WriteFile(p, []byte(body), 0640); err != nil {
+		t.Fatal(err)
+	}
+	return GetConfig(p, ``)
+}
+
+const testGlobal = `
+[Global]
+Ingest-Secret = IngestSecrets
+Cleartext-Backend-target=127.0.0.1:4023
+Log-Level=INFO
+`
+
+func TestConfig(t *testing.T) {
+	cfg, err := loadTestConfig(t, testGlobal+`
+[Subscriber "plant"]
+	Broker="mqtts://broker.example.com"
+	Protocol-Version=5
+	Username=user
+	Password=pass
+	Topic-Filter="plant/+/telemetry"
+	Topic-Filter="plant/alarms/#"
+	Shared-Group=gravwell
+	QoS=2
+	Default-Tag=plant
+	Tag-Match="plant/alarms/#:alarms"
+	Tag-Match="plant/+/telemetry:telemetry"
+
+[Subscriber "local"]
+	Broker="127.0.0.1"
+	Client-ID=local
+	Topic-Filter="#"
+	Default-Tag=mqtt
+	Ignore-Timestamps=true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := cfg.Tags(); err != nil {
+		t.Fatal(err)
+	} else if len(tags) != 4 {
+		t.Fatalf("bad tags: %v", tags)
+	}
+	plant := cfg.Subscriber[`plant`]
+	cc, err := plant.clientConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc.Address != `broker.example.com:8883` || cc.TLS == nil || cc.TLS.ServerName != `broker.example.com` || cc.Version != mqtt.Version5 {
+		t.Fatalf("bad client config: %+v", cc)
+	} else if len(cc.Subscriptions) != 2 || cc.Subscriptions[0].Filter != `$share/gravwell/plant/+/telemetry` || cc.Subscriptions[1].QoS != 2 {
+		t.Fatalf("bad subscriptions: %+v", cc.Subscriptions)
+	} else if cc.ClientID == `` || cc.CleanSession {
+		t.Fatalf("bad session: %q %v", cc.ClientID, cc.CleanSession)
+	}
+	tagTests := map[string]string{
+		`plant/alarms/boiler/overheat`: `alarms`,
+		`plant/press1/telemetry`:       `telemetry`,
+		`plant/press1/status`:          `plant`,
+	}
+	for topic, tag := range tagTests {
+		if r := plant.tagFor(topic); r != tag {
+			t.Fatalf("bad tag for %s: %s != %s", topic, r, tag)
+		}
+	}
+	if cc, err = cfg.Subscriber[`local`].clientConfig(); err != nil {
+		t.Fatal(err)
+	} else if cc.Address != `127.0.0.1:1883` || cc.TLS != nil || cc.Version != mqtt.Version311 {
+		t.Fatalf("bad client config: %+v", cc)
+	}
+
+	bad := []string{
+		`Topic-Filter="#"`,      //no broker
+		`Broker=ws://127.0.0.1`, //unsupported scheme
+		`Broker=127.0.0.1`,      //no filters
+		`Broker=127.0.0.1
+		Topic-Filter="a/#/b"`, //bad filter
+		`Broker=127.0.0.1
+		Topic-Filter="#"
+		Protocol-Version=4.0`, //bad version
+		`Broker=127.0.0.1
+		Topic-Filter="#"
+		QoS=3`, //bad QoS
+		`Broker=127.0.0.1
+		Topic-Filter="$share/a/#"
+		Shared-Group=b`, //shared twice
+		`Broker=127.0.0.1
+		Topic-Filter="#"
+		Tag-Match="sensors/#"`, //missing tag
+		`Broker=127.0.0.1
+		Topic-Filter="#"
+		Tag-Match="sensors/#:bad tag"`, //invalid tag
+		`Broker=127.0.0.1
+		Topic-Filter="#"
+		Client-Cert=/tmp/cert.pem`, //missing key
+		`Broker=127.0.0.1
+		Topic-Filter="#"
+		Keep-Alive=100ms`, //keep alive too short
+	}
+	for i, b := range bad {
+		if _, err := loadTestConfig(t, testGlobal+"[Subscriber \"bad\"]\n\tDefault-Tag=mqtt\n\t"+b+"\n"); err == nil {
+			t.Fatalf("failed to catch bad config %d", i)
+		}
+	}
+}
+
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+	for start := time.Now(); !f(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > testTimeout {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestSubscriber(t *testing.T) {
+	b, err := mqtttest.NewBroker(`127.0.0.1:0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	cfg, err := loadTestConfig(t, testGlobal+`
+[Subscriber "test"]
+	Broker="tcp://`+b.Addr()+`"
+	Protocol-Version=5
+	Client-ID=gravwell
+	Topic-Filter="sensors/#"
+	QoS=1
+	Default-Tag=sensors
+	Tag-Match="sensors/+/temp:temps"
+	Source-Override=10.0.0.1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mux ingesttest.Muxer
+	var tw ingesttest.Writer
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := newSubscriber(ctx, `test`, cfg.Subscriber[`test`], &mux, processors.NewProcessorSet(&tw), log.NewDiscardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go sub.run(&wg)
+	waitFor(t, `subscription`, func() bool { return b.Subscribed(`gravwell`) })
+
+	b.Publish(`sensors/kiln/temp`, []byte(`2024-03-04T05:06:07Z kiln 1200C`), 1)
+	b.Publish(`sensors/kiln/door`, []byte(`closed`), 1)
+	waitFor(t, `entries`, func() bool { return tw.Count() == 2 })
+	waitFor(t, `acknowledgements`, func() bool { return b.Pending(`gravwell`) == 0 })
+
+	ents := tw.Entries()
+	ent := ents[0]
+	if ent.Tag != mux.Tag(`temps`) || ents[1].Tag != mux.Tag(`sensors`) {
+		t.Fatalf("bad tags: %v %v", ent.Tag, ents[1].Tag)
+	} else if !ent.TS.StandardTime().Equal(time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Fatalf("bad timestamp: %v", ent.TS)
+	} else if ent.SRC.String() != `10.0.0.1` {
+		t.Fatalf("bad source: %v", ent.SRC)
+	} else if ev, ok := ent.GetEnumeratedValue(topicEVName); !ok || ev != `sensors/kiln/temp` {
+		t.Fatalf("bad topic EV: %v %v", ev, ok)
+	}
+	//stop accepting entries, the message must stay unacknowledged until the writer recovers
+	tw.Refuse(true)
+
+	b.Publish(`sensors/kiln/temp`, []byte(`retry me`), 1)
+	waitFor(t, `disconnect`, func() bool { return !b.Subscribed(`gravwell`) })
+	if n := b.Pending(`gravwell`); n != 1 {
+		t.Fatalf("refused message was acknowledged: %d pending", n)
+	}
+	tw.Refuse(false)
+	waitFor(t, `redelivery`, func() bool { return tw.Count() == 3 && b.Pending(`gravwell`) == 0 })
+
+	cancel()
+	wg.Wait()
+}
@@ -0,0 +1,93 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/gravwell/gravwell/v3/internal/mqtt"
+)
+
+const (
+	topicEVName = `mqtt_topic`
+)
+
+type tagGetter interface {
+	GetTag(string) (entry.EntryTag, error)
+}
+
+type subscriber struct {
+	name string
+	cfg  *subscriberCfg
+	cl   *mqtt.Client
+	tags map[string]entry.EntryTag
+	proc *processors.ProcessorSet
+	lg   *log.Logger
+	ctx  context.Context
+}
+
+func newSubscriber(ctx context.Context, name string, cfg *subscriberCfg, tg tagGetter, proc *processors.ProcessorSet, lg *log.Logger) (s *subscriber, err error) {
+	s = &subscriber{
+		name: name,
+		cfg:  cfg,
+		tags: map[string]entry.EntryTag{},
+		proc: proc,
+		lg:   lg,
+		ctx:  ctx,
+	}
+	for _, t := range cfg.tags() {
+		if s.tags[t], err = tg.GetTag(t); err != nil {
+			return nil, err
+		}
+	}
+	var cc mqtt.Config
+	if cc, err = cfg.clientConfig(); err != nil {
+		return nil, err
+	} else if s.cl, err = mqtt.NewClient(cc); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// run keeps the subscriber connected until the context is cancelled, backing off between failures
+func (s *subscriber) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	utils.RunWithRetry(s.ctx, func() error {
+		return s.cl.Run(s.ctx, s.handle)
+	}, func(err error, retry time.Duration) {
+		s.lg.Warn("MQTT subscriber disconnected", log.KV("subscriber", s.name), log.KV("broker", s.cfg.Broker),
+			log.KV("retry", retry), log.KVErr(err))
+	})
+}
+
+// handle builds an entry for a message, the client only acknowledges the message if the
+// entry was accepted by the muxer
+func (s *subscriber) handle(m *mqtt.Message) error {
+	ent := &entry.Entry{
+		TS:   entry.Now(),
+		SRC:  s.cfg.src,
+		Tag:  s.tags[s.cfg.tagFor(m.Topic)],
+		Data: m.Payload,
+	}
+	if s.cfg.tg != nil {
+		if ts, ok, err := s.cfg.tg.Extract(m.Payload); err != nil {
+			s.lg.Warn("catastrophic timegrinder error", log.KV("subscriber", s.name), log.KVErr(err))
+		} else if ok {
+			ent.TS = entry.FromStandard(ts)
+		}
+	}
+	ent.AddEnumeratedValueEx(topicEVName, m.Topic)
+	return s.proc.ProcessContext(ent, s.ctx)
+}
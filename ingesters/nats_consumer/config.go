@@ -0,0 +1,406 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/attach"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	defaultBatchSize  = 512
+	defaultFetchWait  = 5 * time.Second
+	defaultAckWait    = 30 * time.Second
+	maxBatchSize      = 4096
+	defaultDurablePfx = `gravwell`
+	tlsScheme         = `tls`
+	plainScheme       = `nats`
+	tagMatchSep       = `:`
+
+	deliverAll  = `all`
+	deliverNew  = `new`
+	deliverLast = `last`
+)
+
+type consumerCfg struct {
+	Server                    []string // host:port, nats:// for plaintext and tls:// for TLS
+	Username                  string
+	Password                  string
+	Token                     string
+	Use_TLS                   bool
+	CA_File                   string
+	Client_Cert               string
+	Client_Key                string
+	Insecure_Skip_TLS_Verify  bool
+	Stream                    string
+	Durable                   string // durable consumer name, defaults to gravwell_<consumer name>
+	Filter_Subject            string // only consume stream messages matching this subject
+	Deliver_Policy            string // all, new, or last, only applies when the durable is created
+	Ack_Wait                  string // how long the server waits for an ack before redelivering
+	Max_Ack_Pending           int
+	Batch_Size                int
+	Synchronous               bool // wait for the indexers to confirm a batch before acking it
+	Default_Tag               string
+	Tag_Match                 []string // subject:tag pairs, the first matching subject picks the tag
+	Ignore_Timestamps         bool     // use the time of arrival
+	Extract_Timestamps        bool     // extract timestamps from the payload instead of using the stream timestamp
+	Assume_Local_Timezone     bool
+	Timezone_Override         string
+	Timestamp_Format_Override string
+	Source_Override           string
+	Preprocessor              []string
+
+	src      net.IP
+	tagMatch []tagMatch
+	ackWait  time.Duration
+	tg       *timegrinder.TimeGrinder
+}
+
+type tagMatch struct {
+	subject string
+	tag     string
+}
+
+type cfgType struct {
+	Global       config.IngestConfig
+	Attach       attach.AttachConfig
+	Consumer     map[string]*consumerCfg
+	Preprocessor processors.ProcessorConfig
+	TimeFormat   config.CustomTimeFormat
+}
+
+func GetConfig(path, overlayPath string) (*cfgType, error) {
+	var c cfgType
+	if err := config.LoadConfigFile(&c, path); err != nil {
+		return nil, err
+	} else if err = config.LoadConfigOverlays(&c, overlayPath); err != nil {
+		return nil, err
+	}
+
+	if err := c.Verify(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *cfgType) Verify() error {
+	//verify the global parameters
+	if err := c.Global.Verify(); err != nil {
+		return err
+	} else if err = c.Attach.Verify(); err != nil {
+		return err
+	} else if err = c.TimeFormat.Validate(); err != nil {
+		return err
+	}
+
+	if len(c.Consumer) == 0 {
+		return errors.New("No Consumers specified")
+	}
+
+	if err := c.Preprocessor.Validate(); err != nil {
+		return err
+	}
+
+	durables := map[string]string{}
+	for k, v := range c.Consumer {
+		if err := v.verify(k); err != nil {
+			return fmt.Errorf("Consumer %s %w", k, err)
+		} else if err = c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("Consumer %s preprocessor invalid: %v", k, err)
+		}
+		//two sections sharing a durable would steal each other's messages
+		key := v.Stream + `.` + v.Durable
+		if other, ok := durables[key]; ok {
+			return fmt.Errorf("Consumers %s and %s use the same Stream and Durable", other, k)
+		}
+		durables[key] = k
+		if v.tg != nil {
+			//custom formats must be loaded before the override so it can reference them
+			if err := c.TimeFormat.LoadFormats(v.tg); err != nil {
+				return fmt.Errorf("Consumer %s %w", k, err)
+			} else if v.Timestamp_Format_Override != `` {
+				if err = v.tg.SetFormatOverride(v.Timestamp_Format_Override); err != nil {
+					return fmt.Errorf("Consumer %s invalid Timestamp-Format-Override %q: %w", k, v.Timestamp_Format_Override, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (cc *consumerCfg) verify(name string) (err error) {
+	if len(cc.Server) == 0 {
+		return errors.New("missing Server")
+	}
+	if _, err = cc.serverAddrs(); err != nil {
+		return
+	}
+	if cc.Password != `` && cc.Username == `` {
+		return errors.New("Password requires a Username")
+	} else if cc.Token != `` && cc.Username != `` {
+		return errors.New("Token and Username are mutually exclusive")
+	}
+	if (cc.Client_Cert == ``) != (cc.Client_Key == ``) {
+		return errors.New("Client-Cert and Client-Key must be specified together")
+	}
+
+	//JetStream consumer
+	if cc.Stream == `` {
+		return errors.New("missing Stream")
+	} else if !validName(cc.Stream) {
+		return fmt.Errorf("invalid Stream name %q", cc.Stream)
+	}
+	if cc.Durable == `` {
+		cc.Durable = defaultDurablePfx + `_` + name
+	}
+	if !validName(cc.Durable) {
+		return fmt.Errorf("invalid Durable name %q", cc.Durable)
+	}
+	if cc.Filter_Subject != `` {
+		if err = validateSubject(cc.Filter_Subject); err != nil {
+			return
+		}
+	}
+	switch cc.Deliver_Policy = strings.ToLower(strings.TrimSpace(cc.Deliver_Policy)); cc.Deliver_Policy {
+	case ``:
+		cc.Deliver_Policy = deliverAll
+	case deliverAll, deliverNew, deliverLast:
+	default:
+		return fmt.Errorf("invalid Deliver-Policy %q, options are all, new, or last", cc.Deliver_Policy)
+	}
+	cc.ackWait = defaultAckWait
+	if cc.Ack_Wait != `` {
+		if cc.ackWait, err = time.ParseDuration(cc.Ack_Wait); err != nil {
+			return fmt.Errorf("invalid Ack-Wait %q: %w", cc.Ack_Wait, err)
+		} else if cc.ackWait < time.Second {
+			return fmt.Errorf("Ack-Wait %v must be at least 1s", cc.ackWait)
+		}
+	}
+	if cc.Max_Ack_Pending < 0 {
+		return errors.New("Max-Ack-Pending may not be negative")
+	}
+	if cc.Batch_Size == 0 {
+		cc.Batch_Size = defaultBatchSize
+	} else if cc.Batch_Size < 0 || cc.Batch_Size > maxBatchSize {
+		return fmt.Errorf("Batch-Size %d must be between 1 and %d", cc.Batch_Size, maxBatchSize)
+	}
+	if cc.Max_Ack_Pending > 0 && cc.Max_Ack_Pending < cc.Batch_Size {
+		return fmt.Errorf("Max-Ack-Pending %d is smaller than Batch-Size %d", cc.Max_Ack_Pending, cc.Batch_Size)
+	}
+
+	//tags
+	if cc.Default_Tag == `` {
+		return errors.New("missing Default-Tag")
+	} else if err = ingest.CheckTag(cc.Default_Tag); err != nil {
+		return fmt.Errorf("invalid Default-Tag %q: %w", cc.Default_Tag, err)
+	}
+	cc.tagMatch = cc.tagMatch[:0]
+	for _, tm := range cc.Tag_Match {
+		idx := strings.LastIndex(tm, tagMatchSep)
+		if idx < 0 {
+			return fmt.Errorf("invalid Tag-Match %q, the format is subject:tag", tm)
+		}
+		m := tagMatch{
+			subject: strings.TrimSpace(tm[:idx]),
+			tag:     strings.TrimSpace(tm[idx+1:]),
+		}
+		if err = validateSubject(m.subject); err != nil {
+			return fmt.Errorf("invalid Tag-Match %q: %w", tm, err)
+		} else if err = ingest.CheckTag(m.tag); err != nil {
+			return fmt.Errorf("invalid Tag-Match %q: %w", tm, err)
+		}
+		cc.tagMatch = append(cc.tagMatch, m)
+	}
+
+	//timestamps
+	if cc.Ignore_Timestamps && cc.Extract_Timestamps {
+		return errors.New("Cannot specify Ignore-Timestamps and Extract-Timestamps in the same consumer")
+	} else if cc.Timezone_Override != `` && cc.Assume_Local_Timezone {
+		return errors.New("Cannot specify Assume-Local-Timezone and Timezone-Override in the same consumer")
+	}
+	if cc.Extract_Timestamps {
+		tcfg := timegrinder.Config{
+			EnableLeftMostSeed: true,
+		}
+		if cc.tg, err = timegrinder.NewTimeGrinder(tcfg); err != nil {
+			return fmt.Errorf("failed to generate new timegrinder: %w", err)
+		}
+		if cc.Assume_Local_Timezone {
+			cc.tg.SetLocalTime()
+		}
+		if cc.Timezone_Override != `` {
+			if err = cc.tg.SetTimezone(cc.Timezone_Override); err != nil {
+				return fmt.Errorf("invalid Timezone-Override %q: %w", cc.Timezone_Override, err)
+			}
+		}
+	}
+
+	if cc.Source_Override != `` {
+		if cc.src = net.ParseIP(cc.Source_Override); cc.src == nil {
+			return fmt.Errorf("Invalid Source-Override %q", cc.Source_Override)
+		}
+	}
+	return
+}
+
+// useTLS returns true if any server scheme or the TLS options ask for an encrypted connection
+func (cc *consumerCfg) useTLS() bool {
+	if cc.Use_TLS || cc.CA_File != `` || cc.Client_Cert != `` {
+		return true
+	}
+	for _, s := range cc.Server {
+		if scheme, _, ok := strings.Cut(strings.TrimSpace(s), `://`); ok && strings.ToLower(scheme) == tlsScheme {
+			return true
+		}
+	}
+	return false
+}
+
+// serverAddrs strips schemes from the servers and applies the default port
+func (cc *consumerCfg) serverAddrs() (addrs []string, err error) {
+	for _, s := range cc.Server {
+		addr := strings.TrimSpace(s)
+		if scheme, rest, ok := strings.Cut(addr, `://`); ok {
+			switch strings.ToLower(scheme) {
+			case plainScheme, tlsScheme:
+			default:
+				err = fmt.Errorf("invalid Server %q, unsupported scheme %q", s, scheme)
+				return
+			}
+			addr = rest
+		}
+		if _, _, lerr := net.SplitHostPort(addr); lerr != nil {
+			addr = net.JoinHostPort(addr, fmt.Sprintf("%d", nats.DefaultPort))
+		}
+		if _, _, err = net.SplitHostPort(addr); err != nil {
+			err = fmt.Errorf("invalid Server %q: %w", s, err)
+			return
+		}
+		addrs = append(addrs, addr)
+	}
+	return
+}
+
+func (cc *consumerCfg) tlsConfig() (*tls.Config, error) {
+	if !cc.useTLS() {
+		return nil, nil
+	}
+	//the client fills in the server name from each server URL
+	return utils.TLSClientConfig{
+		CAFile:             cc.CA_File,
+		CertFile:           cc.Client_Cert,
+		KeyFile:            cc.Client_Key,
+		InsecureSkipVerify: cc.Insecure_Skip_TLS_Verify,
+	}.Load()
+}
+
+// clientConfig builds the NATS connection options, TLS files are loaded here
+func (cc *consumerCfg) clientConfig(name string) (o nats.Options, err error) {
+	var addrs []string
+	if addrs, err = cc.serverAddrs(); err != nil {
+		return
+	}
+	o = nats.GetDefaultOptions()
+	if o.TLSConfig, err = cc.tlsConfig(); err != nil {
+		return
+	}
+	scheme := plainScheme
+	if o.Secure = o.TLSConfig != nil; o.Secure {
+		scheme = tlsScheme
+	}
+	for _, addr := range addrs {
+		o.Servers = append(o.Servers, scheme+`://`+addr)
+	}
+	o.User = cc.Username
+	o.Password = cc.Password
+	o.Token = cc.Token
+	o.Name = `gravwell-` + name
+	//the consumer reconnects with its own backoff so a dropped connection is logged and the durable rebound
+	o.AllowReconnect = false
+	return
+}
+
+func (cc *consumerCfg) consumerConfig() jetstream.ConsumerConfig {
+	c := jetstream.ConsumerConfig{
+		Durable:       cc.Durable,
+		FilterSubject: cc.Filter_Subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       cc.ackWait,
+		MaxAckPending: cc.Max_Ack_Pending,
+	}
+	switch cc.Deliver_Policy {
+	case deliverNew:
+		c.DeliverPolicy = jetstream.DeliverNewPolicy
+	case deliverLast:
+		c.DeliverPolicy = jetstream.DeliverLastPolicy
+	default:
+		c.DeliverPolicy = jetstream.DeliverAllPolicy
+	}
+	return c
+}
+
+// tags returns every tag this consumer may produce
+func (cc *consumerCfg) tags() (tags []string) {
+	tags = append(tags, cc.Default_Tag)
+	for _, tm := range cc.tagMatch {
+		tags = append(tags, tm.tag)
+	}
+	return
+}
+
+// tagFor returns the tag name for a subject
+func (cc *consumerCfg) tagFor(subject string) string {
+	for _, tm := range cc.tagMatch {
+		if matchSubject(tm.subject, subject) {
+			return tm.tag
+		}
+	}
+	return cc.Default_Tag
+}
+
+func (c *cfgType) Tags() ([]string, error) {
+	var tags []string
+	tagMp := map[string]bool{}
+	for _, v := range c.Consumer {
+		for _, t := range v.tags() {
+			if !tagMp[t] {
+				tags = append(tags, t)
+				tagMp[t] = true
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("No tags specified")
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func (c *cfgType) IngestBaseConfig() config.IngestConfig {
+	return c.Global
+}
+
+func (c *cfgType) AttachConfig() attach.AttachConfig {
+	return c.Attach
+}
@@ -0,0 +1,182 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	subjectEVName = `nats_subject`
+)
+
+type muxer interface {
+	GetTag(string) (entry.EntryTag, error)
+	SyncContext(context.Context, time.Duration) error
+}
+
+// flusher is the part of a NATS connection used to make sure the server has a batch of acks
+type flusher interface {
+	FlushWithContext(context.Context) error
+}
+
+type consumer struct {
+	name string
+	cfg  *consumerCfg
+	opts nats.Options
+	tags map[string]entry.EntryTag
+	igst muxer
+	proc *processors.ProcessorSet
+	lg   *log.Logger
+	ctx  context.Context
+}
+
+func newConsumer(ctx context.Context, name string, cfg *consumerCfg, igst muxer, proc *processors.ProcessorSet, lg *log.Logger) (c *consumer, err error) {
+	c = &consumer{
+		name: name,
+		cfg:  cfg,
+		tags: map[string]entry.EntryTag{},
+		igst: igst,
+		proc: proc,
+		lg:   lg,
+		ctx:  ctx,
+	}
+	for _, t := range cfg.tags() {
+		if c.tags[t], err = igst.GetTag(t); err != nil {
+			return nil, err
+		}
+	}
+	if c.opts, err = cfg.clientConfig(name); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// run keeps the consumer connected until the context is cancelled, backing off between failures
+func (c *consumer) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	utils.RunWithRetry(c.ctx, c.consume, func(err error, retry time.Duration) {
+		c.lg.Warn("NATS consumer disconnected", log.KV("consumer", c.name), log.KV("servers", c.cfg.Server),
+			log.KV("retry", retry), log.KVErr(err))
+	})
+}
+
+// consume connects, binds to the durable consumer or creates it, and pulls batches until something fails.
+// Existing durables are used as is so administrators can manage them outside of the ingester.
+func (c *consumer) consume() (err error) {
+	var nc *nats.Conn
+	if nc, err = c.opts.Connect(); err != nil {
+		return
+	}
+	defer nc.Close()
+	var js jetstream.JetStream
+	if js, err = jetstream.New(nc); err != nil {
+		return
+	}
+	var cons jetstream.Consumer
+	if cons, err = js.Consumer(c.ctx, c.cfg.Stream, c.cfg.Durable); errors.Is(err, jetstream.ErrConsumerNotFound) {
+		cons, err = js.CreateConsumer(c.ctx, c.cfg.Stream, c.cfg.consumerConfig())
+	}
+	if err != nil {
+		return
+	}
+	c.lg.Info("NATS consumer connected", log.KV("consumer", c.name), log.KV("stream", c.cfg.Stream),
+		log.KV("durable", c.cfg.Durable))
+	for {
+		var msgs []jetstream.Msg
+		if msgs, err = c.fetch(cons); err != nil {
+			return
+		} else if len(msgs) == 0 {
+			continue
+		}
+		if err = c.flush(nc, msgs); err != nil {
+			return
+		}
+	}
+}
+
+// fetch pulls up to a batch of messages, waiting up to defaultFetchWait for them to arrive
+func (c *consumer) fetch(cons jetstream.Consumer) (msgs []jetstream.Msg, err error) {
+	ctx, cancel := context.WithTimeout(c.ctx, defaultFetchWait)
+	defer cancel()
+	var batch jetstream.MessageBatch
+	if batch, err = cons.Fetch(c.cfg.Batch_Size, jetstream.FetchContext(ctx)); err != nil {
+		return
+	}
+	for m := range batch.Messages() {
+		msgs = append(msgs, m)
+	}
+	//running out the wait is not a failure, whatever arrived is handed off
+	if err = batch.Error(); errors.Is(err, context.DeadlineExceeded) && c.ctx.Err() == nil {
+		err = nil
+	}
+	return
+}
+
+// flush hands a batch to the muxer and acks it, if Synchronous is set the acks wait until the
+// indexers have the entries.  Failed batches are nak'd so the server redelivers them right away.
+func (c *consumer) flush(conn flusher, msgs []jetstream.Msg) (err error) {
+	for _, m := range msgs {
+		if err = c.proc.ProcessContext(c.buildEntry(m), c.ctx); err != nil {
+			break
+		}
+	}
+	if err == nil && c.cfg.Synchronous {
+		err = c.igst.SyncContext(c.ctx, 0)
+	}
+	if err != nil {
+		for _, m := range msgs {
+			if lerr := m.Nak(); lerr != nil {
+				break
+			}
+		}
+		return
+	}
+	for _, m := range msgs {
+		if err = m.Ack(); err != nil {
+			return
+		}
+	}
+	//make sure the server has the acks before pulling more
+	return conn.FlushWithContext(c.ctx)
+}
+
+func (c *consumer) buildEntry(m jetstream.Msg) *entry.Entry {
+	ent := &entry.Entry{
+		TS:   entry.Now(),
+		SRC:  c.cfg.src,
+		Tag:  c.tags[c.cfg.tagFor(m.Subject())],
+		Data: m.Data(),
+	}
+	if !c.cfg.Ignore_Timestamps {
+		if md, err := m.Metadata(); err == nil && !md.Timestamp.IsZero() {
+			ent.TS = entry.FromStandard(md.Timestamp)
+		}
+		if c.cfg.tg != nil {
+			if ts, ok, err := c.cfg.tg.Extract(m.Data()); err != nil {
+				c.lg.Warn("catastrophic timegrinder error", log.KV("consumer", c.name), log.KVErr(err))
+			} else if ok {
+				ent.TS = entry.FromStandard(ts)
+			}
+			// if not ok, we'll just use the stream timestamp
+		}
+	}
+	ent.AddEnumeratedValueEx(subjectEVName, m.Subject())
+	return ent
+}
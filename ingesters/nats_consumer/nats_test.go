@@ -0,0 +1,296 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils/ingesttest"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func loadTestConfig(t *testing.T, body string) (*cfgType, error) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), `nats.conf`)
+	if err := os.WriteFile(p, []byte(body), 0640); err != nil {
+		t.Fatal(err)
+	}
+	return GetConfig(p, ``)
+}
+
+const testGlobal = `
+[Global]
+Ingest-Secret = IngestSecrets
+Cleartext-Backend-Target=127.0.0.1:4023
+Log-Level=INFO
+`
+
+func TestConfig(t *testing.T) {
+	cfg, err := loadTestConfig(t, testGlobal+`
+[Consumer "events"]
+	Server="tls://nats1.example.com"
+	Server="nats2.example.com:4333"
+	Token=secret
+	Stream=EVENTS
+	Filter-Subject="events.>"
+	Deliver-Policy=New
+	Ack-Wait=1m
+	Batch-Size=100
+	Default-Tag=events
+	Tag-Match="events.auth.>:auth"
+	Tag-Match="events.*.error:errors"
+	Extract-Timestamps=true
+
+[Consumer "audit"]
+	Server=127.0.0.1
+	Stream=AUDIT
+	Durable=audit
+	Default-Tag=audit
+	Synchronous=true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := cfg.Tags(); err != nil {
+		t.Fatal(err)
+	} else if len(tags) != 4 {
+		t.Fatalf("bad tags: %v", tags)
+	}
+	ev := cfg.Consumer[`events`]
+	nc, err := ev.clientConfig(`events`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nc.Servers) != 2 || nc.Servers[0] != `tls://nats1.example.com:4222` || nc.Servers[1] != `tls://nats2.example.com:4333` {
+		t.Fatalf("bad servers: %v", nc.Servers)
+	} else if nc.TLSConfig == nil || !nc.Secure || nc.Token != `secret` || nc.Name != `gravwell-events` || nc.AllowReconnect {
+		t.Fatalf("bad client config: %+v", nc)
+	}
+	cc := ev.consumerConfig()
+	if cc.Durable != `gravwell_events` || cc.DeliverPolicy != jetstream.DeliverNewPolicy || cc.AckPolicy != jetstream.AckExplicitPolicy || cc.AckWait != time.Minute || cc.FilterSubject != `events.>` {
+		t.Fatalf("bad consumer config: %+v", cc)
+	} else if ev.tg == nil || ev.Batch_Size != 100 {
+		t.Fatalf("bad consumer settings")
+	}
+	tagTests := map[string]string{
+		`events.auth.login`:  `auth`,
+		`events.web.error`:   `errors`,
+		`events.web.request`: `events`,
+	}
+	for subject, tag := range tagTests {
+		if r := ev.tagFor(subject); r != tag {
+			t.Fatalf("bad tag for %s: %s != %s", subject, r, tag)
+		}
+	}
+	audit := cfg.Consumer[`audit`]
+	if nc, err = audit.clientConfig(`audit`); err != nil {
+		t.Fatal(err)
+	} else if nc.TLSConfig != nil || nc.Secure || nc.Servers[0] != `nats://127.0.0.1:4222` {
+		t.Fatalf("bad client config: %+v", nc)
+	} else if audit.Batch_Size != defaultBatchSize || audit.tg != nil {
+		t.Fatalf("bad defaults: %+v", audit)
+	} else if audit.consumerConfig().DeliverPolicy != jetstream.DeliverAllPolicy {
+		t.Fatalf("bad default deliver policy")
+	}
+
+	bad := []string{
+		`Stream=EVENTS`, //no server
+		`Server=ws://127.0.0.1
+		Stream=EVENTS`, //bad scheme
+		`Server=127.0.0.1`, //no stream
+		`Server=127.0.0.1
+		Stream=EVENTS.ALL`, //bad stream name
+		`Server=127.0.0.1
+		Stream=EVENTS
+		Filter-Subject="events.>.x"`, //bad filter
+		`Server=127.0.0.1
+		Stream=EVENTS
+		Deliver-Policy=oldest`, //bad policy
+		`Server=127.0.0.1
+		Stream=EVENTS
+		Tag-Match="events.>"`, //no tag
+		`Server=127.0.0.1
+		Stream=EVENTS
+		Ignore-Timestamps=true
+		Extract-Timestamps=true`, //conflicting timestamps
+		`Server=127.0.0.1
+		Stream=EVENTS
+		Token=x
+		Username=y`, //conflicting auth
+		`Server=127.0.0.1
+		Stream=EVENTS
+		Batch-Size=100
+		Max-Ack-Pending=10`, //batches can never fill
+	}
+	for i, b := range bad {
+		if _, err := loadTestConfig(t, testGlobal+"[Consumer \"bad\"]\n\tDefault-Tag=nats\n\t"+b+"\n"); err == nil {
+			t.Fatalf("failed to catch bad config %d", i)
+		}
+	}
+	//two sections on the same durable
+	if _, err = loadTestConfig(t, testGlobal+`
+[Consumer "a"]
+	Server=127.0.0.1
+	Stream=EVENTS
+	Durable=shared
+	Default-Tag=a
+[Consumer "b"]
+	Server=127.0.0.1
+	Stream=EVENTS
+	Durable=shared
+	Default-Tag=b
+`); err == nil {
+		t.Fatal("failed to catch shared durable")
+	}
+}
+
+func TestSubjects(t *testing.T) {
+	for _, v := range []string{`a`, `a.b.c`, `a.*.c`, `>`, `a.>`, `*.*`} {
+		if err := validateSubject(v); err != nil {
+			t.Fatalf("rejected %q: %v", v, err)
+		}
+	}
+	for _, v := range []string{``, `a..b`, `.a`, `a.`, `a.>.b`, `a b`} {
+		if err := validateSubject(v); err == nil {
+			t.Fatalf("accepted %q", v)
+		}
+	}
+	tests := []struct {
+		pattern, subject string
+		match            bool
+	}{
+		{`a.b`, `a.b`, true},
+		{`a.b`, `a.b.c`, false},
+		{`a.b.c`, `a.b`, false},
+		{`a.*`, `a.b`, true},
+		{`a.*`, `a.b.c`, false},
+		{`*.b`, `a.b`, true},
+		{`a.>`, `a.b.c`, true},
+		{`a.>`, `a`, false},
+		{`>`, `a.b`, true},
+		{`a.*.>`, `a.b.c.d`, true},
+		{`a.*.>`, `a.b`, false},
+	}
+	for _, tt := range tests {
+		if r := matchSubject(tt.pattern, tt.subject); r != tt.match {
+			t.Fatalf("matchSubject(%q, %q) = %v", tt.pattern, tt.subject, r)
+		}
+	}
+}
+
+// settlement records how a batch was settled
+type settlement struct {
+	acks    []string
+	naks    []string
+	flushes int
+}
+
+func (st *settlement) FlushWithContext(ctx context.Context) error {
+	st.flushes++
+	return nil
+}
+
+// testMsg is a JetStream message that settles into a settlement, the embedded interface
+// is nil so calling anything else panics
+type testMsg struct {
+	jetstream.Msg
+	subject string
+	data    []byte
+	ts      time.Time
+	st      *settlement
+}
+
+func (m *testMsg) Subject() string { return m.subject }
+func (m *testMsg) Data() []byte    { return m.data }
+
+func (m *testMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{Timestamp: m.ts}, nil
+}
+
+func (m *testMsg) Ack() error {
+	m.st.acks = append(m.st.acks, m.subject)
+	return nil
+}
+
+func (m *testMsg) Nak() error {
+	m.st.naks = append(m.st.naks, m.subject)
+	return nil
+}
+
+func TestFlush(t *testing.T) {
+	cfg, err := loadTestConfig(t, testGlobal+`
+[Consumer "test"]
+	Server=127.0.0.1
+	Stream=EVENTS
+	Default-Tag=events
+	Tag-Match="events.auth.>:auth"
+	Synchronous=true
+	Source-Override=10.0.0.1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := &ingesttest.Muxer{}
+	tw := &ingesttest.Writer{}
+	c, err := newConsumer(context.Background(), `test`, cfg.Consumer[`test`], mux, processors.NewProcessorSet(tw), log.NewDiscardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	st := &settlement{}
+	msgs := []jetstream.Msg{
+		&testMsg{subject: `events.auth.login`, data: []byte(`user logged in`), ts: ts, st: st},
+		&testMsg{subject: `events.web.request`, data: []byte(`GET /`), ts: ts, st: st},
+	}
+	if err = c.flush(st, msgs); err != nil {
+		t.Fatal(err)
+	}
+	if len(st.acks) != 2 || len(st.naks) != 0 || st.flushes != 1 || mux.Syncs() != 1 {
+		t.Fatalf("bad settlement: %+v syncs %d", st, mux.Syncs())
+	} else if tw.Count() != 2 {
+		t.Fatalf("bad entry count %d", tw.Count())
+	}
+	ents := tw.Take()
+	ent := ents[0]
+	if ent.Tag != mux.Tag(`auth`) || ents[1].Tag != mux.Tag(`events`) {
+		t.Fatalf("bad tags %v %v", ent.Tag, ents[1].Tag)
+	} else if !ent.TS.StandardTime().Equal(ts) {
+		t.Fatalf("did not use the stream timestamp: %v", ent.TS)
+	} else if ent.SRC.String() != `10.0.0.1` {
+		t.Fatalf("bad source %v", ent.SRC)
+	} else if ev, ok := ent.GetEnumeratedValue(subjectEVName); !ok || ev != `events.auth.login` {
+		t.Fatalf("bad subject EV %v %v", ev, ok)
+	}
+
+	//a failed sync must nak the whole batch
+	*st = settlement{}
+	mux.FailSyncs(true)
+	if err = c.flush(st, msgs); !errors.Is(err, ingesttest.ErrSyncFailed) {
+		t.Fatalf("bad error %v", err)
+	} else if len(st.acks) != 0 || len(st.naks) != 2 || st.flushes != 0 {
+		t.Fatalf("bad settlement after sync failure: %+v", st)
+	}
+
+	//as must a refused entry
+	*st = settlement{}
+	mux.FailSyncs(false)
+	tw.Refuse(true)
+	if err = c.flush(st, msgs); !errors.Is(err, ingesttest.ErrRefused) {
+		t.Fatalf("bad error %v", err)
+	} else if len(st.acks) != 0 || len(st.naks) != 2 {
+		t.Fatalf("bad settlement after refusal: %+v", st)
+	}
+}
@@ -0,0 +1,57 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrInvalidSubject = errors.New("Invalid NATS subject")
+)
+
+// validName checks a stream or consumer name, they may not contain subject tokens or whitespace
+func validName(n string) bool {
+	return n != `` && !strings.ContainsAny(n, ".*> \t\r\n")
+}
+
+// validateSubject checks a subject or pattern, * matches a single token and > matches
+// one or more trailing tokens
+func validateSubject(s string) error {
+	if s == `` || strings.ContainsAny(s, " \t\r\n") {
+		return fmt.Errorf("%w %q", ErrInvalidSubject, s)
+	}
+	tok := strings.Split(s, `.`)
+	for i, t := range tok {
+		if t == `` {
+			return fmt.Errorf("%w %q: empty token", ErrInvalidSubject, s)
+		} else if t == `>` && i != len(tok)-1 {
+			return fmt.Errorf("%w %q: > must be the last token", ErrInvalidSubject, s)
+		}
+	}
+	return nil
+}
+
+// matchSubject checks if a subject matches a pattern
+func matchSubject(pattern, subject string) bool {
+	for {
+		pt, prest, pmore := strings.Cut(pattern, `.`)
+		st, srest, smore := strings.Cut(subject, `.`)
+		if pt == `>` {
+			return st != ``
+		} else if pt != `*` && pt != st {
+			return false
+		} else if !pmore || !smore {
+			return pmore == smore
+		}
+		pattern, subject = prest, srest
+	}
+}
@@ -10,8 +10,10 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gravwell/gravwell/v3/ingest"
 	"github.com/gravwell/gravwell/v3/ingest/attach"
@@ -23,9 +25,11 @@ const (
 	MAX_CONFIG_SIZE int64 = (1024 * 1024 * 2) //2MB, even this is crazy large
 	nfv5Type              = iota
 	ipfixType             = iota
+	sflowType             = iota
 
 	nfv5Name  string = `netflowv5`
 	ipfixName string = `ipfix`
+	sflowName string = `sflow`
 )
 
 var ()
@@ -39,16 +43,25 @@ type collector struct {
 	Ignore_Timestamps     bool
 	Flow_Type             string
 	Session_Dump_Enabled  bool
+	Split_Samples         bool //sflow only, ingest each sample as its own datagram
+	Normalize_Flows       bool //decode flows and ingest each as a normalized JSON entry
+}
+
+type global struct {
+	config.IngestConfig
+	Template_Store_Location string //where Netflow v9 and IPFIX templates are persisted
+	Template_Timeout        string //how long a template is kept after it was last seen
+	Disable_Template_Store  bool
 }
 
 type cfgReadType struct {
-	Global    config.IngestConfig
+	Global    global
 	Attach    attach.AttachConfig
 	Collector map[string]*collector
 }
 
 type cfgType struct {
-	config.IngestConfig
+	global
 	Attach    attach.AttachConfig
 	Collector map[string]*collector
 }
@@ -62,9 +75,9 @@ func GetConfig(path, overlayPath string) (*cfgType, error) {
 		return nil, err
 	}
 	c := &cfgType{
-		IngestConfig: cr.Global,
-		Attach:       cr.Attach,
-		Collector:    cr.Collector,
+		global:    cr.Global,
+		Attach:    cr.Attach,
+		Collector: cr.Collector,
 	}
 
 	if err := c.Verify(); err != nil {
@@ -79,6 +92,8 @@ func (c *cfgType) Verify() error {
 		return err
 	} else if c.Attach.Verify(); err != nil {
 		return err
+	} else if err = c.global.verifyTemplateStore(); err != nil {
+		return err
 	}
 	if len(c.Collector) == 0 {
 		return errors.New("No collectors specified")
@@ -98,6 +113,20 @@ func (c *cfgType) Verify() error {
 			return errors.New("Bind-String for " + k + " already in use by " + n)
 		}
 		bindMp[v.Bind_String] = k
+		if v.Split_Samples {
+			if ft, err := translateFlowType(v.Flow_Type); err != nil {
+				return errors.New("Invalid Flow-Type for " + k)
+			} else if ft != sflowType {
+				return errors.New("Split-Samples is only supported for sflow collectors, " + k + " is " + ft.String())
+			}
+		}
+		if v.Normalize_Flows {
+			if ft, err := translateFlowType(v.Flow_Type); err != nil {
+				return errors.New("Invalid Flow-Type for " + k)
+			} else if ft != nfv5Type && ft != ipfixType {
+				return errors.New("Normalize-Flows is not supported for " + ft.String() + " collector " + k)
+			}
+		}
 	}
 	return nil
 }
@@ -121,6 +150,39 @@ func (c *cfgType) Tags() ([]string, error) {
 	return tags, nil
 }
 
+func (g *global) verifyTemplateStore() (err error) {
+	if g.Template_Store_Location == `` {
+		g.Template_Store_Location = defaultTemplateStoreLoc
+	}
+	if g.Template_Timeout != `` {
+		var d time.Duration
+		if d, err = time.ParseDuration(g.Template_Timeout); err != nil {
+			return fmt.Errorf("Invalid Template-Timeout %q: %w", g.Template_Timeout, err)
+		} else if d <= 0 {
+			return errors.New("Template-Timeout must be positive")
+		}
+	}
+	return
+}
+
+// TemplateTimeout returns the configured template timeout, Verify has already validated it
+func (g *global) TemplateTimeout() time.Duration {
+	if d, err := time.ParseDuration(g.Template_Timeout); err == nil && d > 0 {
+		return d
+	}
+	return defaultTemplateTimeout
+}
+
+// hasTemplateCollectors returns true if any collector handles template based flows
+func (c *cfgType) hasTemplateCollectors() bool {
+	for _, v := range c.Collector {
+		if ft, err := translateFlowType(v.Flow_Type); err == nil && ft == ipfixType {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *cfgType) IngestBaseConfig() config.IngestConfig {
 	return c.IngestConfig
 }
@@ -135,6 +197,8 @@ func (ft flowType) String() string {
 		return "Netflow V5"
 	case ipfixType:
 		return "IPFIX"
+	case sflowType:
+		return "sFlow"
 	}
 	return "unknown"
 }
@@ -150,6 +214,8 @@ func translateFlowType(s string) (flowType, error) {
 		return nfv5Type, nil
 	case ipfixName:
 		return ipfixType, nil
+	case sflowName:
+		return sflowType, nil
 	}
 	return -1, errors.New("invalid reader type")
 }
@@ -109,6 +109,13 @@ func (n *NetflowV5Handler) routine(id int) {
 		}
 		lbuff := make([]byte, l)
 		copy(lbuff, tbuff[0:l])
+		if n.normalize {
+			if err = nf.Decode(lbuff); err != nil {
+				continue
+			}
+			n.sendFlows(normalizeNFv5(&nf, addr.IP), addr.IP)
+			continue
+		}
 		if n.ignoreTS {
 			ts = entry.Now()
 		} else {
@@ -269,6 +276,10 @@ func (i *IpfixHandler) routine(id int) {
 			debugout("Creating new session for %v\n", key.String())
 			lg.Info("creating new session", log.KV("address", addr.IP), log.KV("domain", domainID))
 			s = ipfix.NewSession()
+			if trs := i.templates.load(i.collector, key); len(trs) > 0 {
+				s.LoadTemplateRecords(trs)
+				lg.Info("restored stored templates", log.KV("address", addr.IP), log.KV("domain", domainID), log.KV("count", len(trs)))
+			}
 			sessionMap[key] = s
 		}
 
@@ -285,6 +296,15 @@ func (i *IpfixHandler) routine(id int) {
 			// must have been a bad packet
 			continue
 		}
+		if len(msg.TemplateRecords) > 0 {
+			i.templates.update(i.collector, addr.IP, domainID, msg.TemplateRecords)
+		}
+
+		if i.normalize {
+			// the session resolves templates, so template only messages just update the session
+			i.sendFlows(normalizeIpfix(s, msg, addr.IP), addr.IP)
+			continue
+		}
 
 		// LookupTemplateRecords will fail if we haven't seen an appropriate
 		// template packet for this message yet. In that case, just pass along
@@ -321,3 +341,113 @@ func (i *IpfixHandler) routine(id int) {
 		i.ch <- e
 	}
 }
+
+type SflowHandler struct {
+	bindConfig
+	mtx   *sync.Mutex
+	c     *net.UDPConn
+	ready bool
+}
+
+func NewSflowHandler(c bindConfig) (*SflowHandler, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &SflowHandler{
+		bindConfig: c,
+		mtx:        &sync.Mutex{},
+	}, nil
+}
+
+func (s *SflowHandler) String() string {
+	return `sFlow`
+}
+
+func (s *SflowHandler) Listen(str string) (err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.c != nil {
+		err = ErrAlreadyListening
+		return
+	}
+	var a *net.UDPAddr
+	if a, err = net.ResolveUDPAddr("udp", str); err != nil {
+		return
+	}
+	if s.c, err = net.ListenUDP("udp", a); err == nil {
+		s.ready = true
+	}
+	return
+}
+
+func (s *SflowHandler) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s == nil {
+		return ErrAlreadyClosed
+	}
+	s.ready = false
+	return s.c.Close()
+}
+
+func (s *SflowHandler) Start(id int) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if !s.ready || s.c == nil {
+		return ErrNotReady
+	}
+	if id < 0 {
+		return errors.New("invalid id")
+	}
+	go s.routine(id)
+	return nil
+}
+
+func (s *SflowHandler) routine(id int) {
+	defer s.wg.Done()
+	defer delConn(id)
+	var l int
+	var addr *net.UDPAddr
+	var hdr netflow.SFlowHeader
+	var err error
+	tbuff := make([]byte, 65507) // just go with max UDP packet size
+	for {
+		if l, addr, err = s.c.ReadFromUDP(tbuff); err != nil {
+			debugout("Error in ReadFromUDP: %v\n", err)
+			return
+		}
+		if hdr, err = netflow.SFlowValidate(tbuff[:l]); err != nil {
+			debugout("Rejecting sFlow packet from %v: %v\n", addr.IP, err)
+			continue //there isn't much we can do about bad packets...
+		}
+		//the agent address is the device that actually sampled the traffic, it may not be the sender
+		src := hdr.AgentAddr
+		if src == nil || src.IsUnspecified() {
+			src = addr.IP
+		}
+		// sFlow datagrams do not carry a wall clock timestamp, only the agent uptime
+		ts := entry.Now()
+		var dgs [][]byte
+		if s.splitSamples {
+			if dgs, err = netflow.SFlowSplitSamples(tbuff[:l]); err != nil {
+				//ingest the datagram whole rather than drop it
+				debugout("Failed to split sFlow samples from %v: %v\n", addr.IP, err)
+				dgs = nil
+			}
+		}
+		if dgs == nil {
+			lbuff := make([]byte, l)
+			copy(lbuff, tbuff[0:l])
+			dgs = [][]byte{lbuff}
+		}
+		for _, dg := range dgs {
+			s.ch <- &entry.Entry{
+				Tag:  s.tag,
+				SRC:  src,
+				TS:   ts,
+				Data: dg,
+			}
+		}
+	}
+}
@@ -10,6 +10,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net"
 	"os"
@@ -28,14 +29,17 @@ import (
 )
 
 const (
-	defaultConfigLoc  = `/opt/gravwell/etc/netflow_capture.conf`
-	defaultConfigDLoc = `/opt/gravwell/etc/netflow_capture.conf.d`
-	ingesterName      = `flow`
-	appName           = `netflow`
-	batchSize         = 512
+	defaultConfigLoc        = `/opt/gravwell/etc/netflow_capture.conf`
+	defaultConfigDLoc       = `/opt/gravwell/etc/netflow_capture.conf.d`
+	defaultTemplateStoreLoc = `/opt/gravwell/etc/netflow_templates.state`
+	ingesterName            = `flow`
+	appName                 = `netflow`
+	batchSize               = 512
 )
 
 var (
+	dumpTemplates = flag.Bool("dump-templates", false, "Dump the Netflow v9 and IPFIX template store in a human format and exit")
+
 	debugOn bool
 	lg      *log.Logger
 
@@ -62,6 +66,13 @@ func main() {
 	}
 	debugOn = ib.Verbose
 	lg = ib.Logger
+
+	//check if we are just dumping templates, if so, do it and exit cleanly
+	if *dumpTemplates {
+		dumpTemplateStore(cfg.Template_Store_Location)
+		os.Exit(0)
+	}
+
 	id, ok := cfg.IngesterUUID()
 	if !ok {
 		ib.Logger.FatalCode(0, "could not read ingester UUID")
@@ -95,6 +106,13 @@ func main() {
 		}
 	}
 
+	if !cfg.Disable_Template_Store && cfg.hasTemplateCollectors() {
+		if bc.templates, err = newTemplateStore(cfg.Template_Store_Location, cfg.TemplateTimeout()); err != nil {
+			lg.FatalCode(0, "failed to open template store", log.KV("path", cfg.Template_Store_Location), log.KVErr(err))
+		}
+		go bc.templates.routine(exitCtx)
+	}
+
 	//fire up our backends
 	for k, v := range cfg.Collector {
 		//get the tag for this listener
@@ -110,6 +128,9 @@ func main() {
 		bc.ignoreTS = v.Ignore_Timestamps
 		bc.localTZ = v.Assume_Local_Timezone
 		bc.sessionDumpEnabled = v.Session_Dump_Enabled
+		bc.splitSamples = v.Split_Samples
+		bc.normalize = v.Normalize_Flows
+		bc.collector = k
 		bc.lastInfoDump = time.Now()
 		var bh BindHandler
 		switch ft {
@@ -123,6 +144,11 @@ func main() {
 				lg.FatalCode(0, "NewIpfixHandler failed", log.KVErr(err))
 				return
 			}
+		case sflowType:
+			if bh, err = NewSflowHandler(bc); err != nil {
+				lg.FatalCode(0, "NewSflowHandler failed", log.KVErr(err))
+				return
+			}
 		default:
 			lg.FatalCode(0, "invalid flow type", log.KV("flowtype", ft))
 			return
@@ -171,6 +197,11 @@ func main() {
 	}
 
 	exitFn()
+	if bc.templates != nil {
+		if err := bc.templates.flush(); err != nil {
+			lg.Error("failed to write template store", log.KV("path", cfg.Template_Store_Location), log.KVErr(err))
+		}
+	}
 
 	lg.Info("netflow ingester exiting", log.KV("ingesteruuid", id))
 	if err := igst.Sync(utils.ExitSyncTimeout); err != nil {
@@ -0,0 +1,277 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/netflow"
+	"github.com/gravwell/ipfix"
+)
+
+// IANA information element IDs, Netflow v9 uses the same IDs for all of the fields we care about
+const (
+	ieOctetDeltaCount          uint16 = 1
+	iePacketDeltaCount         uint16 = 2
+	ieProtocolIdentifier       uint16 = 4
+	ieIPClassOfService         uint16 = 5
+	ieTCPControlBits           uint16 = 6
+	ieSourceTransportPort      uint16 = 7
+	ieSourceIPv4Address        uint16 = 8
+	ieIngressInterface         uint16 = 10
+	ieDestinationTransportPort uint16 = 11
+	ieDestinationIPv4Address   uint16 = 12
+	ieEgressInterface          uint16 = 14
+	ieIPNextHopIPv4Address     uint16 = 15
+	ieBgpSourceAsNumber        uint16 = 16
+	ieBgpDestinationAsNumber   uint16 = 17
+	ieFlowEndSysUpTime         uint16 = 21
+	ieFlowStartSysUpTime       uint16 = 22
+	ieSourceIPv6Address        uint16 = 27
+	ieDestinationIPv6Address   uint16 = 28
+	ieIPNextHopIPv6Address     uint16 = 62
+	ieOctetTotalCount          uint16 = 85
+	iePacketTotalCount         uint16 = 86
+	ieFlowStartSeconds         uint16 = 150
+	ieFlowEndSeconds           uint16 = 151
+	ieFlowStartMilliseconds    uint16 = 152
+	ieFlowEndMilliseconds      uint16 = 153
+	ieSystemInitTimeMillis     uint16 = 160
+
+	nfv9Version uint16 = 9
+	nfv9Name    string = `netflowv9`
+)
+
+// normalizedFlow is the common representation of a single flow regardless of the wire format
+type normalizedFlow struct {
+	Type        string     `json:"type"`
+	Exporter    net.IP     `json:"exporter"`
+	Src         net.IP     `json:"src_addr,omitempty"`
+	Dst         net.IP     `json:"dst_addr,omitempty"`
+	SrcPort     uint16     `json:"src_port"`
+	DstPort     uint16     `json:"dst_port"`
+	Protocol    uint8      `json:"proto"`
+	Bytes       uint64     `json:"bytes"`
+	Packets     uint64     `json:"packets"`
+	Start       *time.Time `json:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty"`
+	InputIface  uint32     `json:"input_iface"`
+	OutputIface uint32     `json:"output_iface"`
+	TCPFlags    uint8      `json:"tcp_flags"`
+	ToS         uint8      `json:"tos"`
+	NextHop     net.IP     `json:"next_hop,omitempty"`
+	SrcAS       uint32     `json:"src_as"`
+	DstAS       uint32     `json:"dst_as"`
+
+	exportTime time.Time
+}
+
+// entry encodes the flow and wraps it in an entry, the timestamp is the end of the flow
+// when it is known and the export time of the containing message otherwise.
+func (f *normalizedFlow) entry(tag entry.EntryTag, src net.IP, ignoreTS bool) (e *entry.Entry, err error) {
+	var b []byte
+	if b, err = json.Marshal(f); err != nil {
+		return
+	}
+	ts := entry.Now()
+	if !ignoreTS {
+		if f.End != nil {
+			ts = entry.FromStandard(*f.End)
+		} else {
+			ts = entry.FromStandard(f.exportTime)
+		}
+	}
+	e = &entry.Entry{
+		Tag:  tag,
+		SRC:  src,
+		TS:   ts,
+		Data: b,
+	}
+	return
+}
+
+// sendFlows encodes a set of normalized flows and hands them to the relay
+func (bc *bindConfig) sendFlows(flows []normalizedFlow, src net.IP) {
+	for i := range flows {
+		e, err := flows[i].entry(bc.tag, src, bc.ignoreTS)
+		if err != nil {
+			debugout("Failed to encode normalized flow: %v\n", err)
+			continue
+		}
+		bc.ch <- e
+	}
+}
+
+// uptimeToTime converts a flow uptime value into an absolute time using the export time and the
+// uptime of the exporter at the export time, all uptimes are in milliseconds.
+func uptimeToTime(export time.Time, sysUptime, flowUptime uint32) time.Time {
+	// uptimes are unsigned and wrap, the subtraction handles the wrap for us
+	return export.Add(-time.Duration(sysUptime-flowUptime) * time.Millisecond)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// normalizeNFv5 generates a normalized flow for every record in a decoded Netflow v5 datagram
+func normalizeNFv5(nf *netflow.NFv5, exporter net.IP) (flows []normalizedFlow) {
+	export := time.Unix(int64(nf.Sec), int64(nf.Nsec)).UTC()
+	flows = make([]normalizedFlow, 0, nf.Count)
+	for i := uint16(0); i < nf.Count && int(i) < len(nf.Recs); i++ {
+		r := &nf.Recs[i]
+		flows = append(flows, normalizedFlow{
+			Type:        nfv5Name,
+			Exporter:    exporter,
+			Src:         r.Src,
+			Dst:         r.Dst,
+			SrcPort:     r.SrcPort,
+			DstPort:     r.DstPort,
+			Protocol:    r.Protocol,
+			Bytes:       uint64(r.Bytes),
+			Packets:     uint64(r.Pkts),
+			Start:       timePtr(uptimeToTime(export, nf.Uptime, r.UptimeFirst)),
+			End:         timePtr(uptimeToTime(export, nf.Uptime, r.UptimeLast)),
+			InputIface:  uint32(r.Input),
+			OutputIface: uint32(r.Output),
+			TCPFlags:    r.Flags,
+			ToS:         r.ToS,
+			NextHop:     r.Next,
+			SrcAS:       uint32(r.SrcAs),
+			DstAS:       uint32(r.DstAs),
+			exportTime:  export,
+		})
+	}
+	return
+}
+
+// normalizeIpfix generates a normalized flow for every data record in a parsed Netflow v9 or IPFIX message.
+// Templates are resolved through the session that parsed the message, records without a template are skipped.
+func normalizeIpfix(s *ipfix.Session, msg ipfix.Message, exporter net.IP) (flows []normalizedFlow) {
+	if len(msg.DataRecords) == 0 {
+		return
+	}
+	tmpls, err := s.LookupTemplateRecords(msg)
+	if err != nil {
+		return
+	}
+	specs := make(map[uint16][]ipfix.TemplateFieldSpecifier, len(tmpls))
+	for _, t := range tmpls {
+		specs[t.TemplateID] = t.FieldSpecifiers
+	}
+	export := time.Unix(int64(msg.Header.ExportTime), 0).UTC()
+	name := ipfixName
+	if msg.Header.Version == nfv9Version {
+		name = nfv9Name
+	}
+	flows = make([]normalizedFlow, 0, len(msg.DataRecords))
+	for _, dr := range msg.DataRecords {
+		spec, ok := specs[dr.TemplateID]
+		if !ok || len(spec) != len(dr.Fields) {
+			continue
+		}
+		f := normalizedFlow{
+			Type:       name,
+			Exporter:   exporter,
+			exportTime: export,
+		}
+		var startUp, endUp, initMs uint64
+		var haveStartUp, haveEndUp, haveInit bool
+		for i, fs := range spec {
+			if fs.EnterpriseID != 0 {
+				continue
+			}
+			v := dr.Fields[i]
+			switch fs.FieldID {
+			case ieOctetDeltaCount, ieOctetTotalCount:
+				f.Bytes = beUint(v)
+			case iePacketDeltaCount, iePacketTotalCount:
+				f.Packets = beUint(v)
+			case ieProtocolIdentifier:
+				f.Protocol = uint8(beUint(v))
+			case ieIPClassOfService:
+				f.ToS = uint8(beUint(v))
+			case ieTCPControlBits:
+				f.TCPFlags = uint8(beUint(v))
+			case ieSourceTransportPort:
+				f.SrcPort = uint16(beUint(v))
+			case ieDestinationTransportPort:
+				f.DstPort = uint16(beUint(v))
+			case ieSourceIPv4Address, ieSourceIPv6Address:
+				f.Src = ipField(v)
+			case ieDestinationIPv4Address, ieDestinationIPv6Address:
+				f.Dst = ipField(v)
+			case ieIPNextHopIPv4Address, ieIPNextHopIPv6Address:
+				f.NextHop = ipField(v)
+			case ieIngressInterface:
+				f.InputIface = uint32(beUint(v))
+			case ieEgressInterface:
+				f.OutputIface = uint32(beUint(v))
+			case ieBgpSourceAsNumber:
+				f.SrcAS = uint32(beUint(v))
+			case ieBgpDestinationAsNumber:
+				f.DstAS = uint32(beUint(v))
+			case ieFlowStartSeconds:
+				f.Start = timePtr(time.Unix(int64(beUint(v)), 0).UTC())
+			case ieFlowEndSeconds:
+				f.End = timePtr(time.Unix(int64(beUint(v)), 0).UTC())
+			case ieFlowStartMilliseconds:
+				f.Start = timePtr(time.UnixMilli(int64(beUint(v))).UTC())
+			case ieFlowEndMilliseconds:
+				f.End = timePtr(time.UnixMilli(int64(beUint(v))).UTC())
+			case ieFlowStartSysUpTime:
+				startUp, haveStartUp = beUint(v), true
+			case ieFlowEndSysUpTime:
+				endUp, haveEndUp = beUint(v), true
+			case ieSystemInitTimeMillis:
+				initMs, haveInit = beUint(v), true
+			}
+		}
+		// uptime based timestamps are only used when absolute times are not present
+		// v9 carries the exporter uptime in the header, IPFIX needs the system init time in the record
+		if msg.Header.Version == nfv9Version {
+			if f.Start == nil && haveStartUp {
+				f.Start = timePtr(uptimeToTime(export, msg.Header.SysUptime, uint32(startUp)))
+			}
+			if f.End == nil && haveEndUp {
+				f.End = timePtr(uptimeToTime(export, msg.Header.SysUptime, uint32(endUp)))
+			}
+		} else if haveInit {
+			if f.Start == nil && haveStartUp {
+				f.Start = timePtr(time.UnixMilli(int64(initMs + startUp)).UTC())
+			}
+			if f.End == nil && haveEndUp {
+				f.End = timePtr(time.UnixMilli(int64(initMs + endUp)).UTC())
+			}
+		}
+		flows = append(flows, f)
+	}
+	return
+}
+
+// beUint decodes a big endian unsigned integer of up to 8 bytes, IPFIX allows reduced size encoding
+func beUint(v []byte) (r uint64) {
+	if len(v) > 8 {
+		v = v[len(v)-8:]
+	}
+	for _, b := range v {
+		r = (r << 8) | uint64(b)
+	}
+	return
+}
+
+func ipField(v []byte) net.IP {
+	switch len(v) {
+	case net.IPv4len, net.IPv6len:
+		return net.IP(append([]byte(nil), v...))
+	}
+	return nil
+}
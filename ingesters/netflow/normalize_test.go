@@ -0,0 +1,153 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/netflow"
+	"github.com/gravwell/ipfix"
+)
+
+var (
+	testExporter = net.ParseIP("172.16.0.1")
+	testExport   = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+)
+
+func TestNormalizeNFv5(t *testing.T) {
+	nf := netflow.NFv5{
+		NFv5Header: netflow.NFv5Header{
+			Version: 5,
+			Count:   1,
+			Uptime:  100000,
+			Sec:     uint32(testExport.Unix()),
+		},
+	}
+	nf.Recs[0] = netflow.NFv5Record{
+		Src:         net.ParseIP("10.0.0.1").To4(),
+		Dst:         net.ParseIP("10.0.0.2").To4(),
+		Next:        net.ParseIP("10.0.0.254").To4(),
+		Input:       1,
+		Output:      2,
+		Pkts:        10,
+		Bytes:       1500,
+		UptimeFirst: 90000,
+		UptimeLast:  99000,
+		SrcPort:     1234,
+		DstPort:     443,
+		Protocol:    6,
+		Flags:       0x1b,
+	}
+	b, err := nf.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec netflow.NFv5
+	if err = dec.Decode(b); err != nil {
+		t.Fatal(err)
+	}
+	flows := normalizeNFv5(&dec, testExporter)
+	if len(flows) != 1 {
+		t.Fatalf("bad flow count: %d", len(flows))
+	}
+	f := flows[0]
+	if f.Type != nfv5Name || !f.Src.Equal(net.ParseIP("10.0.0.1")) || !f.Dst.Equal(net.ParseIP("10.0.0.2")) ||
+		f.SrcPort != 1234 || f.DstPort != 443 || f.Protocol != 6 || f.Bytes != 1500 || f.Packets != 10 ||
+		f.InputIface != 1 || f.OutputIface != 2 || f.TCPFlags != 0x1b {
+		t.Fatalf("bad normalized flow: %+v", f)
+	}
+	if f.Start == nil || !f.Start.Equal(testExport.Add(-10*time.Second)) {
+		t.Fatalf("bad start time: %v", f.Start)
+	} else if f.End == nil || !f.End.Equal(testExport.Add(-time.Second)) {
+		t.Fatalf("bad end time: %v", f.End)
+	}
+	e, err := f.entry(0, testExporter, false)
+	if err != nil {
+		t.Fatal(err)
+	} else if !e.TS.StandardTime().Equal(*f.End) {
+		t.Fatalf("bad entry timestamp: %v", e.TS)
+	}
+	var mp map[string]interface{}
+	if err = json.Unmarshal(e.Data, &mp); err != nil {
+		t.Fatal(err)
+	} else if mp["src_addr"] != "10.0.0.1" || mp["dst_port"] != float64(443) || mp["exporter"] != testExporter.String() {
+		t.Fatalf("bad encoded flow: %s", e.Data)
+	}
+}
+
+func TestNormalizeIpfix(t *testing.T) {
+	s := ipfix.NewSession()
+	//template message, no flows should come out of it
+	tmpl := ipfixMessage(2, func(b []byte) []byte {
+		b = binary.BigEndian.AppendUint16(b, 256) // template id
+		b = binary.BigEndian.AppendUint16(b, 7)   // field count
+		for _, f := range [][2]uint16{
+			{ieSourceIPv4Address, 4},
+			{ieDestinationIPv4Address, 4},
+			{ieSourceTransportPort, 2},
+			{ieDestinationTransportPort, 2},
+			{ieProtocolIdentifier, 1},
+			{ieOctetDeltaCount, 4}, // reduced size encoding
+			{ieFlowEndMilliseconds, 8},
+		} {
+			b = binary.BigEndian.AppendUint16(b, f[0])
+			b = binary.BigEndian.AppendUint16(b, f[1])
+		}
+		return b
+	})
+	msg, err := s.ParseBuffer(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	} else if flows := normalizeIpfix(s, msg, testExporter); len(flows) != 0 {
+		t.Fatalf("template message produced flows: %d", len(flows))
+	}
+
+	data := ipfixMessage(256, func(b []byte) []byte {
+		b = append(b, 192, 168, 1, 1, 192, 168, 1, 2)
+		b = binary.BigEndian.AppendUint16(b, 53)
+		b = binary.BigEndian.AppendUint16(b, 5353)
+		b = append(b, 17)
+		b = binary.BigEndian.AppendUint32(b, 4096)
+		b = binary.BigEndian.AppendUint64(b, uint64(testExport.UnixMilli()))
+		return b
+	})
+	if msg, err = s.ParseBuffer(data); err != nil {
+		t.Fatal(err)
+	}
+	flows := normalizeIpfix(s, msg, testExporter)
+	if len(flows) != 1 {
+		t.Fatalf("bad flow count: %d", len(flows))
+	}
+	f := flows[0]
+	if f.Type != ipfixName || !f.Src.Equal(net.ParseIP("192.168.1.1")) || !f.Dst.Equal(net.ParseIP("192.168.1.2")) ||
+		f.SrcPort != 53 || f.DstPort != 5353 || f.Protocol != 17 || f.Bytes != 4096 {
+		t.Fatalf("bad normalized flow: %+v", f)
+	}
+	if f.Start != nil || f.End == nil || !f.End.Equal(testExport) {
+		t.Fatalf("bad flow times: %v %v", f.Start, f.End)
+	}
+}
+
+// ipfixMessage builds an IPFIX message with a single set
+func ipfixMessage(setID uint16, body func([]byte) []byte) []byte {
+	set := body(nil)
+	b := make([]byte, 0, 20+len(set))
+	b = binary.BigEndian.AppendUint16(b, 10)
+	b = binary.BigEndian.AppendUint16(b, uint16(16+4+len(set)))
+	b = binary.BigEndian.AppendUint32(b, uint32(testExport.Unix()))
+	b = binary.BigEndian.AppendUint32(b, 1) // sequence
+	b = binary.BigEndian.AppendUint32(b, 0) // domain
+	b = binary.BigEndian.AppendUint16(b, setID)
+	b = binary.BigEndian.AppendUint16(b, uint16(4+len(set)))
+	return append(b, set...)
+}
@@ -0,0 +1,267 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/ipfix"
+)
+
+const (
+	defaultTemplateTimeout     = 24 * time.Hour
+	templateStoreFlushInterval = time.Minute
+)
+
+var (
+	ErrInvalidTemplateStore = errors.New("template store location is not a regular file")
+)
+
+// templateStore persists Netflow v9 and IPFIX templates for each collector, exporter, and domain
+// so that data records can be decoded immediately after a restart rather than waiting for the
+// exporter to resend its templates.
+type templateStore struct {
+	sync.Mutex
+	path    string
+	timeout time.Duration
+	dirty   bool
+	states  map[templateStateKey]*templateState
+}
+
+type templateStateKey struct {
+	collector string
+	session   sessionKey
+}
+
+// templateState is the persisted set of templates for a single exporter session
+type templateState struct {
+	Collector string
+	Exporter  net.IP
+	Domain    uint32
+	Templates map[uint16]storedTemplate
+}
+
+type storedTemplate struct {
+	Record   ipfix.TemplateRecord
+	LastSeen time.Time
+}
+
+// newTemplateStore opens the template store at the given path and loads any unexpired templates
+// a missing file is not an error, it will be created on the first flush
+func newTemplateStore(pth string, timeout time.Duration) (ts *templateStore, err error) {
+	if timeout <= 0 {
+		timeout = defaultTemplateTimeout
+	}
+	ts = &templateStore{
+		path:    pth,
+		timeout: timeout,
+		states:  map[templateStateKey]*templateState{},
+	}
+	var states []templateState
+	if states, err = decodeTemplateStore(pth); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		err = nil
+	}
+	for _, st := range states {
+		if len(st.Templates) == 0 {
+			continue
+		}
+		st := st
+		key := templateStateKey{
+			collector: st.Collector,
+			session:   getSessionKey(st.Domain, st.Exporter),
+		}
+		ts.states[key] = &st
+	}
+	//immediately expire anything that went stale while we were down
+	ts.expire(time.Now())
+	return
+}
+
+// decodeTemplateStore reads the persisted template states from a template store file
+func decodeTemplateStore(pth string) (states []templateState, err error) {
+	var fin *os.File
+	var fi os.FileInfo
+	if fi, err = os.Stat(pth); err != nil {
+		return
+	} else if !fi.Mode().IsRegular() {
+		err = ErrInvalidTemplateStore
+		return
+	} else if fi.Size() == 0 {
+		return
+	}
+	if fin, err = os.Open(pth); err != nil {
+		return
+	}
+	defer fin.Close()
+	if err = gob.NewDecoder(fin).Decode(&states); err != nil {
+		err = fmt.Errorf("failed to decode template store %s: %w", pth, err)
+	}
+	return
+}
+
+// load returns the stored templates for a collector and exporter session
+func (ts *templateStore) load(collector string, key sessionKey) (trs []ipfix.TemplateRecord) {
+	if ts == nil {
+		return
+	}
+	ts.Lock()
+	if st, ok := ts.states[templateStateKey{collector: collector, session: key}]; ok {
+		trs = make([]ipfix.TemplateRecord, 0, len(st.Templates))
+		for _, t := range st.Templates {
+			trs = append(trs, t.Record)
+		}
+	}
+	ts.Unlock()
+	return
+}
+
+// update records templates that were just seen from an exporter, the session must have already
+// registered the templates so that IDs are consistent with what the session will hand back.
+func (ts *templateStore) update(collector string, exporter net.IP, domain uint32, trs []ipfix.TemplateRecord) {
+	if ts == nil || len(trs) == 0 {
+		return
+	}
+	now := time.Now()
+	key := templateStateKey{collector: collector, session: getSessionKey(domain, exporter)}
+	ts.Lock()
+	st, ok := ts.states[key]
+	if !ok {
+		st = &templateState{
+			Collector: collector,
+			Exporter:  exporter,
+			Domain:    domain,
+			Templates: map[uint16]storedTemplate{},
+		}
+		ts.states[key] = st
+	}
+	for _, tr := range trs {
+		if len(tr.FieldSpecifiers) == 0 {
+			//a template withdrawal
+			delete(st.Templates, tr.TemplateID)
+		} else {
+			st.Templates[tr.TemplateID] = storedTemplate{Record: tr, LastSeen: now}
+		}
+	}
+	ts.dirty = true
+	ts.Unlock()
+}
+
+// expire drops any templates that have not been seen within the timeout
+func (ts *templateStore) expire(now time.Time) (cnt int) {
+	ts.Lock()
+	for k, st := range ts.states {
+		for id, t := range st.Templates {
+			if now.Sub(t.LastSeen) > ts.timeout {
+				delete(st.Templates, id)
+				cnt++
+			}
+		}
+		if len(st.Templates) == 0 {
+			delete(ts.states, k)
+		}
+	}
+	if cnt > 0 {
+		ts.dirty = true
+	}
+	ts.Unlock()
+	return
+}
+
+// flush writes the template states out to the store if anything changed
+// the file is written to a temporary file and renamed so that a crash never leaves a partial store
+func (ts *templateStore) flush() (err error) {
+	ts.Lock()
+	defer ts.Unlock()
+	if !ts.dirty {
+		return
+	}
+	states := make([]templateState, 0, len(ts.states))
+	for _, st := range ts.states {
+		states = append(states, *st)
+	}
+	var fout *os.File
+	if fout, err = os.CreateTemp(filepath.Dir(ts.path), filepath.Base(ts.path)+".tmp"); err != nil {
+		return
+	}
+	if err = gob.NewEncoder(fout).Encode(states); err != nil {
+		fout.Close()
+		os.Remove(fout.Name())
+		return
+	} else if err = fout.Close(); err != nil {
+		os.Remove(fout.Name())
+		return
+	} else if err = os.Rename(fout.Name(), ts.path); err != nil {
+		os.Remove(fout.Name())
+		return
+	}
+	ts.dirty = false
+	return
+}
+
+// routine periodically expires and flushes templates until the context is cancelled
+func (ts *templateStore) routine(ctx context.Context) {
+	tckr := time.NewTicker(templateStoreFlushInterval)
+	defer tckr.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tckr.C:
+		}
+		if cnt := ts.expire(time.Now()); cnt > 0 {
+			lg.Info("expired stale flow templates", log.KV("count", cnt))
+		}
+		if err := ts.flush(); err != nil {
+			lg.Warn("failed to write template store", log.KV("path", ts.path), log.KVErr(err))
+		}
+	}
+}
+
+// dumpTemplateStore prints the templates in a template store in a human format
+func dumpTemplateStore(pth string) {
+	states, err := decodeTemplateStore(pth)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load template store: %v\n", err)
+		return
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Collector != states[j].Collector {
+			return states[i].Collector < states[j].Collector
+		}
+		if a, b := states[i].Exporter.String(), states[j].Exporter.String(); a != b {
+			return a < b
+		}
+		return states[i].Domain < states[j].Domain
+	})
+	fmt.Printf("%-24s %-40s %-12s %-12s %-8s %s\n", "Collector", "Exporter", "Domain", "Template ID", "Fields", "Last Seen")
+	for _, st := range states {
+		ids := make([]int, 0, len(st.Templates))
+		for id := range st.Templates {
+			ids = append(ids, int(id))
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			t := st.Templates[uint16(id)]
+			fmt.Printf("%-24s %-40s %-12d %-12d %-8d %s\n", st.Collector, st.Exporter, st.Domain, id, len(t.Record.FieldSpecifiers), t.LastSeen.Format(time.RFC3339))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gravwell/ipfix"
+)
+
+var testTemplate = ipfix.TemplateRecord{
+	TemplateID: 256,
+	FieldSpecifiers: []ipfix.TemplateFieldSpecifier{
+		{FieldID: ieSourceIPv4Address, Length: 4},
+		{FieldID: ieDestinationIPv4Address, Length: 4},
+		{FieldID: ieOctetDeltaCount, Length: 8},
+	},
+}
+
+func TestTemplateStorePersist(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), "templates.state")
+	ts, err := newTemplateStore(pth, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := net.ParseIP("10.0.0.1")
+	ts.update("ipfix", exp, 7, []ipfix.TemplateRecord{testTemplate})
+	if err = ts.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	//reopen and make sure the template comes back for the right session only
+	if ts, err = newTemplateStore(pth, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	trs := ts.load("ipfix", getSessionKey(7, exp))
+	if len(trs) != 1 || trs[0].TemplateID != 256 || len(trs[0].FieldSpecifiers) != 3 {
+		t.Fatalf("bad restored templates: %+v", trs)
+	}
+	if trs = ts.load("ipfix", getSessionKey(8, exp)); len(trs) != 0 {
+		t.Fatalf("templates restored for wrong domain: %+v", trs)
+	} else if trs = ts.load("other", getSessionKey(7, exp)); len(trs) != 0 {
+		t.Fatalf("templates restored for wrong collector: %+v", trs)
+	}
+
+	//restored templates must be usable by a fresh session
+	s := ipfix.NewSession()
+	s.LoadTemplateRecords(ts.load("ipfix", getSessionKey(7, exp)))
+	if exported := s.ExportTemplateRecords(); len(exported) != 1 || exported[0].TemplateID != 256 {
+		t.Fatalf("session did not load templates: %+v", exported)
+	}
+
+	//a withdrawal removes the template
+	ts.update("ipfix", exp, 7, []ipfix.TemplateRecord{{TemplateID: 256}})
+	if trs = ts.load("ipfix", getSessionKey(7, exp)); len(trs) != 0 {
+		t.Fatalf("withdrawn template still present: %+v", trs)
+	}
+}
+
+func TestTemplateStoreExpire(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), "templates.state")
+	ts, err := newTemplateStore(pth, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := net.ParseIP("10.0.0.1")
+	ts.update("ipfix", exp, 0, []ipfix.TemplateRecord{testTemplate})
+	if cnt := ts.expire(time.Now()); cnt != 0 {
+		t.Fatalf("fresh template expired")
+	}
+	if cnt := ts.expire(time.Now().Add(2 * time.Hour)); cnt != 1 {
+		t.Fatalf("stale template not expired: %d", cnt)
+	}
+	if trs := ts.load("ipfix", getSessionKey(0, exp)); len(trs) != 0 {
+		t.Fatalf("expired template still present: %+v", trs)
+	}
+}
@@ -57,6 +57,10 @@ type bindConfig struct {
 	igst               *ingest.IngestMuxer
 	lastInfoDump       time.Time
 	sessionDumpEnabled bool
+	splitSamples       bool
+	normalize          bool
+	collector          string
+	templates          *templateStore
 }
 
 type BindHandler interface {
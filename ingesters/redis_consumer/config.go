@@ -0,0 +1,395 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/attach"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultBatchSize     = 512
+	defaultBlockTime     = 5 * time.Second
+	defaultClaimInterval = 30 * time.Second
+	defaultClaimMinIdle  = 5 * time.Minute
+	maxBatchSize         = 4096
+	defaultPort          = 6379
+	defaultGroup         = `gravwell`
+	startAll             = `all`
+	startNew             = `new`
+	tlsScheme            = `rediss`
+	plainScheme          = `redis`
+	tagMatchSep          = `:`
+
+	groupStartAll = `0` // XGROUP CREATE IDs
+	groupStartNew = `$`
+	newEntries    = `>` // XREADGROUP ID for entries never delivered to the group
+	claimStart    = `0-0`
+)
+
+type consumerCfg struct {
+	Server                    string // host:port, redis:// for plaintext and rediss:// for TLS
+	Username                  string // ACL user, leave empty to authenticate with just a Password
+	Password                  string
+	Database                  int
+	Use_TLS                   bool
+	CA_File                   string
+	Client_Cert               string
+	Client_Key                string
+	Insecure_Skip_TLS_Verify  bool
+	Stream                    []string
+	Group                     string // consumer group, defaults to gravwell
+	Consumer_Name             string // name within the group, defaults to <hostname>-<consumer name>
+	Start_Position            string // all or new, only applies when the group is created
+	Batch_Size                int
+	Claim_Interval            string // how often to look for entries abandoned by other consumers
+	Claim_Min_Idle            string // how long an entry must be pending before it is claimed
+	Synchronous               bool   // wait for the indexers to confirm a batch before acking it
+	Default_Tag               string
+	Tag_Match                 []string // stream:tag pairs
+	Ignore_Timestamps         bool     // use the time of arrival
+	Extract_Timestamps        bool     // extract timestamps from the entry instead of using the stream ID
+	Assume_Local_Timezone     bool
+	Timezone_Override         string
+	Timestamp_Format_Override string
+	Source_Override           string
+	Preprocessor              []string
+
+	src           net.IP
+	tagMatch      map[string]string
+	claimInterval time.Duration
+	claimMinIdle  time.Duration
+	tg            *timegrinder.TimeGrinder
+}
+
+type cfgType struct {
+	Global       config.IngestConfig
+	Attach       attach.AttachConfig
+	Consumer     map[string]*consumerCfg
+	Preprocessor processors.ProcessorConfig
+	TimeFormat   config.CustomTimeFormat
+}
+
+func GetConfig(path, overlayPath string) (*cfgType, error) {
+	var c cfgType
+	if err := config.LoadConfigFile(&c, path); err != nil {
+		return nil, err
+	} else if err = config.LoadConfigOverlays(&c, overlayPath); err != nil {
+		return nil, err
+	}
+
+	if err := c.Verify(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *cfgType) Verify() error {
+	//verify the global parameters
+	if err := c.Global.Verify(); err != nil {
+		return err
+	} else if err = c.Attach.Verify(); err != nil {
+		return err
+	} else if err = c.TimeFormat.Validate(); err != nil {
+		return err
+	}
+
+	if len(c.Consumer) == 0 {
+		return errors.New("No Consumers specified")
+	}
+
+	if err := c.Preprocessor.Validate(); err != nil {
+		return err
+	}
+
+	members := map[string]string{}
+	for k, v := range c.Consumer {
+		if err := v.verify(k); err != nil {
+			return fmt.Errorf("Consumer %s %w", k, err)
+		} else if err = c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("Consumer %s preprocessor invalid: %v", k, err)
+		}
+		//two sections reading a stream as the same group member would ack each other's entries
+		addr, _ := v.serverAddr()
+		for _, s := range v.Stream {
+			key := fmt.Sprintf("%s/%d/%s/%s/%s", addr, v.Database, s, v.Group, v.Consumer_Name)
+			if other, ok := members[key]; ok {
+				return fmt.Errorf("Consumers %s and %s read Stream %s with the same Group and Consumer-Name", other, k, s)
+			}
+			members[key] = k
+		}
+		if v.tg != nil {
+			//custom formats must be loaded before the override so it can reference them
+			if err := c.TimeFormat.LoadFormats(v.tg); err != nil {
+				return fmt.Errorf("Consumer %s %w", k, err)
+			} else if v.Timestamp_Format_Override != `` {
+				if err = v.tg.SetFormatOverride(v.Timestamp_Format_Override); err != nil {
+					return fmt.Errorf("Consumer %s invalid Timestamp-Format-Override %q: %w", k, v.Timestamp_Format_Override, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (cc *consumerCfg) verify(name string) (err error) {
+	if cc.Server == `` {
+		return errors.New("missing Server")
+	} else if _, err = cc.serverAddr(); err != nil {
+		return
+	}
+	if cc.Username != `` && cc.Password == `` {
+		return errors.New("Username requires a Password")
+	}
+	if (cc.Client_Cert == ``) != (cc.Client_Key == ``) {
+		return errors.New("Client-Cert and Client-Key must be specified together")
+	}
+	if cc.Database < 0 {
+		return errors.New("Database may not be negative")
+	}
+
+	//consumer group
+	if len(cc.Stream) == 0 {
+		return errors.New("missing Stream")
+	}
+	streams := map[string]bool{}
+	for i, s := range cc.Stream {
+		if s = strings.TrimSpace(s); s == `` {
+			return errors.New("empty Stream")
+		} else if streams[s] {
+			return fmt.Errorf("duplicate Stream %q", s)
+		}
+		streams[s] = true
+		cc.Stream[i] = s
+	}
+	if cc.Group = strings.TrimSpace(cc.Group); cc.Group == `` {
+		cc.Group = defaultGroup
+	}
+	if cc.Consumer_Name = strings.TrimSpace(cc.Consumer_Name); cc.Consumer_Name == `` {
+		//the name must survive restarts so entries we had in flight are delivered back to us
+		var host string
+		if host, err = os.Hostname(); err != nil {
+			return fmt.Errorf("failed to get hostname for the default Consumer-Name: %w", err)
+		}
+		cc.Consumer_Name = host + `-` + name
+	}
+	switch cc.Start_Position = strings.ToLower(strings.TrimSpace(cc.Start_Position)); cc.Start_Position {
+	case ``:
+		cc.Start_Position = startAll
+	case startAll, startNew:
+	default:
+		return fmt.Errorf("invalid Start-Position %q, options are all or new", cc.Start_Position)
+	}
+	if cc.Batch_Size == 0 {
+		cc.Batch_Size = defaultBatchSize
+	} else if cc.Batch_Size < 0 || cc.Batch_Size > maxBatchSize {
+		return fmt.Errorf("Batch-Size %d must be between 1 and %d", cc.Batch_Size, maxBatchSize)
+	}
+	cc.claimInterval = defaultClaimInterval
+	if cc.Claim_Interval != `` {
+		if cc.claimInterval, err = time.ParseDuration(cc.Claim_Interval); err != nil {
+			return fmt.Errorf("invalid Claim-Interval %q: %w", cc.Claim_Interval, err)
+		} else if cc.claimInterval < time.Second {
+			return fmt.Errorf("Claim-Interval %v must be at least 1s", cc.claimInterval)
+		}
+	}
+	cc.claimMinIdle = defaultClaimMinIdle
+	if cc.Claim_Min_Idle != `` {
+		if cc.claimMinIdle, err = time.ParseDuration(cc.Claim_Min_Idle); err != nil {
+			return fmt.Errorf("invalid Claim-Min-Idle %q: %w", cc.Claim_Min_Idle, err)
+		} else if cc.claimMinIdle < time.Second {
+			//anything shorter steals entries live consumers are still working on
+			return fmt.Errorf("Claim-Min-Idle %v must be at least 1s", cc.claimMinIdle)
+		}
+	}
+
+	//tags
+	if cc.Default_Tag == `` {
+		return errors.New("missing Default-Tag")
+	} else if err = ingest.CheckTag(cc.Default_Tag); err != nil {
+		return fmt.Errorf("invalid Default-Tag %q: %w", cc.Default_Tag, err)
+	}
+	cc.tagMatch = map[string]string{}
+	for _, tm := range cc.Tag_Match {
+		//stream names often contain colons, tags cannot
+		idx := strings.LastIndex(tm, tagMatchSep)
+		if idx < 0 {
+			return fmt.Errorf("invalid Tag-Match %q, the format is stream:tag", tm)
+		}
+		stream, tag := strings.TrimSpace(tm[:idx]), strings.TrimSpace(tm[idx+1:])
+		if !streams[stream] {
+			return fmt.Errorf("invalid Tag-Match %q, %q is not a Stream of this consumer", tm, stream)
+		} else if err = ingest.CheckTag(tag); err != nil {
+			return fmt.Errorf("invalid Tag-Match %q: %w", tm, err)
+		} else if _, ok := cc.tagMatch[stream]; ok {
+			return fmt.Errorf("duplicate Tag-Match for stream %q", stream)
+		}
+		cc.tagMatch[stream] = tag
+	}
+
+	//timestamps
+	if cc.Ignore_Timestamps && cc.Extract_Timestamps {
+		return errors.New("Cannot specify Ignore-Timestamps and Extract-Timestamps in the same consumer")
+	} else if cc.Timezone_Override != `` && cc.Assume_Local_Timezone {
+		return errors.New("Cannot specify Assume-Local-Timezone and Timezone-Override in the same consumer")
+	}
+	if cc.Extract_Timestamps {
+		tcfg := timegrinder.Config{
+			EnableLeftMostSeed: true,
+		}
+		if cc.tg, err = timegrinder.NewTimeGrinder(tcfg); err != nil {
+			return fmt.Errorf("failed to generate new timegrinder: %w", err)
+		}
+		if cc.Assume_Local_Timezone {
+			cc.tg.SetLocalTime()
+		}
+		if cc.Timezone_Override != `` {
+			if err = cc.tg.SetTimezone(cc.Timezone_Override); err != nil {
+				return fmt.Errorf("invalid Timezone-Override %q: %w", cc.Timezone_Override, err)
+			}
+		}
+	}
+
+	if cc.Source_Override != `` {
+		if cc.src = net.ParseIP(cc.Source_Override); cc.src == nil {
+			return fmt.Errorf("Invalid Source-Override %q", cc.Source_Override)
+		}
+	}
+	return
+}
+
+// useTLS returns true if the server scheme or the TLS options ask for an encrypted connection
+func (cc *consumerCfg) useTLS() bool {
+	if cc.Use_TLS || cc.CA_File != `` || cc.Client_Cert != `` {
+		return true
+	}
+	scheme, _, ok := strings.Cut(strings.TrimSpace(cc.Server), `://`)
+	return ok && strings.ToLower(scheme) == tlsScheme
+}
+
+// serverAddr strips the scheme from the server and applies the default port
+func (cc *consumerCfg) serverAddr() (addr string, err error) {
+	addr = strings.TrimSpace(cc.Server)
+	if scheme, rest, ok := strings.Cut(addr, `://`); ok {
+		switch strings.ToLower(scheme) {
+		case plainScheme, tlsScheme:
+		default:
+			err = fmt.Errorf("invalid Server %q, unsupported scheme %q", cc.Server, scheme)
+			return
+		}
+		addr = rest
+	}
+	if _, _, lerr := net.SplitHostPort(addr); lerr != nil {
+		addr = net.JoinHostPort(addr, fmt.Sprintf("%d", defaultPort))
+	}
+	if _, _, err = net.SplitHostPort(addr); err != nil {
+		err = fmt.Errorf("invalid Server %q: %w", cc.Server, err)
+	}
+	return
+}
+
+func (cc *consumerCfg) tlsConfig(host string) (*tls.Config, error) {
+	if !cc.useTLS() {
+		return nil, nil
+	}
+	return utils.TLSClientConfig{
+		ServerName:         host,
+		CAFile:             cc.CA_File,
+		CertFile:           cc.Client_Cert,
+		KeyFile:            cc.Client_Key,
+		InsecureSkipVerify: cc.Insecure_Skip_TLS_Verify,
+	}.Load()
+}
+
+// clientConfig builds the Redis client options, TLS files are loaded here
+func (cc *consumerCfg) clientConfig(name string) (o *redis.Options, err error) {
+	o = &redis.Options{
+		Username:   cc.Username,
+		Password:   cc.Password,
+		DB:         cc.Database,
+		ClientName: `gravwell-` + strings.Join(strings.Fields(name), `_`),
+		//a single consumer only ever has one command in flight
+		PoolSize: 1,
+	}
+	var host string
+	if o.Addr, err = cc.serverAddr(); err != nil {
+		return nil, err
+	} else if host, _, err = net.SplitHostPort(o.Addr); err != nil {
+		return nil, err
+	} else if o.TLSConfig, err = cc.tlsConfig(host); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// groupStart returns the XGROUP CREATE ID for the Start-Position
+func (cc *consumerCfg) groupStart() string {
+	if cc.Start_Position == startNew {
+		return groupStartNew
+	}
+	return groupStartAll
+}
+
+// tags returns every tag this consumer may produce
+func (cc *consumerCfg) tags() (tags []string) {
+	tags = append(tags, cc.Default_Tag)
+	for _, t := range cc.tagMatch {
+		tags = append(tags, t)
+	}
+	return
+}
+
+// tagFor returns the tag name for a stream
+func (cc *consumerCfg) tagFor(stream string) string {
+	if t, ok := cc.tagMatch[stream]; ok {
+		return t
+	}
+	return cc.Default_Tag
+}
+
+func (c *cfgType) Tags() ([]string, error) {
+	var tags []string
+	tagMp := map[string]bool{}
+	for _, v := range c.Consumer {
+		for _, t := range v.tags() {
+			if !tagMp[t] {
+				tags = append(tags, t)
+				tagMp[t] = true
+			}
+		}
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("No tags specified")
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func (c *cfgType) IngestBaseConfig() config.IngestConfig {
+	return c.Global
+}
+
+func (c *cfgType) AttachConfig() attach.AttachConfig {
+	return c.Attach
+}
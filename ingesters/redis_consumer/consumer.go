@@ -0,0 +1,281 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	streamEVName = `redis_stream`
+	idEVName     = `redis_id`
+)
+
+type muxer interface {
+	GetTag(string) (entry.EntryTag, error)
+	SyncContext(context.Context, time.Duration) error
+}
+
+type consumer struct {
+	name   string
+	cfg    *consumerCfg
+	opts   *redis.Options
+	tags   map[string]entry.EntryTag
+	newIDs []string
+	block  time.Duration
+	igst   muxer
+	proc   *processors.ProcessorSet
+	lg     *log.Logger
+	ctx    context.Context
+}
+
+func newConsumer(ctx context.Context, name string, cfg *consumerCfg, igst muxer, proc *processors.ProcessorSet, lg *log.Logger) (c *consumer, err error) {
+	c = &consumer{
+		name:  name,
+		cfg:   cfg,
+		tags:  map[string]entry.EntryTag{},
+		block: defaultBlockTime,
+		igst:  igst,
+		proc:  proc,
+		lg:    lg,
+		ctx:   ctx,
+	}
+	for _, t := range cfg.tags() {
+		if c.tags[t], err = igst.GetTag(t); err != nil {
+			return nil, err
+		}
+	}
+	if c.opts, err = cfg.clientConfig(name); err != nil {
+		return nil, err
+	}
+	for range cfg.Stream {
+		c.newIDs = append(c.newIDs, newEntries)
+	}
+	//wake up often enough to claim on schedule
+	if c.block > cfg.claimInterval {
+		c.block = cfg.claimInterval
+	}
+	return
+}
+
+// run keeps the consumer connected until the context is cancelled, backing off between failures
+func (c *consumer) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	utils.RunWithRetry(c.ctx, c.consume, func(err error, retry time.Duration) {
+		c.lg.Warn("Redis consumer disconnected", log.KV("consumer", c.name), log.KV("server", c.cfg.Server),
+			log.KV("retry", retry), log.KVErr(err))
+	})
+}
+
+// consume connects, makes sure the group exists on every stream, finishes anything left pending
+// from a previous connection, and then reads new entries until something fails
+func (c *consumer) consume() (err error) {
+	rdb := redis.NewClient(c.opts)
+	defer rdb.Close()
+	//a blocked read only honors deadlines, closing the client is what interrupts it
+	stop := context.AfterFunc(c.ctx, func() { rdb.Close() })
+	defer stop()
+	if err = rdb.Ping(c.ctx).Err(); err != nil {
+		return
+	}
+	for _, s := range c.cfg.Stream {
+		if err = rdb.XGroupCreateMkStream(c.ctx, s, c.cfg.Group, c.cfg.groupStart()).Err(); err == nil {
+			c.lg.Info("created consumer group", log.KV("consumer", c.name), log.KV("stream", s), log.KV("group", c.cfg.Group))
+		} else if !redis.HasErrorPrefix(err, `BUSYGROUP`) {
+			return
+		}
+		//an existing group is left untouched
+		err = nil
+	}
+	c.lg.Info("Redis consumer connected", log.KV("consumer", c.name), log.KV("streams", c.cfg.Stream),
+		log.KV("group", c.cfg.Group), log.KV("member", c.cfg.Consumer_Name))
+	if err = c.replay(rdb); err != nil {
+		return
+	}
+	var lastClaim time.Time
+	for {
+		if time.Since(lastClaim) >= c.cfg.claimInterval {
+			if err = c.claim(rdb); err != nil {
+				return
+			}
+			lastClaim = time.Now()
+		}
+		var streams []redis.XStream
+		if streams, err = c.read(rdb, c.cfg.Stream, c.newIDs, c.block); err != nil {
+			return
+		}
+		for _, s := range streams {
+			if err = c.flush(rdb, s.Stream, s.Messages); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// read issues an XREADGROUP for the streams, a negative block returns right away.
+// Nothing arriving before the block expires is not an error.
+func (c *consumer) read(rdb redis.Cmdable, streams, ids []string, block time.Duration) (r []redis.XStream, err error) {
+	args := &redis.XReadGroupArgs{
+		Group:    c.cfg.Group,
+		Consumer: c.cfg.Consumer_Name,
+		Streams:  append(append(make([]string, 0, 2*len(streams)), streams...), ids...),
+		Count:    int64(c.cfg.Batch_Size),
+		Block:    block,
+	}
+	if r, err = rdb.XReadGroup(c.ctx, args).Result(); errors.Is(err, redis.Nil) {
+		err = nil
+	}
+	return
+}
+
+// replay re-reads entries that were delivered to this consumer but never acked, such as those in
+// flight when the ingester last stopped or when a batch failed
+func (c *consumer) replay(rdb redis.Cmdable) (err error) {
+	for _, stream := range c.cfg.Stream {
+		after := `0`
+		for {
+			var streams []redis.XStream
+			if streams, err = c.read(rdb, []string{stream}, []string{after}, -1); err != nil {
+				return
+			} else if len(streams) == 0 || len(streams[0].Messages) == 0 {
+				break
+			}
+			msgs := streams[0].Messages
+			if err = c.flush(rdb, stream, msgs); err != nil {
+				return
+			}
+			after = msgs[len(msgs)-1].ID
+		}
+	}
+	return
+}
+
+// claim takes over entries that other consumers in the group have left pending for longer than
+// Claim-Min-Idle, such as those held by a consumer that died.  Entries deleted while pending are
+// dropped from the group by the server.
+func (c *consumer) claim(rdb redis.Cmdable) (err error) {
+	for _, stream := range c.cfg.Stream {
+		args := &redis.XAutoClaimArgs{
+			Stream:   stream,
+			Group:    c.cfg.Group,
+			Consumer: c.cfg.Consumer_Name,
+			MinIdle:  c.cfg.claimMinIdle,
+			Start:    claimStart,
+			Count:    int64(c.cfg.Batch_Size),
+		}
+		for {
+			var msgs []redis.XMessage
+			if msgs, args.Start, err = rdb.XAutoClaim(c.ctx, args).Result(); err != nil {
+				return
+			}
+			if len(msgs) > 0 {
+				c.lg.Info("claimed abandoned entries", log.KV("consumer", c.name), log.KV("stream", stream), log.KV("count", len(msgs)))
+				if err = c.flush(rdb, stream, msgs); err != nil {
+					return
+				}
+			}
+			if args.Start == claimStart {
+				break
+			}
+		}
+	}
+	return
+}
+
+// flush hands entries from a stream to the muxer and acks the ones it accepted, if Synchronous is
+// set the acks wait until the indexers have the entries.  Unacked entries stay pending and are
+// replayed once we reconnect.
+func (c *consumer) flush(rdb redis.Cmdable, stream string, msgs []redis.XMessage) (err error) {
+	ids := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		//entries deleted while pending come back without fields
+		if m.Values != nil {
+			if err = c.proc.ProcessContext(c.buildEntry(stream, m), c.ctx); err != nil {
+				break
+			}
+		}
+		//deleted entries have nothing left to ingest, acking them clears the pending list
+		ids = append(ids, m.ID)
+	}
+	if c.cfg.Synchronous {
+		if err == nil {
+			err = c.igst.SyncContext(c.ctx, 0)
+		}
+		if err != nil {
+			return
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if lerr := rdb.XAck(c.ctx, stream, c.cfg.Group, ids...).Err(); err == nil {
+		err = lerr
+	}
+	return
+}
+
+func (c *consumer) buildEntry(stream string, m redis.XMessage) *entry.Entry {
+	data := entryJSON(m.Values)
+	ent := &entry.Entry{
+		TS:   entry.Now(),
+		SRC:  c.cfg.src,
+		Tag:  c.tags[c.cfg.tagFor(stream)],
+		Data: data,
+	}
+	if !c.cfg.Ignore_Timestamps {
+		if ts, err := idTime(m.ID); err == nil {
+			ent.TS = entry.FromStandard(ts)
+		}
+		if c.cfg.tg != nil {
+			if ts, ok, err := c.cfg.tg.Extract(data); err != nil {
+				c.lg.Warn("catastrophic timegrinder error", log.KV("consumer", c.name), log.KVErr(err))
+			} else if ok {
+				ent.TS = entry.FromStandard(ts)
+			}
+			// if not ok, we'll just use the stream ID timestamp
+		}
+	}
+	ent.AddEnumeratedValueEx(streamEVName, stream)
+	ent.AddEnumeratedValueEx(idEVName, m.ID)
+	return ent
+}
+
+// idTime returns the time in the millisecond part of a stream ID
+func idTime(id string) (time.Time, error) {
+	ms, _, _ := strings.Cut(id, `-`)
+	v, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(v), nil
+}
+
+// entryJSON encodes the fields of an entry as a JSON object, the client hands fields back as a
+// map so the keys come out sorted rather than in the order they were added
+func entryJSON(fields map[string]interface{}) []byte {
+	var bb bytes.Buffer
+	enc := json.NewEncoder(&bb)
+	enc.SetEscapeHTML(false)
+	//field values are always strings, which always encode
+	enc.Encode(fields)
+	return bytes.TrimSuffix(bb.Bytes(), []byte("\n"))
+}
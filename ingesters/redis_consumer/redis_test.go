@@ -0,0 +1,478 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/ingesters/utils/ingesttest"
+	"github.com/redis/go-redis/v9"
+)
+
+func loadTestConfig(t *testing.T, body string) (*cfgType, error) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), `redis.conf`)
+	if err := os.WriteFile(p, []byte(body), 0640); err != nil {
+		t.Fatal(err)
+	}
+	return GetConfig(p, ``)
+}
+
+const testGlobal = `
+[Global]
+Ingest-Secret = IngestSecrets
+Cleartext-Backend-Target=127.0.0.1:4023
+Log-Level=INFO
+`
+
+func TestConfig(t *testing.T) {
+	cfg, err := loadTestConfig(t, testGlobal+`
+[Consumer "audit"]
+	Server="rediss://redis.example.com"
+	Username=gravwell
+	Password=secret
+	Database=2
+	Stream="audit:auth"
+	Stream="audit:billing"
+	Start-Position=New
+	Claim-Interval=1m
+	Claim-Min-Idle=10m
+	Batch-Size=100
+	Default-Tag=audit
+	Tag-Match="audit:auth:auth"
+	Extract-Timestamps=true
+
+[Consumer "events"]
+	Server=127.0.0.1
+	Stream=events
+	Group=ingest
+	Consumer-Name=ingest1
+	Default-Tag=events
+	Synchronous=true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := cfg.Tags(); err != nil {
+		t.Fatal(err)
+	} else if len(tags) != 3 {
+		t.Fatalf("bad tags: %v", tags)
+	}
+	audit := cfg.Consumer[`audit`]
+	rc, err := audit.clientConfig(`audit`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.Addr != `redis.example.com:6379` || rc.TLSConfig == nil || rc.TLSConfig.ServerName != `redis.example.com` || rc.Username != `gravwell` || rc.DB != 2 {
+		t.Fatalf("bad client config: %+v", rc)
+	}
+	host, _ := os.Hostname()
+	if audit.Group != defaultGroup || audit.Consumer_Name != host+`-audit` || audit.groupStart() != groupStartNew {
+		t.Fatalf("bad group settings: %+v", audit)
+	} else if audit.claimInterval != time.Minute || audit.claimMinIdle != 10*time.Minute || audit.tg == nil {
+		t.Fatalf("bad consumer settings: %+v", audit)
+	}
+	if audit.tagFor(`audit:auth`) != `auth` || audit.tagFor(`audit:billing`) != `audit` {
+		t.Fatalf("bad tag mapping: %v", audit.tagMatch)
+	}
+	events := cfg.Consumer[`events`]
+	if rc, err = events.clientConfig(`events`); err != nil {
+		t.Fatal(err)
+	} else if rc.TLSConfig != nil || rc.Addr != `127.0.0.1:6379` || rc.ClientName != `gravwell-events` {
+		t.Fatalf("bad client config: %+v", rc)
+	} else if events.Batch_Size != defaultBatchSize || events.claimMinIdle != defaultClaimMinIdle || events.groupStart() != groupStartAll {
+		t.Fatalf("bad defaults: %+v", events)
+	}
+
+	bad := []string{
+		`Stream=events`, //no server
+		`Server=http://127.0.0.1
+		Stream=events`, //bad scheme
+		`Server=127.0.0.1`, //no stream
+		`Server=127.0.0.1
+		Stream=events
+		Stream=events`, //duplicate stream
+		`Server=127.0.0.1
+		Stream=events
+		Start-Position=oldest`, //bad position
+		`Server=127.0.0.1
+		Stream=events
+		Tag-Match="other:tag"`, //tag for a stream we do not read
+		`Server=127.0.0.1
+		Stream=events
+		Tag-Match="events"`, //no tag
+		`Server=127.0.0.1
+		Stream=events
+		Claim-Min-Idle=10ms`, //steals live entries
+		`Server=127.0.0.1
+		Stream=events
+		Username=gravwell`, //no password
+		`Server=127.0.0.1
+		Stream=events
+		Ignore-Timestamps=true
+		Extract-Timestamps=true`, //conflicting timestamps
+	}
+	for i, b := range bad {
+		if _, err := loadTestConfig(t, testGlobal+"[Consumer \"bad\"]\n\tDefault-Tag=redis\n\t"+b+"\n"); err == nil {
+			t.Fatalf("failed to catch bad config %d", i)
+		}
+	}
+	//two sections as the same group member
+	if _, err = loadTestConfig(t, testGlobal+`
+[Consumer "a"]
+	Server=127.0.0.1
+	Stream=events
+	Consumer-Name=shared
+	Default-Tag=a
+[Consumer "b"]
+	Server="redis://127.0.0.1:6379"
+	Stream=events
+	Consumer-Name=shared
+	Default-Tag=b
+`); err == nil {
+		t.Fatal("failed to catch shared consumer name")
+	}
+}
+
+type testServer struct {
+	*miniredis.Miniredis
+	rdb *redis.Client
+	tw  *ingesttest.Writer
+	mux *ingesttest.Muxer
+}
+
+// newTestConsumer starts a miniredis server and points a consumer at it, the groups are created
+// from the start of each stream
+func newTestConsumer(t *testing.T, ctx context.Context, body string) (*consumer, *testServer) {
+	t.Helper()
+	cfg, err := loadTestConfig(t, testGlobal+body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := &testServer{
+		Miniredis: miniredis.RunT(t),
+		tw:        &ingesttest.Writer{},
+		mux:       &ingesttest.Muxer{},
+	}
+	c, err := newConsumer(ctx, `test`, cfg.Consumer[`test`], ts.mux, processors.NewProcessorSet(ts.tw), log.NewDiscardLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.opts.Addr = ts.Addr()
+	ts.rdb = redis.NewClient(c.opts)
+	t.Cleanup(func() { ts.rdb.Close() })
+	return c, ts
+}
+
+func (ts *testServer) createGroups(t *testing.T, c *consumer) {
+	t.Helper()
+	for _, s := range c.cfg.Stream {
+		if err := ts.rdb.XGroupCreateMkStream(context.Background(), s, c.cfg.Group, groupStartAll).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// add appends an entry with an ID at the given time
+func (ts *testServer) add(t *testing.T, stream string, tm time.Time, seq int, fields ...string) string {
+	t.Helper()
+	id := strconv.FormatInt(tm.UnixMilli(), 10) + `-` + strconv.Itoa(seq)
+	if err := ts.rdb.XAdd(context.Background(), &redis.XAddArgs{Stream: stream, ID: id, Values: fields}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// deliver reads every new entry on a stream as member, leaving them pending
+func (ts *testServer) deliver(t *testing.T, c *consumer, stream, member string) []redis.XMessage {
+	t.Helper()
+	streams, err := ts.rdb.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    c.cfg.Group,
+		Consumer: member,
+		Streams:  []string{stream, newEntries},
+		Block:    -1,
+	}).Result()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(streams) != 1 {
+		t.Fatalf("bad streams %v", streams)
+	}
+	return streams[0].Messages
+}
+
+// pending returns the IDs left pending on a stream
+func (ts *testServer) pending(t *testing.T, c *consumer, stream string) (ids []string) {
+	t.Helper()
+	ps, err := ts.rdb.XPendingExt(context.Background(), &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  c.cfg.Group,
+		Start:  `-`,
+		End:    `+`,
+		Count:  100,
+	}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range ps {
+		ids = append(ids, p.ID)
+	}
+	return
+}
+
+func TestFlush(t *testing.T) {
+	c, ts := newTestConsumer(t, context.Background(), `
+[Consumer "test"]
+	Server=127.0.0.1
+	Stream="audit:auth"
+	Stream=other
+	Default-Tag=audit
+	Tag-Match="audit:auth:auth"
+	Source-Override=10.0.0.1
+`)
+	ts.createGroups(t, c)
+	tm := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	ts.add(t, `audit:auth`, tm, 0, `user`, `alice`, `action`, `<login>`, `quote`, `"x"`)
+	ts.add(t, `audit:auth`, tm, 1, `user`, `mallory`)
+	ts.add(t, `audit:auth`, tm, 2, `user`, `bob`)
+	msgs := ts.deliver(t, c, `audit:auth`, c.cfg.Consumer_Name)
+	//redis returns entries deleted while pending without fields, miniredis just hides them
+	msgs[1].Values = nil
+	if err := c.flush(ts.rdb, `audit:auth`, msgs); err != nil {
+		t.Fatal(err)
+	}
+	if p := ts.pending(t, c, `audit:auth`); len(p) != 0 {
+		t.Fatalf("entries left pending %v", p)
+	} else if ts.tw.Count() != 2 {
+		t.Fatalf("bad entry count %d", ts.tw.Count())
+	} else if ts.mux.Syncs() != 0 {
+		t.Fatal("synced without Synchronous")
+	}
+	ents := ts.tw.Take()
+	ent := ents[0]
+	if string(ent.Data) != `{"action":"<login>","quote":"\"x\"","user":"alice"}` {
+		t.Fatalf("bad data %s", ent.Data)
+	} else if ent.Tag != ts.mux.Tag(`auth`) {
+		t.Fatalf("bad tag %v", ent.Tag)
+	} else if !ent.TS.StandardTime().Equal(tm) {
+		t.Fatalf("did not use the stream ID timestamp: %v", ent.TS)
+	} else if ent.SRC.String() != `10.0.0.1` {
+		t.Fatalf("bad source %v", ent.SRC)
+	} else if ev, ok := ent.GetEnumeratedValue(streamEVName); !ok || ev != `audit:auth` {
+		t.Fatalf("bad stream EV %v %v", ev, ok)
+	} else if ev, ok = ent.GetEnumeratedValue(idEVName); !ok || ev != msgs[0].ID {
+		t.Fatalf("bad ID EV %v %v", ev, ok)
+	} else if string(ents[1].Data) != `{"user":"bob"}` {
+		t.Fatalf("bad data %s", ents[1].Data)
+	}
+	ts.add(t, `other`, tm, 0, `user`, `carol`)
+	if err := c.flush(ts.rdb, `other`, ts.deliver(t, c, `other`, c.cfg.Consumer_Name)); err != nil {
+		t.Fatal(err)
+	} else if ents = ts.tw.Take(); len(ents) != 1 || ents[0].Tag != ts.mux.Tag(`audit`) {
+		t.Fatalf("bad default tag %v", ents)
+	}
+
+	//only the entries the muxer accepted are acked
+	ts.add(t, `audit:auth`, tm, 3, `user`, `dave`)
+	ts.add(t, `audit:auth`, tm, 4, `user`, `erin`)
+	ts.add(t, `audit:auth`, tm, 5, `user`, `frank`)
+	msgs = ts.deliver(t, c, `audit:auth`, c.cfg.Consumer_Name)
+	msgs[1].Values = nil
+	ts.tw.RefuseAfter(1)
+	if err := c.flush(ts.rdb, `audit:auth`, msgs); !errors.Is(err, ingesttest.ErrRefused) {
+		t.Fatalf("bad error %v", err)
+	} else if p := ts.pending(t, c, `audit:auth`); len(p) != 1 || p[0] != msgs[2].ID {
+		t.Fatalf("bad pending after refusal %v", p)
+	}
+}
+
+func TestFlushSynchronous(t *testing.T) {
+	c, ts := newTestConsumer(t, context.Background(), `
+[Consumer "test"]
+	Server=127.0.0.1
+	Stream=events
+	Default-Tag=events
+	Synchronous=true
+	Ignore-Timestamps=true
+`)
+	ts.createGroups(t, c)
+	tm := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	ts.add(t, `events`, tm, 0, `a`, `1`)
+	ts.add(t, `events`, tm, 1, `a`, `2`)
+	msgs := ts.deliver(t, c, `events`, c.cfg.Consumer_Name)
+	if err := c.flush(ts.rdb, `events`, msgs); err != nil {
+		t.Fatal(err)
+	} else if p := ts.pending(t, c, `events`); len(p) != 0 || ts.mux.Syncs() != 1 {
+		t.Fatalf("bad settlement %v syncs %d", p, ts.mux.Syncs())
+	} else if ts.tw.Take()[0].TS.StandardTime().Equal(tm) {
+		t.Fatal("used the stream ID timestamp with Ignore-Timestamps")
+	}
+
+	//nothing is acked until the indexers have the batch
+	ts.add(t, `events`, tm, 2, `a`, `3`)
+	ts.add(t, `events`, tm, 3, `a`, `4`)
+	msgs = ts.deliver(t, c, `events`, c.cfg.Consumer_Name)
+	ts.mux.FailSyncs(true)
+	if err := c.flush(ts.rdb, `events`, msgs); !errors.Is(err, ingesttest.ErrSyncFailed) {
+		t.Fatalf("bad error %v", err)
+	} else if p := ts.pending(t, c, `events`); len(p) != 2 {
+		t.Fatalf("acked after sync failure %v", p)
+	}
+	ts.mux.FailSyncs(false)
+	ts.tw.RefuseAfter(ts.tw.Count() + 1)
+	if err := c.flush(ts.rdb, `events`, msgs); !errors.Is(err, ingesttest.ErrRefused) {
+		t.Fatalf("bad error %v", err)
+	} else if p := ts.pending(t, c, `events`); len(p) != 2 {
+		t.Fatalf("acked after refusal %v", p)
+	}
+}
+
+func TestReplayAndClaim(t *testing.T) {
+	c, ts := newTestConsumer(t, context.Background(), `
+[Consumer "test"]
+	Server=127.0.0.1
+	Stream=a
+	Stream=b
+	Batch-Size=2
+	Default-Tag=events
+`)
+	ts.createGroups(t, c)
+	tm := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		ts.add(t, `a`, tm, i, `n`, `a`+strconv.Itoa(i))
+	}
+	ts.add(t, `b`, tm, 0, `n`, `b0`)
+	ts.deliver(t, c, `a`, c.cfg.Consumer_Name)
+	ts.deliver(t, c, `b`, c.cfg.Consumer_Name)
+	if err := c.replay(ts.rdb); err != nil {
+		t.Fatal(err)
+	} else if pa, pb := ts.pending(t, c, `a`), ts.pending(t, c, `b`); len(pa) != 0 || len(pb) != 0 {
+		t.Fatalf("history left pending %v %v", pa, pb)
+	} else if got := testData(ts.tw); got != `a0,a1,a2,a3,a4,b0` {
+		t.Fatalf("bad replay %s", got)
+	}
+
+	//entries held by another member are only claimed once they have been idle long enough
+	for i := 0; i < 3; i++ {
+		ts.add(t, `b`, tm, 10+i, `n`, `c`+strconv.Itoa(i))
+	}
+	ts.deliver(t, c, `b`, `dead`)
+	if err := c.claim(ts.rdb); err != nil {
+		t.Fatal(err)
+	} else if got := testData(ts.tw); got != `` {
+		t.Fatalf("claimed live entries %s", got)
+	}
+	ts.SetTime(time.Now().Add(2 * c.cfg.claimMinIdle))
+	if err := c.claim(ts.rdb); err != nil {
+		t.Fatal(err)
+	} else if got := testData(ts.tw); got != `c0,c1,c2` {
+		t.Fatalf("bad claims %s", got)
+	} else if p := ts.pending(t, c, `b`); len(p) != 0 {
+		t.Fatalf("claimed entries not acked %v", p)
+	}
+
+	//a failure stops the replay, leaving the rest pending for the next connection
+	ts.add(t, `a`, tm, 10, `n`, `x`)
+	ts.add(t, `a`, tm, 11, `n`, `y`)
+	ts.deliver(t, c, `a`, c.cfg.Consumer_Name)
+	ts.tw.RefuseAfter(1)
+	if err := c.replay(ts.rdb); !errors.Is(err, ingesttest.ErrRefused) {
+		t.Fatalf("bad error %v", err)
+	} else if p := ts.pending(t, c, `a`); len(p) != 1 {
+		t.Fatalf("bad pending after failure %v", p)
+	}
+}
+
+func TestConsume(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, ts := newTestConsumer(t, ctx, `
+[Consumer "test"]
+	Server=127.0.0.1
+	Stream=events
+	Default-Tag=events
+	Synchronous=true
+`)
+	//an entry left pending by a previous run is replayed, the group exists already
+	ts.createGroups(t, c)
+	tm := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	ts.add(t, `events`, tm, 0, `n`, `old`)
+	ts.deliver(t, c, `events`, c.cfg.Consumer_Name)
+	//new entries wake a blocked read, so a long block only matters on shutdown
+	c.block = time.Minute
+	done := make(chan error, 1)
+	go func() { done <- c.consume() }()
+	ts.add(t, `events`, tm, 1, `n`, `new`)
+	for deadline := time.Now().Add(5 * time.Second); ts.tw.Count() < 2; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out with %d entries", ts.tw.Count())
+		}
+	}
+	if got := testData(ts.tw); got != `old,new` {
+		t.Fatalf("bad entries %s", got)
+	} else if p := ts.pending(t, c, `events`); len(p) != 0 {
+		t.Fatalf("entries left pending %v", p)
+	}
+
+	//cancelling interrupts the blocked read
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("consume did not return after cancel")
+	}
+}
+
+func TestConsumeCreatesGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, ts := newTestConsumer(t, ctx, `
+[Consumer "test"]
+	Server=127.0.0.1
+	Stream=events
+	Start-Position=new
+	Default-Tag=events
+`)
+	tm := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	c.block = 10 * time.Millisecond
+	done := make(chan error, 1)
+	go func() { done <- c.consume() }()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		if groups, err := ts.rdb.XInfoGroups(context.Background(), `events`).Result(); err == nil && len(groups) == 1 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("group was not created")
+		}
+	}
+	ts.add(t, `events`, tm, 0, `n`, `x`)
+	for deadline := time.Now().Add(5 * time.Second); ts.tw.Count() < 1; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the entry")
+		}
+	}
+	cancel()
+	<-done
+}
+
+func testData(tw *ingesttest.Writer) string {
+	var r []string
+	for _, ent := range tw.Take() {
+		r = append(r, strings.TrimSuffix(strings.TrimPrefix(string(ent.Data), `{"n":"`), `"}`))
+	}
+	return strings.Join(r, `,`)
+}
@@ -0,0 +1,42 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// httpClient returns the client requests are made with, OAuth2 clients fetch and refresh their
+// own tokens
+func (ac *apiCfg) httpClient(ctx context.Context, base *http.Client) *http.Client {
+	if ac.Auth_Type != authOAuth2 {
+		return base
+	}
+	cc := clientcredentials.Config{
+		ClientID:     ac.Client_ID,
+		ClientSecret: ac.Client_Secret,
+		TokenURL:     ac.Token_URL,
+		Scopes:       ac.Scope,
+	}
+	return cc.Client(context.WithValue(ctx, oauth2.HTTPClient, base))
+}
+
+// authorize adds static credentials to a request
+func (ac *apiCfg) authorize(req *http.Request) {
+	switch ac.Auth_Type {
+	case authBearer:
+		req.Header.Set(`Authorization`, ac.Token_Prefix+` `+ac.Token)
+	case authBasic:
+		req.SetBasicAuth(ac.Username, ac.Password)
+	}
+}
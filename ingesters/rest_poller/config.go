@@ -0,0 +1,374 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest"
+	"github.com/gravwell/gravwell/v3/ingest/attach"
+	"github.com/gravwell/gravwell/v3/ingest/config"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+const (
+	defaultStateLoc     = `/opt/gravwell/etc/rest_poller.state`
+	defaultPollInterval = time.Minute
+	defaultTokenPrefix  = `Bearer`
+	minPollInterval     = time.Second
+	maxPageSize         = 100000
+
+	authNone   = `none`
+	authBearer = `bearer`
+	authBasic  = `basic`
+	authOAuth2 = `oauth2`
+
+	pageNone   = `none`
+	pageCursor = `cursor`
+	pageLink   = `link`
+	pageOffset = `offset`
+
+	sinceUnix      = `unix`
+	sinceUnixMilli = `unixmilli`
+)
+
+type global struct {
+	config.IngestConfig
+	State_Store_Location string
+}
+
+type apiCfg struct {
+	URL                       string
+	Method                    string   // GET or POST
+	Body                      string   // request body sent with POST
+	Header                    []string // Name: value
+	Auth_Type                 string   // none, bearer, basic, or oauth2
+	Token                     string   `json:"-"`
+	Token_Prefix              string   // Authorization scheme sent with the Token, defaults to Bearer
+	Username                  string
+	Password                  string `json:"-"`
+	Client_ID                 string
+	Client_Secret             string `json:"-"`
+	Token_URL                 string
+	Scope                     []string
+	Pagination                string // none, cursor, link, or offset
+	Cursor_Path               string // JSONPath to the next page cursor in each response
+	Cursor_Parameter          string // query parameter the cursor is sent in
+	Offset_Parameter          string
+	Limit_Parameter           string // query parameter the Page-Size is sent in
+	Page_Size                 int
+	Records_Path              string // JSONPath to the records array, the response itself if empty
+	Timestamp_Field           string // JSONPath within each record, enables checkpointing
+	Since_Parameter           string // query parameter the checkpoint is sent in
+	Since_Format              string // Go time layout, unix, or unixmilli, defaults to RFC3339
+	Initial_Lookback          string // how far back to start when there is no checkpoint
+	Poll_Interval             string
+	Requests_Per_Minute       int
+	Tag_Name                  string
+	Ignore_Timestamps         bool // use the time of arrival, the checkpoint still uses Timestamp-Field
+	Assume_Local_Timezone     bool
+	Timezone_Override         string
+	Timestamp_Format_Override string
+	Source_Override           string
+	Preprocessor              []string
+
+	u            *url.URL
+	headers      http.Header
+	recordsPath  []string
+	cursorPath   []string
+	tsPath       []string
+	pollInterval time.Duration
+	lookback     time.Duration
+	src          net.IP
+	tg           *timegrinder.TimeGrinder
+}
+
+type cfgType struct {
+	Global       global
+	Attach       attach.AttachConfig
+	API          map[string]*apiCfg
+	Preprocessor processors.ProcessorConfig
+	TimeFormat   config.CustomTimeFormat
+}
+
+func GetConfig(path, overlayPath string) (*cfgType, error) {
+	var c cfgType
+	if err := config.LoadConfigFile(&c, path); err != nil {
+		return nil, err
+	} else if err = config.LoadConfigOverlays(&c, overlayPath); err != nil {
+		return nil, err
+	}
+
+	if err := c.Verify(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (c *cfgType) Verify() error {
+	//verify the global parameters
+	if err := c.Global.Verify(); err != nil {
+		return err
+	} else if err = c.Attach.Verify(); err != nil {
+		return err
+	} else if err = c.TimeFormat.Validate(); err != nil {
+		return err
+	}
+	if c.Global.State_Store_Location == `` {
+		c.Global.State_Store_Location = defaultStateLoc
+	}
+
+	if len(c.API) == 0 {
+		return errors.New("No APIs specified")
+	}
+
+	if err := c.Preprocessor.Validate(); err != nil {
+		return err
+	}
+
+	for k, v := range c.API {
+		if err := v.verify(); err != nil {
+			return fmt.Errorf("API %s %w", k, err)
+		} else if err = c.Preprocessor.CheckProcessors(v.Preprocessor); err != nil {
+			return fmt.Errorf("API %s preprocessor invalid: %v", k, err)
+		}
+		if v.tg != nil {
+			//custom formats must be loaded before the override so it can reference them
+			if err := c.TimeFormat.LoadFormats(v.tg); err != nil {
+				return fmt.Errorf("API %s %w", k, err)
+			} else if v.Timestamp_Format_Override != `` {
+				if err = v.tg.SetFormatOverride(v.Timestamp_Format_Override); err != nil {
+					return fmt.Errorf("API %s invalid Timestamp-Format-Override %q: %w", k, v.Timestamp_Format_Override, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (ac *apiCfg) verify() (err error) {
+	if ac.URL == `` {
+		return errors.New("missing URL")
+	} else if ac.u, err = url.Parse(ac.URL); err != nil {
+		return fmt.Errorf("invalid URL %q: %w", ac.URL, err)
+	} else if (ac.u.Scheme != `http` && ac.u.Scheme != `https`) || ac.u.Host == `` {
+		return fmt.Errorf("invalid URL %q, must be an http or https URL", ac.URL)
+	}
+	switch ac.Method = strings.ToUpper(strings.TrimSpace(ac.Method)); ac.Method {
+	case ``:
+		ac.Method = http.MethodGet
+	case http.MethodGet, http.MethodPost:
+	default:
+		return fmt.Errorf("invalid Method %q, options are GET or POST", ac.Method)
+	}
+	if ac.Body != `` && ac.Method != http.MethodPost {
+		return errors.New("Body requires Method=POST")
+	}
+	ac.headers = http.Header{}
+	for _, h := range ac.Header {
+		name, val, ok := strings.Cut(h, `:`)
+		if name = strings.TrimSpace(name); !ok || name == `` {
+			return fmt.Errorf("invalid Header %q, the format is Name: value", h)
+		}
+		ac.headers.Add(name, strings.TrimSpace(val))
+	}
+	if err = ac.verifyAuth(); err != nil {
+		return
+	}
+	if err = ac.verifyPagination(); err != nil {
+		return
+	}
+
+	if ac.recordsPath, err = parsePath(ac.Records_Path); err != nil {
+		return fmt.Errorf("invalid Records-Path %q: %w", ac.Records_Path, err)
+	}
+	if ac.Timestamp_Field != `` {
+		if ac.tsPath, err = parsePath(ac.Timestamp_Field); err != nil {
+			return fmt.Errorf("invalid Timestamp-Field %q: %w", ac.Timestamp_Field, err)
+		} else if len(ac.tsPath) == 0 {
+			return errors.New("Timestamp-Field may not refer to the entire record")
+		}
+	}
+	if ac.Since_Parameter != `` && ac.tsPath == nil {
+		return errors.New("Since-Parameter requires a Timestamp-Field to checkpoint")
+	}
+	switch ac.Since_Format {
+	case ``:
+		ac.Since_Format = time.RFC3339
+	case sinceUnix, sinceUnixMilli:
+	default:
+		if !strings.ContainsAny(ac.Since_Format, `0123456789`) {
+			return fmt.Errorf("invalid Since-Format %q, must be a Go time layout, unix, or unixmilli", ac.Since_Format)
+		}
+	}
+	if ac.Initial_Lookback != `` {
+		if ac.lookback, err = time.ParseDuration(ac.Initial_Lookback); err != nil {
+			return fmt.Errorf("invalid Initial-Lookback %q: %w", ac.Initial_Lookback, err)
+		} else if ac.lookback < 0 {
+			return errors.New("Initial-Lookback may not be negative")
+		}
+	}
+	ac.pollInterval = defaultPollInterval
+	if ac.Poll_Interval != `` {
+		if ac.pollInterval, err = time.ParseDuration(ac.Poll_Interval); err != nil {
+			return fmt.Errorf("invalid Poll-Interval %q: %w", ac.Poll_Interval, err)
+		} else if ac.pollInterval < minPollInterval {
+			return fmt.Errorf("Poll-Interval %v must be at least %v", ac.pollInterval, minPollInterval)
+		}
+	}
+	if ac.Requests_Per_Minute < 0 {
+		return errors.New("Requests-Per-Minute may not be negative")
+	}
+
+	if ac.Tag_Name == `` {
+		return errors.New("missing Tag-Name")
+	} else if err = ingest.CheckTag(ac.Tag_Name); err != nil {
+		return fmt.Errorf("invalid Tag-Name %q: %w", ac.Tag_Name, err)
+	}
+
+	//timestamps, the timegrinder is needed for checkpoints even when ignoring them for entries
+	if ac.Timezone_Override != `` && ac.Assume_Local_Timezone {
+		return errors.New("Cannot specify Assume-Local-Timezone and Timezone-Override in the same API")
+	}
+	if ac.tsPath != nil {
+		tcfg := timegrinder.Config{
+			EnableLeftMostSeed: true,
+		}
+		if ac.tg, err = timegrinder.NewTimeGrinder(tcfg); err != nil {
+			return fmt.Errorf("failed to generate new timegrinder: %w", err)
+		}
+		if ac.Assume_Local_Timezone {
+			ac.tg.SetLocalTime()
+		}
+		if ac.Timezone_Override != `` {
+			if err = ac.tg.SetTimezone(ac.Timezone_Override); err != nil {
+				return fmt.Errorf("invalid Timezone-Override %q: %w", ac.Timezone_Override, err)
+			}
+		}
+	}
+
+	if ac.Source_Override != `` {
+		if ac.src = net.ParseIP(ac.Source_Override); ac.src == nil {
+			return fmt.Errorf("Invalid Source-Override %q", ac.Source_Override)
+		}
+	}
+	return
+}
+
+func (ac *apiCfg) verifyAuth() error {
+	switch ac.Auth_Type = strings.ToLower(strings.TrimSpace(ac.Auth_Type)); ac.Auth_Type {
+	case ``, authNone:
+		ac.Auth_Type = authNone
+	case authBearer:
+		if ac.Token == `` {
+			return errors.New("bearer auth requires a Token")
+		}
+		if ac.Token_Prefix == `` {
+			ac.Token_Prefix = defaultTokenPrefix
+		}
+	case authBasic:
+		if ac.Username == `` {
+			return errors.New("basic auth requires a Username")
+		}
+	case authOAuth2:
+		if ac.Client_ID == `` || ac.Client_Secret == `` {
+			return errors.New("oauth2 auth requires a Client-ID and Client-Secret")
+		} else if ac.Token_URL == `` {
+			return errors.New("oauth2 auth requires a Token-URL")
+		} else if u, err := url.Parse(ac.Token_URL); err != nil || (u.Scheme != `http` && u.Scheme != `https`) {
+			return fmt.Errorf("invalid Token-URL %q", ac.Token_URL)
+		}
+	default:
+		return fmt.Errorf("invalid Auth-Type %q, options are none, bearer, basic, or oauth2", ac.Auth_Type)
+	}
+	if ac.headers.Get(`Authorization`) != `` && ac.Auth_Type != authNone {
+		return errors.New("an Authorization Header conflicts with the Auth-Type")
+	}
+	return nil
+}
+
+func (ac *apiCfg) verifyPagination() (err error) {
+	if ac.Page_Size < 0 || ac.Page_Size > maxPageSize {
+		return fmt.Errorf("Page-Size %d must be between 1 and %d", ac.Page_Size, maxPageSize)
+	} else if ac.Limit_Parameter != `` && ac.Page_Size == 0 {
+		return errors.New("Limit-Parameter requires a Page-Size")
+	}
+	switch ac.Pagination = strings.ToLower(strings.TrimSpace(ac.Pagination)); ac.Pagination {
+	case ``, pageNone:
+		ac.Pagination = pageNone
+	case pageLink:
+	case pageCursor:
+		if ac.Cursor_Parameter == `` {
+			return errors.New("cursor pagination requires a Cursor-Parameter")
+		} else if ac.cursorPath, err = parsePath(ac.Cursor_Path); err != nil {
+			return fmt.Errorf("invalid Cursor-Path %q: %w", ac.Cursor_Path, err)
+		} else if len(ac.cursorPath) == 0 {
+			return errors.New("cursor pagination requires a Cursor-Path")
+		}
+	case pageOffset:
+		if ac.Offset_Parameter == `` {
+			return errors.New("offset pagination requires an Offset-Parameter")
+		} else if ac.Page_Size == 0 {
+			//a short page is how we know we hit the end
+			return errors.New("offset pagination requires a Page-Size")
+		}
+	default:
+		return fmt.Errorf("invalid Pagination %q, options are none, cursor, link, or offset", ac.Pagination)
+	}
+	return
+}
+
+// checkpointing returns true if the API tracks the newest record timestamp between polls
+func (ac *apiCfg) checkpointing() bool {
+	return ac.tsPath != nil
+}
+
+// formatSince renders a checkpoint for the Since-Parameter
+func (ac *apiCfg) formatSince(t time.Time) string {
+	switch ac.Since_Format {
+	case sinceUnix:
+		return fmt.Sprintf("%d", t.Unix())
+	case sinceUnixMilli:
+		return fmt.Sprintf("%d", t.UnixMilli())
+	}
+	return t.UTC().Format(ac.Since_Format)
+}
+
+func (c *cfgType) Tags() ([]string, error) {
+	var tags []string
+	tagMp := map[string]bool{}
+	for _, v := range c.API {
+		if !tagMp[v.Tag_Name] {
+			tags = append(tags, v.Tag_Name)
+			tagMp[v.Tag_Name] = true
+		}
+	}
+	if len(tags) == 0 {
+		return nil, errors.New("No tags specified")
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+func (c *cfgType) IngestBaseConfig() config.IngestConfig {
+	return c.Global.IngestConfig
+}
+
+func (c *cfgType) AttachConfig() attach.AttachConfig {
+	return c.Attach
+}
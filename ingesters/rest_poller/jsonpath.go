@@ -0,0 +1,69 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedPath = errors.New("only member names and array indexes are supported")
+)
+
+// parsePath converts a simple JSONPath such as $.data.items, $['odd.key'][0], or just data.items
+// into jsonparser keys.  An empty path or $ refers to the whole document and returns no keys.
+func parsePath(p string) (keys []string, err error) {
+	p = strings.TrimSpace(p)
+	p = strings.TrimPrefix(p, `$`)
+	for len(p) > 0 {
+		switch p[0] {
+		case '.':
+			p = p[1:]
+			if len(p) > 0 && p[0] == '.' {
+				return nil, fmt.Errorf("recursive descent %w", ErrUnsupportedPath)
+			}
+			fallthrough
+		default:
+			end := strings.IndexAny(p, `.[`)
+			if end < 0 {
+				end = len(p)
+			}
+			name := p[:end]
+			if name == `` {
+				return nil, errors.New("empty member name")
+			} else if name == `*` {
+				return nil, fmt.Errorf("wildcards %w", ErrUnsupportedPath)
+			}
+			keys = append(keys, name)
+			p = p[end:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, errors.New("unterminated [")
+			}
+			sel := strings.TrimSpace(p[1:end])
+			if len(sel) >= 2 && (sel[0] == '\'' || sel[0] == '"') {
+				//quoted member names may contain dots, but not the closing bracket
+				if sel[len(sel)-1] != sel[0] {
+					return nil, fmt.Errorf("unterminated quote in %q", p[:end+1])
+				}
+				keys = append(keys, sel[1:len(sel)-1])
+			} else if idx, lerr := strconv.Atoi(sel); lerr == nil && idx >= 0 {
+				keys = append(keys, `[`+sel+`]`)
+			} else {
+				return nil, fmt.Errorf("selector %q %w", sel, ErrUnsupportedPath)
+			}
+			p = p[end+1:]
+		}
+	}
+	return
+}
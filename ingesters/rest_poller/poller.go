@@ -0,0 +1,444 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/jsonparser"
+	"golang.org/x/time/rate"
+)
+
+const (
+	requestTimeout  = time.Minute
+	maxResponseSize = 64 * 1024 * 1024
+	maxErrorBody    = 512
+	maxPagesPerPoll = 10000
+	maxRetries      = 8
+	minBackoff      = time.Second
+	maxBackoff      = 5 * time.Minute
+
+	// numeric timestamps at or above this are epoch milliseconds rather than seconds
+	epochMilliThreshold = 1e12
+)
+
+var (
+	ErrNotArray = errors.New("Records-Path does not refer to an array")
+)
+
+// statusError is a non-2xx response
+type statusError struct {
+	code int
+	body string
+}
+
+func (se *statusError) Error() string {
+	return fmt.Sprintf("request failed with %d %s: %s", se.code, http.StatusText(se.code), se.body)
+}
+
+type poller struct {
+	name  string
+	cfg   *apiCfg
+	hc    *http.Client
+	rl    *rate.Limiter
+	tag   entry.EntryTag
+	proc  *processors.ProcessorSet
+	state *stateStore
+	lg    *log.Logger
+	ctx   context.Context
+	now   func() time.Time
+}
+
+func newPoller(ctx context.Context, name string, cfg *apiCfg, tag entry.EntryTag, proc *processors.ProcessorSet, state *stateStore, lg *log.Logger) *poller {
+	p := &poller{
+		name:  name,
+		cfg:   cfg,
+		hc:    cfg.httpClient(ctx, &http.Client{Timeout: requestTimeout}),
+		tag:   tag,
+		proc:  proc,
+		state: state,
+		lg:    lg,
+		ctx:   ctx,
+		now:   time.Now,
+	}
+	if cfg.Requests_Per_Minute > 0 {
+		p.rl = rate.NewLimiter(rate.Every(time.Minute/time.Duration(cfg.Requests_Per_Minute)), 1)
+	}
+	return p
+}
+
+// run polls the API every Poll-Interval until the context is cancelled
+func (p *poller) run(wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		if cnt, err := p.poll(); err != nil {
+			if p.ctx.Err() != nil {
+				return
+			}
+			p.lg.Error("poll failed", log.KV("api", p.name), log.KV("ingested", cnt), log.KVErr(err))
+		} else if cnt > 0 {
+			p.lg.Info("poll complete", log.KV("api", p.name), log.KV("ingested", cnt))
+		}
+		if !p.sleep(p.cfg.pollInterval) {
+			return
+		}
+	}
+}
+
+func (p *poller) sleep(d time.Duration) bool {
+	tmr := time.NewTimer(d)
+	defer tmr.Stop()
+	select {
+	case <-p.ctx.Done():
+		return false
+	case <-tmr.C:
+	}
+	return true
+}
+
+// poll fetches every page of new records, the checkpoint only advances once all of them have been
+// handed to the muxer so a failed poll is retried from the same point
+func (p *poller) poll() (cnt int, err error) {
+	cp := p.state.get(p.name)
+	next := checkpoint{Latest: cp.Latest}
+	seen := make(map[uint64]bool, len(cp.Seen))
+	for _, h := range cp.Seen {
+		seen[h] = true
+		next.Seen = append(next.Seen, h)
+	}
+
+	first := p.firstURL(cp)
+	u := first
+	var offset int
+	for page := 0; page < maxPagesPerPoll && u != nil; page++ {
+		var body []byte
+		var hdr http.Header
+		if body, hdr, err = p.fetch(u); err != nil {
+			return
+		}
+		var records [][]byte
+		if records, err = p.records(body); err != nil {
+			return
+		}
+		for _, rec := range records {
+			var ok bool
+			if ok, err = p.handle(rec, cp, seen, &next); err != nil {
+				return
+			} else if ok {
+				cnt++
+			}
+		}
+		offset += len(records)
+		if u, err = p.nextURL(first, u, hdr, body, len(records), offset); err != nil {
+			return
+		}
+	}
+	if p.cfg.checkpointing() && (!next.Latest.Equal(cp.Latest) || len(next.Seen) != len(cp.Seen)) {
+		if err = p.state.set(p.name, next); err != nil {
+			err = fmt.Errorf("failed to save checkpoint: %w", err)
+		}
+	}
+	return
+}
+
+// handle ingests a single record unless the checkpoint says we already have it
+func (p *poller) handle(rec []byte, cp checkpoint, seen map[uint64]bool, next *checkpoint) (ok bool, err error) {
+	ts, hasTS := p.recordTime(rec)
+	var h uint64
+	if hasTS {
+		if ts.Before(cp.Latest) {
+			return
+		}
+		hf := fnv.New64a()
+		hf.Write(rec)
+		h = hf.Sum64()
+		if ts.Equal(cp.Latest) && seen[h] {
+			return
+		}
+	}
+	ent := &entry.Entry{
+		TS:   entry.Now(),
+		SRC:  p.cfg.src,
+		Tag:  p.tag,
+		Data: rec,
+	}
+	if hasTS && !p.cfg.Ignore_Timestamps {
+		ent.TS = entry.FromStandard(ts)
+	}
+	if err = p.proc.ProcessContext(ent, p.ctx); err != nil {
+		return
+	}
+	if hasTS {
+		if ts.After(next.Latest) {
+			next.Latest = ts
+			next.Seen = next.Seen[:0]
+		}
+		if ts.Equal(next.Latest) {
+			next.Seen = append(next.Seen, h)
+		}
+	}
+	ok = true
+	return
+}
+
+// recordTime pulls the Timestamp-Field out of a record, numbers are epoch seconds or milliseconds
+func (p *poller) recordTime(rec []byte) (ts time.Time, ok bool) {
+	if p.cfg.tsPath == nil {
+		return
+	}
+	v, dt, _, err := jsonparser.Get(rec, p.cfg.tsPath...)
+	if err != nil {
+		return
+	}
+	switch dt {
+	case jsonparser.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil || f <= 0 {
+			return
+		}
+		if f >= epochMilliThreshold {
+			f /= 1000
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	case jsonparser.String:
+		var err error
+		if ts, ok, err = p.cfg.tg.Extract(v); err != nil {
+			p.lg.Warn("catastrophic timegrinder error", log.KV("api", p.name), log.KVErr(err))
+			ok = false
+		}
+	}
+	return
+}
+
+// records splits a response into the individual records at Records-Path
+func (p *poller) records(body []byte) (records [][]byte, err error) {
+	v, dt, _, err := jsonparser.Get(body, p.cfg.recordsPath...)
+	if err == jsonparser.KeyPathNotFoundError {
+		return nil, nil //many APIs leave out empty arrays
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	switch dt {
+	case jsonparser.Null:
+		return
+	case jsonparser.Array:
+	default:
+		return nil, ErrNotArray
+	}
+	_, err = jsonparser.ArrayEach(v, func(rec []byte, dt jsonparser.ValueType, off int, lerr error) {
+		r := make([]byte, 0, len(rec)+2)
+		if dt == jsonparser.String {
+			//ArrayEach strips the quotes from the still escaped string, put them back so the entry is JSON
+			r = append(append(append(r, '"'), rec...), '"')
+		} else {
+			r = append(r, rec...)
+		}
+		records = append(records, r)
+	})
+	if err != nil {
+		err = fmt.Errorf("invalid records array: %w", err)
+	}
+	return
+}
+
+// firstURL builds the URL for the first page of a poll
+func (p *poller) firstURL(cp checkpoint) *url.URL {
+	u := *p.cfg.u
+	q := u.Query()
+	if p.cfg.Since_Parameter != `` {
+		since := cp.Latest
+		if since.IsZero() && p.cfg.lookback > 0 {
+			since = p.now().Add(-p.cfg.lookback)
+		}
+		if !since.IsZero() {
+			q.Set(p.cfg.Since_Parameter, p.cfg.formatSince(since))
+		}
+	}
+	if p.cfg.Limit_Parameter != `` {
+		q.Set(p.cfg.Limit_Parameter, strconv.Itoa(p.cfg.Page_Size))
+	}
+	if p.cfg.Pagination == pageOffset {
+		q.Set(p.cfg.Offset_Parameter, `0`)
+	}
+	u.RawQuery = q.Encode()
+	return &u
+}
+
+// nextURL returns the URL of the next page, or nil if there are no more
+func (p *poller) nextURL(first, cur *url.URL, hdr http.Header, body []byte, cnt, offset int) (next *url.URL, err error) {
+	if cnt == 0 {
+		return //every pagination style ends with an empty page
+	}
+	switch p.cfg.Pagination {
+	case pageLink:
+		if link := nextLink(hdr.Values(`Link`)); link != `` {
+			if next, err = cur.Parse(link); err != nil {
+				err = fmt.Errorf("invalid next link %q: %w", link, err)
+			}
+		}
+	case pageCursor:
+		v, dt, _, lerr := jsonparser.Get(body, p.cfg.cursorPath...)
+		if lerr != nil || (dt != jsonparser.String && dt != jsonparser.Number) || len(v) == 0 {
+			return
+		}
+		cursor := string(v)
+		if dt == jsonparser.String {
+			if cursor, err = jsonparser.ParseString(v); err != nil {
+				err = fmt.Errorf("invalid cursor: %w", err)
+				return
+			}
+		}
+		u := *first
+		q := u.Query()
+		q.Set(p.cfg.Cursor_Parameter, cursor)
+		u.RawQuery = q.Encode()
+		next = &u
+	case pageOffset:
+		if cnt < p.cfg.Page_Size {
+			return
+		}
+		u := *first
+		q := u.Query()
+		q.Set(p.cfg.Offset_Parameter, strconv.Itoa(offset))
+		u.RawQuery = q.Encode()
+		next = &u
+	}
+	return
+}
+
+// nextLink finds the rel="next" target in RFC 8288 Link headers
+func nextLink(links []string) string {
+	for _, hdr := range links {
+		for _, link := range strings.Split(hdr, `,`) {
+			target, params, _ := strings.Cut(link, `;`)
+			target = strings.TrimSpace(target)
+			if !strings.HasPrefix(target, `<`) || !strings.HasSuffix(target, `>`) {
+				continue
+			}
+			for _, param := range strings.Split(params, `;`) {
+				k, v, _ := strings.Cut(param, `=`)
+				if !strings.EqualFold(strings.TrimSpace(k), `rel`) {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(v), `"`)) {
+					if strings.EqualFold(rel, `next`) {
+						return target[1 : len(target)-1]
+					}
+				}
+			}
+		}
+	}
+	return ``
+}
+
+// fetch requests a page, backing off when rate limited or when the server has a problem
+func (p *poller) fetch(u *url.URL) (body []byte, hdr http.Header, err error) {
+	backoff := minBackoff
+	for attempt := 0; ; attempt++ {
+		if p.rl != nil {
+			if err = p.rl.Wait(p.ctx); err != nil {
+				return
+			}
+		}
+		var wait time.Duration
+		if body, hdr, wait, err = p.request(u); err == nil {
+			return
+		} else if wait < 0 || attempt >= maxRetries || p.ctx.Err() != nil {
+			return
+		}
+		if wait == 0 {
+			wait = backoff
+			if backoff *= 2; backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		} else if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		p.lg.Warn("request failed, retrying", log.KV("api", p.name), log.KV("retry", wait), log.KVErr(err))
+		if !p.sleep(wait) {
+			return nil, nil, p.ctx.Err()
+		}
+	}
+}
+
+// request makes a single request, wait is negative if the error is not worth retrying and
+// positive if the server said how long to wait
+func (p *poller) request(u *url.URL) (body []byte, hdr http.Header, wait time.Duration, err error) {
+	var rdr io.Reader
+	if p.cfg.Body != `` {
+		rdr = strings.NewReader(p.cfg.Body)
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(p.ctx, p.cfg.Method, u.String(), rdr); err != nil {
+		wait = -1
+		return
+	}
+	for k, v := range p.cfg.headers {
+		req.Header[k] = v
+	}
+	if req.Header.Get(`Accept`) == `` {
+		req.Header.Set(`Accept`, `application/json`)
+	}
+	if rdr != nil && req.Header.Get(`Content-Type`) == `` {
+		req.Header.Set(`Content-Type`, `application/json`)
+	}
+	p.cfg.authorize(req)
+	var resp *http.Response
+	if resp, err = p.hc.Do(req); err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		eb, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		err = &statusError{code: resp.StatusCode, body: string(bytes.TrimSpace(eb))}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			wait = retryAfter(resp.Header.Get(`Retry-After`), p.now())
+		} else {
+			wait = -1
+		}
+		return
+	}
+	if body, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1)); err != nil {
+		return
+	} else if len(body) > maxResponseSize {
+		err = fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+		wait = -1
+		return
+	}
+	hdr = resp.Header
+	return
+}
+
+// retryAfter decodes a Retry-After header, which is either seconds or an HTTP date
+func retryAfter(v string, now time.Time) time.Duration {
+	if v = strings.TrimSpace(v); v == `` {
+		return 0
+	} else if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,544 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+)
+
+func loadTestConfig(t *testing.T, body string) (*cfgType, error) {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), `rest_poller.conf`)
+	if err := os.WriteFile(p, []byte(body), 0640); err != nil {
+		t.Fatal(err)
+	}
+	return GetConfig(p, ``)
+}
+
+const testGlobal = `
+[Global]
+Ingest-Secret = IngestSecrets
+Cleartext-Backend-Target=127.0.0.1:4023
+Log-Level=INFO
+`
+
+func TestParsePath(t *testing.T) {
+	tests := map[string][]string{
+		``:                       nil,
+		`$`:                      nil,
+		`data`:                   {`data`},
+		`$.data.items`:           {`data`, `items`},
+		`data.items[2].name`:     {`data`, `items`, `[2]`, `name`},
+		`$['odd.key']["x y"]`:    {`odd.key`, `x y`},
+		`$.response[0]['a.b'].c`: {`response`, `[0]`, `a.b`, `c`},
+	}
+	for p, want := range tests {
+		keys, err := parsePath(p)
+		if err != nil {
+			t.Fatalf("%q: %v", p, err)
+		} else if strings.Join(keys, `|`) != strings.Join(want, `|`) || len(keys) != len(want) {
+			t.Fatalf("%q: bad keys %q != %q", p, keys, want)
+		}
+	}
+	for _, p := range []string{`$..data`, `$.data[*]`, `$.items[-1]`, `$.a[?(@.b)]`, `$.a[0`, `$['a]`, `$.a.`} {
+		if _, err := parsePath(p); err == nil {
+			t.Fatalf("failed to catch bad path %q", p)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	cfg, err := loadTestConfig(t, testGlobal+`
+State-Store-Location=/tmp/rest_poller.state
+
+[API "okta"]
+	URL="https://example.okta.com/api/v1/logs?sortOrder=ASCENDING"
+	Auth-Type=Bearer
+	Token=secret
+	Token-Prefix=SSWS
+	Pagination=link
+	Limit-Parameter=limit
+	Page-Size=100
+	Timestamp-Field=published
+	Since-Parameter=since
+	Initial-Lookback=24h
+	Poll-Interval=30s
+	Tag-Name=okta
+
+[API "inventory"]
+	URL="https://api.example.com/v2/assets"
+	Method=POST
+	Body="{\"filter\":\"all\"}"
+	Header="X-Api-Version: 2"
+	Auth-Type=oauth2
+	Client-ID=id
+	Client-Secret=secret
+	Token-URL="https://auth.example.com/oauth2/token"
+	Scope=assets.read
+	Pagination=cursor
+	Cursor-Path="$.meta.next"
+	Cursor-Parameter=after
+	Records-Path="$.data.assets"
+	Tag-Name=assets
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := cfg.Tags(); err != nil || len(tags) != 2 {
+		t.Fatalf("bad tags %v %v", tags, err)
+	}
+	okta := cfg.API[`okta`]
+	if okta.Method != http.MethodGet || okta.Token_Prefix != `SSWS` || okta.pollInterval != 30*time.Second || okta.lookback != 24*time.Hour {
+		t.Fatalf("bad settings %+v", okta)
+	} else if !okta.checkpointing() || okta.tg == nil || okta.Since_Format != time.RFC3339 {
+		t.Fatalf("bad checkpoint settings %+v", okta)
+	}
+	inv := cfg.API[`inventory`]
+	if inv.headers.Get(`X-Api-Version`) != `2` || strings.Join(inv.recordsPath, `.`) != `data.assets` || inv.checkpointing() {
+		t.Fatalf("bad settings %+v", inv)
+	} else if inv.pollInterval != defaultPollInterval {
+		t.Fatalf("bad default interval %v", inv.pollInterval)
+	}
+
+	bad := []string{
+		`URL="ftp://example.com/logs"`,
+		`URL="https://example.com"
+		Method=DELETE`,
+		`URL="https://example.com"
+		Body=abc`, //body without POST
+		`URL="https://example.com"
+		Header=noColon`,
+		`URL="https://example.com"
+		Auth-Type=bearer`, //no token
+		`URL="https://example.com"
+		Auth-Type=oauth2
+		Client-ID=x
+		Client-Secret=y`, //no token URL
+		`URL="https://example.com"
+		Auth-Type=basic
+		Username=x
+		Header="Authorization: Basic abc"`, //conflicting auth
+		`URL="https://example.com"
+		Pagination=cursor
+		Cursor-Parameter=after`, //no cursor path
+		`URL="https://example.com"
+		Pagination=offset
+		Offset-Parameter=offset`, //no page size
+		`URL="https://example.com"
+		Pagination=pages`,
+		`URL="https://example.com"
+		Since-Parameter=since`, //nothing to checkpoint
+		`URL="https://example.com"
+		Records-Path="$..items"`,
+		`URL="https://example.com"
+		Poll-Interval=10ms`,
+		`URL="https://example.com"
+		Timestamp-Field=ts
+		Since-Format=junk`,
+	}
+	for i, b := range bad {
+		if _, err := loadTestConfig(t, testGlobal+"[API \"bad\"]\n\tTag-Name=bad\n\t"+b+"\n"); err == nil {
+			t.Fatalf("failed to catch bad config %d", i)
+		}
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	hdrs := []string{
+		`<https://example.com/logs?after=1>; rel="self"`,
+		`<https://example.com/logs?after=2>; rel="prev first", </logs?after=3&limit=10>; rel="next"`,
+	}
+	if l := nextLink(hdrs); l != `/logs?after=3&limit=10` {
+		t.Fatalf("bad link %q", l)
+	} else if l = nextLink(hdrs[:1]); l != `` {
+		t.Fatalf("bad missing link %q", l)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	if d := retryAfter(`7`, now); d != 7*time.Second {
+		t.Fatal(d)
+	} else if d = retryAfter(now.Add(time.Minute).Format(http.TimeFormat), now); d != time.Minute {
+		t.Fatal(d)
+	} else if d = retryAfter(`soon`, now); d != 0 {
+		t.Fatal(d)
+	}
+}
+
+// testWriter collects entries
+type testWriter struct {
+	mtx  sync.Mutex
+	ents []*entry.Entry
+}
+
+func (tw *testWriter) WriteEntry(ent *entry.Entry) error {
+	tw.mtx.Lock()
+	defer tw.mtx.Unlock()
+	tw.ents = append(tw.ents, ent)
+	return nil
+}
+
+func (tw *testWriter) WriteEntryContext(ctx context.Context, ent *entry.Entry) error {
+	return tw.WriteEntry(ent)
+}
+
+func (tw *testWriter) WriteBatch(ents []*entry.Entry) error {
+	for _, ent := range ents {
+		if err := tw.WriteEntry(ent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tw *testWriter) WriteBatchContext(ctx context.Context, ents []*entry.Entry) error {
+	return tw.WriteBatch(ents)
+}
+
+func (tw *testWriter) data() (r []string) {
+	tw.mtx.Lock()
+	defer tw.mtx.Unlock()
+	for _, ent := range tw.ents {
+		r = append(r, string(ent.Data))
+	}
+	tw.ents = nil
+	return
+}
+
+type testEvent struct {
+	ID        int    `json:"id"`
+	Published string `json:"published"`
+}
+
+// testAPI serves events newer than or equal to the since parameter, a page at a time
+type testAPI struct {
+	mtx      sync.Mutex
+	events   []testEvent
+	requests []string
+	auth     string
+}
+
+func (ta *testAPI) add(id int, ts time.Time) {
+	ta.mtx.Lock()
+	defer ta.mtx.Unlock()
+	ta.events = append(ta.events, testEvent{ID: id, Published: ts.Format(time.RFC3339Nano)})
+}
+
+// page returns the events after the since filter, starting at off
+func (ta *testAPI) page(r *http.Request, off, limit int) (evs []testEvent, more bool) {
+	ta.mtx.Lock()
+	defer ta.mtx.Unlock()
+	ta.requests = append(ta.requests, r.URL.RawQuery)
+	ta.auth = r.Header.Get(`Authorization`)
+	var since time.Time
+	if s := r.URL.Query().Get(`since`); s != `` {
+		since, _ = time.Parse(time.RFC3339, s)
+	}
+	var all []testEvent
+	for _, ev := range ta.events {
+		if ts, _ := time.Parse(time.RFC3339Nano, ev.Published); !ts.Before(since) {
+			all = append(all, ev)
+		}
+	}
+	if off > len(all) {
+		off = len(all)
+	}
+	end := off + limit
+	if end > len(all) {
+		end = len(all)
+	}
+	return all[off:end], end < len(all)
+}
+
+func (ta *testAPI) lastRequests() []string {
+	ta.mtx.Lock()
+	defer ta.mtx.Unlock()
+	r := ta.requests
+	ta.requests = nil
+	return r
+}
+
+func newTestPoller(t *testing.T, srvURL, body string, state *stateStore) (*poller, *testWriter) {
+	t.Helper()
+	cfg, err := loadTestConfig(t, testGlobal+strings.ReplaceAll(body, `SERVER`, srvURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state == nil {
+		if state, err = newStateStore(filepath.Join(t.TempDir(), `state`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw := &testWriter{}
+	p := newPoller(context.Background(), `test`, cfg.API[`test`], entry.EntryTag(1), processors.NewProcessorSet(tw), state, log.NewDiscardLogger())
+	return p, tw
+}
+
+func eventIDs(t *testing.T, data []string) string {
+	var ids []string
+	for _, d := range data {
+		var ev testEvent
+		if err := json.Unmarshal([]byte(d), &ev); err != nil {
+			t.Fatalf("bad record %q: %v", d, err)
+		}
+		ids = append(ids, strconv.Itoa(ev.ID))
+	}
+	return strings.Join(ids, `,`)
+}
+
+func TestPollCursor(t *testing.T) {
+	api := &testAPI{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		off, _ := strconv.Atoi(r.URL.Query().Get(`after`))
+		evs, more := api.page(r, off, 2)
+		resp := map[string]interface{}{`data`: map[string]interface{}{`events`: evs}}
+		if more {
+			resp[`meta`] = map[string]string{`next`: strconv.Itoa(off + len(evs))}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		api.add(i, base.Add(time.Duration(i)*time.Second))
+	}
+	api.add(5, base.Add(4*time.Second)) //shares the newest timestamp
+
+	statePath := filepath.Join(t.TempDir(), `state`)
+	state, err := newStateStore(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const body = `
+[API "test"]
+	URL="SERVER/events?sort=asc"
+	Auth-Type=bearer
+	Token=secret
+	Pagination=cursor
+	Cursor-Path="$.meta.next"
+	Cursor-Parameter=after
+	Records-Path="$.data.events"
+	Timestamp-Field=published
+	Since-Parameter=since
+	Tag-Name=events
+`
+	p, tw := newTestPoller(t, srv.URL, body, state)
+	if cnt, err := p.poll(); err != nil || cnt != 6 {
+		t.Fatal(cnt, err)
+	}
+	if got := eventIDs(t, tw.data()); got != `0,1,2,3,4,5` {
+		t.Fatalf("bad events %s", got)
+	}
+	if reqs := api.lastRequests(); len(reqs) != 3 || strings.Contains(reqs[0], `since`) || !strings.Contains(reqs[1], `after=2`) || !strings.Contains(reqs[1], `sort=asc`) {
+		t.Fatalf("bad requests %v", reqs)
+	} else if api.auth != `Bearer secret` {
+		t.Fatalf("bad auth %q", api.auth)
+	}
+	cp := state.get(`test`)
+	if !cp.Latest.Equal(base.Add(4*time.Second)) || len(cp.Seen) != 2 {
+		t.Fatalf("bad checkpoint %+v", cp)
+	}
+
+	//the since parameter is inclusive, the records at the checkpoint are not ingested again
+	api.add(6, base.Add(4*time.Second))
+	api.add(7, base.Add(5*time.Second))
+	if cnt, err := p.poll(); err != nil || cnt != 2 {
+		t.Fatal(cnt, err)
+	} else if got := eventIDs(t, tw.data()); got != `6,7` {
+		t.Fatalf("bad events %s", got)
+	}
+	if reqs := api.lastRequests(); !strings.Contains(reqs[0], `since=2025-03-01T12%3A00%3A04Z`) {
+		t.Fatalf("checkpoint not sent %v", reqs)
+	}
+
+	//the checkpoint survives a restart
+	if state, err = newStateStore(statePath); err != nil {
+		t.Fatal(err)
+	}
+	p, tw = newTestPoller(t, srv.URL, body, state)
+	if cnt, err := p.poll(); err != nil || cnt != 0 {
+		t.Fatal(cnt, err)
+	}
+	if cp = state.get(`test`); !cp.Latest.Equal(base.Add(5*time.Second)) || len(cp.Seen) != 1 {
+		t.Fatalf("bad checkpoint %+v", cp)
+	}
+}
+
+func TestPollLink(t *testing.T) {
+	api := &testAPI{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		off, _ := strconv.Atoi(r.URL.Query().Get(`page`))
+		evs, more := api.page(r, off, 2)
+		if more {
+			w.Header().Add(`Link`, fmt.Sprintf(`</events?page=%d>; rel="next"`, off+len(evs)))
+		}
+		json.NewEncoder(w).Encode(evs)
+	}))
+	defer srv.Close()
+	base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		api.add(i, base.Add(time.Duration(i)*time.Second))
+	}
+	p, tw := newTestPoller(t, srv.URL, `
+[API "test"]
+	URL="SERVER/events"
+	Auth-Type=basic
+	Username=user
+	Password=pass
+	Pagination=link
+	Tag-Name=events
+`, nil)
+	if cnt, err := p.poll(); err != nil || cnt != 3 {
+		t.Fatal(cnt, err)
+	} else if got := eventIDs(t, tw.data()); got != `0,1,2` {
+		t.Fatalf("bad events %s", got)
+	} else if !strings.HasPrefix(api.auth, `Basic `) {
+		t.Fatalf("bad auth %q", api.auth)
+	}
+	//without a timestamp every poll gets everything
+	if cnt, err := p.poll(); err != nil || cnt != 3 {
+		t.Fatal(cnt, err)
+	}
+}
+
+func TestPollOffset(t *testing.T) {
+	api := &testAPI{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		off, _ := strconv.Atoi(r.URL.Query().Get(`offset`))
+		limit, _ := strconv.Atoi(r.URL.Query().Get(`limit`))
+		evs, _ := api.page(r, off, limit)
+		json.NewEncoder(w).Encode(map[string]interface{}{`results`: evs})
+	}))
+	defer srv.Close()
+	base := time.Now().Add(-time.Hour).UTC()
+	for i := 0; i < 4; i++ {
+		api.add(i, base)
+	}
+	p, tw := newTestPoller(t, srv.URL, `
+[API "test"]
+	URL="SERVER/events"
+	Pagination=offset
+	Offset-Parameter=offset
+	Limit-Parameter=limit
+	Page-Size=2
+	Records-Path=results
+	Timestamp-Field=published
+	Since-Parameter=since
+	Initial-Lookback=2h
+	Tag-Name=events
+`, nil)
+	if cnt, err := p.poll(); err != nil || cnt != 4 {
+		t.Fatal(cnt, err)
+	} else if got := eventIDs(t, tw.data()); got != `0,1,2,3` {
+		t.Fatalf("bad events %s", got)
+	}
+	//full pages keep going until an empty one
+	reqs := api.lastRequests()
+	if len(reqs) != 3 || !strings.Contains(reqs[2], `offset=4`) || !strings.Contains(reqs[0], `since=`) {
+		t.Fatalf("bad requests %v", reqs)
+	}
+}
+
+func TestOAuth2(t *testing.T) {
+	var tokens int
+	mux := http.NewServeMux()
+	mux.HandleFunc(`/token`, func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != `client` || secret != `secret` {
+			http.Error(w, `bad client`, http.StatusUnauthorized)
+			return
+		} else if r.FormValue(`grant_type`) != `client_credentials` || r.FormValue(`scope`) != `logs.read` {
+			http.Error(w, `bad grant`, http.StatusBadRequest)
+			return
+		}
+		tokens++
+		w.Header().Set(`Content-Type`, `application/json`)
+		fmt.Fprint(w, `{"access_token":"tok123","token_type":"Bearer","expires_in":3600}`)
+	})
+	mux.HandleFunc(`/events`, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(`Authorization`) != `Bearer tok123` {
+			http.Error(w, `unauthorized`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `["a","b\"c",{"d":1}]`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	p, tw := newTestPoller(t, srv.URL, `
+[API "test"]
+	URL="SERVER/events"
+	Auth-Type=oauth2
+	Client-ID=client
+	Client-Secret=secret
+	Token-URL="SERVER/token"
+	Scope=logs.read
+	Tag-Name=events
+`, nil)
+	for i := 0; i < 2; i++ {
+		if cnt, err := p.poll(); err != nil || cnt != 3 {
+			t.Fatal(cnt, err)
+		}
+	}
+	if got := strings.Join(tw.data(), ` `); got != `"a" "b\"c" {"d":1} "a" "b\"c" {"d":1}` {
+		t.Fatalf("bad records %s", got)
+	} else if tokens != 1 {
+		t.Fatalf("token was not reused: %d", tokens)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch calls {
+		case 1:
+			w.Header().Set(`Retry-After`, `1`)
+			http.Error(w, `slow down`, http.StatusTooManyRequests)
+		case 2:
+			fmt.Fprint(w, `[{"id":1}]`)
+		default:
+			http.Error(w, `no such thing`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	p, tw := newTestPoller(t, srv.URL, `
+[API "test"]
+	URL="SERVER/events"
+	Tag-Name=events
+`, nil)
+	start := time.Now()
+	if cnt, err := p.poll(); err != nil || cnt != 1 {
+		t.Fatal(cnt, err)
+	} else if time.Since(start) < time.Second {
+		t.Fatal("did not honor Retry-After")
+	} else if len(tw.data()) != 1 {
+		t.Fatal("missing record")
+	}
+	//client errors are not retried
+	var se *statusError
+	if _, err := p.poll(); !errors.As(err, &se) || se.code != http.StatusNotFound || calls != 3 {
+		t.Fatalf("bad error %v after %d calls", err, calls)
+	}
+}
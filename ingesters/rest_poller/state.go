@@ -0,0 +1,63 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingesters/utils"
+)
+
+const (
+	stateFilePerm = 0640
+)
+
+// checkpoint is the newest record timestamp seen for an API.  Most APIs treat the since parameter
+// as inclusive, so the records at exactly that time are remembered by hash to avoid ingesting
+// them again.
+type checkpoint struct {
+	Latest time.Time
+	Seen   []uint64
+}
+
+// stateStore persists the checkpoints for every API in a single state file
+type stateStore struct {
+	mtx sync.Mutex
+	st  *utils.State
+	cps map[string]checkpoint
+}
+
+func newStateStore(pth string) (ss *stateStore, err error) {
+	ss = &stateStore{
+		cps: map[string]checkpoint{},
+	}
+	if ss.st, err = utils.NewState(pth, stateFilePerm); err != nil {
+		return nil, err
+	}
+	if err = ss.st.Read(&ss.cps); err == utils.ErrNoState {
+		err = nil
+	} else if err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (ss *stateStore) get(name string) checkpoint {
+	ss.mtx.Lock()
+	defer ss.mtx.Unlock()
+	return ss.cps[name]
+}
+
+func (ss *stateStore) set(name string, cp checkpoint) error {
+	ss.mtx.Lock()
+	defer ss.mtx.Unlock()
+	ss.cps[name] = cp
+	return ss.st.Write(ss.cps)
+}
@@ -0,0 +1,392 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azqueue"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+const (
+	azureBlobCreated         = `Microsoft.Storage.BlobCreated`
+	azureMaxMessages         = 32 // most messages a single queue receive may return
+	azurePollInterval        = 5 * time.Second
+	defaultAzureVisibility   = 10 * time.Minute
+	maxAzureVisibility       = 7 * 24 * time.Hour
+	azureBlobEndpointFormat  = `https://%s.blob.core.windows.net`
+	azureQueueEndpointFormat = `https://%s.queue.core.windows.net`
+)
+
+var (
+	ErrMissingStorageAccount = errors.New("Storage-Account is required")
+	ErrMissingAzureAuth      = errors.New("One of Account-Key or SAS-Token is required")
+	ErrAzureAuthConflict     = errors.New("Account-Key and SAS-Token are mutually exclusive")
+)
+
+// azureAuth holds the storage account settings shared by Azure containers and Event Grid listeners
+type azureAuth struct {
+	Storage_Account string
+	Account_Key     string `json:"-"` // DO NOT send this when marshalling
+	SAS_Token       string `json:"-"` // DO NOT send this when marshalling
+	Blob_Endpoint   string // defaults to https://<account>.blob.core.windows.net, set for emulators and sovereign clouds
+}
+
+func (aa azureAuth) validate() (err error) {
+	if aa.Storage_Account == `` {
+		err = ErrMissingStorageAccount
+	} else if aa.Account_Key == `` && aa.SAS_Token == `` {
+		err = ErrMissingAzureAuth
+	} else if aa.Account_Key != `` && aa.SAS_Token != `` {
+		err = ErrAzureAuthConflict
+	} else if aa.Account_Key != `` {
+		if _, err = base64.StdEncoding.DecodeString(aa.Account_Key); err != nil {
+			err = fmt.Errorf("Invalid Account-Key %w", err)
+		}
+	} else if _, err = url.ParseQuery(strings.TrimPrefix(aa.SAS_Token, `?`)); err != nil {
+		err = fmt.Errorf("Invalid SAS-Token %w", err)
+	}
+	if err == nil && aa.Blob_Endpoint != `` {
+		_, err = parseEndpoint(aa.Blob_Endpoint)
+	}
+	return
+}
+
+func parseEndpoint(v string) (u *url.URL, err error) {
+	if u, err = url.Parse(v); err != nil {
+		return
+	} else if u.Scheme != `http` && u.Scheme != `https` {
+		err = fmt.Errorf("Invalid endpoint %q, must be an http or https URL", v)
+	} else if u.Host == `` {
+		err = fmt.Errorf("Invalid endpoint %q, missing host", v)
+	}
+	return
+}
+
+// azureClient holds the blob and queue service clients for a single storage account.
+// Requests are authorized with the account key or a SAS token.
+type azureClient struct {
+	blob  *azblob.Client
+	queue *azqueue.ServiceClient
+}
+
+func newAzureClient(aa azureAuth, queueEndpoint string) (ac *azureClient, err error) {
+	if err = aa.validate(); err != nil {
+		return
+	}
+	if aa.Blob_Endpoint == `` {
+		aa.Blob_Endpoint = fmt.Sprintf(azureBlobEndpointFormat, aa.Storage_Account)
+	}
+	if queueEndpoint == `` {
+		queueEndpoint = fmt.Sprintf(azureQueueEndpointFormat, aa.Storage_Account)
+	} else if _, err = parseEndpoint(queueEndpoint); err != nil {
+		return
+	}
+	ac = &azureClient{}
+	if aa.Account_Key != `` {
+		var bcred *azblob.SharedKeyCredential
+		var qcred *azqueue.SharedKeyCredential
+		if bcred, err = azblob.NewSharedKeyCredential(aa.Storage_Account, aa.Account_Key); err != nil {
+			return nil, err
+		} else if qcred, err = azqueue.NewSharedKeyCredential(aa.Storage_Account, aa.Account_Key); err != nil {
+			return nil, err
+		} else if ac.blob, err = azblob.NewClientWithSharedKeyCredential(aa.Blob_Endpoint, bcred, nil); err != nil {
+			return nil, err
+		} else if ac.queue, err = azqueue.NewServiceClientWithSharedKeyCredential(queueEndpoint, qcred, nil); err != nil {
+			return nil, err
+		}
+		return
+	}
+	//SAS tokens ride along in the query of every request
+	if ac.blob, err = azblob.NewClientWithNoCredential(withSAS(aa.Blob_Endpoint, aa.SAS_Token), nil); err != nil {
+		return nil, err
+	} else if ac.queue, err = azqueue.NewServiceClientWithNoCredential(withSAS(queueEndpoint, aa.SAS_Token), nil); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func withSAS(endpoint, sas string) string {
+	return strings.TrimSuffix(endpoint, `/`) + `/?` + strings.TrimPrefix(sas, `?`)
+}
+
+// azureBlobStore reads blobs from a single container
+type azureBlobStore struct {
+	client    *azureClient
+	container string
+}
+
+func (as *azureBlobStore) List(ctx context.Context, prefix string, fn func(objectInfo) bool) error {
+	var opts azblob.ListBlobsFlatOptions
+	if prefix != `` {
+		opts.Prefix = &prefix
+	}
+	pager := as.client.blob.NewListBlobsFlatPager(as.container, &opts)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return err
+		} else if page.Segment == nil {
+			continue
+		}
+		for _, b := range page.Segment.BlobItems {
+			if b == nil || b.Name == nil || b.Properties == nil {
+				continue
+			}
+			oi := objectInfo{Key: *b.Name}
+			if b.Properties.ContentLength != nil {
+				oi.Size = *b.Properties.ContentLength
+			}
+			if b.Properties.LastModified == nil {
+				return fmt.Errorf("missing Last-Modified on blob %q", oi.Key)
+			}
+			oi.Updated = *b.Properties.LastModified
+			if !fn(oi) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+func (as *azureBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	resp, err := as.client.blob.DownloadStream(ctx, as.container, key, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	var sz int64
+	if resp.ContentLength != nil {
+		sz = *resp.ContentLength
+	}
+	return resp.Body, sz, nil
+}
+
+func azureStateKey(account, container string) string {
+	return `azure://` + account + `/` + container
+}
+
+// azureMessage is a message pulled from a storage queue
+type azureMessage struct {
+	id         string
+	popReceipt string
+	text       string
+}
+
+// receiveMessages pulls messages from a queue, hiding them from other consumers until the
+// visibility timeout expires or they are deleted
+func (ac *azureClient) receiveMessages(ctx context.Context, queue string, count int, visibility time.Duration) (msgs []azureMessage, err error) {
+	n, vis := int32(count), int32(visibility/time.Second)
+	var resp azqueue.DequeueMessagesResponse
+	if resp, err = ac.queue.NewQueueClient(queue).DequeueMessages(ctx, &azqueue.DequeueMessagesOptions{
+		NumberOfMessages:  &n,
+		VisibilityTimeout: &vis,
+	}); err != nil {
+		return
+	}
+	for _, m := range resp.Messages {
+		if m == nil || m.MessageID == nil || m.PopReceipt == nil {
+			continue
+		}
+		am := azureMessage{id: *m.MessageID, popReceipt: *m.PopReceipt}
+		if m.MessageText != nil {
+			am.text = *m.MessageText
+		}
+		msgs = append(msgs, am)
+	}
+	return
+}
+
+func (ac *azureClient) deleteMessage(ctx context.Context, queue string, m azureMessage) (err error) {
+	_, err = ac.queue.NewQueueClient(queue).DeleteMessage(ctx, m.id, m.popReceipt, nil)
+	return
+}
+
+// eventGridEvent covers both the Event Grid and CloudEvents schemas
+type eventGridEvent struct {
+	EventType string `json:"eventType"` // Event Grid schema
+	Type      string `json:"type"`      // CloudEvents schema
+	Subject   string `json:"subject"`
+	Data      struct {
+		URL           string `json:"url"`
+		ContentLength *int64 `json:"contentLength"`
+	} `json:"data"`
+}
+
+// blobRef is a blob or object named in a notification
+type blobRef struct {
+	Container string // container or bucket
+	Name      string
+	Size      int64 // -1 if the notification does not say
+}
+
+// decodeEventGrid extracts created blobs from a queue message holding Event Grid events, the
+// message may be a single event or an array and may be base64 encoded
+func decodeEventGrid(msg string) (refs []blobRef, err error) {
+	body := []byte(strings.TrimSpace(msg))
+	if len(body) > 0 && body[0] != '{' && body[0] != '[' {
+		if body, err = base64.StdEncoding.DecodeString(string(body)); err != nil {
+			err = fmt.Errorf("message is neither JSON nor base64 %w", err)
+			return
+		}
+		body = bytes.TrimSpace(body)
+	}
+	var evs []eventGridEvent
+	if len(body) > 0 && body[0] == '[' {
+		err = json.Unmarshal(body, &evs)
+	} else {
+		var ev eventGridEvent
+		err = json.Unmarshal(body, &ev)
+		evs = append(evs, ev)
+	}
+	if err != nil {
+		return
+	}
+	for _, ev := range evs {
+		if ev.EventType != azureBlobCreated && ev.Type != azureBlobCreated {
+			continue
+		}
+		var ref blobRef
+		if ref, err = ev.blob(); err != nil {
+			return
+		}
+		refs = append(refs, ref)
+	}
+	return
+}
+
+// blob pulls the container and blob name from the subject, which looks like
+// /blobServices/default/containers/<container>/blobs/<name>, falling back to the blob URL
+func (ev eventGridEvent) blob() (ref blobRef, err error) {
+	ref.Size = -1 //unknown
+	if ev.Data.ContentLength != nil {
+		ref.Size = *ev.Data.ContentLength
+	}
+	const pfx = `/blobServices/default/containers/`
+	if rest, ok := strings.CutPrefix(ev.Subject, pfx); ok {
+		if ref.Container, ref.Name, ok = strings.Cut(rest, `/blobs/`); ok && ref.Container != `` && ref.Name != `` {
+			return
+		}
+	}
+	var u *url.URL
+	if u, err = url.Parse(ev.Data.URL); err != nil {
+		return
+	}
+	ref.Container, ref.Name, _ = strings.Cut(strings.TrimPrefix(u.Path, `/`), `/`)
+	if ref.Container == `` {
+		err = errEmptyBucket
+	} else if ref.Name == `` {
+		err = errEmptyKey
+	}
+	return
+}
+
+// azureQueueListener consumes Event Grid blob created notifications from a storage queue
+type azureQueueListener struct {
+	*BucketReader // reader settings, blobs are read from whatever container the event names
+	client        *azureClient
+	queue         string
+	visibility    time.Duration
+}
+
+func (al *azureQueueListener) run(wg *sync.WaitGroup, ctx context.Context, lg *log.Logger, numWorkers int) {
+	defer wg.Done()
+
+	// create workers
+	var workerWg sync.WaitGroup
+	queue := make(chan []azureMessage, QUEUE_DEPTH)
+	for i := 0; i < numWorkers; i++ {
+		workerWg.Add(1)
+		go al.worker(ctx, lg, &workerWg, queue, i)
+	}
+
+	for ctx.Err() == nil {
+		msgs, err := al.client.receiveMessages(ctx, al.queue, azureMaxMessages, al.visibility)
+		if err != nil {
+			if ctx.Err() == nil {
+				lg.Error("azure queue receive error", log.KV("name", al.Name), log.KV("queue", al.queue), log.KVErr(err))
+				sleepContext(ctx, ERROR_BACKOFF)
+			}
+			continue
+		} else if len(msgs) == 0 {
+			//storage queues do not support long polling
+			sleepContext(ctx, azurePollInterval)
+			continue
+		}
+		lg.Info("azure queue received messages", log.KV("name", al.Name), log.KV("count", len(msgs)))
+		select {
+		case queue <- msgs:
+		case <-ctx.Done():
+		}
+	}
+	lg.Info("azure event grid routine exiting", log.KV("name", al.Name))
+	close(queue)
+	workerWg.Wait()
+}
+
+func (al *azureQueueListener) worker(ctx context.Context, lg *log.Logger, wg *sync.WaitGroup, queue <-chan []azureMessage, workerID int) {
+	defer wg.Done()
+	for msgs := range queue {
+		for _, m := range msgs {
+			if al.handle(ctx, lg, m, workerID) {
+				if err := al.client.deleteMessage(ctx, al.queue, m); err != nil {
+					lg.Error("deleting message", log.KV("queue", al.queue), log.KVErr(err))
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	}
+}
+
+// handle processes every blob in a message, returning true if the message should be deleted
+func (al *azureQueueListener) handle(ctx context.Context, lg *log.Logger, m azureMessage, workerID int) bool {
+	refs, err := decodeEventGrid(m.text)
+	if err != nil {
+		//a malformed message will never succeed and storage queues have no dead letter queue, drop it
+		lg.Warn("error decoding message, dropping it", log.KV("queue", al.queue), log.KV("id", m.id), log.KVErr(err))
+		return true
+	}
+	ok := true
+	for _, ref := range refs {
+		if !al.ShouldTrack(ref.Name) {
+			lg.Info("skipping key based on filter", log.KV("key", ref.Name))
+			continue
+		} else if ref.Size == 0 {
+			continue
+		}
+		store := &azureBlobStore{client: al.client, container: ref.Container}
+		sz, fetchrtt, rtt, err := al.ProcessFrom(store, ref.Name, ctx)
+		if err != nil {
+			ok = false
+			lg.Error("error processing message", log.KV("container", ref.Container), log.KV("key", ref.Name), log.KVErr(err))
+		} else {
+			lg.Info("successfully processed message",
+				log.KV("worker", workerID),
+				log.KV("container", ref.Container),
+				log.KV("key", ref.Name),
+				log.KV("fetch-rtt", fetchrtt),
+				log.KV("rtt", rtt),
+				log.KV("size", sz))
+		}
+	}
+	return ok
+}
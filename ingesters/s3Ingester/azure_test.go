@@ -0,0 +1,494 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+)
+
+const (
+	// well known account and key of the Azurite storage emulator
+	testAzureAccount = `devstoreaccount1`
+	testAzureKey     = `Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw==`
+)
+
+type testBlob struct {
+	data    string
+	updated time.Time
+}
+
+// fakeAzure emulates the parts of the blob and queue services we use, with the account name in the
+// path the way Azurite does.  Signatures are not checked, Azurite covers that.
+type fakeAzure struct {
+	sync.Mutex
+	containers map[string]map[string]testBlob
+	messages   []azureMessage
+	deleted    []string
+}
+
+type fakeBlobList struct {
+	XMLName xml.Name `xml:"EnumerationResults"`
+	Blobs   struct {
+		Blob []fakeBlob
+	}
+	NextMarker string
+}
+
+type fakeBlob struct {
+	Name       string
+	Properties struct {
+		LastModified  string `xml:"Last-Modified"`
+		ContentLength int64  `xml:"Content-Length"`
+	}
+}
+
+type fakeMessageList struct {
+	XMLName      xml.Name `xml:"QueueMessagesList"`
+	QueueMessage []fakeMessage
+}
+
+type fakeMessage struct {
+	MessageId    string
+	PopReceipt   string
+	DequeueCount int
+	MessageText  string
+}
+
+func (fa *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fa.Lock()
+	defer fa.Unlock()
+	if !strings.HasPrefix(r.Header.Get(`Authorization`), `SharedKey `+testAzureAccount+`:`) {
+		http.Error(w, `missing signature`, http.StatusForbidden)
+		return
+	} else if r.Header.Get(`x-ms-version`) == `` {
+		http.Error(w, `missing version`, http.StatusBadRequest)
+		return
+	}
+	pth, ok := strings.CutPrefix(r.URL.Path, `/`+testAzureAccount+`/`)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	q := r.URL.Query()
+	if pth == `events/messages` {
+		fa.serveQueue(w, r)
+		return
+	} else if id, ok := strings.CutPrefix(pth, `events/messages/`); ok && r.Method == http.MethodDelete {
+		if q.Get(`popreceipt`) != `receipt-`+id {
+			http.Error(w, `bad pop receipt`, http.StatusBadRequest)
+			return
+		}
+		fa.deleted = append(fa.deleted, id)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	container, name, _ := strings.Cut(pth, `/`)
+	blobs, ok := fa.containers[container]
+	if !ok {
+		w.Header().Set(`x-ms-error-code`, `ContainerNotFound`)
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>ContainerNotFound</Code><Message>nope</Message></Error>`)
+		return
+	}
+	if name == `` && q.Get(`comp`) == `list` && q.Get(`restype`) == `container` {
+		fa.serveList(w, q, blobs)
+		return
+	}
+	b, ok := blobs[name]
+	if !ok {
+		http.Error(w, `no blob`, http.StatusNotFound)
+		return
+	}
+	fmt.Fprint(w, b.data)
+}
+
+// serveList returns one blob per page to exercise paging
+func (fa *fakeAzure) serveList(w http.ResponseWriter, q map[string][]string, blobs map[string]testBlob) {
+	var names []string
+	for k := range blobs {
+		if strings.HasPrefix(k, firstOf(q[`prefix`])) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	var idx int
+	if m := firstOf(q[`marker`]); m != `` {
+		idx, _ = strconv.Atoi(m)
+	}
+	var bl fakeBlobList
+	if idx < len(names) {
+		b := blobs[names[idx]]
+		fb := fakeBlob{Name: names[idx]}
+		fb.Properties.LastModified = b.updated.Format(http.TimeFormat)
+		fb.Properties.ContentLength = int64(len(b.data))
+		bl.Blobs.Blob = append(bl.Blobs.Blob, fb)
+		if idx+1 < len(names) {
+			bl.NextMarker = strconv.Itoa(idx + 1)
+		}
+	}
+	w.Header().Set(`Content-Type`, `application/xml`)
+	xml.NewEncoder(w).Encode(bl)
+}
+
+func (fa *fakeAzure) serveQueue(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get(`visibilitytimeout`) == `` {
+		http.Error(w, `missing visibility timeout`, http.StatusBadRequest)
+		return
+	}
+	var ml fakeMessageList
+	for _, m := range fa.messages {
+		ml.QueueMessage = append(ml.QueueMessage, fakeMessage{MessageId: m.id, PopReceipt: m.popReceipt, DequeueCount: 1, MessageText: m.text})
+	}
+	fa.messages = nil
+	w.Header().Set(`Content-Type`, `application/xml`)
+	xml.NewEncoder(w).Encode(ml)
+}
+
+func firstOf(v []string) string {
+	if len(v) == 0 {
+		return ``
+	}
+	return v[0]
+}
+
+func newFakeAzure(t *testing.T) (*fakeAzure, *azureClient) {
+	fa := &fakeAzure{
+		containers: map[string]map[string]testBlob{},
+	}
+	srv := httptest.NewServer(fa)
+	t.Cleanup(srv.Close)
+	aa := azureAuth{
+		Storage_Account: testAzureAccount,
+		Account_Key:     testAzureKey,
+		Blob_Endpoint:   srv.URL + `/` + testAzureAccount,
+	}
+	client, err := newAzureClient(aa, srv.URL+`/`+testAzureAccount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fa, client
+}
+
+func newTestBucketConfig(name string, tw *testWriter) BucketConfig {
+	return BucketConfig{
+		TimeConfig: TimeConfig{Ignore_Timestamps: true},
+		Name:       name,
+		Proc:       processors.NewProcessorSet(tw),
+		Logger:     log.NewDiscardLogger(),
+	}
+}
+
+func entryData(tw *testWriter) (r []string) {
+	for _, ent := range tw.ents {
+		r = append(r, string(ent.Data))
+	}
+	sort.Strings(r)
+	return
+}
+
+func TestAzureDefaultEndpoints(t *testing.T) {
+	client, err := newAzureClient(azureAuth{Storage_Account: testAzureAccount, Account_Key: testAzureKey}, ``)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := client.blob.URL(); u != `https://devstoreaccount1.blob.core.windows.net` {
+		t.Fatalf("bad default blob endpoint %s", u)
+	} else if u = client.queue.URL(); u != `https://devstoreaccount1.queue.core.windows.net` {
+		t.Fatalf("bad default queue endpoint %s", u)
+	}
+}
+
+func TestAzureAuthValidate(t *testing.T) {
+	good := []azureAuth{
+		{Storage_Account: `a`, Account_Key: testAzureKey},
+		{Storage_Account: `a`, SAS_Token: `?sv=2021-08-06&sig=abc`},
+		{Storage_Account: `a`, SAS_Token: `sv=2021-08-06&sig=abc`, Blob_Endpoint: `http://127.0.0.1:10000/a`},
+	}
+	for _, v := range good {
+		if err := v.validate(); err != nil {
+			t.Fatalf("%+v failed %v", v, err)
+		}
+	}
+	bad := []azureAuth{
+		{Account_Key: testAzureKey},
+		{Storage_Account: `a`},
+		{Storage_Account: `a`, Account_Key: testAzureKey, SAS_Token: `sig=abc`},
+		{Storage_Account: `a`, Account_Key: `not base64!`},
+		{Storage_Account: `a`, Account_Key: testAzureKey, Blob_Endpoint: `ftp://foo`},
+	}
+	for _, v := range bad {
+		if err := v.validate(); err == nil {
+			t.Fatalf("%+v did not fail", v)
+		}
+	}
+}
+
+func TestAzureSAS(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(`Authorization`) != `` {
+			t.Error("SAS requests should not be signed")
+		}
+		got = r.URL.RawQuery
+		fmt.Fprint(w, `hello`)
+	}))
+	defer srv.Close()
+	client, err := newAzureClient(azureAuth{Storage_Account: `a`, SAS_Token: `?sv=2021-08-06&sig=a%2Bb`, Blob_Endpoint: srv.URL}, ``)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr, _, err := (&azureBlobStore{client: client, container: `logs`}).Open(context.Background(), `x.log`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdr.Close()
+	if q, err := url.ParseQuery(got); err != nil || q.Get(`sig`) != `a+b` || q.Get(`sv`) != `2021-08-06` {
+		t.Fatalf("bad SAS query %q", got)
+	}
+}
+
+func TestAzureContainerScan(t *testing.T) {
+	fa, client := newFakeAzure(t)
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	fa.containers[`logs`] = map[string]testBlob{
+		`2025/03/01/a.log`:  {data: "a1\na2\n", updated: ts},
+		`2025/03/01/b c.js`: {data: "b1\n", updated: ts},
+		`empty.log`:         {updated: ts},
+	}
+	ot, err := NewObjectTracker(filepath.Join(t.TempDir(), `state`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tw testWriter
+	cfg := newTestBucketConfig(`azure`, &tw)
+	cfg.FileFilters = []string{`**/*.log`}
+	br, err := newObjectReader(cfg, &azureBlobStore{client: client, container: `logs`}, azureStateKey(testAzureAccount, `logs`))
+	if err != nil {
+		t.Fatal(err)
+	} else if err = br.Test(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lg := log.NewDiscardLogger()
+	fullScan(context.Background(), []*BucketReader{br}, ot, lg, 1)
+	if got := entryData(&tw); strings.Join(got, `,`) != `a1,a2` {
+		t.Fatalf("bad entries %v", got)
+	}
+	if _, ok := ot.Get(`azure://devstoreaccount1/logs`, `2025/03/01/a.log`); !ok {
+		t.Fatal("blob not tracked")
+	}
+
+	//nothing new
+	fullScan(context.Background(), []*BucketReader{br}, ot, lg, 1)
+	if len(tw.ents) != 2 {
+		t.Fatalf("blobs were read twice: %d", len(tw.ents))
+	}
+
+	//a rewritten blob is read again
+	fa.Lock()
+	fa.containers[`logs`][`2025/03/01/a.log`] = testBlob{data: "a3\n", updated: ts.Add(time.Hour)}
+	fa.Unlock()
+	fullScan(context.Background(), []*BucketReader{br}, ot, lg, 1)
+	if got := entryData(&tw); strings.Join(got, `,`) != `a1,a2,a3` {
+		t.Fatalf("bad entries %v", got)
+	}
+
+	//errors are reported
+	missing := &azureBlobStore{client: client, container: `missing`}
+	if err = missing.List(context.Background(), ``, func(objectInfo) bool { return true }); !bloberror.HasCode(err, bloberror.ContainerNotFound) {
+		t.Fatalf("bad error %v", err)
+	}
+}
+
+func TestDecodeEventGrid(t *testing.T) {
+	eg := `{"topic":"/subscriptions/x/resourceGroups/y/providers/Microsoft.Storage/storageAccounts/acct",
+		"subject":"/blobServices/default/containers/insights-logs/blobs/resourceId=/SUBSCRIPTIONS/X/y=2025/PT1H.json",
+		"eventType":"Microsoft.Storage.BlobCreated","id":"1","data":{"api":"PutBlockList","contentLength":524,
+		"url":"https://acct.blob.core.windows.net/insights-logs/resourceId=/SUBSCRIPTIONS/X/y=2025/PT1H.json"},
+		"dataVersion":"","metadataVersion":"1","eventTime":"2025-03-01T12:00:00Z"}`
+	ce := `{"specversion":"1.0","type":"Microsoft.Storage.BlobCreated",
+		"source":"/subscriptions/x/resourceGroups/y/providers/Microsoft.Storage/storageAccounts/acct",
+		"id":"2","time":"2025-03-01T12:00:00Z","data":{"url":"https://acct.blob.core.windows.net/logs/a%20b.log"}}`
+	deleted := `{"subject":"/blobServices/default/containers/logs/blobs/old.log","eventType":"Microsoft.Storage.BlobDeleted","data":{}}`
+
+	refs, err := decodeEventGrid(eg)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(refs) != 1 || refs[0] != (blobRef{Container: `insights-logs`, Name: `resourceId=/SUBSCRIPTIONS/X/y=2025/PT1H.json`, Size: 524}) {
+		t.Fatalf("bad refs %+v", refs)
+	}
+	//queue messages are frequently base64 encoded, and may carry arrays of events
+	msg := base64.StdEncoding.EncodeToString([]byte(`[` + ce + `,` + deleted + `]`))
+	if refs, err = decodeEventGrid(msg); err != nil {
+		t.Fatal(err)
+	} else if len(refs) != 1 || refs[0] != (blobRef{Container: `logs`, Name: `a b.log`, Size: -1}) {
+		t.Fatalf("bad refs %+v", refs)
+	}
+	if refs, err = decodeEventGrid(deleted); err != nil || len(refs) != 0 {
+		t.Fatalf("deleted blobs should be ignored %v %v", refs, err)
+	}
+	for _, v := range []string{`not json`, `{"eventType":"Microsoft.Storage.BlobCreated","data":{"url":"https://acct.blob.core.windows.net/"}}`} {
+		if _, err = decodeEventGrid(v); err == nil {
+			t.Fatalf("%q did not fail", v)
+		}
+	}
+}
+
+func TestAzureEventGridListener(t *testing.T) {
+	fa, client := newFakeAzure(t)
+	fa.containers[`logs`] = map[string]testBlob{
+		`a.log`:    {data: "a1\n"},
+		`skip.txt`: {data: "nope\n"},
+	}
+	fa.messages = []azureMessage{
+		{id: `1`, popReceipt: `receipt-1`, text: `{"subject":"/blobServices/default/containers/logs/blobs/a.log","eventType":"Microsoft.Storage.BlobCreated","data":{"contentLength":3}}`},
+		{id: `2`, popReceipt: `receipt-2`, text: `{"subject":"/blobServices/default/containers/logs/blobs/skip.txt","eventType":"Microsoft.Storage.BlobCreated","data":{"contentLength":5}}`},
+		{id: `3`, popReceipt: `receipt-3`, text: `garbage`},
+		{id: `4`, popReceipt: `receipt-4`, text: `{"subject":"/blobServices/default/containers/logs/blobs/gone.log","eventType":"Microsoft.Storage.BlobCreated","data":{"contentLength":3}}`},
+	}
+	var tw testWriter
+	cfg := newTestBucketConfig(`eventgrid`, &tw)
+	cfg.FileFilters = []string{`*.log`}
+	br, err := newObjectReader(cfg, nil, ``)
+	if err != nil {
+		t.Fatal(err)
+	}
+	al := &azureQueueListener{BucketReader: br, client: client, queue: `events`, visibility: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go al.run(&wg, ctx, log.NewDiscardLogger(), 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		fa.Lock()
+		n := len(fa.deleted)
+		fa.Unlock()
+		if n == 3 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("messages not deleted, %d", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+	//the message naming a missing blob stays on the queue to be retried
+	if d := strings.Join(fa.deleted, `,`); d != `1,2,3` {
+		t.Fatalf("bad deletes %s", d)
+	}
+	if got := entryData(&tw); strings.Join(got, `,`) != `a1` {
+		t.Fatalf("bad entries %v", got)
+	}
+}
+
+// azuriteClient connects to the Azurite emulator named by AZURITE_BLOB_ENDPOINT and AZURITE_QUEUE_ENDPOINT,
+// e.g. http://127.0.0.1:10000/devstoreaccount1 and http://127.0.0.1:10001/devstoreaccount1
+func azuriteClient(t *testing.T) *azureClient {
+	blob, queue := os.Getenv(`AZURITE_BLOB_ENDPOINT`), os.Getenv(`AZURITE_QUEUE_ENDPOINT`)
+	if blob == `` || queue == `` {
+		t.Skip("AZURITE_BLOB_ENDPOINT and AZURITE_QUEUE_ENDPOINT are not set")
+	}
+	client, err := newAzureClient(azureAuth{Storage_Account: testAzureAccount, Account_Key: testAzureKey, Blob_Endpoint: blob}, queue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestAzurite(t *testing.T) {
+	client := azuriteClient(t)
+	ctx := context.Background()
+	name := fmt.Sprintf("gravwell-%d", time.Now().UnixNano())
+	if _, err := client.blob.CreateContainer(ctx, name, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer client.blob.DeleteContainer(ctx, name, nil)
+	for k, v := range map[string]string{`a/1.log`: "a1\na2\n", `a/b c.log`: "b1\n", `skip.txt`: "nope\n"} {
+		if _, err := client.blob.UploadBuffer(ctx, name, k, []byte(v), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ot, err := NewObjectTracker(filepath.Join(t.TempDir(), `state`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tw testWriter
+	cfg := newTestBucketConfig(`azurite`, &tw)
+	cfg.FileFilters = []string{`**/*.log`}
+	br, err := newObjectReader(cfg, &azureBlobStore{client: client, container: name}, azureStateKey(testAzureAccount, name))
+	if err != nil {
+		t.Fatal(err)
+	} else if err = br.Test(ctx); err != nil {
+		t.Fatal(err)
+	}
+	fullScan(ctx, []*BucketReader{br}, ot, log.NewDiscardLogger(), 1)
+	if got := entryData(&tw); strings.Join(got, `,`) != `a1,a2,b1` {
+		t.Fatalf("bad entries %v", got)
+	}
+
+	//event grid delivers base64 encoded messages
+	qc := client.queue.NewQueueClient(name)
+	if _, err = qc.Create(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer qc.Delete(ctx, nil)
+	for _, m := range []string{
+		base64.StdEncoding.EncodeToString([]byte(`{"subject":"/blobServices/default/containers/` + name + `/blobs/a/b c.log","eventType":"Microsoft.Storage.BlobCreated","data":{"contentLength":3}}`)),
+		`garbage`,
+	} {
+		if _, err = qc.EnqueueMessage(ctx, m, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.ents = nil
+	if br, err = newObjectReader(cfg, nil, ``); err != nil {
+		t.Fatal(err)
+	}
+	al := &azureQueueListener{BucketReader: br, client: client, queue: name, visibility: time.Minute}
+	lctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go al.run(&wg, lctx, log.NewDiscardLogger(), 1)
+	//the approximate count includes messages that are hidden while being processed
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		props, err := qc.GetProperties(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		} else if props.ApproximateMessagesCount != nil && *props.ApproximateMessagesCount == 0 {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("messages not deleted")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+	if got := entryData(&tw); strings.Join(got, `,`) != `b1` {
+		t.Fatalf("bad entries %v", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
 	"time"
@@ -56,11 +57,14 @@ type BucketConfig struct {
 	Credentials_Type string
 }
 
+// BucketReader scans a bucket or container, the store handles the specifics of each cloud
 type BucketReader struct {
 	BucketConfig
 	prefixFilter string
 	session      *session.Session
 	svc          *s3.S3
+	store        objectStore
+	stateKey     string // name of the bucket in the object tracker
 	filter       *matcher
 	tg           timegrinder.TimeGrinder
 	src          net.IP
@@ -68,8 +72,28 @@ type BucketReader struct {
 }
 
 func NewBucketReader(cfg BucketConfig) (br *BucketReader, err error) {
-	var rdr reader
 	var sess *session.Session
+	c, err := sqs_common.GetCredentials(cfg.Credentials_Type, cfg.ID, cfg.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if sess, err = cfg.AuthConfig.getSession(cfg, c); err != nil {
+		err = fmt.Errorf("Failed to create S3 session %w", err)
+		return
+	}
+	svc := s3.New(sess)
+	if br, err = newObjectReader(cfg, &s3Store{svc: svc, bucket: cfg.Bucket_Name}, cfg.Bucket_Name); err != nil {
+		return
+	}
+	br.session = sess
+	br.svc = svc
+	return
+}
+
+// newObjectReader builds a reader for any object store, the state key must be unique across
+// every bucket and container because they all share the object tracker
+func newObjectReader(cfg BucketConfig, store objectStore, stateKey string) (br *BucketReader, err error) {
+	var rdr reader
 	if err = cfg.validate(); err != nil {
 		return
 	}
@@ -80,19 +104,10 @@ func NewBucketReader(cfg BucketConfig) (br *BucketReader, err error) {
 	if rdr, err = parseReader(cfg.Reader); err != nil {
 		return
 	}
-	c, err := sqs_common.GetCredentials(cfg.Credentials_Type, cfg.ID, cfg.Secret)
-	if err != nil {
-		return nil, err
-	}
-	if sess, err = cfg.AuthConfig.getSession(cfg, c); err != nil {
-		err = fmt.Errorf("Failed to create S3 session %w", err)
-		return
-	}
-
 	br = &BucketReader{
 		BucketConfig: cfg,
-		session:      sess,
-		svc:          s3.New(sess),
+		store:        store,
+		stateKey:     stateKey,
 		filter:       filter,
 		src:          cfg.srcOverride(),
 		rdr:          rdr,
@@ -102,9 +117,7 @@ func NewBucketReader(cfg BucketConfig) (br *BucketReader, err error) {
 }
 
 func (bc *BucketConfig) validate() (err error) {
-	if err = bc.AuthConfig.validate(); err != nil {
-		return
-	} else if err = bc.TimeConfig.validate(); err != nil {
+	if err = bc.TimeConfig.validate(); err != nil {
 		return
 	} else if bc.Proc == nil {
 		err = errors.New("processor is empty")
@@ -137,12 +150,8 @@ func (bc *BucketConfig) srcOverride() net.IP {
 
 func (br *BucketReader) Test(ctx context.Context) error {
 	//list the objects in the bucket
-	req := s3.ListObjectsV2Input{
-		Bucket:  aws.String(br.Bucket_Name),
-		MaxKeys: aws.Int64(1), //just need one to check
-	}
-	return br.svc.ListObjectsV2Pages(&req, func(resp *s3.ListObjectsV2Output, lastPage bool) bool {
-		return false //do not continue the scan
+	return br.store.List(ctx, ``, func(objectInfo) bool {
+		return false //just need one to check, do not continue the scan
 	})
 }
 
@@ -154,77 +163,60 @@ func (br *BucketReader) ShouldTrack(obj string) (ok bool) {
 }
 
 // Process reads the object in and processes its contents
-func (br *BucketReader) Process(obj *s3.Object, ctx context.Context) (sz int64, s3rtt, rtt time.Duration, err error) {
-	return ProcessContext(obj, ctx, br.svc, br.Bucket_Name, br.rdr, br.TG, br.src, br.Tag, br.Proc, br.MaxLineSize)
+func (br *BucketReader) Process(key string, ctx context.Context) (sz int64, fetchrtt, rtt time.Duration, err error) {
+	return br.ProcessFrom(br.store, key, ctx)
 }
 
-func (br *BucketReader) ManualScan(lg *log.Logger, ctx context.Context, ot *objectTracker, queue chan<- *s3.Object) (err error) {
-	lg.Info("manual scan started", log.KV("bucket", br.Name))
+// ProcessFrom reads an object from another store using this reader's settings, notification
+// listeners use it because they only learn the bucket from each notification
+func (br *BucketReader) ProcessFrom(store objectStore, key string, ctx context.Context) (sz int64, fetchrtt, rtt time.Duration, err error) {
+	return ProcessContext(ctx, store, key, br.rdr, br.TG, br.src, br.Tag, br.Proc, br.MaxLineSize, br.Timestamp_Field)
+}
 
-	//list the objects in the bucket
-	req := s3.ListObjectsV2Input{
-		Bucket: aws.String(br.Bucket_Name),
-	}
-	if br.prefixFilter != `` {
-		req.Prefix = aws.String(br.prefixFilter)
-	}
+func (br *BucketReader) ManualScan(lg *log.Logger, ctx context.Context, ot *objectTracker, queue chan<- objectInfo) (err error) {
+	lg.Info("manual scan started", log.KV("bucket", br.Name))
 
 	var count uint64
-
-	var lerr error
-	objListHandler := func(resp *s3.ListObjectsV2Output, lastPage bool) bool {
-		for _, item := range resp.Contents {
-			select {
-			case queue <- item:
-				count++
-			case <-ctx.Done():
-				return false
-			}
-			if ctx.Err() != nil {
-				return false
-			}
+	err = br.store.List(ctx, br.prefixFilter, func(item objectInfo) bool {
+		select {
+		case queue <- item:
+			count++
+		case <-ctx.Done():
+			return false
 		}
-		return true
-	}
-
-	if err = br.svc.ListObjectsV2Pages(&req, objListHandler); err == nil {
-		err = lerr
-	}
+		return ctx.Err() == nil
+	})
 
 	lg.Info("manual scan completed", log.KV("bucket", br.Name), log.KV("object_count", count))
 	return
 }
 
-func (br *BucketReader) worker(lg *log.Logger, ctx context.Context, ot *objectTracker, queue <-chan *s3.Object, wg *sync.WaitGroup) {
+func (br *BucketReader) worker(lg *log.Logger, ctx context.Context, ot *objectTracker, queue <-chan objectInfo, wg *sync.WaitGroup) {
 	lg.Info("manual scan worker started", log.KV("bucket", br.Name))
 	defer wg.Done()
 
 	var processed, alreadyProcessed, skipped, errored uint64
 
 	for item := range queue {
-		if item == nil {
-			return
-		}
-
 		//do a quick check for stupidity
-		if item.Size == nil || item.LastModified == nil || item.Key == nil {
+		if item.Key == `` || item.Updated.IsZero() {
 			skipped++
 			continue
 		}
-		sz, lm, key := *item.Size, *item.LastModified, *item.Key
+		sz, lm, key := item.Size, item.Updated, item.Key
 		if sz == 0 || !br.ShouldTrack(key) {
 			skipped++
 			continue //skip empty objects or things we should not track
 		}
 		//lookup the object in the objectTracker
-		state, ok := ot.Get(br.Bucket_Name, key)
+		state, ok := ot.Get(br.stateKey, key)
 		if ok && state.Updated.Equal(lm) {
 			alreadyProcessed++
 			continue //already handled this
 		}
 
 		//ok, lets process this thing
-		if objsz, s3rtt, rtt, err := br.Process(item, ctx); err != nil {
+		if objsz, fetchrtt, rtt, err := br.Process(key, ctx); err != nil {
 			br.Logger.Error("failed to process object",
 				log.KV("name", br.Name),
 				log.KV("object", key),
@@ -237,7 +229,7 @@ func (br *BucketReader) worker(lg *log.Logger, ctx context.Context, ot *objectTr
 				log.KV("name", br.Name),
 				log.KV("object", key),
 				log.KV("tag", br.TagName),
-				log.KV("s3-rtt", s3rtt),
+				log.KV("fetch-rtt", fetchrtt),
 				log.KV("rtt", rtt),
 				log.KV("size", objsz))
 			processed++
@@ -246,7 +238,7 @@ func (br *BucketReader) worker(lg *log.Logger, ctx context.Context, ot *objectTr
 			Updated: lm,
 			Size:    sz,
 		}
-		err := ot.Set(br.Bucket_Name, key, state, false)
+		err := ot.Set(br.stateKey, key, state, false)
 		if err != nil {
 			br.Logger.Error("failed to update state",
 				log.KV("name", br.Name),
@@ -344,3 +336,40 @@ func (ac *AuthConfig) getSession(lgr aws.Logger, c *credentials.Credentials) (se
 	sess, err = session.NewSession(&cfg)
 	return
 }
+
+// s3Store reads objects from a single S3 bucket
+type s3Store struct {
+	svc    *s3.S3
+	bucket string
+}
+
+func (ss *s3Store) List(ctx context.Context, prefix string, fn func(objectInfo) bool) error {
+	req := s3.ListObjectsV2Input{
+		Bucket: aws.String(ss.bucket),
+	}
+	if prefix != `` {
+		req.Prefix = aws.String(prefix)
+	}
+	return ss.svc.ListObjectsV2PagesWithContext(ctx, &req, func(resp *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, item := range resp.Contents {
+			if item == nil || item.Key == nil {
+				continue
+			}
+			if !fn(objectInfo{Key: *item.Key, Size: aws.Int64Value(item.Size), Updated: aws.TimeValue(item.LastModified)}) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (ss *s3Store) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	r, err := ss.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(ss.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return r.Body, aws.Int64Value(r.ContentLength), nil
+}
@@ -29,6 +29,10 @@ type TimeConfig struct {
 	Assume_Local_Timezone     bool
 	Timezone_Override         string
 	Timestamp_Format_Override string //override the timestamp format
+	Max_Future_Skew           string //how far into the future a timestamp may be, e.g. 1h
+	Max_Past_Skew             string //how far into the past a timestamp may be, e.g. 720h
+	Skew_Policy               string //ignore, now, clamp, or mark
+	Timestamp_Field           string //record field holding the timestamp for the json, csv, parquet, and vpcflow readers
 }
 
 type bucket struct {
@@ -60,6 +64,47 @@ type sqsS3 struct {
 	Source_Override  string
 }
 
+// objectConfig holds the reader settings shared by the Azure and GCS sections
+type objectConfig struct {
+	Reader          string //defaults to line
+	Tag_Name        string
+	Source_Override string
+	File_Filters    []string
+	Preprocessor    []string
+	Max_Line_Size   int
+}
+
+type azureContainer struct {
+	TimeConfig
+	azureAuth
+	objectConfig
+	Container string
+}
+
+type azureEventGrid struct {
+	TimeConfig
+	azureAuth
+	objectConfig
+	Queue_Name         string // storage queue the Event Grid subscription delivers to
+	Queue_Endpoint     string // defaults to https://<account>.queue.core.windows.net
+	Visibility_Timeout string // how long a message is hidden while its blobs are read
+}
+
+type gcsBucket struct {
+	TimeConfig
+	gcsAuth
+	objectConfig
+	Bucket_Name string
+}
+
+type gcsPubSub struct {
+	TimeConfig
+	gcsAuth
+	objectConfig
+	Project_ID        string
+	Subscription_Name string // subscription attached to the bucket notification topic
+}
+
 type global struct {
 	config.IngestConfig
 	State_Store_Location string
@@ -67,23 +112,31 @@ type global struct {
 }
 
 type cfgReadType struct {
-	Global          global
-	Attach          attach.AttachConfig
-	Bucket          map[string]*bucket
-	SQS_S3_Listener map[string]*sqsS3
-	Preprocessor    processors.ProcessorConfig
-	TimeFormat      config.CustomTimeFormat
+	Global                    global
+	Attach                    attach.AttachConfig
+	Bucket                    map[string]*bucket
+	SQS_S3_Listener           map[string]*sqsS3
+	Azure_Container           map[string]*azureContainer
+	Azure_Event_Grid_Listener map[string]*azureEventGrid
+	GCS_Bucket                map[string]*gcsBucket
+	GCS_PubSub_Listener       map[string]*gcsPubSub
+	Preprocessor              processors.ProcessorConfig
+	TimeFormat                config.CustomTimeFormat
 }
 
 type cfgType struct {
 	config.IngestConfig
-	Attach               attach.AttachConfig
-	State_Store_Location string
-	Worker_Pool_Size     int
-	Bucket               map[string]*bucket
-	SQS_S3_Listener      map[string]*sqsS3
-	Preprocessor         processors.ProcessorConfig
-	TimeFormat           config.CustomTimeFormat
+	Attach                    attach.AttachConfig
+	State_Store_Location      string
+	Worker_Pool_Size          int
+	Bucket                    map[string]*bucket
+	SQS_S3_Listener           map[string]*sqsS3
+	Azure_Container           map[string]*azureContainer
+	Azure_Event_Grid_Listener map[string]*azureEventGrid
+	GCS_Bucket                map[string]*gcsBucket
+	GCS_PubSub_Listener       map[string]*gcsPubSub
+	Preprocessor              processors.ProcessorConfig
+	TimeFormat                config.CustomTimeFormat
 }
 
 func GetConfig(path, overlayPath string) (*cfgType, error) {
@@ -95,14 +148,18 @@ func GetConfig(path, overlayPath string) (*cfgType, error) {
 		return nil, err
 	}
 	c := &cfgType{
-		IngestConfig:         cr.Global.IngestConfig,
-		Attach:               cr.Attach,
-		State_Store_Location: cr.Global.State_Store_Location,
-		Worker_Pool_Size:     cr.Global.Worker_Pool_Size,
-		Bucket:               cr.Bucket,
-		SQS_S3_Listener:      cr.SQS_S3_Listener,
-		Preprocessor:         cr.Preprocessor,
-		TimeFormat:           cr.TimeFormat,
+		IngestConfig:              cr.Global.IngestConfig,
+		Attach:                    cr.Attach,
+		State_Store_Location:      cr.Global.State_Store_Location,
+		Worker_Pool_Size:          cr.Global.Worker_Pool_Size,
+		Bucket:                    cr.Bucket,
+		SQS_S3_Listener:           cr.SQS_S3_Listener,
+		Azure_Container:           cr.Azure_Container,
+		Azure_Event_Grid_Listener: cr.Azure_Event_Grid_Listener,
+		GCS_Bucket:                cr.GCS_Bucket,
+		GCS_PubSub_Listener:       cr.GCS_PubSub_Listener,
+		Preprocessor:              cr.Preprocessor,
+		TimeFormat:                cr.TimeFormat,
 	}
 
 	// Verify and set UUID
@@ -127,7 +184,8 @@ func (c *cfgType) Verify() error {
 		c.Worker_Pool_Size = 1
 	}
 
-	if len(c.Bucket) == 0 && len(c.SQS_S3_Listener) == 0 {
+	if len(c.Bucket) == 0 && len(c.SQS_S3_Listener) == 0 && len(c.Azure_Container) == 0 &&
+		len(c.Azure_Event_Grid_Listener) == 0 && len(c.GCS_Bucket) == 0 && len(c.GCS_PubSub_Listener) == 0 {
 		return errors.New("No listeners specified")
 	}
 	if c.State_Store_Location == `` {
@@ -209,31 +267,132 @@ func (c *cfgType) Verify() error {
 		}
 	}
 
+	for k, v := range c.Azure_Container {
+		if err := v.objectConfig.verify(k, v.TimeConfig, c.Preprocessor); err != nil {
+			return err
+		} else if err = v.azureAuth.validate(); err != nil {
+			return fmt.Errorf("Azure-Container %s: %w", k, err)
+		} else if v.Container == `` {
+			return fmt.Errorf("Azure-Container %s is missing Container", k)
+		}
+	}
+
+	for k, v := range c.Azure_Event_Grid_Listener {
+		if err := v.objectConfig.verify(k, v.TimeConfig, c.Preprocessor); err != nil {
+			return err
+		} else if err = v.azureAuth.validate(); err != nil {
+			return fmt.Errorf("Azure-Event-Grid-Listener %s: %w", k, err)
+		} else if v.Queue_Name == `` {
+			return fmt.Errorf("Azure-Event-Grid-Listener %s is missing Queue-Name", k)
+		} else if _, err = v.visibility(); err != nil {
+			return fmt.Errorf("Azure-Event-Grid-Listener %s: %w", k, err)
+		}
+		if v.Queue_Endpoint != `` {
+			if _, err := parseEndpoint(v.Queue_Endpoint); err != nil {
+				return fmt.Errorf("Azure-Event-Grid-Listener %s: %w", k, err)
+			}
+		}
+	}
+
+	for k, v := range c.GCS_Bucket {
+		if err := v.objectConfig.verify(k, v.TimeConfig, c.Preprocessor); err != nil {
+			return err
+		} else if err = v.gcsAuth.validate(); err != nil {
+			return fmt.Errorf("GCS-Bucket %s: %w", k, err)
+		} else if v.Bucket_Name == `` {
+			return fmt.Errorf("GCS-Bucket %s is missing Bucket-Name", k)
+		}
+	}
+
+	for k, v := range c.GCS_PubSub_Listener {
+		if err := v.objectConfig.verify(k, v.TimeConfig, c.Preprocessor); err != nil {
+			return err
+		} else if err = v.gcsAuth.validate(); err != nil {
+			return fmt.Errorf("GCS-PubSub-Listener %s: %w", k, err)
+		} else if v.Project_ID == `` {
+			return fmt.Errorf("GCS-PubSub-Listener %s: %w", k, ErrMissingProjectID)
+		} else if v.Subscription_Name == `` {
+			return fmt.Errorf("GCS-PubSub-Listener %s: %w", k, ErrMissingSubscription)
+		}
+	}
+
+	return nil
+}
+
+// verify checks the reader settings of an Azure or GCS section
+func (oc *objectConfig) verify(name string, tc TimeConfig, pp processors.ProcessorConfig) error {
+	if len(oc.Tag_Name) == 0 {
+		oc.Tag_Name = entry.DefaultTagName
+	}
+	if ingest.CheckTag(oc.Tag_Name) != nil {
+		return errors.New("Invalid characters in the Tag-Name for " + name)
+	}
+	if tc.Timezone_Override != "" && tc.Assume_Local_Timezone {
+		// cannot do both
+		return fmt.Errorf("Cannot specify Assume-Local-Timezone and Timezone-Override in the same listener %v", name)
+	}
+	if err := tc.validate(); err != nil {
+		return fmt.Errorf("Invalid time settings in listener %v: %v", name, err)
+	}
+	if oc.Source_Override != `` {
+		if net.ParseIP(oc.Source_Override) == nil {
+			return fmt.Errorf("Source-Override %s is not a valid IP address", oc.Source_Override)
+		}
+	}
+	if err := pp.CheckProcessors(oc.Preprocessor); err != nil {
+		return fmt.Errorf("Listener %s preprocessor invalid: %v", name, err)
+	}
+	if _, err := newMatcher(oc.File_Filters); err != nil {
+		return fmt.Errorf("Listener %s: %v", name, err)
+	}
+	if _, err := parseReader(oc.Reader); err != nil {
+		return fmt.Errorf("Invalid Reader %q - %v", oc.Reader, err)
+	}
 	return nil
 }
 
+func (eg *azureEventGrid) visibility() (d time.Duration, err error) {
+	if eg.Visibility_Timeout == `` {
+		return defaultAzureVisibility, nil
+	}
+	if d, err = time.ParseDuration(eg.Visibility_Timeout); err != nil {
+		err = fmt.Errorf("Invalid Visibility-Timeout %q: %w", eg.Visibility_Timeout, err)
+	} else if d < time.Second || d > maxAzureVisibility {
+		err = fmt.Errorf("Visibility-Timeout %v must be between 1s and %v", d, maxAzureVisibility)
+	}
+	return
+}
+
 func (c *cfgType) Tags() ([]string, error) {
 	var tags []string
 	tagMp := make(map[string]bool, 1)
-
-	for _, v := range c.Bucket {
-		if len(v.Tag_Name) == 0 {
-			continue
+	addTag := func(tag string) {
+		if len(tag) == 0 {
+			return
 		}
-		if _, ok := tagMp[v.Tag_Name]; !ok {
-			tags = append(tags, v.Tag_Name)
-			tagMp[v.Tag_Name] = true
+		if _, ok := tagMp[tag]; !ok {
+			tags = append(tags, tag)
+			tagMp[tag] = true
 		}
 	}
 
+	for _, v := range c.Bucket {
+		addTag(v.Tag_Name)
+	}
 	for _, v := range c.SQS_S3_Listener {
-		if len(v.Tag_Name) == 0 {
-			continue
-		}
-		if _, ok := tagMp[v.Tag_Name]; !ok {
-			tags = append(tags, v.Tag_Name)
-			tagMp[v.Tag_Name] = true
-		}
+		addTag(v.Tag_Name)
+	}
+	for _, v := range c.Azure_Container {
+		addTag(v.Tag_Name)
+	}
+	for _, v := range c.Azure_Event_Grid_Listener {
+		addTag(v.Tag_Name)
+	}
+	for _, v := range c.GCS_Bucket {
+		addTag(v.Tag_Name)
+	}
+	for _, v := range c.GCS_PubSub_Listener {
+		addTag(v.Tag_Name)
 	}
 
 	if len(tags) == 0 {
@@ -255,6 +414,9 @@ func (c *cfgType) newTimeGrinder(tc TimeConfig) (tg *timegrinder.TimeGrinder, er
 	tcfg := timegrinder.Config{
 		EnableLeftMostSeed: true,
 	}
+	if tcfg.MaxFutureSkew, tcfg.MaxPastSkew, tcfg.SkewPolicy, err = tc.skew(); err != nil {
+		return
+	}
 	if tg, err = timegrinder.NewTimeGrinder(tcfg); err != nil {
 		err = fmt.Errorf("failed to get a handle on the timegrinder %w", err)
 		return
@@ -306,6 +468,9 @@ func (g *global) verifyStateStore() (err error) {
 }
 
 func (tc TimeConfig) validate() (err error) {
+	if _, _, _, err = tc.skew(); err != nil {
+		return
+	}
 	if tc.Timezone_Override != `` {
 		if _, err = time.LoadLocation(tc.Timezone_Override); err != nil {
 			return
@@ -324,3 +489,25 @@ func (tc TimeConfig) validate() (err error) {
 	}
 	return
 }
+
+// skew parses the timestamp skew limits and the policy applied to timestamps outside of them
+func (tc TimeConfig) skew() (future, past time.Duration, policy timegrinder.SkewPolicy, err error) {
+	if tc.Max_Future_Skew != `` {
+		if future, err = time.ParseDuration(tc.Max_Future_Skew); err != nil {
+			err = fmt.Errorf("Invalid Max-Future-Skew %q: %w", tc.Max_Future_Skew, err)
+			return
+		}
+	}
+	if tc.Max_Past_Skew != `` {
+		if past, err = time.ParseDuration(tc.Max_Past_Skew); err != nil {
+			err = fmt.Errorf("Invalid Max-Past-Skew %q: %w", tc.Max_Past_Skew, err)
+			return
+		}
+	}
+	if future < 0 || past < 0 {
+		err = timegrinder.ErrInvalidSkew
+	} else if policy, err = timegrinder.ParseSkewPolicy(tc.Skew_Policy); err == nil && policy != timegrinder.SkewIgnore && future == 0 && past == 0 {
+		err = fmt.Errorf("Skew-Policy %s requires Max-Future-Skew or Max-Past-Skew", policy)
+	}
+	return
+}
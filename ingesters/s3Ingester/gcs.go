@@ -0,0 +1,220 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/storage"
+	"github.com/gravwell/gravwell/v3/ingest/log"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+const (
+	gcsObjectFinalize   = `OBJECT_FINALIZE`
+	gcsReceiveRetryWait = 10 * time.Second
+)
+
+var (
+	ErrMissingProjectID    = errors.New("Project-ID is required")
+	ErrMissingSubscription = errors.New("Subscription-Name is required")
+	ErrGCSAuthConflict     = errors.New("Credentials-Path and Disable-Auth are mutually exclusive")
+)
+
+// gcsAuth holds the settings shared by GCS buckets and Pub/Sub listeners, without a credentials
+// file the application default credentials are used
+type gcsAuth struct {
+	Credentials_Path string // service account JSON key
+	Endpoint         string // defaults to https://storage.googleapis.com, set for emulators
+	Disable_Auth     bool   // send unauthenticated requests, only useful with emulators
+}
+
+func (ga gcsAuth) validate() (err error) {
+	if ga.Credentials_Path != `` && ga.Disable_Auth {
+		return ErrGCSAuthConflict
+	}
+	if ga.Credentials_Path != `` {
+		if _, err = os.Stat(ga.Credentials_Path); err != nil {
+			return fmt.Errorf("Invalid Credentials-Path %w", err)
+		}
+	}
+	if ga.Endpoint != `` {
+		_, err = parseEndpoint(ga.Endpoint)
+	}
+	return
+}
+
+func (ga gcsAuth) options(scope string) (opts []option.ClientOption) {
+	if ga.Disable_Auth {
+		opts = append(opts, option.WithoutAuthentication())
+	} else {
+		opts = append(opts, option.WithScopes(scope))
+		if ga.Credentials_Path != `` {
+			opts = append(opts, option.WithCredentialsFile(ga.Credentials_Path))
+		}
+	}
+	return
+}
+
+// gcsClient reads objects with the Cloud Storage client
+type gcsClient struct {
+	*storage.Client
+}
+
+func newGCSClient(ctx context.Context, ga gcsAuth) (gc *gcsClient, err error) {
+	if err = ga.validate(); err != nil {
+		return
+	}
+	//objects are read with the JSON API so that an emulator endpoint handles everything
+	opts := append(ga.options(storage.ScopeReadOnly), storage.WithJSONReads())
+	if ga.Endpoint != `` {
+		opts = append(opts, option.WithEndpoint(strings.TrimSuffix(ga.Endpoint, `/`)+`/storage/v1/`))
+	}
+	gc = &gcsClient{}
+	if gc.Client, err = storage.NewClient(ctx, opts...); err != nil {
+		return nil, fmt.Errorf("failed to create GCS client %w", err)
+	}
+	return
+}
+
+// gcsStore reads objects from a single bucket
+type gcsStore struct {
+	client *gcsClient
+	bucket string
+}
+
+func (gs *gcsStore) List(ctx context.Context, prefix string, fn func(objectInfo) bool) error {
+	q := &storage.Query{Prefix: prefix}
+	if err := q.SetAttrSelection([]string{`Name`, `Size`, `Updated`}); err != nil {
+		return err
+	}
+	it := gs.client.Bucket(gs.bucket).Objects(ctx, q)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !fn(objectInfo{Key: attrs.Name, Size: attrs.Size, Updated: attrs.Updated}) {
+			return nil
+		}
+	}
+}
+
+func (gs *gcsStore) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	rdr, err := gs.client.Bucket(gs.bucket).Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rdr, rdr.Attrs.Size, nil
+}
+
+func gcsStateKey(bucket string) string {
+	return `gs://` + bucket
+}
+
+// gcsNotification is the JSON_API_V1 payload of a Cloud Storage Pub/Sub notification
+type gcsNotification struct {
+	Size int64 `json:"size,string"`
+}
+
+// decodeGCSNotification pulls the object out of a Pub/Sub notification, ok is false for events
+// other than new objects.  The object is named by the message attributes, the payload is only
+// consulted for the size and may be absent if the notification was created with no payload.
+func decodeGCSNotification(attrs map[string]string, data []byte) (ref blobRef, ok bool, err error) {
+	if attrs[`eventType`] != gcsObjectFinalize {
+		return
+	}
+	if ref.Container = attrs[`bucketId`]; ref.Container == `` {
+		err = errEmptyBucket
+		return
+	} else if ref.Name = attrs[`objectId`]; ref.Name == `` {
+		err = errEmptyKey
+		return
+	}
+	ref.Size = -1 //unknown
+	if attrs[`payloadFormat`] == `JSON_API_V1` && len(data) > 0 {
+		var n gcsNotification
+		if err = json.Unmarshal(data, &n); err != nil {
+			err = fmt.Errorf("invalid notification payload %w", err)
+			return
+		}
+		ref.Size = n.Size
+	}
+	ok = true
+	return
+}
+
+// gcsPubSubListener consumes Cloud Storage notifications from a Pub/Sub subscription
+type gcsPubSubListener struct {
+	*BucketReader // reader settings, objects are read from whatever bucket the notification names
+	client        *gcsClient
+	sub           *pubsub.Subscription
+}
+
+func (gl *gcsPubSubListener) run(wg *sync.WaitGroup, ctx context.Context, lg *log.Logger, numWorkers int) {
+	defer wg.Done()
+	//the subscription hands messages to concurrent callbacks, bound them by the worker pool size
+	gl.sub.ReceiveSettings.MaxOutstandingMessages = numWorkers
+	for ctx.Err() == nil {
+		err := gl.sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+			if gl.handle(ctx, lg, msg.Attributes, msg.Data) {
+				msg.Ack()
+			} else {
+				msg.Nack()
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			lg.Error("pubsub receive error", log.KV("name", gl.Name), log.KV("subscription", gl.sub.ID()), log.KVErr(err))
+			sleepContext(ctx, gcsReceiveRetryWait)
+		}
+	}
+	lg.Info("gcs pubsub routine exiting", log.KV("name", gl.Name))
+}
+
+// handle processes the object named in a notification, returning true if the message should be acked
+func (gl *gcsPubSubListener) handle(ctx context.Context, lg *log.Logger, attrs map[string]string, data []byte) bool {
+	ref, ok, err := decodeGCSNotification(attrs, data)
+	if err != nil {
+		//a malformed notification will never succeed, ack it so it is not redelivered forever
+		lg.Warn("error decoding notification, dropping it", log.KV("name", gl.Name), log.KVErr(err))
+		return true
+	} else if !ok {
+		return true //not a new object
+	} else if !gl.ShouldTrack(ref.Name) {
+		lg.Info("skipping key based on filter", log.KV("key", ref.Name))
+		return true
+	} else if ref.Size == 0 {
+		return true
+	}
+	store := &gcsStore{client: gl.client, bucket: ref.Container}
+	sz, fetchrtt, rtt, err := gl.ProcessFrom(store, ref.Name, ctx)
+	if err != nil {
+		lg.Error("error processing message", log.KV("bucket", ref.Container), log.KV("key", ref.Name), log.KVErr(err))
+		return false
+	}
+	lg.Info("successfully processed message",
+		log.KV("bucket", ref.Container),
+		log.KV("key", ref.Name),
+		log.KV("fetch-rtt", fetchrtt),
+		log.KV("rtt", rtt),
+		log.KV("size", sz))
+	return true
+}
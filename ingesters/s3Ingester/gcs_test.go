@@ -0,0 +1,301 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/log"
+)
+
+// fakeGCS emulates the JSON API object list and media download the way fake-gcs-server does
+type fakeGCS struct {
+	sync.Mutex
+	buckets map[string]map[string]testBlob
+}
+
+func (fg *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fg.Lock()
+	defer fg.Unlock()
+	rest, ok := strings.CutPrefix(r.URL.EscapedPath(), `/storage/v1/b/`)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	bucket, rest, _ := strings.Cut(rest, `/`)
+	objs, ok := fg.buckets[bucket]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"code":404,"message":"The specified bucket does not exist."}}`)
+		return
+	}
+	if rest == `o` {
+		fg.serveList(w, r.URL.Query(), objs)
+		return
+	}
+	//object names are escaped as a single path element, slashes included
+	escaped, ok := strings.CutPrefix(rest, `o/`)
+	if !ok || strings.Contains(escaped, `/`) || r.URL.Query().Get(`alt`) != `media` {
+		http.Error(w, `bad request`, http.StatusBadRequest)
+		return
+	}
+	name, err := url.PathUnescape(escaped)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b, ok := objs[name]
+	if !ok {
+		http.Error(w, `{"error":{"code":404,"message":"No such object"}}`, http.StatusNotFound)
+		return
+	}
+	fmt.Fprint(w, b.data)
+}
+
+// serveList returns one object per page to exercise paging
+func (fg *fakeGCS) serveList(w http.ResponseWriter, q url.Values, objs map[string]testBlob) {
+	var names []string
+	for k := range objs {
+		if strings.HasPrefix(k, q.Get(`prefix`)) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	var idx int
+	if tok := q.Get(`pageToken`); tok != `` {
+		idx, _ = strconv.Atoi(tok)
+	}
+	resp := map[string]interface{}{}
+	if idx < len(names) {
+		b := objs[names[idx]]
+		resp[`items`] = []map[string]string{{
+			`name`:    names[idx],
+			`size`:    strconv.Itoa(len(b.data)),
+			`updated`: b.updated.Format(time.RFC3339Nano),
+		}}
+		if idx+1 < len(names) {
+			resp[`nextPageToken`] = strconv.Itoa(idx + 1)
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func newFakeGCS(t *testing.T) (*fakeGCS, *gcsClient) {
+	fg := &fakeGCS{buckets: map[string]map[string]testBlob{}}
+	srv := httptest.NewServer(fg)
+	t.Cleanup(srv.Close)
+	client, err := newGCSClient(context.Background(), gcsAuth{Endpoint: srv.URL, Disable_Auth: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fg, client
+}
+
+func TestGCSBucketScan(t *testing.T) {
+	fg, client := newFakeGCS(t)
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	fg.buckets[`logs`] = map[string]testBlob{
+		`cloudaudit.googleapis.com/activity/2025/03/01/00:00:00_00:59:59_S0.json`: {data: "{\"a\":1}\n{\"a\":2}\n", updated: ts},
+		`dir/with space+plus.json`: {data: "{\"b\":1}\n", updated: ts},
+		`other.txt`:                {data: "nope\n", updated: ts},
+	}
+	ot, err := NewObjectTracker(filepath.Join(t.TempDir(), `state`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tw testWriter
+	cfg := newTestBucketConfig(`gcs`, &tw)
+	cfg.FileFilters = []string{`**/*.json`}
+	br, err := newObjectReader(cfg, &gcsStore{client: client, bucket: `logs`}, gcsStateKey(`logs`))
+	if err != nil {
+		t.Fatal(err)
+	} else if err = br.Test(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lg := log.NewDiscardLogger()
+	fullScan(context.Background(), []*BucketReader{br}, ot, lg, 1)
+	if got := entryData(&tw); strings.Join(got, `,`) != `{"a":1},{"a":2},{"b":1}` {
+		t.Fatalf("bad entries %v", got)
+	}
+	if st, ok := ot.Get(`gs://logs`, `dir/with space+plus.json`); !ok || !st.Updated.Equal(ts) || st.Size != 8 {
+		t.Fatalf("object not tracked %v %+v", ok, st)
+	}
+	fullScan(context.Background(), []*BucketReader{br}, ot, lg, 1)
+	if len(tw.ents) != 3 {
+		t.Fatalf("objects were read twice: %d", len(tw.ents))
+	}
+
+	missing := &gcsStore{client: client, bucket: `missing`}
+	if err = missing.List(context.Background(), ``, func(objectInfo) bool { return true }); err == nil || !strings.Contains(err.Error(), `does not exist`) {
+		t.Fatalf("bad error %v", err)
+	}
+}
+
+func TestDecodeGCSNotification(t *testing.T) {
+	attrs := map[string]string{
+		`eventType`:     `OBJECT_FINALIZE`,
+		`bucketId`:      `logs`,
+		`objectId`:      `a/b.json`,
+		`payloadFormat`: `JSON_API_V1`,
+	}
+	ref, ok, err := decodeGCSNotification(attrs, []byte(`{"kind":"storage#object","name":"a/b.json","bucket":"logs","size":"42"}`))
+	if err != nil || !ok {
+		t.Fatal(ok, err)
+	} else if ref != (blobRef{Container: `logs`, Name: `a/b.json`, Size: 42}) {
+		t.Fatalf("bad ref %+v", ref)
+	}
+	attrs[`payloadFormat`] = `NONE`
+	if ref, ok, err = decodeGCSNotification(attrs, nil); err != nil || !ok || ref.Size != -1 {
+		t.Fatalf("bad ref without payload %+v %v %v", ref, ok, err)
+	}
+	attrs[`eventType`] = `OBJECT_DELETE`
+	if _, ok, err = decodeGCSNotification(attrs, nil); err != nil || ok {
+		t.Fatalf("deletes should be ignored %v %v", ok, err)
+	}
+	attrs[`eventType`] = `OBJECT_FINALIZE`
+	delete(attrs, `objectId`)
+	if _, _, err = decodeGCSNotification(attrs, nil); err == nil {
+		t.Fatal("missing object did not fail")
+	}
+}
+
+func TestGCSPubSubHandle(t *testing.T) {
+	fg, client := newFakeGCS(t)
+	fg.buckets[`logs`] = map[string]testBlob{
+		`a.json`: {data: "{\"a\":1}\n"},
+		`b.txt`:  {data: "nope\n"},
+	}
+	var tw testWriter
+	cfg := newTestBucketConfig(`pubsub`, &tw)
+	cfg.FileFilters = []string{`*.json`}
+	br, err := newObjectReader(cfg, nil, ``)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl := &gcsPubSubListener{BucketReader: br, client: client}
+	lg := log.NewDiscardLogger()
+	notify := func(bucket, object string) map[string]string {
+		return map[string]string{`eventType`: gcsObjectFinalize, `bucketId`: bucket, `objectId`: object}
+	}
+	tests := []struct {
+		attrs map[string]string
+		ack   bool
+	}{
+		{notify(`logs`, `a.json`), true},
+		{notify(`logs`, `b.txt`), true},      //filtered
+		{notify(`logs`, `gone.json`), false}, //retry later
+		{notify(`missing`, `a.json`), false},
+		{map[string]string{`eventType`: `OBJECT_METADATA_UPDATE`}, true},
+		{map[string]string{`eventType`: gcsObjectFinalize}, true}, //malformed
+	}
+	for i, tc := range tests {
+		if ack := gl.handle(context.Background(), lg, tc.attrs, nil); ack != tc.ack {
+			t.Fatalf("%d: ack %v != %v", i, ack, tc.ack)
+		}
+	}
+	if got := entryData(&tw); strings.Join(got, `,`) != `{"a":1}` {
+		t.Fatalf("bad entries %v", got)
+	}
+}
+
+func TestGCSAuthValidate(t *testing.T) {
+	if err := (gcsAuth{Credentials_Path: `/nonexistent/creds.json`}).validate(); err == nil {
+		t.Fatal("missing credentials file did not fail")
+	} else if err = (gcsAuth{Credentials_Path: filepath.Join(t.TempDir()), Disable_Auth: true}).validate(); err == nil {
+		t.Fatal("conflicting auth did not fail")
+	} else if err = (gcsAuth{Endpoint: `localhost:4443`}).validate(); err == nil {
+		t.Fatal("endpoint without a scheme did not fail")
+	} else if err = (gcsAuth{Endpoint: `http://localhost:4443`, Disable_Auth: true}).validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGCSEmulator runs against fake-gcs-server when STORAGE_EMULATOR_HOST names it, e.g. http://127.0.0.1:4443
+func TestGCSEmulator(t *testing.T) {
+	if os.Getenv(`STORAGE_EMULATOR_HOST`) == `` {
+		t.Skip("STORAGE_EMULATOR_HOST is not set")
+	}
+	ctx := context.Background()
+	client, err := newGCSClient(ctx, gcsAuth{Disable_Auth: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	name := fmt.Sprintf("gravwell-%d", time.Now().UnixNano())
+	bkt := client.Bucket(name)
+	if err = bkt.Create(ctx, `test`, nil); err != nil {
+		t.Fatal(err)
+	}
+	objs := map[string]string{`a/1.json`: "{\"a\":1}\n{\"a\":2}\n", `a/b c+d.json`: "{\"b\":1}\n", `skip.txt`: "nope\n"}
+	for k, v := range objs {
+		w := bkt.Object(k).NewWriter(ctx)
+		if _, err = w.Write([]byte(v)); err != nil {
+			t.Fatal(err)
+		} else if err = w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		for k := range objs {
+			bkt.Object(k).Delete(ctx)
+		}
+		bkt.Delete(ctx)
+	}()
+
+	ot, err := NewObjectTracker(filepath.Join(t.TempDir(), `state`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tw testWriter
+	cfg := newTestBucketConfig(`gcs`, &tw)
+	cfg.FileFilters = []string{`**/*.json`}
+	br, err := newObjectReader(cfg, &gcsStore{client: client, bucket: name}, gcsStateKey(name))
+	if err != nil {
+		t.Fatal(err)
+	} else if err = br.Test(ctx); err != nil {
+		t.Fatal(err)
+	}
+	fullScan(ctx, []*BucketReader{br}, ot, log.NewDiscardLogger(), 1)
+	if got := entryData(&tw); strings.Join(got, `,`) != `{"a":1},{"a":2},{"b":1}` {
+		t.Fatalf("bad entries %v", got)
+	}
+	if st, ok := ot.Get(gcsStateKey(name), `a/b c+d.json`); !ok || st.Size != 8 {
+		t.Fatalf("object not tracked %v %+v", ok, st)
+	}
+
+	tw.ents = nil
+	if br, err = newObjectReader(cfg, nil, ``); err != nil {
+		t.Fatal(err)
+	}
+	gl := &gcsPubSubListener{BucketReader: br, client: client}
+	attrs := map[string]string{`eventType`: gcsObjectFinalize, `bucketId`: name, `objectId`: `a/b c+d.json`}
+	if !gl.handle(ctx, log.NewDiscardLogger(), attrs, nil) {
+		t.Fatal("notification not acked")
+	}
+	attrs[`objectId`] = `gone.json`
+	if gl.handle(ctx, log.NewDiscardLogger(), attrs, nil) {
+		t.Fatal("missing object acked")
+	}
+	if got := entryData(&tw); strings.Join(got, `,`) != `{"b":1}` {
+		t.Fatalf("bad entries %v", got)
+	}
+}
@@ -20,7 +20,9 @@ import (
 	// Embed tzdata so that we don't rely on potentially broken timezone DBs on the host
 	_ "time/tzdata"
 
+	"cloud.google.com/go/pubsub"
 	"github.com/gravwell/gravwell/v3/debug"
+	"github.com/gravwell/gravwell/v3/ingest"
 	"github.com/gravwell/gravwell/v3/ingest/log"
 	"github.com/gravwell/gravwell/v3/ingesters/base"
 	"github.com/gravwell/gravwell/v3/ingesters/utils"
@@ -158,6 +160,79 @@ func main() {
 		}
 	}
 
+	var listeners []notificationListener
+	bctx := context.Background()
+	for k, v := range cfg.Azure_Container {
+		bcfg, err := cfg.objectBucketConfig(k, v.objectConfig, v.TimeConfig, igst, ib)
+		if err != nil {
+			ib.Logger.FatalCode(0, "failed to configure azure container", log.KV("container", k), log.KVErr(err))
+		}
+		client, err := newAzureClient(v.azureAuth, ``)
+		if err != nil {
+			ib.Logger.FatalCode(0, "failed to create azure client", log.KV("container", k), log.KVErr(err))
+		}
+		store := &azureBlobStore{client: client, container: v.Container}
+		if b, err := newObjectReader(bcfg, store, azureStateKey(v.Storage_Account, v.Container)); err != nil {
+			ib.Logger.FatalCode(0, "failed to create azure container reader", log.KVErr(err))
+		} else {
+			brs = append(brs, b)
+		}
+	}
+
+	for k, v := range cfg.Azure_Event_Grid_Listener {
+		bcfg, err := cfg.objectBucketConfig(k, v.objectConfig, v.TimeConfig, igst, ib)
+		if err != nil {
+			ib.Logger.FatalCode(0, "failed to configure azure event grid listener", log.KV("listener", k), log.KVErr(err))
+		}
+		al := &azureQueueListener{queue: v.Queue_Name}
+		if al.client, err = newAzureClient(v.azureAuth, v.Queue_Endpoint); err != nil {
+			ib.Logger.FatalCode(0, "failed to create azure client", log.KV("listener", k), log.KVErr(err))
+		} else if al.visibility, err = v.visibility(); err != nil {
+			ib.Logger.FatalCode(0, "invalid visibility timeout", log.KV("listener", k), log.KVErr(err))
+		} else if al.BucketReader, err = newObjectReader(bcfg, nil, ``); err != nil {
+			ib.Logger.FatalCode(0, "failed to create azure event grid listener", log.KVErr(err))
+		}
+		listeners = append(listeners, al)
+	}
+
+	for k, v := range cfg.GCS_Bucket {
+		bcfg, err := cfg.objectBucketConfig(k, v.objectConfig, v.TimeConfig, igst, ib)
+		if err != nil {
+			ib.Logger.FatalCode(0, "failed to configure gcs bucket", log.KV("bucket", k), log.KVErr(err))
+		}
+		client, err := newGCSClient(bctx, v.gcsAuth)
+		if err != nil {
+			ib.Logger.FatalCode(0, "failed to create gcs client", log.KV("bucket", k), log.KVErr(err))
+		}
+		store := &gcsStore{client: client, bucket: v.Bucket_Name}
+		if b, err := newObjectReader(bcfg, store, gcsStateKey(v.Bucket_Name)); err != nil {
+			ib.Logger.FatalCode(0, "failed to create gcs bucket reader", log.KVErr(err))
+		} else {
+			brs = append(brs, b)
+		}
+	}
+
+	for k, v := range cfg.GCS_PubSub_Listener {
+		bcfg, err := cfg.objectBucketConfig(k, v.objectConfig, v.TimeConfig, igst, ib)
+		if err != nil {
+			ib.Logger.FatalCode(0, "failed to configure gcs pubsub listener", log.KV("listener", k), log.KVErr(err))
+		}
+		gl := &gcsPubSubListener{}
+		if gl.client, err = newGCSClient(bctx, v.gcsAuth); err != nil {
+			ib.Logger.FatalCode(0, "failed to create gcs client", log.KV("listener", k), log.KVErr(err))
+		} else if gl.BucketReader, err = newObjectReader(bcfg, nil, ``); err != nil {
+			ib.Logger.FatalCode(0, "failed to create gcs pubsub listener", log.KVErr(err))
+		}
+		//the pubsub client honors PUBSUB_EMULATOR_HOST on its own
+		psc, err := pubsub.NewClient(bctx, v.Project_ID, v.gcsAuth.options(pubsub.ScopePubSub)...)
+		if err != nil {
+			ib.Logger.FatalCode(0, "failed to create pubsub client", log.KV("listener", k), log.KVErr(err))
+		}
+		defer psc.Close()
+		gl.sub = psc.Subscription(v.Subscription_Name)
+		listeners = append(listeners, gl)
+	}
+
 	if *fTestConfig && len(brs) != 0 {
 		igst.Close()
 		err = testConfig(brs, ib.Verbose)
@@ -175,7 +250,7 @@ func main() {
 	ib.Debug("Running\n")
 
 	//kick off our consumer routines
-	if err = start(&wg, ctx, brs, sqsS3, ot, ib.Logger, cfg.Worker_Pool_Size); err != nil {
+	if err = start(&wg, ctx, brs, sqsS3, listeners, ot, ib.Logger, cfg.Worker_Pool_Size); err != nil {
 		ib.Logger.Error("failed to run bucket consumers", log.KVErr(err))
 	}
 
@@ -198,6 +273,29 @@ func main() {
 	}
 }
 
+// objectBucketConfig builds the reader settings for an Azure or GCS section
+func (c *cfgType) objectBucketConfig(name string, oc objectConfig, tc TimeConfig, igst *ingest.IngestMuxer, ib base.IngesterBase) (bcfg BucketConfig, err error) {
+	bcfg = BucketConfig{
+		TimeConfig:     tc,
+		Verbose:        ib.Verbose,
+		Name:           name,
+		Reader:         oc.Reader,
+		FileFilters:    oc.File_Filters,
+		TagName:        oc.Tag_Name,
+		SourceOverride: oc.Source_Override,
+		Logger:         ib.Logger,
+		MaxLineSize:    oc.Max_Line_Size,
+	}
+	if bcfg.Tag, err = igst.GetTag(oc.Tag_Name); err != nil {
+		err = fmt.Errorf("failed to get established tag %s %w", oc.Tag_Name, err)
+	} else if bcfg.Proc, err = c.Preprocessor.ProcessorSet(igst, oc.Preprocessor); err != nil {
+		err = fmt.Errorf("preprocessor failure %w", err)
+	} else if !tc.Ignore_Timestamps {
+		bcfg.TG, err = c.newTimeGrinder(tc)
+	}
+	return
+}
+
 func testConfig(brs []*BucketReader, verbose bool) (err error) {
 	if len(brs) == 0 {
 		err = errors.New("no bucket readers defined")
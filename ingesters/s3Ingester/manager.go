@@ -11,8 +11,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/gravwell/gravwell/v3/ingest/log"
 )
@@ -28,7 +26,12 @@ var (
 	errEmptyKey    = errors.New("empty key name")
 )
 
-func start(wg *sync.WaitGroup, ctx context.Context, buckets []*BucketReader, sqsS3 []*SQSS3Listener, ot *objectTracker, lg *log.Logger, numWorkers int) (err error) {
+// notificationListener consumes object created notifications from a queue or subscription
+type notificationListener interface {
+	run(wg *sync.WaitGroup, ctx context.Context, lg *log.Logger, numWorkers int)
+}
+
+func start(wg *sync.WaitGroup, ctx context.Context, buckets []*BucketReader, sqsS3 []*SQSS3Listener, listeners []notificationListener, ot *objectTracker, lg *log.Logger, numWorkers int) (err error) {
 	if len(buckets) != 0 {
 		wg.Add(1)
 		go manualScanner(wg, ctx, buckets, ot, lg, numWorkers)
@@ -37,6 +40,10 @@ func start(wg *sync.WaitGroup, ctx context.Context, buckets []*BucketReader, sqs
 		wg.Add(1)
 		go sqsS3Routine(v, wg, ctx, lg, numWorkers)
 	}
+	for _, v := range listeners {
+		wg.Add(1)
+		go v.run(wg, ctx, lg, numWorkers)
+	}
 	return
 }
 
@@ -139,20 +146,8 @@ func (s *SQSS3Listener) worker(ctx context.Context, lg *log.Logger, wg *sync.Wai
 					continue
 				}
 
-				obj := &s3.Object{
-					Key: aws.String(x),
-				}
-
-				if obj != nil && obj.Size != nil && *obj.Size == int64(0) {
-					// don't even bother fetching it, just delete and move on
-					lg.Info("skipping zero-byte object",
-						log.KV("worker", workerID),
-						log.KV("bucket", buckets[i]),
-						log.KV("key", x))
-					continue
-				}
-
-				sz, s3rtt, rtt, err = ProcessContext(obj, ctx, s.svc, buckets[i], s.rdr, s.TG, s.src, s.Tag, s.Proc, s.MaxLineSize)
+				store := &s3Store{svc: s.svc, bucket: buckets[i]}
+				sz, s3rtt, rtt, err = ProcessContext(ctx, store, x, s.rdr, s.TG, s.src, s.Tag, s.Proc, s.MaxLineSize, s.Timestamp_Field)
 				if err != nil {
 					shouldDelete = false
 					lg.Error("error processing message", log.KV("bucket", buckets[i]), log.KV("key", x), log.KVErr(err))
@@ -337,7 +332,7 @@ func fullScan(ctx context.Context, buckets []*BucketReader, ot *objectTracker, l
 	lg.Info("starting full manual scan")
 	for _, b := range buckets {
 		// start workers
-		queue := make(chan *s3.Object, QUEUE_DEPTH)
+		queue := make(chan objectInfo, QUEUE_DEPTH)
 		for i := 0; i < numWorkers; i++ {
 			wg.Add(1)
 			go b.worker(lg, ctx, ot, queue, &wg)
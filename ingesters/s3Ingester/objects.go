@@ -0,0 +1,30 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// objectInfo describes an object found while listing a bucket or container
+type objectInfo struct {
+	Key     string
+	Size    int64
+	Updated time.Time
+}
+
+// objectStore is a single bucket or container in S3, Azure Blob Storage, or Google Cloud Storage
+type objectStore interface {
+	// List walks every object under a prefix, stopping early if the callback returns false
+	List(ctx context.Context, prefix string, fn func(objectInfo) bool) error
+	// Open fetches an object, returning its contents and size
+	Open(ctx context.Context, key string) (io.ReadCloser, int64, error)
+}
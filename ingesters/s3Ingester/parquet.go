@@ -0,0 +1,207 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf8"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pq "github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+const parquetBatchSize = 1024
+
+// parquetField is a single named value in a row
+type parquetField struct {
+	name  string
+	value interface{}
+}
+
+// parquetRow is a record with its fields in schema order.
+// Nested groups are flattened into dotted names, lists and maps keep their structure.
+type parquetRow []parquetField
+
+// get returns the value of a named field
+func (r parquetRow) get(name string) (interface{}, bool) {
+	for _, f := range r {
+		if f.name == name {
+			return f.value, true
+		}
+	}
+	return nil, false
+}
+
+// MarshalJSON renders the row as an object, preserving the field order
+func (r parquetRow) MarshalJSON() ([]byte, error) {
+	bb := bytes.NewBuffer(make([]byte, 0, 64*len(r)))
+	bb.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			bb.WriteByte(',')
+		}
+		k, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		bb.Write(k)
+		bb.WriteByte(':')
+		bb.Write(v)
+	}
+	bb.WriteByte('}')
+	return bb.Bytes(), nil
+}
+
+// readParquet walks every row in a parquet file handing each one to fn
+func readParquet(ctx context.Context, r pq.ReaderAtSeeker, fn func(parquetRow) error) (err error) {
+	//objects come from places we don't control, don't let a malformed one take down the ingester
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("corrupt parquet object: %v", r)
+		}
+	}()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var pf *file.Reader
+	if pf, err = file.NewParquetReader(r); err != nil {
+		return
+	}
+	defer pf.Close()
+	var fr *pqarrow.FileReader
+	if fr, err = pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: parquetBatchSize}, memory.DefaultAllocator); err != nil {
+		return
+	}
+	var rr pqarrow.RecordReader
+	if rr, err = fr.GetRecordReader(ctx, nil, nil); err != nil {
+		return
+	}
+	defer rr.Release()
+	for rr.Next() {
+		rec := rr.RecordBatch()
+		sc := rec.Schema()
+		for i := 0; i < int(rec.NumRows()); i++ {
+			var row parquetRow
+			for j, col := range rec.Columns() {
+				row = flattenArrow(row, sc.Field(j).Name, col, i, false)
+			}
+			if err = fn(row); err != nil {
+				return
+			}
+		}
+	}
+	return rr.Err()
+}
+
+// flattenArrow adds a column value to the row, structs are flattened into dotted names
+// and a null struct flattens to nulls for each of its fields
+func flattenArrow(row parquetRow, name string, arr arrow.Array, i int, null bool) parquetRow {
+	null = null || arr.IsNull(i)
+	if st, ok := arr.(*array.Struct); ok {
+		typ := st.DataType().(*arrow.StructType)
+		for j := 0; j < st.NumField(); j++ {
+			row = flattenArrow(row, name+`.`+typ.Field(j).Name, st.Field(j), i, null)
+		}
+		return row
+	} else if null {
+		return append(row, parquetField{name: name})
+	}
+	return append(row, parquetField{name: name, value: arrowValue(arr, i)})
+}
+
+// arrowValue converts a single value into something that renders sensibly as JSON
+func arrowValue(arr arrow.Array, i int) interface{} {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.Struct:
+		//structs inside of lists and maps are rendered as objects
+		typ := a.DataType().(*arrow.StructType)
+		r := make(parquetRow, 0, a.NumField())
+		for j := 0; j < a.NumField(); j++ {
+			r = append(r, parquetField{name: typ.Field(j).Name, value: arrowValue(a.Field(j), i)})
+		}
+		return r
+	case *array.Map:
+		keys, items := a.Keys(), a.Items()
+		start, end := a.ValueOffsets(i)
+		r := make(parquetRow, 0, end-start)
+		for j := int(start); j < int(end); j++ {
+			r = append(r, parquetField{name: fmt.Sprint(arrowValue(keys, j)), value: arrowValue(items, j)})
+		}
+		return r
+	case array.ListLike:
+		vals := a.ListValues()
+		start, end := a.ValueOffsets(i)
+		l := make([]interface{}, 0, end-start)
+		for j := int(start); j < int(end); j++ {
+			l = append(l, arrowValue(vals, j))
+		}
+		return l
+	case *array.Timestamp:
+		unit := a.DataType().(*arrow.TimestampType).Unit
+		return timeOr(a.Value(i).ToTime(unit), int64(a.Value(i)))
+	case *array.Date32:
+		return a.Value(i).FormattedString()
+	case *array.Date64:
+		return a.Value(i).FormattedString()
+	case *array.Float32:
+		if v := float64(a.Value(i)); math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil //JSON has no way to say these
+		}
+	case *array.Float64:
+		if v := a.Value(i); math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+	case *array.String:
+		return a.Value(i)
+	case *array.Binary:
+		return binaryValue(a.Value(i))
+	case *array.FixedSizeBinary:
+		return binaryValue(a.Value(i))
+	}
+	v := arr.GetOneForMarshal(i)
+	if _, ok := arr.DataType().(arrow.DecimalType); ok {
+		//decimals are rendered as strings so they don't lose precision, they are numbers as far as JSON is concerned
+		if s, ok := v.(string); ok {
+			return json.Number(s)
+		}
+	}
+	return v
+}
+
+// binaryValue hands back unannotated byte arrays as strings when they are valid UTF-8, they usually are
+func binaryValue(b []byte) interface{} {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	return append([]byte(nil), b...)
+}
+
+// timeOr returns the time in UTC, or the raw value if the time can't be rendered as RFC3339
+func timeOr(t time.Time, raw interface{}) interface{} {
+	if y := t.Year(); y < 0 || y > 9999 {
+		return raw
+	}
+	return t.UTC()
+}
@@ -0,0 +1,141 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	pq "github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+var testParquetSchema = arrow.NewSchema([]arrow.Field{
+	{Name: `ts`, Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: `UTC`}},
+	{Name: `msg`, Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: `source`, Type: arrow.StructOf(
+		arrow.Field{Name: `ip`, Type: arrow.BinaryTypes.String},
+		arrow.Field{Name: `port`, Type: arrow.PrimitiveTypes.Int32},
+	), Nullable: true},
+	//lists of lists, two levels of repetition
+	{Name: `hops`, Type: arrow.ListOf(arrow.StructOf(
+		arrow.Field{Name: `addrs`, Type: arrow.ListOf(arrow.BinaryTypes.String)},
+	))},
+	{Name: `labels`, Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.BinaryTypes.String)},
+	{Name: `price`, Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}},
+	{Name: `day`, Type: arrow.FixedWidthTypes.Date32},
+	{Name: `raw`, Type: arrow.BinaryTypes.Binary},
+}, nil)
+
+func testParquetFile(t *testing.T, codec compress.Compression) []byte {
+	t.Helper()
+	rb := array.NewRecordBuilder(memory.DefaultAllocator, testParquetSchema)
+	defer rb.Release()
+	tsb := rb.Field(0).(*array.TimestampBuilder)
+	msgb := rb.Field(1).(*array.StringBuilder)
+	srcb := rb.Field(2).(*array.StructBuilder)
+	hopsb := rb.Field(3).(*array.ListBuilder)
+	hopb := hopsb.ValueBuilder().(*array.StructBuilder)
+	addrsb := hopb.FieldBuilder(0).(*array.ListBuilder)
+	addrb := addrsb.ValueBuilder().(*array.StringBuilder)
+	labelsb := rb.Field(4).(*array.MapBuilder)
+	priceb := rb.Field(5).(*array.Decimal128Builder)
+	dayb := rb.Field(6).(*array.Date32Builder)
+	rawb := rb.Field(7).(*array.BinaryBuilder)
+
+	//first row has everything populated
+	tsb.Append(arrow.Timestamp(1700000000000))
+	msgb.Append(`hello`)
+	srcb.Append(true)
+	srcb.FieldBuilder(0).(*array.StringBuilder).Append(`192.168.1.1`)
+	srcb.FieldBuilder(1).(*array.Int32Builder).Append(443)
+	hopsb.Append(true)
+	hopb.Append(true)
+	addrsb.Append(true)
+	addrb.AppendValues([]string{`10.0.0.1`, `10.0.0.2`}, nil)
+	hopb.Append(true)
+	addrsb.Append(true)
+	labelsb.Append(true)
+	labelsb.KeyBuilder().(*array.StringBuilder).AppendValues([]string{`app`, `env`}, nil)
+	labelsb.ItemBuilder().(*array.StringBuilder).AppendValues([]string{`web`, `prod`}, nil)
+	priceb.Append(decimal128.FromI64(-1234))
+	dayb.Append(arrow.Date32(19675))
+	rawb.Append([]byte{0xff, 0xfe})
+
+	//second row is mostly empty
+	tsb.Append(arrow.Timestamp(1700000060000))
+	msgb.AppendNull()
+	srcb.AppendNull()
+	hopsb.Append(true)
+	labelsb.Append(true)
+	priceb.Append(decimal128.FromI64(5))
+	dayb.Append(arrow.Date32(0))
+	rawb.Append([]byte(`text`))
+
+	rec := rb.NewRecord()
+	defer rec.Release()
+	var bb bytes.Buffer
+	fw, err := pqarrow.NewFileWriter(testParquetSchema, &bb, pq.NewWriterProperties(pq.WithCompression(codec)), pqarrow.DefaultWriterProps())
+	if err != nil {
+		t.Fatal(err)
+	} else if err = fw.Write(rec); err != nil {
+		t.Fatal(err)
+	} else if err = fw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bb.Bytes()
+}
+
+func TestParquetReader(t *testing.T) {
+	data := []string{
+		`{"ts":"2023-11-14T22:13:20Z","msg":"hello","source.ip":"192.168.1.1","source.port":443,"hops":[{"addrs":["10.0.0.1","10.0.0.2"]},{"addrs":[]}],"labels":{"app":"web","env":"prod"},"price":-12.34,"day":"2023-11-14","raw":"//4="}`,
+		`{"ts":"2023-11-14T22:14:20Z","msg":null,"source.ip":null,"source.port":null,"hops":[],"labels":{},"price":0.05,"day":"1970-01-01","raw":"text"}`,
+	}
+	ts := []time.Time{time.Unix(1700000000, 0).UTC(), time.Unix(1700000060, 0).UTC()}
+	for _, codec := range []compress.Compression{compress.Codecs.Uncompressed, compress.Codecs.Snappy, compress.Codecs.Gzip, compress.Codecs.Brotli, compress.Codecs.Zstd, compress.Codecs.Lz4Raw} {
+		body := testParquetFile(t, codec)
+		tc := readerTest{
+			name:    `parquet ` + codec.String(),
+			rdr:     parquetReader,
+			tsField: `ts`,
+			data:    data,
+			ts:      ts,
+		}
+		runReaderTest(t, tc, body)
+
+		//without a field the whole row goes to the timegrinder
+		tc.tsField = ``
+		runReaderTest(t, tc, body)
+	}
+
+	tg, err := timegrinder.New(timegrinder.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := &recordSink{tg: tg, proc: processors.NewProcessorSet(&testWriter{})}
+	if err = processParquetContext(strings.NewReader(`this is not parquet`), ``, rs); err == nil {
+		t.Fatal("failed to catch bad parquet object")
+	}
+	//a truncated file must fail rather than panic
+	body := testParquetFile(t, compress.Codecs.Snappy)
+	for _, n := range []int{len(body) / 2, len(body) - 8} {
+		if err = processParquetContext(bytes.NewReader(body[n:]), ``, rs); err == nil {
+			t.Fatalf("failed to catch parquet object truncated at %d", n)
+		}
+	}
+}
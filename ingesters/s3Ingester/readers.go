@@ -0,0 +1,310 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+	"github.com/gravwell/jsonparser"
+)
+
+const (
+	defaultVPCFlowTimeField = `start`
+	defaultVPCFlowTimeIndex = 10 // start is the 11th field of the default version 2 format
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// recordSink is where the readers send records, it handles timestamps and hands entries to the processors
+type recordSink struct {
+	ctx  context.Context
+	tg   *timegrinder.TimeGrinder
+	src  net.IP
+	tag  entry.EntryTag
+	proc *processors.ProcessorSet
+}
+
+// emit sends a record, tsb is the part of the record that holds the timestamp which is usually a
+// single field, if it is nil the whole record is handed to the timegrinder.
+// The data is not copied, so callers that reuse buffers must hand in a copy.
+func (rs *recordSink) emit(data, tsb []byte) error {
+	if tsb == nil {
+		tsb = data
+	}
+	ts, skew := extractTimestamp(rs.tg, tsb)
+	ent := entry.Entry{
+		TS:   ts,
+		SRC:  rs.src, //may be nil, ingest muxer will handle if it is
+		Tag:  rs.tag,
+		Data: data,
+	}
+	markSkew(&ent, rs.tg, skew)
+	if rs.ctx != nil {
+		return rs.proc.ProcessContext(&ent, rs.ctx)
+	}
+	return rs.proc.Process(&ent)
+}
+
+// decodeBody transparently decompresses gzip objects, most AWS services write their logs gzipped
+// without setting a content encoding so the transport doesn't do it for us
+func decodeBody(rdr io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(rdr)
+	if hdr, err := br.Peek(len(gzipMagic)); err == nil && bytes.Equal(hdr, gzipMagic) {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+// processJSONContext handles objects that are a JSON array of records or a stream of records
+func processJSONContext(rdr io.Reader, tsField string, rs *recordSink) (err error) {
+	br := bufio.NewReader(rdr)
+	dec := json.NewDecoder(br)
+	var array bool
+	if array, err = leadingArray(br); err != nil {
+		return
+	} else if array {
+		if _, err = dec.Token(); err != nil {
+			return
+		}
+	}
+	var path []string
+	if tsField != `` {
+		path = strings.Split(tsField, `.`)
+	}
+	for {
+		var raw json.RawMessage
+		if array && !dec.More() {
+			break
+		} else if err = dec.Decode(&raw); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return fmt.Errorf("invalid JSON record: %w", err)
+		}
+		bb := bytes.NewBuffer(make([]byte, 0, len(raw)))
+		if err = json.Compact(bb, raw); err != nil {
+			return
+		}
+		rec := bb.Bytes()
+		var tsb []byte
+		if path != nil {
+			if v, vt, _, lerr := jsonparser.Get(rec, path...); lerr == nil && vt != jsonparser.Null {
+				tsb = v
+			}
+		}
+		if err = rs.emit(rec, tsb); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// leadingArray checks if the first non-whitespace character is the start of an array
+func leadingArray(br *bufio.Reader) (bool, error) {
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c == '[', br.UnreadByte()
+	}
+}
+
+// processCSVContext reads a CSV object with a header row and emits each row as a JSON object.
+// Rows with more columns than the header get names like field12 for the extras.
+func processCSVContext(rdr io.Reader, tsField string, rs *recordSink) (err error) {
+	cr := csv.NewReader(rdr)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.ReuseRecord = true
+	var header []string
+	if header, err = cr.Read(); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	names := make([]string, len(header))
+	tsIdx := -1
+	for i, h := range header {
+		if i == 0 {
+			h = strings.TrimPrefix(h, "\ufeff") //excel likes to lead with a byte order mark
+		}
+		if names[i] = strings.TrimSpace(h); names[i] == `` {
+			names[i] = `field` + strconv.Itoa(i+1)
+		}
+		if tsField != `` && names[i] == tsField {
+			tsIdx = i
+		}
+	}
+	for {
+		var row []string
+		if row, err = cr.Read(); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return fmt.Errorf("invalid CSV row: %w", err)
+		}
+		bb := bytes.NewBuffer(nil)
+		bb.WriteByte('{')
+		for i, v := range row {
+			name := `field` + strconv.Itoa(i+1)
+			if i < len(names) {
+				name = names[i]
+			}
+			if i > 0 {
+				bb.WriteByte(',')
+			}
+			writeJSONString(bb, name)
+			bb.WriteByte(':')
+			writeJSONString(bb, v)
+		}
+		bb.WriteByte('}')
+		var tsb []byte
+		if tsIdx >= 0 && tsIdx < len(row) {
+			tsb = []byte(row[tsIdx])
+		}
+		if err = rs.emit(bb.Bytes(), tsb); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func writeJSONString(bb *bytes.Buffer, s string) {
+	v, _ := json.Marshal(s) //marshalling a string can't fail
+	bb.Write(v)
+}
+
+// processVPCFlowContext handles VPC flow logs, header lines are dropped and the timestamp
+// comes from the start column which is located using the header
+func processVPCFlowContext(rdr io.Reader, maxLineSize int, tsField string, rs *recordSink) (err error) {
+	if tsField == `` {
+		tsField = defaultVPCFlowTimeField
+	}
+	tsIdx := defaultVPCFlowTimeIndex
+	sc := bufio.NewScanner(rdr)
+	sc.Buffer(nil, maxLineSize)
+	for sc.Scan() {
+		bts := sc.Bytes()
+		if len(bts) == 0 {
+			continue
+		} else if isVPCFlowHeader(bts) {
+			//custom formats may move the start column around
+			for i, f := range strings.Fields(string(bts)) {
+				if f == tsField {
+					tsIdx = i
+					break
+				}
+			}
+			continue
+		}
+		data := bytes.Clone(bts) //scanner re-uses the buffer
+		if err = rs.emit(data, nthField(data, tsIdx)); err != nil {
+			return
+		}
+	}
+	return sc.Err()
+}
+
+// isVPCFlowHeader checks if a line is a header, header fields are lower case names like srcaddr and
+// log-status while every record has digits or upper case values like ACCEPT and OK
+func isVPCFlowHeader(b []byte) bool {
+	for _, c := range b {
+		if (c < 'a' || c > 'z') && c != '-' && c != ' ' {
+			return false
+		}
+	}
+	return true
+}
+
+// processLoadBalancerContext handles ALB and classic ELB access logs, the timestamp field is
+// the second field for application load balancers and the first for classic load balancers
+func processLoadBalancerContext(rdr io.Reader, maxLineSize int, tsIdx int, rs *recordSink) (err error) {
+	sc := bufio.NewScanner(rdr)
+	sc.Buffer(nil, maxLineSize)
+	for sc.Scan() {
+		bts := sc.Bytes()
+		if len(bts) == 0 {
+			continue
+		}
+		data := bytes.Clone(bts) //scanner re-uses the buffer
+		if err = rs.emit(data, nthField(data, tsIdx)); err != nil {
+			return
+		}
+	}
+	return sc.Err()
+}
+
+// nthField returns the nth space delimited field, or nil if there aren't that many
+func nthField(b []byte, n int) []byte {
+	for i := 0; ; i++ {
+		b = bytes.TrimLeft(b, " \t")
+		if len(b) == 0 {
+			return nil
+		}
+		end := bytes.IndexAny(b, " \t")
+		if end < 0 {
+			end = len(b)
+		}
+		if i == n {
+			return b[:end]
+		}
+		b = b[end:]
+	}
+}
+
+// processParquetContext spools the object to disk, parquet keeps its metadata at the end of the
+// file so it can't be streamed, and then emits each row as a JSON object
+func processParquetContext(rdr io.Reader, tsField string, rs *recordSink) (err error) {
+	var fout *os.File
+	if fout, err = os.CreateTemp(``, `s3parquet`); err != nil {
+		return
+	}
+	defer os.Remove(fout.Name())
+	defer fout.Close()
+	if _, err = io.Copy(fout, rdr); err != nil {
+		return fmt.Errorf("failed to spool parquet object: %w", err)
+	}
+	return readParquet(rs.ctx, fout, func(row parquetRow) error {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		var tsb []byte
+		if tsField != `` {
+			if v, ok := row.get(tsField); ok && v != nil {
+				if tsb, err = json.Marshal(v); err != nil {
+					return err
+				}
+				tsb = bytes.Trim(tsb, `"`)
+			}
+		}
+		return rs.emit(data, tsb)
+	})
+}
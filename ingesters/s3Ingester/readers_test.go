@@ -0,0 +1,193 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+	"github.com/gravwell/gravwell/v3/ingest/processors"
+	"github.com/gravwell/gravwell/v3/timegrinder"
+)
+
+type testWriter struct {
+	ents []*entry.Entry
+}
+
+func (tw *testWriter) WriteEntry(ent *entry.Entry) error {
+	tw.ents = append(tw.ents, ent)
+	return nil
+}
+
+func (tw *testWriter) WriteEntryContext(ctx context.Context, ent *entry.Entry) error {
+	return tw.WriteEntry(ent)
+}
+
+func (tw *testWriter) WriteBatch(ents []*entry.Entry) error {
+	tw.ents = append(tw.ents, ents...)
+	return nil
+}
+
+func (tw *testWriter) WriteBatchContext(ctx context.Context, ents []*entry.Entry) error {
+	return tw.WriteBatch(ents)
+}
+
+type readerTest struct {
+	name    string
+	rdr     reader
+	tsField string
+	body    string
+	data    []string
+	ts      []time.Time
+}
+
+func runReaderTest(t *testing.T, tc readerTest, body []byte) {
+	tg, err := timegrinder.New(timegrinder.Config{EnableLeftMostSeed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tw testWriter
+	rs := &recordSink{tg: tg, proc: processors.NewProcessorSet(&tw)}
+	var rdr io.Reader = bytes.NewReader(body)
+	if tc.rdr.gunzip() {
+		if rdr, err = decodeBody(rdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	switch tc.rdr {
+	case jsonReader:
+		err = processJSONContext(rdr, tc.tsField, rs)
+	case csvReader:
+		err = processCSVContext(rdr, tc.tsField, rs)
+	case vpcFlowReader:
+		err = processVPCFlowContext(rdr, defaultMaxLineSize, tc.tsField, rs)
+	case albReader:
+		err = processLoadBalancerContext(rdr, defaultMaxLineSize, 1, rs)
+	case elbReader:
+		err = processLoadBalancerContext(rdr, defaultMaxLineSize, 0, rs)
+	case parquetReader:
+		err = processParquetContext(rdr, tc.tsField, rs)
+	case lineReader:
+		err = processLinesContext(rdr, defaultMaxLineSize, rs)
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", tc.name, err)
+	} else if len(tw.ents) != len(tc.data) {
+		t.Fatalf("%s: got %d entries, expected %d", tc.name, len(tw.ents), len(tc.data))
+	}
+	for i, ent := range tw.ents {
+		if string(ent.Data) != tc.data[i] {
+			t.Fatalf("%s: entry %d data mismatch\n%s\n%s", tc.name, i, ent.Data, tc.data[i])
+		} else if !ent.TS.StandardTime().Equal(tc.ts[i]) {
+			t.Fatalf("%s: entry %d timestamp mismatch %v != %v", tc.name, i, ent.TS.StandardTime(), tc.ts[i])
+		}
+	}
+}
+
+func TestReaders(t *testing.T) {
+	t1 := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	t2 := time.Date(2024, 3, 5, 5, 6, 7, 0, time.UTC)
+	alb1 := `https 2024-03-04T05:06:07.000000Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 0 2024-03-01T01:02:03.000000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+	elb1 := `2024-03-05T05:06:07.000000Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`
+	vpc1 := `2 123456789010 eni-1235b8ca123456789 172.31.16.139 172.31.16.21 20641 22 6 20 4249 1709528767 1709528827 ACCEPT OK`
+	vpc2 := `eni-1235b8ca123456789 1709615167 ACCEPT`
+	tests := []readerTest{
+		{
+			name: `json array`,
+			rdr:  jsonReader,
+			body: "[\n {\"a\": 1, \"when\": \"2024-03-04T05:06:07Z\"},\n {\"a\": 2, \"nested\": {\"when\": \"2024-03-05T05:06:07Z\"}}\n]\n",
+			data: []string{`{"a":1,"when":"2024-03-04T05:06:07Z"}`, `{"a":2,"nested":{"when":"2024-03-05T05:06:07Z"}}`},
+			ts:   []time.Time{t1, t2},
+		},
+		{
+			name:    `json stream with field`,
+			rdr:     jsonReader,
+			tsField: `meta.ts`,
+			body:    `{"msg":"2020-01-01T00:00:00Z","meta":{"ts":1709528767}}` + "\n" + `{"msg":"x","meta":{"ts":"2024-03-05T05:06:07Z"}}`,
+			data:    []string{`{"msg":"2020-01-01T00:00:00Z","meta":{"ts":1709528767}}`, `{"msg":"x","meta":{"ts":"2024-03-05T05:06:07Z"}}`},
+			ts:      []time.Time{t1, t2},
+		},
+		{
+			name:    `csv`,
+			rdr:     csvReader,
+			tsField: `Time`,
+			body:    "\ufeffName,Time,,Note\nalpha,2024-03-04T05:06:07Z,x,\"with, comma\"\nbeta,2024-03-05T05:06:07Z,y,z,extra\n",
+			data: []string{
+				`{"Name":"alpha","Time":"2024-03-04T05:06:07Z","field3":"x","Note":"with, comma"}`,
+				`{"Name":"beta","Time":"2024-03-05T05:06:07Z","field3":"y","Note":"z","field5":"extra"}`,
+			},
+			ts: []time.Time{t1, t2},
+		},
+		{
+			name: `vpc flow`,
+			rdr:  vpcFlowReader,
+			body: "version account-id interface-id srcaddr dstaddr srcport dstport protocol packets bytes start end action log-status\n" +
+				vpc1 + "\n" +
+				"interface-id start action\n" + //a different format appended to the same object
+				vpc2 + "\n",
+			data: []string{vpc1, vpc2},
+			ts:   []time.Time{t1, t2},
+		},
+		{
+			name: `alb`,
+			rdr:  albReader,
+			body: alb1 + "\n",
+			data: []string{alb1},
+			ts:   []time.Time{t1},
+		},
+		{
+			name: `elb`,
+			rdr:  elbReader,
+			body: elb1 + "\n",
+			data: []string{elb1},
+			ts:   []time.Time{t2},
+		},
+	}
+	for _, tc := range tests {
+		runReaderTest(t, tc, []byte(tc.body))
+
+		//everything should work the same when gzipped
+		bb := bytes.NewBuffer(nil)
+		gz := gzip.NewWriter(bb)
+		gz.Write([]byte(tc.body))
+		gz.Close()
+		tc.name += ` gzipped`
+		runReaderTest(t, tc, bb.Bytes())
+	}
+}
+
+func TestReaderGunzip(t *testing.T) {
+	//existing readers hand over objects as stored, only the structured readers decompress
+	for _, r := range []reader{lineReader, cloudtrailReader, parquetReader} {
+		if r.gunzip() {
+			t.Fatalf("%s reader decompresses objects", r)
+		}
+	}
+	for _, r := range []reader{jsonReader, csvReader, vpcFlowReader, albReader, elbReader} {
+		if !r.gunzip() {
+			t.Fatalf("%s reader does not decompress objects", r)
+		}
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	for _, v := range []string{``, `line`, `CloudTrail`, `json`, `csv`, `vpcflow`, `alb`, `elb`, ` parquet `} {
+		if _, err := parseReader(v); err != nil {
+			t.Fatalf("failed to parse %q: %v", v, err)
+		}
+	}
+	if _, err := parseReader(`avro`); err != ErrUnknownReader {
+		t.Fatalf("bad error on unknown reader: %v", err)
+	}
+}
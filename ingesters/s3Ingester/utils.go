@@ -18,9 +18,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/arn"
-	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/gravwell/gravwell/v3/ingest/entry"
 	"github.com/gravwell/gravwell/v3/ingest/log"
@@ -32,6 +30,14 @@ import (
 const (
 	lineReader       reader = `line`
 	cloudtrailReader reader = `cloudtrail`
+	jsonReader       reader = `json`
+	csvReader        reader = `csv`
+	vpcFlowReader    reader = `vpcflow`
+	albReader        reader = `alb`
+	elbReader        reader = `elb`
+	parquetReader    reader = `parquet`
+
+	skewEVName = `timestamp_skew`
 )
 
 var (
@@ -40,6 +46,16 @@ var (
 
 type reader string
 
+// gunzip returns true if gzipped objects are transparently decompressed for the reader.
+// The line and cloudtrail readers have always handed over objects exactly as stored, parquet has its own compression.
+func (r reader) gunzip() bool {
+	switch r {
+	case jsonReader, csvReader, vpcFlowReader, albReader, elbReader:
+		return true
+	}
+	return false
+}
+
 type matcher struct {
 	patterns []string
 }
@@ -180,10 +196,8 @@ func parseReader(v string) (reader, error) {
 	switch reader(v) {
 	case ``: //empty means line
 		return lineReader, nil
-	case lineReader:
-		return lineReader, nil
-	case cloudtrailReader:
-		return cloudtrailReader, nil
+	case lineReader, cloudtrailReader, jsonReader, csvReader, vpcFlowReader, albReader, elbReader, parquetReader:
+		return reader(v), nil
 	}
 	return ``, ErrUnknownReader
 }
@@ -280,35 +294,49 @@ var (
 	awsUrlRegex = regexp.MustCompile(`s3[-\.]?([a-zA-Z\-0-9]+)?\.amazonaws\.com`)
 )
 
-func ProcessContext(obj *s3.Object, ctx context.Context, svc *s3.S3, bucket string, rdr reader, tg *timegrinder.TimeGrinder, src net.IP, tag entry.EntryTag, proc *processors.ProcessorSet, maxLineSize int) (sz int64, s3rtt, rtt time.Duration, err error) {
-	var r *s3.GetObjectOutput
+// ProcessContext fetches an object from a store and hands its contents to the reader
+func ProcessContext(ctx context.Context, store objectStore, key string, rdr reader, tg *timegrinder.TimeGrinder, src net.IP, tag entry.EntryTag, proc *processors.ProcessorSet, maxLineSize int, tsField string) (sz int64, fetchrtt, rtt time.Duration, err error) {
+	var r io.ReadCloser
 	now := time.Now()
-	r, err = svc.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    obj.Key,
-	})
-	if err != nil {
+	if r, sz, err = store.Open(ctx, key); err != nil {
 		return
 	}
-	defer r.Body.Close()
-	s3rtt = time.Since(now)
+	defer r.Close()
+	fetchrtt = time.Since(now)
+
+	rs := &recordSink{ctx: ctx, tg: tg, src: src, tag: tag, proc: proc}
+	var body io.Reader = r
+	if rdr.gunzip() {
+		if body, err = decodeBody(r); err != nil {
+			return
+		}
+	}
 
 	switch rdr {
 	case lineReader:
-		err = processLinesContext(ctx, r.Body, maxLineSize, tg, src, tag, proc)
+		err = processLinesContext(body, maxLineSize, rs)
 	case cloudtrailReader:
-		err = processCloudtrailContext(ctx, r.Body, tg, src, tag, proc)
+		err = processCloudtrailContext(body, rs)
+	case jsonReader:
+		err = processJSONContext(body, tsField, rs)
+	case csvReader:
+		err = processCSVContext(body, tsField, rs)
+	case vpcFlowReader:
+		err = processVPCFlowContext(body, maxLineSize, tsField, rs)
+	case albReader:
+		err = processLoadBalancerContext(body, maxLineSize, 1, rs)
+	case elbReader:
+		err = processLoadBalancerContext(body, maxLineSize, 0, rs)
+	case parquetReader:
+		err = processParquetContext(body, tsField, rs)
 	default:
 		err = errors.New("no reader set")
 	}
 	rtt = time.Since(now)
-	if r != nil && r.ContentLength != nil {
-		sz = *r.ContentLength
-	}
 	return
 }
 
-func processLinesContext(ctx context.Context, rdr io.Reader, maxLineSize int, tg *timegrinder.TimeGrinder, src net.IP, tag entry.EntryTag, proc *processors.ProcessorSet) (err error) {
+func processLinesContext(rdr io.Reader, maxLineSize int, rs *recordSink) (err error) {
 	sc := bufio.NewScanner(rdr)
 	sc.Buffer(nil, maxLineSize)
 	for sc.Scan() {
@@ -316,22 +344,7 @@ func processLinesContext(ctx context.Context, rdr io.Reader, maxLineSize int, tg
 		if len(bts) == 0 {
 			continue
 		}
-		ts, ok, _ := tg.Extract(bts)
-		if !ok {
-			ts = time.Now()
-		}
-		ent := entry.Entry{
-			TS:   entry.FromStandard(ts),
-			SRC:  src, //may be nil, ingest muxer will handle if it is
-			Tag:  tag,
-			Data: bytes.Clone(bts), //scanner re-uses the buffer
-		}
-		if ctx != nil {
-			err = proc.ProcessContext(&ent, ctx)
-		} else {
-			err = proc.Process(&ent)
-		}
-		if err != nil {
+		if err = rs.emit(bytes.Clone(bts), nil); err != nil { //scanner re-uses the buffer
 			return //just leave
 		}
 	}
@@ -339,7 +352,7 @@ func processLinesContext(ctx context.Context, rdr io.Reader, maxLineSize int, tg
 	return
 }
 
-func processCloudtrailContext(ctx context.Context, rdr io.Reader, tg *timegrinder.TimeGrinder, src net.IP, tag entry.EntryTag, proc *processors.ProcessorSet) (err error) {
+func processCloudtrailContext(rdr io.Reader, rs *recordSink) (err error) {
 	var obj json.RawMessage
 	dec := json.NewDecoder(rdr)
 
@@ -355,27 +368,10 @@ func processCloudtrailContext(ctx context.Context, rdr io.Reader, tg *timegrinde
 		if vt == jsonparser.Object {
 			if eventTime, err := jsonparser.GetString(val, `eventTime`); err == nil {
 				bts = []byte(eventTime)
-			} else {
-				bts = val // could not match, just set to whole thing and let TG do its thing
 			}
-		} else {
-			bts = val
-		}
-		ts, ok, _ := tg.Extract(bts)
-		if !ok {
-			ts = time.Now()
-		}
-		ent := entry.Entry{
-			TS:   entry.FromStandard(ts),
-			SRC:  src,                         //may be nil, ingest muxer will handle if it is
-			Data: append([]byte(nil), val...), //scanner re-uses the buffer
-			Tag:  tag,
-		}
-		if ctx != nil {
-			cberr = proc.ProcessContext(&ent, ctx)
-		} else {
-			cberr = proc.Process(&ent)
+			// could not match, leave it nil and let TG do its thing on the whole record
 		}
+		cberr = rs.emit(append([]byte(nil), val...), bts)
 		return
 	}
 
@@ -405,6 +401,24 @@ func processCloudtrailContext(ctx context.Context, rdr io.Reader, tg *timegrinde
 	return
 }
 
+// extractTimestamp pulls a timestamp out of the data, falling back to now if there isn't one or
+// timestamps are being ignored, in which case there is no timegrinder
+func extractTimestamp(tg *timegrinder.TimeGrinder, bts []byte) (entry.Timestamp, timegrinder.Skew) {
+	if tg != nil {
+		if ts, skew, ok, _ := tg.ExtractSkew(bts); ok {
+			return entry.FromStandard(ts), skew
+		}
+	}
+	return entry.Now(), timegrinder.SkewNone
+}
+
+// markSkew attaches an enumerated value to entries whose timestamps are out of bounds when the skew policy is mark
+func markSkew(ent *entry.Entry, tg *timegrinder.TimeGrinder, skew timegrinder.Skew) {
+	if tg != nil && tg.SkewPolicy == timegrinder.SkewMark && skew != timegrinder.SkewNone {
+		ent.AddEnumeratedValueEx(skewEVName, skew.String())
+	}
+}
+
 func logSnsKeyDecode(lg *log.Logger, keytype string, buckets, keys []string) {
 	if len(buckets) != len(keys) {
 		lg.Info("successfully decoded messages", log.KV("type", keytype), log.KV("buckets", buckets), log.KV("keys", keys))
@@ -0,0 +1,149 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// Package ingesttest provides fakes of the ingest muxer for testing ingesters.
+// A Writer goes behind a processors.ProcessorSet and a Muxer stands in for the tag and sync
+// side of the muxer, so ingesters can be tested without an indexer.
+package ingesttest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/ingest/entry"
+)
+
+var (
+	ErrRefused    = errors.New("Entry refused")
+	ErrSyncFailed = errors.New("Sync failed")
+)
+
+// Muxer hands out tags and counts syncs, syncs can be made to fail
+type Muxer struct {
+	mtx      sync.Mutex
+	tags     map[string]entry.EntryTag
+	syncs    int
+	syncFail bool
+}
+
+// GetTag returns the tag for a name, new names get the next tag number
+func (m *Muxer) GetTag(name string) (entry.EntryTag, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if t, ok := m.tags[name]; ok {
+		return t, nil
+	} else if m.tags == nil {
+		m.tags = map[string]entry.EntryTag{}
+	}
+	t := entry.EntryTag(len(m.tags) + 1)
+	m.tags[name] = t
+	return t, nil
+}
+
+// Tag is GetTag for test assertions
+func (m *Muxer) Tag(name string) entry.EntryTag {
+	t, _ := m.GetTag(name)
+	return t
+}
+
+func (m *Muxer) SyncContext(ctx context.Context, to time.Duration) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.syncs++
+	if m.syncFail {
+		return ErrSyncFailed
+	}
+	return nil
+}
+
+// Syncs returns the number of sync calls, including failed ones
+func (m *Muxer) Syncs() int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.syncs
+}
+
+// FailSyncs makes every sync fail with ErrSyncFailed until it is called with false
+func (m *Muxer) FailSyncs(fail bool) {
+	m.mtx.Lock()
+	m.syncFail = fail
+	m.mtx.Unlock()
+}
+
+// Writer collects entries and can be made to refuse them
+type Writer struct {
+	mtx   sync.Mutex
+	ents  []*entry.Entry
+	limit int // refuse entries once this many are held, zero means no limit
+	fail  bool
+}
+
+func (w *Writer) WriteEntry(ent *entry.Entry) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.fail || (w.limit > 0 && len(w.ents) >= w.limit) {
+		return ErrRefused
+	}
+	w.ents = append(w.ents, ent)
+	return nil
+}
+
+func (w *Writer) WriteEntryContext(ctx context.Context, ent *entry.Entry) error {
+	return w.WriteEntry(ent)
+}
+
+func (w *Writer) WriteBatch(ents []*entry.Entry) error {
+	for _, ent := range ents {
+		if err := w.WriteEntry(ent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) WriteBatchContext(ctx context.Context, ents []*entry.Entry) error {
+	return w.WriteBatch(ents)
+}
+
+// Refuse makes every write fail with ErrRefused until it is called with false
+func (w *Writer) Refuse(fail bool) {
+	w.mtx.Lock()
+	w.fail = fail
+	w.mtx.Unlock()
+}
+
+// RefuseAfter makes writes fail with ErrRefused once n entries are held, zero removes the limit
+func (w *Writer) RefuseAfter(n int) {
+	w.mtx.Lock()
+	w.limit = n
+	w.mtx.Unlock()
+}
+
+// Count returns the number of entries held
+func (w *Writer) Count() int {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return len(w.ents)
+}
+
+// Entries returns the entries held
+func (w *Writer) Entries() []*entry.Entry {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return append([]*entry.Entry(nil), w.ents...)
+}
+
+// Take returns the entries held and forgets them
+func (w *Writer) Take() (ents []*entry.Entry) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	ents, w.ents = w.ents, nil
+	return
+}
@@ -0,0 +1,46 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+var (
+	minRetryBackoff = time.Second
+	maxRetryBackoff = 30 * time.Second
+)
+
+// RunWithRetry calls run until the context is cancelled, waiting between failures with an exponential backoff.
+// The backoff starts over when run lasted longer than the maximum backoff, a connection that was up for a
+// while and then dropped is a fresh failure.  failed is called with each error and the wait before the next
+// attempt, it is not called for the failure caused by cancelling the context.
+func RunWithRetry(ctx context.Context, run func() error, failed func(err error, retry time.Duration)) {
+	backoff := minRetryBackoff
+	for {
+		start := time.Now()
+		err := run()
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > maxRetryBackoff {
+			backoff = minRetryBackoff
+		}
+		failed(err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
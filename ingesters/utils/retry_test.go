@@ -0,0 +1,52 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunWithRetry(t *testing.T) {
+	origMin, origMax := minRetryBackoff, maxRetryBackoff
+	minRetryBackoff, maxRetryBackoff = 5*time.Millisecond, 40*time.Millisecond
+	defer func() { minRetryBackoff, maxRetryBackoff = origMin, origMax }()
+
+	errFail := errors.New("fail")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var runs int
+	var retries []time.Duration
+	RunWithRetry(ctx, func() error {
+		if runs++; runs == 6 {
+			//connected for longer than the max backoff, the next failure starts over
+			time.Sleep(2 * maxRetryBackoff)
+		} else if runs == 8 {
+			cancel()
+			return context.Canceled
+		}
+		return errFail
+	}, func(err error, retry time.Duration) {
+		if err != errFail {
+			t.Fatalf("bad error %v", err)
+		}
+		retries = append(retries, retry)
+	})
+	want := []time.Duration{5, 10, 20, 40, 40, 5, 10}
+	if len(retries) != len(want) {
+		t.Fatalf("bad retries %v", retries)
+	}
+	for i := range want {
+		if retries[i] != want[i]*time.Millisecond {
+			t.Fatalf("bad retry %d %v != %v", i, retries[i], want[i]*time.Millisecond)
+		}
+	}
+}
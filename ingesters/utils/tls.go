@@ -0,0 +1,52 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSClientConfig holds the TLS settings of an ingester that connects out to a broker or server
+type TLSClientConfig struct {
+	ServerName         string // name the server certificate must match, the dialed host is used if empty
+	CAFile             string // PEM bundle that replaces the system roots
+	CertFile           string // client certificate, required with KeyFile for certificate authentication
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+// Load builds the tls.Config, reading the CA bundle and client certificate from disk
+func (c TLSClientConfig) Load() (tc *tls.Config, err error) {
+	tc = &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	if c.CAFile != `` {
+		var b []byte
+		if b, err = os.ReadFile(c.CAFile); err != nil {
+			return nil, err
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in CA-File %q", c.CAFile)
+		}
+	}
+	if c.CertFile != `` {
+		var cert tls.Certificate
+		if cert, err = tls.LoadX509KeyPair(c.CertFile, c.KeyFile); err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return
+}
@@ -0,0 +1,87 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self signed certificate and its key as PEM files
+func writeTestCert(t *testing.T, dir string) (certPath, keyPath string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: `test`},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath, keyPath = filepath.Join(dir, `cert.pem`), filepath.Join(dir, `key.pem`)
+	if err = os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: `CERTIFICATE`, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	} else if err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: `EC PRIVATE KEY`, Bytes: kder}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestTLSClientConfig(t *testing.T) {
+	dir := t.TempDir()
+	cert, key := writeTestCert(t, dir)
+	tc, err := TLSClientConfig{ServerName: `broker`, InsecureSkipVerify: true}.Load()
+	if err != nil {
+		t.Fatal(err)
+	} else if tc.MinVersion != tls.VersionTLS12 || tc.ServerName != `broker` || !tc.InsecureSkipVerify || tc.RootCAs != nil || len(tc.Certificates) != 0 {
+		t.Fatalf("bad config %+v", tc)
+	}
+	if tc, err = (TLSClientConfig{CAFile: cert, CertFile: cert, KeyFile: key}).Load(); err != nil {
+		t.Fatal(err)
+	} else if tc.RootCAs == nil || len(tc.Certificates) != 1 {
+		t.Fatalf("certificates not loaded %+v", tc)
+	}
+
+	empty := filepath.Join(dir, `empty.pem`)
+	if err = os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	bad := []TLSClientConfig{
+		{CAFile: filepath.Join(dir, `missing.pem`)},
+		{CAFile: empty},
+		{CertFile: cert},
+		{CertFile: cert, KeyFile: empty},
+	}
+	for i, c := range bad {
+		if _, err = c.Load(); err == nil {
+			t.Fatalf("failed to catch bad config %d", i)
+		}
+	}
+}
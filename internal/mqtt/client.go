@@ -0,0 +1,462 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// Package mqtt implements a minimal MQTT 3.1.1 and 5 subscriber.
+// Messages are handed to a handler one at a time in the order they arrive and QoS 1 and 2 messages
+// are only acknowledged after the handler accepts them, so a message the handler fails on is
+// redelivered by the broker when the session resumes.
+//
+// The Eclipse clients split the protocol versions, paho.mqtt.golang only speaks 3.1.1 and
+// paho.golang only speaks 5, and each has its own session and acknowledgement model.  Supporting
+// both versions behind one ingester would mean two clients with different redelivery behavior,
+// while the subscribe side of the protocol is small enough to implement once.  The package is
+// internal because it only implements what the MQTT ingester needs; it never publishes.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/internal/mqtt/packet"
+)
+
+// protocol levels
+const (
+	Version311 = packet.Version311
+	Version5   = packet.Version5
+)
+
+const (
+	DefaultKeepAlive      = 30 * time.Second
+	DefaultConnectTimeout = 10 * time.Second
+	DefaultMaxPacketSize  = 16 * 1024 * 1024
+	DefaultReceiveMaximum = 256
+
+	writeTimeout = 10 * time.Second
+	subscribeID  = 1
+)
+
+var (
+	ErrConnectionRefused = errors.New("MQTT connection refused")
+	ErrSubscribeFailed   = errors.New("MQTT subscription rejected")
+	ErrUnexpectedPacket  = errors.New("Unexpected MQTT packet")
+	ErrServerDisconnect  = errors.New("MQTT server disconnected")
+	ErrNoSubscriptions   = errors.New("No subscriptions")
+	ErrBadVersion        = errors.New("MQTT protocol version must be 3.1.1 or 5")
+	ErrBadQoS            = errors.New("MQTT QoS must be 0, 1, or 2")
+	ErrTopicAlias        = errors.New("MQTT topic aliases are not supported")
+)
+
+type Subscription struct {
+	Filter string
+	QoS    byte
+}
+
+type Config struct {
+	Address        string      // host:port of the broker
+	TLS            *tls.Config // nil for plain TCP
+	Version        byte        // Version311 or Version5, defaults to 3.1.1
+	ClientID       string
+	CleanSession   bool
+	SessionExpiry  uint32 // MQTT 5 session expiry in seconds, only used when CleanSession is false
+	Username       string
+	Password       string
+	KeepAlive      time.Duration
+	ConnectTimeout time.Duration
+	MaxPacketSize  int
+	ReceiveMaximum uint16 // MQTT 5 limit on unacknowledged QoS 1 and 2 messages
+	Subscriptions  []Subscription
+}
+
+// Message is a single published message
+type Message struct {
+	Topic          string
+	Payload        []byte
+	QoS            byte
+	Retain         bool
+	Duplicate      bool
+	UserProperties [][2]string // MQTT 5 only
+}
+
+// Handler is called for each message in order, returning nil acknowledges the message.
+// Returning an error tears down the connection without acknowledging the message.
+type Handler func(*Message) error
+
+// Client is a subscriber, the session state for QoS 2 messages survives across calls to Run
+type Client struct {
+	cfg  Config
+	mtx  sync.Mutex
+	qos2 map[uint16]bool // QoS 2 messages that were delivered and are waiting on a PUBREL
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Version == 0 {
+		cfg.Version = Version311
+	} else if cfg.Version != Version311 && cfg.Version != Version5 {
+		return nil, ErrBadVersion
+	}
+	if _, _, err := net.SplitHostPort(cfg.Address); err != nil {
+		return nil, fmt.Errorf("Invalid broker address %q: %w", cfg.Address, err)
+	}
+	if len(cfg.Subscriptions) == 0 {
+		return nil, ErrNoSubscriptions
+	}
+	for _, s := range cfg.Subscriptions {
+		if err := ValidateFilter(s.Filter); err != nil {
+			return nil, err
+		} else if s.QoS > 2 {
+			return nil, ErrBadQoS
+		}
+	}
+	if cfg.ClientID == `` && !cfg.CleanSession && cfg.Version == Version311 {
+		return nil, errors.New("A persistent MQTT 3.1.1 session requires a client ID")
+	}
+	if cfg.KeepAlive == 0 {
+		cfg.KeepAlive = DefaultKeepAlive
+	} else if cfg.KeepAlive < 0 || cfg.KeepAlive > 65535*time.Second {
+		return nil, errors.New("Invalid keep alive")
+	}
+	if cfg.ConnectTimeout <= 0 {
+		cfg.ConnectTimeout = DefaultConnectTimeout
+	}
+	if cfg.MaxPacketSize <= 0 || cfg.MaxPacketSize > packet.MaxRemainingLength {
+		cfg.MaxPacketSize = DefaultMaxPacketSize
+	}
+	if cfg.ReceiveMaximum == 0 {
+		cfg.ReceiveMaximum = DefaultReceiveMaximum
+	}
+	return &Client{
+		cfg:  cfg,
+		qos2: map[uint16]bool{},
+	}, nil
+}
+
+// conn is a single connection to the broker
+type conn struct {
+	net.Conn
+	br   *bufio.Reader
+	wmtx sync.Mutex
+}
+
+func (c *conn) write(p packet.Packet) (err error) {
+	var b []byte
+	if b, err = p.Encode(); err != nil {
+		return
+	}
+	c.wmtx.Lock()
+	defer c.wmtx.Unlock()
+	if err = c.SetWriteDeadline(time.Now().Add(writeTimeout)); err == nil {
+		_, err = c.Write(b)
+	}
+	return
+}
+
+// Run connects, subscribes, and delivers messages until the context is cancelled or the connection fails.
+// It always returns a non-nil error, callers that want to stay connected call Run again after a backoff.
+func (cl *Client) Run(ctx context.Context, h Handler) error {
+	c, err := cl.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	defer stop()
+
+	if err = c.write(cl.subscribePacket()); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	queue := make(chan *inbound, cl.cfg.ReceiveMaximum)
+	done := make(chan struct{})
+	var herr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		for in := range queue {
+			if herr = cl.deliver(c, in, h); herr != nil {
+				c.Close()
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		tckr := time.NewTicker(cl.cfg.KeepAlive)
+		defer tckr.Stop()
+		for {
+			select {
+			case <-tckr.C:
+				if c.write(packet.Packet{Type: packet.Pingreq}) != nil {
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = cl.readLoop(c, queue, done)
+	close(queue)
+	c.Close()
+	wg.Wait()
+	if herr != nil {
+		err = herr
+	} else if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return err
+}
+
+type inbound struct {
+	msg Message
+	id  uint16
+}
+
+func (cl *Client) readLoop(c *conn, queue chan *inbound, done chan struct{}) error {
+	//the broker has one and a half keep alive periods to say something, we ping every period
+	readTimeout := cl.cfg.KeepAlive + cl.cfg.KeepAlive/2
+	for {
+		if err := c.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+			return err
+		}
+		p, err := packet.Read(c.br, cl.cfg.MaxPacketSize)
+		if err != nil {
+			return err
+		}
+		switch p.Type {
+		case packet.Publish:
+			in, err := cl.parsePublish(p)
+			if err != nil {
+				return err
+			}
+			select {
+			case queue <- in:
+			case <-done:
+				return errors.New("handler exited")
+			}
+		case packet.Pubrel:
+			d := packet.NewDecoder(p.Body)
+			id := d.Uint16()
+			if d.Err != nil {
+				return d.Err
+			}
+			cl.mtx.Lock()
+			delete(cl.qos2, id)
+			cl.mtx.Unlock()
+			//always complete, even for IDs we don't know about, the broker is cleaning up a previous session
+			if err = c.write(packet.Ack(packet.Pubcomp, id)); err != nil {
+				return err
+			}
+		case packet.Suback:
+			if err = cl.checkSuback(p); err != nil {
+				return err
+			}
+		case packet.Pingresp:
+		case packet.Disconnect:
+			err = ErrServerDisconnect
+			if cl.cfg.Version == Version5 && len(p.Body) > 0 {
+				err = fmt.Errorf("%w: reason 0x%02x", ErrServerDisconnect, p.Body[0])
+			}
+			return err
+		default:
+			return fmt.Errorf("%w type %d", ErrUnexpectedPacket, p.Type)
+		}
+	}
+}
+
+func (cl *Client) parsePublish(p packet.Packet) (in *inbound, err error) {
+	in = &inbound{}
+	in.msg.QoS = (p.Flags >> 1) & 0x3
+	in.msg.Retain = p.Flags&0x1 != 0
+	in.msg.Duplicate = p.Flags&0x8 != 0
+	if in.msg.QoS > 2 {
+		return nil, fmt.Errorf("%w: bad QoS", packet.ErrMalformed)
+	}
+	d := packet.NewDecoder(p.Body)
+	in.msg.Topic = d.Text()
+	if in.msg.QoS > 0 {
+		in.id = d.Uint16()
+	}
+	if cl.cfg.Version == Version5 {
+		props := d.Properties()
+		if props.TopicAlias != 0 {
+			return nil, ErrTopicAlias //we never advertise a topic alias maximum, so the broker should not send these
+		}
+		in.msg.UserProperties = props.User
+	}
+	in.msg.Payload = d.Rest()
+	if d.Err != nil {
+		return nil, d.Err
+	} else if in.msg.Topic == `` {
+		return nil, fmt.Errorf("%w: empty topic", packet.ErrMalformed)
+	}
+	return
+}
+
+// deliver hands a message to the handler and acknowledges it once the handler accepts it
+func (cl *Client) deliver(c *conn, in *inbound, h Handler) error {
+	switch in.msg.QoS {
+	case 0:
+		return h(&in.msg)
+	case 1:
+		if err := h(&in.msg); err != nil {
+			return err
+		}
+		return c.write(packet.Ack(packet.Puback, in.id))
+	}
+	//QoS 2, only deliver once per packet ID until the broker releases it
+	cl.mtx.Lock()
+	seen := cl.qos2[in.id]
+	cl.mtx.Unlock()
+	if !seen {
+		if err := h(&in.msg); err != nil {
+			return err
+		}
+		cl.mtx.Lock()
+		cl.qos2[in.id] = true
+		cl.mtx.Unlock()
+	}
+	return c.write(packet.Ack(packet.Pubrec, in.id))
+}
+
+func (cl *Client) dial(ctx context.Context) (nc net.Conn, err error) {
+	d := net.Dialer{Timeout: cl.cfg.ConnectTimeout}
+	if cl.cfg.TLS != nil {
+		td := tls.Dialer{NetDialer: &d, Config: cl.cfg.TLS}
+		return td.DialContext(ctx, `tcp`, cl.cfg.Address)
+	}
+	return d.DialContext(ctx, `tcp`, cl.cfg.Address)
+}
+
+// connect dials the broker and completes the CONNECT/CONNACK exchange
+func (cl *Client) connect(ctx context.Context) (c *conn, err error) {
+	var nc net.Conn
+	if nc, err = cl.dial(ctx); err != nil {
+		return
+	}
+	c = &conn{Conn: nc, br: bufio.NewReader(nc)}
+	defer func() {
+		if err != nil {
+			c.Close()
+			c = nil
+		}
+	}()
+	if err = c.write(cl.connectPacket()); err != nil {
+		return
+	} else if err = c.SetReadDeadline(time.Now().Add(cl.cfg.ConnectTimeout)); err != nil {
+		return
+	}
+	var p packet.Packet
+	if p, err = packet.Read(c.br, cl.cfg.MaxPacketSize); err != nil {
+		return
+	} else if p.Type != packet.Connack {
+		err = fmt.Errorf("%w type %d waiting for CONNACK", ErrUnexpectedPacket, p.Type)
+		return
+	}
+	d := packet.NewDecoder(p.Body)
+	sessionPresent := d.Byte()&0x1 != 0
+	code := d.Byte()
+	var props packet.Properties
+	if cl.cfg.Version == Version5 {
+		props = d.Properties()
+	}
+	if d.Err != nil {
+		err = d.Err
+		return
+	} else if code != 0 {
+		err = fmt.Errorf("%w: reason 0x%02x", ErrConnectionRefused, code)
+		if props.ReasonString != `` {
+			err = fmt.Errorf("%w: reason 0x%02x %s", ErrConnectionRefused, code, props.ReasonString)
+		}
+		return
+	}
+	if props.HasKeepAlive && props.ServerKeepAlive > 0 {
+		cl.cfg.KeepAlive = time.Duration(props.ServerKeepAlive) * time.Second
+	}
+	if !sessionPresent {
+		//the broker has no record of our session so nothing is waiting on a release
+		cl.mtx.Lock()
+		cl.qos2 = map[uint16]bool{}
+		cl.mtx.Unlock()
+	}
+	return
+}
+
+func (cl *Client) connectPacket() packet.Packet {
+	var flags byte
+	if cl.cfg.CleanSession || cl.cfg.ClientID == `` {
+		flags |= 0x02
+	}
+	if cl.cfg.Username != `` {
+		flags |= 0x80
+	}
+	if cl.cfg.Password != `` {
+		flags |= 0x40
+	}
+	b := packet.AppendString(nil, `MQTT`)
+	b = append(b, cl.cfg.Version, flags)
+	b = binary.BigEndian.AppendUint16(b, uint16(cl.cfg.KeepAlive/time.Second))
+	if cl.cfg.Version == Version5 {
+		var pw packet.PropWriter
+		if flags&0x02 == 0 && cl.cfg.SessionExpiry > 0 {
+			pw = pw.Uint32(packet.PropSessionExpiry, cl.cfg.SessionExpiry)
+		}
+		pw = pw.Uint16(packet.PropReceiveMaximum, cl.cfg.ReceiveMaximum)
+		pw = pw.Uint32(packet.PropMaximumPacketSize, uint32(cl.cfg.MaxPacketSize))
+		b = pw.Append(b)
+	}
+	b = packet.AppendString(b, cl.cfg.ClientID)
+	if cl.cfg.Username != `` {
+		b = packet.AppendString(b, cl.cfg.Username)
+	}
+	if cl.cfg.Password != `` {
+		b = packet.AppendBinary(b, []byte(cl.cfg.Password))
+	}
+	return packet.Packet{Type: packet.Connect, Body: b}
+}
+
+func (cl *Client) subscribePacket() packet.Packet {
+	b := binary.BigEndian.AppendUint16(nil, subscribeID)
+	if cl.cfg.Version == Version5 {
+		b = packet.PropWriter(nil).Append(b)
+	}
+	for _, s := range cl.cfg.Subscriptions {
+		b = packet.AppendString(b, s.Filter)
+		b = append(b, s.QoS)
+	}
+	return packet.Packet{Type: packet.Subscribe, Flags: 0x02, Body: b}
+}
+
+func (cl *Client) checkSuback(p packet.Packet) error {
+	d := packet.NewDecoder(p.Body)
+	d.Uint16()
+	if cl.cfg.Version == Version5 {
+		d.Properties()
+	}
+	codes := d.Rest()
+	if d.Err != nil {
+		return d.Err
+	} else if len(codes) != len(cl.cfg.Subscriptions) {
+		return fmt.Errorf("%w: SUBACK has %d codes for %d subscriptions", packet.ErrMalformed, len(codes), len(cl.cfg.Subscriptions))
+	}
+	for i, c := range codes {
+		if c >= 0x80 {
+			return fmt.Errorf("%w: %s reason 0x%02x", ErrSubscribeFailed, cl.cfg.Subscriptions[i].Filter, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package mqtt_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/internal/mqtt"
+	"github.com/gravwell/gravwell/v3/internal/mqtt/mqtttest"
+)
+
+const testTimeout = 5 * time.Second
+
+func newTestBroker(t *testing.T) *mqtttest.Broker {
+	b, err := mqtttest.NewBroker(`127.0.0.1:0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+// waitFor polls a condition until it is true or the test times out
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+	for start := time.Now(); !f(); time.Sleep(5 * time.Millisecond) {
+		if time.Since(start) > testTimeout {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// runClient starts Run in the background, the returned channel gets the result
+func runClient(ctx context.Context, cl *mqtt.Client, h mqtt.Handler) chan error {
+	ch := make(chan error, 1)
+	go func() { ch <- cl.Run(ctx, h) }()
+	return ch
+}
+
+func waitResult(t *testing.T, ch chan error) error {
+	t.Helper()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(testTimeout):
+		t.Fatal("timed out waiting for client to exit")
+	}
+	return nil
+}
+
+func TestClientDelivery(t *testing.T) {
+	for _, v := range []byte{mqtt.Version311, mqtt.Version5} {
+		t.Run(fmt.Sprintf("v%d", v), func(t *testing.T) {
+			b := newTestBroker(t)
+			b.SetCredentials(`user`, `pass`)
+			cl, err := mqtt.NewClient(mqtt.Config{
+				Address:  b.Addr(),
+				Version:  v,
+				ClientID: `sub`,
+				Username: `user`,
+				Password: `pass`,
+				Subscriptions: []mqtt.Subscription{
+					{Filter: `sensors/+/temp`, QoS: 1},
+					{Filter: `$share/workers/alerts/#`, QoS: 2},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			msgs := make(chan *mqtt.Message, 16)
+			ctx, cf := context.WithCancel(context.Background())
+			defer cf()
+			res := runClient(ctx, cl, func(m *mqtt.Message) error {
+				msgs <- m
+				return nil
+			})
+			waitFor(t, `subscription`, func() bool { return b.Subscribed(`sub`) })
+
+			b.Publish(`sensors/a/temp`, []byte(`qos0`), 0)
+			b.Publish(`sensors/a/humidity`, []byte(`unmatched`), 1)
+			b.Publish(`sensors/b/temp`, []byte(`qos1`), 2) //downgraded to the subscription QoS
+			b.Publish(`alerts/fire/kitchen`, []byte(`qos2`), 2)
+			want := []struct {
+				topic, payload string
+				qos            byte
+			}{
+				{`sensors/a/temp`, `qos0`, 0},
+				{`sensors/b/temp`, `qos1`, 1},
+				{`alerts/fire/kitchen`, `qos2`, 2},
+			}
+			for _, w := range want {
+				select {
+				case m := <-msgs:
+					if m.Topic != w.topic || string(m.Payload) != w.payload || m.QoS != w.qos {
+						t.Fatalf("bad message: %+v != %+v", m, w)
+					}
+				case <-time.After(testTimeout):
+					t.Fatal("timed out waiting for message")
+				}
+			}
+			waitFor(t, `acknowledgements`, func() bool { return b.Pending(`sub`) == 0 })
+			cf()
+			if err = waitResult(t, res); !errors.Is(err, context.Canceled) {
+				t.Fatalf("bad exit error: %v", err)
+			}
+			if len(msgs) != 0 {
+				t.Fatalf("unexpected extra messages: %d", len(msgs))
+			}
+		})
+	}
+}
+
+func TestClientRedelivery(t *testing.T) {
+	b := newTestBroker(t)
+	cl, err := mqtt.NewClient(mqtt.Config{
+		Address:       b.Addr(),
+		Version:       mqtt.Version5,
+		ClientID:      `sub`,
+		SessionExpiry: 60,
+		Subscriptions: []mqtt.Subscription{{Filter: `#`, QoS: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cf := context.WithCancel(context.Background())
+	defer cf()
+	errFull := errors.New("full")
+	res := runClient(ctx, cl, func(m *mqtt.Message) error {
+		return errFull
+	})
+	waitFor(t, `subscription`, func() bool { return b.Subscribed(`sub`) })
+	b.Publish(`a`, []byte(`first`), 1)
+	b.Publish(`b`, []byte(`second`), 1)
+	if err = waitResult(t, res); !errors.Is(err, errFull) {
+		t.Fatalf("handler error not returned: %v", err)
+	} else if n := b.Pending(`sub`); n != 2 {
+		t.Fatalf("rejected messages were acknowledged: %d pending", n)
+	}
+
+	//the persistent session redelivers in order
+	var got []*mqtt.Message
+	res = runClient(ctx, cl, func(m *mqtt.Message) error {
+		got = append(got, m)
+		return nil
+	})
+	waitFor(t, `acknowledgements`, func() bool { return b.Pending(`sub`) == 0 })
+	cf()
+	waitResult(t, res)
+	if len(got) != 2 || string(got[0].Payload) != `first` || string(got[1].Payload) != `second` || !got[0].Duplicate {
+		t.Fatalf("bad redelivery: %+v", got)
+	}
+}
+
+func TestClientQoS2Duplicate(t *testing.T) {
+	b := newTestBroker(t)
+	b.DropPubrec(1) //act like the first PUBREC was lost so the broker resends the PUBLISH
+	cl, err := mqtt.NewClient(mqtt.Config{
+		Address:       b.Addr(),
+		ClientID:      `sub`,
+		Subscriptions: []mqtt.Subscription{{Filter: `a/#`, QoS: 2}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	h := func(m *mqtt.Message) error {
+		count++
+		return nil
+	}
+	ctx, cf := context.WithCancel(context.Background())
+	res := runClient(ctx, cl, h)
+	waitFor(t, `subscription`, func() bool { return b.Subscribed(`sub`) })
+	b.Publish(`a/b`, []byte(`once`), 2)
+	waitFor(t, `delivery`, func() bool { return b.DroppingPubrec() == 0 })
+	cf()
+	waitResult(t, res)
+
+	ctx, cf = context.WithCancel(context.Background())
+	defer cf()
+	res = runClient(ctx, cl, h)
+	waitFor(t, `acknowledgements`, func() bool { return b.Pending(`sub`) == 0 })
+	cf()
+	waitResult(t, res)
+	if count != 1 {
+		t.Fatalf("QoS 2 message delivered %d times", count)
+	}
+}
+
+func TestClientFailures(t *testing.T) {
+	b := newTestBroker(t)
+	b.SetCredentials(`user`, `pass`)
+	b.Reject(`private/#`)
+	nop := func(*mqtt.Message) error { return nil }
+	for _, v := range []byte{mqtt.Version311, mqtt.Version5} {
+		cl, err := mqtt.NewClient(mqtt.Config{
+			Address:       b.Addr(),
+			Version:       v,
+			Username:      `user`,
+			Password:      `wrong`,
+			CleanSession:  true,
+			Subscriptions: []mqtt.Subscription{{Filter: `#`}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = cl.Run(context.Background(), nop); !errors.Is(err, mqtt.ErrConnectionRefused) {
+			t.Fatalf("bad credentials not refused: %v", err)
+		}
+		cl, err = mqtt.NewClient(mqtt.Config{
+			Address:       b.Addr(),
+			Version:       v,
+			Username:      `user`,
+			Password:      `pass`,
+			CleanSession:  true,
+			Subscriptions: []mqtt.Subscription{{Filter: `public/#`}, {Filter: `private/#`}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = cl.Run(context.Background(), nop); !errors.Is(err, mqtt.ErrSubscribeFailed) {
+			t.Fatalf("rejected subscription not reported: %v", err)
+		}
+	}
+
+	bad := []mqtt.Config{
+		{Address: b.Addr()},
+		{Address: `nohost`, Subscriptions: []mqtt.Subscription{{Filter: `#`}}},
+		{Address: b.Addr(), Version: 3, Subscriptions: []mqtt.Subscription{{Filter: `#`}}},
+		{Address: b.Addr(), Subscriptions: []mqtt.Subscription{{Filter: `a/#/b`}}},
+		{Address: b.Addr(), Subscriptions: []mqtt.Subscription{{Filter: `#`, QoS: 3}}},
+		{Address: b.Addr(), Subscriptions: []mqtt.Subscription{{Filter: `#`}}}, //persistent 3.1.1 session without an ID
+	}
+	for i, c := range bad {
+		if _, err := mqtt.NewClient(c); err == nil {
+			t.Fatalf("failed to catch bad config %d", i)
+		}
+	}
+}
+
+func TestClientKeepAlive(t *testing.T) {
+	b := newTestBroker(t)
+	cl, err := mqtt.NewClient(mqtt.Config{
+		Address:       b.Addr(),
+		ClientID:      `sub`,
+		KeepAlive:     time.Second,
+		Subscriptions: []mqtt.Subscription{{Filter: `#`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cf := context.WithCancel(context.Background())
+	defer cf()
+	res := runClient(ctx, cl, func(*mqtt.Message) error { return nil })
+	waitFor(t, `ping`, func() bool { return b.Pings() > 0 })
+	cf()
+	if err = waitResult(t, res); !errors.Is(err, context.Canceled) {
+		t.Fatalf("bad exit error: %v", err)
+	}
+}
@@ -0,0 +1,429 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// Package mqtttest provides an in-process MQTT broker for testing subscribers.
+package mqtttest
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gravwell/gravwell/v3/internal/mqtt"
+	"github.com/gravwell/gravwell/v3/internal/mqtt/packet"
+)
+
+const writeTimeout = 10 * time.Second
+
+// Broker is a minimal in-process MQTT broker for testing subscribers.  It supports 3.1.1 and 5
+// clients, persistent sessions with redelivery of unacknowledged messages, shared subscriptions,
+// and QoS 0, 1, and 2 delivery to subscribers.  Messages are injected with Publish, client
+// PUBLISH packets, retained messages, and wills are not supported.
+type Broker struct {
+	ln       net.Listener
+	mtx      sync.Mutex
+	username string
+	password string
+	sessions map[string]*brokerSession
+	conns    map[*brokerConn]bool
+	anon     int
+	wg       sync.WaitGroup
+
+	//test hooks
+	pings      int
+	reject     map[string]bool // filters that are refused at subscribe time
+	dropPubrec int             // number of PUBREC packets to ignore
+}
+
+type brokerSession struct {
+	id       string
+	clean    bool
+	version  byte
+	subs     map[string]byte // filter to max QoS
+	conn     *brokerConn
+	nextID   uint16
+	inflight map[uint16]*brokerMsg // sent and waiting on a PUBACK or PUBREC
+	released map[uint16]bool       // PUBREL sent and waiting on a PUBCOMP
+	order    []uint16              // inflight IDs in the order they were published
+}
+
+type brokerMsg struct {
+	topic   string
+	payload []byte
+	qos     byte
+}
+
+type brokerConn struct {
+	net.Conn
+	wmtx sync.Mutex
+}
+
+func (bc *brokerConn) write(p packet.Packet) error {
+	b, err := p.Encode()
+	if err != nil {
+		return err
+	}
+	bc.wmtx.Lock()
+	defer bc.wmtx.Unlock()
+	bc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err = bc.Write(b)
+	return err
+}
+
+// NewBroker starts a broker listening on the given address, use 127.0.0.1:0 to pick a free port
+func NewBroker(addr string) (*Broker, error) {
+	ln, err := net.Listen(`tcp`, addr)
+	if err != nil {
+		return nil, err
+	}
+	b := &Broker{
+		ln:       ln,
+		sessions: map[string]*brokerSession{},
+		conns:    map[*brokerConn]bool{},
+		reject:   map[string]bool{},
+	}
+	b.wg.Add(1)
+	go b.accept()
+	return b, nil
+}
+
+// Addr returns the address the broker is listening on
+func (b *Broker) Addr() string {
+	return b.ln.Addr().String()
+}
+
+// SetCredentials requires clients to present a username and password
+func (b *Broker) SetCredentials(username, password string) {
+	b.mtx.Lock()
+	b.username, b.password = username, password
+	b.mtx.Unlock()
+}
+
+// Close stops the listener and drops every connection
+func (b *Broker) Close() error {
+	err := b.ln.Close()
+	b.mtx.Lock()
+	for c := range b.conns {
+		c.Close()
+	}
+	b.mtx.Unlock()
+	b.wg.Wait()
+	return err
+}
+
+// Reject refuses subscriptions to a filter
+func (b *Broker) Reject(filter string) {
+	b.mtx.Lock()
+	b.reject[filter] = true
+	b.mtx.Unlock()
+}
+
+// DropPubrec ignores the next n PUBREC packets, as if they were lost, so QoS 2 messages are
+// resent when the client reconnects
+func (b *Broker) DropPubrec(n int) {
+	b.mtx.Lock()
+	b.dropPubrec = n
+	b.mtx.Unlock()
+}
+
+// DroppingPubrec returns the number of PUBREC packets that will still be ignored
+func (b *Broker) DroppingPubrec() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.dropPubrec
+}
+
+// Pings returns the number of PINGREQ packets received
+func (b *Broker) Pings() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.pings
+}
+
+// Subscribed reports whether a client is connected and has an active subscription
+func (b *Broker) Subscribed(clientID string) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	s, ok := b.sessions[clientID]
+	return ok && s.conn != nil && len(s.subs) > 0
+}
+
+// Pending returns the number of QoS 1 and 2 messages a client has not finished acknowledging
+func (b *Broker) Pending(clientID string) int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if s, ok := b.sessions[clientID]; ok {
+		return len(s.inflight) + len(s.released)
+	}
+	return 0
+}
+
+// Publish routes a message to every matching subscription, shared subscriptions deliver
+// to a single connected member of each group
+func (b *Broker) Publish(topic string, payload []byte, qos byte) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	groups := map[string]bool{}
+	for _, s := range b.sessions {
+		var best byte
+		var matched bool
+		for f, sq := range s.subs {
+			if !mqtt.MatchTopic(f, topic) {
+				continue
+			}
+			if g, _, _ := mqtt.SplitShared(f); g != `` {
+				if s.conn == nil || groups[f] {
+					continue
+				}
+				groups[f] = true
+			}
+			if !matched || sq > best {
+				best = sq
+			}
+			matched = true
+		}
+		if matched {
+			s.deliver(&brokerMsg{topic: topic, payload: payload, qos: min(qos, best)})
+		}
+	}
+}
+
+// deliver sends a message to a session, QoS 1 and 2 messages are held until acknowledged
+func (s *brokerSession) deliver(m *brokerMsg) {
+	var id uint16
+	if m.qos > 0 {
+		for {
+			if s.nextID++; s.nextID == 0 {
+				s.nextID = 1
+			}
+			if _, ok := s.inflight[s.nextID]; !ok && !s.released[s.nextID] {
+				break
+			}
+		}
+		id = s.nextID
+		s.inflight[id] = m
+		s.order = append(s.order, id)
+	}
+	if s.conn != nil {
+		s.conn.write(s.publishPacket(m, id, false))
+	}
+}
+
+func (s *brokerSession) publishPacket(m *brokerMsg, id uint16, dup bool) packet.Packet {
+	p := packet.Packet{Type: packet.Publish, Flags: m.qos << 1}
+	if dup {
+		p.Flags |= 0x08
+	}
+	p.Body = packet.AppendString(nil, m.topic)
+	if m.qos > 0 {
+		p.Body = binary.BigEndian.AppendUint16(p.Body, id)
+	}
+	if s.version == packet.Version5 {
+		p.Body = packet.PropWriter(nil).Append(p.Body)
+	}
+	p.Body = append(p.Body, m.payload...)
+	return p
+}
+
+// resume resends everything that is still outstanding on a reconnect
+func (s *brokerSession) resume() {
+	var order []uint16
+	for _, id := range s.order {
+		if m, ok := s.inflight[id]; ok {
+			order = append(order, id)
+			s.conn.write(s.publishPacket(m, id, true))
+		}
+	}
+	s.order = order
+	for id := range s.released {
+		s.conn.write(packet.Ack(packet.Pubrel, id))
+	}
+}
+
+func (b *Broker) accept() {
+	defer b.wg.Done()
+	for {
+		c, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		bc := &brokerConn{Conn: c}
+		b.mtx.Lock()
+		b.conns[bc] = true
+		b.mtx.Unlock()
+		b.wg.Add(1)
+		go b.serve(bc)
+	}
+}
+
+func (b *Broker) serve(bc *brokerConn) {
+	defer b.wg.Done()
+	defer func() {
+		bc.Close()
+		b.mtx.Lock()
+		delete(b.conns, bc)
+		b.mtx.Unlock()
+	}()
+	br := bufio.NewReader(bc)
+	p, err := packet.Read(br, 0)
+	if err != nil || p.Type != packet.Connect {
+		return
+	}
+	s, err := b.connect(bc, p)
+	if err != nil {
+		return
+	}
+	defer func() {
+		b.mtx.Lock()
+		if s.conn == bc {
+			s.conn = nil
+			if s.clean {
+				delete(b.sessions, s.id)
+			}
+		}
+		b.mtx.Unlock()
+	}()
+	for {
+		if p, err = packet.Read(br, 0); err != nil {
+			return
+		} else if err = b.handle(s, bc, p); err != nil {
+			return
+		}
+	}
+}
+
+func (b *Broker) connect(bc *brokerConn, p packet.Packet) (s *brokerSession, err error) {
+	d := packet.NewDecoder(p.Body)
+	proto := d.Text()
+	version := d.Byte()
+	flags := d.Byte()
+	d.Uint16() //keep alive
+	if version == packet.Version5 {
+		d.Properties()
+	}
+	id := d.Text()
+	var user, pass string
+	if flags&0x80 != 0 {
+		user = d.Text()
+	}
+	if flags&0x40 != 0 {
+		pass = string(d.Binary())
+	}
+	if d.Err != nil || proto != `MQTT` || (version != packet.Version311 && version != packet.Version5) {
+		return nil, packet.ErrMalformed
+	}
+	clean := flags&0x02 != 0
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	connack := func(present bool, code byte) error {
+		body := []byte{0, code}
+		if present {
+			body[0] = 1
+		}
+		if version == packet.Version5 {
+			body = packet.PropWriter(nil).Append(body)
+		}
+		return bc.write(packet.Packet{Type: packet.Connack, Body: body})
+	}
+	if b.username != `` && (user != b.username || pass != b.password) {
+		code := byte(0x04)
+		if version == packet.Version5 {
+			code = 0x86
+		}
+		connack(false, code)
+		return nil, errors.New("bad credentials")
+	}
+	if id == `` {
+		b.anon++
+		id = fmt.Sprintf("anon-%d", b.anon)
+	}
+	if s = b.sessions[id]; s != nil && s.conn != nil {
+		s.conn.Close() //session takeover
+		s.conn = nil
+	}
+	present := s != nil && !clean
+	if !present {
+		s = &brokerSession{
+			id:       id,
+			subs:     map[string]byte{},
+			inflight: map[uint16]*brokerMsg{},
+			released: map[uint16]bool{},
+		}
+		b.sessions[id] = s
+	}
+	s.clean, s.version, s.conn = clean, version, bc
+	if err = connack(present, 0); err != nil {
+		return
+	}
+	if present {
+		s.resume()
+	}
+	return
+}
+
+func (b *Broker) handle(s *brokerSession, bc *brokerConn, p packet.Packet) error {
+	d := packet.NewDecoder(p.Body)
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if s.conn != bc {
+		return errors.New("session taken over")
+	}
+	switch p.Type {
+	case packet.Pingreq:
+		b.pings++
+		return bc.write(packet.Packet{Type: packet.Pingresp})
+	case packet.Puback:
+		delete(s.inflight, d.Uint16())
+	case packet.Pubrec:
+		id := d.Uint16()
+		if b.dropPubrec > 0 {
+			b.dropPubrec--
+			return nil
+		}
+		if _, ok := s.inflight[id]; ok {
+			delete(s.inflight, id)
+			s.released[id] = true
+		}
+		return bc.write(packet.Ack(packet.Pubrel, id))
+	case packet.Pubcomp:
+		delete(s.released, d.Uint16())
+	case packet.Subscribe:
+		id := d.Uint16()
+		if s.version == packet.Version5 {
+			d.Properties()
+		}
+		codes := binary.BigEndian.AppendUint16(nil, id)
+		if s.version == packet.Version5 {
+			codes = packet.PropWriter(nil).Append(codes)
+		}
+		for d.Remaining() > 0 && d.Err == nil {
+			f := d.Text()
+			qos := d.Byte() & 0x3
+			if d.Err == nil && (b.reject[f] || mqtt.ValidateFilter(f) != nil || qos > 2) {
+				codes = append(codes, 0x80)
+				continue
+			}
+			s.subs[f] = qos
+			codes = append(codes, qos)
+		}
+		if d.Err != nil {
+			return d.Err
+		}
+		return bc.write(packet.Packet{Type: packet.Suback, Body: codes})
+	case packet.Disconnect:
+		return errors.New("client disconnected")
+	default:
+		return fmt.Errorf("unexpected packet type %d", p.Type)
+	}
+	return d.Err
+}
@@ -0,0 +1,330 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// Package packet encodes and decodes MQTT 3.1.1 and 5 control packets.
+// It is shared by the subscriber and the test broker so both sides speak the same wire format.
+package packet
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// protocol levels
+const (
+	Version311 byte = 4
+	Version5   byte = 5
+)
+
+// packet types
+const (
+	Connect     byte = 1
+	Connack     byte = 2
+	Publish     byte = 3
+	Puback      byte = 4
+	Pubrec      byte = 5
+	Pubrel      byte = 6
+	Pubcomp     byte = 7
+	Subscribe   byte = 8
+	Suback      byte = 9
+	Unsubscribe byte = 10
+	Unsuback    byte = 11
+	Pingreq     byte = 12
+	Pingresp    byte = 13
+	Disconnect  byte = 14
+	Auth        byte = 15
+)
+
+// MQTT 5 property identifiers we produce or consume
+const (
+	PropSessionExpiry     byte = 0x11
+	PropAssignedClientID  byte = 0x12
+	PropServerKeepAlive   byte = 0x13
+	PropReasonString      byte = 0x1f
+	PropReceiveMaximum    byte = 0x21
+	PropTopicAliasMaximum byte = 0x22
+	PropTopicAlias        byte = 0x23
+	PropUserProperty      byte = 0x26
+	PropMaximumPacketSize byte = 0x27
+)
+
+const (
+	MaxRemainingLength = 268435455 // largest value the 4 byte variable length integer can hold
+	MaxStringLength    = 65535
+)
+
+var (
+	ErrMalformed = errors.New("Malformed MQTT packet")
+	ErrTooLarge  = errors.New("MQTT packet exceeds maximum size")
+)
+
+// Packet is a raw control packet, Body holds everything after the fixed header
+type Packet struct {
+	Type  byte
+	Flags byte
+	Body  []byte
+}
+
+// Read reads a single packet, a maxSize of zero means no limit
+func Read(br *bufio.Reader, maxSize int) (p Packet, err error) {
+	var b byte
+	if b, err = br.ReadByte(); err != nil {
+		return
+	}
+	p.Type, p.Flags = b>>4, b&0x0f
+	var n int
+	if n, err = readVarInt(br); err != nil {
+		return
+	} else if maxSize > 0 && n > maxSize {
+		err = ErrTooLarge
+		return
+	}
+	p.Body = make([]byte, n)
+	_, err = io.ReadFull(br, p.Body)
+	return
+}
+
+func readVarInt(br io.ByteReader) (v int, err error) {
+	var mult = 1
+	for i := 0; i < 4; i++ {
+		var b byte
+		if b, err = br.ReadByte(); err != nil {
+			return
+		}
+		v += int(b&0x7f) * mult
+		if b&0x80 == 0 {
+			return
+		}
+		mult *= 128
+	}
+	err = ErrMalformed
+	return
+}
+
+// Ack builds a PUBACK, PUBREC, PUBREL, or PUBCOMP for a packet ID
+func Ack(typ byte, id uint16) Packet {
+	p := Packet{Type: typ, Body: binary.BigEndian.AppendUint16(nil, id)}
+	if typ == Pubrel {
+		p.Flags = 0x02
+	}
+	return p
+}
+
+func AppendVarInt(b []byte, v int) []byte {
+	for {
+		c := byte(v % 128)
+		if v /= 128; v > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if v == 0 {
+			return b
+		}
+	}
+}
+
+func AppendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func AppendBinary(b []byte, v []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(v)))
+	return append(b, v...)
+}
+
+// Encode builds the wire form of the packet
+func (p Packet) Encode() ([]byte, error) {
+	if len(p.Body) > MaxRemainingLength {
+		return nil, ErrTooLarge
+	}
+	b := make([]byte, 0, len(p.Body)+5)
+	b = append(b, p.Type<<4|p.Flags&0x0f)
+	b = AppendVarInt(b, len(p.Body))
+	return append(b, p.Body...), nil
+}
+
+// Decoder walks a packet body, the first failure is kept in Err and every later read returns a zero value
+type Decoder struct {
+	b   []byte
+	off int
+	Err error
+}
+
+func NewDecoder(b []byte) *Decoder {
+	return &Decoder{b: b}
+}
+
+func (d *Decoder) fail() {
+	if d.Err == nil {
+		d.Err = ErrMalformed
+	}
+}
+
+func (d *Decoder) Remaining() int {
+	return len(d.b) - d.off
+}
+
+func (d *Decoder) Byte() (v byte) {
+	if d.Err != nil || d.Remaining() < 1 {
+		d.fail()
+		return
+	}
+	v = d.b[d.off]
+	d.off++
+	return
+}
+
+func (d *Decoder) Uint16() (v uint16) {
+	if d.Err != nil || d.Remaining() < 2 {
+		d.fail()
+		return
+	}
+	v = binary.BigEndian.Uint16(d.b[d.off:])
+	d.off += 2
+	return
+}
+
+func (d *Decoder) Uint32() (v uint32) {
+	if d.Err != nil || d.Remaining() < 4 {
+		d.fail()
+		return
+	}
+	v = binary.BigEndian.Uint32(d.b[d.off:])
+	d.off += 4
+	return
+}
+
+func (d *Decoder) VarInt() (v int) {
+	if d.Err != nil {
+		return
+	}
+	var mult = 1
+	for i := 0; i < 4; i++ {
+		c := d.Byte()
+		if d.Err != nil {
+			return
+		}
+		v += int(c&0x7f) * mult
+		if c&0x80 == 0 {
+			return
+		}
+		mult *= 128
+	}
+	d.fail()
+	return
+}
+
+func (d *Decoder) Bytes(n int) (v []byte) {
+	if d.Err != nil || n < 0 || d.Remaining() < n {
+		d.fail()
+		return
+	}
+	v = d.b[d.off : d.off+n]
+	d.off += n
+	return
+}
+
+func (d *Decoder) Binary() []byte {
+	return d.Bytes(int(d.Uint16()))
+}
+
+// Text reads a UTF-8 encoded string
+func (d *Decoder) Text() string {
+	return string(d.Binary())
+}
+
+func (d *Decoder) Rest() (v []byte) {
+	if d.Err == nil {
+		v = d.b[d.off:]
+		d.off = len(d.b)
+	}
+	return
+}
+
+// Properties are the MQTT 5 properties we care about, everything else is skipped
+type Properties struct {
+	SessionExpiry    uint32
+	AssignedClientID string
+	ServerKeepAlive  uint16
+	HasKeepAlive     bool
+	ReasonString     string
+	TopicAlias       uint16
+	User             [][2]string
+}
+
+// Properties reads a property block, each property type has a fixed encoding so unknown
+// properties can be skipped as long as the identifier is valid
+func (d *Decoder) Properties() (p Properties) {
+	n := d.VarInt()
+	pd := Decoder{b: d.Bytes(n)}
+	if d.Err != nil {
+		return
+	}
+	for pd.Remaining() > 0 && pd.Err == nil {
+		id := pd.VarInt()
+		if id > 0x7f {
+			pd.fail()
+			break
+		}
+		switch byte(id) {
+		case 0x01, 0x17, 0x19, 0x24, 0x25, 0x28, 0x29, 0x2a: //single byte
+			pd.Byte()
+		case PropServerKeepAlive:
+			p.ServerKeepAlive = pd.Uint16()
+			p.HasKeepAlive = true
+		case PropTopicAlias:
+			p.TopicAlias = pd.Uint16()
+		case PropReceiveMaximum, PropTopicAliasMaximum: //two byte integer
+			pd.Uint16()
+		case PropSessionExpiry:
+			p.SessionExpiry = pd.Uint32()
+		case 0x02, 0x18, PropMaximumPacketSize: //four byte integer
+			pd.Uint32()
+		case 0x0b: //variable byte integer
+			pd.VarInt()
+		case PropAssignedClientID:
+			p.AssignedClientID = pd.Text()
+		case PropReasonString:
+			p.ReasonString = pd.Text()
+		case 0x03, 0x08, 0x15, 0x1a, 0x1c: //utf-8 string
+			pd.Text()
+		case 0x09, 0x16: //binary data
+			pd.Binary()
+		case PropUserProperty:
+			k := pd.Text()
+			v := pd.Text()
+			p.User = append(p.User, [2]string{k, v})
+		default:
+			pd.fail()
+		}
+	}
+	if pd.Err != nil {
+		d.Err = fmt.Errorf("%w: bad properties", ErrMalformed)
+	}
+	return
+}
+
+// PropWriter builds a property block
+type PropWriter []byte
+
+func (pw PropWriter) Uint16(id byte, v uint16) PropWriter {
+	return binary.BigEndian.AppendUint16(append(pw, id), v)
+}
+
+func (pw PropWriter) Uint32(id byte, v uint32) PropWriter {
+	return binary.BigEndian.AppendUint32(append(pw, id), v)
+}
+
+// Append writes the property block, with its length, to the end of b
+func (pw PropWriter) Append(b []byte) []byte {
+	return append(AppendVarInt(b, len(pw)), pw...)
+}
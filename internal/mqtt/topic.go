@@ -0,0 +1,95 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package mqtt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gravwell/gravwell/v3/internal/mqtt/packet"
+)
+
+const sharePrefix = `$share/`
+
+var (
+	ErrInvalidFilter = errors.New("Invalid MQTT topic filter")
+)
+
+// ValidateFilter checks a topic filter, multi-level wildcards must be the last level and
+// wildcards must occupy an entire level
+func ValidateFilter(f string) error {
+	if _, f, err := SplitShared(f); err != nil {
+		return err
+	} else if err = checkTopicString(f); err != nil {
+		return err
+	}
+	lvls := strings.Split(f, `/`)
+	for i, l := range lvls {
+		if strings.Contains(l, `#`) && (l != `#` || i != len(lvls)-1) {
+			return fmt.Errorf("%w %q: # must be the entire last level", ErrInvalidFilter, f)
+		} else if strings.Contains(l, `+`) && l != `+` {
+			return fmt.Errorf("%w %q: + must be an entire level", ErrInvalidFilter, f)
+		}
+	}
+	return nil
+}
+
+// SplitShared splits a shared subscription like $share/group/sensors/# into the group and filter,
+// the group is empty for ordinary filters
+func SplitShared(f string) (group, filter string, err error) {
+	if !strings.HasPrefix(f, sharePrefix) {
+		return ``, f, nil
+	}
+	var ok bool
+	if group, filter, ok = strings.Cut(f[len(sharePrefix):], `/`); !ok || group == `` || filter == `` {
+		err = fmt.Errorf("%w %q: shared subscriptions look like $share/group/filter", ErrInvalidFilter, f)
+	} else if strings.ContainsAny(group, `+#`) {
+		err = fmt.Errorf("%w %q: share group may not contain wildcards", ErrInvalidFilter, f)
+	}
+	return
+}
+
+func checkTopicString(s string) error {
+	if len(s) == 0 || len(s) > packet.MaxStringLength {
+		return fmt.Errorf("%w %q: bad length", ErrInvalidFilter, s)
+	} else if !utf8.ValidString(s) || strings.ContainsRune(s, 0) {
+		return fmt.Errorf("%w %q: bad characters", ErrInvalidFilter, s)
+	}
+	return nil
+}
+
+// MatchTopic checks if a topic name matches a filter.  Shared subscription prefixes are ignored and
+// topics that start with $ are not matched by filters that start with a wildcard.
+func MatchTopic(filter, topic string) bool {
+	if _, f, err := SplitShared(filter); err != nil {
+		return false
+	} else {
+		filter = f
+	}
+	if strings.HasPrefix(topic, `$`) && (strings.HasPrefix(filter, `+`) || strings.HasPrefix(filter, `#`)) {
+		return false
+	}
+	for {
+		fl, frest, fmore := strings.Cut(filter, `/`)
+		if fl == `#` {
+			return true //matches this level and everything below it, including the parent
+		}
+		tl, trest, tmore := strings.Cut(topic, `/`)
+		if fl != `+` && fl != tl {
+			return false
+		}
+		if !fmore || !tmore {
+			//one side ran out, sport/# also matches sport
+			return fmore == tmore || (fmore && frest == `#`)
+		}
+		filter, topic = frest, trest
+	}
+}
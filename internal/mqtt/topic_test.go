@@ -0,0 +1,70 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package mqtt
+
+import (
+	"testing"
+)
+
+func TestValidateFilter(t *testing.T) {
+	good := []string{`a`, `a/b/c`, `+`, `#`, `a/+/c`, `a/#`, `+/+/#`, `/a`, `a//b`, `$SYS/#`, `$share/grp/a/+`}
+	for _, f := range good {
+		if err := ValidateFilter(f); err != nil {
+			t.Fatalf("rejected good filter %q: %v", f, err)
+		}
+	}
+	bad := []string{``, `a#`, `a/#/b`, `a/b+`, `a/+b/c`, "a/\x00", `$share/grp`, `$share//a`, `$share/g+/a`, `$share/grp/a#`}
+	for _, f := range bad {
+		if err := ValidateFilter(f); err == nil {
+			t.Fatalf("accepted bad filter %q", f)
+		}
+	}
+}
+
+func TestSplitShared(t *testing.T) {
+	if g, f, err := SplitShared(`$share/workers/sensors/#`); err != nil || g != `workers` || f != `sensors/#` {
+		t.Fatalf("bad split: %q %q %v", g, f, err)
+	}
+	if g, f, err := SplitShared(`sensors/#`); err != nil || g != `` || f != `sensors/#` {
+		t.Fatalf("bad split: %q %q %v", g, f, err)
+	}
+}
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		match         bool
+	}{
+		{`a/b/c`, `a/b/c`, true},
+		{`a/b/c`, `a/b`, false},
+		{`a/b`, `a/b/c`, false},
+		{`a/+/c`, `a/b/c`, true},
+		{`a/+/c`, `a//c`, true},
+		{`a/+`, `a/b/c`, false},
+		{`+`, `a`, true},
+		{`+`, `/a`, false},
+		{`+/+`, `/a`, true},
+		{`#`, `a/b/c`, true},
+		{`a/#`, `a`, true},
+		{`a/#`, `a/b/c`, true},
+		{`a/#`, `ab`, false},
+		{`a/b/#`, `a`, false},
+		{`#`, `$SYS/uptime`, false},
+		{`+/uptime`, `$SYS/uptime`, false},
+		{`$SYS/#`, `$SYS/uptime`, true},
+		{`$share/grp/a/+`, `a/b`, true},
+		{`$share/grp/a/+`, `b/b`, false},
+		{`A/b`, `a/b`, false},
+	}
+	for _, tt := range tests {
+		if r := MatchTopic(tt.filter, tt.topic); r != tt.match {
+			t.Fatalf("MatchTopic(%q, %q) = %v", tt.filter, tt.topic, r)
+		}
+	}
+}
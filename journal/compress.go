@@ -0,0 +1,63 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+var zdec, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxObjectSize))
+
+// decompress handles compressed data object payloads.
+// LZ4 payloads are prefixed with the little endian 64bit decompressed size followed by a raw LZ4 block,
+// ZSTD and XZ payloads are a complete ZSTD frame or XZ stream, XZ is only used by older journals.
+func decompress(flags uint8, b []byte) ([]byte, error) {
+	switch flags {
+	case objectCompressedZSTD:
+		return zdec.DecodeAll(b, nil)
+	case objectCompressedLZ4:
+		if len(b) < 8 {
+			return nil, fmt.Errorf("%w: short LZ4 payload", ErrInvalidObject)
+		}
+		sz := binary.LittleEndian.Uint64(b)
+		if sz > maxObjectSize {
+			return nil, fmt.Errorf("%w: LZ4 payload too large %d", ErrInvalidObject, sz)
+		}
+		out := make([]byte, sz)
+		n, err := lz4.UncompressBlock(b[8:], out)
+		if err != nil {
+			return nil, err
+		}
+		return out[:n], nil
+	case objectCompressedXZ:
+		return decompressXZ(b)
+	}
+	return nil, fmt.Errorf("%w: flags %#x", ErrUnsupportedCompress, flags)
+}
+
+func decompressXZ(b []byte) ([]byte, error) {
+	rdr, err := xz.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidObject, err)
+	}
+	out, err := io.ReadAll(io.LimitReader(rdr, maxObjectSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidObject, err)
+	} else if len(out) > maxObjectSize {
+		return nil, fmt.Errorf("%w: XZ payload too large", ErrInvalidObject)
+	}
+	return out, nil
+}
@@ -0,0 +1,394 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// Package journal implements a pure Go reader for the systemd journal file format.
+// The format is documented at https://systemd.io/JOURNAL_FILE_FORMAT/
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	headerMinSize   = 208 // size of the header prior to systemd 187
+	objectHdrSize   = 16
+	dataHdrSize     = 64
+	dataCompactSize = 72
+	entryHdrSize    = 64
+	arrayHdrSize    = 24
+
+	// maxObjectSize caps objects so that a corrupt size doesn't cause a massive allocation
+	maxObjectSize = 256 * 1024 * 1024
+)
+
+// header incompatible flags
+const (
+	IncompatibleCompressedXZ   uint32 = 1 << 0
+	IncompatibleCompressedLZ4  uint32 = 1 << 1
+	IncompatibleKeyedHash      uint32 = 1 << 2
+	IncompatibleCompressedZSTD uint32 = 1 << 3
+	IncompatibleCompact        uint32 = 1 << 4
+
+	supportedIncompatible = IncompatibleCompressedXZ | IncompatibleCompressedLZ4 |
+		IncompatibleKeyedHash | IncompatibleCompressedZSTD | IncompatibleCompact
+)
+
+// file states
+const (
+	StateOffline  uint8 = 0
+	StateOnline   uint8 = 1
+	StateArchived uint8 = 2
+)
+
+// object types
+const (
+	objectUnused         uint8 = 0
+	objectData           uint8 = 1
+	objectField          uint8 = 2
+	objectEntry          uint8 = 3
+	objectDataHashTable  uint8 = 4
+	objectFieldHashTable uint8 = 5
+	objectEntryArray     uint8 = 6
+	objectTag            uint8 = 7
+)
+
+// object flags
+const (
+	objectCompressedXZ   uint8 = 1 << 0
+	objectCompressedLZ4  uint8 = 1 << 1
+	objectCompressedZSTD uint8 = 1 << 2
+
+	objectCompressedMask = objectCompressedXZ | objectCompressedLZ4 | objectCompressedZSTD
+)
+
+var (
+	ErrBadSignature        = errors.New("Not a systemd journal file")
+	ErrHeaderTooShort      = errors.New("Journal header is too short")
+	ErrUnsupportedFeatures = errors.New("Journal file uses unsupported features")
+	ErrInvalidObject       = errors.New("Invalid journal object")
+	ErrUnsupportedCompress = errors.New("Unsupported journal field compression")
+
+	signature = []byte("LPKSHHRH")
+)
+
+// ID128 is a 128bit systemd ID, e.g. a boot ID or machine ID
+type ID128 [16]byte
+
+func (id ID128) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+func (id ID128) IsZero() bool {
+	return id == ID128{}
+}
+
+// ParseID128 parses the hex form of a 128bit ID
+func ParseID128(s string) (id ID128, err error) {
+	var b []byte
+	if b, err = hex.DecodeString(s); err != nil {
+		return
+	} else if len(b) != len(id) {
+		err = fmt.Errorf("Invalid ID128 length %d", len(b))
+		return
+	}
+	copy(id[:], b)
+	return
+}
+
+// Header is the journal file header, only the fields we use are decoded
+type Header struct {
+	CompatibleFlags   uint32
+	IncompatibleFlags uint32
+	State             uint8
+	FileID            ID128
+	MachineID         ID128
+	TailEntryBootID   ID128
+	SeqnumID          ID128
+	HeaderSize        uint64
+	ArenaSize         uint64
+	TailObjectOffset  uint64
+	NObjects          uint64
+	NEntries          uint64
+	TailEntrySeqnum   uint64
+	HeadEntrySeqnum   uint64
+	EntryArrayOffset  uint64
+	HeadEntryRealtime uint64
+	TailEntryRealtime uint64
+}
+
+func (h Header) compact() bool {
+	return h.IncompatibleFlags&IncompatibleCompact != 0
+}
+
+func decodeHeader(b []byte) (h Header, err error) {
+	if len(b) < headerMinSize {
+		err = ErrHeaderTooShort
+		return
+	} else if !bytes.Equal(b[0:8], signature) {
+		err = ErrBadSignature
+		return
+	}
+	h.CompatibleFlags = binary.LittleEndian.Uint32(b[8:])
+	h.IncompatibleFlags = binary.LittleEndian.Uint32(b[12:])
+	h.State = b[16]
+	copy(h.FileID[:], b[24:40])
+	copy(h.MachineID[:], b[40:56])
+	copy(h.TailEntryBootID[:], b[56:72])
+	copy(h.SeqnumID[:], b[72:88])
+	h.HeaderSize = binary.LittleEndian.Uint64(b[88:])
+	h.ArenaSize = binary.LittleEndian.Uint64(b[96:])
+	h.TailObjectOffset = binary.LittleEndian.Uint64(b[136:])
+	h.NObjects = binary.LittleEndian.Uint64(b[144:])
+	h.NEntries = binary.LittleEndian.Uint64(b[152:])
+	h.TailEntrySeqnum = binary.LittleEndian.Uint64(b[160:])
+	h.HeadEntrySeqnum = binary.LittleEndian.Uint64(b[168:])
+	h.EntryArrayOffset = binary.LittleEndian.Uint64(b[176:])
+	h.HeadEntryRealtime = binary.LittleEndian.Uint64(b[184:])
+	h.TailEntryRealtime = binary.LittleEndian.Uint64(b[192:])
+	if h.HeaderSize < headerMinSize {
+		err = ErrHeaderTooShort
+	} else if h.IncompatibleFlags&^supportedIncompatible != 0 {
+		err = fmt.Errorf("%w: %#x", ErrUnsupportedFeatures, h.IncompatibleFlags&^supportedIncompatible)
+	}
+	return
+}
+
+// Field is a single KEY=value pair attached to a journal entry
+type Field struct {
+	Name  string
+	Value []byte
+}
+
+// Entry is a single journal entry
+type Entry struct {
+	Seqnum    uint64
+	Realtime  uint64 // microseconds since the epoch, this is __REALTIME_TIMESTAMP
+	Monotonic uint64 // microseconds since boot, this is __MONOTONIC_TIMESTAMP
+	BootID    ID128
+	XorHash   uint64
+	Fields    []Field
+	Dropped   int // number of fields that could not be decompressed and were left out of Fields
+}
+
+// Get returns the first value of the named field
+func (e Entry) Get(name string) (v []byte, ok bool) {
+	for _, f := range e.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return
+}
+
+// File is an open journal file, journal files are written in place so the header must be refreshed to
+// see new entries.
+type File struct {
+	f   *os.File
+	hdr Header
+}
+
+// Open opens a journal file and decodes its header
+func Open(pth string) (*File, error) {
+	fin, err := os.Open(pth)
+	if err != nil {
+		return nil, err
+	}
+	jf := &File{f: fin}
+	if err = jf.Refresh(); err != nil {
+		fin.Close()
+		return nil, err
+	}
+	return jf, nil
+}
+
+// Header returns the most recently read header
+func (jf *File) Header() Header {
+	return jf.hdr
+}
+
+// Refresh re-reads the header so that entries written since the last refresh are visible
+func (jf *File) Refresh() error {
+	buff := make([]byte, headerMinSize)
+	if _, err := jf.f.ReadAt(buff, 0); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrHeaderTooShort
+		}
+		return err
+	}
+	hdr, err := decodeHeader(buff)
+	if err != nil {
+		return err
+	}
+	jf.hdr = hdr
+	return nil
+}
+
+func (jf *File) Close() error {
+	return jf.f.Close()
+}
+
+// readObject reads an entire object of the given type at the offset
+func (jf *File) readObject(off uint64, typ uint8) (b []byte, err error) {
+	var hdr [objectHdrSize]byte
+	if off == 0 || off%8 != 0 || off < jf.hdr.HeaderSize {
+		err = fmt.Errorf("%w: bad offset %d", ErrInvalidObject, off)
+		return
+	} else if _, err = jf.f.ReadAt(hdr[:], int64(off)); err != nil {
+		return
+	}
+	sz := binary.LittleEndian.Uint64(hdr[8:])
+	if hdr[0] != typ {
+		err = fmt.Errorf("%w: expected type %d at %d, got %d", ErrInvalidObject, typ, off, hdr[0])
+		return
+	} else if sz < objectHdrSize || sz > maxObjectSize {
+		err = fmt.Errorf("%w: bad size %d at %d", ErrInvalidObject, sz, off)
+		return
+	}
+	b = make([]byte, sz)
+	if _, err = jf.f.ReadAt(b, int64(off)); err != nil {
+		b = nil
+	}
+	return
+}
+
+// readEntryHeader reads just the fixed portion of an entry object, used when seeking
+func (jf *File) readEntryHeader(off uint64) (seqnum uint64, err error) {
+	var hdr [entryHdrSize]byte
+	if off == 0 || off%8 != 0 || off < jf.hdr.HeaderSize {
+		err = fmt.Errorf("%w: bad offset %d", ErrInvalidObject, off)
+		return
+	} else if _, err = jf.f.ReadAt(hdr[:], int64(off)); err != nil {
+		return
+	} else if hdr[0] != objectEntry {
+		err = fmt.Errorf("%w: expected entry at %d, got %d", ErrInvalidObject, off, hdr[0])
+		return
+	}
+	seqnum = binary.LittleEndian.Uint64(hdr[16:])
+	return
+}
+
+// readEntry reads and decodes the entry at the given offset, including all of its fields
+func (jf *File) readEntry(off uint64) (ent Entry, err error) {
+	var b []byte
+	if b, err = jf.readObject(off, objectEntry); err != nil {
+		return
+	} else if len(b) < entryHdrSize {
+		err = fmt.Errorf("%w: short entry at %d", ErrInvalidObject, off)
+		return
+	}
+	ent.Seqnum = binary.LittleEndian.Uint64(b[16:])
+	ent.Realtime = binary.LittleEndian.Uint64(b[24:])
+	ent.Monotonic = binary.LittleEndian.Uint64(b[32:])
+	copy(ent.BootID[:], b[40:56])
+	ent.XorHash = binary.LittleEndian.Uint64(b[56:])
+	items := b[entryHdrSize:]
+	itemSize := 16
+	if jf.hdr.compact() {
+		itemSize = 4
+	}
+	ent.Fields = make([]Field, 0, len(items)/itemSize)
+	for ; len(items) >= itemSize; items = items[itemSize:] {
+		var doff uint64
+		if itemSize == 4 {
+			doff = uint64(binary.LittleEndian.Uint32(items))
+		} else {
+			doff = binary.LittleEndian.Uint64(items)
+		}
+		if doff == 0 {
+			continue
+		}
+		var fld Field
+		if fld, err = jf.readData(doff); err != nil {
+			if errors.Is(err, ErrUnsupportedCompress) {
+				//drop fields we can't decompress rather than stalling on the entry, the caller decides what to do about it
+				err = nil
+				ent.Dropped++
+				continue
+			}
+			return
+		}
+		ent.Fields = append(ent.Fields, fld)
+	}
+	return
+}
+
+// readData reads a data object and splits the payload into a field
+func (jf *File) readData(off uint64) (fld Field, err error) {
+	var b []byte
+	if b, err = jf.readObject(off, objectData); err != nil {
+		return
+	}
+	start := dataHdrSize
+	if jf.hdr.compact() {
+		start = dataCompactSize
+	}
+	if len(b) < start {
+		err = fmt.Errorf("%w: short data object at %d", ErrInvalidObject, off)
+		return
+	}
+	payload := b[start:]
+	if flags := b[1] & objectCompressedMask; flags != 0 {
+		if payload, err = decompress(flags, payload); err != nil {
+			return
+		}
+	}
+	idx := bytes.IndexByte(payload, '=')
+	if idx <= 0 {
+		err = fmt.Errorf("%w: data object at %d is not a field", ErrInvalidObject, off)
+		return
+	}
+	fld.Name = string(payload[:idx])
+	fld.Value = payload[idx+1:]
+	return
+}
+
+// entryArray is a decoded entry array object
+type entryArray struct {
+	next  uint64
+	items []uint64
+}
+
+func (jf *File) readEntryArray(off uint64) (ea entryArray, err error) {
+	var b []byte
+	if b, err = jf.readObject(off, objectEntryArray); err != nil {
+		return
+	} else if len(b) < arrayHdrSize {
+		err = fmt.Errorf("%w: short entry array at %d", ErrInvalidObject, off)
+		return
+	}
+	ea.next = binary.LittleEndian.Uint64(b[16:])
+	items := b[arrayHdrSize:]
+	if jf.hdr.compact() {
+		ea.items = make([]uint64, 0, len(items)/4)
+		for ; len(items) >= 4; items = items[4:] {
+			ea.items = append(ea.items, uint64(binary.LittleEndian.Uint32(items)))
+		}
+	} else {
+		ea.items = make([]uint64, 0, len(items)/8)
+		for ; len(items) >= 8; items = items[8:] {
+			ea.items = append(ea.items, binary.LittleEndian.Uint64(items))
+		}
+	}
+	return
+}
+
+// used returns the number of populated items, entry arrays are allocated ahead of time and filled in order
+func (ea entryArray) used() (n int) {
+	for n = 0; n < len(ea.items); n++ {
+		if ea.items[n] == 0 {
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,184 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package journal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gravwell/gravwell/v3/journal/journaltest"
+)
+
+func readEntries(t *testing.T, rdr *Reader) (ents []Entry) {
+	for {
+		ent, ok, err := rdr.Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if !ok {
+			return
+		}
+		ents = append(ents, ent)
+	}
+}
+
+func checkEntries(t *testing.T, ents []Entry, start, cnt int) {
+	t.Helper()
+	if len(ents) != cnt {
+		t.Fatalf("got %d entries, expected %d", len(ents), cnt)
+	}
+	for i, ent := range ents {
+		msg, ok := ent.Get(`MESSAGE`)
+		if !ok || string(msg) != fmt.Sprintf("message %d", start+i) {
+			t.Fatalf("bad message on entry %d: %q", i, msg)
+		} else if unit, ok := ent.Get(`_SYSTEMD_UNIT`); !ok || string(unit) != `test.service` {
+			t.Fatalf("bad unit on entry %d: %q", i, unit)
+		} else if ent.Realtime != journaltest.BaseRealtime+ent.Seqnum {
+			t.Fatalf("bad realtime %d", ent.Realtime)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	modes := []struct {
+		name     string
+		compact  bool
+		compress journaltest.Compression
+	}{
+		{`regular`, false, journaltest.None},
+		{`compact`, true, journaltest.None},
+		{`zstd`, false, journaltest.ZSTD},
+		{`lz4`, true, journaltest.LZ4},
+		{`xz`, false, journaltest.XZ},
+	}
+	for _, m := range modes {
+		t.Run(m.name, func(t *testing.T) {
+			pth := filepath.Join(t.TempDir(), `system.journal`)
+			tw := journaltest.NewWriter(m.compact, m.compress, 4)
+			for i := 0; i < 10; i++ {
+				tw.Add(fmt.Sprintf("MESSAGE=message %d", i), `_SYSTEMD_UNIT=test.service`, `PRIORITY=6`)
+			}
+			if err := tw.Flush(pth); err != nil {
+				t.Fatal(err)
+			}
+			jf, err := Open(pth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer jf.Close()
+			if jf.Header().NEntries != 10 || jf.Header().FileID.IsZero() {
+				t.Fatalf("bad header %+v", jf.Header())
+			}
+			rdr := NewReader(jf)
+			checkEntries(t, readEntries(t, rdr), 0, 10)
+
+			//the writer keeps going and we pick up where we left off
+			for i := 10; i < 15; i++ {
+				tw.Add(fmt.Sprintf("MESSAGE=message %d", i), `_SYSTEMD_UNIT=test.service`)
+			}
+			if err = tw.Flush(pth); err != nil {
+				t.Fatal(err)
+			} else if err = jf.Refresh(); err != nil {
+				t.Fatal(err)
+			}
+			checkEntries(t, readEntries(t, rdr), 10, 5)
+		})
+	}
+}
+
+func TestSeek(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `system.journal`)
+	tw := journaltest.NewWriter(false, journaltest.None, 3)
+	for i := 0; i < 20; i++ {
+		tw.Add(fmt.Sprintf("MESSAGE=message %d", i), `_SYSTEMD_UNIT=test.service`)
+	}
+	if err := tw.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+	jf, err := Open(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jf.Close()
+	rdr := NewReader(jf)
+	all := readEntries(t, rdr)
+	checkEntries(t, all, 0, 20)
+
+	//resume from a cursor in the middle of the file
+	cur, err := ParseCursor(CursorOf(jf.Header(), all[6]).String())
+	if err != nil {
+		t.Fatal(err)
+	} else if cur != CursorOf(jf.Header(), all[6]) {
+		t.Fatalf("cursor did not round trip: %v", cur)
+	}
+	rdr = NewReader(jf)
+	if err = rdr.SeekSeqnum(cur.Seqnum); err != nil {
+		t.Fatal(err)
+	}
+	checkEntries(t, readEntries(t, rdr), 7, 13)
+
+	//seek to the end, the second pass has a full last array so the reader must follow a new one
+	for _, total := range []int{20, 21} {
+		rdr = NewReader(jf)
+		if err = rdr.SeekTail(); err != nil {
+			t.Fatal(err)
+		} else if ents := readEntries(t, rdr); len(ents) != 0 {
+			t.Fatalf("got %d entries after seeking to the tail of %d", len(ents), total)
+		}
+		tw.Add(fmt.Sprintf("MESSAGE=message %d", total), `_SYSTEMD_UNIT=test.service`)
+		if err = tw.Flush(pth); err != nil {
+			t.Fatal(err)
+		} else if err = jf.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+		checkEntries(t, readEntries(t, rdr), total, 1)
+	}
+}
+
+func TestBadFiles(t *testing.T) {
+	dir := t.TempDir()
+	pth := filepath.Join(dir, `bad.journal`)
+	if err := os.WriteFile(pth, []byte("not a journal"), 0640); err != nil {
+		t.Fatal(err)
+	} else if _, err = Open(pth); err != ErrHeaderTooShort {
+		t.Fatalf("bad error on short file: %v", err)
+	}
+	tw := journaltest.NewWriter(false, journaltest.None, 4)
+	copy(tw.Bytes(), "XXXXXXXX")
+	if err := tw.Flush(pth); err != nil {
+		t.Fatal(err)
+	} else if _, err = Open(pth); err != ErrBadSignature {
+		t.Fatalf("bad error on bad signature: %v", err)
+	}
+	if _, err := ParseCursor(`garbage`); err == nil {
+		t.Fatal("failed to catch bad cursor")
+	}
+}
+
+func TestDroppedFields(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), `system.journal`)
+	tw := journaltest.NewWriter(false, journaltest.Unknown, 4)
+	tw.Add(`MESSAGE=message 0`, `_SYSTEMD_UNIT=test.service`)
+	if err := tw.Flush(pth); err != nil {
+		t.Fatal(err)
+	}
+	jf, err := Open(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jf.Close()
+	//fields we can't decompress are dropped and counted, the entry itself is still returned
+	ents := readEntries(t, NewReader(jf))
+	if len(ents) != 1 {
+		t.Fatalf("got %d entries, expected 1", len(ents))
+	} else if len(ents[0].Fields) != 0 || ents[0].Dropped != 2 {
+		t.Fatalf("bad dropped fields %d %d", len(ents[0].Fields), ents[0].Dropped)
+	}
+}
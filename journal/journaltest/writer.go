@@ -0,0 +1,246 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+// Package journaltest builds systemd journal files for testing journal readers.
+// The files are written the same way journald writes them, minus the hash tables which readers do not need.
+package journaltest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+// Compression is the compression applied to data objects, the values are the journal object flags
+type Compression uint8
+
+const (
+	None Compression = 0
+	XZ   Compression = 1 << 0
+	LZ4  Compression = 1 << 1
+	ZSTD Compression = 1 << 2
+	// Unknown marks data objects with a combination of compression flags no reader supports
+	Unknown Compression = XZ | LZ4
+
+	// BaseRealtime is the realtime timestamp of an entry with sequence number 0, each entry is one microsecond later
+	BaseRealtime = 1700000000000000
+
+	// StateOnline and StateArchived are the header states of a file being written and a file journald is done with
+	StateOnline   uint8 = 1
+	StateArchived uint8 = 2
+
+	headerSize      = 272
+	objectHdrSize   = 16
+	dataHdrSize     = 64
+	dataCompactSize = 72
+	entryHdrSize    = 64
+	arrayHdrSize    = 24
+
+	objectData       uint8 = 1
+	objectEntry      uint8 = 3
+	objectEntryArray uint8 = 6
+
+	incompatibleCompressedXZ   uint32 = 1 << 0
+	incompatibleCompressedLZ4  uint32 = 1 << 1
+	incompatibleCompressedZSTD uint32 = 1 << 3
+	incompatibleCompact        uint32 = 1 << 4
+)
+
+var signature = []byte("LPKSHHRH")
+
+// Writer builds a journal file in memory
+type Writer struct {
+	buff     []byte
+	compact  bool
+	compress Compression
+	arrCap   int
+	arrOff   uint64 // current entry array
+	arrUsed  int
+	first    uint64 // first entry array
+	seqnum   uint64
+	nObjects uint64
+	nEntries uint64
+	headRT   uint64
+}
+
+// NewWriter creates a writer, arrCap is the number of entries in each entry array.
+// Sequence numbers start at 101.
+func NewWriter(compact bool, compress Compression, arrCap int) *Writer {
+	w := &Writer{
+		buff:     make([]byte, headerSize),
+		compact:  compact,
+		compress: compress,
+		arrCap:   arrCap,
+		seqnum:   100,
+	}
+	copy(w.buff, signature)
+	var flags uint32
+	if compact {
+		flags |= incompatibleCompact
+	}
+	switch compress {
+	case XZ:
+		flags |= incompatibleCompressedXZ
+	case LZ4:
+		flags |= incompatibleCompressedLZ4
+	case ZSTD:
+		flags |= incompatibleCompressedZSTD
+	}
+	binary.LittleEndian.PutUint32(w.buff[12:], flags)
+	w.buff[16] = StateOnline
+	for i := 0; i < 16; i++ {
+		w.buff[24+i] = byte(i + 1)  //file id
+		w.buff[72+i] = byte(i + 32) //seqnum id
+	}
+	binary.LittleEndian.PutUint64(w.buff[88:], headerSize)
+	return w
+}
+
+// SetFileID changes the file ID, journald gives every file a unique ID
+func (w *Writer) SetFileID(id [16]byte) {
+	copy(w.buff[24:40], id[:])
+}
+
+// SetState sets the header state, StateArchived marks a file that will never change again
+func (w *Writer) SetState(state uint8) {
+	w.buff[16] = state
+}
+
+// SetSeqnum sets the sequence number the next entry follows, journald carries sequence numbers across files
+func (w *Writer) SetSeqnum(seqnum uint64) {
+	w.seqnum = seqnum
+}
+
+// Bytes returns the raw file contents, modifying them is allowed
+func (w *Writer) Bytes() []byte {
+	return w.buff
+}
+
+func (w *Writer) object(typ, flags uint8, payload []byte) (off uint64) {
+	off = uint64(len(w.buff))
+	hdr := make([]byte, objectHdrSize)
+	hdr[0], hdr[1] = typ, flags
+	binary.LittleEndian.PutUint64(hdr[8:], uint64(objectHdrSize+len(payload)))
+	w.buff = append(w.buff, hdr...)
+	w.buff = append(w.buff, payload...)
+	for len(w.buff)%8 != 0 {
+		w.buff = append(w.buff, 0)
+	}
+	w.nObjects++
+	return
+}
+
+func (w *Writer) data(fld string) uint64 {
+	payload := []byte(fld)
+	switch w.compress {
+	case XZ:
+		//journald writes XZ streams without a checksum
+		bb := bytes.NewBuffer(nil)
+		xw, err := xz.WriterConfig{CheckSum: xz.None}.NewWriter(bb)
+		if err != nil {
+			panic(err)
+		} else if _, err = xw.Write(payload); err != nil {
+			panic(err)
+		} else if err = xw.Close(); err != nil {
+			panic(err)
+		}
+		payload = bb.Bytes()
+	case LZ4:
+		out := make([]byte, 8+lz4.CompressBlockBound(len(payload)))
+		binary.LittleEndian.PutUint64(out, uint64(len(payload)))
+		n, err := lz4.CompressBlockHC(payload, out[8:], 0, nil, nil)
+		if err != nil || n == 0 {
+			panic(fmt.Sprintf("failed to compress %v %d", err, n))
+		}
+		payload = out[:8+n]
+	case ZSTD:
+		enc, _ := zstd.NewWriter(nil)
+		payload = enc.EncodeAll(payload, nil)
+	}
+	hdr := make([]byte, dataHdrSize-objectHdrSize)
+	if w.compact {
+		hdr = make([]byte, dataCompactSize-objectHdrSize)
+	}
+	return w.object(objectData, uint8(w.compress), append(hdr, payload...))
+}
+
+// Add appends an entry made up of KEY=value fields
+func (w *Writer) Add(fields ...string) {
+	var items []uint64
+	for _, f := range fields {
+		items = append(items, w.data(f))
+	}
+	w.seqnum++
+	rt := BaseRealtime + w.seqnum
+	if w.headRT == 0 {
+		w.headRT = rt
+	}
+	payload := make([]byte, entryHdrSize-objectHdrSize)
+	binary.LittleEndian.PutUint64(payload[0:], w.seqnum)
+	binary.LittleEndian.PutUint64(payload[8:], rt)
+	binary.LittleEndian.PutUint64(payload[16:], w.seqnum*1000)
+	payload[24] = 0xbb
+	binary.LittleEndian.PutUint64(payload[40:], w.seqnum^0xffff)
+	for _, it := range items {
+		if w.compact {
+			payload = binary.LittleEndian.AppendUint32(payload, uint32(it))
+		} else {
+			payload = binary.LittleEndian.AppendUint64(payload, it)
+			payload = binary.LittleEndian.AppendUint64(payload, 0)
+		}
+	}
+	eoff := w.object(objectEntry, 0, payload)
+	w.nEntries++
+	w.link(eoff)
+}
+
+// link adds an entry to the entry array chain, allocating a new array when the current one is full
+func (w *Writer) link(eoff uint64) {
+	itemSize := 8
+	if w.compact {
+		itemSize = 4
+	}
+	if w.arrOff == 0 || w.arrUsed == w.arrCap {
+		off := w.object(objectEntryArray, 0, make([]byte, 8+itemSize*w.arrCap))
+		if w.arrOff == 0 {
+			w.first = off
+		} else {
+			binary.LittleEndian.PutUint64(w.buff[w.arrOff+16:], off)
+		}
+		w.arrOff, w.arrUsed = off, 0
+	}
+	loc := w.arrOff + arrayHdrSize + uint64(w.arrUsed*itemSize)
+	if w.compact {
+		binary.LittleEndian.PutUint32(w.buff[loc:], uint32(eoff))
+	} else {
+		binary.LittleEndian.PutUint64(w.buff[loc:], eoff)
+	}
+	w.arrUsed++
+}
+
+// Flush writes the journal out in place, the same way journald updates a live file
+func (w *Writer) Flush(pth string) error {
+	binary.LittleEndian.PutUint64(w.buff[144:], w.nObjects)
+	binary.LittleEndian.PutUint64(w.buff[152:], w.nEntries)
+	binary.LittleEndian.PutUint64(w.buff[176:], w.first)
+	binary.LittleEndian.PutUint64(w.buff[184:], w.headRT)
+	fout, err := os.OpenFile(pth, os.O_WRONLY|os.O_CREATE, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err = fout.WriteAt(w.buff, 0); err != nil {
+		fout.Close()
+		return err
+	}
+	return fout.Close()
+}
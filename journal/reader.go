@@ -0,0 +1,212 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidCursor = errors.New("Invalid journal cursor")
+	ErrArrayLoop     = errors.New("Journal entry array chain loops")
+)
+
+// Cursor identifies a single entry, the string form matches the cursors used by journalctl
+type Cursor struct {
+	SeqnumID  ID128
+	Seqnum    uint64
+	BootID    ID128
+	Monotonic uint64
+	Realtime  uint64
+	XorHash   uint64
+}
+
+// CursorOf returns the cursor for an entry in a file
+func CursorOf(h Header, ent Entry) Cursor {
+	return Cursor{
+		SeqnumID:  h.SeqnumID,
+		Seqnum:    ent.Seqnum,
+		BootID:    ent.BootID,
+		Monotonic: ent.Monotonic,
+		Realtime:  ent.Realtime,
+		XorHash:   ent.XorHash,
+	}
+}
+
+func (c Cursor) String() string {
+	return fmt.Sprintf("s=%s;i=%x;b=%s;m=%x;t=%x;x=%x", c.SeqnumID, c.Seqnum, c.BootID, c.Monotonic, c.Realtime, c.XorHash)
+}
+
+func (c Cursor) IsZero() bool {
+	return c == Cursor{}
+}
+
+// ParseCursor parses the string form of a cursor
+func ParseCursor(s string) (c Cursor, err error) {
+	var seen int
+	for _, kv := range strings.Split(s, ";") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			err = fmt.Errorf("%w: %q", ErrInvalidCursor, s)
+			return
+		}
+		switch k {
+		case `s`:
+			c.SeqnumID, err = ParseID128(v)
+		case `i`:
+			c.Seqnum, err = strconv.ParseUint(v, 16, 64)
+		case `b`:
+			c.BootID, err = ParseID128(v)
+		case `m`:
+			c.Monotonic, err = strconv.ParseUint(v, 16, 64)
+		case `t`:
+			c.Realtime, err = strconv.ParseUint(v, 16, 64)
+		case `x`:
+			c.XorHash, err = strconv.ParseUint(v, 16, 64)
+		default:
+			continue
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %q %v", ErrInvalidCursor, s, err)
+			return
+		}
+		seen++
+	}
+	if seen == 0 {
+		err = fmt.Errorf("%w: %q", ErrInvalidCursor, s)
+	}
+	return
+}
+
+// Reader walks the entries of a journal file in order.  When it runs out of entries it can be
+// called again after the writer adds more, it picks up where it left off.
+type Reader struct {
+	jf  *File
+	off uint64     // offset of the current entry array
+	arr entryArray // current entry array
+	idx int        // index of the next entry in the current array
+	hop uint64     // number of arrays walked, guards against loops in corrupt files
+}
+
+func NewReader(jf *File) *Reader {
+	return &Reader{jf: jf}
+}
+
+// Next returns the next entry, ok is false when there are no more entries right now
+func (r *Reader) Next() (ent Entry, ok bool, err error) {
+	var eoff uint64
+	if eoff, ok, err = r.nextOffset(); err != nil || !ok {
+		return
+	}
+	if ent, err = r.jf.readEntry(eoff); err != nil {
+		ok = false
+		return
+	}
+	r.idx++
+	return
+}
+
+// nextOffset finds the offset of the next entry, following the entry array chain as needed
+func (r *Reader) nextOffset() (off uint64, ok bool, err error) {
+	if r.off == 0 {
+		if r.off = r.jf.hdr.EntryArrayOffset; r.off == 0 {
+			return //no entries yet
+		} else if r.arr, err = r.jf.readEntryArray(r.off); err != nil {
+			r.off = 0
+			return
+		}
+	}
+	for {
+		if r.idx < len(r.arr.items) && r.arr.items[r.idx] != 0 {
+			off, ok = r.arr.items[r.idx], true
+			return
+		}
+		//re-read the array, the writer fills arrays in place and links the next one when full
+		if r.arr, err = r.jf.readEntryArray(r.off); err != nil {
+			return
+		}
+		if r.idx < len(r.arr.items) {
+			if r.arr.items[r.idx] != 0 {
+				continue
+			}
+			return //array isn't full, nothing new
+		} else if r.arr.next == 0 {
+			return //array is full but the next one isn't linked yet
+		}
+		if err = r.hopArray(r.arr.next); err != nil {
+			return
+		}
+	}
+}
+
+func (r *Reader) hopArray(next uint64) (err error) {
+	if r.hop++; r.jf.hdr.NObjects > 0 && r.hop > r.jf.hdr.NObjects {
+		return ErrArrayLoop
+	}
+	var arr entryArray
+	if arr, err = r.jf.readEntryArray(next); err != nil {
+		return
+	}
+	r.off, r.arr, r.idx = next, arr, 0
+	return
+}
+
+// SeekSeqnum positions the reader on the first entry with a sequence number greater than seqnum,
+// entries within a file are always in sequence number order.
+func (r *Reader) SeekSeqnum(seqnum uint64) (err error) {
+	r.off, r.idx, r.hop, r.arr = 0, 0, 0, entryArray{}
+	if r.off = r.jf.hdr.EntryArrayOffset; r.off == 0 {
+		return
+	} else if r.arr, err = r.jf.readEntryArray(r.off); err != nil {
+		r.off = 0
+		return
+	}
+	for {
+		n := r.arr.used()
+		if n == 0 {
+			return
+		}
+		var last uint64
+		if last, err = r.jf.readEntryHeader(r.arr.items[n-1]); err != nil {
+			return
+		}
+		if last <= seqnum {
+			if n == len(r.arr.items) && r.arr.next != 0 {
+				//everything in this array has been seen, skip it entirely
+				if err = r.hopArray(r.arr.next); err != nil {
+					return
+				}
+				continue
+			}
+			r.idx = n
+			return
+		}
+		//the target is in this array
+		var serr error
+		r.idx = sort.Search(n, func(i int) bool {
+			if serr != nil {
+				return true
+			}
+			var s uint64
+			s, serr = r.jf.readEntryHeader(r.arr.items[i])
+			return s > seqnum
+		})
+		err = serr
+		return
+	}
+}
+
+// SeekTail positions the reader after the last entry currently in the file
+func (r *Reader) SeekTail() (err error) {
+	return r.SeekSeqnum(^uint64(0))
+}
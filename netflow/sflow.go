@@ -0,0 +1,577 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package netflow
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// sFlow version 5 datagrams are XDR encoded, everything is big endian and padded to 4 byte boundaries
+// see https://sflow.org/sflow_version_5.txt for the full specification
+
+const (
+	SFlowVersion uint32 = 5
+
+	SFlowAddressIPv4 uint32 = 1
+	SFlowAddressIPv6 uint32 = 2
+
+	// standard (enterprise 0) sample formats
+	SFlowFlowSample            uint32 = 1
+	SFlowCounterSample         uint32 = 2
+	SFlowExpandedFlowSample    uint32 = 3
+	SFlowExpandedCounterSample uint32 = 4
+
+	// standard (enterprise 0) flow record formats
+	SFlowRawPacketHeader uint32 = 1
+	SFlowEthernetFrame   uint32 = 2
+	SFlowIPv4Data        uint32 = 3
+	SFlowIPv6Data        uint32 = 4
+	SFlowExtendedSwitch  uint32 = 1001
+	SFlowExtendedRouter  uint32 = 1002
+	SFlowExtendedGateway uint32 = 1003
+	SFlowExtendedUser    uint32 = 1004
+	SFlowExtendedURL     uint32 = 1005
+
+	// standard (enterprise 0) counter record formats
+	SFlowGenericInterfaceCounters  uint32 = 1
+	SFlowEthernetInterfaceCounters uint32 = 2
+	SFlowProcessorCounters         uint32 = 1001
+
+	// raw packet header protocols
+	SFlowHeaderEthernet uint32 = 1
+	SFlowHeaderIPv4     uint32 = 11
+	SFlowHeaderIPv6     uint32 = 12
+
+	sflowMinHeaderSize = 28 // version, ipv4 agent address, sub agent, sequence, uptime, sample count
+	genericIfaceSize   = 88
+	ethernetIfaceSize  = 52
+	processorSize      = 28
+	maxSFlowSamples    = 1024 // a UDP datagram can't hold more than this many samples anyway
+)
+
+var (
+	ErrSFlowTooShort       = errors.New("Buffer too small for sFlow datagram")
+	ErrSFlowInvalidVersion = errors.New("Not a valid sFlow V5 datagram")
+	ErrSFlowInvalidAddress = errors.New("Invalid sFlow agent address type")
+	ErrSFlowInvalidCount   = errors.New("sFlow sample or record count is invalid")
+	ErrSFlowInvalidLength  = errors.New("sFlow sample or record length is invalid")
+)
+
+// SFlowDatagram is a fully decoded sFlow V5 datagram
+type SFlowDatagram struct {
+	SFlowHeader
+	Samples []SFlowSample
+}
+
+// SFlowHeader is the datagram header that precedes the samples
+type SFlowHeader struct {
+	Version     uint32
+	AgentAddr   net.IP
+	SubAgentID  uint32
+	Sequence    uint32
+	Uptime      uint32 // milliseconds since the agent booted
+	SampleCount uint32
+	size        int // the encoded size of the header, it varies based on agent address type
+}
+
+// SFlowSample is a single flow or counter sample, Enterprise and Format identify the sample type.
+// Flow samples populate the flow fields and FlowRecords, counter samples populate CounterRecords.
+// Samples of unknown types are left undecoded in Data.
+type SFlowSample struct {
+	Enterprise     uint32
+	Format         uint32
+	Sequence       uint32
+	SourceIDType   uint32
+	SourceIDIndex  uint32
+	SamplingRate   uint32
+	SamplePool     uint32
+	Drops          uint32
+	InputFormat    uint32
+	Input          uint32
+	OutputFormat   uint32
+	Output         uint32
+	FlowRecords    []SFlowRecord
+	CounterRecords []SFlowRecord
+	Data           []byte // the raw sample body
+}
+
+// SFlowRecord is a single flow or counter record.  Known record formats are decoded into the
+// matching field, the raw record body is always available in Data.
+type SFlowRecord struct {
+	Enterprise uint32
+	Format     uint32
+	Data       []byte
+
+	RawHeader         *SFlowRawHeader
+	Ethernet          *SFlowEthernet
+	IP                *SFlowIPData
+	Switch            *SFlowSwitch
+	Router            *SFlowRouter
+	GenericInterface  *SFlowGenericInterface
+	EthernetInterface *SFlowEthernetInterface
+	Processor         *SFlowProcessor
+}
+
+// SFlowRawHeader is a sampled packet header
+type SFlowRawHeader struct {
+	Protocol    uint32
+	FrameLength uint32
+	Stripped    uint32
+	Header      []byte
+}
+
+type SFlowEthernet struct {
+	Length uint32
+	Src    net.HardwareAddr
+	Dst    net.HardwareAddr
+	Type   uint32
+}
+
+// SFlowIPData covers both the IPv4 and IPv6 data records, ToS holds the IPv6 priority
+type SFlowIPData struct {
+	Length   uint32
+	Protocol uint32
+	Src      net.IP
+	Dst      net.IP
+	SrcPort  uint32
+	DstPort  uint32
+	TCPFlags uint32
+	ToS      uint32
+}
+
+type SFlowSwitch struct {
+	SrcVlan     uint32
+	SrcPriority uint32
+	DstVlan     uint32
+	DstPriority uint32
+}
+
+type SFlowRouter struct {
+	NextHop net.IP
+	SrcMask uint32
+	DstMask uint32
+}
+
+type SFlowGenericInterface struct {
+	Index            uint32
+	Type             uint32
+	Speed            uint64
+	Direction        uint32
+	Status           uint32
+	InOctets         uint64
+	InUcastPkts      uint32
+	InMulticastPkts  uint32
+	InBroadcastPkts  uint32
+	InDiscards       uint32
+	InErrors         uint32
+	InUnknownProtos  uint32
+	OutOctets        uint64
+	OutUcastPkts     uint32
+	OutMulticastPkts uint32
+	OutBroadcastPkts uint32
+	OutDiscards      uint32
+	OutErrors        uint32
+	PromiscuousMode  uint32
+}
+
+type SFlowEthernetInterface struct {
+	AlignmentErrors           uint32
+	FCSErrors                 uint32
+	SingleCollisionFrames     uint32
+	MultipleCollisionFrames   uint32
+	SQETestErrors             uint32
+	DeferredTransmissions     uint32
+	LateCollisions            uint32
+	ExcessiveCollisions       uint32
+	InternalMacTransmitErrors uint32
+	CarrierSenseErrors        uint32
+	FrameTooLongs             uint32
+	InternalMacReceiveErrors  uint32
+	SymbolErrors              uint32
+}
+
+type SFlowProcessor struct {
+	CPU5s       uint32 // percentages are in hundredths of a percent
+	CPU1m       uint32
+	CPU5m       uint32
+	TotalMemory uint64
+	FreeMemory  uint64
+}
+
+// xdr is a tiny cursor over an XDR encoded buffer
+type xdr struct {
+	b   []byte
+	err error
+}
+
+func (x *xdr) u32() (v uint32) {
+	if x.err != nil {
+		return
+	} else if len(x.b) < 4 {
+		x.err = ErrSFlowTooShort
+		return
+	}
+	v = binary.BigEndian.Uint32(x.b)
+	x.b = x.b[4:]
+	return
+}
+
+func (x *xdr) u64() (v uint64) {
+	if x.err != nil {
+		return
+	} else if len(x.b) < 8 {
+		x.err = ErrSFlowTooShort
+		return
+	}
+	v = binary.BigEndian.Uint64(x.b)
+	x.b = x.b[8:]
+	return
+}
+
+// bytes pulls n bytes plus any XDR padding out of the buffer, the returned slice is not padded
+func (x *xdr) bytes(n int) (v []byte) {
+	if x.err != nil {
+		return
+	}
+	padded := (n + 3) &^ 3
+	if n < 0 || len(x.b) < padded {
+		x.err = ErrSFlowInvalidLength
+		return
+	}
+	v = x.b[:n]
+	x.b = x.b[padded:]
+	return
+}
+
+// opaque pulls a length prefixed XDR opaque value
+func (x *xdr) opaque() []byte {
+	return x.bytes(int(x.u32()))
+}
+
+func (x *xdr) address() (ip net.IP) {
+	switch x.u32() {
+	case SFlowAddressIPv4:
+		if b := x.bytes(net.IPv4len); b != nil {
+			ip = net.IP(append([]byte(nil), b...))
+		}
+	case SFlowAddressIPv6:
+		if b := x.bytes(net.IPv6len); b != nil {
+			ip = net.IP(append([]byte(nil), b...))
+		}
+	default:
+		if x.err == nil {
+			x.err = ErrSFlowInvalidAddress
+		}
+	}
+	return
+}
+
+// Decode decodes the datagram header, the buffer is not referenced after decoding
+func (h *SFlowHeader) Decode(b []byte) error {
+	if len(b) < sflowMinHeaderSize {
+		return ErrSFlowTooShort
+	}
+	x := xdr{b: b}
+	if h.Version = x.u32(); h.Version != SFlowVersion {
+		return ErrSFlowInvalidVersion
+	}
+	h.AgentAddr = x.address()
+	h.SubAgentID = x.u32()
+	h.Sequence = x.u32()
+	h.Uptime = x.u32()
+	h.SampleCount = x.u32()
+	if x.err != nil {
+		return x.err
+	} else if h.SampleCount > maxSFlowSamples {
+		return ErrSFlowInvalidCount
+	}
+	h.size = len(b) - len(x.b)
+	return nil
+}
+
+// Size returns the encoded size of the header
+func (h *SFlowHeader) Size() int {
+	return h.size
+}
+
+// Decode decodes an entire sFlow datagram including all samples and records
+// Decoded samples and records reference the provided buffer, callers must not reuse it
+func (d *SFlowDatagram) Decode(b []byte) (err error) {
+	if err = d.SFlowHeader.Decode(b); err != nil {
+		return
+	}
+	x := xdr{b: b[d.size:]}
+	d.Samples = make([]SFlowSample, 0, d.SampleCount)
+	for i := uint32(0); i < d.SampleCount; i++ {
+		var s SFlowSample
+		dataFormat := x.u32()
+		body := x.opaque()
+		if x.err != nil {
+			return x.err
+		}
+		s.Enterprise, s.Format = dataFormat>>12, dataFormat&0xfff
+		if err = s.decode(body); err != nil {
+			return
+		}
+		d.Samples = append(d.Samples, s)
+	}
+	return
+}
+
+func (s *SFlowSample) decode(b []byte) (err error) {
+	s.Data = b
+	if s.Enterprise != 0 {
+		return //not something we know about, leave it raw
+	}
+	x := xdr{b: b}
+	switch s.Format {
+	case SFlowFlowSample:
+		s.Sequence = x.u32()
+		s.SourceIDType, s.SourceIDIndex = splitSourceID(x.u32())
+		s.SamplingRate = x.u32()
+		s.SamplePool = x.u32()
+		s.Drops = x.u32()
+		s.InputFormat, s.Input = splitInterface(x.u32())
+		s.OutputFormat, s.Output = splitInterface(x.u32())
+		s.FlowRecords, err = decodeRecords(&x, decodeFlowRecord)
+	case SFlowExpandedFlowSample:
+		s.Sequence = x.u32()
+		s.SourceIDType = x.u32()
+		s.SourceIDIndex = x.u32()
+		s.SamplingRate = x.u32()
+		s.SamplePool = x.u32()
+		s.Drops = x.u32()
+		s.InputFormat = x.u32()
+		s.Input = x.u32()
+		s.OutputFormat = x.u32()
+		s.Output = x.u32()
+		s.FlowRecords, err = decodeRecords(&x, decodeFlowRecord)
+	case SFlowCounterSample:
+		s.Sequence = x.u32()
+		s.SourceIDType, s.SourceIDIndex = splitSourceID(x.u32())
+		s.CounterRecords, err = decodeRecords(&x, decodeCounterRecord)
+	case SFlowExpandedCounterSample:
+		s.Sequence = x.u32()
+		s.SourceIDType = x.u32()
+		s.SourceIDIndex = x.u32()
+		s.CounterRecords, err = decodeRecords(&x, decodeCounterRecord)
+	}
+	if err == nil {
+		err = x.err
+	}
+	return
+}
+
+// IsFlow returns true if the sample is a flow sample (compact or expanded)
+func (s *SFlowSample) IsFlow() bool {
+	return s.Enterprise == 0 && (s.Format == SFlowFlowSample || s.Format == SFlowExpandedFlowSample)
+}
+
+// IsCounter returns true if the sample is a counter sample (compact or expanded)
+func (s *SFlowSample) IsCounter() bool {
+	return s.Enterprise == 0 && (s.Format == SFlowCounterSample || s.Format == SFlowExpandedCounterSample)
+}
+
+func splitSourceID(v uint32) (tp, idx uint32) {
+	return v >> 24, v & 0xffffff
+}
+
+func splitInterface(v uint32) (format, value uint32) {
+	return v >> 30, v & 0x3fffffff
+}
+
+func decodeRecords(x *xdr, dec func(*SFlowRecord) error) (recs []SFlowRecord, err error) {
+	cnt := x.u32()
+	if x.err != nil {
+		return nil, x.err
+	} else if int(cnt) > len(x.b)/8 {
+		//every record is at least a format and length
+		return nil, ErrSFlowInvalidCount
+	}
+	recs = make([]SFlowRecord, 0, cnt)
+	for i := uint32(0); i < cnt; i++ {
+		var r SFlowRecord
+		dataFormat := x.u32()
+		r.Data = x.opaque()
+		if x.err != nil {
+			return nil, x.err
+		}
+		r.Enterprise, r.Format = dataFormat>>12, dataFormat&0xfff
+		if r.Enterprise == 0 {
+			if err = dec(&r); err != nil {
+				return nil, err
+			}
+		}
+		recs = append(recs, r)
+	}
+	return
+}
+
+func decodeFlowRecord(r *SFlowRecord) error {
+	x := xdr{b: r.Data}
+	switch r.Format {
+	case SFlowRawPacketHeader:
+		rh := &SFlowRawHeader{
+			Protocol:    x.u32(),
+			FrameLength: x.u32(),
+			Stripped:    x.u32(),
+		}
+		rh.Header = x.opaque()
+		r.RawHeader = rh
+	case SFlowEthernetFrame:
+		eth := &SFlowEthernet{
+			Length: x.u32(),
+		}
+		if b := x.bytes(6); b != nil {
+			eth.Src = net.HardwareAddr(b)
+		}
+		if b := x.bytes(6); b != nil {
+			eth.Dst = net.HardwareAddr(b)
+		}
+		eth.Type = x.u32()
+		r.Ethernet = eth
+	case SFlowIPv4Data, SFlowIPv6Data:
+		sz := net.IPv4len
+		if r.Format == SFlowIPv6Data {
+			sz = net.IPv6len
+		}
+		ipd := &SFlowIPData{
+			Length:   x.u32(),
+			Protocol: x.u32(),
+			Src:      net.IP(x.bytes(sz)),
+			Dst:      net.IP(x.bytes(sz)),
+			SrcPort:  x.u32(),
+			DstPort:  x.u32(),
+			TCPFlags: x.u32(),
+			ToS:      x.u32(),
+		}
+		r.IP = ipd
+	case SFlowExtendedSwitch:
+		r.Switch = &SFlowSwitch{
+			SrcVlan:     x.u32(),
+			SrcPriority: x.u32(),
+			DstVlan:     x.u32(),
+			DstPriority: x.u32(),
+		}
+	case SFlowExtendedRouter:
+		r.Router = &SFlowRouter{
+			NextHop: x.address(),
+			SrcMask: x.u32(),
+			DstMask: x.u32(),
+		}
+	}
+	return x.err
+}
+
+func decodeCounterRecord(r *SFlowRecord) error {
+	x := xdr{b: r.Data}
+	switch r.Format {
+	case SFlowGenericInterfaceCounters:
+		if len(r.Data) < genericIfaceSize {
+			return ErrSFlowInvalidLength
+		}
+		r.GenericInterface = &SFlowGenericInterface{
+			Index:            x.u32(),
+			Type:             x.u32(),
+			Speed:            x.u64(),
+			Direction:        x.u32(),
+			Status:           x.u32(),
+			InOctets:         x.u64(),
+			InUcastPkts:      x.u32(),
+			InMulticastPkts:  x.u32(),
+			InBroadcastPkts:  x.u32(),
+			InDiscards:       x.u32(),
+			InErrors:         x.u32(),
+			InUnknownProtos:  x.u32(),
+			OutOctets:        x.u64(),
+			OutUcastPkts:     x.u32(),
+			OutMulticastPkts: x.u32(),
+			OutBroadcastPkts: x.u32(),
+			OutDiscards:      x.u32(),
+			OutErrors:        x.u32(),
+			PromiscuousMode:  x.u32(),
+		}
+	case SFlowEthernetInterfaceCounters:
+		if len(r.Data) < ethernetIfaceSize {
+			return ErrSFlowInvalidLength
+		}
+		r.EthernetInterface = &SFlowEthernetInterface{
+			AlignmentErrors:           x.u32(),
+			FCSErrors:                 x.u32(),
+			SingleCollisionFrames:     x.u32(),
+			MultipleCollisionFrames:   x.u32(),
+			SQETestErrors:             x.u32(),
+			DeferredTransmissions:     x.u32(),
+			LateCollisions:            x.u32(),
+			ExcessiveCollisions:       x.u32(),
+			InternalMacTransmitErrors: x.u32(),
+			CarrierSenseErrors:        x.u32(),
+			FrameTooLongs:             x.u32(),
+			InternalMacReceiveErrors:  x.u32(),
+			SymbolErrors:              x.u32(),
+		}
+	case SFlowProcessorCounters:
+		if len(r.Data) < processorSize {
+			return ErrSFlowInvalidLength
+		}
+		r.Processor = &SFlowProcessor{
+			CPU5s:       x.u32(),
+			CPU1m:       x.u32(),
+			CPU5m:       x.u32(),
+			TotalMemory: x.u64(),
+			FreeMemory:  x.u64(),
+		}
+	}
+	return x.err
+}
+
+// SFlowValidate checks that the buffer contains a structurally valid sFlow V5 datagram without
+// decoding the samples, it returns the decoded header.
+func SFlowValidate(b []byte) (h SFlowHeader, err error) {
+	if err = h.Decode(b); err != nil {
+		return
+	}
+	x := xdr{b: b[h.size:]}
+	for i := uint32(0); i < h.SampleCount; i++ {
+		x.u32()
+		x.opaque()
+		if x.err != nil {
+			err = x.err
+			return
+		}
+	}
+	return
+}
+
+// SFlowSplitSamples breaks a datagram into a set of datagrams that each contain a single sample.
+// Every returned datagram carries the original header with the sample count set to one so that
+// it remains a valid sFlow datagram. The returned buffers are newly allocated.
+func SFlowSplitSamples(b []byte) (dgs [][]byte, err error) {
+	var h SFlowHeader
+	if h, err = SFlowValidate(b); err != nil {
+		return
+	}
+	hdr := b[:h.size]
+	x := xdr{b: b[h.size:]}
+	dgs = make([][]byte, 0, h.SampleCount)
+	for i := uint32(0); i < h.SampleCount; i++ {
+		start := len(b) - len(x.b)
+		x.u32()
+		x.opaque()
+		end := len(b) - len(x.b)
+		dg := make([]byte, 0, len(hdr)+(end-start))
+		dg = append(dg, hdr...)
+		binary.BigEndian.PutUint32(dg[len(dg)-4:], 1)
+		dg = append(dg, b[start:end]...)
+		dgs = append(dgs, dg)
+	}
+	return
+}
@@ -0,0 +1,209 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package netflow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+type xdrBuilder struct {
+	b []byte
+}
+
+func (x *xdrBuilder) u32(v uint32) *xdrBuilder {
+	x.b = binary.BigEndian.AppendUint32(x.b, v)
+	return x
+}
+
+func (x *xdrBuilder) u64(v uint64) *xdrBuilder {
+	x.b = binary.BigEndian.AppendUint64(x.b, v)
+	return x
+}
+
+func (x *xdrBuilder) raw(v []byte) *xdrBuilder {
+	x.b = append(x.b, v...)
+	for len(x.b)%4 != 0 {
+		x.b = append(x.b, 0)
+	}
+	return x
+}
+
+func (x *xdrBuilder) opaque(v []byte) *xdrBuilder {
+	return x.u32(uint32(len(v))).raw(v)
+}
+
+var (
+	testAgent     = net.ParseIP("10.0.0.1").To4()
+	testPktHeader = []byte{
+		0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0x08, 0x00, // ethernet
+		0x45, 0x00, 0x00, 0x54, 0x00, 0x00, 0x40, 0x00, 0x40, 0x01, 0x00, 0x00,
+		0xc0, 0xa8, 0x01, 0x01, 0xc0, 0xa8, 0x01, 0x02, // ipv4 192.168.1.1 -> 192.168.1.2
+		0x08, 0x00, 0x00, // truncated icmp, forces padding
+	}
+)
+
+func flowSampleBody() []byte {
+	var rh, sw, body xdrBuilder
+	rh.u32(SFlowHeaderEthernet).u32(98).u32(4).opaque(testPktHeader)
+	sw.u32(10).u32(0).u32(20).u32(0)
+	body.u32(7). // sequence
+			u32(0<<24 | 3). // source id
+			u32(512).       // sampling rate
+			u32(4096).      // sample pool
+			u32(1).         // drops
+			u32(3).         // input
+			u32(1<<30 | 5). // output, format 1 is discarded
+			u32(2).         // record count
+			u32(SFlowRawPacketHeader).opaque(rh.b).
+			u32(SFlowExtendedSwitch).opaque(sw.b)
+	return body.b
+}
+
+func counterSampleBody() []byte {
+	var gen, proc, body xdrBuilder
+	gen.u32(3).u32(6).u64(10000000000).u32(1).u32(3).
+		u64(123456789).u32(1000).u32(10).u32(5).u32(0).u32(1).u32(0).
+		u64(987654321).u32(2000).u32(20).u32(6).u32(0).u32(2).u32(0)
+	proc.u32(1500).u32(1200).u32(1000).u64(1 << 34).u64(1 << 32)
+	body.u32(8).u32(0<<24 | 3).u32(2).
+		u32(SFlowGenericInterfaceCounters).opaque(gen.b).
+		u32(SFlowProcessorCounters).opaque(proc.b)
+	return body.b
+}
+
+func testDatagram() []byte {
+	var dg xdrBuilder
+	dg.u32(SFlowVersion).u32(SFlowAddressIPv4).raw(testAgent).
+		u32(1).     // sub agent
+		u32(42).    // sequence
+		u32(60000). // uptime
+		u32(3).     // samples
+		u32(SFlowFlowSample).opaque(flowSampleBody()).
+		u32(SFlowCounterSample).opaque(counterSampleBody()).
+		u32(1234<<12 | 1).opaque([]byte{1, 2, 3, 4}) // enterprise specific
+	return dg.b
+}
+
+func TestSFlowDecode(t *testing.T) {
+	var d SFlowDatagram
+	if err := d.Decode(testDatagram()); err != nil {
+		t.Fatal(err)
+	}
+	if d.Version != SFlowVersion || !d.AgentAddr.Equal(testAgent) || d.SubAgentID != 1 || d.Sequence != 42 || d.Uptime != 60000 {
+		t.Fatalf("bad header: %+v", d.SFlowHeader)
+	}
+	if len(d.Samples) != 3 {
+		t.Fatalf("bad sample count: %d", len(d.Samples))
+	}
+
+	fs := d.Samples[0]
+	if !fs.IsFlow() || fs.IsCounter() {
+		t.Fatal("first sample is not a flow sample")
+	}
+	if fs.Sequence != 7 || fs.SourceIDIndex != 3 || fs.SamplingRate != 512 || fs.SamplePool != 4096 || fs.Drops != 1 {
+		t.Fatalf("bad flow sample: %+v", fs)
+	}
+	if fs.Input != 3 || fs.OutputFormat != 1 || fs.Output != 5 {
+		t.Fatalf("bad flow sample interfaces: %+v", fs)
+	}
+	if len(fs.FlowRecords) != 2 {
+		t.Fatalf("bad flow record count: %d", len(fs.FlowRecords))
+	}
+	rh := fs.FlowRecords[0].RawHeader
+	if rh == nil {
+		t.Fatal("missing raw header record")
+	} else if rh.Protocol != SFlowHeaderEthernet || rh.FrameLength != 98 || rh.Stripped != 4 {
+		t.Fatalf("bad raw header: %+v", rh)
+	} else if !bytes.Equal(rh.Header, testPktHeader) {
+		t.Fatalf("bad raw packet header: %x", rh.Header)
+	}
+	if sw := fs.FlowRecords[1].Switch; sw == nil || sw.SrcVlan != 10 || sw.DstVlan != 20 {
+		t.Fatalf("bad switch record: %+v", sw)
+	}
+
+	cs := d.Samples[1]
+	if !cs.IsCounter() || len(cs.CounterRecords) != 2 {
+		t.Fatalf("bad counter sample: %+v", cs)
+	}
+	gi := cs.CounterRecords[0].GenericInterface
+	if gi == nil || gi.Index != 3 || gi.Speed != 10000000000 || gi.InOctets != 123456789 || gi.OutOctets != 987654321 || gi.OutErrors != 2 {
+		t.Fatalf("bad generic interface counters: %+v", gi)
+	}
+	if p := cs.CounterRecords[1].Processor; p == nil || p.CPU5s != 1500 || p.TotalMemory != 1<<34 || p.FreeMemory != 1<<32 {
+		t.Fatalf("bad processor counters: %+v", p)
+	}
+
+	es := d.Samples[2]
+	if es.Enterprise != 1234 || es.Format != 1 || !bytes.Equal(es.Data, []byte{1, 2, 3, 4}) {
+		t.Fatalf("bad enterprise sample: %+v", es)
+	}
+}
+
+func TestSFlowSplitSamples(t *testing.T) {
+	dg := testDatagram()
+	dgs, err := SFlowSplitSamples(dg)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(dgs) != 3 {
+		t.Fatalf("bad split count: %d", len(dgs))
+	}
+	for i, b := range dgs {
+		var d SFlowDatagram
+		if err := d.Decode(b); err != nil {
+			t.Fatalf("failed to decode split datagram %d: %v", i, err)
+		} else if d.SampleCount != 1 || len(d.Samples) != 1 {
+			t.Fatalf("bad sample count on split datagram %d: %d", i, d.SampleCount)
+		} else if !d.AgentAddr.Equal(testAgent) || d.Sequence != 42 {
+			t.Fatalf("bad header on split datagram %d: %+v", i, d.SFlowHeader)
+		}
+	}
+	//make sure we didn't clobber the original
+	if h, err := SFlowValidate(dg); err != nil || h.SampleCount != 3 {
+		t.Fatalf("original datagram modified: %v %d", err, h.SampleCount)
+	}
+}
+
+func TestSFlowBadDatagrams(t *testing.T) {
+	dg := testDatagram()
+	var d SFlowDatagram
+	//truncate at every point, none of these should decode or panic
+	for i := 0; i < len(dg); i++ {
+		if err := d.Decode(dg[:i]); err == nil {
+			t.Fatalf("truncated datagram of %d bytes decoded", i)
+		}
+		if _, err := SFlowValidate(dg[:i]); err == nil {
+			t.Fatalf("truncated datagram of %d bytes validated", i)
+		}
+	}
+	bad := append([]byte(nil), dg...)
+	binary.BigEndian.PutUint32(bad, 4)
+	if err := d.Decode(bad); err != ErrSFlowInvalidVersion {
+		t.Fatalf("bad version not caught: %v", err)
+	}
+	bad = append([]byte(nil), dg...)
+	binary.BigEndian.PutUint32(bad[4:], 3)
+	if err := d.Decode(bad); err != ErrSFlowInvalidAddress {
+		t.Fatalf("bad address type not caught: %v", err)
+	}
+}
+
+func BenchmarkSFlowDecode(b *testing.B) {
+	dg := testDatagram()
+	var d SFlowDatagram
+	b.SetBytes(int64(len(dg)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := d.Decode(dg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
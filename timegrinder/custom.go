@@ -33,6 +33,11 @@ type CustomFormat struct {
 	Name   string
 	Regex  string
 	Format string
+	// Format_Syntax is the syntax of Format: go (the default), strftime, or java.
+	// When the syntax is strftime or java and Regex is empty, the extraction regex is generated from the format.
+	Format_Syntax string
+	// Locale adds month and weekday names from other languages to this format, e.g. de, fr, or es
+	Locale []string
 
 	// optional pre-extraction system that can go get the meat of a timestamp before actually trying to handle the timestamp
 	Extraction_Regex string
@@ -55,6 +60,11 @@ func (cf *CustomFormat) Validate() (err error) {
 	} else if cf.Format == `` {
 		return ErrMissingFormat
 	}
+	if err = cf.translate(); err != nil {
+		return
+	} else if err = ValidateLocales(cf.Locale); err != nil {
+		return
+	}
 
 	if cf.Regex != `` {
 		if _, ok := tg.GetProcessor(cf.Format); ok {
@@ -110,6 +120,22 @@ func (cf *CustomFormat) Validate() (err error) {
 	return
 }
 
+// translate converts strftime and java formats to a Go layout, generating the extraction regex if it was not provided
+func (cf *CustomFormat) translate() (err error) {
+	var layout, rx string
+	if layout, rx, err = translateFormat(cf.Format_Syntax, cf.Format); err != nil {
+		err = fmt.Errorf("Invalid %s time format: %w", cf.Format_Syntax, err)
+		return
+	} else if rx == `` {
+		return //already a Go layout
+	}
+	cf.Format, cf.Format_Syntax = layout, SyntaxGo
+	if cf.Regex == `` {
+		cf.Regex = rx
+	}
+	return
+}
+
 func (cf CustomFormat) ExtractionRegex() string {
 	return cf.Regex
 }
@@ -146,6 +172,13 @@ func NewCustomProcessor(cf CustomFormat) (p Processor, err error) {
 		}
 		p = cp
 	}
+	if len(cf.Locale) > 0 {
+		var lz *localizer
+		if lz, err = newLocalizer(cf.Locale); err != nil {
+			return
+		}
+		p = localize(p, lz)
+	}
 	return
 }
 
@@ -0,0 +1,433 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Format syntaxes supported by CustomFormat, the default is a Go reference time layout
+const (
+	SyntaxGo       = `go`
+	SyntaxStrftime = `strftime`
+	SyntaxJava     = `java`
+)
+
+var (
+	ErrInvalidSyntax      = errors.New("Invalid format syntax, must be go, strftime, or java")
+	ErrFractionNoSep      = errors.New("Fractional seconds must follow a '.' or ','")
+	ErrUnterminatedQuote  = errors.New("Unterminated quote in format")
+	ErrUnterminatedEscape = errors.New("Format ends in an incomplete directive")
+)
+
+// goLayoutWords are the chunks of text Go treats as layout elements, literal text cannot contribute to them
+// because Go layouts have no escape mechanism.
+var goLayoutWords = []string{`Jan`, `Mon`, `MST`, `PM`, `pm`, `_2`}
+
+// layoutBuilder accumulates a Go layout and a matching extraction regular expression
+type layoutBuilder struct {
+	layout strings.Builder
+	rx     strings.Builder
+	lit    []bool // which bytes of the layout came from literal text
+	last   byte   // last literal byte written, used to check fractional seconds
+}
+
+type layoutElement struct {
+	layout string
+	rx     string
+}
+
+var (
+	elYear4      = layoutElement{`2006`, `\d{4}`}
+	elYear2      = layoutElement{`06`, `\d{2}`}
+	elMonth      = layoutElement{`01`, `\d{2}`}
+	elMonthU     = layoutElement{`1`, `\d{1,2}`}
+	elMonthShort = layoutElement{`Jan`, `[A-Za-z]{3}`}
+	elMonthLong  = layoutElement{`January`, `[A-Za-z]{3,9}`}
+	elDay        = layoutElement{`02`, `\d{2}`}
+	elDayU       = layoutElement{`2`, `\d{1,2}`}
+	elDaySpace   = layoutElement{`_2`, `[ \d]\d`}
+	elYearDay    = layoutElement{`002`, `\d{3}`}
+	elWeekShort  = layoutElement{`Mon`, `[A-Za-z]{3}`}
+	elWeekLong   = layoutElement{`Monday`, `[A-Za-z]{6,9}`}
+	elHour       = layoutElement{`15`, `\d{2}`}
+	elHour12     = layoutElement{`03`, `\d{2}`}
+	elHour12U    = layoutElement{`3`, `\d{1,2}`}
+	elMinute     = layoutElement{`04`, `\d{2}`}
+	elMinuteU    = layoutElement{`4`, `\d{1,2}`}
+	elSecond     = layoutElement{`05`, `\d{2}`}
+	elSecondU    = layoutElement{`5`, `\d{1,2}`}
+	elPM         = layoutElement{`PM`, `[AP]M`}
+	elZoneName   = layoutElement{`MST`, `[A-Z][A-Za-z]{2,4}`}
+	elZone       = layoutElement{`-0700`, `[+\-]\d{4}`}
+	elZoneColon  = layoutElement{`-07:00`, `[+\-]\d{2}:\d{2}`}
+	elZoneHour   = layoutElement{`-07`, `[+\-]\d{2}`}
+	elZoneZ      = layoutElement{`Z0700`, `(?:Z|[+\-]\d{4})`}
+	elZoneZColon = layoutElement{`Z07:00`, `(?:Z|[+\-]\d{2}:\d{2})`}
+	elZoneZHour  = layoutElement{`Z07`, `(?:Z|[+\-]\d{2})`}
+)
+
+func (lb *layoutBuilder) element(el layoutElement) {
+	lb.layout.WriteString(el.layout)
+	lb.rx.WriteString(el.rx)
+	lb.lit = append(lb.lit, make([]bool, len(el.layout))...)
+	lb.last = 0
+}
+
+// fraction adds n digits of fractional seconds, Go only recognizes them directly after a period or comma
+func (lb *layoutBuilder) fraction(n int) error {
+	if lb.last != '.' && lb.last != ',' {
+		return ErrFractionNoSep
+	}
+	lb.layout.WriteString(strings.Repeat(`0`, n))
+	lb.rx.WriteString(fmt.Sprintf(`\d{%d}`, n))
+	lb.lit = append(lb.lit, make([]bool, n)...)
+	lb.last = 0
+	return nil
+}
+
+// literal adds literal text, the text is checked once the whole layout is built
+func (lb *layoutBuilder) literal(s string) {
+	if len(s) == 0 {
+		return
+	}
+	lb.layout.WriteString(s)
+	lb.rx.WriteString(regexp.QuoteMeta(s))
+	for i := 0; i < len(s); i++ {
+		lb.lit = append(lb.lit, true)
+	}
+	lb.last = s[len(s)-1]
+}
+
+// translateFormat converts a format in the given syntax to a Go layout and an extraction regular expression.
+// Go layouts are handed back as is with no regular expression.
+func translateFormat(syntax, format string) (layout, rx string, err error) {
+	var lb *layoutBuilder
+	switch strings.ToLower(syntax) {
+	case ``, SyntaxGo:
+		layout = format
+		return
+	case SyntaxStrftime:
+		lb, err = strftimeLayout(format)
+	case SyntaxJava:
+		lb, err = javaLayout(format)
+	default:
+		err = ErrInvalidSyntax
+	}
+	if err != nil {
+		return
+	}
+	layout, rx = lb.layout.String(), lb.rx.String()
+	err = lb.checkLiterals(format)
+	return
+}
+
+// checkLiterals makes sure that literal text did not turn into layout elements, a literal digit or
+// the word Jan would change the meaning of the Go layout
+func (lb *layoutBuilder) checkLiterals(format string) error {
+	layout := lb.layout.String()
+	for i, c := range []byte(layout) {
+		if lb.lit[i] && c >= '0' && c <= '9' {
+			return fmt.Errorf("Format %q contains literal digits, which cannot be represented in a timestamp layout", format)
+		}
+	}
+	for _, w := range goLayoutWords {
+		for off := 0; ; {
+			idx := strings.Index(layout[off:], w)
+			if idx < 0 {
+				break
+			}
+			idx += off
+			if w == `_2` && strings.HasPrefix(layout[idx+1:], `2006`) {
+				off = idx + 1
+				continue //Go treats _2006 as an underscore and a year
+			}
+			for _, l := range lb.lit[idx : idx+len(w)] {
+				if l {
+					return fmt.Errorf("Format %q contains literal text that forms %q, which cannot be represented in a timestamp layout", format, w)
+				}
+			}
+			off = idx + 1
+		}
+	}
+	return nil
+}
+
+// strftimeLayout handles the C strftime directives along with the GNU %- modifier for unpadded values
+func strftimeLayout(format string) (lb *layoutBuilder, err error) {
+	lb = &layoutBuilder{}
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			lb.literal(format[i : i+1])
+			continue
+		}
+		if i++; i >= len(format) {
+			return nil, ErrUnterminatedEscape
+		}
+		unpadded := format[i] == '-'
+		if unpadded {
+			if i++; i >= len(format) {
+				return nil, ErrUnterminatedEscape
+			}
+		}
+		d := format[i]
+		if unpadded {
+			switch d {
+			case 'm':
+				lb.element(elMonthU)
+			case 'd', 'e':
+				lb.element(elDayU)
+			case 'I', 'l':
+				lb.element(elHour12U)
+			case 'M':
+				lb.element(elMinuteU)
+			case 'S':
+				lb.element(elSecondU)
+			default:
+				return nil, fmt.Errorf("Unsupported strftime directive %%-%c", d)
+			}
+			continue
+		}
+		switch d {
+		case 'Y':
+			lb.element(elYear4)
+		case 'y':
+			lb.element(elYear2)
+		case 'm':
+			lb.element(elMonth)
+		case 'b', 'h':
+			lb.element(elMonthShort)
+		case 'B':
+			lb.element(elMonthLong)
+		case 'd':
+			lb.element(elDay)
+		case 'e':
+			lb.element(elDaySpace)
+		case 'j':
+			lb.element(elYearDay)
+		case 'a':
+			lb.element(elWeekShort)
+		case 'A':
+			lb.element(elWeekLong)
+		case 'H':
+			lb.element(elHour)
+		case 'I':
+			lb.element(elHour12)
+		case 'M':
+			lb.element(elMinute)
+		case 'S':
+			lb.element(elSecond)
+		case 'p':
+			lb.element(elPM)
+		case 'f':
+			err = lb.fraction(6)
+		case 'L':
+			err = lb.fraction(3)
+		case 'N':
+			err = lb.fraction(9)
+		case 'z':
+			lb.element(elZone)
+		case 'Z':
+			lb.element(elZoneName)
+		case 'F':
+			lb.element(elYear4)
+			lb.literal(`-`)
+			lb.element(elMonth)
+			lb.literal(`-`)
+			lb.element(elDay)
+		case 'D':
+			lb.element(elMonth)
+			lb.literal(`/`)
+			lb.element(elDay)
+			lb.literal(`/`)
+			lb.element(elYear2)
+		case 'T':
+			lb.element(elHour)
+			lb.literal(`:`)
+			lb.element(elMinute)
+			lb.literal(`:`)
+			lb.element(elSecond)
+		case 'R':
+			lb.element(elHour)
+			lb.literal(`:`)
+			lb.element(elMinute)
+		case 't':
+			lb.literal("\t")
+		case '%':
+			lb.literal(`%`)
+		default:
+			return nil, fmt.Errorf("Unsupported strftime directive %%%c", d)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+func isPatternLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// javaLayout handles the Java SimpleDateFormat and DateTimeFormatter pattern letters that have
+// a Go equivalent.  Text in single quotes is literal and two single quotes are a literal quote.
+func javaLayout(format string) (lb *layoutBuilder, err error) {
+	lb = &layoutBuilder{}
+	for i := 0; i < len(format); {
+		c := format[i]
+		if c == '\'' {
+			var lit string
+			if lit, i, err = javaQuoted(format, i); err != nil {
+				return nil, err
+			}
+			lb.literal(lit)
+			continue
+		} else if !isPatternLetter(c) {
+			lb.literal(format[i : i+1])
+			i++
+			continue
+		}
+		n := 1
+		for i+n < len(format) && format[i+n] == c {
+			n++
+		}
+		i += n
+		if err = lb.javaElement(c, n); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// javaQuoted returns the literal text of a quoted section starting at offset i and the offset after it
+func javaQuoted(format string, i int) (lit string, next int, err error) {
+	if i+1 < len(format) && format[i+1] == '\'' {
+		return `'`, i + 2, nil
+	}
+	var sb strings.Builder
+	for j := i + 1; j < len(format); j++ {
+		if format[j] != '\'' {
+			sb.WriteByte(format[j])
+			continue
+		} else if j+1 < len(format) && format[j+1] == '\'' {
+			sb.WriteByte('\'')
+			j++
+			continue
+		}
+		return sb.String(), j + 1, nil
+	}
+	return ``, 0, ErrUnterminatedQuote
+}
+
+func (lb *layoutBuilder) javaElement(c byte, n int) (err error) {
+	switch c {
+	case 'y', 'u':
+		if n == 2 {
+			lb.element(elYear2)
+		} else {
+			lb.element(elYear4)
+		}
+	case 'M', 'L':
+		switch n {
+		case 1:
+			lb.element(elMonthU)
+		case 2:
+			lb.element(elMonth)
+		case 3:
+			lb.element(elMonthShort)
+		default:
+			lb.element(elMonthLong)
+		}
+	case 'd':
+		if n == 1 {
+			lb.element(elDayU)
+		} else if n == 2 {
+			lb.element(elDay)
+		} else {
+			err = fmt.Errorf("Unsupported pattern %s", strings.Repeat(string(c), n))
+		}
+	case 'D':
+		if n == 3 {
+			lb.element(elYearDay)
+		} else {
+			err = fmt.Errorf("Unsupported pattern %s, day of year must be DDD", strings.Repeat(string(c), n))
+		}
+	case 'E':
+		if n < 4 {
+			lb.element(elWeekShort)
+		} else {
+			lb.element(elWeekLong)
+		}
+	case 'H':
+		if n == 2 {
+			lb.element(elHour)
+		} else {
+			err = fmt.Errorf("Unsupported pattern %s, 24 hour values must be HH", strings.Repeat(string(c), n))
+		}
+	case 'h':
+		if n == 1 {
+			lb.element(elHour12U)
+		} else {
+			lb.element(elHour12)
+		}
+	case 'm':
+		if n == 1 {
+			lb.element(elMinuteU)
+		} else {
+			lb.element(elMinute)
+		}
+	case 's':
+		if n == 1 {
+			lb.element(elSecondU)
+		} else {
+			lb.element(elSecond)
+		}
+	case 'S':
+		if n > 9 {
+			err = fmt.Errorf("Unsupported pattern %s, at most 9 fractional digits are allowed", strings.Repeat(string(c), n))
+		} else {
+			err = lb.fraction(n)
+		}
+	case 'a':
+		lb.element(elPM)
+	case 'z':
+		if n < 4 {
+			lb.element(elZoneName)
+		} else {
+			err = fmt.Errorf("Unsupported pattern %s, full zone names are not supported", strings.Repeat(string(c), n))
+		}
+	case 'Z':
+		if n < 4 {
+			lb.element(elZone)
+		} else if n == 5 {
+			lb.element(elZoneColon)
+		} else {
+			err = fmt.Errorf("Unsupported pattern %s", strings.Repeat(string(c), n))
+		}
+	case 'X', 'x':
+		zones := [2][3]layoutElement{
+			{elZoneZHour, elZoneZ, elZoneZColon},
+			{elZoneHour, elZone, elZoneColon},
+		}
+		if n > 3 {
+			err = fmt.Errorf("Unsupported pattern %s", strings.Repeat(string(c), n))
+		} else if c == 'X' {
+			lb.element(zones[0][n-1])
+		} else {
+			lb.element(zones[1][n-1])
+		}
+	default:
+		err = fmt.Errorf("Unsupported pattern letter %c", c)
+	}
+	return
+}
@@ -0,0 +1,120 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTranslateFormat(t *testing.T) {
+	tests := []struct {
+		syntax string
+		format string
+		layout string
+	}{
+		{SyntaxStrftime, `%Y-%m-%d %H:%M:%S`, `2006-01-02 15:04:05`},
+		{SyntaxStrftime, `%F %T.%f %z`, `2006-01-02 15:04:05.000000 -0700`},
+		{SyntaxStrftime, `%a %b %e %I:%M:%S %p %Z %Y`, `Mon Jan _2 03:04:05 PM MST 2006`},
+		{SyntaxStrftime, `%A, %B %-d %y %%`, `Monday, January 2 06 %`},
+		{SyntaxStrftime, `%D %R,%L`, `01/02/06 15:04,000`},
+		{SyntaxStrftime, `%Y.%j`, `2006.002`},
+		{SyntaxJava, `yyyy-MM-dd'T'HH:mm:ss.SSSXXX`, `2006-01-02T15:04:05.000Z07:00`},
+		{SyntaxJava, `dd/MMM/yyyy:HH:mm:ss Z`, `02/Jan/2006:15:04:05 -0700`},
+		{SyntaxJava, `EEE, d MMMM uuuu h:mm a z`, `Mon, 2 January 2006 3:04 PM MST`},
+		{SyntaxJava, `EEEE yy-M-d HH:mm:ss,SSSSSSSSS xx`, `Monday 06-1-2 15:04:05,000000000 -0700`},
+		{SyntaxJava, `yyyyMMddHHmmss 'o''clock' ZZZZZ`, `20060102150405 o'clock -07:00`},
+	}
+	ts := time.Date(2024, time.November, 7, 16, 5, 9, 123456789, time.FixedZone(`PST`, -8*3600))
+	for _, tc := range tests {
+		layout, rx, err := translateFormat(tc.syntax, tc.format)
+		if err != nil {
+			t.Fatalf("%s %q: %v", tc.syntax, tc.format, err)
+		} else if layout != tc.layout {
+			t.Fatalf("%s %q: bad layout %q != %q", tc.syntax, tc.format, layout, tc.layout)
+		}
+		//the generated regex must match exactly what the layout produces
+		re, err := regexp.Compile(`^` + rx + `$`)
+		if err != nil {
+			t.Fatalf("%s %q: bad regex %q: %v", tc.syntax, tc.format, rx, err)
+		} else if v := ts.Format(layout); !re.MatchString(v) {
+			t.Fatalf("%s %q: regex %q did not match %q", tc.syntax, tc.format, rx, v)
+		}
+	}
+}
+
+func TestTranslateFormatErrors(t *testing.T) {
+	tests := []struct {
+		syntax string
+		format string
+	}{
+		{`bogus`, `%Y`},
+		{SyntaxStrftime, `%Y-%m-%d %`},
+		{SyntaxStrftime, `%s`},
+		{SyntaxStrftime, `%-H`},
+		{SyntaxStrftime, `%H:%M:%S%f`},  // no separator before the fraction
+		{SyntaxStrftime, `%Y-%m-%d 1`},  // literal digit
+		{SyntaxStrftime, `Jan %d %Y`},   // literal month name
+		{SyntaxStrftime, `%H:%M P%a`},   // literal P joins the weekday to form PM
+		{SyntaxStrftime, `%H_%-d`},      // literal underscore joins the day to form _2
+		{SyntaxJava, `yyyy-MM-dd 'T`},   // unterminated quote
+		{SyntaxJava, `yyyy-MM-dd H:mm`}, // no unpadded 24 hour in Go
+		{SyntaxJava, `yyyy-MM-dd G`},
+		{SyntaxJava, `HH:mm:ssSSS`},
+		{SyntaxJava, `yyyy zzzz`},
+		{SyntaxJava, `'Mon' yyyy`},
+	}
+	for _, tc := range tests {
+		if layout, _, err := translateFormat(tc.syntax, tc.format); err == nil {
+			t.Fatalf("%s %q: failed to catch bad format, got %q", tc.syntax, tc.format, layout)
+		}
+	}
+	//an underscore before a year is fine
+	if layout, _, err := translateFormat(SyntaxStrftime, `%H_%Y`); err != nil || layout != `15_2006` {
+		t.Fatalf("bad layout %q %v", layout, err)
+	}
+}
+
+func TestCustomFormatSyntax(t *testing.T) {
+	tg, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cf := range []CustomFormat{
+		{Name: `strf`, Format: `%d|%m|%Y %H.%M.%S`, Format_Syntax: SyntaxStrftime},
+		{Name: `java`, Format: `dd~MM~yyyy HH.mm.ss`, Format_Syntax: SyntaxJava},
+	} {
+		p, err := NewCustomProcessor(cf)
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err = tg.AddProcessor(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := map[string]string{
+		`prefix 07|11|2024 16.05.09 suffix`: `strf`,
+		`prefix 07~11~2024 16.05.09 suffix`: `java`,
+	}
+	exp := time.Date(2024, time.November, 7, 16, 5, 9, 0, time.UTC)
+	for v, name := range tests {
+		ts, pname, _, _, ok := tg.DebugMatch([]byte(v))
+		if !ok {
+			t.Fatalf("failed to match %q", v)
+		} else if pname != name || !ts.Equal(exp) {
+			t.Fatalf("bad match on %q: %v %v", v, pname, ts)
+		}
+	}
+
+	//a provided regex is left alone, a bad one still fails validation
+	cf := CustomFormat{Name: `bad`, Format: `%Y-%m-%d`, Format_Syntax: SyntaxStrftime, Regex: `\d{2}:\d{2}`}
+	if err = cf.Validate(); err != ErrRegexFormatMismatch {
+		t.Fatalf("failed to catch mismatched regex: %v", err)
+	}
+}
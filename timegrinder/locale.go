@@ -0,0 +1,326 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// localeNames holds month and weekday spellings for a language, the first spelling of each is the full name.
+// Spellings are lower case, matching is case insensitive.
+type localeNames struct {
+	months   [12][]string
+	weekdays [7][]string // Sunday first, the same as time.Weekday
+}
+
+var locales = map[string]localeNames{
+	`de`: {
+		months: [12][]string{
+			{`januar`, `jan`, `jänner`, `jän`}, {`februar`, `feb`, `feber`}, {`märz`, `mär`, `mrz`, `maerz`},
+			{`april`, `apr`}, {`mai`}, {`juni`, `jun`}, {`juli`, `jul`}, {`august`, `aug`},
+			{`september`, `sep`, `sept`}, {`oktober`, `okt`}, {`november`, `nov`}, {`dezember`, `dez`},
+		},
+		weekdays: [7][]string{
+			{`sonntag`, `so`, `son`}, {`montag`, `mo`, `mon`}, {`dienstag`, `di`, `die`}, {`mittwoch`, `mi`, `mit`},
+			{`donnerstag`, `do`, `don`}, {`freitag`, `fr`, `fre`}, {`samstag`, `sa`, `sam`, `sonnabend`},
+		},
+	},
+	`fr`: {
+		months: [12][]string{
+			{`janvier`, `janv`, `jan`}, {`février`, `févr`, `fév`, `fevrier`, `fevr`, `fev`}, {`mars`},
+			{`avril`, `avr`}, {`mai`}, {`juin`}, {`juillet`, `juil`}, {`août`, `aoû`, `aout`},
+			{`septembre`, `sept`, `sep`}, {`octobre`, `oct`}, {`novembre`, `nov`}, {`décembre`, `déc`, `decembre`, `dec`},
+		},
+		weekdays: [7][]string{
+			{`dimanche`, `dim`}, {`lundi`, `lun`}, {`mardi`, `mar`}, {`mercredi`, `mer`},
+			{`jeudi`, `jeu`}, {`vendredi`, `ven`}, {`samedi`, `sam`},
+		},
+	},
+	`es`: {
+		months: [12][]string{
+			{`enero`, `ene`}, {`febrero`, `feb`}, {`marzo`, `mar`}, {`abril`, `abr`}, {`mayo`, `may`},
+			{`junio`, `jun`}, {`julio`, `jul`}, {`agosto`, `ago`}, {`septiembre`, `sep`, `sept`, `setiembre`, `set`},
+			{`octubre`, `oct`}, {`noviembre`, `nov`}, {`diciembre`, `dic`},
+		},
+		weekdays: [7][]string{
+			{`domingo`, `dom`}, {`lunes`, `lun`}, {`martes`, `mar`}, {`miércoles`, `mié`, `miercoles`, `mie`},
+			{`jueves`, `jue`}, {`viernes`, `vie`}, {`sábado`, `sáb`, `sabado`, `sab`},
+		},
+	},
+	`it`: {
+		months: [12][]string{
+			{`gennaio`, `gen`}, {`febbraio`, `feb`}, {`marzo`, `mar`}, {`aprile`, `apr`}, {`maggio`, `mag`},
+			{`giugno`, `giu`}, {`luglio`, `lug`}, {`agosto`, `ago`}, {`settembre`, `set`},
+			{`ottobre`, `ott`}, {`novembre`, `nov`}, {`dicembre`, `dic`},
+		},
+		weekdays: [7][]string{
+			{`domenica`, `dom`}, {`lunedì`, `lun`, `lunedi`}, {`martedì`, `mar`, `martedi`}, {`mercoledì`, `mer`, `mercoledi`},
+			{`giovedì`, `gio`, `giovedi`}, {`venerdì`, `ven`, `venerdi`}, {`sabato`, `sab`},
+		},
+	},
+	`pt`: {
+		months: [12][]string{
+			{`janeiro`, `jan`}, {`fevereiro`, `fev`}, {`março`, `mar`, `marco`}, {`abril`, `abr`}, {`maio`, `mai`},
+			{`junho`, `jun`}, {`julho`, `jul`}, {`agosto`, `ago`}, {`setembro`, `set`},
+			{`outubro`, `out`}, {`novembro`, `nov`}, {`dezembro`, `dez`},
+		},
+		weekdays: [7][]string{
+			{`domingo`, `dom`}, {`segunda`, `seg`}, {`terça`, `ter`, `terca`}, {`quarta`, `qua`},
+			{`quinta`, `qui`}, {`sexta`, `sex`}, {`sábado`, `sáb`, `sabado`, `sab`},
+		},
+	},
+}
+
+// Locales returns the names of the supported locales
+func Locales() (r []string) {
+	for k := range locales {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return
+}
+
+// ValidateLocales checks that every locale is supported
+func ValidateLocales(names []string) error {
+	for _, n := range names {
+		if _, ok := locales[strings.ToLower(strings.TrimSpace(n))]; !ok {
+			return fmt.Errorf("Unsupported locale %q, supported locales are %s", n, strings.Join(Locales(), ", "))
+		}
+	}
+	return nil
+}
+
+type localeToken struct {
+	month   time.Month // zero if the token is not a month
+	weekday time.Weekday
+	abbrev  bool // abbreviations swallow a trailing period
+}
+
+// localizer rewrites localized month and weekday names to English so that the Go layouts can parse them
+type localizer struct {
+	names  []string
+	tokens map[string]localeToken
+}
+
+// newLocalizer builds a localizer for a set of locales.  When a spelling is both a month and a weekday,
+// for example the Spanish mar, it is treated as a month.
+func newLocalizer(names []string) (*localizer, error) {
+	if err := ValidateLocales(names); err != nil {
+		return nil, err
+	}
+	lz := &localizer{
+		tokens: map[string]localeToken{},
+	}
+	seen := map[string]bool{}
+	for _, n := range names {
+		if n = strings.ToLower(strings.TrimSpace(n)); seen[n] {
+			continue
+		}
+		seen[n] = true
+		lz.names = append(lz.names, n)
+		ln := locales[n]
+		for i, spellings := range ln.weekdays {
+			for j, v := range spellings {
+				if _, ok := lz.tokens[v]; !ok {
+					lz.tokens[v] = localeToken{weekday: time.Weekday(i), abbrev: j > 0}
+				}
+			}
+		}
+		for i, spellings := range ln.months {
+			for j, v := range spellings {
+				if tok, ok := lz.tokens[v]; !ok || tok.month == 0 {
+					lz.tokens[v] = localeToken{month: time.Month(i + 1), abbrev: j > 0}
+				}
+			}
+		}
+	}
+	return lz, nil
+}
+
+// merge returns a localizer covering both sets of locales
+func (lz *localizer) merge(names []string) (*localizer, error) {
+	if lz == nil {
+		return newLocalizer(names)
+	}
+	return newLocalizer(append(append([]string{}, lz.names...), names...))
+}
+
+type localeReplacement struct {
+	origStart, origEnd int
+	newStart, newEnd   int
+}
+
+// translate rewrites localized names in d to English, full names are used when the layout calls for them.
+// The replacements are returned so that offsets can be mapped back to the original data.
+func (lz *localizer) translate(d []byte, fullMonth, fullWeekday bool) (out []byte, reps []localeReplacement) {
+	var lower [32]byte
+	var last int
+	for i := 0; i < len(d); {
+		r, w := utf8.DecodeRune(d[i:])
+		if !unicode.IsLetter(r) {
+			i += w
+			continue
+		}
+		//grab the run of letters
+		start := i
+		for i < len(d) {
+			if r, w = utf8.DecodeRune(d[i:]); !unicode.IsLetter(r) {
+				break
+			}
+			i += w
+		}
+		word := d[start:i]
+		if len(word) < 2 || len(word) > len(lower) {
+			continue
+		}
+		tok, ok := lz.tokens[string(toLower(lower[:0], word))]
+		if !ok {
+			continue
+		}
+		end := i
+		if tok.abbrev && end < len(d) && d[end] == '.' {
+			end++
+			i++
+		}
+		var eng string
+		if tok.month != 0 {
+			if eng = tok.month.String(); !fullMonth {
+				eng = eng[:3]
+			}
+		} else if eng = tok.weekday.String(); !fullWeekday {
+			eng = eng[:3]
+		}
+		if out == nil {
+			out = make([]byte, 0, len(d)+16)
+		}
+		out = append(out, d[last:start]...)
+		reps = append(reps, localeReplacement{
+			origStart: start,
+			origEnd:   end,
+			newStart:  len(out),
+			newEnd:    len(out) + len(eng),
+		})
+		out = append(out, eng...)
+		last = end
+	}
+	if out != nil {
+		out = append(out, d[last:]...)
+	}
+	return
+}
+
+func toLower(dst, word []byte) []byte {
+	for _, c := range word {
+		if c >= utf8.RuneSelf {
+			return bytes.ToLower(word)
+		}
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// mapOffset maps an offset in translated data back to the original, offsets that land inside a
+// replacement are moved to its start, or its end if end is set
+func mapOffset(reps []localeReplacement, off int, end bool) int {
+	shift := 0
+	for _, r := range reps {
+		if off >= r.newEnd {
+			shift = r.origEnd - r.newEnd
+			continue
+		} else if off > r.newStart {
+			if end {
+				return r.origEnd
+			}
+			return r.origStart
+		}
+		break
+	}
+	return off + shift
+}
+
+// localeProcessor wraps a processor whose format contains month or weekday names
+type localeProcessor struct {
+	Processor
+	lz          *localizer
+	fullMonth   bool
+	fullWeekday bool
+}
+
+// localizable returns true if the processor format has month or weekday names
+func localizable(p Processor) bool {
+	f := p.Format()
+	return strings.Contains(f, `Jan`) || strings.Contains(f, `Mon`)
+}
+
+// localize wraps a processor so that it understands localized names, processors that don't need it are
+// returned as is.  Wrapping an already localized processor replaces its locales.
+func localize(p Processor, lz *localizer) Processor {
+	if lp, ok := p.(*localeProcessor); ok {
+		p = lp.Processor
+	}
+	if lz == nil || !localizable(p) {
+		return p
+	}
+	f := p.Format()
+	return &localeProcessor{
+		Processor:   p,
+		lz:          lz,
+		fullMonth:   strings.Contains(f, `January`),
+		fullWeekday: strings.Contains(f, `Monday`),
+	}
+}
+
+// Extract tries the data as is and then with localized names translated
+func (lp *localeProcessor) Extract(d []byte, loc *time.Location) (t time.Time, ok bool, off int) {
+	if t, ok, off = lp.Processor.Extract(d, loc); ok {
+		return
+	}
+	td, reps := lp.lz.translate(d, lp.fullMonth, lp.fullWeekday)
+	if td == nil {
+		return
+	}
+	if t, ok, off = lp.Processor.Extract(td, loc); ok {
+		off = mapOffset(reps, off, false)
+	}
+	return
+}
+
+func (lp *localeProcessor) Match(d []byte) (start, end int, ok bool) {
+	if start, end, ok = lp.Processor.Match(d); ok {
+		return
+	}
+	td, reps := lp.lz.translate(d, lp.fullMonth, lp.fullWeekday)
+	if td == nil {
+		return
+	}
+	if start, end, ok = lp.Processor.Match(td); ok {
+		start, end = mapOffset(reps, start, false), mapOffset(reps, end, true)
+	}
+	return
+}
+
+// localize wraps a processor with the timegrinder locales, processors that already have their own locales keep them
+func (tg *TimeGrinder) localize(p Processor) Processor {
+	lz := tg.lz
+	if lp, ok := p.(*localeProcessor); ok {
+		if m, err := lp.lz.merge(tg.lz.names); err == nil {
+			lz = m
+		}
+	}
+	return localize(p, lz)
+}
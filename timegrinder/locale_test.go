@@ -0,0 +1,128 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocaleTranslate(t *testing.T) {
+	lz, err := newLocalizer([]string{`de`, `fr`, `es`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		in, out string
+		full    bool
+	}{
+		{`12 Mär 2024`, `12 Mar 2024`, false},
+		{`12 MÄRZ 2024`, `12 March 2024`, true},
+		{`3 févr. 2024 lundi`, `3 Feb 2024 Mon`, false},
+		{`3 févr. 2024 lundi`, `3 February 2024 Monday`, true},
+		{`dic 11 10:11:12`, `Dec 11 10:11:12`, false},
+		{`mar 5 marzo`, `Mar 5 Mar`, false}, //months win over weekdays
+		{`Maintenance window`, ``, false},
+	}
+	for _, tc := range tests {
+		out, _ := lz.translate([]byte(tc.in), tc.full, tc.full)
+		if string(out) != tc.out {
+			t.Fatalf("bad translation of %q: %q != %q", tc.in, out, tc.out)
+		}
+	}
+	if _, err = newLocalizer([]string{`de`, `klingon`}); err == nil {
+		t.Fatal("failed to catch bad locale")
+	}
+}
+
+func TestLocaleExtract(t *testing.T) {
+	tg, err := New(Config{Locales: []string{`de`, `fr`, `es`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		data  string
+		ts    time.Time
+		match string
+	}{
+		{
+			data:  `127.0.0.1 - - [10/févr./2024:13:55:36 -0700] "GET / HTTP/1.0" 200 2326`,
+			ts:    time.Date(2024, time.February, 10, 13, 55, 36, 0, time.FixedZone(``, -7*3600)),
+			match: `10/févr./2024:13:55:36 -0700`,
+		},
+		{
+			data:  `prefix Di Okt 29 08:09:10 2024 suffix`,
+			ts:    time.Date(2024, time.October, 29, 8, 9, 10, 0, time.UTC),
+			match: `Okt 29 08:09:10 2024`,
+		},
+		{
+			data:  `prefix Fri Mar 29 08:09:10 2024 suffix`, //English still works with locales set
+			ts:    time.Date(2024, time.March, 29, 8, 9, 10, 0, time.UTC),
+			match: `Mar 29 08:09:10 2024`,
+		},
+	}
+	for _, tc := range tests {
+		tg.Reset() //start from the top so the ANSIC format wins over syslog
+		ts, name, start, end, ok := tg.DebugMatch([]byte(tc.data))
+		if !ok {
+			t.Fatalf("failed to match %q", tc.data)
+		} else if !ts.Equal(tc.ts) {
+			t.Fatalf("%s bad timestamp on %q: %v != %v", name, tc.data, ts, tc.ts)
+		} else if m := tc.data[start:end]; m != tc.match {
+			t.Fatalf("%s bad match on %q: %q != %q", name, tc.data, m, tc.match)
+		}
+		if ts, ok, err = tg.Extract([]byte(tc.data)); err != nil || !ok || !ts.Equal(tc.ts) {
+			t.Fatalf("bad extraction on %q: %v %v %v", tc.data, ts, ok, err)
+		}
+	}
+
+	//without locales nothing is found
+	if tg, err = New(Config{}); err != nil {
+		t.Fatal(err)
+	} else if _, ok, _ := tg.Extract([]byte(tests[0].data)); ok {
+		t.Fatal("extracted a localized timestamp without locales")
+	}
+}
+
+func TestLocaleCustomFormat(t *testing.T) {
+	tg, err := New(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cf := CustomFormat{
+		Name:          `german`,
+		Format:        `%d. %B %Y %H:%M`,
+		Format_Syntax: SyntaxStrftime,
+		Locale:        []string{`de`},
+	}
+	p, err := NewCustomProcessor(cf)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err = tg.AddProcessor(p); err != nil {
+		t.Fatal(err)
+	}
+	ts, ok, err := tg.Extract([]byte(`am 24. Dezember 2024 18:30 Uhr`))
+	if err != nil || !ok {
+		t.Fatal("failed to extract", ok, err)
+	} else if exp := time.Date(2024, time.December, 24, 18, 30, 0, 0, time.UTC); !ts.Equal(exp) {
+		t.Fatalf("bad timestamp %v", ts)
+	}
+
+	//locales added later apply to existing custom formats too
+	if err = tg.AddLocales(`es`); err != nil {
+		t.Fatal(err)
+	} else if ts, ok, _ = tg.Extract([]byte(`24. diciembre 2024 18:30`)); !ok || ts.Month() != time.December {
+		t.Fatalf("failed to extract with added locale %v %v", ts, ok)
+	}
+
+	cf.Locale = []string{`xx`}
+	if err = cf.Validate(); err == nil {
+		t.Fatal("failed to catch bad locale")
+	}
+}
@@ -185,6 +185,7 @@ type Processor interface {
 type processor struct {
 	rxp    *regexp.Regexp
 	trxpEx *regexp.Regexp // a tail regex to exclude (used for timezones)
+	scan   scanner        // optional fast path that replaces both regular expressions
 	rxstr  string
 	format string
 	name   string
@@ -238,6 +239,8 @@ func extract(rx, rxt *regexp.Regexp, d []byte, format string, loc *time.Location
 func (a *processor) Extract(d []byte, loc *time.Location) (time.Time, bool, int) {
 	if len(d) < a.min {
 		return time.Time{}, false, -1 //cannot possibly hit
+	} else if a.scan != nil {
+		return scanExtract(a.scan, d, a.format, loc)
 	}
 	return extract(a.rxp, a.trxpEx, d, a.format, loc)
 }
@@ -263,6 +266,8 @@ func match(rx, rxt *regexp.Regexp, d []byte) (start, end int, ok bool) {
 func (a *processor) Match(d []byte) (int, int, bool) {
 	if len(d) < a.min {
 		return -1, -1, false //cannot possibly hit
+	} else if a.scan != nil {
+		return a.scan(d)
 	}
 	return match(a.rxp, a.trxpEx, d)
 }
@@ -350,6 +355,7 @@ func NewRFC1123ZProcessor() *processor {
 func NewRFC3339Processor() *processor {
 	return &processor{
 		rxp:    regexp.MustCompile(RFC3339Regex),
+		scan:   scanRFC3339,
 		rxstr:  RFC3339Regex,
 		format: RFC3339Format,
 		name:   RFC3339.String(),
@@ -360,6 +366,7 @@ func NewRFC3339Processor() *processor {
 func NewRFC3339NanoProcessor() *processor {
 	return &processor{
 		rxp:    regexp.MustCompile(RFC3339NanoRegex),
+		scan:   scanRFC3339Nano,
 		rxstr:  RFC3339NanoRegex,
 		format: RFC3339NanoFormat,
 		name:   RFC3339Nano.String(),
@@ -370,6 +377,7 @@ func NewRFC3339NanoProcessor() *processor {
 func NewApacheProcessor() *processor {
 	return &processor{
 		rxp:    regexp.MustCompile(ApacheRegex),
+		scan:   scanApache,
 		rxstr:  ApacheRegex,
 		format: ApacheFormat,
 		name:   Apache.String(),
@@ -381,6 +389,7 @@ func NewApacheNoTZProcessor() *processor {
 	return &processor{
 		rxp:    regexp.MustCompile(ApacheNoTzRegex),
 		trxpEx: regexp.MustCompile(`^\s?[-+]{1}\d{4}`),
+		scan:   scanApacheNoTz,
 		rxstr:  ApacheNoTzRegex,
 		format: ApacheNoTzFormat,
 		name:   ApacheNoTz.String(),
@@ -391,6 +400,7 @@ func NewApacheNoTZProcessor() *processor {
 func NewSyslogFileProcessor() *processor {
 	return &processor{
 		rxp:    regexp.MustCompile(SyslogFileRegex),
+		scan:   scanSyslogFile,
 		rxstr:  SyslogFileRegex,
 		format: SyslogFileFormat,
 		name:   SyslogFile.String(),
@@ -401,6 +411,7 @@ func NewSyslogFileProcessor() *processor {
 func NewSyslogFileProcessorTZ2() *processor {
 	return &processor{
 		rxp:    regexp.MustCompile(SyslogFileTZRegex),
+		scan:   scanSyslogFileTZ,
 		rxstr:  SyslogFileTZRegex,
 		format: SyslogFileTZFormat,
 		name:   SyslogFileTZ.String(),
@@ -432,6 +443,7 @@ func NewZonelessRFC3339() *processor {
 	return &processor{
 		rxp:    regexp.MustCompile(ZonelessRFC3339Regex),
 		trxpEx: regexp.MustCompile(tzRegexMatch),
+		scan:   scanZonelessRFC3339,
 		rxstr:  ZonelessRFC3339Regex,
 		format: ZonelessRFC3339Format,
 		name:   ZonelessRFC3339.String(),
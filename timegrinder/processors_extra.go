@@ -28,6 +28,7 @@ func NewSyslogProcessor() *syslogProcessor {
 	return &syslogProcessor{
 		processor: processor{
 			rxp:    regexp.MustCompile(SyslogRegex),
+			scan:   scanSyslog,
 			rxstr:  SyslogRegex,
 			format: SyslogFormat,
 			name:   Syslog.String(),
@@ -38,6 +39,7 @@ func NewSyslogProcessor() *syslogProcessor {
 
 type unixProcessor struct {
 	re     *regexp.Regexp
+	scan   scanner
 	rxstr  string
 	format string
 	name   string
@@ -47,6 +49,7 @@ type unixProcessor struct {
 func NewUnixMilliTimeProcessor() *unixProcessor {
 	return &unixProcessor{
 		re:     regexp.MustCompile(UnixMilliRegex),
+		scan:   epochScanner(9, 10, true),
 		rxstr:  UnixMilliRegex,
 		format: ``, //format API doesn't work here
 		name:   UnixMilli.String(),
@@ -126,15 +129,15 @@ func (up unixProcessor) Extract(d []byte, loc *time.Location) (t time.Time, ok b
 		return time.Time{}, false, -1
 	}
 	offset = -1
-	idx := up.re.FindSubmatchIndex(d)
-	if len(idx) != 4 {
+	start, end, hit := findGroup(up.re, up.scan, d)
+	if !hit {
 		return
 	}
-	s, err := strconv.ParseFloat(string(d[idx[2]:idx[3]]), 64)
+	s, err := strconv.ParseFloat(string(d[start:end]), 64)
 	if err != nil {
 		return
 	}
-	offset = idx[2]
+	offset = start
 	sec := int64(s)
 	nsec := int64((s - float64(sec)) * 1000000000.0)
 	t = time.Unix(sec, nsec).In(loc)
@@ -143,16 +146,12 @@ func (up unixProcessor) Extract(d []byte, loc *time.Location) (t time.Time, ok b
 }
 
 func (up unixProcessor) Match(d []byte) (start, end int, ok bool) {
-	idx := up.re.FindSubmatchIndex(d)
-	if len(idx) == 4 {
-		start, end = idx[2], idx[3]
-		ok = true
-	}
-	return
+	return findGroup(up.re, up.scan, d)
 }
 
 type unixMsProcessor struct {
 	re     *regexp.Regexp
+	scan   scanner
 	rxstr  string
 	format string
 	name   string
@@ -163,6 +162,7 @@ type unixMsProcessor struct {
 func NewUnixMsTimeProcessor() *unixMsProcessor {
 	return &unixMsProcessor{
 		re:     regexp.MustCompile(UnixMsRegex),
+		scan:   epochScanner(12, 13, false),
 		rxstr:  UnixMsRegex,
 		format: ``, //API doesn't work here
 		name:   UnixMs.String(),
@@ -191,15 +191,15 @@ func (unp unixMsProcessor) Extract(d []byte, loc *time.Location) (t time.Time, o
 		return
 	}
 	offset = -1
-	idx := unp.re.FindSubmatchIndex(d)
-	if len(idx) != 4 {
+	start, end, hit := findGroup(unp.re, unp.scan, d)
+	if !hit {
 		return
 	}
-	ms, err := strconv.ParseInt(string(d[idx[2]:idx[3]]), 10, 64)
+	ms, err := strconv.ParseInt(string(d[start:end]), 10, 64)
 	if err != nil {
 		return
 	}
-	offset = idx[2]
+	offset = start
 	t = time.Unix(0, ms*1000000).In(loc)
 	ok = true
 	return
@@ -209,16 +209,12 @@ func (unp unixMsProcessor) Match(d []byte) (start, end int, ok bool) {
 	if len(d) < unp.min {
 		return
 	}
-	idx := unp.re.FindSubmatchIndex(d)
-	if len(idx) == 4 {
-		start, end = idx[2], idx[3]
-		ok = true
-	}
-	return
+	return findGroup(unp.re, unp.scan, d)
 }
 
 type unixNanoProcessor struct {
 	re     *regexp.Regexp
+	scan   scanner
 	rxstr  string
 	format string
 	name   string
@@ -229,6 +225,7 @@ type unixNanoProcessor struct {
 func NewUnixNanoTimeProcessor() *unixNanoProcessor {
 	return &unixNanoProcessor{
 		re:     regexp.MustCompile(UnixNanoRegex),
+		scan:   epochScanner(18, 19, false),
 		rxstr:  UnixNanoRegex,
 		format: ``, //api doesn't work here
 		name:   UnixNano.String(),
@@ -257,15 +254,15 @@ func (unp unixNanoProcessor) Extract(d []byte, loc *time.Location) (t time.Time,
 		return
 	}
 	offset = -1
-	idx := unp.re.FindSubmatchIndex(d)
-	if len(idx) != 4 {
+	start, end, hit := findGroup(unp.re, unp.scan, d)
+	if !hit {
 		return
 	}
-	nsec, err := strconv.ParseInt(string(d[idx[2]:idx[3]]), 10, 64)
+	nsec, err := strconv.ParseInt(string(d[start:end]), 10, 64)
 	if err != nil {
 		return
 	}
-	offset = idx[2]
+	offset = start
 	t = time.Unix(0, nsec).In(loc)
 	ok = true
 	return
@@ -275,12 +272,7 @@ func (unp unixNanoProcessor) Match(d []byte) (start, end int, ok bool) {
 	if len(d) < unp.min {
 		return
 	}
-	idx := unp.re.FindSubmatchIndex(d)
-	if len(idx) == 4 {
-		start, end = idx[2], idx[3]
-		ok = true
-	}
-	return
+	return findGroup(unp.re, unp.scan, d)
 }
 
 func NewUK() Processor {
@@ -358,6 +350,7 @@ func (p *ukProc) parse(value string, loc *time.Location) (time.Time, error) {
 
 type ldapProcessor struct {
 	re     *regexp.Regexp
+	scan   scanner
 	rxstr  string
 	format string
 	name   string
@@ -368,6 +361,7 @@ type ldapProcessor struct {
 func NewLDAPProcessor() *ldapProcessor {
 	return &ldapProcessor{
 		re:     regexp.MustCompile(LDAPRegex),
+		scan:   epochScanner(18, 18, false),
 		rxstr:  LDAPRegex,
 		format: ``, //api doesn't work here
 		name:   LDAP.String(),
@@ -397,16 +391,16 @@ func (lp ldapProcessor) Extract(d []byte, loc *time.Location) (t time.Time, ok b
 		return
 	}
 	offset = -1
-	idx := lp.re.FindSubmatchIndex(d)
-	if len(idx) != 4 {
+	start, end, hit := findGroup(lp.re, lp.scan, d)
+	if !hit {
 		return
 	}
 
-	ldap, err := strconv.ParseInt(string(d[idx[2]:idx[3]]), 10, 64)
+	ldap, err := strconv.ParseInt(string(d[start:end]), 10, 64)
 	if err != nil {
 		return
 	}
-	offset = idx[2]
+	offset = start
 
 	s := (ldap / 10000000) - 11644473600
 	t = time.Unix(s, 0).In(loc)
@@ -418,16 +412,12 @@ func (lp ldapProcessor) Match(d []byte) (start, end int, ok bool) {
 	if len(d) < lp.min {
 		return
 	}
-	idx := lp.re.FindSubmatchIndex(d)
-	if len(idx) == 4 {
-		start, end = idx[2], idx[3]
-		ok = true
-	}
-	return
+	return findGroup(lp.re, lp.scan, d)
 }
 
 type unixSecondsProcessor struct {
 	re     *regexp.Regexp
+	scan   scanner
 	rxstr  string
 	format string
 	name   string
@@ -437,6 +427,7 @@ type unixSecondsProcessor struct {
 func NewUnixSecondsProcessor() *unixSecondsProcessor {
 	return &unixSecondsProcessor{
 		re:     regexp.MustCompile(UnixSecondsRegex),
+		scan:   epochScanner(9, 10, false),
 		rxstr:  UnixSecondsRegex,
 		format: ``, //format API doesn't work here
 		name:   UnixSeconds.String(),
@@ -466,15 +457,15 @@ func (up unixSecondsProcessor) Extract(d []byte, loc *time.Location) (t time.Tim
 		return
 	}
 	offset = -1
-	idx := up.re.FindSubmatchIndex(d)
-	if len(idx) != 4 {
+	start, end, hit := findGroup(up.re, up.scan, d)
+	if !hit {
 		return
 	}
-	s, err := strconv.ParseInt(string(d[idx[2]:idx[3]]), 10, 64)
+	s, err := strconv.ParseInt(string(d[start:end]), 10, 64)
 	if err != nil {
 		return
 	}
-	offset = idx[2]
+	offset = start
 	t = time.Unix(s, 0).In(loc)
 	ok = true
 	return
@@ -484,26 +475,14 @@ func (up unixSecondsProcessor) Match(d []byte) (start, end int, ok bool) {
 	if len(d) < up.min {
 		return
 	}
-	idx := up.re.FindSubmatchIndex(d)
-	if len(idx) == 4 {
-		start, end = idx[2], idx[3]
-		ok = true
-	}
-	return
+	return findGroup(up.re, up.scan, d)
 }
 
 // tweakYear tries to figure out an appropriate year for the timestamp
-// if the current year is zero.
+// if the current year is zero, the time of extraction is used as the reference.
 func tweakYear(t time.Time) time.Time {
 	if t.Year() != 0 {
 		return t
 	}
-	now := time.Now()
-	year := now.Year()
-	// If setting the current year puts us too far in the future,
-	// more than 25 hours, we'll set the previous year instead.
-	if t.AddDate(year, 0, 0).Sub(now) > (25 * time.Hour) {
-		return t.AddDate(year-1, 0, 0)
-	}
-	return t.AddDate(year, 0, 0)
+	return inferYear(t, time.Now())
 }
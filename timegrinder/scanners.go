@@ -0,0 +1,354 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"bytes"
+	"regexp"
+	"time"
+	"unicode/utf8"
+)
+
+//the scanners file contains hand written replacements for the regular expressions used by the most common
+//processors.  A scanner MUST return exactly what the regular expression would, the processors still carry
+//their regular expressions and the tests check that both agree.  Any exclusion regex is folded into the
+//scanner, a match that is excluded is a miss just like it is in extract and match.
+
+// scanner finds the leftmost match in a buffer, for the unix processors the bounds are those of the capture group.
+// A miss returns zero bounds, the same as match.
+type scanner func([]byte) (start, end int, ok bool)
+
+const rfc3339PrefixLen = 17 // 2006-01-02T15:04:
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isSpace matches the \s character class
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// isMonthStart matches [JFMASOND]
+func isMonthStart(c byte) bool {
+	switch c {
+	case 'J', 'F', 'M', 'A', 'S', 'O', 'N', 'D':
+		return true
+	}
+	return false
+}
+
+// isMonthChar matches [anebriyunlgpctov]
+func isMonthChar(c byte) bool {
+	switch c {
+	case 'a', 'n', 'e', 'b', 'r', 'i', 'y', 'u', 'l', 'g', 'p', 'c', 't', 'o', 'v':
+		return true
+	}
+	return false
+}
+
+// digits returns true if there are n digits at offset i
+func digits(d []byte, i, n int) bool {
+	if i+n > len(d) {
+		return false
+	}
+	for _, c := range d[i : i+n] {
+		if !isDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// skipDigits returns the offset of the first non digit at or after i
+func skipDigits(d []byte, i int) int {
+	for i < len(d) && isDigit(d[i]) {
+		i++
+	}
+	return i
+}
+
+func skipSpace(d []byte, i int) int {
+	for i < len(d) && isSpace(d[i]) {
+		i++
+	}
+	return i
+}
+
+func skipMonthChars(d []byte, i int) int {
+	for i < len(d) && isMonthChar(d[i]) {
+		i++
+	}
+	return i
+}
+
+// char returns true if the byte at offset i is c
+func char(d []byte, i int, c byte) bool {
+	return i < len(d) && d[i] == c
+}
+
+// hms checks for \d\d:\d\d:\d\d at offset i
+func hms(d []byte, i int) bool {
+	return digits(d, i, 2) && char(d, i+2, ':') && digits(d, i+3, 2) && char(d, i+5, ':') && digits(d, i+6, 2)
+}
+
+// rfc3339Prefix checks for \d{4}-\d{2}-\d{2}T\d\d:\d\d: at offset i
+func rfc3339Prefix(d []byte, i int) bool {
+	return digits(d, i, 4) && char(d, i+4, '-') && digits(d, i+5, 2) && char(d, i+7, '-') && digits(d, i+8, 2) &&
+		char(d, i+10, 'T') && digits(d, i+11, 2) && char(d, i+13, ':') && digits(d, i+14, 2) && char(d, i+16, ':')
+}
+
+// scanRFC3339Family walks every T in the buffer, all of the RFC3339 style formats have the T at a fixed
+// offset so the candidates come out in the same order the regex would try them.
+// The tail function is handed the offset of the seconds and returns the end of the match.
+func scanRFC3339Family(d []byte, tail func([]byte, int) (int, bool)) (start, end int, ok bool) {
+	for off := 10; off < len(d); {
+		idx := bytes.IndexByte(d[off:], 'T')
+		if idx < 0 {
+			break
+		}
+		t := off + idx
+		off = t + 1
+		if start = t - 10; !rfc3339Prefix(d, start) {
+			continue
+		}
+		if end, ok = tail(d, start+rfc3339PrefixLen); ok {
+			return
+		}
+	}
+	return 0, 0, false
+}
+
+// scanRFC3339 matches RFC3339Regex \d{4}-\d{2}-\d{2}T\d\d:\d\d:\d\d[Z\-+]
+func scanRFC3339(d []byte) (int, int, bool) {
+	return scanRFC3339Family(d, func(d []byte, i int) (int, bool) {
+		if !digits(d, i, 2) || i+2 >= len(d) {
+			return 0, false
+		}
+		switch d[i+2] {
+		case 'Z', '-', '+':
+			return i + 3, true
+		}
+		return 0, false
+	})
+}
+
+// scanRFC3339Nano matches RFC3339NanoRegex \d{4}-\d{2}-\d{2}T\d\d:\d\d:\d\d.\d+[Z\-+]
+// the unescaped dot matches any character other than a newline
+func scanRFC3339Nano(d []byte) (int, int, bool) {
+	return scanRFC3339Family(d, func(d []byte, i int) (int, bool) {
+		if !digits(d, i, 2) || i+2 >= len(d) {
+			return 0, false
+		}
+		i += 2
+		r, w := utf8.DecodeRune(d[i:])
+		if r == '\n' {
+			return 0, false
+		}
+		i += w
+		end := skipDigits(d, i)
+		if end == i || end >= len(d) {
+			return 0, false
+		}
+		switch d[end] {
+		case 'Z', '-', '+':
+			return end + 1, true
+		}
+		return 0, false
+	})
+}
+
+// scanZonelessRFC3339 matches ZonelessRFC3339Regex \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.*\d* and applies
+// the tzRegexMatch exclusion, which hits on anything starting with a Z, + or -
+func scanZonelessRFC3339(d []byte) (start, end int, ok bool) {
+	if start, end, ok = scanRFC3339Family(d, func(d []byte, i int) (int, bool) {
+		if !digits(d, i, 2) {
+			return 0, false
+		}
+		i += 2
+		for i < len(d) && d[i] == '.' {
+			i++
+		}
+		return skipDigits(d, i), true
+	}); ok && end < len(d) {
+		switch d[end] {
+		case 'Z', '+', '-':
+			return 0, 0, false
+		}
+	}
+	return
+}
+
+// syslogFileTail matches \d+\.?\d*[-+] followed by the offset, \d\d:\d\d when colon is set and \d\d\d\d otherwise
+func syslogFileTail(d []byte, i int, colon bool) (int, bool) {
+	end := skipDigits(d, i)
+	if end == i {
+		return 0, false
+	}
+	if char(d, end, '.') {
+		end = skipDigits(d, end+1)
+	}
+	if !char(d, end, '-') && !char(d, end, '+') {
+		return 0, false
+	}
+	end++
+	if colon {
+		if digits(d, end, 2) && char(d, end+2, ':') && digits(d, end+3, 2) {
+			return end + 5, true
+		}
+	} else if digits(d, end, 4) {
+		return end + 4, true
+	}
+	return 0, false
+}
+
+// scanSyslogFile matches SyslogFileRegex \d{4}-\d{2}-\d{2}T\d\d:\d\d:\d+\.?\d*[-+]\d\d:\d\d
+func scanSyslogFile(d []byte) (int, int, bool) {
+	return scanRFC3339Family(d, func(d []byte, i int) (int, bool) {
+		return syslogFileTail(d, i, true)
+	})
+}
+
+// scanSyslogFileTZ matches SyslogFileTZRegex \d{4}-\d{2}-\d{2}T\d\d:\d\d:\d+\.?\d*[-+]\d\d\d\d
+func scanSyslogFileTZ(d []byte) (int, int, bool) {
+	return scanRFC3339Family(d, func(d []byte, i int) (int, bool) {
+		return syslogFileTail(d, i, false)
+	})
+}
+
+// scanSyslog matches SyslogRegex [JFMASOND][anebriyunlgpctov]+\s+\d+\s+\d\d:\d\d:\d\d
+func scanSyslog(d []byte) (int, int, bool) {
+	for i := 0; i < len(d); i++ {
+		if !isMonthStart(d[i]) {
+			continue
+		}
+		j := i + 1
+		if k := skipMonthChars(d, j); k == j {
+			continue
+		} else if j = skipSpace(d, k); j == k {
+			continue
+		} else if k = skipDigits(d, j); k == j {
+			continue
+		} else if j = skipSpace(d, k); j == k {
+			continue
+		}
+		if hms(d, j) {
+			return i, j + 8, true
+		}
+	}
+	return 0, 0, false
+}
+
+// apacheDate matches \d{1,2}/[JFMASOND][anebriyunlgpctov]+/\d{4}:\d\d:\d\d:\d\d at offset i and returns the end
+func apacheDate(d []byte, i int) (int, bool) {
+	if !isDigit(d[i]) {
+		return 0, false
+	}
+	j := i + 1
+	if j < len(d) && isDigit(d[j]) {
+		j++ //the regex tries two digits first, backing off to one can't help because the slash would have to be a digit
+	}
+	if !char(d, j, '/') || j+1 >= len(d) || !isMonthStart(d[j+1]) {
+		return 0, false
+	}
+	k := skipMonthChars(d, j+2)
+	if k == j+2 || !char(d, k, '/') || !digits(d, k+1, 4) || !char(d, k+5, ':') || !hms(d, k+6) {
+		return 0, false
+	}
+	return k + 14, true
+}
+
+// scanApache matches ApacheRegex \d{1,2}/[JFMASOND][anebriyunlgpctov]+/\d{4}:\d\d:\d\d:\d\d\s[\-|\+]\d{4}
+func scanApache(d []byte) (int, int, bool) {
+	for i := range d {
+		end, ok := apacheDate(d, i)
+		if !ok || end >= len(d) || !isSpace(d[end]) || end+1 >= len(d) {
+			continue
+		}
+		switch d[end+1] {
+		case '-', '|', '+':
+			if digits(d, end+2, 4) {
+				return i, end + 6, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// scanApacheNoTz matches ApacheNoTzRegex \d{1,2}/[JFMASOND][anebriyunlgpctov]+/\d{4}:\d\d:\d\d:\d\d
+// and applies the exclusion ^\s?[-+]{1}\d{4}
+func scanApacheNoTz(d []byte) (int, int, bool) {
+	for i := range d {
+		end, ok := apacheDate(d, i)
+		if !ok {
+			continue
+		}
+		j := end
+		if j < len(d) && isSpace(d[j]) {
+			j++
+		}
+		if (char(d, j, '-') || char(d, j, '+')) && digits(d, j+1, 4) {
+			return 0, 0, false
+		}
+		return i, end, true
+	}
+	return 0, 0, false
+}
+
+// epochScanner matches the unix regular expressions \A\s*(\d{min,max})(?:\D|$) and, when frac is set,
+// \A\s*(\d{min,max}\.\d+)(?:\D|$).  The bounds returned are those of the capture group.
+func epochScanner(min, max int, frac bool) scanner {
+	return func(d []byte) (start, end int, ok bool) {
+		start = skipSpace(d, 0)
+		end = skipDigits(d, start)
+		if n := end - start; n < min || n > max {
+			return 0, 0, false
+		}
+		if frac {
+			if !char(d, end, '.') {
+				return 0, 0, false
+			}
+			fend := skipDigits(d, end+1)
+			if fend == end+1 {
+				return 0, 0, false
+			}
+			end = fend
+		}
+		//the run of digits always ends on a non digit or the end of the buffer
+		ok = true
+		return
+	}
+}
+
+// scanExtract is extract using a scanner in place of the regular expressions
+func scanExtract(scan scanner, d []byte, format string, loc *time.Location) (t time.Time, ok bool, off int) {
+	var err error
+	off = -1
+	start, end, hit := scan(d)
+	if !hit {
+		return
+	}
+	if t, err = time.ParseInLocation(format, string(d[start:end]), loc); err != nil {
+		return
+	}
+	ok = true
+	off = start
+	return
+}
+
+// findGroup returns the bounds of the first capture group, using the scanner if there is one
+func findGroup(re *regexp.Regexp, scan scanner, d []byte) (start, end int, ok bool) {
+	if scan != nil {
+		return scan(d)
+	}
+	if idx := re.FindSubmatchIndex(d); len(idx) == 4 {
+		start, end, ok = idx[2], idx[3], true
+	}
+	return
+}
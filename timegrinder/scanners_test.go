@@ -0,0 +1,212 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+type scannerPair struct {
+	name   string
+	format string
+	fast   Processor // uses the hand written scanner
+	slow   Processor // uses the regular expressions
+}
+
+func scannerPairs() []scannerPair {
+	var pairs []scannerPair
+	add := func(format string, fast, slow Processor) {
+		pairs = append(pairs, scannerPair{name: fast.Name(), format: format, fast: fast, slow: slow})
+	}
+	for _, f := range []func() *processor{
+		NewRFC3339Processor, NewRFC3339NanoProcessor, NewZonelessRFC3339,
+		NewSyslogFileProcessor, NewSyslogFileProcessorTZ2, NewApacheProcessor, NewApacheNoTZProcessor,
+	} {
+		slow := f()
+		slow.scan = nil
+		add(slow.format, f(), slow)
+	}
+	sys := NewSyslogProcessor()
+	sys.scan = nil
+	add(SyslogFormat, NewSyslogProcessor(), sys)
+
+	um := NewUnixMilliTimeProcessor()
+	um.scan = nil
+	add(``, NewUnixMilliTimeProcessor(), um)
+	ums := NewUnixMsTimeProcessor()
+	ums.scan = nil
+	add(``, NewUnixMsTimeProcessor(), ums)
+	un := NewUnixNanoTimeProcessor()
+	un.scan = nil
+	add(``, NewUnixNanoTimeProcessor(), un)
+	us := NewUnixSecondsProcessor()
+	us.scan = nil
+	add(``, NewUnixSecondsProcessor(), us)
+	ldap := NewLDAPProcessor()
+	ldap.scan = nil
+	add(``, NewLDAPProcessor(), ldap)
+	return pairs
+}
+
+var scannerSeeds = []string{
+	`2023-01-02T15:04:05Z hello`,
+	`ts=2023-01-02T15:04:05.123456789-07:00 msg`,
+	`2023-01-02T15:04:05+07:00`,
+	`xx 2023-01-02T15:04:05 no zone`,
+	`2023-01-02T15:04:05...123Z`,
+	`2023-01-02T15:04:05.123`,
+	`2023-01-02T15:04:05,123Z`,
+	"2023-01-02T15:04:05\n123Z",
+	`2023-01-02T15:04:05é123Z`,
+	"2023-01-02T15:04:05\xff123Z",
+	`1234-56-78T2023-01-02T15:04:05.5+0700`,
+	`2023-01-02T15:04:5.5-07:00`,
+	`2023-01-02T15:04:05-0700 end`,
+	`<13>Jan  2 15:04:05 host app: message`,
+	`Oct 11 22:14:15 mymachine su: 'su root' failed`,
+	`ZOct 1 22:14:15`,
+	`Jann 2 15:04:05`,
+	"Dec\t\t31\r\n23:59:59",
+	`127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326`,
+	`[1/Oct/2000:13:55:36 |0700]`,
+	`123/Oct/2000:13:55:36 +0700`,
+	`10/Oct/2000:13:55:36`,
+	`10/Oct/2000:13:55:36 +0700`,
+	`10/Oct/2000:13:55:36+0700`,
+	`10/Oct/2000:13:55:36 x`,
+	`1136473445.123456 some data`,
+	` 1136473445 data`,
+	"\t\n136473445x",
+	`11364734450 too long`,
+	`1136473445000 ms`,
+	`1136473445000000000`,
+	`133345678901234567 ldap`,
+	`113647344.5`,
+	`1136473445.`,
+}
+
+func checkScannerPair(t *testing.T, p scannerPair, d []byte) {
+	t.Helper()
+	ft, fok, foff := p.fast.Extract(d, time.UTC)
+	st, sok, soff := p.slow.Extract(d, time.UTC)
+	if fok != sok || foff != soff || !ft.Equal(st) {
+		t.Fatalf("%s extract mismatch on %q: scanner (%v %v %d) regex (%v %v %d)", p.name, d, ft, fok, foff, st, sok, soff)
+	}
+	fs, fe, fok := p.fast.Match(d)
+	ss, se, sok := p.slow.Match(d)
+	if fs != ss || fe != se || fok != sok {
+		t.Fatalf("%s match mismatch on %q: scanner (%d %d %v) regex (%d %d %v)", p.name, d, fs, fe, fok, ss, se, sok)
+	}
+}
+
+const mutationChars = "0123456789-:.T Z+/|\n\tJanOctDecé\xff"
+
+// mutate randomly changes, inserts, and removes characters that matter to the scanners
+func mutate(r *rand.Rand, b []byte) []byte {
+	b = append([]byte(nil), b...)
+	for n := r.Intn(4); n >= 0; n-- {
+		c := mutationChars[r.Intn(len(mutationChars))]
+		switch i := r.Intn(len(b) + 1); r.Intn(3) {
+		case 0:
+			if i < len(b) {
+				b[i] = c
+			}
+		case 1:
+			b = append(b[:i], append([]byte{c}, b[i:]...)...)
+		case 2:
+			if i < len(b) {
+				b = append(b[:i], b[i+1:]...)
+			}
+		}
+	}
+	return b
+}
+
+func TestScannerEquivalence(t *testing.T) {
+	pairs := scannerPairs()
+	r := rand.New(rand.NewSource(1))
+	var corpus [][]byte
+	for _, v := range scannerSeeds {
+		corpus = append(corpus, []byte(v))
+	}
+	//real timestamps for every processor
+	ts := time.Date(2023, 7, 4, 3, 2, 1, 123456789, time.FixedZone(``, -7*3600))
+	for _, p := range pairs {
+		if p.format != `` {
+			corpus = append(corpus, []byte(`prefix `+ts.Format(p.format)+` suffix`))
+		}
+		corpus = append(corpus, []byte(p.fast.ToString(ts)+` suffix`))
+	}
+	for _, seed := range corpus {
+		for _, p := range pairs {
+			checkScannerPair(t, p, seed)
+		}
+		for i := 0; i < 500; i++ {
+			m := mutate(r, seed)
+			for _, p := range pairs {
+				checkScannerPair(t, p, m)
+			}
+		}
+	}
+}
+
+func FuzzScanners(f *testing.F) {
+	for _, v := range scannerSeeds {
+		f.Add([]byte(v))
+	}
+	pairs := scannerPairs()
+	f.Fuzz(func(t *testing.T, d []byte) {
+		for _, p := range pairs {
+			checkScannerPair(t, p, d)
+		}
+	})
+}
+
+// BenchmarkScanners compares the scanners against the regular expressions they replace, both on
+// a hit and on a miss, a miss is what happens when the timegrinder walks every processor
+func BenchmarkScanners(b *testing.B) {
+	miss := []byte(`this line has no timestamp in it at all, just some words and numbers like 12345 and 10.0.0.1`)
+	ts := time.Date(2023, 7, 4, 3, 2, 1, 123456789, time.UTC)
+	for _, p := range scannerPairs() {
+		hit := []byte(`<13>` + p.fast.ToString(ts) + ` host app[123]: some message that follows the timestamp`)
+		if _, ok, _ := p.fast.Extract(hit, time.UTC); !ok {
+			hit = []byte(p.fast.ToString(ts) + ` host app[123]: some message that follows the timestamp`)
+		}
+		for _, c := range []struct {
+			name string
+			proc Processor
+		}{{`regex`, p.slow}, {`scanner`, p.fast}} {
+			b.Run(p.name+`/hit/`+c.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					c.proc.Extract(hit, time.UTC)
+				}
+			})
+			b.Run(p.name+`/miss/`+c.name, func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					c.proc.Extract(miss, time.UTC)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkGrinderMiss measures a full pass over every processor, which is what a line without a timestamp costs
+func BenchmarkGrinderMiss(b *testing.B) {
+	tg, err := New(Config{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	miss := []byte(`this line has no timestamp in it at all, just some words and numbers like 12345 and 10.0.0.1`)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tg.Extract(miss)
+	}
+}
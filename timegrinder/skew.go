@@ -0,0 +1,153 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SkewPolicy controls what happens to an extracted timestamp that falls outside of the allowed window
+// relative to the time of extraction.
+type SkewPolicy int
+
+const (
+	SkewIgnore SkewPolicy = iota // timestamps are handed back untouched, this is the default
+	SkewNow                      // timestamps outside the window are replaced with the current time
+	SkewClamp                    // timestamps outside the window are moved to the nearest edge of the window
+	SkewMark                     // timestamps are handed back untouched and flagged, see ExtractSkew
+)
+
+// Skew describes where an extracted timestamp fell relative to the allowed window.
+type Skew int
+
+const (
+	SkewNone   Skew = iota // within the window, or no window is set
+	SkewFuture             // further in the future than MaxFutureSkew allows
+	SkewPast               // further in the past than MaxPastSkew allows
+)
+
+var (
+	ErrInvalidSkewPolicy = errors.New("Invalid timestamp skew policy, must be ignore, now, clamp, or mark")
+	ErrInvalidSkew       = errors.New("Timestamp skew limits may not be negative")
+)
+
+// ParseSkewPolicy converts a configuration string to a SkewPolicy, an empty string is SkewIgnore
+func ParseSkewPolicy(v string) (p SkewPolicy, err error) {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case ``, `ignore`:
+		p = SkewIgnore
+	case `now`:
+		p = SkewNow
+	case `clamp`:
+		p = SkewClamp
+	case `mark`:
+		p = SkewMark
+	default:
+		err = ErrInvalidSkewPolicy
+	}
+	return
+}
+
+func (p SkewPolicy) String() string {
+	switch p {
+	case SkewIgnore:
+		return `ignore`
+	case SkewNow:
+		return `now`
+	case SkewClamp:
+		return `clamp`
+	case SkewMark:
+		return `mark`
+	}
+	return `unknown`
+}
+
+func (s Skew) String() string {
+	switch s {
+	case SkewNone:
+		return `none`
+	case SkewFuture:
+		return `future`
+	case SkewPast:
+		return `past`
+	}
+	return `unknown`
+}
+
+func (c Config) validateSkew() error {
+	if c.MaxFutureSkew < 0 || c.MaxPastSkew < 0 {
+		return ErrInvalidSkew
+	}
+	switch c.SkewPolicy {
+	case SkewIgnore, SkewNow, SkewClamp, SkewMark:
+	default:
+		return ErrInvalidSkewPolicy
+	}
+	return nil
+}
+
+// checkSkew compares a timestamp against the allowed window around now and applies the skew policy.
+// A zero limit means there is no limit in that direction.
+func (c Config) checkSkew(t, now time.Time) (time.Time, Skew) {
+	var edge time.Time
+	var s Skew
+	if c.MaxFutureSkew > 0 {
+		if edge = now.Add(c.MaxFutureSkew); t.After(edge) {
+			s = SkewFuture
+		}
+	}
+	if s == SkewNone && c.MaxPastSkew > 0 {
+		if edge = now.Add(-c.MaxPastSkew); t.Before(edge) {
+			s = SkewPast
+		}
+	}
+	if s == SkewNone {
+		return t, s
+	}
+	switch c.SkewPolicy {
+	case SkewNow:
+		t = now
+	case SkewClamp:
+		t = edge.In(t.Location())
+	}
+	return t, s
+}
+
+// inferYear resolves the year for a timestamp that did not carry one.  The year closest to the reference
+// time is used, as long as that does not put the timestamp more than a day into the future.  This handles
+// both sides of New Year, a December timestamp seen in January lands in the previous year and a January
+// timestamp from a source with a fast clock seen on December 31st lands in the next year.
+func inferYear(t, ref time.Time) time.Time {
+	const maxFuture = 25 * time.Hour
+	var best time.Time
+	var bestDist time.Duration
+	ref = ref.In(t.Location())
+	for year := ref.Year() + 1; year >= ref.Year()-1; year-- {
+		c := time.Date(year, t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		if c.Month() != t.Month() {
+			continue //Feb 29th in a year that doesn't have one
+		}
+		dist := c.Sub(ref)
+		if dist > maxFuture {
+			continue
+		} else if dist < 0 {
+			dist = -dist
+		}
+		if best.IsZero() || dist < bestDist {
+			best, bestDist = c, dist
+		}
+	}
+	if best.IsZero() {
+		//a leap day that doesn't fit any nearby year, fall back to date normalization
+		best = t.AddDate(ref.Year()-1, 0, 0)
+	}
+	return best
+}
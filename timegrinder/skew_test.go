@@ -0,0 +1,105 @@
+/*************************************************************************
+ * Copyright 2025 Gravwell, Inc. All rights reserved.
+ * Contact: <legal@gravwell.io>
+ *
+ * This software may be modified and distributed under the terms of the
+ * BSD 2-clause license. See the LICENSE file for details.
+ **************************************************************************/
+
+package timegrinder
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInferYear(t *testing.T) {
+	yearless := func(m time.Month, d, h int) time.Time {
+		return time.Date(0, m, d, h, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		ts   time.Time
+		ref  time.Time
+		year int
+	}{
+		{yearless(time.June, 1, 12), time.Date(2024, time.June, 1, 13, 0, 0, 0, time.UTC), 2024},
+		// a December entry received just after New Year is from last year
+		{yearless(time.December, 31, 23), time.Date(2025, time.January, 1, 0, 5, 0, 0, time.UTC), 2024},
+		// a January entry received just before New Year, from a source with a fast clock, is from next year
+		{yearless(time.January, 1, 0), time.Date(2024, time.December, 31, 23, 0, 0, 0, time.UTC), 2025},
+		// slightly ahead is tolerated
+		{yearless(time.June, 2, 12), time.Date(2024, time.June, 1, 13, 0, 0, 0, time.UTC), 2024},
+		// well into the future is last year
+		{yearless(time.August, 1, 0), time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC), 2023},
+		// leap days go to a year that has one
+		{yearless(time.February, 29, 0), time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), 2024},
+	}
+	for i, tc := range tests {
+		if r := inferYear(tc.ts, tc.ref); r.Year() != tc.year || r.Month() != tc.ts.Month() || r.Day() != tc.ts.Day() {
+			t.Fatalf("%d: bad inferred time %v, expected year %d", i, r, tc.year)
+		}
+	}
+}
+
+func TestCheckSkew(t *testing.T) {
+	now := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	c := Config{MaxFutureSkew: time.Hour, MaxPastSkew: 24 * time.Hour}
+	future := now.Add(2 * time.Hour)
+	past := now.Add(-48 * time.Hour)
+	for _, tc := range []struct {
+		policy SkewPolicy
+		in     time.Time
+		out    time.Time
+		skew   Skew
+	}{
+		{SkewIgnore, future, future, SkewFuture},
+		{SkewMark, past, past, SkewPast},
+		{SkewNow, future, now, SkewFuture},
+		{SkewNow, past, now, SkewPast},
+		{SkewClamp, future, now.Add(time.Hour), SkewFuture},
+		{SkewClamp, past, now.Add(-24 * time.Hour), SkewPast},
+		{SkewClamp, now.Add(-time.Hour), now.Add(-time.Hour), SkewNone},
+	} {
+		c.SkewPolicy = tc.policy
+		if r, s := c.checkSkew(tc.in, now); !r.Equal(tc.out) || s != tc.skew {
+			t.Fatalf("%v: bad result on %v: %v %v", tc.policy, tc.in, r, s)
+		}
+	}
+}
+
+func TestExtractSkew(t *testing.T) {
+	if _, err := New(Config{MaxPastSkew: -time.Second}); err == nil {
+		t.Fatal("failed to catch negative skew")
+	} else if _, err = New(Config{SkewPolicy: SkewPolicy(99)}); err == nil {
+		t.Fatal("failed to catch bad policy")
+	}
+	for _, v := range []string{`now`, `CLAMP`, ` mark`, ``} {
+		if _, err := ParseSkewPolicy(v); err != nil {
+			t.Fatalf("failed to parse %q: %v", v, err)
+		}
+	}
+	if _, err := ParseSkewPolicy(`bogus`); err == nil {
+		t.Fatal("failed to catch bad policy")
+	}
+
+	tg, err := New(Config{MaxPastSkew: 24 * time.Hour, SkewPolicy: SkewMark})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, skew, ok, err := tg.ExtractSkew([]byte(`1999-01-02T15:04:05Z some old entry`))
+	if err != nil || !ok {
+		t.Fatal("failed to extract", ok, err)
+	} else if skew != SkewPast || ts.Year() != 1999 {
+		t.Fatalf("bad skew %v %v", skew, ts)
+	}
+
+	if tg, err = New(Config{MaxPastSkew: 24 * time.Hour, SkewPolicy: SkewNow}); err != nil {
+		t.Fatal(err)
+	}
+	// a garbage number that looks like unix seconds
+	if ts, ok, err = tg.Extract([]byte(`100000000 things`)); err != nil || !ok {
+		t.Fatal("failed to extract", ok, err)
+	} else if time.Since(ts) > time.Minute {
+		t.Fatalf("timestamp was not replaced: %v", ts)
+	}
+}
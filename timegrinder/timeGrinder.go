@@ -44,6 +44,7 @@ type TimeGrinder struct {
 	seed     bool
 	override Processor
 	loc      *time.Location
+	lz       *localizer
 }
 
 // Config defines a few configuration options when instantiating a new TimeGrinder.
@@ -54,6 +55,14 @@ type Config struct {
 	EnableLeftMostSeed bool
 	// FormatOverride sets a format (e.g. "AnsiC") which should be tried first during parsing.
 	FormatOverride string
+	// MaxFutureSkew and MaxPastSkew bound how far an extracted timestamp may be from the time of extraction,
+	// zero means no bound.  SkewPolicy decides what happens to timestamps outside the bounds.
+	MaxFutureSkew time.Duration
+	MaxPastSkew   time.Duration
+	SkewPolicy    SkewPolicy
+	// Locales adds month and weekday names from other languages, e.g. de, fr, or es, to the processors and
+	// custom formats that use names.  Names are tried as is first, so English names always work.
+	Locales []string
 }
 
 func Extract(b []byte) (t time.Time, ok bool, err error) {
@@ -182,12 +191,27 @@ func New(c Config) (tg *TimeGrinder, err error) {
 	// DirectAdmin format
 	procs = append(procs, NewDirectAdmin())
 
+	if err = c.validateSkew(); err != nil {
+		return
+	}
+
 	tg = &TimeGrinder{
+		Config: Config{
+			MaxFutureSkew: c.MaxFutureSkew,
+			MaxPastSkew:   c.MaxPastSkew,
+			SkewPolicy:    c.SkewPolicy,
+		},
 		procs: procs,
 		count: len(procs),
 		loc:   time.UTC,
 		seed:  c.EnableLeftMostSeed,
 	}
+	if len(c.Locales) > 0 {
+		if err = tg.AddLocales(c.Locales...); err != nil {
+			tg = nil
+			return
+		}
+	}
 	if c.FormatOverride != `` {
 		err = tg.SetFormatOverride(c.FormatOverride)
 	}
@@ -246,12 +270,35 @@ func (tg *TimeGrinder) AddProcessor(p Processor) (idx int, err error) {
 			return
 		}
 	}
+	if tg.lz != nil {
+		p = tg.localize(p)
+	}
 	tg.procs = append([]Processor{p}, tg.procs...)
 	tg.count++
 	idx = 0
 	return
 }
 
+// AddLocales adds month and weekday names from the given locales to every processor that uses names,
+// including processors added later.
+func (tg *TimeGrinder) AddLocales(names ...string) (err error) {
+	var lz *localizer
+	if len(names) == 0 {
+		return
+	} else if lz, err = tg.lz.merge(names); err != nil {
+		return
+	}
+	tg.lz = lz
+	tg.Locales = lz.names
+	for i := range tg.procs {
+		tg.procs[i] = tg.localize(tg.procs[i])
+		if tg.override != nil && tg.override.Name() == tg.procs[i].Name() {
+			tg.override = tg.procs[i]
+		}
+	}
+	return
+}
+
 func (tg *TimeGrinder) GetProcessor(name string) (p Processor, ok bool) {
 	for _, v := range tg.procs {
 		if v.Name() == name {
@@ -298,7 +345,22 @@ func (tg *TimeGrinder) setSeed(data []byte) (hit bool) {
 
 // Extract returns time and error.  If no time can be extracted time is the zero
 // value and bool is false.  Error indicates a catastrophic failure.
+// The skew policy is applied to the extracted time.
 func (tg *TimeGrinder) Extract(data []byte) (t time.Time, ok bool, err error) {
+	t, _, ok, err = tg.ExtractSkew(data)
+	return
+}
+
+// ExtractSkew is Extract, but also returns where the timestamp fell relative to the configured skew limits.
+// This is how callers find timestamps flagged by the SkewMark policy.
+func (tg *TimeGrinder) ExtractSkew(data []byte) (t time.Time, skew Skew, ok bool, err error) {
+	if t, ok, err = tg.extract(data); ok && (tg.MaxFutureSkew > 0 || tg.MaxPastSkew > 0) {
+		t, skew = tg.checkSkew(t, time.Now())
+	}
+	return
+}
+
+func (tg *TimeGrinder) extract(data []byte) (t time.Time, ok bool, err error) {
 	var i int
 	var c int
 
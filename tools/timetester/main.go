@@ -12,6 +12,7 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	// Embed tzdata so that we don't rely on potentially broken timezone DBs on the host
@@ -26,8 +27,14 @@ var (
 	lms            = flag.Bool("enable-left-most-seed", false, "Activate EnableLeftMostSeed config option")
 	fo             = flag.String("format-override", "", "Enable FormatOverride config option")
 	metrics        = flag.Bool("metrics", false, "Output metrics about captures")
+	format         = flag.String("format", "", "Add a custom time format, the extraction regex is generated for strftime and java formats")
+	formatSyntax   = flag.String("format-syntax", timegrinder.SyntaxStrftime, "Syntax of the -format flag: go, strftime, or java")
+	formatRegex    = flag.String("format-regex", "", "Extraction regex for the -format flag, required for go formats")
+	localeList     = flag.String("locales", "", "Comma separated list of locales for month and weekday names, e.g. de,fr,es")
 )
 
+const cliFormatName = `cli`
+
 type customFormats struct {
 	TimeFormat config.CustomTimeFormat
 }
@@ -38,6 +45,9 @@ func main() {
 	cfg := timegrinder.Config{
 		EnableLeftMostSeed: *lms,
 	}
+	if *localeList != `` {
+		cfg.Locales = strings.Split(*localeList, ",")
+	}
 	tg, err := timegrinder.New(cfg)
 	if err != nil {
 		log.Fatalf("Failed to build timegrinder: %v\n", err)
@@ -50,12 +60,19 @@ func main() {
 		for k, v := range cf.TimeFormat {
 			if v == nil {
 				continue
+			} else if v.Format == `` && len(v.Locale) > 0 {
+				if err := tg.AddLocales(v.Locale...); err != nil {
+					log.Fatalf("Invalid locales in %q: %v\n", k, err)
+				}
+				continue
 			}
 			cf := timegrinder.CustomFormat{
 				Name:             k,
 				Regex:            v.Regex,
 				Format:           v.Format,
+				Format_Syntax:    v.Format_Syntax,
 				Extraction_Regex: v.Extraction_Regex,
+				Locale:           v.Locale,
 			}
 			if cp, err := timegrinder.NewCustomProcessor(cf); err != nil {
 				log.Fatalf("Invalid custom format %q: %v\n", k, err)
@@ -64,6 +81,22 @@ func main() {
 			}
 		}
 	}
+	if *format != `` {
+		cf := timegrinder.CustomFormat{
+			Name:          cliFormatName,
+			Format:        *format,
+			Format_Syntax: *formatSyntax,
+			Regex:         *formatRegex,
+		}
+		if cp, err := timegrinder.NewCustomProcessor(cf); err != nil {
+			log.Fatalf("Invalid custom format %q: %v\n", *format, err)
+		} else if _, err := tg.AddProcessor(cp); err != nil {
+			log.Fatalf("Failed to load custom time format %q: %v\n", *format, err)
+		} else {
+			fmt.Printf("Layout: %s%s%s\n", Yellow, cp.Format(), Reset)
+			fmt.Printf("Regex:  %s%s%s\n", Yellow, cp.ExtractionRegex(), Reset)
+		}
+	}
 	if *fo != `` {
 		if err := tg.SetFormatOverride(*fo); err != nil {
 			log.Fatalf("Failed to set timestamp format override to %q: %v\n", *fo, err)